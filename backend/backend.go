@@ -7,10 +7,12 @@ package backend
 
 import (
 	"errors"
+	"github.com/benbjohnson/clock"
 	"github.com/diffeo/go-coordinate/coordinate"
 	"github.com/diffeo/go-coordinate/memory"
 	"github.com/diffeo/go-coordinate/postgres"
 	"github.com/diffeo/go-coordinate/restclient"
+	"net/url"
 	"strings"
 )
 
@@ -23,6 +25,14 @@ import (
 //         flag.Parse()
 //         coordinate := backend.Coordinate()
 //     }
+//
+// The string form may end with "#namespace" to name a default
+// namespace, so that tools sharing a backend can keep their work
+// apart, for instance "memory#test" or
+// "postgres://localhost/coordinate#staging".  This is only possible
+// when there is no address, or the address is a URL; other addresses,
+// such as PostgreSQL "key=value" connection strings, are used whole
+// and may contain "#".
 type Backend struct {
 	// Implementation holds the name of the implementation; for
 	// instance, "memory".
@@ -31,6 +41,11 @@ type Backend struct {
 	// Address holds some backend-specific address, such as a
 	// database connect string.
 	Address string
+
+	// Namespace holds the name of the namespace tools should
+	// use if they are not told otherwise.  The empty string is
+	// the default namespace.
+	Namespace string
 }
 
 // Coordinate creates a new coordinate interface.  This generally should be
@@ -47,18 +62,63 @@ type Backend struct {
 func (b *Backend) Coordinate() (coordinate.Coordinate, error) {
 	switch b.Implementation {
 	case "http", "https":
-		return restclient.New(b.String())
+		return restclient.New(b.location())
+	default:
+		return b.CoordinateWithClock(clock.New())
+	}
+}
+
+// CoordinateWithClock creates a new coordinate interface, like
+// Coordinate, that uses clk as its time source.  Test code can pass
+// a mock clock here to control the backend's notion of time.
+//
+// The "http" and "https" implementations get their time from the
+// remote server, and so return an error here.  To test the REST
+// client with a mock clock, create a restserver router around a
+// backend built with that clock, and point the client at it.
+func (b *Backend) CoordinateWithClock(clk clock.Clock) (coordinate.Coordinate, error) {
+	switch b.Implementation {
+	case "http", "https":
+		return nil, errors.New("coordinate backend " + b.Implementation + " uses the server's clock")
 	case "memory":
-		return memory.New(), nil
+		return memory.NewWithClock(clk), nil
 	case "postgres":
-		return postgres.New(b.Address)
+		return postgres.NewWithClock(b.Address, clk)
 	default:
 		return nil, errors.New("unknown coordinate backend " + b.Implementation)
 	}
 }
 
+// NamespaceName returns the name of the namespace a tool should use.
+// If name is non-empty, for instance because it was given on the
+// command line, it is returned; otherwise the backend's default
+// namespace is.
+func (b *Backend) NamespaceName(name string) string {
+	if name != "" {
+		return name
+	}
+	return b.Namespace
+}
+
 // String renders a backend description as a string.
 func (b *Backend) String() string {
+	s := b.location()
+	if b.Namespace != "" && (b.Address == "" || isURLAddress(b.Address)) {
+		s += "#" + url.PathEscape(b.Namespace)
+	}
+	return s
+}
+
+// isURLAddress returns whether a backend address is a URL, possibly
+// with the scheme split off as the implementation name, and so whether
+// a "#" in it starts a fragment.
+func isURLAddress(address string) bool {
+	return strings.HasPrefix(address, "//") || strings.Contains(address, "://")
+}
+
+// location renders the implementation and address parts of a
+// backend description, without the default namespace.
+func (b *Backend) location() string {
 	if b.Address == "" {
 		return b.Implementation
 	}
@@ -66,16 +126,20 @@ func (b *Backend) String() string {
 }
 
 // Set parses a string into an existing backend description.  The
-// string should be of the form "implementation:address", where
-// address can be any string.  Set checks to see if the provided
-// implementation is any of the known implementations, and returns an
-// appropriate error if not.
+// string should be of the form "implementation:address#namespace",
+// where both ":address" and "#namespace" are optional.  The namespace
+// is only split off if there is no address, or if the address is a
+// URL, in which case it is the URL fragment; any other address is
+// used as is.  Set checks to see if the
+// provided implementation is any of the known implementations, and
+// returns an appropriate error if not.
 //
 // This is part of the flag.Value interface.  If Set returns a nil
 // error then Coordinate() will return successfully.  Note that
 // neither function attempts to validate the b.Address part of the
 // string or attempts to actually make a connection.
 func (b *Backend) Set(param string) (err error) {
+	b.Namespace = ""
 	parts := strings.SplitN(param, ":", 2)
 	switch len(parts) {
 	case 0:
@@ -83,9 +147,22 @@ func (b *Backend) Set(param string) (err error) {
 	case 1:
 		b.Implementation = parts[0]
 		b.Address = ""
+		if i := strings.Index(b.Implementation, "#"); i >= 0 {
+			b.Namespace = b.Implementation[i+1:]
+			b.Implementation = b.Implementation[:i]
+		}
 	case 2:
 		b.Implementation = parts[0]
 		b.Address = parts[1]
+		if isURLAddress(b.Address) {
+			// url.Parse splits the fragment at the first "#"
+			var u *url.URL
+			u, err = url.Parse(b.Address)
+			if i := strings.Index(b.Address, "#"); err == nil && i >= 0 {
+				b.Namespace = u.Fragment
+				b.Address = b.Address[:i]
+			}
+		}
 	default:
 		err = errors.New("strings.SplitN did something odd")
 	}
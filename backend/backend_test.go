@@ -0,0 +1,96 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package backend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSet checks parsing of backend strings, with and without a
+// default namespace.
+func TestSet(t *testing.T) {
+	tests := []struct {
+		Param string
+		Want  Backend
+	}{
+		{"memory", Backend{Implementation: "memory"}},
+		{"memory#test", Backend{Implementation: "memory", Namespace: "test"}},
+		{
+			"postgres://localhost/coordinate",
+			Backend{Implementation: "postgres", Address: "//localhost/coordinate"},
+		},
+		{
+			"postgres://localhost/coordinate#staging",
+			Backend{Implementation: "postgres", Address: "//localhost/coordinate", Namespace: "staging"},
+		},
+		{
+			"http://localhost:5980/",
+			Backend{Implementation: "http", Address: "//localhost:5980/"},
+		},
+		{
+			"http://localhost:5980/?x=1#a%20b",
+			Backend{Implementation: "http", Address: "//localhost:5980/?x=1", Namespace: "a b"},
+		},
+		{
+			"postgres:dbname=coordinate password=a#b",
+			Backend{Implementation: "postgres", Address: "dbname=coordinate password=a#b"},
+		},
+	}
+	for _, test := range tests {
+		var b Backend
+		if assert.NoError(t, b.Set(test.Param), test.Param) {
+			assert.Equal(t, test.Want, b, test.Param)
+			assert.Equal(t, test.Param, b.String())
+		}
+	}
+}
+
+// TestDefaultNamespace checks that the namespace from the backend
+// string is used unless another is given.
+func TestDefaultNamespace(t *testing.T) {
+	var b Backend
+	err := b.Set("memory#isolated")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "isolated", b.NamespaceName(""))
+	assert.Equal(t, "other", b.NamespaceName("other"))
+
+	coord, err := b.Coordinate()
+	if !assert.NoError(t, err) {
+		return
+	}
+	ns, err := coord.Namespace(b.NamespaceName(""))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "isolated", ns.Name())
+	}
+}
+
+// TestCoordinateWithClock checks that a backend built with a mock
+// clock reports that clock's time, and that the REST client, whose
+// time comes from its server, refuses a clock.
+func TestCoordinateWithClock(t *testing.T) {
+	clk := clock.NewMock()
+	clk.Add(90 * time.Minute)
+
+	var b Backend
+	if assert.NoError(t, b.Set("memory")) {
+		coord, err := b.CoordinateWithClock(clk)
+		if assert.NoError(t, err) {
+			now, err := coord.ServerTime()
+			if assert.NoError(t, err) {
+				assert.True(t, clk.Now().Equal(now), "server time %v, clock %v", now, clk.Now())
+			}
+		}
+	}
+
+	if assert.NoError(t, b.Set("http://localhost:5980/")) {
+		_, err := b.CoordinateWithClock(clk)
+		assert.Error(t, err)
+	}
+}
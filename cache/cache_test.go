@@ -8,6 +8,7 @@ import (
 	"github.com/diffeo/go-coordinate/coordinate"
 	"github.com/diffeo/go-coordinate/memory"
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 )
 
@@ -187,3 +188,22 @@ func TestWorkerChildren(t *testing.T) {
 		}
 	}
 }
+
+// TestWorkUnitKeyNormalization checks that with a key normalizer, a
+// work unit is cached under its normalized name, however it is
+// looked up.
+func TestWorkUnitKeyNormalization(t *testing.T) {
+	a := NewCacheAssertions(t)
+	a.Coordinate.(coordinate.KeyNormalizerSetter).SetKeyNormalizer(strings.ToLower)
+	ns := a.Namespace("")
+	spec := a.WorkSpec(ns, "spec")
+	unit := a.WorkUnit(spec, "Unit")
+	a.Equal("unit", unit.Name())
+
+	for _, name := range []string{"unit", "UNIT", "Unit"} {
+		found, err := spec.WorkUnit(name)
+		if a.NoError(err, name) {
+			a.True(unit == found, "%v is not the cached work unit", name)
+		}
+	}
+}
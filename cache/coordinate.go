@@ -63,12 +63,15 @@
 package cache
 
 import (
+	"time"
+
 	"github.com/diffeo/go-coordinate/coordinate"
 )
 
 type cache struct {
 	backend    coordinate.Coordinate
 	namespaces *lru
+	keys       coordinate.KeyNormalizer
 }
 
 // New creates a new caching backend, wrapping some other backend.
@@ -98,6 +101,77 @@ func (cache *cache) Namespaces() (map[string]coordinate.Namespace, error) {
 	return cache.backend.Namespaces()
 }
 
+func (cache *cache) ServerTime() (time.Time, error) {
+	return cache.backend.ServerTime()
+}
+
 func (cache *cache) Summarize() (coordinate.Summary, error) {
 	return cache.backend.Summarize()
 }
+
+// Supports reports the optional features of the upstream backend,
+// implementing coordinate.Capable.  The cache implements each of the
+// optional setter interfaces by passing the call upstream, so any
+// feature the upstream backend supports works through the cache too;
+// the setters do nothing if the upstream backend lacks the feature.
+func (cache *cache) Supports(feature string) bool {
+	return coordinate.Supports(cache.backend, feature)
+}
+
+func (cache *cache) SetDataHistory(limit int) {
+	if setter, ok := cache.backend.(coordinate.DataHistorySetter); ok {
+		setter.SetDataHistory(limit)
+	}
+}
+
+func (cache *cache) SetMaxNamespaces(limit int) {
+	if limiter, ok := cache.backend.(coordinate.NamespaceLimiter); ok {
+		limiter.SetMaxNamespaces(limit)
+	}
+}
+
+func (cache *cache) SetRequestInterval(interval time.Duration) {
+	if throttler, ok := cache.backend.(coordinate.RequestThrottler); ok {
+		throttler.SetRequestInterval(interval)
+	}
+}
+
+func (cache *cache) SetWorkerGracePeriod(grace time.Duration) {
+	if setter, ok := cache.backend.(coordinate.WorkerGracePeriodSetter); ok {
+		setter.SetWorkerGracePeriod(grace)
+	}
+}
+
+// SetKeyNormalizer also normalizes work unit names locally, so that
+// cached work units are found under the names the backend gives
+// them.
+func (cache *cache) SetKeyNormalizer(normalize func(string) string) {
+	if setter, ok := cache.backend.(coordinate.KeyNormalizerSetter); ok {
+		setter.SetKeyNormalizer(normalize)
+		cache.keys.Set(normalize)
+	}
+}
+
+func (cache *cache) SetAttemptArchive(keep int, age time.Duration) {
+	if archiver, ok := cache.backend.(coordinate.AttemptArchiver); ok {
+		archiver.SetAttemptArchive(keep, age)
+	}
+}
+
+func (cache *cache) SetStrictCompletion(strict bool) {
+	if setter, ok := cache.backend.(coordinate.StrictCompletionSetter); ok {
+		setter.SetStrictCompletion(strict)
+	}
+}
+
+func (cache *cache) SetMaxWorkSpecData(limit int) {
+	if limiter, ok := cache.backend.(coordinate.WorkSpecDataLimiter); ok {
+		limiter.SetMaxWorkSpecData(limit)
+	}
+}
+
+func (cache *cache) SetSchedulingGate(gate func(namespace, workSpec string) bool) {
+	if gater, ok := cache.backend.(coordinate.SchedulingGater); ok {
+		gater.SetSchedulingGate(gate)
+	}
+}
@@ -5,8 +5,10 @@ package cache_test
 
 import (
 	"github.com/diffeo/go-coordinate/cache"
+	"github.com/diffeo/go-coordinate/coordinate"
 	"github.com/diffeo/go-coordinate/coordinate/coordinatetest"
 	"github.com/diffeo/go-coordinate/memory"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"testing"
 )
@@ -26,6 +28,8 @@ func (s *Suite) TestTrivialWorkUnitFlow() {}
 func (s *Suite) SetupSuite() {
 	s.Suite.SetupSuite()
 	backend := memory.NewWithClock(s.Clock)
+	s.DataHistory = 3
+	backend.(coordinate.DataHistorySetter).SetDataHistory(s.DataHistory)
 	backend = cache.New(backend)
 	s.Coordinate = backend
 }
@@ -35,3 +39,24 @@ func (s *Suite) SetupSuite() {
 func TestCoordinate(t *testing.T) {
 	suite.Run(t, &Suite{})
 }
+
+// TestSupportsUpstream checks that the cache supports exactly the
+// optional features of the backend it wraps.
+func TestSupportsUpstream(t *testing.T) {
+	backend := memory.New()
+	c := cache.New(backend)
+	for _, feature := range []string{
+		coordinate.FeatureDataHistory,
+		coordinate.FeatureNamespaceLimit,
+		coordinate.FeatureRequestThrottle,
+		coordinate.FeatureWorkerGracePeriod,
+		coordinate.FeatureKeyNormalization,
+		coordinate.FeatureAttemptArchive,
+		coordinate.FeatureStrictCompletion,
+		coordinate.FeatureWorkSpecDataLimit,
+		coordinate.FeatureSchedulingGate,
+	} {
+		assert.Equal(t, coordinate.Supports(backend, feature),
+			coordinate.Supports(c, feature), feature)
+	}
+}
@@ -58,6 +58,13 @@ func (lru *lru) Get(name string, fetch func(string) (named, error)) (named, erro
 	if err != nil {
 		return item, err
 	}
+	// The item may know itself by a different name than the one
+	// it was fetched by; do not index it twice
+	if element, present := lru.index[item.Name()]; present {
+		element.Value = item
+		lru.evictList.MoveToBack(element)
+		return item, nil
+	}
 	lru.add(item)
 	return item, nil
 }
@@ -5,6 +5,7 @@ package cache
 
 import (
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 )
 
@@ -177,3 +178,25 @@ func TestLRURemoval(t *testing.T) {
 	a.LRUDoesNotHave("Horton")
 	a.LRUHas("Sam")
 }
+
+// TestLRURenamed tests that fetching an item that names itself
+// differently from the name it was fetched by does not index it
+// twice.
+func TestLRURenamed(t *testing.T) {
+	a := NewLRUAssertions(t, 2)
+	lower := func(name string) (named, error) {
+		return AName{IAm: strings.ToLower(name)}, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := a.LRU.Get("Marvin", lower)
+		a.NoError(err)
+	}
+	a.LRUHas("marvin")
+	a.Equal(1, a.LRU.evictList.Len())
+	a.Len(a.LRU.index, 1)
+
+	a.LRU.Remove("marvin")
+	a.LRUDoesNotHave("marvin")
+	a.Equal(0, a.LRU.evictList.Len())
+}
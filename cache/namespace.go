@@ -4,6 +4,8 @@
 package cache
 
 import (
+	"time"
+
 	"github.com/diffeo/go-coordinate/coordinate"
 )
 
@@ -144,6 +146,20 @@ func (ns *namespace) DestroyWorkSpec(name string) error {
 	return err
 }
 
+func (ns *namespace) DestroyWorkSpecs(names []string) (count int, err error) {
+	err = ns.withNamespace(func(namespace coordinate.Namespace) error {
+		var err error
+		count, err = namespace.DestroyWorkSpecs(names)
+		return err
+	})
+	if err == nil {
+		for _, name := range names {
+			ns.workSpecs.Remove(name)
+		}
+	}
+	return
+}
+
 func (ns *namespace) WorkSpecNames() (names []string, err error) {
 	err = ns.withNamespace(func(namespace coordinate.Namespace) error {
 		var err error
@@ -153,6 +169,15 @@ func (ns *namespace) WorkSpecNames() (names []string, err error) {
 	return
 }
 
+func (ns *namespace) AvailableRuntimes() (runtimes []string, err error) {
+	err = ns.withNamespace(func(namespace coordinate.Namespace) error {
+		var err error
+		runtimes, err = namespace.AvailableRuntimes()
+		return err
+	})
+	return
+}
+
 func (ns *namespace) Worker(name string) (coordinate.Worker, error) {
 	worker, err := ns.workers.Get(name, func(n string) (named, error) {
 		var upstream coordinate.Worker
@@ -187,6 +212,51 @@ func (ns *namespace) Workers() (workers map[string]coordinate.Worker, err error)
 	return
 }
 
+func (ns *namespace) DeactivateWorkers(q coordinate.WorkerQuery) (count int, err error) {
+	err = ns.withNamespace(func(namespace coordinate.Namespace) error {
+		var err error
+		count, err = namespace.DeactivateWorkers(q)
+		return err
+	})
+	return
+}
+
+func (ns *namespace) DeleteWorkers(q coordinate.WorkerQuery) (count int, err error) {
+	err = ns.withNamespace(func(namespace coordinate.Namespace) error {
+		var err error
+		count, err = namespace.DeleteWorkers(q)
+		return err
+	})
+	return
+}
+
+func (ns *namespace) ExpiringAttempts(within time.Duration) (attempts []coordinate.Attempt, err error) {
+	err = ns.withNamespace(func(namespace coordinate.Namespace) error {
+		var err error
+		attempts, err = namespace.ExpiringAttempts(within)
+		return err
+	})
+	return
+}
+
+func (ns *namespace) RebalanceAttempts(maxPending int) (count int, err error) {
+	err = ns.withNamespace(func(namespace coordinate.Namespace) error {
+		var err error
+		count, err = namespace.RebalanceAttempts(maxPending)
+		return err
+	})
+	return
+}
+
+func (ns *namespace) PipelineHealth() (issues []coordinate.PipelineIssue, err error) {
+	err = ns.withNamespace(func(namespace coordinate.Namespace) error {
+		var err error
+		issues, err = namespace.PipelineHealth()
+		return err
+	})
+	return
+}
+
 func (ns *namespace) Summarize() (summary coordinate.Summary, err error) {
 	err = ns.withNamespace(func(namespace coordinate.Namespace) error {
 		var err error
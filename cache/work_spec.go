@@ -4,7 +4,9 @@
 package cache
 
 import (
+	"context"
 	"github.com/diffeo/go-coordinate/coordinate"
+	"time"
 )
 
 type workSpec struct {
@@ -67,14 +69,21 @@ func (spec *workSpec) withWorkSpec(f func(coordinate.WorkSpec) error) error {
 	}
 }
 
+// invalidateWorkUnit removes a work unit name from the cache.  Cached
+// work units are indexed by their normalized keys, as WorkUnit.Name()
+// returns them, so the name is normalized the same way.
 func (spec *workSpec) invalidateWorkUnit(name string) {
-	spec.workUnits.Remove(name)
+	spec.workUnits.Remove(spec.namespace.coordinate.keys.Key(name))
 }
 
 func (spec *workSpec) Name() string {
 	return spec.workSpec.Name()
 }
 
+func (spec *workSpec) Namespace() coordinate.Namespace {
+	return spec.namespace
+}
+
 func (spec *workSpec) Data() (data map[string]interface{}, err error) {
 	err = spec.withWorkSpec(func(workSpec coordinate.WorkSpec) (err error) {
 		data, err = spec.workSpec.Data()
@@ -103,6 +112,38 @@ func (spec *workSpec) SetMeta(meta coordinate.WorkSpecMeta) error {
 	})
 }
 
+func (spec *workSpec) DataSize() (size int64, err error) {
+	err = spec.withWorkSpec(func(workSpec coordinate.WorkSpec) (err error) {
+		size, err = workSpec.DataSize()
+		return
+	})
+	return
+}
+
+func (spec *workSpec) PriorityHistogram() (histogram map[float64]int, err error) {
+	err = spec.withWorkSpec(func(workSpec coordinate.WorkSpec) (err error) {
+		histogram, err = workSpec.PriorityHistogram()
+		return
+	})
+	return
+}
+
+func (spec *workSpec) Successors() (names []string, err error) {
+	err = spec.withWorkSpec(func(workSpec coordinate.WorkSpec) (err error) {
+		names, err = workSpec.Successors()
+		return
+	})
+	return
+}
+
+func (spec *workSpec) IsSchedulable() (ok bool, reason string, err error) {
+	err = spec.withWorkSpec(func(workSpec coordinate.WorkSpec) (err error) {
+		ok, reason, err = workSpec.IsSchedulable()
+		return
+	})
+	return
+}
+
 func (spec *workSpec) AddWorkUnit(name string, data map[string]interface{}, meta coordinate.WorkUnitMeta) (workUnit coordinate.WorkUnit, err error) {
 	err = spec.withWorkSpec(func(workSpec coordinate.WorkSpec) (err error) {
 		workUnit, err = workSpec.AddWorkUnit(name, data, meta)
@@ -115,7 +156,21 @@ func (spec *workSpec) AddWorkUnit(name string, data map[string]interface{}, meta
 	return
 }
 
+// AddWorkUnits implements coordinate.WorkUnitBatchAdder, passing
+// the batch upstream and forgetting any cached copies of the work
+// units.
+func (spec *workSpec) AddWorkUnits(items []coordinate.AddWorkUnitItem) error {
+	for _, item := range items {
+		spec.invalidateWorkUnit(item.Key)
+	}
+	return spec.withWorkSpec(func(workSpec coordinate.WorkSpec) error {
+		_, err := coordinate.AddWorkUnitItems(workSpec, items)
+		return err
+	})
+}
+
 func (spec *workSpec) WorkUnit(name string) (workUnit coordinate.WorkUnit, err error) {
+	name = spec.namespace.coordinate.keys.Key(name)
 	unit, err := spec.workUnits.Get(name, func(n string) (unit named, err error) {
 		err = spec.withWorkSpec(func(workSpec coordinate.WorkSpec) error {
 			upstream, err := workSpec.WorkUnit(n)
@@ -140,6 +195,30 @@ func (spec *workSpec) WorkUnits(q coordinate.WorkUnitQuery) (units map[string]co
 	return
 }
 
+func (spec *workSpec) WorkUnitsPage(q coordinate.WorkUnitQuery) (units map[string]coordinate.WorkUnit, cursor string, err error) {
+	err = spec.withWorkSpec(func(workSpec coordinate.WorkSpec) (err error) {
+		units, cursor, err = workSpec.WorkUnitsPage(q)
+		return
+	})
+	return
+}
+
+func (spec *workSpec) WorkUnitStatuses(names []string) (statuses map[string]coordinate.WorkUnitStatus, err error) {
+	err = spec.withWorkSpec(func(workSpec coordinate.WorkSpec) (err error) {
+		statuses, err = workSpec.WorkUnitStatuses(names)
+		return
+	})
+	return
+}
+
+func (spec *workSpec) WorkUnitPriorities(names []string) (priorities map[string]float64, err error) {
+	err = spec.withWorkSpec(func(workSpec coordinate.WorkSpec) (err error) {
+		priorities, err = workSpec.WorkUnitPriorities(names)
+		return
+	})
+	return
+}
+
 func (spec *workSpec) CountWorkUnitStatus() (counts map[coordinate.WorkUnitStatus]int, err error) {
 	err = spec.withWorkSpec(func(workSpec coordinate.WorkSpec) (err error) {
 		counts, err = workSpec.CountWorkUnitStatus()
@@ -160,6 +239,62 @@ func (spec *workSpec) AdjustWorkUnitPriorities(q coordinate.WorkUnitQuery, p flo
 	})
 }
 
+func (spec *workSpec) ResetPriorities(q coordinate.WorkUnitQuery) error {
+	return spec.withWorkSpec(func(workSpec coordinate.WorkSpec) error {
+		return workSpec.ResetPriorities(q)
+	})
+}
+
+// WatchWork implements coordinate.WorkWatcher if the upstream work
+// spec does.
+func (spec *workSpec) WatchWork(ctx context.Context) (ch <-chan struct{}, err error) {
+	err = spec.withWorkSpec(func(workSpec coordinate.WorkSpec) (err error) {
+		watcher, ok := workSpec.(coordinate.WorkWatcher)
+		if !ok {
+			return coordinate.ErrWatchNotSupported
+		}
+		ch, err = watcher.WatchWork(ctx)
+		return
+	})
+	return
+}
+
+func (spec *workSpec) SetNextContinuous(t time.Time) error {
+	return spec.withWorkSpec(func(workSpec coordinate.WorkSpec) error {
+		return workSpec.SetNextContinuous(t)
+	})
+}
+
+func (spec *workSpec) ReorderAvailable(orderedNames []string) error {
+	return spec.withWorkSpec(func(workSpec coordinate.WorkSpec) error {
+		return workSpec.ReorderAvailable(orderedNames)
+	})
+}
+
+func (spec *workSpec) PurgeAttempts(before time.Time, statuses []coordinate.AttemptStatus) (count int, err error) {
+	err = spec.withWorkSpec(func(workSpec coordinate.WorkSpec) (err error) {
+		count, err = workSpec.PurgeAttempts(before, statuses)
+		return
+	})
+	return
+}
+
+func (spec *workSpec) CountWorkUnits(q coordinate.WorkUnitQuery) (count int, err error) {
+	err = spec.withWorkSpec(func(workSpec coordinate.WorkSpec) (err error) {
+		count, err = workSpec.CountWorkUnits(q)
+		return
+	})
+	return
+}
+
+func (spec *workSpec) Summary() (summary coordinate.WorkSpecSummary, err error) {
+	err = spec.withWorkSpec(func(workSpec coordinate.WorkSpec) (err error) {
+		summary, err = workSpec.Summary()
+		return
+	})
+	return
+}
+
 func (spec *workSpec) DeleteWorkUnits(q coordinate.WorkUnitQuery) (count int, err error) {
 	err = spec.withWorkSpec(func(workSpec coordinate.WorkSpec) (err error) {
 		count, err = workSpec.DeleteWorkUnits(q)
@@ -4,6 +4,8 @@
 package cache
 
 import (
+	"time"
+
 	"github.com/diffeo/go-coordinate/coordinate"
 )
 
@@ -113,6 +115,14 @@ func (unit *workUnit) SetPriority(priority float64) error {
 	})
 }
 
+func (unit *workUnit) CreatedAt() (createdAt time.Time, err error) {
+	err = unit.withWorkUnit(func(workUnit coordinate.WorkUnit) (err error) {
+		createdAt, err = workUnit.CreatedAt()
+		return
+	})
+	return
+}
+
 func (unit *workUnit) ActiveAttempt() (attempt coordinate.Attempt, err error) {
 	err = unit.withWorkUnit(func(workUnit coordinate.WorkUnit) (err error) {
 		attempt, err = workUnit.ActiveAttempt()
@@ -135,6 +145,20 @@ func (unit *workUnit) Attempts() (attempts []coordinate.Attempt, err error) {
 	return
 }
 
+func (unit *workUnit) QueryAttempts(q coordinate.AttemptQuery) (attempts []coordinate.Attempt, err error) {
+	err = unit.withWorkUnit(func(workUnit coordinate.WorkUnit) (err error) {
+		attempts, err = workUnit.QueryAttempts(q)
+		return
+	})
+	return
+}
+
+func (unit *workUnit) SetData(data map[string]interface{}) error {
+	return unit.withWorkUnit(func(workUnit coordinate.WorkUnit) error {
+		return workUnit.SetData(data)
+	})
+}
+
 func (unit *workUnit) NumAttempts() (int, error) {
 	n := 0
 	var err error
@@ -20,12 +20,9 @@ func newWorker(upstream coordinate.Worker, namespace *namespace) *worker {
 	}
 }
 
-// Can workers ever return ErrGone?  There's not an obvious way to
-// delete them.  The two answers, really, are, that we will eventually
-// implement worker expiry, and that deleting a whole namespace will
-// take the workers with it.  In any case, I've gotten good enough at
-// copy-and-pasting the refresh/with* code that I may as well do this
-// right.
+// Can workers ever return ErrGone?  Namespace.DeleteWorkers removes
+// them, and deleting a whole namespace will take the workers with
+// it.  Refreshing gets a new worker with the same name.
 
 // refresh re-fetches the upstream object if possible.  This should be
 // called when code strongly expects the cached object is invalid,
@@ -195,6 +192,14 @@ func (w *worker) AllAttempts() (attempts []coordinate.Attempt, err error) {
 	return
 }
 
+func (w *worker) AttemptsInWindow(start, end time.Time) (attempts []coordinate.Attempt, err error) {
+	err = w.withWorker(func(upstream coordinate.Worker) (err error) {
+		attempts, err = upstream.AttemptsInWindow(start, end)
+		return
+	})
+	return
+}
+
 func (w *worker) ChildAttempts() (attempts []coordinate.Attempt, err error) {
 	err = w.withWorker(func(upstream coordinate.Worker) (err error) {
 		attempts, err = upstream.ChildAttempts()
@@ -202,3 +207,29 @@ func (w *worker) ChildAttempts() (attempts []coordinate.Attempt, err error) {
 	})
 	return
 }
+
+func (w *worker) FinishAttempts(attempts []coordinate.Attempt, data []map[string]interface{}) error {
+	return w.withWorker(func(upstream coordinate.Worker) error {
+		return upstream.FinishAttempts(attempts, data)
+	})
+}
+
+func (w *worker) FailAttempts(attempts []coordinate.Attempt, data []map[string]interface{}) error {
+	return w.withWorker(func(upstream coordinate.Worker) error {
+		return upstream.FailAttempts(attempts, data)
+	})
+}
+
+func (w *worker) RetryAttempts(attempts []coordinate.Attempt, data []map[string]interface{}, delay time.Duration) error {
+	return w.withWorker(func(upstream coordinate.Worker) error {
+		return upstream.RetryAttempts(attempts, data, delay)
+	})
+}
+
+func (w *worker) CompleteAttempts(outcomes []coordinate.AttemptOutcome) (errs []error, err error) {
+	err = w.withWorker(func(upstream coordinate.Worker) (err error) {
+		errs, err = upstream.CompleteAttempts(outcomes)
+		return
+	})
+	return
+}
@@ -4,21 +4,57 @@
 package cborrpc
 
 import (
-	"errors"
+	"fmt"
 	"github.com/mitchellh/mapstructure"
 	"reflect"
 )
 
+// ErrWrongParamCount is returned from CreateParamList if the
+// CBOR-RPC request has a different number of parameters than the
+// method takes.
+type ErrWrongParamCount struct {
+	Expected int
+	Actual   int
+}
+
+func (err ErrWrongParamCount) Error() string {
+	return fmt.Sprintf("wrong number of parameters: expected %v, got %v",
+		err.Expected, err.Actual)
+}
+
+// ErrBadParam is returned from CreateParamList if a single parameter
+// cannot be converted to the type the method expects.
+type ErrBadParam struct {
+	// Position is the zero-based index of the parameter.
+	Position int
+
+	// Expected is the type the method takes at Position.
+	Expected reflect.Type
+
+	// Value is the parameter value from the request.
+	Value interface{}
+
+	// Err is the underlying conversion error.
+	Err error
+}
+
+func (err ErrBadParam) Error() string {
+	return fmt.Sprintf("parameter %v: expected %v, got %T: %v",
+		err.Position, err.Expected, err.Value, err.Err)
+}
+
 // CreateParamList tries to match a CBOR-RPC parameter list to a specific
 // callable's parameter list.  funcv is the reflected method to eventually
 // call, and params is the list of parameters from the CBOR-RPC request.
 // On success, the return value is a list of parameter values that can be
-// passed to funcv.Call().
+// passed to funcv.Call().  If the parameter list is the wrong length,
+// returns ErrWrongParamCount; if any parameter has the wrong type,
+// returns ErrBadParam identifying the first one.
 func CreateParamList(funcv reflect.Value, params []interface{}) ([]reflect.Value, error) {
 	funct := funcv.Type()
 	numParams := funct.NumIn()
 	if len(params) != numParams {
-		return nil, errors.New("wrong number of parameters")
+		return nil, ErrWrongParamCount{Expected: numParams, Actual: len(params)}
 	}
 	results := make([]reflect.Value, numParams)
 	for i := 0; i < numParams; i++ {
@@ -35,7 +71,12 @@ func CreateParamList(funcv reflect.Value, params []interface{}) ([]reflect.Value
 		}
 		err = decoder.Decode(params[i])
 		if err != nil {
-			return nil, err
+			return nil, ErrBadParam{
+				Position: i,
+				Expected: paramType,
+				Value:    params[i],
+				Err:      err,
+			}
 		}
 		results[i] = paramValue.Elem()
 	}
@@ -0,0 +1,61 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package cborrpc
+
+import (
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"testing"
+)
+
+func sampleMethod(name string, count int, options map[string]interface{}) {}
+
+func TestCreateParamList(t *testing.T) {
+	funcv := reflect.ValueOf(sampleMethod)
+	params, err := CreateParamList(funcv, []interface{}{
+		[]byte("spec"),
+		5,
+		map[string]interface{}{"key": "value"},
+	})
+	if assert.NoError(t, err) && assert.Len(t, params, 3) {
+		assert.Equal(t, "spec", params[0].Interface())
+		assert.Equal(t, 5, params[1].Interface())
+		assert.Equal(t, map[string]interface{}{"key": "value"}, params[2].Interface())
+	}
+}
+
+func TestCreateParamListWrongArity(t *testing.T) {
+	funcv := reflect.ValueOf(sampleMethod)
+	_, err := CreateParamList(funcv, []interface{}{"spec"})
+	assert.Equal(t, ErrWrongParamCount{Expected: 3, Actual: 1}, err)
+	if assert.Error(t, err) {
+		assert.Equal(t, "wrong number of parameters: expected 3, got 1", err.Error())
+	}
+}
+
+func TestCreateParamListWrongType(t *testing.T) {
+	funcv := reflect.ValueOf(sampleMethod)
+	_, err := CreateParamList(funcv, []interface{}{
+		"spec",
+		"five",
+		map[string]interface{}{},
+	})
+	if assert.IsType(t, ErrBadParam{}, err) {
+		bad := err.(ErrBadParam)
+		assert.Equal(t, 1, bad.Position)
+		assert.Equal(t, reflect.TypeOf(0), bad.Expected)
+		assert.Equal(t, "five", bad.Value)
+		assert.Contains(t, err.Error(), "parameter 1: expected int, got string")
+	}
+
+	_, err = CreateParamList(funcv, []interface{}{
+		"spec",
+		5,
+		[]interface{}{"not", "a", "map"},
+	})
+	if assert.IsType(t, ErrBadParam{}, err) {
+		assert.Equal(t, 2, err.(ErrBadParam).Position)
+		assert.Contains(t, err.Error(), "expected map[string]interface {}, got []interface {}")
+	}
+}
@@ -102,10 +102,8 @@ var doWork = cli.Command{
 				if err != nil || len(attempts) == 0 {
 					break
 				}
-				for _, attempt := range attempts {
-					time.Sleep(delay)
-					_ = attempt.Finish(nil)
-				}
+				time.Sleep(delay * time.Duration(len(attempts)))
+				_ = worker.FinishAttempts(attempts, nil)
 			}
 			_ = worker.Deactivate()
 		})
@@ -128,7 +126,7 @@ func main() {
 		cli.GenericFlag{
 			Name:  "backend",
 			Value: &backend,
-			Usage: "impl:[address] of Coordinate backend",
+			Usage: "impl:[address][#namespace] of Coordinate backend",
 		},
 		cli.StringFlag{
 			Name:  "namespace",
@@ -151,7 +149,7 @@ func main() {
 			return
 		}
 
-		bench.Namespace, err = bench.Coordinate.Namespace(c.String("namespace"))
+		bench.Namespace, err = bench.Coordinate.Namespace(backend.NamespaceName(c.String("namespace")))
 		if err != nil {
 			return
 		}
@@ -0,0 +1,117 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+// Coordexport copies a single work spec, with all of its work units,
+// between a Coordinate backend and a file.  Usage:
+//
+//     coordexport --backend postgres:... export spec_name spec.jsonl
+//     coordexport --backend memory import spec.jsonl
+//
+// If the file name is omitted or "-", standard output or input is
+// used.  See the github.com/diffeo/go-coordinate/export package for
+// the file format.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/diffeo/go-coordinate/backend"
+	"github.com/diffeo/go-coordinate/coordinate"
+	"github.com/diffeo/go-coordinate/export"
+	"github.com/urfave/cli"
+)
+
+var namespace coordinate.Namespace
+
+var exportSpec = cli.Command{
+	Name:      "export",
+	Usage:     "write a work spec and its work units to a file",
+	ArgsUsage: "work_spec [file]",
+	Flags: []cli.Flag{
+		cli.IntFlag{
+			Name:  "batch",
+			Value: export.DefaultBatchSize,
+			Usage: "fetch this many work units at a time",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if len(c.Args()) < 1 {
+			return errors.New("export requires a work spec name")
+		}
+		spec, err := namespace.WorkSpec(c.Args().Get(0))
+		if err != nil {
+			return err
+		}
+		var out io.Writer = os.Stdout
+		if name := c.Args().Get(1); name != "" && name != "-" {
+			f, err := os.Create(name)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+		count, err := export.WriteWorkSpec(out, spec, c.Int("batch"))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "exported %v work units from %v\n", count, spec.Name())
+		return nil
+	},
+}
+
+var importSpec = cli.Command{
+	Name:      "import",
+	Usage:     "create a work spec and its work units from a file",
+	ArgsUsage: "[file]",
+	Action: func(c *cli.Context) error {
+		var in io.Reader = os.Stdin
+		if name := c.Args().Get(0); name != "" && name != "-" {
+			f, err := os.Open(name)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			in = f
+		}
+		spec, count, err := export.ReadWorkSpec(in, namespace)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "imported %v work units into %v\n", count, spec.Name())
+		return nil
+	},
+}
+
+func main() {
+	backend := backend.Backend{Implementation: "memory"}
+	app := cli.NewApp()
+	app.Usage = "export or import a single Coordinate work spec"
+	app.Flags = []cli.Flag{
+		cli.GenericFlag{
+			Name:  "backend",
+			Value: &backend,
+			Usage: "impl:[address][#namespace] of Coordinate backend",
+		},
+		cli.StringFlag{
+			Name:  "namespace",
+			Usage: "Coordinate namespace name",
+		},
+	}
+	app.Commands = []cli.Command{
+		exportSpec,
+		importSpec,
+	}
+	app.Before = func(c *cli.Context) error {
+		coord, err := backend.Coordinate()
+		if err != nil {
+			return err
+		}
+		namespace, err = coord.Namespace(backend.NamespaceName(c.String("namespace")))
+		return err
+	}
+	app.RunAndExitOnError()
+}
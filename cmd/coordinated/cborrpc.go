@@ -12,31 +12,57 @@ import (
 	"reflect"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/benbjohnson/clock"
 	"github.com/diffeo/go-coordinate/cborrpc"
 	"github.com/diffeo/go-coordinate/coordinate"
 	"github.com/diffeo/go-coordinate/jobserver"
+	"github.com/diffeo/go-coordinate/restdata"
+	"github.com/satori/go.uuid"
 	"github.com/sirupsen/logrus"
 	"github.com/ugorji/go/codec"
 )
 
+// errCBORRPCUnauthenticated is returned from checkCBORRPCAuth when
+// HTTP requires authentication but CBOR-RPC would be served without
+// it.
+var errCBORRPCUnauthenticated = errors.New("CBOR-RPC has no authentication; disable it with -cborrpc= or allow it with -cborrpc-unauthenticated")
+
+// checkCBORRPCAuth refuses to serve CBOR-RPC on bind when the HTTP
+// interface requires authentication, since the CBOR-RPC protocol has
+// no way to carry credentials and would bypass it, unless
+// allowUnauthenticated says to anyway.
+func checkCBORRPCAuth(auth HTTPAuth, bind string, allowUnauthenticated bool) error {
+	if bind == "" || allowUnauthenticated || auth.Authenticator() == nil {
+		return nil
+	}
+	return errCBORRPCUnauthenticated
+}
+
 // ServeCBORRPC runs a CBOR-RPC server on the specified local address.
 // This serves connections forever, and probably wants to be run in a
 // goroutine.  Panics on any error in the initial setup or in accepting
-// connections.
+// connections.  Requests operate on the namespace named nsName, since
+// the Python Coordinate protocol has no notion of namespaces.  Each
+// request is assigned a tracing ID that is attached to every log line
+// about it; requests that take at least slow are logged as warnings,
+// unless slow is zero.  Failed requests are counted in the
+// coordinate_cborrpc_request_errors_total metric, by method and
+// Coordinate error type.
 func ServeCBORRPC(
 	coord coordinate.Coordinate,
+	nsName string,
 	gConfig map[string]interface{},
 	network, laddr string,
 	reqLogger *logrus.Logger,
+	slow time.Duration,
 ) {
 	var (
 		cbor      *codec.CborHandle
 		err       error
 		namespace coordinate.Namespace
 		ln        net.Listener
-		conn      net.Conn
 		jobd      *jobserver.JobServer
 	)
 
@@ -45,7 +71,7 @@ func ServeCBORRPC(
 		err = cborrpc.SetExts(cbor)
 	}
 	if err == nil {
-		namespace, err = coord.Namespace("")
+		namespace, err = coord.Namespace(nsName)
 	}
 	if err == nil {
 		jobd = &jobserver.JobServer{
@@ -55,15 +81,35 @@ func ServeCBORRPC(
 		}
 		ln, err = net.Listen(network, laddr)
 	}
-	for err == nil {
-		conn, err = ln.Accept()
-		if err == nil {
-			go handleConnection(conn, jobd, cbor, reqLogger)
-		}
+	if err == nil {
+		err = acceptCBORRPC(ln, jobd, cbor, reqLogger, slow)
 	}
 	panic(err)
 }
 
+// acceptCBORRPC accepts connections from ln and handles each in its
+// own goroutine, until accepting a connection fails.  The number of
+// open connections is tracked in the cborrpcConnections gauge.
+func acceptCBORRPC(
+	ln net.Listener,
+	jobd *jobserver.JobServer,
+	cbor *codec.CborHandle,
+	reqLogger *logrus.Logger,
+	slow time.Duration,
+) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		cborrpcConnections.Inc()
+		go func() {
+			defer cborrpcConnections.Dec()
+			handleConnection(conn, jobd, cbor, reqLogger, slow)
+		}()
+	}
+}
+
 // Convert a "snake case" name, like 'foo_bar_baz', to a "camel case" name
 // with its first letter capitalized, like 'FooBarBaz'.
 func snakeToCamel(s string) string {
@@ -74,7 +120,7 @@ func snakeToCamel(s string) string {
 	return strings.Join(words, "")
 }
 
-func handleConnection(conn net.Conn, jobd *jobserver.JobServer, cbor *codec.CborHandle, reqLogger *logrus.Logger) {
+func handleConnection(conn net.Conn, jobd *jobserver.JobServer, cbor *codec.CborHandle, reqLogger *logrus.Logger, slow time.Duration) {
 	defer conn.Close()
 
 	var reqLog, errLog *logrus.Entry
@@ -105,46 +151,65 @@ func handleConnection(conn net.Conn, jobd *jobserver.JobServer, cbor *codec.Cbor
 			errLog.WithError(err).Error("Error reading message")
 			return
 		}
+		// The CBOR-RPC ID is only unique within a connection,
+		// so make up a globally unique one for tracing.
+		traceFields := logrus.Fields{
+			"request_id": uuid.NewV4().String(),
+			"id":         request.ID,
+			"method":     request.Method,
+		}
+		traceErrLog := errLog.WithFields(traceFields)
 		if reqLog != nil {
-			reqLog.WithFields(logrus.Fields{
-				"id":     request.ID,
-				"method": request.Method,
-			}).Debug("Request")
+			reqLog.WithFields(traceFields).Debug("Request")
 		}
-		response := doRequest(jobdv, request)
+		start := time.Now()
+		response := doRequest(jobdv, request, traceErrLog)
+		elapsed := time.Since(start)
 		if reqLog != nil {
-			entry := reqLog.WithField("id", response.ID)
+			entry := reqLog.WithFields(traceFields).WithField("duration", elapsed)
 			if response.Error != "" {
 				entry = entry.WithField("error", response.Error)
 			}
 			entry.Debug("Response")
 		}
+		if slow > 0 && elapsed >= slow {
+			traceErrLog.WithField("duration", elapsed).Warn("Slow request")
+		}
 		err = encoder.Encode(response)
 		if err != nil {
-			errLog.WithError(err).Error("Error encoding response")
+			traceErrLog.WithError(err).Error("Error encoding response")
 			return
 		}
 		err = writer.Flush()
 		if err != nil {
-			errLog.WithError(err).Error("Error writing response")
+			traceErrLog.WithError(err).Error("Error writing response")
 			return
 		}
 	}
 }
 
-func doRequest(jobdv reflect.Value, request cborrpc.Request) (response cborrpc.Response) {
+// doRequest calls the job server method named in request and builds
+// its response.  Failures are counted in cborrpcErrors, including
+// missing work specs that are reported as string messages rather
+// than errors.
+func doRequest(jobdv reflect.Value, request cborrpc.Request, log *logrus.Entry) (response cborrpc.Response) {
 	response.ID = request.ID
 
+	// Label metrics with the method name, unless it is nonsense
+	// from the client
+	metricMethod := request.Method
+
 	// If we panic in the middle of this, turn it into a response
 	defer func() {
 		if oops := recover(); oops != nil {
 			buf := make([]byte, 65536)
 			runtime.Stack(buf, false)
-			logrus.WithFields(logrus.Fields{
+			log.WithFields(logrus.Fields{
 				"panic": oops,
 				"stack": string(buf),
 			}).Error("Panic in job server")
 			response.Error = fmt.Sprintf("%v", oops)
+			cborrpcErrors.WithLabelValues(metricMethod, "panic").Inc()
 		}
 	}()
 
@@ -155,6 +220,7 @@ func doRequest(jobdv reflect.Value, request cborrpc.Request) (response cborrpc.R
 	funcv := jobdv.MethodByName(method)
 	if !funcv.IsValid() {
 		err = fmt.Errorf("no such method %v", method)
+		metricMethod = "unknown"
 	}
 	if err == nil {
 		funct := funcv.Type()
@@ -208,12 +274,14 @@ func doRequest(jobdv reflect.Value, request cborrpc.Request) (response cborrpc.R
 			if nsws, ok := err.(coordinate.ErrNoSuchWorkSpec); ok {
 				err = nil
 				returns[len(returns)-1] = reflect.ValueOf(nsws.Error())
+				cborrpcErrors.WithLabelValues(metricMethod, restdata.ErrorCode(nsws)).Inc()
 			}
 		}
 	}
 
 	if err != nil {
 		response.Error = err.Error()
+		cborrpcErrors.WithLabelValues(metricMethod, restdata.ErrorCode(err)).Inc()
 	} else if len(returns) == 1 {
 		response.Result = returns[0].Interface()
 	} else {
@@ -0,0 +1,127 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package main
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/diffeo/go-coordinate/cborrpc"
+	"github.com/diffeo/go-coordinate/jobserver"
+	"github.com/diffeo/go-coordinate/memory"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/ugorji/go/codec"
+)
+
+// waitForConnections polls the active-connection gauge until it reaches
+// want, since connections are counted asynchronously.
+func waitForConnections(t *testing.T, want float64) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if testutil.ToFloat64(cborrpcConnections) == want {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, want, testutil.ToFloat64(cborrpcConnections))
+}
+
+// TestCheckCBORRPCAuth checks that CBOR-RPC is only refused when
+// HTTP requires authentication and nobody said to allow it.
+func TestCheckCBORRPCAuth(t *testing.T) {
+	secured := HTTPAuth{BearerTokens: []string{"token"}}
+	assert.NoError(t, checkCBORRPCAuth(HTTPAuth{}, ":5932", false))
+	assert.Equal(t, errCBORRPCUnauthenticated, checkCBORRPCAuth(secured, ":5932", false))
+	assert.NoError(t, checkCBORRPCAuth(secured, ":5932", true))
+	assert.NoError(t, checkCBORRPCAuth(secured, "", false))
+}
+
+// TestCBORRPCConnectionGauge checks that open CBOR-RPC connections
+// are reflected in the active-connection gauge.
+func TestCBORRPCConnectionGauge(t *testing.T) {
+	namespace, err := memory.New().Namespace("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	jobd := &jobserver.JobServer{
+		Namespace: namespace,
+		Clock:     clock.New(),
+	}
+	cbor := new(codec.CborHandle)
+	err = cborrpc.SetExts(cbor)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer ln.Close()
+	go acceptCBORRPC(ln, jobd, cbor, nil, 0)
+
+	before := testutil.ToFloat64(cborrpcConnections)
+	first, err := net.Dial("tcp", ln.Addr().String())
+	if !assert.NoError(t, err) {
+		return
+	}
+	second, err := net.Dial("tcp", ln.Addr().String())
+	if !assert.NoError(t, err) {
+		first.Close()
+		return
+	}
+	waitForConnections(t, before+2)
+
+	first.Close()
+	waitForConnections(t, before+1)
+	second.Close()
+	waitForConnections(t, before)
+}
+
+// TestCBORRPCErrorMetrics checks that failed CBOR-RPC requests are
+// counted by method and error type, including missing work specs
+// reported as string messages.
+func TestCBORRPCErrorMetrics(t *testing.T) {
+	namespace, err := memory.New().Namespace("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	jobdv := reflect.ValueOf(&jobserver.JobServer{
+		Namespace: namespace,
+		Clock:     clock.New(),
+	})
+	log := logrus.NewEntry(logrus.New())
+
+	counter := func(method, errorType string) float64 {
+		return testutil.ToFloat64(cborrpcErrors.WithLabelValues(method, errorType))
+	}
+	getBefore := counter("get_work_spec", "ErrNoSuchWorkSpec")
+	delBefore := counter("del_work_spec", "ErrNoSuchWorkSpec")
+	unknownBefore := counter("unknown", "error")
+
+	for i := 0; i < 2; i++ {
+		response := doRequest(jobdv, cborrpc.Request{
+			Method: "get_work_spec",
+			Params: []interface{}{"missing"},
+		}, log)
+		assert.NotEmpty(t, response.Error)
+	}
+	response := doRequest(jobdv, cborrpc.Request{
+		Method: "del_work_spec",
+		Params: []interface{}{"missing"},
+	}, log)
+	assert.Empty(t, response.Error)
+	response = doRequest(jobdv, cborrpc.Request{
+		Method: "no_such_method",
+	}, log)
+	assert.NotEmpty(t, response.Error)
+
+	assert.Equal(t, getBefore+2, counter("get_work_spec", "ErrNoSuchWorkSpec"))
+	assert.Equal(t, delBefore+1, counter("del_work_spec", "ErrNoSuchWorkSpec"))
+	assert.Equal(t, unknownBefore+1, counter("unknown", "error"))
+}
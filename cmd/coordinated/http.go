@@ -4,13 +4,17 @@
 package main
 
 import (
+	"io/ioutil"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/diffeo/go-coordinate/coordinate"
 	"github.com/diffeo/go-coordinate/restserver"
 	"github.com/google/go-cloud/requestlog"
 	"github.com/gorilla/mux"
+	"github.com/mitchellh/mapstructure"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/negroni"
@@ -20,12 +24,92 @@ import (
 type HTTP struct {
 	coord coordinate.Coordinate
 	laddr string
+
+	// slow is the threshold beyond which requests are logged as
+	// slow; zero disables this.
+	slow time.Duration
+
+	// cors controls which browser origins may call the REST API;
+	// the zero value disables cross-origin requests.
+	cors restserver.CORS
+
+	// timeouts bounds how long a single connection can take.
+	timeouts HTTPTimeouts
+
+	// auth holds the credentials requests must carry; the zero
+	// value allows every request.
+	auth HTTPAuth
+}
+
+// HTTPAuth holds the credentials the HTTP server accepts.  If there
+// are neither bearer tokens nor basic users, every request is
+// allowed.
+type HTTPAuth struct {
+	// BearerTokens lists the tokens accepted in an
+	// "Authorization: Bearer" header.
+	BearerTokens []string `mapstructure:"bearer_tokens"`
+
+	// BasicUsers maps user names to passwords accepted with HTTP
+	// Basic authentication.
+	BasicUsers map[string]string `mapstructure:"basic_users"`
+
+	// Exempt lists URL paths, such as "/" or "/metrics", that do
+	// not require authentication.
+	Exempt []string `mapstructure:"exempt"`
+}
+
+// Authenticator builds the authenticator for these credentials, or
+// returns nil if there are none.
+func (a HTTPAuth) Authenticator() restserver.Authenticator {
+	var auths []restserver.Authenticator
+	if len(a.BearerTokens) > 0 {
+		auths = append(auths, restserver.BearerToken(a.BearerTokens...))
+	}
+	if len(a.BasicUsers) > 0 {
+		auths = append(auths, restserver.BasicAuth(a.BasicUsers))
+	}
+	switch len(auths) {
+	case 0:
+		return nil
+	case 1:
+		return auths[0]
+	default:
+		return restserver.AnyAuthenticator(auths...)
+	}
+}
+
+// HTTPTimeouts holds the connection timeouts for the HTTP server.
+// Each corresponds to the same-named field in net/http.Server, and a
+// zero value means no timeout.
+type HTTPTimeouts struct {
+	// ReadTimeout is the maximum time to read an entire request,
+	// including its body.
+	ReadTimeout time.Duration `mapstructure:"read_timeout"`
+
+	// WriteTimeout is the maximum time from the start of handling
+	// a request to the end of writing the response.  It does not
+	// apply to the work spec event stream, which stays open for
+	// as long as the client wants it; the HTTP server's own
+	// WriteTimeout is left at zero, and this is applied to every
+	// other request instead.  It is ignored if the connection
+	// cannot set write deadlines.
+	WriteTimeout time.Duration `mapstructure:"write_timeout"`
+
+	// IdleTimeout is the maximum time to wait for the next
+	// request on a keep-alive connection.
+	IdleTimeout time.Duration `mapstructure:"idle_timeout"`
 }
 
 // Serve runs an HTTP server on the specified local address. This serves
 // connections forever, and probably wants to be run in a goroutine. Panics on
 // any error in the initial setup or in accepting connections.
 func (h *HTTP) Serve(logRequests bool, logFormat string, logger *logrus.Logger) {
+	h.server(logRequests, logFormat, logger).ListenAndServe()
+}
+
+// server builds the http.Server that Serve runs, with its handler
+// chain and configured timeouts.
+func (h *HTTP) server(logRequests bool, logFormat string, logger *logrus.Logger) *http.Server {
 	r := mux.NewRouter()
 	r.PathPrefix("/").Subrouter()
 	restserver.PopulateRouter(r, h.coord)
@@ -34,19 +118,162 @@ func (h *HTTP) Serve(logRequests bool, logFormat string, logger *logrus.Logger)
 	n := negroni.New()
 	n.Use(negroni.NewRecovery())
 
-	// Wrap the root handler in a logger if desired.
-	var handler http.Handler = r
+	// Wrap the root handler in an access logger if desired, and
+	// tag every request with a tracing ID before it gets there.
+	var handler http.Handler = restserver.RequireAuth(r, h.auth.Authenticator(), h.auth.Exempt...)
+	handler = restserver.AllowCORS(handler, h.cors)
 	if logRequests {
 		handler = logWrapper(logFormat, logger, handler)
 	}
+	handler = restserver.LogRequests(handler, logger, h.slow)
 	n.UseHandler(handler)
 
-	http.ListenAndServe(h.laddr, n)
+	var root http.Handler = n
+	if h.timeouts.WriteTimeout > 0 {
+		root = writeTimeout(n, h.timeouts.WriteTimeout, func(req *http.Request) bool {
+			var match mux.RouteMatch
+			return r.Match(req, &match) && match.Route.GetName() == eventsRoute
+		})
+	}
+
+	return &http.Server{
+		Addr:        h.laddr,
+		Handler:     root,
+		ReadTimeout: h.timeouts.ReadTimeout,
+		IdleTimeout: h.timeouts.IdleTimeout,
+	}
+}
+
+// eventsRoute is the name of the restserver route for the work spec
+// event stream.
+const eventsRoute = "workSpecEvents"
+
+// writeDeadliner is implemented by the net/http ResponseWriter since
+// Go 1.20, which go.mod requires.
+type writeDeadliner interface {
+	SetWriteDeadline(time.Time) error
+}
+
+// writeTimeout wraps an HTTP handler so that each request must
+// finish writing its response within timeout, except for requests
+// where exempt returns true.  This must wrap the ResponseWriter that
+// net/http passes in directly.
+func writeTimeout(inner http.Handler, timeout time.Duration, exempt func(*http.Request) bool) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if deadliner, ok := resp.(writeDeadliner); ok {
+			var deadline time.Time
+			if !exempt(req) {
+				deadline = time.Now().Add(timeout)
+			}
+			// A keep-alive connection keeps the deadline
+			// from its last request, so always set it
+			_ = deadliner.SetWriteDeadline(deadline)
+		}
+		inner.ServeHTTP(resp, req)
+	})
+}
+
+// corsConfig builds the CORS configuration for the HTTP server.  The
+// starting point is the "cors" section of the global YAML
+// configuration, if any; then any of the comma-separated flag values
+// that are non-empty replace the corresponding setting.
+func corsConfig(gConfig map[string]interface{}, origins, methods, headers string) (restserver.CORS, error) {
+	var cors restserver.CORS
+	err := decodeConfigSection(gConfig, "cors", &cors)
+	if err != nil {
+		return cors, err
+	}
+	if origins != "" {
+		cors.AllowedOrigins = strings.Split(origins, ",")
+	}
+	if methods != "" {
+		cors.AllowedMethods = strings.Split(methods, ",")
+	}
+	if headers != "" {
+		cors.AllowedHeaders = strings.Split(headers, ",")
+	}
+	return cors, nil
+}
+
+// httpTimeouts builds the HTTP server timeouts.  These start from the
+// "http" section of the global YAML configuration, if any, where
+// durations are strings like "30s"; then any of the flag values that
+// are non-zero replace the corresponding setting.
+func httpTimeouts(gConfig map[string]interface{}, read, write, idle time.Duration) (HTTPTimeouts, error) {
+	var timeouts HTTPTimeouts
+	err := decodeConfigSection(gConfig, "http", &timeouts)
+	if err != nil {
+		return timeouts, err
+	}
+	if read != 0 {
+		timeouts.ReadTimeout = read
+	}
+	if write != 0 {
+		timeouts.WriteTimeout = write
+	}
+	if idle != 0 {
+		timeouts.IdleTimeout = idle
+	}
+	return timeouts, nil
+}
+
+// httpAuth builds the HTTP server credentials.  These start from the
+// "auth" section of the global YAML configuration, if any; then, if
+// tokenFile is non-empty, each non-blank line of that file is added
+// as a bearer token.
+func httpAuth(gConfig map[string]interface{}, tokenFile string) (HTTPAuth, error) {
+	var auth HTTPAuth
+	err := decodeConfigSection(gConfig, "auth", &auth)
+	if err != nil || tokenFile == "" {
+		return auth, err
+	}
+	content, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return auth, err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if token := strings.TrimSpace(line); token != "" {
+			auth.BearerTokens = append(auth.BearerTokens, token)
+		}
+	}
+	return auth, nil
+}
+
+// decodeConfigSection decodes the named top-level section of the
+// global YAML configuration into result, if that section is present.
+func decodeConfigSection(gConfig map[string]interface{}, name string, result interface{}) error {
+	section, present := gConfig[name]
+	if !present {
+		return nil
+	}
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.StringToTimeDurationHookFunc(),
+		Result:     result,
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(section)
+}
+
+// flushWriter adds http.Flusher back to a ResponseWriter that hides
+// it, flushing the original writer underneath.
+type flushWriter struct {
+	http.ResponseWriter
+	flusher http.Flusher
+}
+
+func (w flushWriter) Flush() {
+	w.flusher.Flush()
 }
 
 // logWrapper creates a wrapping logger for the given handler. It is setup this
 // way rather than conforming to the negroni paradigm because the API fo the
 // requestlog package, which this uses, is not directly compatible.
+//
+// The requestlog writer does not implement http.Flusher, so if the
+// original writer does, the inner handler gets a writer that flushes
+// it; otherwise streaming responses would never be sent.
 func logWrapper(logFormat string, logger *logrus.Logger, inner http.Handler) http.Handler {
 	var reqLog requestlog.Logger
 	// See the following documentation for more information on formats:
@@ -68,5 +295,13 @@ func logWrapper(logFormat string, logger *logrus.Logger, inner http.Handler) htt
 		logger.WithField("format", logFormat).Fatal("unrecognized log format")
 	}
 
-	return requestlog.NewHandler(reqLog, inner)
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		flusher, canFlush := resp.(http.Flusher)
+		requestlog.NewHandler(reqLog, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if canFlush {
+				w = flushWriter{ResponseWriter: w, flusher: flusher}
+			}
+			inner.ServeHTTP(w, r)
+		})).ServeHTTP(resp, req)
+	})
 }
@@ -0,0 +1,205 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package main
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/diffeo/go-coordinate/coordinate"
+	"github.com/diffeo/go-coordinate/memory"
+	"github.com/diffeo/go-coordinate/restdata"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHTTPTimeoutsApplied checks that configured timeouts end up on
+// the http.Server, except for the write timeout, which is applied per
+// request.
+func TestHTTPTimeoutsApplied(t *testing.T) {
+	h := HTTP{
+		coord: memory.New(),
+		laddr: ":0",
+		timeouts: HTTPTimeouts{
+			ReadTimeout:  10 * time.Second,
+			WriteTimeout: 20 * time.Second,
+			IdleTimeout:  30 * time.Second,
+		},
+	}
+	server := h.server(false, "ncsa", logrus.New())
+	assert.Equal(t, ":0", server.Addr)
+	assert.Equal(t, 10*time.Second, server.ReadTimeout)
+	assert.Equal(t, time.Duration(0), server.WriteTimeout)
+	assert.Equal(t, 30*time.Second, server.IdleTimeout)
+}
+
+// TestHTTPWriteTimeoutStreams checks that the work spec event stream
+// outlives the write timeout, while ordinary requests still work.
+func TestHTTPWriteTimeoutStreams(t *testing.T) {
+	coord := memory.New()
+	namespace, err := coord.Namespace("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	spec, err := namespace.SetWorkSpec(map[string]interface{}{"name": "spec"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	h := HTTP{
+		coord:    coord,
+		laddr:    ":0",
+		timeouts: HTTPTimeouts{WriteTimeout: 50 * time.Millisecond},
+	}
+	server := httptest.NewUnstartedServer(nil)
+	server.Config = h.server(false, "ncsa", logrus.New())
+	server.Start()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/namespace/-/work_spec/spec/events")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	time.Sleep(200 * time.Millisecond)
+	_, err = spec.AddWorkUnit("unit", map[string]interface{}{}, coordinate.WorkUnitMeta{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if assert.NoError(t, err) {
+		assert.Equal(t, "event: available\n", line)
+	}
+
+	other, err := http.Get(server.URL + "/")
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, other.StatusCode)
+		other.Body.Close()
+	}
+}
+
+// TestHTTPLogRequestsStreams checks that the work spec event stream
+// still delivers events, and that the request ID still reaches the
+// client, when access logging is on.
+func TestHTTPLogRequestsStreams(t *testing.T) {
+	coord := memory.New()
+	namespace, err := coord.Namespace("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	spec, err := namespace.SetWorkSpec(map[string]interface{}{"name": "spec"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	h := HTTP{coord: coord, laddr: ":0"}
+	server := httptest.NewServer(h.server(true, "ncsa", logrus.New()).Handler)
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/namespace/-/work_spec/spec/events", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	req.Header.Set(restdata.RequestIDHeader, "streaming")
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "streaming", resp.Header.Get(restdata.RequestIDHeader))
+
+	_, err = spec.AddWorkUnit("unit", map[string]interface{}{}, coordinate.WorkUnitMeta{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if assert.NoError(t, err) {
+		assert.Equal(t, "event: available\n", line)
+	}
+}
+
+// TestHTTPTimeoutsConfig checks that timeouts are read from the YAML
+// configuration, and that flags override them.
+func TestHTTPTimeoutsConfig(t *testing.T) {
+	gConfig := map[string]interface{}{
+		"http": map[interface{}]interface{}{
+			"read_timeout":  "5s",
+			"write_timeout": "1m",
+		},
+	}
+	timeouts, err := httpTimeouts(gConfig, 0, 2*time.Minute, 0)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 5*time.Second, timeouts.ReadTimeout)
+		assert.Equal(t, 2*time.Minute, timeouts.WriteTimeout)
+		assert.Equal(t, time.Duration(0), timeouts.IdleTimeout)
+	}
+
+	timeouts, err = httpTimeouts(nil, 0, 0, 0)
+	if assert.NoError(t, err) {
+		assert.Equal(t, HTTPTimeouts{}, timeouts)
+	}
+}
+
+// TestHTTPAuthConfig checks that credentials are read from the YAML
+// configuration and the token file, and that the server requires
+// them.
+func TestHTTPAuthConfig(t *testing.T) {
+	auth, err := httpAuth(nil, "")
+	if assert.NoError(t, err) {
+		assert.Nil(t, auth.Authenticator())
+	}
+
+	tokenFile, err := ioutil.TempFile("", "coordinated-tokens")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.Remove(tokenFile.Name())
+	_, err = tokenFile.WriteString("from-file\n\n  other  \n")
+	assert.NoError(t, err)
+	assert.NoError(t, tokenFile.Close())
+
+	gConfig := map[string]interface{}{
+		"auth": map[interface{}]interface{}{
+			"bearer_tokens": []interface{}{"from-config"},
+			"basic_users":   map[interface{}]interface{}{"user": "password"},
+			"exempt":        []interface{}{"/"},
+		},
+	}
+	auth, err = httpAuth(gConfig, tokenFile.Name())
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"from-config", "from-file", "other"}, auth.BearerTokens)
+	assert.Equal(t, map[string]string{"user": "password"}, auth.BasicUsers)
+	assert.Equal(t, []string{"/"}, auth.Exempt)
+
+	h := HTTP{coord: memory.New(), laddr: ":0", auth: auth}
+	handler := h.server(false, "ncsa", logrus.New()).Handler
+	for _, test := range []struct {
+		Path          string
+		Authorization string
+		Status        int
+	}{
+		{"/", "", http.StatusOK},
+		{"/namespace", "", http.StatusUnauthorized},
+		{"/namespace", "Bearer from-file", http.StatusOK},
+		{"/namespace", "Bearer from-config", http.StatusOK},
+		{"/namespace", "Bearer nope", http.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest(http.MethodGet, test.Path, nil)
+		req.Header.Set("Accept", "application/json")
+		if test.Authorization != "" {
+			req.Header.Set("Authorization", test.Authorization)
+		}
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		assert.Equal(t, test.Status, resp.Code, "%+v", test)
+	}
+}
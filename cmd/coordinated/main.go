@@ -14,10 +14,14 @@ import (
 	"flag"
 	"io/ioutil"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/diffeo/go-coordinate/backend"
 	"github.com/diffeo/go-coordinate/cache"
+	"github.com/diffeo/go-coordinate/coordinate"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
 )
@@ -26,16 +30,38 @@ func main() {
 	var err error
 
 	cborRPCBind := flag.String("cborrpc", ":5932",
-		"[ip]:port for CBOR-RPC interface")
+		"[ip]:port for CBOR-RPC interface (empty to disable)")
+	cborRPCUnauthenticated := flag.Bool("cborrpc-unauthenticated", false,
+		"serve CBOR-RPC, which has no authentication, even if HTTP requires it")
 	httpBind := flag.String("http", ":5980",
 		"[ip]:port for HTTP REST interface")
 	backend := backend.Backend{Implementation: "memory", Address: ""}
-	flag.Var(&backend, "backend", "impl[:address] of the storage backend")
+	flag.Var(&backend, "backend", "impl[:address][#namespace] of the storage backend")
 	config := flag.String("config", "", "global configuration YAML file")
 	logRequests := flag.Bool("log-requests", false, "log all requests")
 	logMetrics := flag.Bool("log-metrics", false, "log metrics")
 	logFormat := flag.String("log-format", "ncsa", "request log format [ncsa stackdriver]")
 	metricPeriod := flag.String("metric-period", "2m", "time period between each metric update")
+	slowRequest := flag.Duration("slow-request", 1*time.Second, "log requests taking longer than this (0 to disable)")
+	corsOrigins := flag.String("cors-origins", "", "comma-separated origins allowed to make cross-origin HTTP requests (\"*\" for any)")
+	corsMethods := flag.String("cors-methods", "", "comma-separated HTTP methods allowed in cross-origin requests")
+	corsHeaders := flag.String("cors-headers", "", "comma-separated request headers allowed in cross-origin requests")
+	httpReadTimeout := flag.Duration("http-read-timeout", 0, "maximum time to read an HTTP request (0 for no limit)")
+	httpWriteTimeout := flag.Duration("http-write-timeout", 0, "maximum time to write an HTTP response, except event streams (0 for no limit)")
+	httpIdleTimeout := flag.Duration("http-idle-timeout", 0, "maximum time to keep an idle HTTP connection open (0 for no limit)")
+	httpTokenFile := flag.String("http-token-file", "", "file of bearer tokens, one per line, required on HTTP requests")
+	dataHistory := flag.Int("data-history", 0, "number of attempt data snapshots to keep from renewals (0 to disable)")
+	maxNamespaces := flag.Int("max-namespaces", 0, "maximum number of namespaces to create (0 for unlimited)")
+	maxWorkSpecData := flag.Int("max-work-spec-data", 0, "maximum encoded size of a work spec's data in bytes (0 for unlimited)")
+	requestInterval := flag.Duration("request-interval", 0, "minimum time between attempt requests from one worker (0 for unlimited)")
+	workerGrace := flag.Duration("worker-grace", 0, "time after a worker's expiration before it is considered dead")
+	strictCompletion := flag.Bool("strict-completion", false, "expire attempts that are no longer active when workers complete them, returning an error")
+	maxDBConnections := flag.Int("max-db-connections", 0, "maximum number of open database connections (0 for unlimited)")
+	snapshotFile := flag.String("snapshot", "", "file to restore the memory backend from at startup and save it to periodically")
+	snapshotInterval := flag.Duration("snapshot-interval", 1*time.Minute, "time between snapshots of the memory backend")
+	statsdAddress := flag.String("statsd", "", "host:port of a StatsD server to push metrics to every metric period")
+	statsdFormat := flag.String("statsd-format", "", "StatsD line format [statsd dogstatsd]")
+	statsdPrefix := flag.String("statsd-prefix", "", "prefix for metric names sent to StatsD")
 	flag.Parse()
 
 	var gConfig map[string]interface{}
@@ -49,14 +75,145 @@ func main() {
 		}
 	}
 
-	coordinate, err := backend.Coordinate()
+	cors, err := corsConfig(gConfig, *corsOrigins, *corsMethods, *corsHeaders)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err": err,
+		}).Fatal("Invalid CORS configuration")
+		return
+	}
+
+	timeouts, err := httpTimeouts(gConfig, *httpReadTimeout, *httpWriteTimeout, *httpIdleTimeout)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err": err,
+		}).Fatal("Invalid HTTP timeout configuration")
+		return
+	}
+
+	auth, err := httpAuth(gConfig, *httpTokenFile)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err": err,
+		}).Fatal("Invalid HTTP authentication configuration")
+		return
+	}
+	err = checkCBORRPCAuth(auth, *cborRPCBind, *cborRPCUnauthenticated)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err": err,
+		}).Fatal("Invalid CBOR-RPC configuration")
+		return
+	}
+
+	statsd, err := statsdConfig(gConfig, *statsdAddress, *statsdFormat, *statsdPrefix)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err": err,
+		}).Fatal("Invalid StatsD configuration")
+		return
+	}
+
+	coord, err := backend.Coordinate()
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"err": err,
 		}).Fatal("Could not create Coordinate backend")
 		return
 	}
-	coordinate = cache.New(coordinate)
+	var snapshots snapshotter
+	if *snapshotFile != "" {
+		if backend.Implementation != "memory" {
+			logrus.WithFields(logrus.Fields{
+				"backend": backend.Implementation,
+			}).Fatal("Only the memory backend can be saved to a snapshot")
+			return
+		}
+		loaded, err := loadSnapshot(*snapshotFile)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"err":  err,
+				"file": *snapshotFile,
+			}).Fatal("Could not load snapshot")
+			return
+		}
+		if loaded != nil {
+			coord = loaded
+		}
+		snapshots = coord.(snapshotter)
+	}
+	if *dataHistory > 0 {
+		setter, ok := coord.(coordinate.DataHistorySetter)
+		if !ok {
+			logrus.WithFields(logrus.Fields{
+				"backend": backend.Implementation,
+			}).Fatal("Backend does not support attempt data history")
+			return
+		}
+		setter.SetDataHistory(*dataHistory)
+	}
+	if *maxNamespaces > 0 {
+		limiter, ok := coord.(coordinate.NamespaceLimiter)
+		if !ok {
+			logrus.WithFields(logrus.Fields{
+				"backend": backend.Implementation,
+			}).Fatal("Backend does not support a namespace limit")
+			return
+		}
+		limiter.SetMaxNamespaces(*maxNamespaces)
+	}
+	if *maxWorkSpecData > 0 {
+		limiter, ok := coord.(coordinate.WorkSpecDataLimiter)
+		if !ok {
+			logrus.WithFields(logrus.Fields{
+				"backend": backend.Implementation,
+			}).Fatal("Backend does not support a work spec data limit")
+			return
+		}
+		limiter.SetMaxWorkSpecData(*maxWorkSpecData)
+	}
+	if *requestInterval > 0 {
+		throttler, ok := coord.(coordinate.RequestThrottler)
+		if !ok {
+			logrus.WithFields(logrus.Fields{
+				"backend": backend.Implementation,
+			}).Fatal("Backend does not support request throttling")
+			return
+		}
+		throttler.SetRequestInterval(*requestInterval)
+	}
+	if *workerGrace > 0 {
+		setter, ok := coord.(coordinate.WorkerGracePeriodSetter)
+		if !ok {
+			logrus.WithFields(logrus.Fields{
+				"backend": backend.Implementation,
+			}).Fatal("Backend does not support a worker grace period")
+			return
+		}
+		setter.SetWorkerGracePeriod(*workerGrace)
+	}
+	if *strictCompletion {
+		setter, ok := coord.(coordinate.StrictCompletionSetter)
+		if !ok {
+			logrus.WithFields(logrus.Fields{
+				"backend": backend.Implementation,
+			}).Fatal("Backend does not support strict completion")
+			return
+		}
+		setter.SetStrictCompletion(true)
+	}
+	if *maxDBConnections > 0 {
+		limiter, ok := coord.(coordinate.ConnectionLimiter)
+		if !ok {
+			logrus.WithFields(logrus.Fields{
+				"backend": backend.Implementation,
+			}).Fatal("Backend does not support a database connection limit")
+			return
+		}
+		limiter.SetMaxConnections(*maxDBConnections)
+	}
+	pool, _ := coord.(dbStatser)
+	coord = cache.New(coord)
 
 	logrus.SetLevel(logrus.DebugLevel)
 	logrus.SetOutput(ioutil.Discard) // default unless log flags are passed
@@ -76,15 +233,52 @@ func main() {
 		return
 	}
 
-	go ServeCBORRPC(coordinate, gConfig, "tcp", *cborRPCBind, reqLogger)
+	if *cborRPCBind != "" {
+		go ServeCBORRPC(coord, backend.Namespace, gConfig, "tcp", *cborRPCBind, reqLogger, *slowRequest)
+	}
 	http := HTTP{
-		coord: coordinate,
-		laddr: *httpBind,
+		coord:    coord,
+		laddr:    *httpBind,
+		slow:     *slowRequest,
+		cors:     cors,
+		timeouts: timeouts,
+		auth:     auth,
 	}
 	go http.Serve(*logRequests, *logFormat, reqLogger)
-	go Observe(context.Background(), coordinate, period, metricsLogger)
+	go Observe(context.Background(), coord, pool, period, metricsLogger)
+	if statsd.Address != "" {
+		sink, err := NewStatsDSink(statsd, prometheus.DefaultGatherer)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"err":     err,
+				"address": statsd.Address,
+			}).Fatal("Could not connect to StatsD")
+			return
+		}
+		go PushStatsD(context.Background(), sink, period, metricsLogger)
+	}
 
-	select {}
+	// Snapshot errors always go to stderr, since they mean
+	// losing work on restart
+	snapshotLogger := logrus.New()
+	if snapshots != nil {
+		go SaveSnapshots(context.Background(), snapshots, *snapshotFile, *snapshotInterval, snapshotLogger)
+	}
+
+	// Run until interrupted, then exit cleanly; with -snapshot,
+	// save once more first, so it loses nothing
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+	if snapshots != nil {
+		err = saveSnapshot(snapshots, *snapshotFile)
+		if err != nil {
+			snapshotLogger.WithFields(logrus.Fields{
+				"err":  err,
+				"file": *snapshotFile,
+			}).Fatal("Could not save snapshot")
+		}
+	}
 }
 
 func loadConfigYaml(filename string) (map[string]interface{}, error) {
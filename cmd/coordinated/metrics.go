@@ -5,7 +5,9 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"math"
+	"runtime"
 	"time"
 
 	"github.com/diffeo/go-coordinate/coordinate"
@@ -33,19 +35,107 @@ var (
 			"work_spec",
 			"status",
 		})
+
+	cborrpcConnections = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "coordinate",
+			Subsystem: "cborrpc",
+			Name:      "active_connections",
+			Help:      "Number of open CBOR-RPC client connections",
+		})
+
+	cborrpcErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "coordinate",
+			Subsystem: "cborrpc",
+			Name:      "request_errors_total",
+			Help:      "Number of CBOR-RPC requests that failed, by method and error type",
+		},
+		[]string{
+			"method",
+			"error",
+		})
+
+	goroutines = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "coordinate",
+			Name:      "goroutines",
+			Help:      "Number of goroutines in coordinated",
+		})
+
+	dbConnections = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "coordinate",
+			Name:      "db_connections",
+			Help:      "Number of database connections held by the backend",
+		},
+		[]string{
+			"state",
+		})
 )
 
 func init() {
 	prometheus.MustRegister(summarySeconds)
 	prometheus.MustRegister(workUnitsNumber)
+	prometheus.MustRegister(cborrpcConnections)
+	prometheus.MustRegister(cborrpcErrors)
+	prometheus.MustRegister(goroutines)
+	prometheus.MustRegister(dbConnections)
+}
+
+// dbStatser is implemented by backends that hold a database
+// connection pool, such as the PostgreSQL backend.
+type dbStatser interface {
+	DBStats() sql.DBStats
+}
+
+// observeResources records coordinated's own resource usage.  pool
+// may be nil if the backend has no database connections.
+func observeResources(pool dbStatser) {
+	goroutines.Set(float64(runtime.NumGoroutine()))
+	if pool != nil {
+		stats := pool.DBStats()
+		dbConnections.WithLabelValues("open").Set(float64(stats.OpenConnections))
+		dbConnections.WithLabelValues("in_use").Set(float64(stats.InUse))
+		dbConnections.WithLabelValues("idle").Set(float64(stats.Idle))
+	}
+}
+
+// observeSummary calls Summarize() on coord once, and records the
+// work unit counts.  Each work spec's counts are labeled with its
+// namespace, so work specs with the same name in different
+// namespaces are reported separately.
+func observeSummary(coord coordinate.Coordinate, log *logrus.Logger) {
+	t0 := time.Now()
+	summary, err := coord.Summarize()
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	workUnitsNumber.Observe(time.Since(t0).Seconds())
+	for _, record := range summary {
+		status, err := record.Status.MarshalText()
+		if err != nil {
+			log.Error(err)
+			break
+		}
+		summarySeconds.With(prometheus.Labels{
+			"namespace": record.Namespace,
+			"work_spec": record.WorkSpec,
+			"status":    string(status),
+		}).Set(float64(record.Count))
+	}
 }
 
 // Observe repeatedly calls Summarize() on coordinate in an infinite loop, and
 // observes each SummaryRecord's fields on a prometheus GaugeVec, and the
-// resultant time duration on a prometheus Histogram.
+// resultant time duration on a prometheus Histogram.  Each pass also
+// records the process's goroutine count and, if pool is not nil, its
+// database connection counts.
 func Observe(
 	ctx context.Context,
 	coord coordinate.Coordinate,
+	pool dbStatser,
 	period time.Duration,
 	log *logrus.Logger,
 ) {
@@ -54,25 +144,8 @@ func Observe(
 		case <-ctx.Done():
 			return
 		case <-time.After(period):
-			t0 := time.Now()
-			summary, err := coord.Summarize()
-			if err != nil {
-				log.Error(err)
-				break
-			}
-			workUnitsNumber.Observe(time.Since(t0).Seconds())
-			for _, record := range summary {
-				status, err := record.Status.MarshalText()
-				if err != nil {
-					log.Error(err)
-					break
-				}
-				summarySeconds.With(prometheus.Labels{
-					"namespace": record.Namespace,
-					"work_spec": record.WorkSpec,
-					"status":    string(status),
-				}).Set(float64(record.Count))
-			}
+			observeResources(pool)
+			observeSummary(coord, log)
 		}
 	}
 }
@@ -0,0 +1,42 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/diffeo/go-coordinate/coordinate"
+	"github.com/diffeo/go-coordinate/memory"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSummaryNamespaceLabel checks that work specs with the same name
+// in two namespaces produce separate work unit gauges.
+func TestSummaryNamespaceLabel(t *testing.T) {
+	coord := memory.New()
+	for i, nsName := range []string{"tenant1", "tenant2"} {
+		ns, err := coord.Namespace(nsName)
+		if !assert.NoError(t, err) {
+			return
+		}
+		spec, err := ns.SetWorkSpec(map[string]interface{}{
+			"name": "spec",
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+		for j := 0; j <= i; j++ {
+			_, err = spec.AddWorkUnit(fmt.Sprintf("unit%d", j), map[string]interface{}{}, coordinate.WorkUnitMeta{})
+			assert.NoError(t, err)
+		}
+	}
+
+	observeSummary(coord, logrus.New())
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(summarySeconds.WithLabelValues("tenant1", "spec", "available")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(summarySeconds.WithLabelValues("tenant2", "spec", "available")))
+}
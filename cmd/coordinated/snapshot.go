@@ -0,0 +1,94 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package main
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/diffeo/go-coordinate/coordinate"
+	"github.com/diffeo/go-coordinate/memory"
+	"github.com/sirupsen/logrus"
+)
+
+// snapshotter is implemented by backends that can write out their
+// entire state, namely the memory backend.
+type snapshotter interface {
+	Snapshot(w io.Writer) error
+}
+
+// loadSnapshot creates a memory backend from the snapshot in
+// filename.  If the file does not exist, as on the very first run,
+// returns nil and no error.
+func loadSnapshot(filename string) (coordinate.Coordinate, error) {
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	// A snapshot with a bad attempt reference should not keep
+	// the server from starting; log it and carry on without it
+	return memory.LoadSkipping(f, func(err error) {
+		logrus.WithFields(logrus.Fields{
+			"err":  err,
+			"file": filename,
+		}).Warn("Skipping bad attempt reference in snapshot")
+	})
+}
+
+// saveSnapshot writes a snapshot of coord to filename.  It writes to
+// a temporary file in the same directory and renames it into place,
+// so a crash partway through leaves the previous snapshot intact.
+func saveSnapshot(coord snapshotter, filename string) error {
+	f, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+".tmp")
+	if err != nil {
+		return err
+	}
+	err = coord.Snapshot(f)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filename)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// SaveSnapshots writes a snapshot of coord to filename every period
+// until ctx is done, logging any errors to log.
+func SaveSnapshots(
+	ctx context.Context,
+	coord snapshotter,
+	filename string,
+	period time.Duration,
+	log *logrus.Logger,
+) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(period):
+			err := saveSnapshot(coord, filename)
+			if err != nil {
+				log.WithFields(logrus.Fields{
+					"err":  err,
+					"file": filename,
+				}).Error("Could not save snapshot")
+			}
+		}
+	}
+}
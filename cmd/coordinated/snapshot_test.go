@@ -0,0 +1,73 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/diffeo/go-coordinate/coordinate"
+	"github.com/diffeo/go-coordinate/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSnapshotFile checks that a snapshot saved to a file loads back
+// with the same work, and that nothing else is left in the directory.
+func TestSnapshotFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coordinated")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "coordinate.snap")
+
+	// Before the first save there is nothing to load
+	loaded, err := loadSnapshot(filename)
+	if assert.NoError(t, err) {
+		assert.Nil(t, loaded)
+	}
+
+	coord := memory.New()
+	ns, err := coord.Namespace("ns")
+	if !assert.NoError(t, err) {
+		return
+	}
+	spec, err := ns.SetWorkSpec(map[string]interface{}{"name": "spec"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = spec.AddWorkUnit("unit", map[string]interface{}{}, coordinate.WorkUnitMeta{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Saving twice replaces the first snapshot
+	for i := 0; i < 2; i++ {
+		err = saveSnapshot(coord.(snapshotter), filename)
+		if !assert.NoError(t, err) {
+			return
+		}
+	}
+	files, err := ioutil.ReadDir(dir)
+	if assert.NoError(t, err) && assert.Len(t, files, 1) {
+		assert.Equal(t, "coordinate.snap", files[0].Name())
+	}
+
+	loaded, err = loadSnapshot(filename)
+	if !assert.NoError(t, err) || !assert.NotNil(t, loaded) {
+		return
+	}
+	ns, err = loaded.Namespace("ns")
+	if !assert.NoError(t, err) {
+		return
+	}
+	spec, err = ns.WorkSpec("spec")
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = spec.WorkUnit("unit")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,264 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+)
+
+// StatsD line formats.
+const (
+	// statsdPlain encodes labels into the metric name, as
+	// "name.value1.value2", since plain StatsD has no tags.
+	statsdPlain = "statsd"
+
+	// statsdDog encodes labels as DogStatsD tags, as
+	// "name:1|g|#label1:value1,label2:value2".
+	statsdDog = "dogstatsd"
+)
+
+// statsdPacketSize is the largest datagram sent to the StatsD
+// server, small enough to avoid fragmentation on most networks.
+const statsdPacketSize = 1432
+
+// StatsDConfig describes a StatsD server to push metrics to, as an
+// alternative to Prometheus scraping the /metrics endpoint.
+type StatsDConfig struct {
+	// Address is the host:port of the server's UDP listener.  If
+	// empty, nothing is pushed.
+	Address string `mapstructure:"address"`
+
+	// Format is "statsd" (the default) or "dogstatsd".
+	Format string `mapstructure:"format"`
+
+	// Prefix is prepended to every metric name.
+	Prefix string `mapstructure:"prefix"`
+}
+
+// statsdConfig builds the StatsD settings.  These start from the
+// "statsd" section of the global YAML configuration, if any; then any
+// of the flag values that are non-empty replace the corresponding
+// setting.
+func statsdConfig(gConfig map[string]interface{}, address, format, prefix string) (StatsDConfig, error) {
+	var config StatsDConfig
+	err := decodeConfigSection(gConfig, "statsd", &config)
+	if err != nil {
+		return config, err
+	}
+	if address != "" {
+		config.Address = address
+	}
+	if format != "" {
+		config.Format = format
+	}
+	if prefix != "" {
+		config.Prefix = prefix
+	}
+	switch config.Format {
+	case "":
+		config.Format = statsdPlain
+	case statsdPlain, statsdDog:
+	default:
+		return config, fmt.Errorf("invalid StatsD format %q", config.Format)
+	}
+	return config, nil
+}
+
+// StatsDSink sends the metrics in a Prometheus registry to a StatsD
+// server.  Gauges are sent as gauges.  Counters are sent as StatsD
+// counts of how much they grew since the previous push.  Histograms
+// and summaries are sent as counts of their "_count" and "_sum"
+// series, the same way.
+type StatsDSink struct {
+	config   StatsDConfig
+	gatherer prometheus.Gatherer
+	conn     net.Conn
+
+	// counters holds the value of each counter series at the
+	// previous push, keyed by its name and labels
+	counters map[string]float64
+}
+
+// NewStatsDSink creates a sink that sends the metrics in gatherer
+// to the server in config.
+func NewStatsDSink(config StatsDConfig, gatherer prometheus.Gatherer) (*StatsDSink, error) {
+	conn, err := net.Dial("udp", config.Address)
+	if err != nil {
+		return nil, err
+	}
+	return &StatsDSink{
+		config:   config,
+		gatherer: gatherer,
+		conn:     conn,
+		counters: make(map[string]float64),
+	}, nil
+}
+
+// Close closes the sink's connection.
+func (s *StatsDSink) Close() error {
+	return s.conn.Close()
+}
+
+// Push gathers the current metrics and sends them to the server.
+func (s *StatsDSink) Push() error {
+	lines, err := s.lines()
+	if err != nil {
+		return err
+	}
+	var packet []byte
+	for _, line := range lines {
+		if len(packet) > 0 && len(packet)+1+len(line) > statsdPacketSize {
+			if _, err = s.conn.Write(packet); err != nil {
+				return err
+			}
+			packet = packet[:0]
+		}
+		if len(packet) > 0 {
+			packet = append(packet, '\n')
+		}
+		packet = append(packet, line...)
+	}
+	if len(packet) > 0 {
+		_, err = s.conn.Write(packet)
+	}
+	return err
+}
+
+// lines gathers the current metrics and formats them as StatsD
+// lines.
+func (s *StatsDSink) lines() ([]string, error) {
+	families, err := s.gatherer.Gather()
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, family := range families {
+		name := family.GetName()
+		for _, metric := range family.GetMetric() {
+			labels := metric.GetLabel()
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				lines = s.appendCount(lines, name, labels, metric.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				lines = s.appendLine(lines, name, labels, metric.GetGauge().GetValue(), "g")
+			case dto.MetricType_UNTYPED:
+				lines = s.appendLine(lines, name, labels, metric.GetUntyped().GetValue(), "g")
+			case dto.MetricType_HISTOGRAM:
+				h := metric.GetHistogram()
+				lines = s.appendCount(lines, name+"_count", labels, float64(h.GetSampleCount()))
+				lines = s.appendCount(lines, name+"_sum", labels, h.GetSampleSum())
+			case dto.MetricType_SUMMARY:
+				sm := metric.GetSummary()
+				lines = s.appendCount(lines, name+"_count", labels, float64(sm.GetSampleCount()))
+				lines = s.appendCount(lines, name+"_sum", labels, sm.GetSampleSum())
+			}
+		}
+	}
+	return lines, nil
+}
+
+// appendCount adds a line for a cumulative value, sending the
+// change since the previous push.  Nothing is sent if the value has
+// not changed.  If it went down, the process restarted the series,
+// and the whole value is new.
+func (s *StatsDSink) appendCount(lines []string, name string, labels []*dto.LabelPair, value float64) []string {
+	key := s.key(name, labels)
+	delta := value - s.counters[key]
+	if delta < 0 {
+		delta = value
+	}
+	s.counters[key] = value
+	if delta == 0 {
+		return lines
+	}
+	return s.appendLine(lines, name, labels, delta, "c")
+}
+
+// appendLine adds a single formatted StatsD line to lines.  Values
+// that StatsD cannot represent are skipped.
+func (s *StatsDSink) appendLine(lines []string, name string, labels []*dto.LabelPair, value float64, kind string) []string {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return lines
+	}
+	line := s.config.Prefix + name
+	if s.config.Format != statsdDog {
+		line = s.key(name, labels)
+	}
+	line += ":" + strconv.FormatFloat(value, 'f', -1, 64) + "|" + kind
+	if s.config.Format == statsdDog && len(labels) > 0 {
+		tags := make([]string, len(labels))
+		for i, label := range labels {
+			tags[i] = label.GetName() + ":" + strings.Map(dogTagRune, label.GetValue())
+		}
+		line += "|#" + strings.Join(tags, ",")
+	}
+	return append(lines, line)
+}
+
+// key returns the plain StatsD name of a series, which has its label
+// values appended to the metric name.
+func (s *StatsDSink) key(name string, labels []*dto.LabelPair) string {
+	key := s.config.Prefix + name
+	for _, label := range labels {
+		value := strings.Map(statsdNameRune, label.GetValue())
+		if value == "" {
+			value = "_"
+		}
+		key += "." + value
+	}
+	return key
+}
+
+// statsdNameRune replaces characters that are not safe in a plain
+// StatsD metric name.
+func statsdNameRune(r rune) rune {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+		return r
+	}
+	return '_'
+}
+
+// dogTagRune replaces characters that would end a DogStatsD tag.
+func dogTagRune(r rune) rune {
+	switch r {
+	case ',', '|', '#', '\n':
+		return '_'
+	}
+	return r
+}
+
+// PushStatsD pushes metrics from sink every period until ctx is
+// done, logging any errors to log.
+func PushStatsD(
+	ctx context.Context,
+	sink *StatsDSink,
+	period time.Duration,
+	log *logrus.Logger,
+) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(period):
+			err := sink.Push()
+			if err != nil {
+				log.WithFields(logrus.Fields{
+					"err":     err,
+					"address": sink.config.Address,
+				}).Error("Could not push metrics to StatsD")
+			}
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package main
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+// statsdStub listens for StatsD packets on a local UDP port.
+type statsdStub struct {
+	conn net.PacketConn
+}
+
+func newStatsDStub(t *testing.T) *statsdStub {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return &statsdStub{conn: conn}
+}
+
+// Lines returns the lines in the next packet the stub receives.
+func (stub *statsdStub) Lines(t *testing.T) []string {
+	buf := make([]byte, 65536)
+	err := stub.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	n, _, err := stub.conn.ReadFrom(buf)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return strings.Split(string(buf[:n]), "\n")
+}
+
+// statsdRegistry creates a registry with one metric of each kind.
+func statsdRegistry() (*prometheus.Registry, *prometheus.GaugeVec, prometheus.Counter, prometheus.Histogram) {
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "coordinate",
+		Name:      "work_units",
+		Help:      "Work units",
+	}, []string{"namespace", "status"})
+	counter := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "coordinate",
+		Name:      "requests_total",
+		Help:      "Requests",
+	})
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "coordinate",
+		Name:      "summary_seconds",
+		Help:      "Summary time",
+	})
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(gauge, counter, histogram)
+	return registry, gauge, counter, histogram
+}
+
+// TestStatsDPush checks the lines sent to a StatsD server, including
+// that counters are sent as the change since the last push.
+func TestStatsDPush(t *testing.T) {
+	stub := newStatsDStub(t)
+	defer stub.conn.Close()
+	registry, gauge, counter, histogram := statsdRegistry()
+
+	sink, err := NewStatsDSink(StatsDConfig{
+		Address: stub.conn.LocalAddr().String(),
+		Format:  statsdPlain,
+		Prefix:  "test.",
+	}, registry)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer sink.Close()
+
+	gauge.WithLabelValues("tenant.1", "available").Set(3)
+	counter.Add(5)
+	histogram.Observe(0.5)
+	if assert.NoError(t, sink.Push()) {
+		assert.Equal(t, []string{
+			"test.coordinate_requests_total:5|c",
+			"test.coordinate_summary_seconds_count:1|c",
+			"test.coordinate_summary_seconds_sum:0.5|c",
+			"test.coordinate_work_units.tenant_1.available:3|g",
+		}, stub.Lines(t))
+	}
+
+	// The counter grows by 2 and the histogram is unchanged
+	counter.Add(2)
+	if assert.NoError(t, sink.Push()) {
+		assert.Equal(t, []string{
+			"test.coordinate_requests_total:2|c",
+			"test.coordinate_work_units.tenant_1.available:3|g",
+		}, stub.Lines(t))
+	}
+}
+
+// TestDogStatsDPush checks that labels become DogStatsD tags.
+func TestDogStatsDPush(t *testing.T) {
+	stub := newStatsDStub(t)
+	defer stub.conn.Close()
+	registry, gauge, _, _ := statsdRegistry()
+
+	sink, err := NewStatsDSink(StatsDConfig{
+		Address: stub.conn.LocalAddr().String(),
+		Format:  statsdDog,
+	}, registry)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer sink.Close()
+
+	gauge.WithLabelValues("tenant.1", "available").Set(3)
+	if assert.NoError(t, sink.Push()) {
+		assert.Equal(t, []string{
+			"coordinate_work_units:3|g|#namespace:tenant.1,status:available",
+		}, stub.Lines(t))
+	}
+}
+
+// TestStatsDPacketSize checks that many metrics are split across
+// packets of limited size.
+func TestStatsDPacketSize(t *testing.T) {
+	stub := newStatsDStub(t)
+	defer stub.conn.Close()
+	registry, gauge, _, _ := statsdRegistry()
+
+	sink, err := NewStatsDSink(StatsDConfig{
+		Address: stub.conn.LocalAddr().String(),
+		Format:  statsdPlain,
+	}, registry)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer sink.Close()
+
+	names := make([]string, 100)
+	for i := range names {
+		names[i] = strings.Repeat("n", 10) + string(rune('a'+i/26)) + string(rune('a'+i%26))
+		gauge.WithLabelValues(names[i], "available").Set(1)
+	}
+	if !assert.NoError(t, sink.Push()) {
+		return
+	}
+	var lines []string
+	for len(lines) < len(names) {
+		packet := stub.Lines(t)
+		assert.True(t, len(strings.Join(packet, "\n")) <= statsdPacketSize)
+		lines = append(lines, packet...)
+	}
+	assert.Len(t, lines, len(names))
+}
+
+// TestStatsDConfig checks that flags override the YAML
+// configuration, and that the format is checked.
+func TestStatsDConfig(t *testing.T) {
+	gConfig := map[string]interface{}{
+		"statsd": map[interface{}]interface{}{
+			"address": "statsd:8125",
+			"format":  "dogstatsd",
+			"prefix":  "coord.",
+		},
+	}
+	config, err := statsdConfig(gConfig, "", "", "")
+	if assert.NoError(t, err) {
+		assert.Equal(t, StatsDConfig{
+			Address: "statsd:8125",
+			Format:  statsdDog,
+			Prefix:  "coord.",
+		}, config)
+	}
+
+	config, err = statsdConfig(gConfig, "localhost:9125", "statsd", "")
+	if assert.NoError(t, err) {
+		assert.Equal(t, StatsDConfig{
+			Address: "localhost:9125",
+			Format:  statsdPlain,
+			Prefix:  "coord.",
+		}, config)
+	}
+
+	config, err = statsdConfig(nil, "", "", "")
+	if assert.NoError(t, err) {
+		assert.Equal(t, StatsDConfig{Format: statsdPlain}, config)
+	}
+
+	_, err = statsdConfig(nil, "localhost:8125", "graphite", "")
+	assert.Error(t, err)
+}
@@ -21,7 +21,7 @@ import (
 
 func main() {
 	backend := backend.Backend{Implementation: "memory", Address: ""}
-	flag.Var(&backend, "backend", "impl[:address] of the storage backend")
+	flag.Var(&backend, "backend", "impl[:address][#namespace] of the storage backend")
 	bootstrap := flag.Bool("bootstrap", true, "Create initial work specs")
 	nsName := flag.String("namespace", "", "Coordinate namespace name")
 	flag.Parse()
@@ -31,7 +31,7 @@ func main() {
 		panic(err)
 	}
 
-	namespace, err := coordinateRoot.Namespace(*nsName)
+	namespace, err := coordinateRoot.Namespace(backend.NamespaceName(*nsName))
 	if err != nil {
 		panic(err)
 	}
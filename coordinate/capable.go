@@ -0,0 +1,62 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package coordinate
+
+// Capable is implemented by Coordinate backends that can report
+// which optional features they support.  Generic code can check this
+// before reaching for an optional interface, rather than probing with
+// type assertions of its own.  Use the Supports function to query a
+// backend that may not implement this interface.
+type Capable interface {
+	// Supports returns true if this backend supports the named
+	// feature, one of the Feature constants.  Unknown feature
+	// names are not supported.
+	Supports(feature string) bool
+}
+
+// Names of optional features, for Capable.Supports().
+const (
+	// FeatureDataHistory indicates that the backend implements
+	// DataHistorySetter.
+	FeatureDataHistory = "data_history"
+
+	// FeatureNamespaceLimit indicates that the backend implements
+	// NamespaceLimiter.
+	FeatureNamespaceLimit = "namespace_limit"
+
+	// FeatureRequestThrottle indicates that the backend
+	// implements RequestThrottler.
+	FeatureRequestThrottle = "request_throttle"
+
+	// FeatureWorkerGracePeriod indicates that the backend
+	// implements WorkerGracePeriodSetter.
+	FeatureWorkerGracePeriod = "worker_grace_period"
+
+	// FeatureKeyNormalization indicates that the backend
+	// implements KeyNormalizerSetter.
+	FeatureKeyNormalization = "key_normalization"
+
+	// FeatureAttemptArchive indicates that the backend
+	// implements AttemptArchiver.
+	FeatureAttemptArchive = "attempt_archive"
+
+	// FeatureStrictCompletion indicates that the backend
+	// implements StrictCompletionSetter.
+	FeatureStrictCompletion = "strict_completion"
+
+	// FeatureWorkSpecDataLimit indicates that the backend
+	// implements WorkSpecDataLimiter.
+	FeatureWorkSpecDataLimit = "work_spec_data_limit"
+
+	// FeatureSchedulingGate indicates that the backend
+	// implements SchedulingGater.
+	FeatureSchedulingGate = "scheduling_gate"
+)
+
+// Supports returns true if c implements Capable and it supports the
+// named feature.
+func Supports(c Coordinate, feature string) bool {
+	capable, ok := c.(Capable)
+	return ok && capable.Supports(feature)
+}
@@ -0,0 +1,31 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package coordinate
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// capableCoordinate is a Coordinate that supports exactly one
+// feature.  Its other methods are never called.
+type capableCoordinate struct {
+	Coordinate
+}
+
+func (capableCoordinate) Supports(feature string) bool {
+	return feature == FeatureDataHistory
+}
+
+func TestSupports(t *testing.T) {
+	var c Coordinate = capableCoordinate{}
+	assert.True(t, Supports(c, FeatureDataHistory))
+	assert.False(t, Supports(c, FeatureRequestThrottle))
+	assert.False(t, Supports(c, "no_such_feature"))
+}
+
+func TestSupportsNotCapable(t *testing.T) {
+	type plainCoordinate struct{ Coordinate }
+	assert.False(t, Supports(plainCoordinate{}, FeatureDataHistory))
+}
@@ -29,6 +29,113 @@ type Coordinate interface {
 
 	// Namespaces retrieves a map of all known namespaces.
 	Namespaces() (map[string]Namespace, error)
+
+	// ServerTime returns the current time according to the
+	// backend.  Lease and expiration decisions are made against
+	// this clock, which may differ from the caller's, particularly
+	// through a REST server or with a mock clock in tests.
+	ServerTime() (time.Time, error)
+}
+
+// DataHistorySetter is implemented by Coordinate backends that can
+// keep a history of Attempt data; see Attempt.DataHistory().  This is
+// not part of the Coordinate interface, so callers holding a backend
+// must use a type assertion to reach it.
+type DataHistorySetter interface {
+	// SetDataHistory sets the number of data snapshots kept for
+	// each Attempt.  Only the most recent snapshots are kept.
+	// Zero, the default, keeps no history at all.
+	SetDataHistory(limit int)
+}
+
+// NamespaceLimiter is implemented by Coordinate backends that can
+// limit how many namespaces they create.  Like DataHistorySetter, it
+// is reached with a type assertion.
+type NamespaceLimiter interface {
+	// SetMaxNamespaces sets the maximum number of namespaces
+	// Coordinate.Namespace() will create.  Once there are this
+	// many, existing namespaces can still be retrieved, but
+	// asking for a new one returns ErrTooManyNamespaces.  Zero,
+	// the default, is unlimited.
+	SetMaxNamespaces(limit int)
+}
+
+// WorkerGracePeriodSetter is implemented by Coordinate backends that
+// can wait for some time after a worker's expiration before treating
+// it as dead.  Like DataHistorySetter, it is reached with a type
+// assertion.
+type WorkerGracePeriodSetter interface {
+	// SetWorkerGracePeriod sets how long a worker that has not
+	// checked in is still considered alive after its expiration
+	// time.  This keeps a brief network outage from reclaiming
+	// attempts in work specs with ExpireWithWorker set.  Workers
+	// that have been explicitly deactivated are dead immediately.
+	// Zero, the default, has no grace period.
+	SetWorkerGracePeriod(grace time.Duration)
+}
+
+// AttemptArchiver is implemented by Coordinate backends that can move
+// old attempts out of the tables used for everyday queries, keeping
+// them for later auditing.  Like DataHistorySetter, it is reached
+// with a type assertion.
+type AttemptArchiver interface {
+	// SetAttemptArchive configures automatic archival of
+	// completed attempts.  Whenever an attempt completes, the
+	// completed attempts for its work unit other than the most
+	// recent keep attempts, and those that ended more than age
+	// ago, are archived.  Pending and active attempts are never
+	// archived.  Zero for either disables that limit; the
+	// default, both zero, never archives anything.
+	//
+	// Archived attempts are still returned from
+	// WorkUnit.Attempts() and counted by WorkUnit.NumAttempts(),
+	// but not from Worker.AllAttempts() or
+	// Worker.AttemptsInWindow().  Their data history and logs
+	// are kept, and Attempt objects fetched before an attempt was
+	// archived still read it.
+	SetAttemptArchive(keep int, age time.Duration)
+}
+
+// StrictCompletionSetter is implemented by Coordinate backends that
+// can refuse to complete attempts that are no longer active.  Like
+// DataHistorySetter, it is reached with a type assertion.
+type StrictCompletionSetter interface {
+	// SetStrictCompletion sets what Attempt.Finish(),
+	// Attempt.Fail(), and Attempt.Retry() do to a pending
+	// attempt that is no longer its work unit's active attempt,
+	// for instance after WorkUnit.ClearActiveAttempt().  If
+	// false, the default, the attempt is completed with its new
+	// data but nothing else changes: Finish() does not create
+	// "output" work units and Retry() does not delay the work
+	// unit.  If true, the attempt instead expires, as
+	// Attempt.Renew() would, and the call returns ErrLostLease.
+	SetStrictCompletion(strict bool)
+}
+
+// WorkSpecDataLimiter is implemented by Coordinate backends that can
+// limit the size of work spec data, which is read whenever a work
+// spec's definition or metadata is.  Like DataHistorySetter, it is
+// reached with a type assertion.
+type WorkSpecDataLimiter interface {
+	// SetMaxWorkSpecData sets the largest work spec data, in
+	// bytes of its CBOR encoding, that Namespace.SetWorkSpec()
+	// and WorkSpec.SetData() accept.  Larger data returns
+	// ErrWorkSpecDataTooLarge and leaves the work spec unchanged.
+	// Existing work specs are not checked.  Zero, the default,
+	// is unlimited.
+	SetMaxWorkSpecData(limit int)
+}
+
+// ConnectionLimiter is implemented by Coordinate backends that keep
+// a pool of database connections and can limit its size.  Like
+// DataHistorySetter, it is reached with a type assertion.
+type ConnectionLimiter interface {
+	// SetMaxConnections sets the maximum number of open
+	// connections to the database.  Once there are this many,
+	// operations wait for a connection to be returned to the
+	// pool rather than opening a new one.  Zero, the default,
+	// is unlimited.
+	SetMaxConnections(limit int)
 }
 
 // Namespace is a single application's state within Coordinate.  A
@@ -75,13 +182,31 @@ type Namespace interface {
 	// does not exist, returns an instance of ErrNoSuchWorkSpec.
 	DestroyWorkSpec(name string) error
 
+	// DestroyWorkSpecs destroys several work specs at once, as
+	// DestroyWorkSpec(), and returns the number destroyed.  Names
+	// that do not exist are skipped rather than returning an
+	// error.  Backends with transactions destroy them all in
+	// one transaction.
+	DestroyWorkSpecs(names []string) (int, error)
+
 	// WorkSpecNames returns the names of all of the work specs in
-	// this namespace.  This may be an empty slice if there are no
-	// work specs.  Unless one of the work specs is destroyed,
-	// calling GetWorkSpec on one of these names will retrieve the
-	// corresponding WorkSpec object.
+	// this namespace, sorted alphabetically.  This may be an
+	// empty slice if there are no work specs.  Unless one of the
+	// work specs is destroyed, calling GetWorkSpec on one of
+	// these names will retrieve the corresponding WorkSpec
+	// object.
 	WorkSpecNames() ([]string, error)
 
+	// AvailableRuntimes returns the distinct runtimes of the
+	// available work units in this namespace, in sorted order.
+	// A work unit's runtime is its WorkUnitMeta.Runtime if set,
+	// or else its work spec's runtime.  This includes work specs
+	// that are paused, but not continuous work specs that have
+	// no actual work units.  The default runtime is reported as
+	// an empty string.  This may be an empty slice if there is
+	// no available work.
+	AvailableRuntimes() ([]string, error)
+
 	// Worker retrieves or creates a Worker object by its name.
 	// Every Worker in this Namespace has a nominally unique but
 	// client-provided name.  If no Worker exists yet with the
@@ -102,6 +227,64 @@ type Namespace interface {
 	// like this.  Another fairly obvious change is to add
 	// (start,limit) windowing like elsewhere.
 	Workers() (map[string]Worker, error)
+
+	// DeactivateWorkers deactivates every worker in this
+	// namespace that matches a query, exactly as though
+	// Deactivate had been called on each.  Children of a matched
+	// worker are not deactivated unless they match the query
+	// themselves.  Pending attempts the matched workers hold in
+	// work specs with ExpireWithWorker set expire at once, so
+	// their work units become available to other workers; other
+	// attempts run until their own expiration times.  Returns
+	// the number of workers that were active and are now
+	// inactive.
+	DeactivateWorkers(q WorkerQuery) (int, error)
+
+	// DeleteWorkers removes every worker in this namespace that
+	// matches a query, along with all of its attempts.  Pending
+	// attempts are released first, as by Attempt.Release, so
+	// their work units are immediately available to other
+	// workers.  A matched worker that holds the active attempt
+	// of a finished or failed work unit is kept, since deleting
+	// that attempt would make the work unit available again.
+	// Children of a deleted worker are left without a parent.
+	// Returns the number of workers deleted.
+	DeleteWorkers(q WorkerQuery) (int, error)
+
+	// ExpiringAttempts returns the pending attempts in all work
+	// specs in this namespace that will expire within the given
+	// duration from now, ordered so that the attempt expiring
+	// soonest is first.  This may be an empty slice if no
+	// attempts are expiring.
+	ExpiringAttempts(within time.Duration) ([]Attempt, error)
+
+	// RebalanceAttempts releases pending attempts from workers
+	// that hold more than maxPending of them.  Each such worker
+	// keeps its maxPending oldest pending attempts; the rest are
+	// released as by Attempt.Release, so their work units become
+	// available to other workers at once, without counting a
+	// retry against max_retries or retry_delays.  If
+	// maxPending is zero or negative, every pending attempt in
+	// the namespace is released.  Returns the number of attempts
+	// that were expired.
+	//
+	// This is intended as an operational tool, for instance to
+	// spread out work after one worker picked up a large burst
+	// of it.  Workers are not notified; a worker that tries to
+	// complete a released attempt will get an error as for any
+	// other expired attempt.
+	RebalanceAttempts(maxPending int) (int, error)
+
+	// PipelineHealth looks for "then" pipelines in this namespace
+	// that are stuck.  It follows the WorkSpec.Successors()
+	// links between work specs, and reports stages that have
+	// available work units but no live worker running them or
+	// free to pick them up, paused stages with work waiting for
+	// them, and stages whose next work spec does not exist.
+	// Work specs that are not part of any pipeline are not
+	// considered.  This may be an empty slice if nothing is
+	// wrong.  See PipelineIssue for details.
+	PipelineHealth() ([]PipelineIssue, error)
 }
 
 // WorkSpecMeta defines control data for a work spec.  This information
@@ -134,6 +317,22 @@ type WorkSpecMeta struct {
 	// Defaults to the value of CanBeContinuous.
 	Continuous bool `json:"continuous"`
 
+	// ContinuousPaused stops the system from generating new
+	// artificial work units for a continuous work spec, while
+	// its existing work units can still run.  Unlike Paused,
+	// this does not stop the work spec entirely.  Defaults to
+	// false.
+	ContinuousPaused bool `json:"continuous_paused,omitempty"`
+
+	// Gated stops the work spec from being scheduled, like
+	// Paused, but is meant to be set and cleared by an external
+	// controller that watches some condition the work spec
+	// depends on, such as a downstream system being healthy.
+	// Keeping this separate from Paused means the controller
+	// never undoes a pause an operator made by hand.  Defaults
+	// to false.
+	Gated bool `json:"gated,omitempty"`
+
 	// CanBeContinuous indicates whether the work spec allows
 	// continuous work unit generation.  This is directly set from
 	// the "continuous" flag in the work spec data, and
@@ -175,17 +374,78 @@ type WorkSpecMeta struct {
 	// MaxRunning work units.  Defaults to the value of the
 	// "max_getwork" field in the work spec data, or 0.  A zero
 	// value is interpreted as "unlimited".
+	//
+	// When this cuts a batch short, the work units returned are
+	// the first ones in Order, and they are returned in that
+	// order.  In the default priority order that means the
+	// highest-priority work units, breaking ties within a
+	// priority by name; a group of equal-priority work units is
+	// split at the same point a series of single-unit requests
+	// would reach.  A work unit left out of one batch is at the
+	// front of the next, so it can only wait behind work units
+	// that sort ahead of it.
 	MaxAttemptsReturned int `json:"max_attempts_returned"`
 
 	// MaxRetries specifies the maximum number of attempts that
 	// can exist for a work unit.  If non-zero, then when
 	// Worker.RequestAttempts() produces attempts, it will
 	// immediately fail any that have more than this many attempts
-	// already.  Defaults to the value of the "max_retries" field
-	// in the work spec data, or 0.  A zero value is interpreted
-	// as "unlimited".
+	// already, and Attempt.Retry() fails a work unit that has
+	// this many attempts rather than retrying it.  Defaults to
+	// the value of the "max_retries" field in the work spec data,
+	// or 0.  A zero value is interpreted as "unlimited".
 	MaxRetries int `json:"max_retries"`
 
+	// ExpireWithWorker indicates that pending attempts should be
+	// expired as soon as the worker performing them is no longer
+	// alive, rather than waiting for the attempts' own expiration
+	// times.  A worker is not alive if it has been deactivated or
+	// if its own expiration time has passed without an update.
+	// Defaults to the value of the "expire_with_worker" field in
+	// the work spec data, or false.
+	ExpireWithWorker bool `json:"expire_with_worker"`
+
+	// MaxLeaseTotal is the longest time an attempt may be held,
+	// measured from its start time, across all renewals.  If
+	// non-zero, a new attempt's initial lease from
+	// Worker.RequestAttempts() or Worker.MakeAttempt() is no
+	// longer than this, Attempt.Renew() will not extend an
+	// attempt past this limit, and renewing an attempt that has
+	// already reached it expires the attempt and returns
+	// ErrLeaseTotalExceeded.  Defaults to the value of the
+	// "max_lease_total" field in the work spec data, or 0
+	// (unlimited).
+	MaxLeaseTotal time.Duration `json:"max_lease_total"`
+
+	// HeartbeatExtension lets a worker keep its attempts alive
+	// without renewing them individually.  If non-zero, every
+	// call to Worker.Update() extends each of that worker's
+	// pending attempts in this work spec so that it expires no
+	// sooner than this long after the update, still subject to
+	// MaxLeaseTotal.  Attempts that have already expired are not
+	// revived.  Defaults to the value of the
+	// "heartbeat_extension" field in the work spec data, or 0
+	// (disabled).
+	HeartbeatExtension time.Duration `json:"heartbeat_extension"`
+
+	// DefaultLease is how long attempts last if the worker does
+	// not ask for a specific time, through
+	// AttemptRequest.Lifetime or the duration passed to
+	// Worker.MakeAttempt().  If zero, the system-provided
+	// default of 15 minutes applies.  Defaults to the value of
+	// the "default_lease" field in the work spec data, or 0.
+	DefaultLease time.Duration `json:"default_lease"`
+
+	// RetryDelays gives a backoff schedule for work units that
+	// are retried.  When a work unit's attempt is retried or
+	// expires for the Nth time, the work unit is not available
+	// again until the Nth delay has passed, or the last delay if
+	// there are fewer than N.  An explicit non-zero delay passed
+	// to Attempt.Retry() overrides this schedule.  Defaults to
+	// the value of the "retry_delays" field in the work spec
+	// data, a list of numbers of seconds, or empty (no delay).
+	RetryDelays []time.Duration `json:"retry_delays,omitempty"`
+
 	// NextWorkSpecName gives the name of a work spec that runs
 	// after this one.  If this is a non-empty string, then when
 	// an attempt completes successfully, if the updated work unit
@@ -220,8 +480,56 @@ type WorkSpecMeta struct {
 	// empty string.  For backwards compatibility, empty string
 	// should be interpreted as "python_2".
 	Runtime string `json:"runtime"`
+
+	// Order selects the order in which Worker.RequestAttempts()
+	// hands out this work spec's work units: one of
+	// OrderPriority, OrderFIFO, or OrderLIFO.
+	// WorkSpec.SetMeta() ignores this field.  Defaults to the
+	// value of the "order" field in the work spec data, or
+	// OrderPriority.
+	Order string `json:"order"`
+
+	// SchemaVersion is an application-defined version number for
+	// the shape of this work spec's data and its work units'
+	// data.  Workers can compare it against the version they
+	// expect with CheckSchemaVersion().  WorkSpec.SetMeta()
+	// ignores this field.  Defaults to the value of the
+	// "schema_version" field in the work spec data, or 0.
+	SchemaVersion int `json:"schema_version"`
 }
 
+// WorkSpecSummary combines the most commonly needed information about
+// a work spec, as returned by WorkSpec.Summary().
+type WorkSpecSummary struct {
+	// Data is the work spec's data dictionary, as returned by
+	// WorkSpec.Data().
+	Data map[string]interface{}
+
+	// Meta is the work spec's metadata including its available
+	// and pending counts, as returned by WorkSpec.Meta(true).
+	Meta WorkSpecMeta
+
+	// Counts gives the number of work units in each status, as
+	// returned by WorkSpec.CountWorkUnitStatus().
+	Counts map[WorkUnitStatus]int
+}
+
+// Orderings of work units within a work spec, for
+// WorkSpecMeta.Order.
+const (
+	// OrderPriority returns the highest-priority work units
+	// first, breaking ties by name.
+	OrderPriority = "priority"
+
+	// OrderFIFO returns the oldest work units first, ignoring
+	// priority.
+	OrderFIFO = "fifo"
+
+	// OrderLIFO returns the newest work units first, ignoring
+	// priority.
+	OrderLIFO = "lifo"
+)
+
 // WorkUnitStatus defines a high-level status of a work unit.
 type WorkUnitStatus int
 
@@ -232,8 +540,8 @@ const (
 
 	// AvailableUnit corresponds to work units that do not have
 	// active attempts, or if they do have active attempts, they are
-	// either Expired or Retryable.  These are work units that
-	// Worker.RequestAttempts can return.
+	// either Expired or Retryable, and that are not DelayedUnit.
+	// These are work units that Worker.RequestAttempts can return.
 	AvailableUnit
 
 	// PendingUnit corresponds to work units that have an active
@@ -251,9 +559,12 @@ const (
 	// completed unsuccessfully.
 	FailedUnit
 
-	// DelayedUnit corresponds to work units that do not have
-	// active attempts, but do have a not-before start time that
-	// has not yet been reached.
+	// DelayedUnit corresponds to work units that would be
+	// AvailableUnit, but have a WorkUnitMeta.NotBefore time that
+	// has not yet been reached.  Every backend applies this same
+	// rule in WorkUnit.Status(), in the Statuses of a
+	// WorkUnitQuery, and in WorkSpec.CountWorkUnitStatus(); a
+	// delayed work unit is never counted as available.
 	DelayedUnit
 )
 
@@ -279,6 +590,20 @@ func (wus *WorkUnitStatus) String() string {
 	return s
 }
 
+// WorkerQuery defines terms to select some subset of the workers in
+// a single namespace.  Its zero value selects all workers.  A worker
+// must match every non-zero field to be selected.
+type WorkerQuery struct {
+	// NamePrefix, if non-empty, selects only workers whose
+	// names begin with this string.
+	NamePrefix string
+
+	// Expired, if true, selects only workers whose expiration
+	// time has passed; that is, workers that have not checked in
+	// recently enough.
+	Expired bool
+}
+
 // WorkUnitQuery defines terms to select some subset of the work units
 // in a single work spec.  Its zero value selects all work units.
 type WorkUnitQuery struct {
@@ -300,10 +625,36 @@ type WorkUnitQuery struct {
 	// there is no constraint.
 	PreviousName string
 
+	// NeverAttempted, if true, selects only work units that have
+	// never had any attempts at all.  This is narrower than
+	// selecting AvailableUnit status, which also includes work
+	// units whose previous attempts expired or were retried.
+	NeverAttempted bool
+
+	// WorkerName, if non-empty, selects only work units whose
+	// active attempt belongs to the worker with this name.  This
+	// includes finished and failed attempts that are still
+	// active; combine this with a PendingUnit status to find only
+	// the work units the worker is currently running.
+	WorkerName string
+
+	// Offset specifies a number of work units to skip.  If the
+	// possible work unit keys are sorted lexicographically, the
+	// first Offset keys will not be returned, and Limit applies
+	// to the keys after them.
+	Offset int
+
 	// Limit specifies the maximum number of work units to select.
 	// If the possible work unit keys are sorted
 	// lexicographically, the first Limit keys will be returned.
 	Limit int
+
+	// Cursor is an opaque token returned from
+	// WorkSpec.WorkUnitsPage(), selecting only work units after
+	// the page that returned it.  If non-empty, it replaces both
+	// PreviousName and Offset.  Only WorkUnitsPage() honors this
+	// field.
+	Cursor string
 }
 
 // A WorkSpec defines a collection of related jobs.  For instance, a
@@ -318,6 +669,10 @@ type WorkSpec interface {
 	// Name returns the name of this work spec.
 	Name() string
 
+	// Namespace returns the namespace that contains this work
+	// spec.
+	Namespace() Namespace
+
 	// Data returns the definition of this work spec.
 	Data() (map[string]interface{}, error)
 
@@ -349,6 +704,25 @@ type WorkSpec interface {
 	// The WorkSpecMeta.PendingCount field is ignored.
 	SetMeta(WorkSpecMeta) error
 
+	// SetNextContinuous sets WorkSpecMeta.NextContinuous, the
+	// earliest time a new continuous work unit can be generated,
+	// without changing any other metadata.  A time in the past,
+	// or a zero time, allows a continuous work unit to be
+	// generated immediately; a time in the future delays it.
+	// This has no effect on work specs that are not continuous.
+	SetNextContinuous(t time.Time) error
+
+	// IsSchedulable determines whether the scheduler could
+	// currently choose this work spec to hand out work.  This
+	// requires the work spec to not be paused or gated, either
+	// by WorkSpecMeta.Gated or by a SchedulingGater function, to
+	// have positive weight, to be under its max_running limit,
+	// and to have either available work units or the ability to
+	// create a continuous work unit.  If it is not schedulable,
+	// also returns a short reason, such as "paused", "gated",
+	// "max_running reached", or "no available work units".
+	IsSchedulable() (bool, string, error)
+
 	// AddWorkUnit adds a single work unit to this work spec.  If
 	// a work unit already exists with the specified name, it is
 	// overridden.
@@ -364,6 +738,30 @@ type WorkSpec interface {
 	// will be selected.
 	WorkUnits(WorkUnitQuery) (map[string]WorkUnit, error)
 
+	// WorkUnitsPage retrieves one page of work units by a query,
+	// along with a cursor for the next page.  If the query has a
+	// positive Limit and more work units match it, the cursor is
+	// a non-empty string that can be passed as the Cursor of the
+	// same query to retrieve the following page; otherwise it is
+	// empty.  Returns ErrBadCursor if the query's Cursor was not
+	// produced by this function.
+	WorkUnitsPage(WorkUnitQuery) (map[string]WorkUnit, string, error)
+
+	// WorkUnitStatuses retrieves the status of many work units
+	// at once, keyed by work unit name.  This gives the same
+	// result as WorkUnit.Status() for each unit, but may be much
+	// faster than retrieving each work unit separately.  Names
+	// that do not name a work unit in this work spec are not
+	// included in the result.
+	WorkUnitStatuses(names []string) (map[string]WorkUnitStatus, error)
+
+	// WorkUnitPriorities retrieves the priority of many work
+	// units at once, keyed by work unit name.  This gives the
+	// same result as WorkUnit.Priority() for each unit, and, like
+	// WorkUnitStatuses(), names that do not name a work unit in
+	// this work spec are not included in the result.
+	WorkUnitPriorities(names []string) (map[string]float64, error)
+
 	// CountWorkUnitStatus retrieves the number of work units in
 	// each status in this work spec.  This is mostly useful as an
 	// administrator's tool.  It is expected to typically be
@@ -371,6 +769,28 @@ type WorkSpec interface {
 	// results.
 	CountWorkUnitStatus() (map[WorkUnitStatus]int, error)
 
+	// DataSize returns the total size, in bytes, of the encoded
+	// data dictionaries of all of the work units in this work
+	// spec.  The encoding is backend-specific, so this is only
+	// meaningful to compare against other values from the same
+	// backend.
+	DataSize() (int64, error)
+
+	// PriorityHistogram returns the number of work units in this
+	// work spec at each priority, keyed by priority.  Every work
+	// unit is counted, whatever its status.  This is mostly
+	// useful as an administrator's tool for understanding how
+	// work will be scheduled.
+	PriorityHistogram() (map[float64]int, error)
+
+	// Successors returns the names of the work specs that
+	// receive this work spec's output work units, as given by
+	// the NextWorkSpecName field of its metadata.  This is an
+	// empty slice if there are none.  The named work specs are
+	// not required to exist.  WorkSpecChain() follows these
+	// links transitively.
+	Successors() ([]string, error)
+
 	// SetWorkUnitPriorities updates the priorities of multiple
 	// work units to all have the same value.
 	SetWorkUnitPriorities(WorkUnitQuery, float64) error
@@ -379,6 +799,33 @@ type WorkSpec interface {
 	// priorities of multiple work units.
 	AdjustWorkUnitPriorities(WorkUnitQuery, float64) error
 
+	// ResetPriorities returns the priorities of multiple work
+	// units to the default priority a work unit gets when it is
+	// added without one, which is 0.
+	ResetPriorities(WorkUnitQuery) error
+
+	// ReorderAvailable changes the priorities of the available
+	// and delayed work units so that they will be scheduled in
+	// the order given by orderedNames.  Listed work units get
+	// decreasing priorities, all higher than any unlisted
+	// available or delayed work unit; unlisted work units keep
+	// their current priorities.  Names that do not refer to
+	// available or delayed work units are ignored.  See
+	// ReorderPriorities for details.
+	ReorderAvailable(orderedNames []string) error
+
+	// PurgeAttempts deletes historical attempts for work units in
+	// this work spec, without deleting the work units
+	// themselves.  It deletes attempts whose status is one of
+	// statuses and whose end time is strictly before the given
+	// time.  If statuses is empty, it defaults to
+	// TerminalAttemptStatuses(), since deleting Expired or
+	// Retryable attempts would reset the count MaxRetries checks.
+	// Pending attempts and attempts that are the active attempt
+	// for their work unit are never deleted.  Returns the number
+	// of attempts deleted.
+	PurgeAttempts(before time.Time, statuses []AttemptStatus) (int, error)
+
 	// DeleteWorkUnits deletes work units selected by a query.  If
 	// a zero WorkUnitQuery is passed, this deletes all work units
 	// in this work spec.  Deleting a work unit also deletes all
@@ -391,6 +838,17 @@ type WorkSpec interface {
 	//
 	// On success, returns the number of work units actually deleted.
 	DeleteWorkUnits(WorkUnitQuery) (int, error)
+
+	// CountWorkUnits returns the number of work units selected
+	// by a query, without changing them.  Unless the work spec
+	// changes in between, passing the same query to
+	// DeleteWorkUnits deletes this many work units.
+	CountWorkUnits(WorkUnitQuery) (int, error)
+
+	// Summary returns this work spec's data, metadata, and work
+	// unit status counts together.  Backends that can compute
+	// these consistently, as of a single point in time, do so.
+	Summary() (WorkSpecSummary, error)
 }
 
 // WorkUnitMeta defines control data for a work unit.  This information
@@ -405,6 +863,22 @@ type WorkUnitMeta struct {
 	// allowed to run.  A zero time allows the work unit to run
 	// immediately.
 	NotBefore time.Time `json:"not_before"`
+
+	// Runtime, if non-empty, overrides the work spec's
+	// WorkSpecMeta.Runtime for this work unit alone.  This is
+	// occasionally useful to send a special case to a different
+	// language runtime.  AttemptRequest.Runtimes is matched
+	// against this runtime, not the work spec's.
+	Runtime string `json:"runtime"`
+}
+
+// EffectiveRuntime returns the runtime a work unit needs: its own
+// Runtime if set, or else specRuntime, its work spec's runtime.
+func (meta WorkUnitMeta) EffectiveRuntime(specRuntime string) string {
+	if meta.Runtime != "" {
+		return meta.Runtime
+	}
+	return specRuntime
 }
 
 // A WorkUnit is a single job to perform.  It is associated with a
@@ -417,6 +891,14 @@ type WorkUnit interface {
 	// Data returns the data map of this work unit.
 	Data() (map[string]interface{}, error)
 
+	// SetData replaces the data map of this work unit.  This is
+	// only possible if the work unit has no active attempt,
+	// which is to say it is available or delayed; otherwise it
+	// returns ErrWorkUnitActive and changes nothing.  To change
+	// the data of a finished or failed work unit, add it again
+	// with WorkSpec.AddWorkUnit().
+	SetData(data map[string]interface{}) error
+
 	// WorkSpec returns the associated work spec.
 	WorkSpec() WorkSpec
 
@@ -450,6 +932,11 @@ type WorkUnit interface {
 	// coordinate 0.4.0.
 	SetPriority(float64) error
 
+	// CreatedAt returns the time this work unit was first added
+	// to its work spec.  Adding a work unit again with the same
+	// name does not change this.
+	CreatedAt() (time.Time, error)
+
 	// ActiveAttempt returns the current Attempt for this work
 	// unit, if any.  If the work unit is completed, either
 	// successfully or unsuccessfully, this is the Attempt that
@@ -466,14 +953,27 @@ type WorkUnit interface {
 
 	// Attempts returns all current and past Attempts for this
 	// work unit, if any.  This includes the attempt reported by
-	// ActiveAttempt().
+	// ActiveAttempt() and any archived attempts.
 	Attempts() ([]Attempt, error)
 
+	// QueryAttempts returns a subset of the Attempts for this
+	// work unit.  With a zero query, this is the same as
+	// Attempts().
+	QueryAttempts(q AttemptQuery) ([]Attempt, error)
+
 	// NumAttempts returns the number of times this work unit has
 	// been attempted.
 	NumAttempts() (int, error)
 }
 
+// AttemptQuery selects attempts for WorkUnit.QueryAttempts().  Its
+// zero value selects every attempt.
+type AttemptQuery struct {
+	// ArchivedOnly returns only attempts that have been
+	// archived; see AttemptArchiver.
+	ArchivedOnly bool
+}
+
 // AttemptRequest describes parameters to Worker.RequestAttempts().
 // Its zero value provides reasonable defaults, returning a single
 // work unit from any work spec ignoring resource constraints if
@@ -487,7 +987,8 @@ type AttemptRequest struct {
 
 	// Lifetime is the minimum requested time to perform this
 	// attempt; it must be completed or renewed by this deadline.
-	// If zero, use a system-provided default, generally 15
+	// If zero, use the work spec's DefaultLease, or if that is
+	// also zero, a system-provided default, generally 15
 	// minutes.
 	Lifetime time.Duration `json:"lifetime"`
 
@@ -509,13 +1010,42 @@ type AttemptRequest struct {
 
 	// Runtimes limits this request to only allow specific
 	// language runtimes.  If this is nil or an empty slice, any
-	// runtime is acceptable; otherwise only work units from work
-	// specs whose WorkSpecMeta.Runtime exactly matches one of
-	// these strings will be returned.  This could cause no work
+	// runtime is acceptable; otherwise only work units whose
+	// runtime exactly matches one of these strings will be
+	// returned.  A work unit's runtime is its WorkUnitMeta.Runtime
+	// if set, or else its work spec's WorkSpecMeta.Runtime; see
+	// WorkUnitMeta.EffectiveRuntime.  This could cause no work
 	// units to be returned if none of the work specs with any of
 	// these runtimes have work, even though other work specs that
 	// use other runtimes do.
 	Runtimes []string `json:"runtimes"`
+
+	// MinPriority, if non-nil, limits this request to work units
+	// whose WorkUnitMeta.Priority is at least this value.  Work
+	// units with lower priority remain available for other
+	// requests.  Continuous work units are created with priority
+	// zero, so a positive MinPriority never creates them.
+	MinPriority *float64 `json:"min_priority,omitempty"`
+}
+
+// AllowsPriority returns whether a work unit with the given priority
+// can be returned for this request, considering MinPriority.
+func (req AttemptRequest) AllowsPriority(priority float64) bool {
+	return req.MinPriority == nil || priority >= *req.MinPriority
+}
+
+// AllowsRuntime returns whether a work unit with the given effective
+// runtime can be returned for this request, considering Runtimes.
+func (req AttemptRequest) AllowsRuntime(runtime string) bool {
+	if len(req.Runtimes) == 0 {
+		return true
+	}
+	for _, allowed := range req.Runtimes {
+		if allowed == runtime {
+			return true
+		}
+	}
+	return false
 }
 
 // A Worker is a process that is doing work.  Workers may be
@@ -552,7 +1082,11 @@ type Worker interface {
 	// active.
 	Active() (bool, error)
 
-	// Deactivate immediately sets this worker to inactive.
+	// Deactivate immediately sets this worker to inactive.  It
+	// does not deactivate this worker's children.  This worker's
+	// pending attempts in work specs with ExpireWithWorker set
+	// expire at once, with no grace period; its other attempts
+	// are unaffected and run until their own expiration times.
 	Deactivate() error
 
 	// Mode gets the mode reported in the last call to Update().
@@ -572,7 +1106,9 @@ type Worker interface {
 	// Update refreshes this worker's data.  The worker is set to
 	// active.  The data, current and expiration times, and modes
 	// are recorded for future calls to Data(), LastUpdate(),
-	// Expiration(), and Mode(), respectively.
+	// Expiration(), and Mode(), respectively.  Pending attempts
+	// in work specs with a non-zero HeartbeatExtension are also
+	// extended.
 	Update(data map[string]interface{}, now, expiration time.Time, mode string) error
 
 	// RequestAttempts tries to allocate new work to this worker.
@@ -590,7 +1126,9 @@ type Worker interface {
 	// MakeAttempt creates an attempt for a specific work unit.
 	// On success the new attempt is added to the current and
 	// historic attempts for this worker, and becomes the active
-	// attempt for the work unit.
+	// attempt for the work unit.  The attempt lasts for the
+	// given duration; if that is zero, for the work spec's
+	// DefaultLease, or if that is also zero, 15 minutes.
 	//
 	// This method is principally intended for testing and
 	// debugging.  It should not be used to resurrect an attempt
@@ -598,7 +1136,9 @@ type Worker interface {
 	// outcome of this is two workers fighting over the same unit
 	// of work.  This will not check the state of the work unit,
 	// and could restart a work unit that otherwise is in a
-	// terminal state.
+	// terminal state.  The memory backend returns
+	// ErrWrongNamespace if the work unit is in a different
+	// namespace from the worker.
 	MakeAttempt(WorkUnit, time.Duration) (Attempt, error)
 
 	// ActiveAttempts returns all Attempts this worker is
@@ -608,12 +1148,71 @@ type Worker interface {
 
 	// AllAttempts returns all Attempts this worker has ever
 	// performed, including those returned in ActiveAttempts().
+	// Archived attempts are not included.
 	AllAttempts() ([]Attempt, error)
 
+	// AttemptsInWindow returns the Attempts this worker has
+	// started at or after start, and strictly before end.  If
+	// either time is zero, that side of the window is
+	// unbounded.  This is a subset of AllAttempts().
+	AttemptsInWindow(start, end time.Time) ([]Attempt, error)
+
 	// ChildAttempts returns any attempts this worker's
 	// children are performing.  It is similar to calling
 	// ActiveAttempt on each of Children, but is atomic.
 	ChildAttempts() ([]Attempt, error)
+
+	// FinishAttempts calls Attempt.Finish() on each of attempts
+	// as a single operation.  data is either nil or has the same
+	// length as attempts, and holds the data to pass for the
+	// corresponding attempt; otherwise this returns
+	// ErrBatchLength.  Every attempt must belong to this worker,
+	// or this returns ErrWrongWorker.  If any attempt could not
+	// be finished on its own, because it is not pending, this
+	// returns ErrNotPending and finishes none of them.  If the
+	// coordinate has strict completion enabled, attempts that
+	// lost their lease are expired, the others are finished,
+	// and this returns ErrLostLease.
+	FinishAttempts(attempts []Attempt, data []map[string]interface{}) error
+
+	// FailAttempts calls Attempt.Fail() on each of attempts as a
+	// single operation, with the same rules as FinishAttempts().
+	FailAttempts(attempts []Attempt, data []map[string]interface{}) error
+
+	// RetryAttempts calls Attempt.Retry() with delay on each of
+	// attempts as a single operation, with the same rules as
+	// FinishAttempts().
+	RetryAttempts(attempts []Attempt, data []map[string]interface{}, delay time.Duration) error
+
+	// CompleteAttempts finishes, fails, or retries several
+	// attempts, each with its own outcome, in a single call.
+	// Unlike FinishAttempts(), each attempt is completed on its
+	// own.  The returned slice has one entry per outcome: nil if
+	// that attempt was completed, or else the error that
+	// Attempt.Finish(), Attempt.Fail(), or Attempt.Retry() would
+	// have returned.  An attempt that belongs to a different
+	// worker gets ErrWrongWorker, and an outcome with a nil
+	// Attempt or any other Status gets ErrBadOutcome.  The error
+	// return reports a failure of the call as a whole.
+	CompleteAttempts(outcomes []AttemptOutcome) ([]error, error)
+}
+
+// AttemptOutcome describes how to complete one attempt in a call to
+// Worker.CompleteAttempts().
+type AttemptOutcome struct {
+	// Attempt is the attempt to complete.
+	Attempt Attempt
+
+	// Status is the new status of the attempt, one of Finished,
+	// Failed, or Retryable.
+	Status AttemptStatus
+
+	// Data, if non-nil, replaces the attempt's data.
+	Data map[string]interface{}
+
+	// Delay is the retry delay, as for Attempt.Retry(), if
+	// Status is Retryable.
+	Delay time.Duration
 }
 
 // AttemptStatus is a brief representation of the current status of
@@ -645,10 +1244,37 @@ const (
 	Retryable
 )
 
+// TerminalAttemptStatuses returns the statuses of attempts that have
+// ended for good, Finished and Failed.  Expired and Retryable
+// attempts are part of a work unit's retry history, and count against
+// its work spec's MaxRetries.
+func TerminalAttemptStatuses() []AttemptStatus {
+	return []AttemptStatus{Finished, Failed}
+}
+
+// DataSnapshot is a single entry in an attempt's data history.
+type DataSnapshot struct {
+	// Time is the time the data was recorded.
+	Time time.Time `json:"time"`
+
+	// Data is the attempt data as of Time.
+	Data map[string]interface{} `json:"data"`
+}
+
+// MaxAttemptLogLines is the number of log lines kept for each
+// attempt; see Attempt.AppendLog().  Once an attempt has this many
+// lines, appending another discards the oldest.
+const MaxAttemptLogLines = 1000
+
 // An Attempt is a persistent record that some worker is attempting to
 // complete some specific work unit.  It has its own copy of the work
 // unit data.
 type Attempt interface {
+	// ID returns an opaque string that uniquely identifies this
+	// attempt within its work unit.  It does not change over the
+	// lifetime of the attempt, including if it is archived.
+	ID() string
+
 	// WorkUnit returns the work unit that is being attempted.
 	WorkUnit() WorkUnit
 
@@ -689,12 +1315,39 @@ type Attempt interface {
 	// any affect.  If it is not, the Attempt data will still be
 	// updated, but Renew() will return ErrLostLease.
 	//
+	// If the work spec has a non-zero MaxLeaseTotal, the new
+	// expiration time will be no later than that long after the
+	// attempt's start time.  If that much time has already
+	// passed, the Attempt is expired, and Renew() returns
+	// ErrLeaseTotalExceeded.
+	//
 	// The Status() of this Attempt must be Pending for Renew()
 	// to have any affect.  If it is Expired but still is the
 	// active Attempt, it can also be Renew()ed.  Otherwise, do
 	// not update anything and return ErrNotPending.
 	Renew(extendDuration time.Duration, data map[string]interface{}) error
 
+	// DataHistory returns the data passed to each call to Renew()
+	// on this Attempt, oldest first.  The backend only keeps
+	// this history if it has been enabled with
+	// DataHistorySetter.SetDataHistory(), and only keeps as many
+	// snapshots as configured there; otherwise this returns an
+	// empty slice.  Calls to Renew() with nil data are not
+	// recorded.
+	DataHistory() ([]DataSnapshot, error)
+
+	// AppendLog adds a line of output to this Attempt's log, so
+	// that operators can follow a running attempt.  Only the
+	// most recent MaxAttemptLogLines lines are kept.  If this
+	// Attempt is not pending, does not change anything and
+	// returns ErrNotPending.
+	AppendLog(line string) error
+
+	// Logs returns the lines passed to AppendLog() on this
+	// Attempt, oldest first, or an empty slice if there are
+	// none.
+	Logs() ([]string, error)
+
 	// Expire explicitly transitions an Attempt from Pending to
 	// Expired status.  If data is non-nil, also updates the work
 	// unit data.  If Status() is already Expired, has no effect.
@@ -709,6 +1362,21 @@ type Attempt interface {
 	// does nothing and returns ErrNotPending.
 	Expire(data map[string]interface{}) error
 
+	// Release gives up a Pending Attempt without it counting as
+	// a try.  The Attempt moves to Expired status and its work
+	// unit becomes available again immediately: the work spec's
+	// RetryDelays do not apply, and the Attempt does not count
+	// towards its MaxRetries.  If data is non-nil, also updates
+	// the work unit data.
+	//
+	// This method is intended for a worker that gives back work
+	// it never really started, for instance because it is
+	// shutting down or the work was only reserved.
+	//
+	// If the Status() of this Attempt is not Pending, does
+	// nothing and returns ErrNotPending.
+	Release(data map[string]interface{}) error
+
 	// Finish transitions an Attempt from Pending to Finished
 	// status.  If data is non-nil, also updates the work unit
 	// data.
@@ -718,6 +1386,33 @@ type Attempt interface {
 	// ErrNotPending and has no effect.
 	Finish(data map[string]interface{}) error
 
+	// PrepareFinish records that the worker has done the work
+	// for this Attempt and is about to call Finish().  If data
+	// is non-nil, it replaces the Attempt data, so the results
+	// are saved before the Attempt is finished.  The Attempt
+	// stays Pending, and FinishPrepared() becomes true.
+	//
+	// A worker that crashes and restarts can find its old
+	// Attempts with Worker.ActiveAttempts().  If one is still
+	// Pending and FinishPrepared() is true, the work was done,
+	// and the worker should call Finish() instead of running the
+	// work unit again; if it is Finished, the earlier Finish()
+	// took effect.
+	//
+	// This does not stop the Attempt from expiring between the
+	// two calls, in which case another worker may run the work
+	// unit again.  Workers using this should keep renewing their
+	// Attempts until Finish() succeeds.
+	//
+	// As with Finish(), the Attempt must be Pending, or Expired
+	// but still its work unit's active Attempt; otherwise this
+	// returns ErrNotPending and has no effect.
+	PrepareFinish(data map[string]interface{}) error
+
+	// FinishPrepared returns whether PrepareFinish() has been
+	// called on this Attempt.
+	FinishPrepared() (bool, error)
+
 	// Fail transitions an Attempt from Pending to Failed status.
 	// If data is non-nil, also updates the work unit data.
 	//
@@ -731,6 +1426,11 @@ type Attempt interface {
 	// data.  If delay is non-zero, sets the work unit to not
 	// be allowed to restart until this time has passed.
 	//
+	// If the work unit already has as many attempts as its work
+	// spec's MaxRetries allows, the Attempt instead becomes
+	// Failed, with the same "too many retries" data that
+	// Worker.RequestAttempts() would record, and data is ignored.
+	//
 	// If the Status() of this attempt is not Pending, or if it
 	// is not both Expired and the current active Attempt, returns
 	// ErrNotPending and has no effect.
@@ -4,6 +4,7 @@
 package coordinatetest
 
 import (
+	"fmt"
 	"github.com/diffeo/go-coordinate/cborrpc"
 	"github.com/diffeo/go-coordinate/coordinate"
 	"reflect"
@@ -510,6 +511,112 @@ func (s *Suite) TestChainingExpiry() {
 	}
 }
 
+// inactiveAttempts sets up a work unit whose first attempt has been
+// superseded by a second, active attempt, for each of the ways to
+// complete the first attempt, singly and in batches.  It calls f with the inactive attempt,
+// a function that completes it, and the active attempt.
+func (s *Suite) inactiveAttempts(name string, f func(inactive, active coordinate.Attempt, complete func() error)) {
+	for _, how := range []string{"finish", "fail", "retry", "finish_batch", "complete_batch"} {
+		sts := SimpleTestSetup{
+			NamespaceName: name + "_" + how,
+			WorkerName:    "worker",
+			WorkSpecName:  "spec",
+			WorkUnitName:  "unit",
+		}
+		sts.SetUp(s)
+
+		inactive := sts.RequestOneAttempt(s)
+		s.NoError(sts.WorkUnit.ClearActiveAttempt())
+		s.Clock.Add(time.Second)
+		active := sts.RequestOneAttempt(s)
+
+		data := map[string]interface{}{"how": how}
+		complete := func() error {
+			switch how {
+			case "finish":
+				return inactive.Finish(data)
+			case "fail":
+				return inactive.Fail(data)
+			case "retry":
+				return inactive.Retry(data, time.Hour)
+			case "finish_batch":
+				return sts.Worker.FinishAttempts([]coordinate.Attempt{inactive}, []map[string]interface{}{data})
+			default:
+				errs, err := sts.Worker.CompleteAttempts([]coordinate.AttemptOutcome{{
+					Attempt: inactive,
+					Status:  coordinate.Retryable,
+					Data:    data,
+					Delay:   time.Hour,
+				}})
+				if err == nil {
+					err = errs[0]
+				}
+				return err
+			}
+		}
+		f(inactive, active, complete)
+
+		// In every case the active attempt is untouched
+		sts.CheckUnitStatus(s, coordinate.PendingUnit)
+		attempt, err := sts.WorkUnit.ActiveAttempt()
+		if s.NoError(err) && s.NotNil(attempt) {
+			s.Equal(active.ID(), attempt.ID())
+		}
+		status, err := active.Status()
+		if s.NoError(err) {
+			s.Equal(coordinate.Pending, status)
+		}
+
+		sts.TearDown(s)
+	}
+}
+
+// TestCompleteInactive tests that, by default, completing an attempt
+// that is no longer active records its status and data but has no
+// other effect.
+func (s *Suite) TestCompleteInactive() {
+	s.inactiveAttempts("TestCompleteInactive", func(inactive, active coordinate.Attempt, complete func() error) {
+		s.NoError(complete())
+		status, err := inactive.Status()
+		if s.NoError(err) {
+			s.Contains([]coordinate.AttemptStatus{
+				coordinate.Finished,
+				coordinate.Failed,
+				coordinate.Retryable,
+			}, status)
+		}
+		data, err := inactive.Data()
+		if s.NoError(err) {
+			s.Contains(data, "how")
+		}
+	})
+}
+
+// TestCompleteInactiveStrict tests that, with strict completion,
+// completing an attempt that is no longer active expires it and
+// returns ErrLostLease.
+func (s *Suite) TestCompleteInactiveStrict() {
+	if !coordinate.Supports(s.Coordinate, coordinate.FeatureStrictCompletion) {
+		s.T().Skip("backend does not support strict completion")
+		return
+	}
+	setter := s.Coordinate.(coordinate.StrictCompletionSetter)
+	setter.SetStrictCompletion(true)
+	defer setter.SetStrictCompletion(false)
+
+	s.inactiveAttempts("TestCompleteInactiveStrict", func(inactive, active coordinate.Attempt, complete func() error) {
+		s.Equal(coordinate.ErrLostLease, complete())
+		status, err := inactive.Status()
+		if s.NoError(err) {
+			s.Equal(coordinate.Expired, status)
+		}
+		data, err := inactive.Data()
+		if s.NoError(err) {
+			s.Contains(data, "how")
+		}
+	})
+}
+
 // TestChainingDuplicate tests that work unit chaining still works
 // even when the same output work unit is generated twice (it should
 // get retried).
@@ -632,217 +739,1646 @@ func (s *Suite) TestAttemptExpiration() {
 	s.AttemptStatus(coordinate.Pending, attempt)
 }
 
-// TestRetryDelay verifies that the delay option on the Retry() call works.
-func (s *Suite) TestRetryDelay() {
+// TestExpireWithWorker validates that, when a work spec sets
+// "expire_with_worker", attempts expire as soon as their worker dies
+// rather than at the end of their lease.
+func (s *Suite) TestExpireWithWorker() {
 	sts := SimpleTestSetup{
-		NamespaceName: "TestRetryDelay",
+		NamespaceName: "TestExpireWithWorker",
 		WorkerName:    "worker",
 		WorkSpecName:  "spec",
-		WorkUnitName:  "unit",
+		WorkSpecData: map[string]interface{}{
+			"expire_with_worker": true,
+		},
+		WorkUnitName: "a",
 	}
 	sts.SetUp(s)
 	defer sts.TearDown(s)
 
-	sts.CheckUnitStatus(s, coordinate.AvailableUnit)
+	meta, err := sts.WorkSpec.Meta(false)
+	if s.NoError(err) {
+		s.True(meta.ExpireWithWorker)
+	}
 
-	attempt := sts.RequestOneAttempt(s)
-	err := attempt.Retry(nil, 90*time.Second)
-	s.NoError(err)
+	// A work spec without the flag, for comparison
+	other, err := sts.Namespace.SetWorkSpec(map[string]interface{}{
+		"name": "other",
+	})
+	if !s.NoError(err) {
+		return
+	}
+	otherUnit, err := other.AddWorkUnit("b", map[string]interface{}{}, coordinate.WorkUnitMeta{})
+	if !s.NoError(err) {
+		return
+	}
 
-	s.Clock.Add(60 * time.Second)
-	sts.CheckUnitStatus(s, coordinate.DelayedUnit)
-	sts.RequestNoAttempts(s)
+	// Get hour-long attempts on both work units
+	attempt, err := sts.Worker.MakeAttempt(sts.WorkUnit, time.Hour)
+	if !s.NoError(err) {
+		return
+	}
+	otherAttempt, err := sts.Worker.MakeAttempt(otherUnit, time.Hour)
+	if !s.NoError(err) {
+		return
+	}
 
-	s.Clock.Add(60 * time.Second)
+	// While the worker is alive nothing happens
+	s.Clock.Add(5 * time.Minute)
+	s.AttemptStatus(coordinate.Pending, attempt)
+	s.AttemptStatus(coordinate.Pending, otherAttempt)
+
+	// Once the worker is deactivated, only the flagged work
+	// spec's attempt expires
+	err = sts.Worker.Deactivate()
+	if !s.NoError(err) {
+		return
+	}
+	s.AttemptStatus(coordinate.Expired, attempt)
+	s.AttemptStatus(coordinate.Pending, otherAttempt)
+	sts.CheckUnitStatus(s, coordinate.AvailableUnit)
+
+	// A worker that stops checking in also counts as dead;
+	// workers get a default 15-minute expiration
+	worker, err := sts.Namespace.Worker("other worker")
+	if !s.NoError(err) {
+		return
+	}
+	attempt, err = worker.MakeAttempt(sts.WorkUnit, time.Hour)
+	if !s.NoError(err) {
+		return
+	}
+	s.Clock.Add(10 * time.Minute)
+	s.AttemptStatus(coordinate.Pending, attempt)
+	s.Clock.Add(10 * time.Minute)
+	s.AttemptStatus(coordinate.Expired, attempt)
+	s.AttemptStatus(coordinate.Pending, otherAttempt)
 	sts.CheckUnitStatus(s, coordinate.AvailableUnit)
-	sts.CheckWorkUnitOrder(s, "unit")
 }
 
-// TestAttemptFractionalStart verifies that an attempt that starts at
-// a non-integral time (as most of them are) can find itself.  This is
-// a regression test for a specific issue in restclient.
-func (s *Suite) TestAttemptFractionalStart() {
+// TestMaxLeaseTotal validates that "max_lease_total" limits how long
+// an attempt can be renewed.
+func (s *Suite) TestMaxLeaseTotal() {
 	sts := SimpleTestSetup{
-		NamespaceName: "TestAttemptFractionalStart",
+		NamespaceName: "TestMaxLeaseTotal",
 		WorkerName:    "worker",
 		WorkSpecName:  "spec",
-		WorkUnitName:  "unit",
+		WorkSpecData: map[string]interface{}{
+			"max_lease_total": 3600,
+		},
+		WorkUnitName: "a",
 	}
 	sts.SetUp(s)
 	defer sts.TearDown(s)
 
-	s.Clock.Add(500 * time.Millisecond)
+	meta, err := sts.WorkSpec.Meta(false)
+	if s.NoError(err) {
+		s.Equal(1*time.Hour, meta.MaxLeaseTotal)
+	}
 
+	start := s.Clock.Now()
 	attempt := sts.RequestOneAttempt(s)
-	// Since restclient uses the start time as one of its keys to
-	// look up attempts, but the code uses two different ways to
-	// format it, having a fractional second means that this call
-	// will fail when restserver can't find the attempt.  Actually
-	// the call above that adds half a second to the mock clock ruins
-	// a lot of tests without the bug fix since *none* of them can
-	// find their own attempts.
-	err := attempt.Finish(nil)
+
+	// Renewing within the limit works normally
+	for i := 0; i < 4; i++ {
+		s.Clock.Add(10 * time.Minute)
+		err = attempt.Renew(15*time.Minute, nil)
+		s.NoError(err)
+	}
+	expirationTime, err := attempt.ExpirationTime()
+	if s.NoError(err) {
+		s.WithinDuration(start.Add(55*time.Minute), expirationTime, 1*time.Millisecond)
+	}
+
+	// Renewing close to the limit only extends up to it
+	s.Clock.Add(10 * time.Minute)
+	err = attempt.Renew(15*time.Minute, nil)
 	s.NoError(err)
+	expirationTime, err = attempt.ExpirationTime()
+	if s.NoError(err) {
+		s.WithinDuration(start.Add(1*time.Hour), expirationTime, 1*time.Millisecond)
+	}
+	s.AttemptStatus(coordinate.Pending, attempt)
+
+	// Renewing at the limit fails and gives up the work unit
+	s.Clock.Add(10 * time.Minute)
+	err = attempt.Renew(15*time.Minute, nil)
+	s.Equal(coordinate.ErrLeaseTotalExceeded, err)
+	s.AttemptStatus(coordinate.Expired, attempt)
+	sts.CheckUnitStatus(s, coordinate.AvailableUnit)
+
+	// Someone else can pick it up
+	attempt = sts.RequestOneAttempt(s)
+	s.Equal("a", attempt.WorkUnit().Name())
 }
 
-// TestAttemptGone verifies that, if a work unit is deleted, its
-// attempts return ErrGone for things.
-func (s *Suite) TestAttemptGone() {
+// TestMaxLeaseTotalInitial validates that "max_lease_total" also
+// limits the lease an attempt starts with.
+func (s *Suite) TestMaxLeaseTotalInitial() {
 	sts := SimpleTestSetup{
-		NamespaceName: "TestAttemptGone",
+		NamespaceName: "TestMaxLeaseTotalInitial",
 		WorkerName:    "worker",
 		WorkSpecName:  "spec",
-		WorkUnitName:  "unit",
+		WorkSpecData: map[string]interface{}{
+			"max_lease_total": 3600,
+		},
+		WorkUnitName: "a",
 	}
 	sts.SetUp(s)
 	defer sts.TearDown(s)
 
-	attempt := sts.RequestOneAttempt(s)
+	start := s.Clock.Now()
+	attempts, err := sts.Worker.RequestAttempts(coordinate.AttemptRequest{
+		Lifetime: 2 * time.Hour,
+	})
+	if !(s.NoError(err) && s.Len(attempts, 1)) {
+		return
+	}
+	expirationTime, err := attempts[0].ExpirationTime()
+	if s.NoError(err) {
+		s.WithinDuration(start.Add(1*time.Hour), expirationTime, 1*time.Millisecond)
+	}
+	err = attempts[0].Release(nil)
+	if !s.NoError(err) {
+		return
+	}
 
-	_, err := sts.WorkSpec.DeleteWorkUnits(coordinate.WorkUnitQuery{})
-	s.NoError(err)
+	attempt, err := sts.Worker.MakeAttempt(sts.WorkUnit, 3*time.Hour)
+	if !s.NoError(err) {
+		return
+	}
+	expirationTime, err = attempt.ExpirationTime()
+	if s.NoError(err) {
+		s.WithinDuration(start.Add(1*time.Hour), expirationTime, 1*time.Millisecond)
+	}
+	err = attempt.Release(nil)
+	if !s.NoError(err) {
+		return
+	}
 
-	_, err = attempt.Status()
-	if err == coordinate.ErrGone {
-		// okay
-	} else if nswu, ok := err.(coordinate.ErrNoSuchWorkUnit); ok {
-		s.Equal(sts.WorkUnitName, nswu.Name)
-	} else if nsws, ok := err.(coordinate.ErrNoSuchWorkSpec); ok {
-		s.Equal(sts.WorkSpecName, nsws.Name)
-	} else {
-		s.Fail("unexpected error deleting work spec",
-			"%+v", err)
+	// A shorter lease is unaffected
+	attempt, err = sts.Worker.MakeAttempt(sts.WorkUnit, 30*time.Minute)
+	if !s.NoError(err) {
+		return
+	}
+	expirationTime, err = attempt.ExpirationTime()
+	if s.NoError(err) {
+		s.WithinDuration(start.Add(30*time.Minute), expirationTime, 1*time.Millisecond)
 	}
 }
 
-// TestMaxRetries is a simple test for the max_retries work spec
-// option.
-func (s *Suite) TestMaxRetries() {
+// TestHeartbeatExtension validates that a worker's updates keep its
+// attempts alive when the work spec has "heartbeat_extension" set,
+// while a worker that stays silent loses its attempts.
+func (s *Suite) TestHeartbeatExtension() {
 	sts := SimpleTestSetup{
-		NamespaceName: "TestMaxRetries",
-		WorkerName:    "worker",
+		NamespaceName: "TestHeartbeatExtension",
+		WorkerName:    "heartbeat",
 		WorkSpecName:  "spec",
 		WorkSpecData: map[string]interface{}{
-			"max_retries": 2,
+			"heartbeat_extension": 600,
 		},
-		WorkUnitName: "unit",
 	}
 	sts.SetUp(s)
 	defer sts.TearDown(s)
 
-	sts.RequestOneAttempt(s)
+	meta, err := sts.WorkSpec.Meta(false)
+	if s.NoError(err) {
+		s.Equal(10*time.Minute, meta.HeartbeatExtension)
+	}
 
-	// Force the clock far enough ahead that the attempt has expired.
-	// We should get an attempt for the same work unit again.
-	s.Clock.Add(1 * time.Hour)
-	sts.RequestOneAttempt(s)
+	for _, name := range []string{"a", "b"} {
+		_, err = sts.AddWorkUnit(name)
+		s.NoError(err)
+	}
+	silent, err := sts.Namespace.Worker("silent")
+	if !s.NoError(err) {
+		return
+	}
 
-	// Force the clock ahead again, and get an attempt.  Since
-	// we exceed max_retries we should not get an attempt.
-	s.Clock.Add(1 * time.Hour)
-	sts.RequestNoAttempts(s)
-	sts.CheckUnitStatus(s, coordinate.FailedUnit)
-	data, err := sts.WorkUnit.Data()
+	request := coordinate.AttemptRequest{Lifetime: 5 * time.Minute}
+	attempts, err := sts.Worker.RequestAttempts(request)
+	if !(s.NoError(err) && s.Len(attempts, 1)) {
+		return
+	}
+	alive := attempts[0]
+	attempts, err = silent.RequestAttempts(request)
+	if !(s.NoError(err) && s.Len(attempts, 1)) {
+		return
+	}
+	dead := attempts[0]
+
+	// Heartbeat a few times, well past the original lifetime
+	for i := 0; i < 4; i++ {
+		s.Clock.Add(4 * time.Minute)
+		now := s.Clock.Now()
+		err = sts.Worker.Update(map[string]interface{}{}, now, now.Add(15*time.Minute), "run")
+		s.NoError(err)
+	}
+
+	expiration, err := alive.ExpirationTime()
 	if s.NoError(err) {
-		s.Equal("too many retries", data["traceback"])
+		s.WithinDuration(s.Clock.Now().Add(10*time.Minute), expiration, 1*time.Millisecond)
 	}
+	s.AttemptStatus(coordinate.Pending, alive)
+	s.AttemptStatus(coordinate.Expired, dead)
 }
 
-// TestMaxRetriesMulti tests both setting max_retries and max_getwork.
-func (s *Suite) TestMaxRetriesMulti() {
+// TestPrepareFinish runs the two-phase PrepareFinish() and Finish()
+// sequence, with a simulated worker crash between the two calls.
+func (s *Suite) TestPrepareFinish() {
 	sts := SimpleTestSetup{
-		NamespaceName: "TestMaxRetries",
+		NamespaceName: "TestPrepareFinish",
 		WorkerName:    "worker",
 		WorkSpecName:  "spec",
-		WorkSpecData: map[string]interface{}{
-			"max_getwork": 2,
-			"max_retries": 1,
-		},
+		WorkUnitName:  "unit",
 	}
 	sts.SetUp(s)
 	defer sts.TearDown(s)
 
-	for _, name := range []string{"a", "b", "c", "d"} {
-		_, err := sts.AddWorkUnit(name)
-		if !s.NoError(err) {
-			return
-		}
+	attempt := sts.RequestOneAttempt(s)
+	prepared, err := attempt.FinishPrepared()
+	if s.NoError(err) {
+		s.False(prepared)
 	}
 
-	// Now we should be able to request work and get both a and b
-	req := coordinate.AttemptRequest{
-		NumberOfWorkUnits: 10,
+	result := map[string]interface{}{"result": "done"}
+	err = attempt.PrepareFinish(result)
+	s.NoError(err)
+	s.AttemptStatus(coordinate.Pending, attempt)
+
+	// The worker "crashes" here.  When it comes back, it finds
+	// its attempt again from scratch.
+	worker, err := sts.Namespace.Worker(sts.WorkerName)
+	if !s.NoError(err) {
+		return
 	}
-	attempts, err := sts.Worker.RequestAttempts(req)
+	attempts, err := worker.ActiveAttempts()
+	if !(s.NoError(err) && s.Len(attempts, 1)) {
+		return
+	}
+	recovered := attempts[0]
+	s.AttemptStatus(coordinate.Pending, recovered)
+	prepared, err = recovered.FinishPrepared()
 	if s.NoError(err) {
-		if s.Len(attempts, 2) {
-			s.Equal("a", attempts[0].WorkUnit().Name())
-			s.Equal("b", attempts[1].WorkUnit().Name())
-		}
+		s.True(prepared)
+	}
+	data, err := recovered.Data()
+	if s.NoError(err) {
+		s.Equal("done", data["result"])
 	}
 
-	// Let the first work unit finish and the second time out
-	s.Clock.Add(1 * time.Minute)
-	err = attempts[0].Finish(nil)
+	// Since the work was done, finish rather than rerunning it
+	err = recovered.Finish(nil)
 	s.NoError(err)
+	s.AttemptStatus(coordinate.Finished, recovered)
 
-	s.Clock.Add(1 * time.Hour)
-
-	// Now get two more work units.  We expect the system to find
-	// b and c, notice that b is expired, and just return c.
-	attempts, err = sts.Worker.RequestAttempts(req)
-	if s.NoError(err) {
-		if s.Len(attempts, 1) {
-			s.Equal("c", attempts[0].WorkUnit().Name())
-		}
-	}
+	err = recovered.PrepareFinish(nil)
+	s.Equal(coordinate.ErrNotPending, err)
 }
 
-// TestMaxRetriesMultiBatch is like TestMaxRetriesMulti, but has an
-// entire batch go over the retry limit.
-func (s *Suite) TestMaxRetriesMultiBatch() {
+// TestPrepareFinishTransitions checks which attempts can be prepared
+// to finish, and that a prepared attempt can still end some other way.
+func (s *Suite) TestPrepareFinishTransitions() {
 	sts := SimpleTestSetup{
-		NamespaceName: "TestMaxRetries",
+		NamespaceName: "TestPrepareFinishTransitions",
 		WorkerName:    "worker",
 		WorkSpecName:  "spec",
-		WorkSpecData: map[string]interface{}{
-			"max_getwork": 2,
-			"max_retries": 1,
-		},
 	}
 	sts.SetUp(s)
 	defer sts.TearDown(s)
+	changed := map[string]interface{}{"changed": true}
 
-	for _, name := range []string{"a", "b", "c", "d"} {
-		_, err := sts.AddWorkUnit(name)
+	start := func(name string) coordinate.Attempt {
+		unit, err := sts.AddWorkUnit(name)
 		if !s.NoError(err) {
-			return
+			s.FailNow("could not create work unit")
 		}
+		attempt, err := sts.Worker.MakeAttempt(unit, 0)
+		if !s.NoError(err) {
+			s.FailNow("could not create attempt")
+		}
+		return attempt
 	}
 
-	// Now we should be able to request work and get both a and b
-	req := coordinate.AttemptRequest{
-		NumberOfWorkUnits: 10,
+	// Completed attempts cannot be prepared, and are unchanged
+	for name, complete := range map[string]func(coordinate.Attempt) error{
+		"finished": func(a coordinate.Attempt) error { return a.Finish(nil) },
+		"failed":   func(a coordinate.Attempt) error { return a.Fail(nil) },
+		"retried":  func(a coordinate.Attempt) error { return a.Retry(nil, 0) },
+		"expired":  func(a coordinate.Attempt) error { return a.Expire(nil) },
+	} {
+		attempt := start(name)
+		s.NoError(complete(attempt), name)
+		s.Equal(coordinate.ErrNotPending, attempt.PrepareFinish(changed), name)
+		prepared, err := attempt.FinishPrepared()
+		if s.NoError(err, name) {
+			s.False(prepared, name)
+		}
+		s.DataEmpty(attempt)
 	}
-	attempts, err := sts.Worker.RequestAttempts(req)
+
+	// Preparing without data keeps the existing data
+	kept := start("kept")
+	s.NoError(kept.Renew(time.Hour, changed))
+	s.NoError(kept.PrepareFinish(nil))
+	s.DataMatches(kept, changed)
+
+	// A prepared attempt can still fail, and stays prepared
+	abandoned := start("abandoned")
+	s.NoError(abandoned.PrepareFinish(changed))
+	s.NoError(abandoned.Fail(nil))
+	s.AttemptStatus(coordinate.Failed, abandoned)
+	prepared, err := abandoned.FinishPrepared()
 	if s.NoError(err) {
-		if s.Len(attempts, 2) {
-			s.Equal("a", attempts[0].WorkUnit().Name())
-			s.Equal("b", attempts[1].WorkUnit().Name())
-		}
+		s.True(prepared)
 	}
+}
 
-	// Let both work units time out
-	s.Clock.Add(1 * time.Hour)
+// TestAttemptTransitions checks that attempts that are no longer
+// pending cannot change status, except that a failed attempt can
+// still be finished.
+func (s *Suite) TestAttemptTransitions() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestAttemptTransitions",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+	changed := map[string]interface{}{"changed": true}
 
-	// Now get two more work units.  We expect the system to find
-	// a and b, see both are expired, find a new batch, and get
-	// both c and d
-	attempts, err = sts.Worker.RequestAttempts(req)
-	if s.NoError(err) {
-		if s.Len(attempts, 2) {
-			s.Equal("c", attempts[0].WorkUnit().Name())
+	start := func(name string) coordinate.Attempt {
+		unit, err := sts.AddWorkUnit(name)
+		if !s.NoError(err) {
+			return nil
+		}
+		attempt, err := sts.Worker.MakeAttempt(unit, 0)
+		if !s.NoError(err) {
+			return nil
+		}
+		return attempt
+	}
+
+	finished := start("finished")
+	if finished == nil {
+		return
+	}
+	s.NoError(finished.Finish(nil))
+	s.Equal(coordinate.ErrNotPending, finished.Finish(changed))
+	s.Equal(coordinate.ErrNotPending, finished.Fail(changed))
+	s.Equal(coordinate.ErrNotPending, finished.Retry(changed, 0))
+	s.Equal(coordinate.ErrNotPending, finished.Expire(changed))
+	s.Equal(coordinate.ErrNotPending, finished.Renew(time.Hour, changed))
+	s.AttemptStatus(coordinate.Finished, finished)
+	s.DataEmpty(finished)
+
+	failed := start("failed")
+	if failed == nil {
+		return
+	}
+	s.NoError(failed.Fail(nil))
+	s.Equal(coordinate.ErrNotPending, failed.Fail(changed))
+	s.Equal(coordinate.ErrNotPending, failed.Retry(changed, 0))
+	s.Equal(coordinate.ErrNotPending, failed.Expire(changed))
+	s.Equal(coordinate.ErrNotPending, failed.Renew(time.Hour, changed))
+	s.AttemptStatus(coordinate.Failed, failed)
+	s.DataEmpty(failed)
+	s.NoError(failed.Finish(nil))
+	s.AttemptStatus(coordinate.Finished, failed)
+
+	retried := start("retried")
+	if retried == nil {
+		return
+	}
+	s.NoError(retried.Retry(nil, 0))
+	s.Equal(coordinate.ErrNotPending, retried.Finish(changed))
+	s.Equal(coordinate.ErrNotPending, retried.Expire(changed))
+	s.Equal(coordinate.ErrNotPending, retried.Renew(time.Hour, changed))
+	s.AttemptStatus(coordinate.Retryable, retried)
+	s.DataEmpty(retried)
+
+	// Expiring an expired attempt does nothing
+	expired := start("expired")
+	if expired == nil {
+		return
+	}
+	s.NoError(expired.Expire(nil))
+	s.NoError(expired.Expire(changed))
+	s.Equal(coordinate.ErrNotPending, expired.Finish(changed))
+	s.Equal(coordinate.ErrNotPending, expired.Fail(changed))
+	s.AttemptStatus(coordinate.Expired, expired)
+	s.DataEmpty(expired)
+}
+
+// TestDataHistory checks that the data from several renewals is
+// kept, up to the backend's configured limit.
+func (s *Suite) TestDataHistory() {
+	if s.DataHistory == 0 {
+		s.T().Skip("backend does not keep attempt data history")
+	}
+	sts := SimpleTestSetup{
+		NamespaceName: "TestDataHistory",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+		WorkUnitName:  "unit",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	attempt := sts.RequestOneAttempt(s)
+	history, err := attempt.DataHistory()
+	if s.NoError(err) {
+		s.Empty(history)
+	}
+
+	// Renew one more time than the history will hold, plus once
+	// more without data
+	renewals := s.DataHistory + 1
+	times := make([]time.Time, renewals)
+	for i := 0; i < renewals; i++ {
+		s.Clock.Add(1 * time.Minute)
+		times[i] = s.Clock.Now()
+		err = attempt.Renew(5*time.Minute, map[string]interface{}{
+			"step": i,
+		})
+		s.NoError(err)
+	}
+	s.Clock.Add(1 * time.Minute)
+	err = attempt.Renew(5*time.Minute, nil)
+	s.NoError(err)
+
+	history, err = attempt.DataHistory()
+	if s.NoError(err) && s.Len(history, s.DataHistory) {
+		for i, snapshot := range history {
+			step := i + 1
+			s.WithinDuration(times[step], snapshot.Time, 1*time.Millisecond)
+			s.EqualValues(step, snapshot.Data["step"])
+		}
+	}
+}
+
+// TestDataHistoryLostLease checks that a renewal that fails because
+// the attempt has lost its lease does not add to the data history.
+func (s *Suite) TestDataHistoryLostLease() {
+	if s.DataHistory == 0 {
+		s.T().Skip("backend does not keep attempt data history")
+	}
+	sts := SimpleTestSetup{
+		NamespaceName: "TestDataHistoryLostLease",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+		WorkUnitName:  "unit",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	attempt := sts.RequestOneAttempt(s)
+	err := sts.WorkUnit.ClearActiveAttempt()
+	if !s.NoError(err) {
+		return
+	}
+
+	err = attempt.Renew(5*time.Minute, map[string]interface{}{
+		"step": 1,
+	})
+	s.Equal(coordinate.ErrLostLease, err)
+
+	history, err := attempt.DataHistory()
+	if s.NoError(err) {
+		s.Empty(history)
+	}
+}
+
+// TestDataDiff checks coordinate.DataDiff() across the attempts of a
+// work unit that is retried and then finished.
+func (s *Suite) TestDataDiff() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestDataDiff",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+	unit, err := sts.WorkSpec.AddWorkUnit("unit", map[string]interface{}{
+		"same":    "same",
+		"changed": "original",
+	}, coordinate.WorkUnitMeta{})
+	if !s.NoError(err) {
+		return
+	}
+	other, err := sts.WorkSpec.AddWorkUnit("other", map[string]interface{}{}, coordinate.WorkUnitMeta{})
+	if !s.NoError(err) {
+		return
+	}
+
+	first, err := sts.Worker.MakeAttempt(unit, 0)
+	if !s.NoError(err) {
+		return
+	}
+	err = first.Retry(map[string]interface{}{
+		"same":    "same",
+		"changed": "first",
+		"removed": "first",
+	}, 0)
+	if !s.NoError(err) {
+		return
+	}
+	second, err := sts.Worker.MakeAttempt(unit, 0)
+	if !s.NoError(err) {
+		return
+	}
+	err = second.Finish(map[string]interface{}{
+		"same":    "same",
+		"changed": "second",
+		"added":   "second",
+	})
+	if !s.NoError(err) {
+		return
+	}
+
+	diff, err := coordinate.DataDiff(unit, first, second)
+	if s.NoError(err) {
+		s.Equal(map[string]interface{}{
+			"changed": map[string]interface{}{
+				coordinate.DataDiffOld: "first",
+				coordinate.DataDiffNew: "second",
+			},
+			"removed": map[string]interface{}{
+				coordinate.DataDiffOld: "first",
+			},
+			"added": map[string]interface{}{
+				coordinate.DataDiffNew: "second",
+			},
+		}, diff)
+	}
+
+	// Going backwards swaps old and new
+	diff, err = coordinate.DataDiff(unit, second, first)
+	if s.NoError(err) && s.Contains(diff, "added") {
+		s.Equal(map[string]interface{}{
+			coordinate.DataDiffOld: "second",
+		}, diff["added"])
+	}
+
+	diff, err = coordinate.DataDiff(unit, second, second)
+	if s.NoError(err) {
+		s.Empty(diff)
+	}
+
+	// An attempt on a different work unit is an error
+	otherAttempt, err := sts.Worker.MakeAttempt(other, 0)
+	if s.NoError(err) {
+		_, err = coordinate.DataDiff(unit, first, otherAttempt)
+		s.Equal(coordinate.ErrWrongWorkUnit, err)
+	}
+
+	// So is one on a work unit with the same name in another
+	// namespace
+	namespace, err := s.Coordinate.Namespace("TestDataDiff_other")
+	if !s.NoError(err) {
+		return
+	}
+	defer namespace.Destroy()
+	spec, err := namespace.SetWorkSpec(map[string]interface{}{"name": "spec"})
+	if !s.NoError(err) {
+		return
+	}
+	copied, err := spec.AddWorkUnit("unit", map[string]interface{}{}, coordinate.WorkUnitMeta{})
+	if !s.NoError(err) {
+		return
+	}
+	worker, err := namespace.Worker("worker")
+	if !s.NoError(err) {
+		return
+	}
+	otherAttempt, err = worker.MakeAttempt(copied, 0)
+	if s.NoError(err) {
+		_, err = coordinate.DataDiff(unit, first, otherAttempt)
+		s.Equal(coordinate.ErrWrongWorkUnit, err)
+	}
+}
+
+// TestPurgeAttempts validates that WorkSpec.PurgeAttempts() deletes
+// old completed attempts but not active or pending ones.
+func (s *Suite) TestPurgeAttempts() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestPurgeAttempts",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	units := make(map[string]coordinate.WorkUnit)
+	for _, name := range []string{"a", "b", "c"} {
+		unit, err := sts.AddWorkUnit(name)
+		if !s.NoError(err) {
+			return
+		}
+		units[name] = unit
+	}
+	makeAttempt := func(name string) coordinate.Attempt {
+		// Give each attempt a distinct start time
+		s.Clock.Add(1 * time.Second)
+		attempt, err := sts.Worker.MakeAttempt(units[name], 24*time.Hour)
+		s.NoError(err)
+		return attempt
+	}
+	checkAttempts := func(name string, expected int) {
+		attempts, err := units[name].Attempts()
+		if s.NoError(err) {
+			s.Len(attempts, expected)
+		}
+	}
+
+	// "a" fails once and then finishes; "b" expires and is
+	// retried, and that attempt is still pending
+	s.NoError(makeAttempt("a").Fail(nil))
+	s.NoError(makeAttempt("a").Finish(nil))
+	s.NoError(makeAttempt("b").Expire(nil))
+	pending := makeAttempt("b")
+
+	s.Clock.Add(1 * time.Hour)
+	cutoff := s.Clock.Now()
+
+	// "c" gets a retryable attempt, after the cutoff
+	s.Clock.Add(1 * time.Minute)
+	s.NoError(makeAttempt("c").Retry(nil, time.Duration(0)))
+
+	count, err := sts.WorkSpec.PurgeAttempts(cutoff, []coordinate.AttemptStatus{coordinate.Failed})
+	if s.NoError(err) {
+		s.Equal(1, count)
+	}
+	checkAttempts("a", 1)
+	checkAttempts("b", 2)
+	checkAttempts("c", 1)
+
+	// The remaining old attempt on "a" is its active attempt,
+	// and the only other old attempt is the expired one on "b",
+	// which is only purged when asked for explicitly
+	count, err = sts.WorkSpec.PurgeAttempts(cutoff, nil)
+	if s.NoError(err) {
+		s.Equal(0, count)
+	}
+	checkAttempts("b", 2)
+	count, err = sts.WorkSpec.PurgeAttempts(cutoff, []coordinate.AttemptStatus{coordinate.Expired})
+	if s.NoError(err) {
+		s.Equal(1, count)
+	}
+	checkAttempts("a", 1)
+	checkAttempts("b", 1)
+	checkAttempts("c", 1)
+
+	// Likewise the retryable attempt on "c"
+	s.Clock.Add(1 * time.Hour)
+	count, err = sts.WorkSpec.PurgeAttempts(s.Clock.Now(), nil)
+	if s.NoError(err) {
+		s.Equal(0, count)
+	}
+	checkAttempts("c", 1)
+	count, err = sts.WorkSpec.PurgeAttempts(s.Clock.Now(), []coordinate.AttemptStatus{coordinate.Retryable})
+	if s.NoError(err) {
+		s.Equal(1, count)
+	}
+	checkAttempts("c", 0)
+
+	// The work units are all still there, and the pending
+	// attempt is unaffected
+	unitMap, err := sts.WorkSpec.WorkUnits(coordinate.WorkUnitQuery{})
+	if s.NoError(err) {
+		s.Len(unitMap, 3)
+	}
+	status, err := units["a"].Status()
+	if s.NoError(err) {
+		s.Equal(coordinate.FinishedUnit, status)
+	}
+	s.AttemptStatus(coordinate.Pending, pending)
+	attempts, err := sts.Worker.AllAttempts()
+	if s.NoError(err) {
+		s.Len(attempts, 2)
+	}
+}
+
+// TestRetryDelay verifies that the delay option on the Retry() call works.
+func (s *Suite) TestRetryDelay() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestRetryDelay",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+		WorkUnitName:  "unit",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	sts.CheckUnitStatus(s, coordinate.AvailableUnit)
+
+	attempt := sts.RequestOneAttempt(s)
+	err := attempt.Retry(nil, 90*time.Second)
+	s.NoError(err)
+
+	s.Clock.Add(60 * time.Second)
+	sts.CheckUnitStatus(s, coordinate.DelayedUnit)
+	sts.RequestNoAttempts(s)
+
+	s.Clock.Add(60 * time.Second)
+	sts.CheckUnitStatus(s, coordinate.AvailableUnit)
+	sts.CheckWorkUnitOrder(s, "unit")
+}
+
+// TestRetryDelaySchedule verifies that a work spec's "retry_delays"
+// schedule delays successive retries by escalating amounts.
+func (s *Suite) TestRetryDelaySchedule() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestRetryDelaySchedule",
+		WorkerName:    "worker",
+		WorkSpecData: map[string]interface{}{
+			"name":         "spec",
+			"retry_delays": []interface{}{10, 60, 300},
+		},
+		WorkUnitName: "unit",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	meta, err := sts.WorkSpec.Meta(false)
+	if s.NoError(err) {
+		s.Equal([]time.Duration{
+			10 * time.Second,
+			60 * time.Second,
+			300 * time.Second,
+		}, meta.RetryDelays)
+	}
+
+	// checkDelay verifies that the work unit stays delayed for
+	// exactly delay, and then hands out its next attempt.
+	checkDelay := func(delay time.Duration) coordinate.Attempt {
+		sts.CheckUnitStatus(s, coordinate.DelayedUnit)
+		s.Clock.Add(delay - time.Second)
+		sts.CheckUnitStatus(s, coordinate.DelayedUnit)
+		sts.RequestNoAttempts(s)
+		s.Clock.Add(time.Second)
+		sts.CheckUnitStatus(s, coordinate.AvailableUnit)
+		return sts.RequestOneAttempt(s)
+	}
+
+	// The first two retries follow the schedule
+	attempt := sts.RequestOneAttempt(s)
+	s.NoError(attempt.Retry(nil, 0))
+	attempt = checkDelay(10 * time.Second)
+	s.NoError(attempt.Retry(nil, 0))
+	attempt = checkDelay(60 * time.Second)
+
+	// Expiring also counts as a retry
+	s.NoError(attempt.Expire(nil))
+	attempt = checkDelay(300 * time.Second)
+
+	// An explicit delay overrides the schedule
+	s.NoError(attempt.Retry(nil, 30*time.Second))
+	attempt = checkDelay(30 * time.Second)
+
+	// Past the end of the schedule, the last delay applies
+	s.NoError(attempt.Retry(nil, 0))
+	checkDelay(300 * time.Second)
+}
+
+// TestAttemptRelease verifies that releasing an attempt makes its
+// work unit available again right away, and does not count against
+// max_retries.
+func (s *Suite) TestAttemptRelease() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestAttemptRelease",
+		WorkerName:    "worker",
+		WorkSpecData: map[string]interface{}{
+			"name":         "spec",
+			"max_retries":  1,
+			"retry_delays": []interface{}{60},
+		},
+		WorkUnitName: "unit",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	// Releasing any number of times never fails the work unit
+	for i := 0; i < 3; i++ {
+		attempt := sts.RequestOneAttempt(s)
+		err := attempt.Release(map[string]interface{}{"released": i})
+		s.NoError(err)
+		s.AttemptStatus(coordinate.Expired, attempt)
+		s.DataMatches(attempt, map[string]interface{}{"released": i})
+		sts.CheckUnitStatus(s, coordinate.AvailableUnit)
+
+		err = attempt.Release(nil)
+		s.Equal(coordinate.ErrNotPending, err)
+	}
+
+	// Expiring still counts, and is the one try max_retries allows
+	attempt := sts.RequestOneAttempt(s)
+	s.NoError(attempt.Expire(nil))
+	sts.CheckUnitStatus(s, coordinate.DelayedUnit)
+	s.Clock.Add(60 * time.Second)
+	sts.RequestNoAttempts(s)
+	sts.CheckUnitStatus(s, coordinate.FailedUnit)
+
+	// An expired attempt cannot be released
+	err := attempt.Release(nil)
+	s.Equal(coordinate.ErrNotPending, err)
+}
+
+// TestAttemptFractionalStart verifies that an attempt that starts at
+// a non-integral time (as most of them are) can find itself.  This is
+// a regression test for a specific issue in restclient.
+func (s *Suite) TestAttemptFractionalStart() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestAttemptFractionalStart",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+		WorkUnitName:  "unit",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	s.Clock.Add(500 * time.Millisecond)
+
+	attempt := sts.RequestOneAttempt(s)
+	// restserver used to identify attempts by their start time,
+	// formatted to whole seconds, and could not find an attempt
+	// that started on a fractional second.  It now uses the
+	// attempt ID and this should just work.
+	err := attempt.Finish(nil)
+	s.NoError(err)
+}
+
+// TestAttemptID verifies that attempts have distinct, stable IDs,
+// even when the same worker attempts the same work unit twice
+// within one second.
+func (s *Suite) TestAttemptID() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestAttemptID",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+		WorkUnitName:  "unit",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	first := sts.RequestOneAttempt(s)
+	s.NotEmpty(first.ID())
+	s.NoError(first.Expire(nil))
+
+	s.Clock.Add(100 * time.Millisecond)
+	second := sts.RequestOneAttempt(s)
+	s.NotEmpty(second.ID())
+	s.NotEqual(first.ID(), second.ID())
+
+	// Operating on the second attempt must not touch the first
+	s.NoError(second.Finish(nil))
+	status, err := first.Status()
+	if s.NoError(err) {
+		s.Equal(coordinate.Expired, status)
+	}
+	status, err = second.Status()
+	if s.NoError(err) {
+		s.Equal(coordinate.Finished, status)
+	}
+
+	attempts, err := sts.WorkUnit.Attempts()
+	if s.NoError(err) {
+		ids := make([]string, len(attempts))
+		for i, attempt := range attempts {
+			ids[i] = attempt.ID()
+		}
+		s.ElementsMatch([]string{first.ID(), second.ID()}, ids)
+	}
+}
+
+// TestAttemptIDSameInstant verifies that two attempts on the same
+// work unit by the same worker, started at exactly the same time,
+// have distinct IDs and can be completed independently.
+func (s *Suite) TestAttemptIDSameInstant() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestAttemptIDSameInstant",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+		WorkUnitName:  "unit",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	first, err := sts.Worker.MakeAttempt(sts.WorkUnit, 0)
+	if !s.NoError(err) {
+		return
+	}
+	second, err := sts.Worker.MakeAttempt(sts.WorkUnit, 0)
+	if !s.NoError(err) {
+		return
+	}
+	s.NotEqual(first.ID(), second.ID())
+
+	firstStart, err := first.StartTime()
+	s.NoError(err)
+	secondStart, err := second.StartTime()
+	if s.NoError(err) {
+		s.True(firstStart.Equal(secondStart))
+	}
+
+	s.NoError(first.Fail(map[string]interface{}{"which": "first"}))
+	s.NoError(second.Finish(map[string]interface{}{"which": "second"}))
+	s.AttemptStatus(coordinate.Failed, first)
+	s.AttemptStatus(coordinate.Finished, second)
+	s.DataMatches(first, map[string]interface{}{"which": "first"})
+	s.DataMatches(second, map[string]interface{}{"which": "second"})
+	sts.CheckUnitStatus(s, coordinate.FinishedUnit)
+}
+
+// TestAttemptGone verifies that, if a work unit is deleted, its
+// attempts return ErrGone for things.
+func (s *Suite) TestAttemptGone() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestAttemptGone",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+		WorkUnitName:  "unit",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	attempt := sts.RequestOneAttempt(s)
+
+	_, err := sts.WorkSpec.DeleteWorkUnits(coordinate.WorkUnitQuery{})
+	s.NoError(err)
+
+	_, err = attempt.Status()
+	if err == coordinate.ErrGone {
+		// okay
+	} else if nswu, ok := err.(coordinate.ErrNoSuchWorkUnit); ok {
+		s.Equal(sts.WorkUnitName, nswu.Name)
+	} else if nsws, ok := err.(coordinate.ErrNoSuchWorkSpec); ok {
+		s.Equal(sts.WorkSpecName, nsws.Name)
+	} else {
+		s.Fail("unexpected error deleting work spec",
+			"%+v", err)
+	}
+}
+
+// TestMaxRetries is a simple test for the max_retries work spec
+// option.
+func (s *Suite) TestMaxRetries() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestMaxRetries",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+		WorkSpecData: map[string]interface{}{
+			"max_retries": 2,
+		},
+		WorkUnitName: "unit",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	sts.RequestOneAttempt(s)
+
+	// Force the clock far enough ahead that the attempt has expired.
+	// We should get an attempt for the same work unit again.
+	s.Clock.Add(1 * time.Hour)
+	sts.RequestOneAttempt(s)
+
+	// Force the clock ahead again, and get an attempt.  Since
+	// we exceed max_retries we should not get an attempt.
+	s.Clock.Add(1 * time.Hour)
+	sts.RequestNoAttempts(s)
+	sts.CheckUnitStatus(s, coordinate.FailedUnit)
+	data, err := sts.WorkUnit.Data()
+	if s.NoError(err) {
+		s.Equal("too many retries", data["traceback"])
+	}
+}
+
+// TestMaxRetriesFailureData verifies that a work spec's "failure_data"
+// is recorded when a work unit exceeds max_retries.
+func (s *Suite) TestMaxRetriesFailureData() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestMaxRetriesFailureData",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+		WorkSpecData: map[string]interface{}{
+			"max_retries": 1,
+			"failure_data": map[string]interface{}{
+				"error_code": 42,
+				"category":   "retries",
+			},
+		},
+		WorkUnitName: "unit",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	sts.RequestOneAttempt(s)
+	s.Clock.Add(1 * time.Hour)
+	sts.RequestNoAttempts(s)
+	sts.CheckUnitStatus(s, coordinate.FailedUnit)
+	s.DataMatches(sts.WorkUnit, map[string]interface{}{
+		"traceback":  "too many retries",
+		"error_code": 42,
+		"category":   "retries",
+	})
+}
+
+// TestMaxRetriesExplicit verifies that explicitly retrying an attempt
+// counts against max_retries, and that the retry that uses up the
+// last one fails the work unit.
+func (s *Suite) TestMaxRetriesExplicit() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestMaxRetriesExplicit",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+		WorkSpecData: map[string]interface{}{
+			"max_retries": 2,
+		},
+		WorkUnitName: "unit",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	attempt := sts.RequestOneAttempt(s)
+	err := attempt.Retry(map[string]interface{}{"try": 1}, 0)
+	s.NoError(err)
+	s.AttemptStatus(coordinate.Retryable, attempt)
+	s.DataMatches(attempt, map[string]interface{}{"try": 1})
+	sts.CheckUnitStatus(s, coordinate.AvailableUnit)
+
+	// The second attempt is the last one max_retries allows, so
+	// retrying it fails the work unit
+	attempt = sts.RequestOneAttempt(s)
+	err = attempt.Retry(map[string]interface{}{"try": 2}, 0)
+	s.NoError(err)
+	s.AttemptStatus(coordinate.Failed, attempt)
+	sts.CheckUnitStatus(s, coordinate.FailedUnit)
+	s.DataMatches(sts.WorkUnit, map[string]interface{}{
+		"traceback": "too many retries",
+	})
+
+	// Retrying it again changes nothing
+	err = attempt.Retry(map[string]interface{}{"try": 3}, 0)
+	s.Equal(coordinate.ErrNotPending, err)
+	sts.CheckUnitStatus(s, coordinate.FailedUnit)
+	s.DataMatches(sts.WorkUnit, map[string]interface{}{
+		"traceback": "too many retries",
+	})
+	sts.RequestNoAttempts(s)
+}
+
+// TestMaxRetriesRetryAttempts verifies that Worker.RetryAttempts()
+// honors max_retries as Attempt.Retry() does.
+func (s *Suite) TestMaxRetriesRetryAttempts() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestMaxRetriesRetryAttempts",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+		WorkSpecData: map[string]interface{}{
+			"max_retries": 2,
+			"failure_data": map[string]interface{}{
+				"category": "retries",
+			},
+		},
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	// Give "a" one attempt before "b" exists
+	unitA, err := sts.AddWorkUnit("a")
+	if !s.NoError(err) {
+		return
+	}
+	attempt := sts.RequestOneAttempt(s)
+	s.NoError(attempt.Expire(nil))
+	unitB, err := sts.AddWorkUnit("b")
+	if !s.NoError(err) {
+		return
+	}
+
+	attempts, err := sts.Worker.RequestAttempts(coordinate.AttemptRequest{
+		NumberOfWorkUnits: 10,
+	})
+	if !(s.NoError(err) && s.Len(attempts, 2)) {
+		return
+	}
+	err = sts.Worker.RetryAttempts(attempts, nil, 0)
+	s.NoError(err)
+
+	// "a" is on its second attempt, and fails; "b" is retried
+	status, err := unitA.Status()
+	if s.NoError(err) {
+		s.Equal(coordinate.FailedUnit, status)
+	}
+	s.DataMatches(unitA, map[string]interface{}{
+		"traceback": "too many retries",
+		"category":  "retries",
+	})
+	status, err = unitB.Status()
+	if s.NoError(err) {
+		s.Equal(coordinate.AvailableUnit, status)
+	}
+}
+
+// TestMaxRetriesMulti tests both setting max_retries and max_getwork.
+func (s *Suite) TestMaxRetriesMulti() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestMaxRetries",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+		WorkSpecData: map[string]interface{}{
+			"max_getwork": 2,
+			"max_retries": 1,
+		},
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	for _, name := range []string{"a", "b", "c", "d"} {
+		_, err := sts.AddWorkUnit(name)
+		if !s.NoError(err) {
+			return
+		}
+	}
+
+	// Now we should be able to request work and get both a and b
+	req := coordinate.AttemptRequest{
+		NumberOfWorkUnits: 10,
+	}
+	attempts, err := sts.Worker.RequestAttempts(req)
+	if s.NoError(err) {
+		if s.Len(attempts, 2) {
+			s.Equal("a", attempts[0].WorkUnit().Name())
+			s.Equal("b", attempts[1].WorkUnit().Name())
+		}
+	}
+
+	// Let the first work unit finish and the second time out
+	s.Clock.Add(1 * time.Minute)
+	err = attempts[0].Finish(nil)
+	s.NoError(err)
+
+	s.Clock.Add(1 * time.Hour)
+
+	// Now get two more work units.  We expect the system to find
+	// b and c, notice that b is expired, and just return c.
+	attempts, err = sts.Worker.RequestAttempts(req)
+	if s.NoError(err) {
+		if s.Len(attempts, 1) {
+			s.Equal("c", attempts[0].WorkUnit().Name())
+		}
+	}
+}
+
+// TestMaxGetworkTruncation checks which work units a batch request
+// gets when max_getwork cuts it short: the highest priority first,
+// then equal-priority work units in name order, picking up each time
+// where the previous batch stopped.
+func (s *Suite) TestMaxGetworkTruncation() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestMaxGetworkTruncation",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+		WorkSpecData: map[string]interface{}{
+			"max_getwork": 3,
+		},
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	// Add the equal-priority work units out of name order, so
+	// the order they come back in is not just insertion order
+	units := map[string]float64{
+		"top": 10,
+		"e4":  5,
+		"e1":  5,
+		"e6":  5,
+		"e0":  5,
+		"e3":  5,
+		"e5":  5,
+		"e2":  5,
+		"low": 0,
+	}
+	for _, name := range []string{"e4", "low", "e1", "e6", "top", "e0", "e3", "e5", "e2"} {
+		_, err := sts.WorkSpec.AddWorkUnit(name, map[string]interface{}{},
+			coordinate.WorkUnitMeta{Priority: units[name]})
+		if !s.NoError(err) {
+			return
+		}
+	}
+
+	req := coordinate.AttemptRequest{NumberOfWorkUnits: 10}
+	for _, expected := range [][]string{
+		{"top", "e0", "e1"},
+		{"e2", "e3", "e4"},
+		{"e5", "e6", "low"},
+		{},
+	} {
+		attempts, err := sts.Worker.RequestAttempts(req)
+		if !s.NoError(err) {
+			return
+		}
+		names := make([]string, len(attempts))
+		for i, attempt := range attempts {
+			names[i] = attempt.WorkUnit().Name()
+		}
+		s.Equal(expected, names)
+	}
+}
+
+// TestMaxRetriesMultiBatch is like TestMaxRetriesMulti, but has an
+// entire batch go over the retry limit.
+func (s *Suite) TestMaxRetriesMultiBatch() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestMaxRetries",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+		WorkSpecData: map[string]interface{}{
+			"max_getwork": 2,
+			"max_retries": 1,
+		},
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	for _, name := range []string{"a", "b", "c", "d"} {
+		_, err := sts.AddWorkUnit(name)
+		if !s.NoError(err) {
+			return
+		}
+	}
+
+	// Now we should be able to request work and get both a and b
+	req := coordinate.AttemptRequest{
+		NumberOfWorkUnits: 10,
+	}
+	attempts, err := sts.Worker.RequestAttempts(req)
+	if s.NoError(err) {
+		if s.Len(attempts, 2) {
+			s.Equal("a", attempts[0].WorkUnit().Name())
+			s.Equal("b", attempts[1].WorkUnit().Name())
+		}
+	}
+
+	// Let both work units time out
+	s.Clock.Add(1 * time.Hour)
+
+	// Now get two more work units.  We expect the system to find
+	// a and b, see both are expired, find a new batch, and get
+	// both c and d
+	attempts, err = sts.Worker.RequestAttempts(req)
+	if s.NoError(err) {
+		if s.Len(attempts, 2) {
+			s.Equal("c", attempts[0].WorkUnit().Name())
 			s.Equal("d", attempts[1].WorkUnit().Name())
 		}
 	}
 }
+
+// TestWorkerGracePeriod validates that, with a worker grace period,
+// attempts in an "expire_with_worker" work spec are only reclaimed
+// once the grace period has passed after the worker's expiration.
+func (s *Suite) TestWorkerGracePeriod() {
+	if !coordinate.Supports(s.Coordinate, coordinate.FeatureWorkerGracePeriod) {
+		s.T().Skip("backend does not support worker grace periods")
+	}
+	setter := s.Coordinate.(coordinate.WorkerGracePeriodSetter)
+	setter.SetWorkerGracePeriod(10 * time.Minute)
+	defer setter.SetWorkerGracePeriod(0)
+
+	sts := SimpleTestSetup{
+		NamespaceName: "TestWorkerGracePeriod",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+		WorkSpecData: map[string]interface{}{
+			"expire_with_worker": true,
+		},
+		WorkUnitName: "a",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	// Workers get a default 15-minute expiration, so the attempt
+	// should survive until 25 minutes have passed
+	attempt, err := sts.Worker.MakeAttempt(sts.WorkUnit, time.Hour)
+	if !s.NoError(err) {
+		return
+	}
+	s.Clock.Add(20 * time.Minute)
+	s.AttemptStatus(coordinate.Pending, attempt)
+	sts.CheckUnitStatus(s, coordinate.PendingUnit)
+
+	s.Clock.Add(10 * time.Minute)
+	s.AttemptStatus(coordinate.Expired, attempt)
+	sts.CheckUnitStatus(s, coordinate.AvailableUnit)
+
+	// An explicitly deactivated worker gets no grace
+	attempt, err = sts.Worker.MakeAttempt(sts.WorkUnit, time.Hour)
+	if !s.NoError(err) {
+		return
+	}
+	err = sts.Worker.Deactivate()
+	if !s.NoError(err) {
+		return
+	}
+	s.AttemptStatus(coordinate.Expired, attempt)
+}
+
+// TestExpiringAttempts checks that Namespace.ExpiringAttempts returns
+// only the pending attempts that expire within the window, soonest
+// first.
+func (s *Suite) TestExpiringAttempts() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestExpiringAttempts",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	attempts, err := sts.Namespace.ExpiringAttempts(time.Hour)
+	if s.NoError(err) {
+		s.Empty(attempts)
+	}
+
+	lifetimes := map[string]time.Duration{
+		"a": 30 * time.Minute,
+		"b": 10 * time.Minute,
+		"c": 2 * time.Hour,
+		"d": 20 * time.Minute,
+		"e": 5 * time.Minute,
+	}
+	for name, lifetime := range lifetimes {
+		unit, err := sts.AddWorkUnit(name)
+		if !s.NoError(err) {
+			return
+		}
+		attempt, err := sts.Worker.MakeAttempt(unit, lifetime)
+		if !s.NoError(err) {
+			return
+		}
+		if name == "e" {
+			s.NoError(attempt.Finish(nil))
+		}
+	}
+
+	attempts, err = sts.Namespace.ExpiringAttempts(time.Hour)
+	if s.NoError(err) {
+		var names []string
+		for _, attempt := range attempts {
+			names = append(names, attempt.WorkUnit().Name())
+		}
+		s.Equal([]string{"b", "d", "a"}, names)
+	}
+
+	attempts, err = sts.Namespace.ExpiringAttempts(15 * time.Minute)
+	if s.NoError(err) && s.Len(attempts, 1) {
+		s.Equal("b", attempts[0].WorkUnit().Name())
+		s.Equal(sts.WorkSpecName, attempts[0].WorkUnit().WorkSpec().Name())
+		s.Equal(sts.WorkerName, attempts[0].Worker().Name())
+	}
+
+	// Once "b" has expired it is no longer pending
+	s.Clock.Add(12 * time.Minute)
+	attempts, err = sts.Namespace.ExpiringAttempts(15 * time.Minute)
+	if s.NoError(err) && s.Len(attempts, 1) {
+		s.Equal("d", attempts[0].WorkUnit().Name())
+	}
+}
+
+// TestAttemptArchive verifies that old attempts are moved to the
+// archive but are still visible through their work unit.
+func (s *Suite) TestAttemptArchive() {
+	if !coordinate.Supports(s.Coordinate, coordinate.FeatureAttemptArchive) {
+		s.T().Skip("backend does not support attempt archival")
+	}
+	archiver := s.Coordinate.(coordinate.AttemptArchiver)
+	archiver.SetAttemptArchive(2, 0)
+	defer archiver.SetAttemptArchive(0, 0)
+
+	sts := SimpleTestSetup{
+		NamespaceName: "TestAttemptArchive",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+		WorkUnitName:  "unit",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	var oldest coordinate.Attempt
+	for i := 0; i < 4; i++ {
+		attempt, err := sts.Worker.MakeAttempt(sts.WorkUnit, time.Duration(0))
+		if !s.NoError(err) {
+			return
+		}
+		if oldest == nil {
+			oldest = attempt
+			s.NoError(attempt.AppendLog("first"))
+		}
+		s.NoError(attempt.Retry(nil, time.Duration(0)))
+		s.Clock.Add(time.Second)
+	}
+
+	// An attempt fetched before it was archived can still be read,
+	// and keeps its logs
+	s.AttemptStatus(coordinate.Retryable, oldest)
+	logs, err := oldest.Logs()
+	if s.NoError(err) {
+		s.Equal([]string{"first"}, logs)
+	}
+	s.Equal(coordinate.ErrNotPending, oldest.Expire(nil))
+
+	attempts, err := sts.WorkUnit.Attempts()
+	if s.NoError(err) {
+		s.Len(attempts, 4)
+	}
+	n, err := sts.WorkUnit.NumAttempts()
+	if s.NoError(err) {
+		s.Equal(4, n)
+	}
+
+	archived, err := sts.WorkUnit.QueryAttempts(coordinate.AttemptQuery{
+		ArchivedOnly: true,
+	})
+	if s.NoError(err) && s.Len(archived, 2) {
+		for _, attempt := range archived {
+			status, err := attempt.Status()
+			if s.NoError(err) {
+				s.Equal(coordinate.Retryable, status)
+			}
+			s.Equal(sts.WorkerName, attempt.Worker().Name())
+		}
+		first, err := archived[0].StartTime()
+		if s.NoError(err) {
+			s.Equal(s.Clock.Now().Add(-4*time.Second), first)
+		}
+	}
+
+	attempts, err = sts.Worker.AllAttempts()
+	if s.NoError(err) {
+		s.Len(attempts, 2)
+	}
+
+	// Switch to age-based archival; once everything is old,
+	// finishing one more attempt archives all of the others
+	archiver.SetAttemptArchive(0, time.Hour)
+	s.Clock.Add(2 * time.Hour)
+	attempt, err := sts.Worker.MakeAttempt(sts.WorkUnit, time.Duration(0))
+	if s.NoError(err) {
+		s.NoError(attempt.Finish(nil))
+	}
+
+	archived, err = sts.WorkUnit.QueryAttempts(coordinate.AttemptQuery{
+		ArchivedOnly: true,
+	})
+	if s.NoError(err) {
+		s.Len(archived, 4)
+	}
+	attempts, err = sts.Worker.AllAttempts()
+	if s.NoError(err) && s.Len(attempts, 1) {
+		s.Equal(attempt.WorkUnit().Name(), attempts[0].WorkUnit().Name())
+	}
+	n, err = sts.WorkUnit.NumAttempts()
+	if s.NoError(err) {
+		s.Equal(5, n)
+	}
+}
+
+// TestAttemptLogs verifies that lines appended to a running attempt's
+// log can be read back, and that the log is bounded.
+func (s *Suite) TestAttemptLogs() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestAttemptLogs",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+		WorkUnitName:  "unit",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	attempt := sts.RequestOneAttempt(s)
+	logs, err := attempt.Logs()
+	if s.NoError(err) {
+		s.Empty(logs)
+	}
+
+	s.NoError(attempt.AppendLog("starting"))
+	s.NoError(attempt.AppendLog("working"))
+	logs, err = attempt.Logs()
+	if s.NoError(err) {
+		s.Equal([]string{"starting", "working"}, logs)
+	}
+
+	// Only the most recent lines are kept
+	for i := 0; i < coordinate.MaxAttemptLogLines; i++ {
+		s.NoError(attempt.AppendLog(fmt.Sprintf("line %d", i)))
+	}
+	logs, err = attempt.Logs()
+	if s.NoError(err) && s.Len(logs, coordinate.MaxAttemptLogLines) {
+		s.Equal("line 0", logs[0])
+		s.Equal(fmt.Sprintf("line %d", coordinate.MaxAttemptLogLines-1), logs[len(logs)-1])
+	}
+
+	// A completed attempt cannot log more, but its log remains
+	s.NoError(attempt.Finish(nil))
+	s.Equal(coordinate.ErrNotPending, attempt.AppendLog("too late"))
+	logs, err = attempt.Logs()
+	if s.NoError(err) {
+		s.Len(logs, coordinate.MaxAttemptLogLines)
+	}
+}
+
+// TestReservation reserves a work unit and releases it, then reserves
+// it again and completes it.
+func (s *Suite) TestReservation() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestReservation",
+		WorkSpecName:  "spec",
+		WorkUnitName:  "unit",
+		WorkUnitData:  map[string]interface{}{"k": "v"},
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	res, err := coordinate.Reserve(sts.Namespace, []string{"spec"}, time.Hour)
+	if !(s.NoError(err) && s.NotNil(res)) {
+		return
+	}
+	s.Equal("spec", res.WorkSpec)
+	s.Equal("unit", res.WorkUnit)
+	s.Equal(map[string]interface{}{"k": "v"}, res.Data)
+	s.NotEmpty(res.Token)
+	sts.CheckUnitStatus(s, coordinate.PendingUnit)
+
+	// Nothing else is available while the unit is reserved
+	other, err := coordinate.Reserve(sts.Namespace, nil, time.Hour)
+	if s.NoError(err) {
+		s.Nil(other)
+	}
+
+	err = coordinate.ReleaseReservation(sts.Namespace, res.Token)
+	s.NoError(err)
+	sts.CheckUnitStatus(s, coordinate.AvailableUnit)
+
+	// The token is no good once released
+	err = coordinate.CompleteReservation(sts.Namespace, res.Token, nil)
+	s.Equal(coordinate.ErrNoSuchReservation, err)
+	err = coordinate.CompleteReservation(sts.Namespace, "garbage", nil)
+	s.Equal(coordinate.ErrNoSuchReservation, err)
+
+	released := res.Token
+	res, err = coordinate.Reserve(sts.Namespace, nil, time.Hour)
+	if !(s.NoError(err) && s.NotNil(res)) {
+		return
+	}
+	s.Equal("unit", res.WorkUnit)
+	err = coordinate.ReleaseReservation(sts.Namespace, released)
+	s.Equal(coordinate.ErrNoSuchReservation, err)
+	err = coordinate.CompleteReservation(sts.Namespace, res.Token, map[string]interface{}{"k": "done"})
+	s.NoError(err)
+	sts.CheckUnitStatus(s, coordinate.FinishedUnit)
+	s.DataMatches(sts.WorkUnit, map[string]interface{}{"k": "done"})
+
+	err = coordinate.ReleaseReservation(sts.Namespace, res.Token)
+	s.Equal(coordinate.ErrNoSuchReservation, err)
+}
+
+// TestReservationMaxRetries verifies that releasing reservations does
+// not use up a work spec's max_retries.
+func (s *Suite) TestReservationMaxRetries() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestReservationMaxRetries",
+		WorkSpecName:  "spec",
+		WorkSpecData: map[string]interface{}{
+			"max_retries": 1,
+		},
+		WorkUnitName: "unit",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	for i := 0; i < 3; i++ {
+		res, err := coordinate.Reserve(sts.Namespace, nil, time.Hour)
+		if !(s.NoError(err) && s.NotNil(res)) {
+			return
+		}
+		s.Equal("unit", res.WorkUnit)
+		err = coordinate.ReleaseReservation(sts.Namespace, res.Token)
+		s.NoError(err)
+		sts.CheckUnitStatus(s, coordinate.AvailableUnit)
+	}
+
+	res, err := coordinate.Reserve(sts.Namespace, nil, time.Hour)
+	if !(s.NoError(err) && s.NotNil(res)) {
+		return
+	}
+	err = coordinate.CompleteReservation(sts.Namespace, res.Token, nil)
+	s.NoError(err)
+	sts.CheckUnitStatus(s, coordinate.FinishedUnit)
+
+	// All of the reservations shared one worker
+	workers, err := sts.Namespace.Workers()
+	if s.NoError(err) {
+		s.Len(workers, 1)
+	}
+}
@@ -28,6 +28,15 @@
 //     func TestCoordinate(t *testing.T) {
 //             suite.Run(t, &Suite{})
 //     }
+//
+// Many of the tests advance Suite.Clock and expect the backend to
+// see the change, so the backend must use it as its time source.
+// The memory and postgres backends take it in NewWithClock, as does
+// backend.Backend.CoordinateWithClock.  Backends that keep no time
+// of their own, like the REST client and the cache, should wrap a
+// backend built with the clock: the REST client talks to a
+// restserver router around such a backend, and the cache wraps it
+// directly.
 package coordinatetest
 
 import (
@@ -47,6 +56,11 @@ type Suite struct {
 	// Coordinate contains the top-level interface to the backend under
 	// test.  It is set by importing packages.
 	Coordinate coordinate.Coordinate
+
+	// DataHistory is the number of attempt data snapshots the
+	// backend has been configured to keep.  If zero, tests of
+	// Attempt.DataHistory() are skipped.
+	DataHistory int
 }
 
 // SetupSuite does one-time initialization for the test suite.
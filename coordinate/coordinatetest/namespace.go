@@ -5,6 +5,7 @@ package coordinatetest
 
 import (
 	"github.com/diffeo/go-coordinate/coordinate"
+	"time"
 )
 
 // TestNamespaceTrivial checks that a namespace's name matches the test name.
@@ -33,6 +34,38 @@ func (s *Suite) TestNamespaces() {
 	}
 }
 
+// TestNamespacesSeveral checks that the namespace list includes every
+// namespace that has been created, including the empty namespace
+// once it has work specs.
+func (s *Suite) TestNamespacesSeveral() {
+	names := []string{
+		"TestNamespacesSeveral_a",
+		"TestNamespacesSeveral_b",
+		"TestNamespacesSeveral_c",
+		"",
+	}
+	for _, name := range names {
+		ns, err := s.Coordinate.Namespace(name)
+		if !s.NoError(err) {
+			return
+		}
+		defer ns.Destroy()
+		_, err = ns.SetWorkSpec(map[string]interface{}{
+			"name": "spec",
+		})
+		s.NoError(err)
+	}
+
+	namespaces, err := s.Coordinate.Namespaces()
+	if s.NoError(err) {
+		for _, name := range names {
+			if s.Contains(namespaces, name) {
+				s.Equal(name, namespaces[name].Name())
+			}
+		}
+	}
+}
+
 // TestSpecCreateDestroy performs basic work spec lifetime tests.
 func (s *Suite) TestSpecCreateDestroy() {
 	var (
@@ -94,6 +127,55 @@ func (s *Suite) TestSpecCreateDestroy() {
 	s.Equal(coordinate.ErrNoSuchWorkSpec{Name: name}, err)
 }
 
+// TestDestroyWorkSpecs destroys several work specs at once,
+// including one that does not exist.  Their attempts go away too.
+func (s *Suite) TestDestroyWorkSpecs() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestDestroyWorkSpecs",
+		WorkerName:    "worker",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	for _, name := range []string{"a", "b", "c"} {
+		spec, err := sts.Namespace.SetWorkSpec(map[string]interface{}{
+			"name": name,
+		})
+		if !s.NoError(err) {
+			return
+		}
+		unit, err := spec.AddWorkUnit("unit", map[string]interface{}{}, coordinate.WorkUnitMeta{})
+		if !s.NoError(err) {
+			return
+		}
+		_, err = sts.Worker.MakeAttempt(unit, 0)
+		if !s.NoError(err) {
+			return
+		}
+	}
+	count, err := sts.Namespace.DestroyWorkSpecs([]string{"a", "missing", "c"})
+	if s.NoError(err) {
+		s.Equal(2, count)
+	}
+
+	names, err := sts.Namespace.WorkSpecNames()
+	if s.NoError(err) {
+		s.Equal([]string{"b"}, names)
+	}
+	_, err = sts.Namespace.WorkSpec("a")
+	s.Equal(coordinate.ErrNoSuchWorkSpec{Name: "a"}, err)
+
+	attempts, err := sts.Worker.ActiveAttempts()
+	if s.NoError(err) && s.Len(attempts, 1) {
+		s.Equal("b", attempts[0].WorkUnit().WorkSpec().Name())
+	}
+
+	count, err = sts.Namespace.DestroyWorkSpecs([]string{"a", "c"})
+	if s.NoError(err) {
+		s.Equal(0, count)
+	}
+}
+
 // TestSpecErrors checks for errors on malformed work specs.
 func (s *Suite) TestSpecErrors() {
 	namespace, err := s.Coordinate.Namespace("TestSpecErrors")
@@ -196,3 +278,217 @@ func (s *Suite) TestTwoWorkSpecsBasic() {
 	spec, err = namespace.WorkSpec(name2)
 	s.Equal(coordinate.ErrNoSuchWorkSpec{Name: name2}, err)
 }
+
+// TestWorkSpecNamesSorted checks that WorkSpecNames returns names in
+// alphabetical order, regardless of creation order.
+func (s *Suite) TestWorkSpecNamesSorted() {
+	namespace, err := s.Coordinate.Namespace("TestWorkSpecNamesSorted")
+	if !s.NoError(err) {
+		return
+	}
+	defer namespace.Destroy()
+
+	for _, name := range []string{"charlie", "alpha", "delta", "bravo"} {
+		_, err = namespace.SetWorkSpec(map[string]interface{}{
+			"name": name,
+		})
+		s.NoError(err)
+	}
+
+	names, err := namespace.WorkSpecNames()
+	if s.NoError(err) {
+		s.Equal([]string{"alpha", "bravo", "charlie", "delta"}, names)
+	}
+}
+
+// TestAvailableRuntimes checks that only runtimes of work specs with
+// available work units are reported.
+func (s *Suite) TestAvailableRuntimes() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestAvailableRuntimes",
+		WorkerName:    "worker",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	addSpec := func(name, runtime string) coordinate.WorkSpec {
+		spec, err := sts.Namespace.SetWorkSpec(map[string]interface{}{
+			"name":    name,
+			"runtime": runtime,
+		})
+		s.NoError(err)
+		return spec
+	}
+	checkRuntimes := func(expected ...string) {
+		runtimes, err := sts.Namespace.AvailableRuntimes()
+		if !s.NoError(err) {
+			return
+		}
+		if len(expected) == 0 {
+			s.Empty(runtimes)
+		} else {
+			s.Equal(expected, runtimes)
+		}
+	}
+
+	checkRuntimes()
+
+	plain := addSpec("plain", "")
+	goSpec := addSpec("go", "go")
+	python := addSpec("python", "python")
+	java := addSpec("java", "java")
+	_ = addSpec("empty", "rust")
+
+	_, err := plain.AddWorkUnit("a", map[string]interface{}{}, coordinate.WorkUnitMeta{})
+	s.NoError(err)
+	_, err = goSpec.AddWorkUnit("a", map[string]interface{}{}, coordinate.WorkUnitMeta{})
+	s.NoError(err)
+	unit, err := python.AddWorkUnit("a", map[string]interface{}{}, coordinate.WorkUnitMeta{})
+	s.NoError(err)
+	_, err = java.AddWorkUnit("a", map[string]interface{}{}, coordinate.WorkUnitMeta{
+		NotBefore: s.Clock.Now().Add(1 * time.Minute),
+	})
+	s.NoError(err)
+
+	checkRuntimes("", "go", "python")
+
+	// Once the only Python work unit is pending, there is no
+	// more Python work
+	attempt, err := sts.Worker.MakeAttempt(unit, 0)
+	if !s.NoError(err) {
+		return
+	}
+	checkRuntimes("", "go")
+
+	// Finishing the Python work doesn't make it available again
+	err = attempt.Finish(nil)
+	s.NoError(err)
+	checkRuntimes("", "go")
+
+	// The delayed Java work unit eventually becomes available
+	s.Clock.Add(90 * time.Second)
+	checkRuntimes("", "go", "java")
+}
+
+// TestSupports checks that every optional feature the backend claims
+// to support is reachable through its interface.  Tests of the
+// features the backend lacks are skipped, so this logs them all in
+// one place.
+func (s *Suite) TestSupports() {
+	features := []struct {
+		Feature string
+		Check   func() bool
+	}{
+		{coordinate.FeatureDataHistory, func() bool {
+			_, ok := s.Coordinate.(coordinate.DataHistorySetter)
+			return ok
+		}},
+		{coordinate.FeatureNamespaceLimit, func() bool {
+			_, ok := s.Coordinate.(coordinate.NamespaceLimiter)
+			return ok
+		}},
+		{coordinate.FeatureRequestThrottle, func() bool {
+			_, ok := s.Coordinate.(coordinate.RequestThrottler)
+			return ok
+		}},
+		{coordinate.FeatureWorkerGracePeriod, func() bool {
+			_, ok := s.Coordinate.(coordinate.WorkerGracePeriodSetter)
+			return ok
+		}},
+		{coordinate.FeatureKeyNormalization, func() bool {
+			_, ok := s.Coordinate.(coordinate.KeyNormalizerSetter)
+			return ok
+		}},
+		{coordinate.FeatureAttemptArchive, func() bool {
+			_, ok := s.Coordinate.(coordinate.AttemptArchiver)
+			return ok
+		}},
+		{coordinate.FeatureStrictCompletion, func() bool {
+			_, ok := s.Coordinate.(coordinate.StrictCompletionSetter)
+			return ok
+		}},
+		{coordinate.FeatureWorkSpecDataLimit, func() bool {
+			_, ok := s.Coordinate.(coordinate.WorkSpecDataLimiter)
+			return ok
+		}},
+		{coordinate.FeatureSchedulingGate, func() bool {
+			_, ok := s.Coordinate.(coordinate.SchedulingGater)
+			return ok
+		}},
+	}
+	var unsupported []string
+	for _, f := range features {
+		if coordinate.Supports(s.Coordinate, f.Feature) {
+			s.True(f.Check(), f.Feature)
+		} else {
+			unsupported = append(unsupported, f.Feature)
+		}
+	}
+	if len(unsupported) > 0 {
+		s.T().Logf("backend does not support %v; their tests are skipped", unsupported)
+	}
+	s.False(coordinate.Supports(s.Coordinate, "no_such_feature"))
+}
+
+// TestServerTime checks that Coordinate.ServerTime() reports the
+// backend's clock.
+func (s *Suite) TestServerTime() {
+	now, err := s.Coordinate.ServerTime()
+	if s.NoError(err) {
+		s.True(s.Clock.Now().Equal(now), "server time %v, clock %v", now, s.Clock.Now())
+	}
+
+	s.Clock.Add(time.Hour)
+	now, err = s.Coordinate.ServerTime()
+	if s.NoError(err) {
+		s.True(s.Clock.Now().Equal(now), "server time %v, clock %v", now, s.Clock.Now())
+	}
+}
+
+// TestClockTimestamps checks that the times the backend records for
+// work units and attempts all come from the suite's clock.
+func (s *Suite) TestClockTimestamps() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestClockTimestamps",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	// Move to a time that is not a round number of anything
+	s.Clock.Add(1*time.Hour + 23*time.Minute + 45*time.Second)
+	created := s.Clock.Now()
+	now, err := s.Coordinate.ServerTime()
+	if s.NoError(err) {
+		s.True(created.Equal(now), "server time %v, clock %v", now, created)
+	}
+	unit, err := sts.AddWorkUnit("unit")
+	if !s.NoError(err) {
+		return
+	}
+	createdAt, err := unit.CreatedAt()
+	if s.NoError(err) {
+		s.True(created.Equal(createdAt), "created at %v, clock %v", createdAt, created)
+	}
+
+	s.Clock.Add(17 * time.Second)
+	started := s.Clock.Now()
+	attempt := sts.RequestOneAttempt(s)
+	startTime, err := attempt.StartTime()
+	if s.NoError(err) {
+		s.True(started.Equal(startTime), "start time %v, clock %v", startTime, started)
+	}
+	expirationTime, err := attempt.ExpirationTime()
+	if s.NoError(err) {
+		s.Equal(15*time.Minute, expirationTime.Sub(started))
+	}
+
+	s.Clock.Add(42 * time.Second)
+	finished := s.Clock.Now()
+	s.NoError(attempt.Finish(nil))
+	endTime, err := attempt.EndTime()
+	if s.NoError(err) {
+		s.True(finished.Equal(endTime), "end time %v, clock %v", endTime, finished)
+	}
+}
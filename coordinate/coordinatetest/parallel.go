@@ -0,0 +1,102 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package coordinatetest
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/diffeo/go-coordinate/coordinate"
+)
+
+// BenchmarkParallel times adding, running, and deleting work units
+// from many goroutines at once, each with its own worker and work
+// spec.  Suite cannot hold benchmarks, so a backend calls this from
+// its own benchmark function with a Coordinate it has built:
+//
+//	func BenchmarkParallel(b *testing.B) {
+//		coordinatetest.BenchmarkParallel(b, mybackend.New())
+//	}
+//
+// It has two sub-benchmarks that do the same work.  In "Shared",
+// every goroutine's work spec is in one namespace; in "Separate",
+// each goroutine has a namespace of its own.  Run it with -cpu 1,2,4,8
+// to see how each scales: a backend with a single lock will have
+// similar times for both, while one that locks each namespace
+// separately will be faster in "Separate" as goroutines are added.
+func BenchmarkParallel(b *testing.B, c coordinate.Coordinate) {
+	b.Run("Shared", func(b *testing.B) {
+		benchmarkParallel(b, c, "BenchmarkParallelShared", func(int) string { return "" })
+	})
+	b.Run("Separate", func(b *testing.B) {
+		benchmarkParallel(b, c, "BenchmarkParallelSeparate", func(i int) string { return fmt.Sprint(i) })
+	})
+}
+
+// benchmarkParallel runs one half of BenchmarkParallel.  Goroutine i
+// works in the namespace named prefix plus suffix(i).
+func benchmarkParallel(b *testing.B, c coordinate.Coordinate, prefix string, suffix func(int) string) {
+	var (
+		next       int32
+		lock       sync.Mutex
+		namespaces []coordinate.Namespace
+	)
+	defer func() {
+		for _, ns := range namespaces {
+			ns.Destroy()
+		}
+	}()
+	b.RunParallel(func(pb *testing.PB) {
+		i := int(atomic.AddInt32(&next, 1))
+		ns, err := c.Namespace(prefix + suffix(i))
+		if err != nil {
+			b.Error(err)
+			return
+		}
+		lock.Lock()
+		namespaces = append(namespaces, ns)
+		lock.Unlock()
+		name := fmt.Sprintf("spec%v", i)
+		spec, err := ns.SetWorkSpec(map[string]interface{}{"name": name})
+		if err != nil {
+			b.Error(err)
+			return
+		}
+		worker, err := ns.Worker(fmt.Sprintf("worker%v", i))
+		if err != nil {
+			b.Error(err)
+			return
+		}
+		req := coordinate.AttemptRequest{WorkSpecs: []string{name}}
+		for n := 0; pb.Next(); n++ {
+			// Delete each work unit when it is done, so the
+			// work spec stays small however long this runs
+			unitName := fmt.Sprintf("u%v", n)
+			_, err = spec.AddWorkUnit(unitName, map[string]interface{}{}, coordinate.WorkUnitMeta{})
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			attempts, err := worker.RequestAttempts(req)
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			for _, attempt := range attempts {
+				err = attempt.Finish(nil)
+				if err != nil {
+					b.Error(err)
+					return
+				}
+			}
+			_, err = spec.DeleteWorkUnits(coordinate.WorkUnitQuery{Names: []string{unitName}})
+			if err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"math/rand"
 	"strings"
 	"sync"
+	"time"
 )
 
 // ------------------------------------------------------------------------
@@ -157,3 +158,65 @@ func (s *Suite) TestAddSameUnit() {
 	})
 	s.Empty(panics)
 }
+
+// TestConcurrentMaxRunning has many workers request work at once
+// from a work spec with max_running 1, and checks that no two of
+// them ever hold attempts at the same time.
+func (s *Suite) TestConcurrentMaxRunning() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestConcurrentMaxRunning",
+		WorkSpecName:  "spec",
+		WorkSpecData: map[string]interface{}{
+			"max_running": 1,
+		},
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	numUnits := 20
+	s.createWorkUnits(sts.WorkSpec, numUnits)
+	var (
+		lock     sync.Mutex
+		running  int
+		finished int
+	)
+	panics := pooled(func() {
+		worker := createWorker(sts.Namespace)
+		// Give up eventually, so a backend that never
+		// returns work fails rather than hangs
+		for i := 0; i < 10*numUnits; i++ {
+			lock.Lock()
+			done := finished >= numUnits
+			lock.Unlock()
+			if done {
+				return
+			}
+			attempts, err := worker.RequestAttempts(coordinate.AttemptRequest{
+				NumberOfWorkUnits: 2,
+			})
+			if !s.NoError(err) {
+				return
+			}
+			if len(attempts) == 0 {
+				continue
+			}
+			s.Len(attempts, 1)
+			lock.Lock()
+			running += len(attempts)
+			s.Equal(1, running, "attempts pending at once")
+			lock.Unlock()
+			// Hold the attempt for a moment, so other
+			// workers' requests overlap it
+			time.Sleep(1 * time.Millisecond)
+			lock.Lock()
+			running -= len(attempts)
+			finished += len(attempts)
+			lock.Unlock()
+			for _, attempt := range attempts {
+				s.NoError(attempt.Finish(nil))
+			}
+		}
+	})
+	s.Empty(panics)
+	s.Equal(numUnits, finished)
+}
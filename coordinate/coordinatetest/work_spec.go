@@ -4,7 +4,9 @@
 package coordinatetest
 
 import (
+	"context"
 	"github.com/diffeo/go-coordinate/coordinate"
+	"strings"
 	"time"
 )
 
@@ -161,6 +163,55 @@ func (s *Suite) TestPrefilledMeta() {
 	}
 }
 
+// TestSchemaVersion tests that the "schema_version" work spec key is
+// reported in the metadata, survives SetMeta(), and is checked by
+// CheckSchemaVersion().
+func (s *Suite) TestSchemaVersion() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestSchemaVersion",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	meta, err := sts.WorkSpec.Meta(false)
+	if s.NoError(err) {
+		s.Equal(0, meta.SchemaVersion)
+	}
+	s.NoError(coordinate.CheckSchemaVersion(sts.WorkSpec, 0))
+
+	err = sts.WorkSpec.SetData(map[string]interface{}{
+		"name":           "spec",
+		"schema_version": 3,
+	})
+	if !s.NoError(err) {
+		return
+	}
+	meta, err = sts.WorkSpec.Meta(false)
+	if !s.NoError(err) {
+		return
+	}
+	s.Equal(3, meta.SchemaVersion)
+
+	// SetMeta can't change it
+	meta.SchemaVersion = 4
+	err = sts.WorkSpec.SetMeta(meta)
+	if s.NoError(err) {
+		meta, err = sts.WorkSpec.Meta(false)
+		if s.NoError(err) {
+			s.Equal(3, meta.SchemaVersion)
+		}
+	}
+
+	s.NoError(coordinate.CheckSchemaVersion(sts.WorkSpec, 3))
+	err = coordinate.CheckSchemaVersion(sts.WorkSpec, 2)
+	s.Equal(coordinate.ErrSchemaVersionMismatch{
+		WorkSpec: "spec",
+		Expected: 2,
+		Actual:   3,
+	}, err)
+}
+
 // TestSetDataSetsMeta tests that...yeah
 func (s *Suite) TestSetDataSetsMeta() {
 	sts := SimpleTestSetup{
@@ -482,6 +533,543 @@ func (s *Suite) TestMetaCounts() {
 	}
 }
 
+// TestSuccessors follows "then" links through a multi-stage chain
+// and a cyclic configuration.
+func (s *Suite) TestSuccessors() {
+	sts := SimpleTestSetup{NamespaceName: "TestSuccessors"}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	specs := []map[string]interface{}{
+		{"name": "first", "then": "second"},
+		{"name": "second", "then": "third"},
+		{"name": "third"},
+		{"name": "ping", "then": "pong"},
+		{"name": "pong", "then": "ping"},
+	}
+	for _, data := range specs {
+		_, err := sts.Namespace.SetWorkSpec(data)
+		if !s.NoError(err) {
+			return
+		}
+	}
+
+	spec, err := sts.Namespace.WorkSpec("first")
+	if s.NoError(err) {
+		names, err := spec.Successors()
+		if s.NoError(err) {
+			s.Equal([]string{"second"}, names)
+		}
+	}
+	spec, err = sts.Namespace.WorkSpec("third")
+	if s.NoError(err) {
+		names, err := spec.Successors()
+		if s.NoError(err) {
+			s.Empty(names)
+		}
+	}
+
+	chain, cyclic, err := coordinate.WorkSpecChain(sts.Namespace, "first")
+	if s.NoError(err) {
+		s.Equal([]string{"first", "second", "third"}, chain)
+		s.False(cyclic)
+	}
+
+	chain, cyclic, err = coordinate.WorkSpecChain(sts.Namespace, "pong")
+	if s.NoError(err) {
+		s.Equal([]string{"pong", "ping"}, chain)
+		s.True(cyclic)
+	}
+}
+
+// TestPipelineHealth builds a stuck pipeline and checks the issues
+// Namespace.PipelineHealth() reports for it.
+func (s *Suite) TestPipelineHealth() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestPipelineHealth",
+		WorkerName:    "worker",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	specs := []map[string]interface{}{
+		{"name": "first", "then": "second"},
+		{"name": "second", "then": "third"},
+		{"name": "third"},
+		{"name": "dangling", "then": "nowhere"},
+		{"name": "alone"},
+	}
+	for _, data := range specs {
+		spec, err := sts.Namespace.SetWorkSpec(data)
+		if !s.NoError(err) {
+			return
+		}
+		for _, unit := range []string{"a", "b"} {
+			if data["name"] == "second" || data["name"] == "dangling" {
+				continue
+			}
+			_, err = spec.AddWorkUnit(unit, map[string]interface{}{}, coordinate.WorkUnitMeta{})
+			if !s.NoError(err) {
+				return
+			}
+		}
+	}
+
+	second, err := sts.Namespace.WorkSpec("second")
+	if !s.NoError(err) {
+		return
+	}
+	err = second.SetMeta(coordinate.WorkSpecMeta{Paused: true})
+	if !s.NoError(err) {
+		return
+	}
+
+	// The worker is running one of the first stage's work units
+	attempts, err := sts.Worker.RequestAttempts(coordinate.AttemptRequest{
+		WorkSpecs: []string{"first"},
+	})
+	if !(s.NoError(err) && s.Len(attempts, 1)) {
+		return
+	}
+
+	checkIssues := func(expected ...coordinate.PipelineIssue) {
+		issues, err := sts.Namespace.PipelineHealth()
+		if !s.NoError(err) {
+			return
+		}
+		// Don't compare messages
+		for i := range issues {
+			issues[i].Message = ""
+		}
+		if len(expected) == 0 {
+			s.Empty(issues)
+		} else {
+			s.Equal(expected, issues)
+		}
+	}
+
+	// "second" is paused with the first stage's work backed up
+	// behind it, nobody is working on "third", and "alone" is not
+	// part of a pipeline at all
+	checkIssues(
+		coordinate.PipelineIssue{
+			Kind:      coordinate.PipelineMissing,
+			WorkSpec:  "dangling",
+			Successor: "nowhere",
+		},
+		coordinate.PipelineIssue{
+			Kind:     coordinate.PipelinePaused,
+			WorkSpec: "second",
+			Upstream: 2,
+		},
+		coordinate.PipelineIssue{
+			Kind:      coordinate.PipelineUnworked,
+			WorkSpec:  "third",
+			Available: 2,
+			Upstream:  2,
+		},
+	)
+
+	// An idle live worker could pick up "third"'s work
+	idle, err := sts.Namespace.Worker("idle")
+	if !s.NoError(err) {
+		return
+	}
+	now := s.Clock.Now()
+	err = idle.Update(map[string]interface{}{}, now, now.Add(15*time.Minute), "run")
+	if !s.NoError(err) {
+		return
+	}
+	checkIssues(
+		coordinate.PipelineIssue{
+			Kind:      coordinate.PipelineMissing,
+			WorkSpec:  "dangling",
+			Successor: "nowhere",
+		},
+		coordinate.PipelineIssue{
+			Kind:     coordinate.PipelinePaused,
+			WorkSpec: "second",
+			Upstream: 2,
+		},
+	)
+	err = idle.Deactivate()
+	if !s.NoError(err) {
+		return
+	}
+
+	// If the worker dies, nobody is working on "first" either
+	err = sts.Worker.Deactivate()
+	if !s.NoError(err) {
+		return
+	}
+	checkIssues(
+		coordinate.PipelineIssue{
+			Kind:      coordinate.PipelineMissing,
+			WorkSpec:  "dangling",
+			Successor: "nowhere",
+		},
+		coordinate.PipelineIssue{
+			Kind:      coordinate.PipelineUnworked,
+			WorkSpec:  "first",
+			Available: 1,
+		},
+		coordinate.PipelineIssue{
+			Kind:     coordinate.PipelinePaused,
+			WorkSpec: "second",
+			Upstream: 2,
+		},
+		coordinate.PipelineIssue{
+			Kind:      coordinate.PipelineUnworked,
+			WorkSpec:  "third",
+			Available: 2,
+			Upstream:  2,
+		},
+	)
+}
+
+// TestDataSize validates that WorkSpec.DataSize() grows as work units
+// are added.
+func (s *Suite) TestDataSize() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestDataSize",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	size, err := sts.WorkSpec.DataSize()
+	if s.NoError(err) {
+		s.Equal(int64(0), size)
+	}
+
+	_, err = sts.WorkSpec.AddWorkUnit("one", map[string]interface{}{
+		"key": "value",
+	}, coordinate.WorkUnitMeta{})
+	s.NoError(err)
+	oneSize, err := sts.WorkSpec.DataSize()
+	if s.NoError(err) {
+		s.True(oneSize > 0, "size %v after one unit", oneSize)
+	}
+
+	_, err = sts.WorkSpec.AddWorkUnit("two", map[string]interface{}{
+		"key":   "value",
+		"other": "a much longer string value than the first",
+	}, coordinate.WorkUnitMeta{})
+	s.NoError(err)
+	twoSize, err := sts.WorkSpec.DataSize()
+	if s.NoError(err) {
+		s.True(twoSize > 2*oneSize, "size %v after two units, %v after one", twoSize, oneSize)
+	}
+}
+
+// TestMaxWorkSpecData checks that work spec data over the limit set
+// with WorkSpecDataLimiter is rejected, and smaller data is not.
+func (s *Suite) TestMaxWorkSpecData() {
+	if !coordinate.Supports(s.Coordinate, coordinate.FeatureWorkSpecDataLimit) {
+		s.T().Skip("backend does not support a work spec data limit")
+	}
+	limiter := s.Coordinate.(coordinate.WorkSpecDataLimiter)
+	limiter.SetMaxWorkSpecData(1024)
+	defer limiter.SetMaxWorkSpecData(0)
+
+	sts := SimpleTestSetup{
+		NamespaceName: "TestMaxWorkSpecData",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	blob := strings.Repeat("x", 2048)
+	checkTooLarge := func(err error, name string) {
+		if tooLarge, ok := err.(coordinate.ErrWorkSpecDataTooLarge); s.True(ok, "%+v", err) {
+			s.Equal(name, tooLarge.WorkSpec)
+			s.Equal(1024, tooLarge.Limit)
+			s.True(tooLarge.Size > 1024, "size %v", tooLarge.Size)
+		}
+	}
+
+	// A normal-sized work spec is fine
+	_, err := sts.Namespace.SetWorkSpec(map[string]interface{}{
+		"name":   "small",
+		"config": "small",
+	})
+	s.NoError(err)
+
+	// A new work spec with too much data is not created
+	_, err = sts.Namespace.SetWorkSpec(map[string]interface{}{
+		"name": "big",
+		"blob": blob,
+	})
+	checkTooLarge(err, "big")
+	_, err = sts.Namespace.WorkSpec("big")
+	s.Equal(coordinate.ErrNoSuchWorkSpec{Name: "big"}, err)
+
+	// An existing work spec keeps its old data
+	err = sts.WorkSpec.SetData(map[string]interface{}{
+		"name": "spec",
+		"blob": blob,
+	})
+	checkTooLarge(err, "spec")
+	_, err = sts.Namespace.SetWorkSpec(map[string]interface{}{
+		"name": "spec",
+		"blob": blob,
+	})
+	checkTooLarge(err, "spec")
+	data, err := sts.WorkSpec.Data()
+	if s.NoError(err) {
+		s.NotContains(data, "blob")
+	}
+
+	// Without the limit, anything goes
+	limiter.SetMaxWorkSpecData(0)
+	err = sts.WorkSpec.SetData(map[string]interface{}{
+		"name": "spec",
+		"blob": blob,
+	})
+	s.NoError(err)
+}
+
+// TestSchedulingGate checks that a work spec the SchedulingGater
+// function vetoes yields no work until the function allows it.
+func (s *Suite) TestSchedulingGate() {
+	if !coordinate.Supports(s.Coordinate, coordinate.FeatureSchedulingGate) {
+		s.T().Skip("backend does not support a scheduling gate")
+	}
+	sts := SimpleTestSetup{
+		NamespaceName: "TestSchedulingGate",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+	unit, err := sts.AddWorkUnit("unit")
+	if !s.NoError(err) {
+		return
+	}
+
+	open := false
+	gater := s.Coordinate.(coordinate.SchedulingGater)
+	gater.SetSchedulingGate(func(namespace, workSpec string) bool {
+		if namespace != "TestSchedulingGate" || workSpec != "spec" {
+			return true
+		}
+		return open
+	})
+	defer gater.SetSchedulingGate(nil)
+
+	sts.RequestNoAttempts(s)
+	ok, reason, err := sts.WorkSpec.IsSchedulable()
+	if s.NoError(err) {
+		s.False(ok)
+		s.Equal("gated", reason)
+	}
+	status, err := unit.Status()
+	if s.NoError(err) {
+		s.Equal(coordinate.AvailableUnit, status)
+	}
+
+	open = true
+	ok, _, err = sts.WorkSpec.IsSchedulable()
+	if s.NoError(err) {
+		s.True(ok)
+	}
+	attempt := sts.RequestOneAttempt(s)
+	s.Equal("unit", attempt.WorkUnit().Name())
+}
+
+// TestGated checks that setting WorkSpecMeta.Gated stops a work spec
+// from being scheduled without pausing it.
+func (s *Suite) TestGated() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestGated",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+	_, err := sts.AddWorkUnit("unit")
+	if !s.NoError(err) {
+		return
+	}
+
+	meta, err := sts.WorkSpec.Meta(false)
+	if !s.NoError(err) {
+		return
+	}
+	meta.Gated = true
+	err = sts.WorkSpec.SetMeta(meta)
+	if !s.NoError(err) {
+		return
+	}
+	meta, err = sts.WorkSpec.Meta(false)
+	if s.NoError(err) {
+		s.True(meta.Gated)
+		s.False(meta.Paused)
+	}
+
+	sts.RequestNoAttempts(s)
+	ok, reason, err := sts.WorkSpec.IsSchedulable()
+	if s.NoError(err) {
+		s.False(ok)
+		s.Equal("gated", reason)
+	}
+
+	meta.Gated = false
+	err = sts.WorkSpec.SetMeta(meta)
+	if s.NoError(err) {
+		attempt := sts.RequestOneAttempt(s)
+		s.Equal("unit", attempt.WorkUnit().Name())
+	}
+}
+
+// TestGatedSetData checks that replacing a gated work spec's data
+// leaves it gated.
+func (s *Suite) TestGatedSetData() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestGatedSetData",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+	_, err := sts.AddWorkUnit("unit")
+	if !s.NoError(err) {
+		return
+	}
+	err = sts.WorkSpec.SetMeta(coordinate.WorkSpecMeta{Gated: true})
+	if !s.NoError(err) {
+		return
+	}
+
+	err = sts.WorkSpec.SetData(map[string]interface{}{
+		"name":     "spec",
+		"priority": 5,
+	})
+	if !s.NoError(err) {
+		return
+	}
+	meta, err := sts.WorkSpec.Meta(false)
+	if s.NoError(err) {
+		s.True(meta.Gated)
+		s.Equal(5, meta.Priority)
+	}
+
+	_, err = sts.Namespace.SetWorkSpec(map[string]interface{}{
+		"name": "spec",
+	})
+	if !s.NoError(err) {
+		return
+	}
+	meta, err = sts.WorkSpec.Meta(false)
+	if s.NoError(err) {
+		s.True(meta.Gated)
+	}
+	sts.RequestNoAttempts(s)
+}
+
+// TestPriorityHistogram validates that WorkSpec.PriorityHistogram()
+// counts work units at each priority, whatever their status.
+func (s *Suite) TestPriorityHistogram() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestPriorityHistogram",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	histogram, err := sts.WorkSpec.PriorityHistogram()
+	if s.NoError(err) {
+		s.Empty(histogram)
+	}
+
+	priorities := map[string]float64{
+		"a": 0,
+		"b": 0,
+		"c": 1.5,
+		"d": 10,
+		"e": 10,
+		"f": 10,
+		"g": -3,
+	}
+	for name, priority := range priorities {
+		_, err = sts.WorkSpec.AddWorkUnit(name, map[string]interface{}{}, coordinate.WorkUnitMeta{
+			Priority: priority,
+		})
+		s.NoError(err)
+	}
+
+	// Completing work units doesn't remove them from the histogram
+	attempt := sts.RequestOneAttempt(s)
+	s.Equal("d", attempt.WorkUnit().Name())
+	s.NoError(attempt.Finish(nil))
+
+	histogram, err = sts.WorkSpec.PriorityHistogram()
+	if s.NoError(err) {
+		s.Equal(map[float64]int{-3: 1, 0: 2, 1.5: 1, 10: 3}, histogram)
+	}
+
+	err = sts.WorkSpec.SetWorkUnitPriorities(coordinate.WorkUnitQuery{
+		Names: []string{"a", "g"},
+	}, 1.5)
+	s.NoError(err)
+	histogram, err = sts.WorkSpec.PriorityHistogram()
+	if s.NoError(err) {
+		s.Equal(map[float64]int{0: 1, 1.5: 3, 10: 3}, histogram)
+	}
+}
+
+// TestWorkSpecSummary validates that WorkSpec.Summary() agrees with
+// the individual data, metadata, and count calls.
+func (s *Suite) TestWorkSpecSummary() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestWorkSpecSummary",
+		WorkerName:    "worker",
+		WorkSpecData: map[string]interface{}{
+			"name":     "spec",
+			"priority": 5,
+			"extra":    "value",
+		},
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	_, err := sts.MakeWorkUnits()
+	if !s.NoError(err) {
+		return
+	}
+
+	summary, err := sts.WorkSpec.Summary()
+	if !s.NoError(err) {
+		return
+	}
+
+	data, err := sts.WorkSpec.Data()
+	if s.NoError(err) {
+		s.Equal(data, summary.Data)
+		s.Equal("value", summary.Data["extra"])
+	}
+
+	meta, err := sts.WorkSpec.Meta(true)
+	if s.NoError(err) {
+		s.Equal(meta, summary.Meta)
+		s.Equal(5, summary.Meta.Priority)
+		s.Equal(1, summary.Meta.PendingCount)
+	}
+
+	counts, err := sts.WorkSpec.CountWorkUnitStatus()
+	if s.NoError(err) {
+		s.Equal(counts, summary.Counts)
+		s.Equal(map[coordinate.WorkUnitStatus]int{
+			coordinate.AvailableUnit: 3,
+			coordinate.PendingUnit:   1,
+			coordinate.FinishedUnit:  1,
+			coordinate.FailedUnit:    1,
+			coordinate.DelayedUnit:   1,
+		}, summary.Counts)
+	}
+}
+
 // TestSpecDeletedGone validates that, if you delete a work spec,
 // subsequent attempts to use it return ErrGone.
 func (s *Suite) TestSpecDeletedGone() {
@@ -571,3 +1159,148 @@ func (s *Suite) TestOneDayInterval() {
 
 	sts.RequestOneAttempt(s)
 }
+
+// TestIsSchedulable tests WorkSpec.IsSchedulable() and each of the
+// reasons a work spec might not be schedulable.
+func (s *Suite) TestIsSchedulable() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestIsSchedulable",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+		WorkSpecData: map[string]interface{}{
+			"max_running": 1,
+		},
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	checkSchedulable := func(expected bool, expectedReason string) {
+		ok, reason, err := sts.WorkSpec.IsSchedulable()
+		if s.NoError(err) {
+			s.Equal(expected, ok)
+			s.Equal(expectedReason, reason)
+		}
+	}
+
+	// A work spec with no work units has nothing to do
+	checkSchedulable(false, "no available work units")
+
+	// Adding work units makes it schedulable
+	for _, name := range []string{"a", "b"} {
+		_, err := sts.AddWorkUnit(name)
+		if !s.NoError(err) {
+			return
+		}
+	}
+	checkSchedulable(true, "")
+
+	// Pausing the work spec makes it not schedulable
+	meta, err := sts.WorkSpec.Meta(false)
+	if !s.NoError(err) {
+		return
+	}
+	meta.Paused = true
+	err = sts.WorkSpec.SetMeta(meta)
+	if !s.NoError(err) {
+		return
+	}
+	checkSchedulable(false, "paused")
+
+	meta.Paused = false
+	err = sts.WorkSpec.SetMeta(meta)
+	if !s.NoError(err) {
+		return
+	}
+	checkSchedulable(true, "")
+
+	// Running one work unit hits the max_running limit, even
+	// though "b" is still available
+	sts.RequestOneAttempt(s)
+	checkSchedulable(false, "max_running reached")
+}
+
+// TestWorkSpecNamespace tests that WorkSpec.Namespace() returns the
+// namespace containing the work spec, however the work spec was found.
+func (s *Suite) TestWorkSpecNamespace() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestWorkSpecNamespace",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+		WorkUnitName:  "unit",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	checkNamespace := func(spec coordinate.WorkSpec) {
+		ns := spec.Namespace()
+		if s.NotNil(ns) {
+			s.Equal(sts.Namespace.Name(), ns.Name())
+			names, err := ns.WorkSpecNames()
+			if s.NoError(err) {
+				s.Equal([]string{"spec"}, names)
+			}
+		}
+	}
+
+	checkNamespace(sts.WorkSpec)
+
+	spec, err := sts.Namespace.WorkSpec("spec")
+	if s.NoError(err) {
+		checkNamespace(spec)
+	}
+
+	attempt := sts.RequestOneAttempt(s)
+	checkNamespace(attempt.WorkUnit().WorkSpec())
+}
+
+// TestWatchWork tests that adding a work unit notifies watchers, for
+// backends that support coordinate.WorkWatcher.
+func (s *Suite) TestWatchWork() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestWatchWork",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	watcher, canWatch := sts.WorkSpec.(coordinate.WorkWatcher)
+	if !canWatch {
+		s.T().Skip("backend does not support WorkWatcher")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := watcher.WatchWork(ctx)
+	if err == coordinate.ErrWatchNotSupported {
+		s.T().Skip("work spec does not support watching")
+	}
+	if !s.NoError(err) {
+		return
+	}
+
+	_, err = sts.AddWorkUnit("unit")
+	if !s.NoError(err) {
+		return
+	}
+	select {
+	case _, ok := <-events:
+		s.True(ok, "channel closed instead of notifying")
+	case <-time.After(5 * time.Second):
+		s.Fail("no notification after adding a work unit")
+	}
+
+	// Cancelling the context closes the channel, perhaps after
+	// a last notification
+	cancel()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-events:
+			if ok {
+				continue
+			}
+		case <-timeout:
+			s.Fail("channel not closed after cancelling")
+		}
+		break
+	}
+}
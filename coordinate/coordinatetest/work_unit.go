@@ -6,6 +6,8 @@ package coordinatetest
 import (
 	"fmt"
 	"github.com/diffeo/go-coordinate/coordinate"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -184,6 +186,276 @@ func (s *Suite) TestWorkUnitQueries() {
 	}
 }
 
+// TestWorkUnitQueryNeverAttempted tests the NeverAttempted flag on
+// work unit queries, which selects a subset of available (and delayed)
+// work units.
+func (s *Suite) TestWorkUnitQueryNeverAttempted() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestWorkUnitQueryNeverAttempted",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	_, err := sts.MakeWorkUnits()
+	s.NoError(err)
+	_, err = sts.WorkSpec.AddWorkUnit("later", map[string]interface{}{}, coordinate.WorkUnitMeta{
+		NotBefore: s.Clock.Now().Add(1 * time.Hour),
+	})
+	s.NoError(err)
+
+	units, err := sts.WorkSpec.WorkUnits(coordinate.WorkUnitQuery{
+		NeverAttempted: true,
+	})
+	if s.NoError(err) {
+		s.Len(units, 2)
+		s.Contains(units, "available")
+		s.Contains(units, "later")
+	}
+
+	// "expired" and "retryable" are available too, but have
+	// been attempted
+	units, err = sts.WorkSpec.WorkUnits(coordinate.WorkUnitQuery{
+		Statuses:       []coordinate.WorkUnitStatus{coordinate.AvailableUnit},
+		NeverAttempted: true,
+	})
+	if s.NoError(err) {
+		s.Len(units, 1)
+		s.Contains(units, "available")
+	}
+
+	// Attempting the work unit removes it from the set, even
+	// once the attempt expires
+	unit, err := sts.WorkSpec.WorkUnit("available")
+	if !s.NoError(err) {
+		return
+	}
+	_, err = sts.Worker.MakeAttempt(unit, 1*time.Minute)
+	s.NoError(err)
+	s.Clock.Add(5 * time.Minute)
+	sts.WorkUnit = unit
+	sts.CheckUnitStatus(s, coordinate.AvailableUnit)
+
+	units, err = sts.WorkSpec.WorkUnits(coordinate.WorkUnitQuery{
+		NeverAttempted: true,
+	})
+	if s.NoError(err) {
+		s.Len(units, 1)
+		s.Contains(units, "later")
+	}
+}
+
+// TestWorkUnitQueryWorkerName tests the WorkerName filter on work
+// unit queries, which selects work units by their active attempt's
+// worker.
+func (s *Suite) TestWorkUnitQueryWorkerName() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestWorkUnitQueryWorkerName",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	other, err := sts.Namespace.Worker("other")
+	if !s.NoError(err) {
+		return
+	}
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		_, err = sts.AddWorkUnit(name)
+		if !s.NoError(err) {
+			return
+		}
+	}
+	attempt := func(worker coordinate.Worker, name string) {
+		unit, err := sts.WorkSpec.WorkUnit(name)
+		if s.NoError(err) {
+			_, err = worker.MakeAttempt(unit, time.Duration(0))
+			s.NoError(err)
+		}
+	}
+	attempt(sts.Worker, "a")
+	attempt(sts.Worker, "b")
+	attempt(other, "c")
+	// "d" was held by the first worker but is now held by the
+	// other one
+	attempt(sts.Worker, "d")
+	attempt(other, "d")
+
+	units, err := sts.WorkSpec.WorkUnits(coordinate.WorkUnitQuery{
+		WorkerName: "worker",
+	})
+	if s.NoError(err) {
+		s.Len(units, 2)
+		s.Contains(units, "a")
+		s.Contains(units, "b")
+	}
+
+	units, err = sts.WorkSpec.WorkUnits(coordinate.WorkUnitQuery{
+		WorkerName: "other",
+		Statuses:   []coordinate.WorkUnitStatus{coordinate.PendingUnit},
+	})
+	if s.NoError(err) {
+		s.Len(units, 2)
+		s.Contains(units, "c")
+		s.Contains(units, "d")
+	}
+
+	units, err = sts.WorkSpec.WorkUnits(coordinate.WorkUnitQuery{
+		WorkerName: "nobody",
+	})
+	if s.NoError(err) {
+		s.Empty(units)
+	}
+}
+
+// TestWorkUnitQueryOffset tests the Offset field on work unit queries.
+func (s *Suite) TestWorkUnitQueryOffset() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestWorkUnitQueryOffset",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		_, err := sts.AddWorkUnit(name)
+		s.NoError(err)
+	}
+
+	units, err := sts.WorkSpec.WorkUnits(coordinate.WorkUnitQuery{
+		Offset: 1,
+		Limit:  2,
+	})
+	if s.NoError(err) {
+		s.Len(units, 2)
+		s.Contains(units, "b")
+		s.Contains(units, "c")
+	}
+
+	units, err = sts.WorkSpec.WorkUnits(coordinate.WorkUnitQuery{
+		PreviousName: "b",
+		Offset:       2,
+	})
+	if s.NoError(err) {
+		s.Len(units, 1)
+		s.Contains(units, "e")
+	}
+
+	units, err = sts.WorkSpec.WorkUnits(coordinate.WorkUnitQuery{
+		Offset: 5,
+	})
+	if s.NoError(err) {
+		s.Len(units, 0)
+	}
+}
+
+// TestWorkUnitsPage tests paging through work units with cursors.
+func (s *Suite) TestWorkUnitsPage() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestWorkUnitsPage",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		_, err := sts.AddWorkUnit(name)
+		s.NoError(err)
+	}
+
+	// Starting at an offset, the cursor picks up where the
+	// first page left off
+	query := coordinate.WorkUnitQuery{Offset: 1, Limit: 2}
+	var pages [][]string
+	for i := 0; i < 5; i++ {
+		units, cursor, err := sts.WorkSpec.WorkUnitsPage(query)
+		if !s.NoError(err) {
+			return
+		}
+		var names []string
+		for name := range units {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		pages = append(pages, names)
+		if cursor == "" {
+			break
+		}
+		query.Cursor = cursor
+	}
+	s.Equal([][]string{{"b", "c"}, {"d", "e"}}, pages)
+
+	// No limit means no further pages
+	units, cursor, err := sts.WorkSpec.WorkUnitsPage(coordinate.WorkUnitQuery{})
+	if s.NoError(err) {
+		s.Len(units, 5)
+		s.Equal("", cursor)
+	}
+
+	// A limit that is not reached also means no further pages
+	units, cursor, err = sts.WorkSpec.WorkUnitsPage(coordinate.WorkUnitQuery{
+		Limit: 10,
+	})
+	if s.NoError(err) {
+		s.Len(units, 5)
+		s.Equal("", cursor)
+	}
+
+	_, _, err = sts.WorkSpec.WorkUnitsPage(coordinate.WorkUnitQuery{
+		Limit:  2,
+		Cursor: "not a cursor",
+	})
+	s.Equal(coordinate.ErrBadCursor, err)
+}
+
+// TestCountWorkUnits checks that WorkSpec.CountWorkUnits() matches
+// the number of work units a following DeleteWorkUnits() removes.
+func (s *Suite) TestCountWorkUnits() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestCountWorkUnits",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	_, err := sts.MakeWorkUnits()
+	if !s.NoError(err) {
+		return
+	}
+
+	queries := []coordinate.WorkUnitQuery{
+		{Names: []string{"available", "missing"}},
+		{Statuses: []coordinate.WorkUnitStatus{
+			coordinate.FinishedUnit,
+			coordinate.FailedUnit,
+		}},
+		{PreviousName: "finished"},
+		{},
+	}
+	expected := []int{1, 2, 2, 2}
+	for i, q := range queries {
+		count, err := sts.WorkSpec.CountWorkUnits(q)
+		if !s.NoError(err) {
+			continue
+		}
+		s.Equal(expected[i], count, "query %v", i)
+
+		// Counting doesn't change anything
+		again, err := sts.WorkSpec.CountWorkUnits(q)
+		if s.NoError(err) {
+			s.Equal(count, again, "query %v", i)
+		}
+
+		deleted, err := sts.WorkSpec.DeleteWorkUnits(q)
+		if s.NoError(err) {
+			s.Equal(count, deleted, "query %v", i)
+		}
+	}
+}
+
 // TestDeleteWorkUnits is a smaller set of tests for
 // WorkSpec.DeleteWorkUnits(), on the assumption that a fair amount of
 // code will typically be shared with GetWorkUnits() and because it is
@@ -309,6 +581,112 @@ func (s *Suite) TestCountWorkUnitStatus() {
 	}
 }
 
+// TestWorkUnitStatuses tests retrieving the statuses of several work
+// units at once.
+func (s *Suite) TestWorkUnitStatuses() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestWorkUnitStatuses",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	_, err := sts.MakeWorkUnits()
+	s.NoError(err)
+
+	statuses, err := sts.WorkSpec.WorkUnitStatuses([]string{
+		"available", "pending", "finished", "failed",
+		"expired", "retryable", "delayed", "missing",
+	})
+	if s.NoError(err) {
+		s.Equal(map[string]coordinate.WorkUnitStatus{
+			"available": coordinate.AvailableUnit,
+			"pending":   coordinate.PendingUnit,
+			"finished":  coordinate.FinishedUnit,
+			"failed":    coordinate.FailedUnit,
+			"expired":   coordinate.AvailableUnit,
+			"retryable": coordinate.AvailableUnit,
+			"delayed":   coordinate.DelayedUnit,
+		}, statuses)
+	}
+
+	statuses, err = sts.WorkSpec.WorkUnitStatuses(nil)
+	if s.NoError(err) {
+		s.Empty(statuses)
+	}
+}
+
+// TestDeleteWorkUnitsPaged tests deleting one page of work units
+// selected by offset and limit.
+func (s *Suite) TestDeleteWorkUnitsPaged() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestDeleteWorkUnitsPaged",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		_, err := sts.AddWorkUnit(name)
+		s.NoError(err)
+	}
+
+	count, err := sts.WorkSpec.DeleteWorkUnits(coordinate.WorkUnitQuery{
+		Offset: 1,
+		Limit:  2,
+	})
+	if s.NoError(err) {
+		s.Equal(2, count)
+	}
+	units, err := sts.WorkSpec.WorkUnits(coordinate.WorkUnitQuery{})
+	if s.NoError(err) {
+		s.Len(units, 3)
+		s.Contains(units, "a")
+		s.Contains(units, "d")
+		s.Contains(units, "e")
+	}
+
+	// An offset alone deletes everything after it
+	count, err = sts.WorkSpec.DeleteWorkUnits(coordinate.WorkUnitQuery{
+		Offset: 1,
+	})
+	if s.NoError(err) {
+		s.Equal(2, count)
+	}
+	units, err = sts.WorkSpec.WorkUnits(coordinate.WorkUnitQuery{})
+	if s.NoError(err) {
+		s.Len(units, 1)
+		s.Contains(units, "a")
+	}
+}
+
+// TestWorkUnitPriorities tests retrieving the priorities of several
+// work units at once.
+func (s *Suite) TestWorkUnitPriorities() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestWorkUnitPriorities",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	for name, priority := range map[string]float64{"a": 10, "b": -1, "c": 0} {
+		_, err := sts.WorkSpec.AddWorkUnit(name, map[string]interface{}{}, coordinate.WorkUnitMeta{Priority: priority})
+		s.NoError(err)
+	}
+
+	priorities, err := sts.WorkSpec.WorkUnitPriorities([]string{"a", "b", "c", "missing"})
+	if s.NoError(err) {
+		s.Equal(map[string]float64{"a": 10, "b": -1, "c": 0}, priorities)
+	}
+
+	priorities, err = sts.WorkSpec.WorkUnitPriorities(nil)
+	if s.NoError(err) {
+		s.Empty(priorities)
+	}
+}
+
 // TestWorkUnitOrder is a very basic test that work units get returned
 // in alphabetic order absent any other constraints.
 func (s *Suite) TestWorkUnitOrder() {
@@ -358,6 +736,91 @@ func (s *Suite) TestWorkUnitPriorityCtor() {
 	sts.CheckWorkUnitOrder(s, "b", "a", "c")
 }
 
+// checkUnitOrdering creates a work spec with the given "order", adds
+// work units "b", "c", and "a" one at a time with priorities 0, 10,
+// and 5, and checks that they are returned in the expected order.
+func (s *Suite) checkUnitOrdering(namespace, order string, expected ...string) {
+	data := map[string]interface{}{}
+	if order != "" {
+		data["order"] = order
+	}
+	sts := SimpleTestSetup{
+		NamespaceName: namespace,
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+		WorkSpecData:  data,
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	var units = []struct {
+		string
+		float64
+	}{
+		{"b", 0},
+		{"c", 10},
+		{"a", 5},
+	}
+	for _, unit := range units {
+		s.Clock.Add(1 * time.Second)
+		_, err := sts.WorkSpec.AddWorkUnit(unit.string, map[string]interface{}{}, coordinate.WorkUnitMeta{Priority: unit.float64})
+		s.NoError(err)
+	}
+
+	sts.CheckWorkUnitOrder(s, expected...)
+}
+
+// TestWorkSpecOrderPriority tests that the default and "priority"
+// orders return work units by priority, then name.
+func (s *Suite) TestWorkSpecOrderPriority() {
+	s.checkUnitOrdering("TestWorkSpecOrderPriority", coordinate.OrderPriority, "c", "a", "b")
+	s.checkUnitOrdering("TestWorkSpecOrderDefault", "", "c", "a", "b")
+}
+
+// TestWorkSpecOrderFIFO tests that the "fifo" order returns the
+// oldest work units first.
+func (s *Suite) TestWorkSpecOrderFIFO() {
+	s.checkUnitOrdering("TestWorkSpecOrderFIFO", coordinate.OrderFIFO, "b", "c", "a")
+}
+
+// TestWorkSpecOrderLIFO tests that the "lifo" order returns the
+// newest work units first.
+func (s *Suite) TestWorkSpecOrderLIFO() {
+	s.checkUnitOrdering("TestWorkSpecOrderLIFO", coordinate.OrderLIFO, "a", "c", "b")
+}
+
+// TestWorkSpecOrderMeta tests that the work spec order is reported
+// in its metadata and that unknown orders are rejected.
+func (s *Suite) TestWorkSpecOrderMeta() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestWorkSpecOrderMeta",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	meta, err := sts.WorkSpec.Meta(false)
+	if s.NoError(err) {
+		s.Equal(coordinate.OrderPriority, meta.Order)
+	}
+
+	err = sts.WorkSpec.SetData(map[string]interface{}{
+		"name":  "spec",
+		"order": coordinate.OrderLIFO,
+	})
+	s.NoError(err)
+	meta, err = sts.WorkSpec.Meta(false)
+	if s.NoError(err) {
+		s.Equal(coordinate.OrderLIFO, meta.Order)
+	}
+
+	_, err = sts.Namespace.SetWorkSpec(map[string]interface{}{
+		"name":  "bad",
+		"order": "random",
+	})
+	s.Exactly(coordinate.ErrBadWorkSpecOrder, err)
+}
+
 // TestWorkUnitPrioritySet tests two different ways of setting work unit
 // priority.
 func (s *Suite) TestWorkUnitPrioritySet() {
@@ -418,6 +881,124 @@ func (s *Suite) TestWorkUnitPrioritySet() {
 	sts.CheckWorkUnitOrder(s, "d", "c", "b", "a")
 }
 
+// TestResetPriorities tests that WorkSpec.ResetPriorities() returns
+// matched work units to the priority of a newly added work unit.
+func (s *Suite) TestResetPriorities() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestResetPriorities",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	fresh, err := sts.WorkSpec.AddWorkUnit("fresh", map[string]interface{}{}, coordinate.WorkUnitMeta{})
+	if !s.NoError(err) {
+		return
+	}
+	meta, err := fresh.Meta()
+	if !s.NoError(err) {
+		return
+	}
+	defaultPriority := meta.Priority
+
+	for _, name := range []string{"a", "b", "c"} {
+		_, err = sts.WorkSpec.AddWorkUnit(name, map[string]interface{}{}, coordinate.WorkUnitMeta{Priority: 10})
+		s.NoError(err)
+	}
+
+	err = sts.WorkSpec.ResetPriorities(coordinate.WorkUnitQuery{
+		Names: []string{"a", "c"},
+	})
+	s.NoError(err)
+
+	expected := map[string]float64{
+		"a": defaultPriority,
+		"b": 10,
+		"c": defaultPriority,
+	}
+	for name, priority := range expected {
+		unit, err := sts.WorkSpec.WorkUnit(name)
+		if s.NoError(err) {
+			s.UnitHasPriority(unit, priority)
+		}
+	}
+
+	err = sts.WorkSpec.ResetPriorities(coordinate.WorkUnitQuery{})
+	s.NoError(err)
+	unit, err := sts.WorkSpec.WorkUnit("b")
+	if s.NoError(err) {
+		s.UnitHasPriority(unit, defaultPriority)
+	}
+}
+
+// TestReorderAvailable tests that WorkSpec.ReorderAvailable() causes
+// work units to be scheduled in the requested order.
+func (s *Suite) TestReorderAvailable() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestReorderAvailable",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	priorities := map[string]float64{
+		"a": 0,
+		"b": 10,
+		"c": 0,
+		"d": 5,
+		"e": 100,
+	}
+	for name, priority := range priorities {
+		_, err := sts.WorkSpec.AddWorkUnit(name, map[string]interface{}{}, coordinate.WorkUnitMeta{Priority: priority})
+		if !s.NoError(err) {
+			return
+		}
+	}
+
+	// "f" is delayed, and would outrank everything else once
+	// it becomes available
+	_, err := sts.WorkSpec.AddWorkUnit("f", map[string]interface{}{}, coordinate.WorkUnitMeta{
+		Priority:  1000,
+		NotBefore: s.Clock.Now().Add(1 * time.Minute),
+	})
+	if !s.NoError(err) {
+		return
+	}
+
+	// "e" is the highest priority, so this makes it pending
+	attempt := sts.RequestOneAttempt(s)
+	s.Equal("e", attempt.WorkUnit().Name())
+
+	// Reorder: "e" is not available and "x" does not exist, so
+	// both are ignored; "b", "d", and the delayed "f" are
+	// unlisted and keep their relative order after the listed
+	// units
+	err = sts.WorkSpec.ReorderAvailable([]string{"c", "e", "x", "a"})
+	if !s.NoError(err) {
+		return
+	}
+
+	unit, err := sts.WorkSpec.WorkUnit("e")
+	if s.NoError(err) {
+		s.UnitHasPriority(unit, 100)
+	}
+	unit, err = sts.WorkSpec.WorkUnit("d")
+	if s.NoError(err) {
+		s.UnitHasPriority(unit, 5)
+	}
+	unit, err = sts.WorkSpec.WorkUnit("f")
+	if s.NoError(err) {
+		s.UnitHasPriority(unit, 1000)
+	}
+
+	// Once "f" becomes available it still comes after the
+	// listed units
+	s.Clock.Add(1 * time.Minute)
+	sts.CheckWorkUnitOrder(s, "c", "a", "f", "b", "d")
+}
+
 // TestWorkUnitData validates that the system can store and update
 // data.
 func (s *Suite) TestWorkUnitData() {
@@ -462,6 +1043,147 @@ func (s *Suite) TestWorkUnitData() {
 	}
 }
 
+// TestWorkUnitSetData validates that a work unit's data can be
+// replaced only while it has no active attempt.
+func (s *Suite) TestWorkUnitSetData() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestWorkUnitSetData",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+		WorkUnitName:  "unit",
+		WorkUnitData:  map[string]interface{}{"value": 1},
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	// An available work unit can be changed
+	err := sts.WorkUnit.SetData(map[string]interface{}{"value": 2})
+	if s.NoError(err) {
+		s.DataMatches(sts.WorkUnit, map[string]interface{}{"value": 2})
+	}
+
+	// A pending work unit cannot
+	attempt := sts.RequestOneAttempt(s)
+	err = sts.WorkUnit.SetData(map[string]interface{}{"value": 3})
+	s.Equal(coordinate.ErrWorkUnitActive, err)
+	s.DataMatches(attempt, map[string]interface{}{"value": 2})
+
+	// Once the attempt expires it can be changed again
+	s.NoError(attempt.Expire(nil))
+	err = sts.WorkUnit.SetData(map[string]interface{}{"value": 4})
+	if s.NoError(err) {
+		s.DataMatches(sts.WorkUnit, map[string]interface{}{"value": 4})
+	}
+
+	// A finished work unit cannot be changed
+	s.Clock.Add(time.Second)
+	attempt = sts.RequestOneAttempt(s)
+	s.DataMatches(attempt, map[string]interface{}{"value": 4})
+	s.NoError(attempt.Finish(map[string]interface{}{"value": 5}))
+	err = sts.WorkUnit.SetData(map[string]interface{}{"value": 6})
+	s.Equal(coordinate.ErrWorkUnitActive, err)
+	s.DataMatches(sts.WorkUnit, map[string]interface{}{"value": 5})
+}
+
+// TestWorkUnitCreatedAt checks that a work unit records when it was
+// first added, and that re-adding it does not change that.
+func (s *Suite) TestWorkUnitCreatedAt() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestWorkUnitCreatedAt",
+		WorkSpecName:  "spec",
+		WorkUnitName:  "unit",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	created := s.Clock.Now()
+	createdAt, err := sts.WorkUnit.CreatedAt()
+	if s.NoError(err) {
+		s.WithinDuration(created, createdAt, 1*time.Millisecond)
+	}
+
+	s.Clock.Add(time.Hour)
+	_, err = sts.WorkSpec.AddWorkUnit("second", map[string]interface{}{}, coordinate.WorkUnitMeta{})
+	s.NoError(err)
+	unit, err := sts.WorkSpec.WorkUnit("second")
+	if s.NoError(err) {
+		createdAt, err = unit.CreatedAt()
+		if s.NoError(err) {
+			s.WithinDuration(created.Add(time.Hour), createdAt, 1*time.Millisecond)
+		}
+	}
+
+	// Re-adding the first unit keeps its original creation time
+	s.Clock.Add(time.Hour)
+	_, err = sts.WorkSpec.AddWorkUnit("unit", map[string]interface{}{"x": 1}, coordinate.WorkUnitMeta{})
+	s.NoError(err)
+	unit, err = sts.WorkSpec.WorkUnit("unit")
+	if s.NoError(err) {
+		createdAt, err = unit.CreatedAt()
+		if s.NoError(err) {
+			s.WithinDuration(created, createdAt, 1*time.Millisecond)
+		}
+	}
+}
+
+// TestChainedCreatedAt checks that a work unit re-added as the output
+// of a chained work spec keeps its original creation time.
+func (s *Suite) TestChainedCreatedAt() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestChainedCreatedAt",
+		WorkerName:    "worker",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	one, err := sts.Namespace.SetWorkSpec(map[string]interface{}{
+		"name": "one",
+		"then": "two",
+	})
+	if !s.NoError(err) {
+		return
+	}
+
+	two, err := sts.Namespace.SetWorkSpec(map[string]interface{}{
+		"name": "two",
+	})
+	if !s.NoError(err) {
+		return
+	}
+
+	created := s.Clock.Now()
+	_, err = two.AddWorkUnit("u2", map[string]interface{}{}, coordinate.WorkUnitMeta{})
+	if !s.NoError(err) {
+		return
+	}
+
+	s.Clock.Add(time.Hour)
+	_, err = one.AddWorkUnit("unit", map[string]interface{}{}, coordinate.WorkUnitMeta{})
+	if !s.NoError(err) {
+		return
+	}
+
+	attempts, err := sts.Worker.RequestAttempts(coordinate.AttemptRequest{
+		WorkSpecs: []string{"one"},
+	})
+	if !(s.NoError(err) && s.Len(attempts, 1)) {
+		return
+	}
+
+	err = attempts[0].Finish(map[string]interface{}{
+		"output": []interface{}{"u2"},
+	})
+	s.NoError(err)
+
+	unit, err := two.WorkUnit("u2")
+	if s.NoError(err) {
+		createdAt, err := unit.CreatedAt()
+		if s.NoError(err) {
+			s.WithinDuration(created, createdAt, 1*time.Millisecond)
+		}
+	}
+}
+
 // TestAddWorkUnitBleedover validates a bug in the postgres backend
 // where adding a duplicate work unit in one work spec would modify
 // similarly-named work units' data in all work specs.
@@ -574,24 +1296,81 @@ func (s *Suite) TestContinuous() {
 		}
 	}
 
-	// While we haven't added any work units yet, since the work
-	// spec is continuous, we should have something
-	makeAttempt(1)
-
-	// If we use SetMeta to turn continuous mode off and on, it
-	// should affect whether work units come back
-	meta, err := sts.WorkSpec.Meta(false)
+	// While we haven't added any work units yet, since the work
+	// spec is continuous, we should have something
+	makeAttempt(1)
+
+	// If we use SetMeta to turn continuous mode off and on, it
+	// should affect whether work units come back
+	meta, err := sts.WorkSpec.Meta(false)
+	if s.NoError(err) {
+		meta.Continuous = false
+		err = sts.WorkSpec.SetMeta(meta)
+		if s.NoError(err) {
+			makeAttempt(0)
+		}
+
+		meta.Continuous = true
+		err = sts.WorkSpec.SetMeta(meta)
+		if s.NoError(err) {
+			makeAttempt(1)
+		}
+	}
+}
+
+// TestContinuousPaused verifies that pausing continuous generation
+// stops new artificial work units while real work units still run.
+func (s *Suite) TestContinuousPaused() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestContinuousPaused",
+		WorkerName:    "worker",
+		WorkSpecData: map[string]interface{}{
+			"name":       "spec",
+			"continuous": true,
+		},
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	meta, err := sts.WorkSpec.Meta(false)
+	if !s.NoError(err) {
+		return
+	}
+	meta.ContinuousPaused = true
+	err = sts.WorkSpec.SetMeta(meta)
+	if !s.NoError(err) {
+		return
+	}
+
+	meta, err = sts.WorkSpec.Meta(false)
 	if s.NoError(err) {
-		meta.Continuous = false
-		err = sts.WorkSpec.SetMeta(meta)
-		if s.NoError(err) {
-			makeAttempt(0)
-		}
+		s.True(meta.Continuous)
+		s.True(meta.ContinuousPaused)
+		s.False(meta.Paused)
+	}
 
-		meta.Continuous = true
-		err = sts.WorkSpec.SetMeta(meta)
-		if s.NoError(err) {
-			makeAttempt(1)
+	// A real work unit still gets scheduled
+	_, err = sts.AddWorkUnit("real")
+	s.NoError(err)
+	s.Clock.Add(5 * time.Second)
+	attempts, err := sts.Worker.RequestAttempts(coordinate.AttemptRequest{})
+	if s.NoError(err) && s.Len(attempts, 1) {
+		s.Equal("real", attempts[0].WorkUnit().Name())
+		s.NoError(attempts[0].Finish(nil))
+	}
+
+	// With nothing else to do, no continuous unit is generated
+	s.Clock.Add(5 * time.Second)
+	sts.RequestNoAttempts(s)
+
+	// Resuming generation produces a continuous unit again
+	meta.ContinuousPaused = false
+	err = sts.WorkSpec.SetMeta(meta)
+	if s.NoError(err) {
+		s.Clock.Add(5 * time.Second)
+		attempts, err = sts.Worker.RequestAttempts(coordinate.AttemptRequest{})
+		if s.NoError(err) && s.Len(attempts, 1) {
+			s.NotEqual("real", attempts[0].WorkUnit().Name())
 		}
 	}
 }
@@ -651,6 +1430,111 @@ func (s *Suite) TestContinuousInterval() {
 	makeAttempt(0)
 }
 
+// TestSetNextContinuous verifies that the next continuous generation
+// time can be moved into the past to generate a work unit right away,
+// or into the future to hold generation off.
+func (s *Suite) TestSetNextContinuous() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestSetNextContinuous",
+		WorkerName:    "worker",
+		WorkSpecData: map[string]interface{}{
+			"name":       "spec",
+			"continuous": true,
+			"interval":   3600,
+		},
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	makeAttempt := func(expected int) {
+		attempts, err := sts.Worker.RequestAttempts(coordinate.AttemptRequest{})
+		if s.NoError(err) {
+			s.Len(attempts, expected)
+			for _, attempt := range attempts {
+				err = attempt.Finish(nil)
+				s.NoError(err)
+			}
+		}
+	}
+
+	// The first continuous unit pushes the next one an hour out
+	makeAttempt(1)
+	s.Clock.Add(1 * time.Second)
+	makeAttempt(0)
+
+	// Resetting the next time to the past generates one now
+	past := s.Clock.Now().Add(-1 * time.Minute)
+	err := sts.WorkSpec.SetNextContinuous(past)
+	if s.NoError(err) {
+		meta, err := sts.WorkSpec.Meta(false)
+		if s.NoError(err) {
+			s.WithinDuration(past, meta.NextContinuous, 1*time.Millisecond)
+		}
+	}
+	makeAttempt(1)
+
+	// ...which again pushes the next one out by the interval
+	meta, err := sts.WorkSpec.Meta(false)
+	if s.NoError(err) {
+		s.WithinDuration(s.Clock.Now().Add(1*time.Hour), meta.NextContinuous, 1*time.Millisecond)
+	}
+
+	// Setting it to the future delays generation past the
+	// interval
+	s.Clock.Add(1 * time.Second)
+	err = sts.WorkSpec.SetNextContinuous(s.Clock.Now().Add(2 * time.Hour))
+	s.NoError(err)
+	s.Clock.Add(90 * time.Minute)
+	makeAttempt(0)
+	s.Clock.Add(31 * time.Minute)
+	makeAttempt(1)
+
+	// Other metadata is unchanged
+	meta, err = sts.WorkSpec.Meta(false)
+	if s.NoError(err) {
+		s.True(meta.Continuous)
+		s.Equal(1*time.Hour, meta.Interval)
+	}
+}
+
+// TestContinuousData verifies that generated continuous work units
+// carry the work spec's "continuous_data".
+func (s *Suite) TestContinuousData() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestContinuousData",
+		WorkerName:    "worker",
+		WorkSpecData: map[string]interface{}{
+			"name":       "spec",
+			"continuous": true,
+			"continuous_data": map[string]interface{}{
+				"seed": "value",
+				"n":    17,
+			},
+		},
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	s.Clock.Add(5 * time.Second)
+	attempt := sts.RequestOneAttempt(s)
+	s.DataMatches(attempt.WorkUnit(), map[string]interface{}{
+		"seed": "value",
+		"n":    17,
+	})
+
+	// Changing the work unit's data shouldn't change what the
+	// next continuous unit gets
+	err := attempt.Finish(map[string]interface{}{"seed": "changed"})
+	s.NoError(err)
+
+	s.Clock.Add(5 * time.Second)
+	attempt = sts.RequestOneAttempt(s)
+	s.DataMatches(attempt.WorkUnit(), map[string]interface{}{
+		"seed": "value",
+		"n":    17,
+	})
+}
+
 // TestMaxRunning tests that setting the max_running limit on a work spec
 // does result in work coming back.
 func (s *Suite) TestMaxRunning() {
@@ -786,6 +1670,70 @@ func (s *Suite) TestRequestSpecificSpec() {
 	}
 }
 
+// TestRequestMinPriority tests that a request with a minimum priority
+// only gets work units at or above that priority, even from a less
+// important work spec, and leaves the rest for other requests.
+func (s *Suite) TestRequestMinPriority() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestRequestMinPriority",
+		WorkerName:    "worker",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	specs := []struct {
+		Name     string
+		Priority int
+		Units    map[string]float64
+	}{
+		{"bulk", 20, map[string]float64{"b1": 0, "b2": 1}},
+		{"interactive", 0, map[string]float64{"urgent": 10, "cleanup": -5}},
+	}
+	for _, specInfo := range specs {
+		spec, err := sts.Namespace.SetWorkSpec(map[string]interface{}{
+			"name":     specInfo.Name,
+			"priority": specInfo.Priority,
+		})
+		if !s.NoError(err) {
+			return
+		}
+		for name, priority := range specInfo.Units {
+			_, err = spec.AddWorkUnit(name, map[string]interface{}{}, coordinate.WorkUnitMeta{Priority: priority})
+			if !s.NoError(err) {
+				return
+			}
+		}
+	}
+
+	request := func(minPriority *float64) []string {
+		s.Clock.Add(5 * time.Second)
+		attempts, err := sts.Worker.RequestAttempts(coordinate.AttemptRequest{
+			NumberOfWorkUnits: 10,
+			MinPriority:       minPriority,
+		})
+		if !s.NoError(err) {
+			return nil
+		}
+		var names []string
+		for _, attempt := range attempts {
+			names = append(names, attempt.WorkUnit().Name())
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	// Even though "bulk" is the more important work spec, none
+	// of its work units are urgent enough
+	urgent := 5.0
+	s.Equal([]string{"urgent"}, request(&urgent))
+	s.Empty(request(&urgent))
+
+	// Everything else is still there for everyone else
+	s.Equal([]string{"b1", "b2"}, request(nil))
+	lowest := -10.0
+	s.Equal([]string{"cleanup"}, request(&lowest))
+}
+
 // TestByRuntime creates two work specs with different runtimes, and
 // validates that requests that want a specific runtime get it.
 func (s *Suite) TestByRuntime() {
@@ -900,6 +1848,94 @@ func (s *Suite) TestByRuntime() {
 	}
 }
 
+// TestUnitRuntimeOverride tests that a work unit's runtime override
+// keeps it away from workers for its work spec's runtime, and sends
+// it to workers for its own runtime.
+func (s *Suite) TestUnitRuntimeOverride() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestUnitRuntimeOverride",
+		WorkerName:    "worker",
+		WorkSpecData: map[string]interface{}{
+			"name":    "spec",
+			"runtime": "go",
+		},
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	_, err := sts.AddWorkUnit("plain")
+	if !s.NoError(err) {
+		return
+	}
+	special, err := sts.WorkSpec.AddWorkUnit("special", map[string]interface{}{}, coordinate.WorkUnitMeta{
+		Runtime: "python",
+	})
+	if !s.NoError(err) {
+		return
+	}
+	meta, err := special.Meta()
+	if s.NoError(err) {
+		s.Equal("python", meta.Runtime)
+	}
+
+	runtimes, err := sts.Namespace.AvailableRuntimes()
+	if s.NoError(err) {
+		s.Equal([]string{"go", "python"}, runtimes)
+	}
+
+	request := func(runtime string) []coordinate.Attempt {
+		s.Clock.Add(5 * time.Second)
+		attempts, err := sts.Worker.RequestAttempts(coordinate.AttemptRequest{
+			NumberOfWorkUnits: 10,
+			Runtimes:          []string{runtime},
+		})
+		s.NoError(err)
+		return attempts
+	}
+
+	// A Go worker only gets the plain work unit
+	attempts := request("go")
+	if s.Len(attempts, 1) {
+		s.Equal("plain", attempts[0].WorkUnit().Name())
+		s.NoError(attempts[0].Finish(nil))
+	}
+	s.Empty(request("go"))
+
+	// A Python worker gets the special one, even though the work
+	// spec is for Go
+	attempts = request("python")
+	if s.Len(attempts, 1) {
+		s.Equal("special", attempts[0].WorkUnit().Name())
+		s.NoError(attempts[0].Retry(nil, 0))
+	}
+
+	// Removing the override sends it back to Go
+	meta.Runtime = ""
+	err = special.SetMeta(meta)
+	if !s.NoError(err) {
+		return
+	}
+	s.Empty(request("python"))
+	attempts = request("go")
+	if s.Len(attempts, 1) {
+		s.Equal("special", attempts[0].WorkUnit().Name())
+		s.NoError(attempts[0].Finish(nil))
+	}
+
+	// Adding it again with the override sends it to Python again
+	_, err = sts.WorkSpec.AddWorkUnit("special", map[string]interface{}{}, coordinate.WorkUnitMeta{
+		Runtime: "python",
+	})
+	if !s.NoError(err) {
+		return
+	}
+	s.Empty(request("go"))
+	attempts = request("python")
+	if s.Len(attempts, 1) {
+		s.Equal("special", attempts[0].WorkUnit().Name())
+	}
+}
+
 // TestNotBeforeDelayedStatus verifies that, if a work unit is created
 // with a "not before" time, its status is returned as DelayedUnit.
 func (s *Suite) TestNotBeforeDelayedStatus() {
@@ -924,6 +1960,130 @@ func (s *Suite) TestNotBeforeDelayedStatus() {
 	sts.CheckUnitStatus(s, coordinate.AvailableUnit)
 }
 
+// TestDelayedEverywhere checks that every way of asking for work
+// unit statuses agrees on which work units are delayed: those that
+// would be available, but whose "not before" time has not arrived.
+// Pending and completed work units keep their status regardless.
+func (s *Suite) TestDelayedEverywhere() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestDelayedEverywhere",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	later := s.Clock.Now().Add(1 * time.Hour)
+	earlier := s.Clock.Now().Add(-1 * time.Hour)
+	for name, notBefore := range map[string]time.Time{
+		"available": {},
+		"past":      earlier,
+		"future":    later,
+		"retried":   {},
+		"pending":   later,
+		"finished":  later,
+	} {
+		unit, err := sts.WorkSpec.AddWorkUnit(name, map[string]interface{}{},
+			coordinate.WorkUnitMeta{NotBefore: notBefore})
+		if !s.NoError(err) {
+			return
+		}
+		var attempt coordinate.Attempt
+		switch name {
+		case "retried", "pending", "finished":
+			attempt, err = sts.Worker.MakeAttempt(unit, 24*time.Hour)
+			if !s.NoError(err) {
+				return
+			}
+		}
+		switch name {
+		case "retried":
+			err = attempt.Retry(nil, 1*time.Hour)
+		case "finished":
+			err = attempt.Finish(nil)
+		}
+		if !s.NoError(err) {
+			return
+		}
+	}
+
+	check := func(expected map[string]coordinate.WorkUnitStatus) {
+		counts := make(map[coordinate.WorkUnitStatus]int)
+		names := make(map[coordinate.WorkUnitStatus][]string)
+		for name, status := range expected {
+			counts[status]++
+			names[status] = append(names[status], name)
+
+			unit, err := sts.WorkSpec.WorkUnit(name)
+			if s.NoError(err) {
+				actual, err := unit.Status()
+				if s.NoError(err) {
+					s.Equal(status, actual, name)
+				}
+			}
+		}
+
+		statuses, err := sts.WorkSpec.WorkUnitStatuses([]string{
+			"available", "past", "future", "retried", "pending", "finished",
+		})
+		if s.NoError(err) {
+			s.Equal(expected, statuses)
+		}
+
+		actualCounts, err := sts.WorkSpec.CountWorkUnitStatus()
+		if s.NoError(err) {
+			s.Equal(counts, actualCounts)
+		}
+
+		for _, status := range []coordinate.WorkUnitStatus{
+			coordinate.AvailableUnit,
+			coordinate.DelayedUnit,
+		} {
+			query := coordinate.WorkUnitQuery{
+				Statuses: []coordinate.WorkUnitStatus{status},
+			}
+			units, err := sts.WorkSpec.WorkUnits(query)
+			if s.NoError(err) {
+				var actualNames []string
+				for name := range units {
+					actualNames = append(actualNames, name)
+				}
+				s.ElementsMatch(names[status], actualNames, status.String())
+			}
+			count, err := sts.WorkSpec.CountWorkUnits(query)
+			if s.NoError(err) {
+				s.Equal(counts[status], count, status.String())
+			}
+		}
+
+		meta, err := sts.WorkSpec.Meta(true)
+		if s.NoError(err) {
+			s.Equal(counts[coordinate.AvailableUnit], meta.AvailableCount)
+			s.Equal(counts[coordinate.PendingUnit], meta.PendingCount)
+		}
+	}
+
+	check(map[string]coordinate.WorkUnitStatus{
+		"available": coordinate.AvailableUnit,
+		"past":      coordinate.AvailableUnit,
+		"future":    coordinate.DelayedUnit,
+		"retried":   coordinate.DelayedUnit,
+		"pending":   coordinate.PendingUnit,
+		"finished":  coordinate.FinishedUnit,
+	})
+
+	// Once the delays pass, those work units become available
+	s.Clock.Add(2 * time.Hour)
+	check(map[string]coordinate.WorkUnitStatus{
+		"available": coordinate.AvailableUnit,
+		"past":      coordinate.AvailableUnit,
+		"future":    coordinate.AvailableUnit,
+		"retried":   coordinate.AvailableUnit,
+		"pending":   coordinate.PendingUnit,
+		"finished":  coordinate.FinishedUnit,
+	})
+}
+
 // TestNotBeforeAttempt verifies that, if a work unit is created with
 // a "not before" time, it is not returned as an attempt.
 func (s *Suite) TestNotBeforeAttempt() {
@@ -1202,3 +2362,108 @@ func (s *Suite) TestSummarize() {
 		}
 	}
 }
+
+// TestKeyNormalization checks that work unit keys that normalize to
+// the same string name a single work unit.
+func (s *Suite) TestKeyNormalization() {
+	if !coordinate.Supports(s.Coordinate, coordinate.FeatureKeyNormalization) {
+		s.T().Skip("backend does not support key normalization")
+	}
+	setter := s.Coordinate.(coordinate.KeyNormalizerSetter)
+	setter.SetKeyNormalizer(strings.ToLower)
+	defer setter.SetKeyNormalizer(nil)
+
+	sts := SimpleTestSetup{
+		NamespaceName: "TestKeyNormalization",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	unit, err := sts.WorkSpec.AddWorkUnit("Foo", map[string]interface{}{"n": 1}, coordinate.WorkUnitMeta{})
+	if s.NoError(err) {
+		s.Equal("foo", unit.Name())
+	}
+	_, err = sts.WorkSpec.AddWorkUnit("foo", map[string]interface{}{"n": 2}, coordinate.WorkUnitMeta{})
+	s.NoError(err)
+
+	unit, err = sts.WorkSpec.WorkUnit("FOO")
+	if s.NoError(err) {
+		s.Equal("foo", unit.Name())
+		data, err := unit.Data()
+		if s.NoError(err) {
+			s.EqualValues(2, data["n"])
+		}
+	}
+
+	units, err := sts.WorkSpec.WorkUnits(coordinate.WorkUnitQuery{})
+	if s.NoError(err) && s.Len(units, 1) {
+		s.Contains(units, "foo")
+	}
+
+	units, err = sts.WorkSpec.WorkUnits(coordinate.WorkUnitQuery{
+		Names: []string{"fOO"},
+	})
+	if s.NoError(err) {
+		s.Len(units, 1)
+	}
+}
+
+// TestAddWorkUnitItems checks that a batch add with an invalid item
+// adds the remaining items and reports each result, that a batch can
+// replace existing work units, and that a batch added to a deleted
+// work spec fails as a whole.
+func (s *Suite) TestAddWorkUnitItems() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestAddWorkUnitItems",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	results, err := coordinate.AddWorkUnitItems(sts.WorkSpec, []coordinate.AddWorkUnitItem{
+		{Key: "a", Data: map[string]interface{}{}},
+		{Key: "", Data: map[string]interface{}{}},
+		{Key: "b", Data: map[string]interface{}{}, Meta: coordinate.WorkUnitMeta{Priority: 5}},
+	})
+	if s.NoError(err) {
+		s.Equal([]coordinate.AddWorkUnitResult{
+			{Key: "a"},
+			{Key: "", Err: coordinate.ErrNoWorkUnitName},
+			{Key: "b"},
+		}, results)
+	}
+
+	units, err := sts.WorkSpec.WorkUnits(coordinate.WorkUnitQuery{})
+	if s.NoError(err) {
+		s.Len(units, 2)
+		s.Contains(units, "a")
+		s.Contains(units, "b")
+	}
+
+	_, err = coordinate.AddWorkUnitItems(sts.WorkSpec, []coordinate.AddWorkUnitItem{
+		{Key: "a", Data: map[string]interface{}{"k": "v"}},
+		{Key: "c", Data: map[string]interface{}{}},
+	})
+	if s.NoError(err) {
+		units, err = sts.WorkSpec.WorkUnits(coordinate.WorkUnitQuery{})
+		if s.NoError(err) {
+			s.Len(units, 3)
+		}
+	}
+	unit, err := sts.WorkSpec.WorkUnit("a")
+	if s.NoError(err) {
+		data, err := unit.Data()
+		if s.NoError(err) {
+			s.Equal(map[string]interface{}{"k": "v"}, data)
+		}
+	}
+
+	err = sts.Namespace.DestroyWorkSpec(sts.WorkSpecName)
+	if s.NoError(err) {
+		_, err = coordinate.AddWorkUnitItems(sts.WorkSpec, []coordinate.AddWorkUnitItem{
+			{Key: "d", Data: map[string]interface{}{}},
+		})
+		s.Error(err)
+	}
+}
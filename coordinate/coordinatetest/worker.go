@@ -5,6 +5,7 @@ package coordinatetest
 
 import (
 	"github.com/diffeo/go-coordinate/coordinate"
+	"sort"
 	"time"
 )
 
@@ -295,6 +296,65 @@ func (s *Suite) TestWorkerAttempts() {
 	}
 }
 
+// TestAttemptsInWindow checks that a worker's attempts can be
+// filtered by their start times.
+func (s *Suite) TestAttemptsInWindow() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestAttemptsInWindow",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	// Start one attempt per minute
+	start := s.Clock.Now()
+	for _, name := range []string{"a", "b", "c"} {
+		unit, err := sts.AddWorkUnit(name)
+		if !s.NoError(err) {
+			return
+		}
+		_, err = sts.Worker.MakeAttempt(unit, time.Duration(0))
+		if !s.NoError(err) {
+			return
+		}
+		s.Clock.Add(1 * time.Minute)
+	}
+
+	checkWindow := func(from, to time.Time, expected ...string) {
+		attempts, err := sts.Worker.AttemptsInWindow(from, to)
+		if !s.NoError(err) {
+			return
+		}
+		names := make([]string, len(attempts))
+		for i, attempt := range attempts {
+			names[i] = attempt.WorkUnit().Name()
+		}
+		sort.Strings(names)
+		if expected == nil {
+			expected = []string{}
+		}
+		s.Equal(expected, names)
+	}
+
+	checkWindow(start, start.Add(1*time.Minute), "a")
+	checkWindow(start.Add(1*time.Minute), time.Time{}, "b", "c")
+	checkWindow(time.Time{}, start.Add(2*time.Minute), "a", "b")
+	checkWindow(time.Time{}, time.Time{}, "a", "b", "c")
+	checkWindow(start.Add(30*time.Second), start.Add(90*time.Second), "b")
+	checkWindow(start.Add(3*time.Minute), time.Time{})
+
+	// Another worker's attempts are not counted
+	other, err := sts.Namespace.Worker("other")
+	if !s.NoError(err) {
+		return
+	}
+	attempts, err := other.AttemptsInWindow(start, time.Time{})
+	if s.NoError(err) {
+		s.Empty(attempts)
+	}
+}
+
 // TestDeactivateChild tests that deactivating a worker with a parent
 // works successfully.  This is a regression test for a specific issue
 // in the REST API.
@@ -316,3 +376,607 @@ func (s *Suite) TestDeactivateChild() {
 	err = child.Deactivate()
 	s.NoError(err)
 }
+
+// TestWorkerChildrenOfParent lists the children of one parent among
+// several, and checks that the full worker list includes everybody.
+func (s *Suite) TestWorkerChildrenOfParent() {
+	sts := SimpleTestSetup{NamespaceName: "TestWorkerChildrenOfParent"}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	parents := map[string][]string{
+		"alpha": {"alpha1", "alpha2", "alpha3"},
+		"beta":  {"beta1"},
+	}
+	for parentName, childNames := range parents {
+		parent, err := sts.Namespace.Worker(parentName)
+		if !s.NoError(err) {
+			return
+		}
+		for _, childName := range childNames {
+			child, err := sts.Namespace.Worker(childName)
+			if !s.NoError(err) {
+				return
+			}
+			err = child.SetParent(parent)
+			s.NoError(err)
+		}
+	}
+	_, err := sts.Namespace.Worker("orphan")
+	s.NoError(err)
+
+	for parentName, childNames := range parents {
+		parent, err := sts.Namespace.Worker(parentName)
+		if !s.NoError(err) {
+			continue
+		}
+		kids, err := parent.Children()
+		if !s.NoError(err) {
+			continue
+		}
+		var names []string
+		for _, kid := range kids {
+			names = append(names, kid.Name())
+		}
+		sort.Strings(names)
+		s.Equal(childNames, names, parentName)
+	}
+
+	workers, err := sts.Namespace.Workers()
+	if s.NoError(err) {
+		s.Len(workers, 7)
+		for _, name := range []string{"alpha", "alpha1", "beta1", "orphan"} {
+			if s.Contains(workers, name) {
+				s.Equal(name, workers[name].Name())
+			}
+		}
+	}
+}
+
+// TestDeactivateWorkers deactivates a group of workers by name
+// prefix, then by expiration, and checks that their attempts in an
+// ExpireWithWorker work spec are released, as Deactivate would.
+func (s *Suite) TestDeactivateWorkers() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestDeactivateWorkers",
+		WorkSpecName:  "spec",
+		WorkSpecData: map[string]interface{}{
+			"expire_with_worker": true,
+		},
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	// A work spec without the flag, whose attempts outlive
+	// their workers
+	other, err := sts.Namespace.SetWorkSpec(map[string]interface{}{
+		"name": "other",
+	})
+	if !s.NoError(err) {
+		return
+	}
+
+	names := []string{"host1-a", "host1-b", "host2-a"}
+	attempts := make(map[string]coordinate.Attempt)
+	otherAttempts := make(map[string]coordinate.Attempt)
+	for i, name := range names {
+		worker, err := sts.Namespace.Worker(name)
+		if !s.NoError(err) {
+			return
+		}
+		now := s.Clock.Now()
+		expiration := now.Add(time.Duration(i+1) * time.Hour)
+		err = worker.Update(nil, now, expiration, "")
+		if !s.NoError(err) {
+			return
+		}
+		unit, err := sts.AddWorkUnit(name)
+		if !s.NoError(err) {
+			return
+		}
+		attempts[name], err = worker.MakeAttempt(unit, 24*time.Hour)
+		if !s.NoError(err) {
+			return
+		}
+		unit, err = other.AddWorkUnit(name, map[string]interface{}{}, coordinate.WorkUnitMeta{})
+		if !s.NoError(err) {
+			return
+		}
+		otherAttempts[name], err = worker.MakeAttempt(unit, 24*time.Hour)
+		if !s.NoError(err) {
+			return
+		}
+	}
+
+	// A child of a matched worker that does not match itself
+	child, err := sts.Namespace.Worker("child")
+	if !s.NoError(err) {
+		return
+	}
+	parent, err := sts.Namespace.Worker("host1-a")
+	if !s.NoError(err) {
+		return
+	}
+	err = child.SetParent(parent)
+	if !s.NoError(err) {
+		return
+	}
+	err = child.Update(nil, s.Clock.Now(), s.Clock.Now().Add(24*time.Hour), "")
+	if !s.NoError(err) {
+		return
+	}
+
+	checkActive := func(expected map[string]bool) {
+		for name, active := range expected {
+			worker, err := sts.Namespace.Worker(name)
+			if !s.NoError(err, name) {
+				continue
+			}
+			actual, err := worker.Active()
+			if s.NoError(err, name) {
+				s.Equal(active, actual, name)
+			}
+			status := coordinate.Pending
+			if !active {
+				status = coordinate.Expired
+			}
+			s.AttemptStatus(status, attempts[name])
+			s.AttemptStatus(coordinate.Pending, otherAttempts[name])
+		}
+	}
+
+	count, err := sts.Namespace.DeactivateWorkers(coordinate.WorkerQuery{
+		NamePrefix: "host1-",
+	})
+	if s.NoError(err) {
+		s.Equal(2, count)
+	}
+	checkActive(map[string]bool{
+		"host1-a": false,
+		"host1-b": false,
+		"host2-a": true,
+	})
+	active, err := child.Active()
+	if s.NoError(err) {
+		s.True(active)
+	}
+	for _, name := range []string{"host1-a", "host1-b"} {
+		unit, err := sts.WorkSpec.WorkUnit(name)
+		if !s.NoError(err) {
+			continue
+		}
+		status, err := unit.Status()
+		if s.NoError(err) {
+			s.Equal(coordinate.AvailableUnit, status, name)
+		}
+	}
+
+	// Doing it again changes nothing
+	count, err = sts.Namespace.DeactivateWorkers(coordinate.WorkerQuery{
+		NamePrefix: "host1-",
+	})
+	if s.NoError(err) {
+		s.Equal(0, count)
+	}
+
+	// Nothing has expired yet
+	count, err = sts.Namespace.DeactivateWorkers(coordinate.WorkerQuery{
+		Expired: true,
+	})
+	if s.NoError(err) {
+		s.Equal(0, count)
+	}
+	checkActive(map[string]bool{"host2-a": true})
+
+	s.Clock.Add(4 * time.Hour)
+	count, err = sts.Namespace.DeactivateWorkers(coordinate.WorkerQuery{
+		Expired: true,
+	})
+	if s.NoError(err) {
+		s.Equal(1, count)
+	}
+	checkActive(map[string]bool{"host2-a": false})
+}
+
+// TestDeleteWorkers deletes a worker holding a pending attempt and
+// checks that its work unit becomes available to another worker,
+// while a worker holding a finished work unit is kept.
+func (s *Suite) TestDeleteWorkers() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestDeleteWorkers",
+		WorkerName:    "survivor",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	pending, err := sts.AddWorkUnit("pending")
+	if !s.NoError(err) {
+		return
+	}
+	finished, err := sts.AddWorkUnit("finished")
+	if !s.NoError(err) {
+		return
+	}
+
+	doomed, err := sts.Namespace.Worker("gone-pending")
+	if !s.NoError(err) {
+		return
+	}
+	attempt, err := doomed.MakeAttempt(pending, 24*time.Hour)
+	if !s.NoError(err) {
+		return
+	}
+	s.AttemptStatus(coordinate.Pending, attempt)
+
+	done, err := sts.Namespace.Worker("gone-finished")
+	if !s.NoError(err) {
+		return
+	}
+	attempt, err = done.MakeAttempt(finished, 0)
+	if !s.NoError(err) {
+		return
+	}
+	err = attempt.Finish(nil)
+	if !s.NoError(err) {
+		return
+	}
+
+	// A child of the deleted worker outlives it
+	child, err := sts.Namespace.Worker("child")
+	if !s.NoError(err) {
+		return
+	}
+	err = child.SetParent(doomed)
+	if !s.NoError(err) {
+		return
+	}
+
+	count, err := sts.Namespace.DeleteWorkers(coordinate.WorkerQuery{
+		NamePrefix: "gone-",
+	})
+	if s.NoError(err) {
+		s.Equal(1, count)
+	}
+
+	workers, err := sts.Namespace.Workers()
+	if s.NoError(err) {
+		s.NotContains(workers, "gone-pending")
+		s.Contains(workers, "gone-finished")
+		s.Contains(workers, "child")
+	}
+	parent, err := child.Parent()
+	if s.NoError(err) {
+		s.Nil(parent)
+	}
+
+	status, err := pending.Status()
+	if s.NoError(err) {
+		s.Equal(coordinate.AvailableUnit, status)
+	}
+	attempts, err := pending.Attempts()
+	if s.NoError(err) {
+		s.Empty(attempts)
+	}
+	status, err = finished.Status()
+	if s.NoError(err) {
+		s.Equal(coordinate.FinishedUnit, status)
+	}
+
+	// Another worker can now do the work unit
+	attempt = sts.RequestOneAttempt(s)
+	s.Equal("pending", attempt.WorkUnit().Name())
+
+	// Deleting again finds nothing more to delete
+	count, err = sts.Namespace.DeleteWorkers(coordinate.WorkerQuery{
+		NamePrefix: "gone-",
+	})
+	if s.NoError(err) {
+		s.Equal(0, count)
+	}
+}
+
+// TestRebalanceAttempts checks that rebalancing releases the newest
+// pending attempts of an overloaded worker, and that the released
+// work units can be picked up by another worker.
+func (s *Suite) TestRebalanceAttempts() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestRebalanceAttempts",
+		WorkerName:    "busy",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	// The busy worker picks up a burst of four attempts, one
+	// second apart; the quiet worker has only one
+	var busy []coordinate.Attempt
+	for _, name := range []string{"a", "b", "c", "d"} {
+		unit, err := sts.AddWorkUnit(name)
+		if !s.NoError(err) {
+			return
+		}
+		attempt, err := sts.Worker.MakeAttempt(unit, 0)
+		if !s.NoError(err) {
+			return
+		}
+		busy = append(busy, attempt)
+		s.Clock.Add(time.Second)
+	}
+	quiet, err := sts.Namespace.Worker("quiet")
+	if !s.NoError(err) {
+		return
+	}
+	unit, err := sts.AddWorkUnit("e")
+	if !s.NoError(err) {
+		return
+	}
+	quietAttempt, err := quiet.MakeAttempt(unit, 0)
+	if !s.NoError(err) {
+		return
+	}
+
+	count, err := sts.Namespace.RebalanceAttempts(2)
+	if s.NoError(err) {
+		s.Equal(2, count)
+	}
+
+	// The busy worker keeps its two oldest attempts
+	s.AttemptStatus(coordinate.Pending, busy[0])
+	s.AttemptStatus(coordinate.Pending, busy[1])
+	s.AttemptStatus(coordinate.Expired, busy[2])
+	s.AttemptStatus(coordinate.Expired, busy[3])
+	s.AttemptStatus(coordinate.Pending, quietAttempt)
+	attempts, err := sts.Worker.ActiveAttempts()
+	if s.NoError(err) {
+		s.Len(attempts, 2)
+	}
+	for name, expected := range map[string]coordinate.WorkUnitStatus{
+		"a": coordinate.PendingUnit,
+		"c": coordinate.AvailableUnit,
+		"d": coordinate.AvailableUnit,
+	} {
+		unit, err := sts.WorkSpec.WorkUnit(name)
+		if !s.NoError(err, name) {
+			continue
+		}
+		status, err := unit.Status()
+		if s.NoError(err, name) {
+			s.Equal(expected, status, name)
+		}
+	}
+
+	// The released work units can go to the quiet worker
+	attempts, err = quiet.RequestAttempts(coordinate.AttemptRequest{
+		NumberOfWorkUnits: 10,
+	})
+	if s.NoError(err) {
+		var names []string
+		for _, attempt := range attempts {
+			names = append(names, attempt.WorkUnit().Name())
+		}
+		s.ElementsMatch([]string{"c", "d"}, names)
+	}
+
+	// Nobody is over the limit any more
+	count, err = sts.Namespace.RebalanceAttempts(3)
+	if s.NoError(err) {
+		s.Equal(0, count)
+	}
+}
+
+// TestRebalanceNoRetry checks that rebalancing does not use up a
+// work unit's retries.
+func (s *Suite) TestRebalanceNoRetry() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestRebalanceNoRetry",
+		WorkerName:    "worker",
+		WorkSpecData: map[string]interface{}{
+			"name":         "spec",
+			"max_retries":  1,
+			"retry_delays": []interface{}{60},
+		},
+		WorkUnitName: "unit",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	for i := 0; i < 3; i++ {
+		attempt := sts.RequestOneAttempt(s)
+		count, err := sts.Namespace.RebalanceAttempts(0)
+		if s.NoError(err) {
+			s.Equal(1, count)
+		}
+		s.AttemptStatus(coordinate.Expired, attempt)
+		sts.CheckUnitStatus(s, coordinate.AvailableUnit)
+	}
+}
+
+// batchAttempts adds work units with the given names to sts's work
+// spec and makes an attempt on each for sts's worker.
+func (s *Suite) batchAttempts(sts *SimpleTestSetup, names ...string) []coordinate.Attempt {
+	attempts := make([]coordinate.Attempt, len(names))
+	for i, name := range names {
+		unit, err := sts.AddWorkUnit(name)
+		if !s.NoError(err) {
+			s.FailNow("could not create work unit")
+		}
+		attempts[i], err = sts.Worker.MakeAttempt(unit, 0)
+		if !s.NoError(err) {
+			s.FailNow("could not create attempt")
+		}
+	}
+	return attempts
+}
+
+// TestFinishAttempts tests finishing several attempts in one call.
+func (s *Suite) TestFinishAttempts() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestFinishAttempts",
+		WorkerName:    "worker",
+		WorkSpecData: map[string]interface{}{
+			"name": "one",
+			"then": "two",
+		},
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	two, err := sts.Namespace.SetWorkSpec(map[string]interface{}{
+		"name":     "two",
+		"disabled": true,
+	})
+	if !s.NoError(err) {
+		return
+	}
+
+	attempts := s.batchAttempts(&sts, "a", "b", "c")
+	err = sts.Worker.FinishAttempts(attempts, []map[string]interface{}{
+		{"output": []string{"x"}},
+		nil,
+		{"result": "c"},
+	})
+	s.NoError(err)
+	for _, attempt := range attempts {
+		s.AttemptStatus(coordinate.Finished, attempt)
+	}
+	s.DataMatches(attempts[2], map[string]interface{}{"result": "c"})
+
+	active, err := sts.Worker.ActiveAttempts()
+	if s.NoError(err) {
+		s.Empty(active)
+	}
+
+	units, err := two.WorkUnits(coordinate.WorkUnitQuery{})
+	if s.NoError(err) {
+		s.Len(units, 1)
+		s.Contains(units, "x")
+	}
+
+	// An empty batch does nothing
+	s.NoError(sts.Worker.FinishAttempts(nil, nil))
+}
+
+// TestFailRetryAttempts tests failing and retrying several attempts
+// in one call.
+func (s *Suite) TestFailRetryAttempts() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestFailRetryAttempts",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	attempts := s.batchAttempts(&sts, "a", "b", "c", "d")
+	s.NoError(sts.Worker.FailAttempts(attempts[:2], nil))
+	s.NoError(sts.Worker.RetryAttempts(attempts[2:], nil, time.Hour))
+
+	statuses, err := sts.WorkSpec.WorkUnitStatuses([]string{"a", "b", "c", "d"})
+	if s.NoError(err) {
+		s.Equal(map[string]coordinate.WorkUnitStatus{
+			"a": coordinate.FailedUnit,
+			"b": coordinate.FailedUnit,
+			"c": coordinate.DelayedUnit,
+			"d": coordinate.DelayedUnit,
+		}, statuses)
+	}
+	s.AttemptStatus(coordinate.Failed, attempts[0])
+	s.AttemptStatus(coordinate.Retryable, attempts[3])
+
+	// A failed attempt can still be finished
+	s.NoError(sts.Worker.FinishAttempts(attempts[:1], nil))
+	s.AttemptStatus(coordinate.Finished, attempts[0])
+}
+
+// TestCompleteAttemptsErrors tests that a batch completion that
+// cannot complete every attempt changes none of them.
+func (s *Suite) TestCompleteAttemptsErrors() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestCompleteAttemptsErrors",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	attempts := s.batchAttempts(&sts, "a", "b")
+
+	err := sts.Worker.FinishAttempts(attempts, []map[string]interface{}{nil})
+	s.Equal(coordinate.ErrBatchLength, err)
+
+	other, err := sts.Namespace.Worker("other")
+	if s.NoError(err) {
+		err = other.FailAttempts(attempts, nil)
+		s.Equal(coordinate.ErrWrongWorker, err)
+	}
+
+	s.NoError(attempts[0].Finish(nil))
+	err = sts.Worker.RetryAttempts(attempts, nil, 0)
+	s.Equal(coordinate.ErrNotPending, err)
+
+	s.AttemptStatus(coordinate.Finished, attempts[0])
+	s.AttemptStatus(coordinate.Pending, attempts[1])
+}
+
+// TestCompleteAttemptsMixed tests completing several attempts with
+// different outcomes in one call, where some of them fail.
+func (s *Suite) TestCompleteAttemptsMixed() {
+	sts := SimpleTestSetup{
+		NamespaceName: "TestCompleteAttemptsMixed",
+		WorkerName:    "worker",
+		WorkSpecName:  "spec",
+	}
+	sts.SetUp(s)
+	defer sts.TearDown(s)
+
+	attempts := s.batchAttempts(&sts, "finish", "fail", "retry", "bad")
+
+	other, err := sts.Namespace.Worker("other")
+	if !s.NoError(err) {
+		return
+	}
+	unit, err := sts.AddWorkUnit("other")
+	if !s.NoError(err) {
+		return
+	}
+	otherAttempt, err := other.MakeAttempt(unit, 0)
+	if !s.NoError(err) {
+		return
+	}
+
+	errs, err := sts.Worker.CompleteAttempts([]coordinate.AttemptOutcome{
+		{Attempt: attempts[0], Status: coordinate.Finished, Data: map[string]interface{}{"ok": true}},
+		{Attempt: attempts[1], Status: coordinate.Failed},
+		{Attempt: attempts[2], Status: coordinate.Retryable, Delay: time.Hour},
+		{Attempt: attempts[3], Status: coordinate.Pending},
+		{Attempt: otherAttempt, Status: coordinate.Finished},
+		{Attempt: nil, Status: coordinate.Finished},
+	})
+	if !s.NoError(err) {
+		return
+	}
+	s.Equal([]error{
+		nil,
+		nil,
+		nil,
+		coordinate.ErrBadOutcome,
+		coordinate.ErrWrongWorker,
+		coordinate.ErrBadOutcome,
+	}, errs)
+
+	statuses, err := sts.WorkSpec.WorkUnitStatuses([]string{
+		"finish", "fail", "retry", "bad", "other",
+	})
+	if s.NoError(err) {
+		s.Equal(map[string]coordinate.WorkUnitStatus{
+			"finish": coordinate.FinishedUnit,
+			"fail":   coordinate.FailedUnit,
+			"retry":  coordinate.DelayedUnit,
+			"bad":    coordinate.PendingUnit,
+			"other":  coordinate.PendingUnit,
+		}, statuses)
+	}
+	s.DataMatches(attempts[0], map[string]interface{}{"ok": true})
+	s.AttemptStatus(coordinate.Retryable, attempts[2])
+}
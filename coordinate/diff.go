@@ -0,0 +1,74 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package coordinate
+
+import "reflect"
+
+// Keys in each value of a DataDiff() result.
+const (
+	// DataDiffOld holds a key's value in the first attempt's
+	// data.  It is absent if the key was added.
+	DataDiffOld = "old"
+
+	// DataDiffNew holds a key's value in the second attempt's
+	// data.  It is absent if the key was removed.
+	DataDiffNew = "new"
+)
+
+// DataDiff compares the data of two of unit's attempts, as returned
+// from Attempt.Data(), to show what changed from a to b.  The result
+// has an entry for each key whose value differs, which is itself a
+// map with the value in a under DataDiffOld and the value in b under
+// DataDiffNew; a key added in b has no DataDiffOld entry, and a key
+// removed in b has no DataDiffNew entry.  Keys with equal values are
+// left out, so identical data produces an empty map.  If either
+// attempt belongs to a different work unit, including one with the
+// same name in another work spec or namespace, returns
+// ErrWrongWorkUnit.
+func DataDiff(unit WorkUnit, a, b Attempt) (map[string]interface{}, error) {
+	spec := unit.WorkSpec()
+	var data [2]map[string]interface{}
+	for i, attempt := range []Attempt{a, b} {
+		attemptUnit := attempt.WorkUnit()
+		attemptSpec := attemptUnit.WorkSpec()
+		if attemptUnit.Name() != unit.Name() ||
+			attemptSpec.Name() != spec.Name() ||
+			attemptSpec.Namespace().Name() != spec.Namespace().Name() {
+			return nil, ErrWrongWorkUnit
+		}
+		var err error
+		data[i], err = attempt.Data()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return diffData(data[0], data[1]), nil
+}
+
+// diffData compares two data maps.  The result has an entry for
+// every key whose value differs, which is a map holding the value
+// from old under DataDiffOld and the value from new under
+// DataDiffNew, omitting either if the key is missing there.  Values
+// are compared with reflect.DeepEqual, so a change to a nested map
+// reports the whole top-level value.
+func diffData(old, new map[string]interface{}) map[string]interface{} {
+	diff := make(map[string]interface{})
+	for key, oldValue := range old {
+		newValue, present := new[key]
+		if !present {
+			diff[key] = map[string]interface{}{DataDiffOld: oldValue}
+		} else if !reflect.DeepEqual(oldValue, newValue) {
+			diff[key] = map[string]interface{}{
+				DataDiffOld: oldValue,
+				DataDiffNew: newValue,
+			}
+		}
+	}
+	for key, newValue := range new {
+		if _, present := old[key]; !present {
+			diff[key] = map[string]interface{}{DataDiffNew: newValue}
+		}
+	}
+	return diff
+}
@@ -17,6 +17,16 @@ var ErrNoWorkSpecName = errors.New("No 'name' key in work spec")
 // string.
 var ErrBadWorkSpecName = errors.New("Work spec 'name' must be a string")
 
+// ErrBadWorkSpecOrder is returned as an error from functions that
+// create or modify work specs if the "order" key names an unknown
+// ordering.
+var ErrBadWorkSpecOrder = errors.New("Work spec 'order' must be \"priority\", \"fifo\", or \"lifo\"")
+
+// ErrBadRetryDelays is returned as an error from functions that
+// create or modify work specs if the "retry_delays" key contains a
+// negative delay.
+var ErrBadRetryDelays = errors.New("Work spec 'retry_delays' must be non-negative numbers of seconds")
+
 // ErrChangedName is returned from WorkSpec.SetData() if it tries to
 // change the name of the work spec.
 var ErrChangedName = errors.New("Cannot change work spec 'name'")
@@ -25,10 +35,42 @@ var ErrChangedName = errors.New("Cannot change work spec 'name'")
 // is no longer the active attempt.
 var ErrLostLease = errors.New("No longer the active attempt")
 
+// ErrLeaseTotalExceeded is returned as an error from Attempt.Renew()
+// if the attempt has already been held for its work spec's
+// MaxLeaseTotal.  The attempt is expired.
+var ErrLeaseTotalExceeded = errors.New("Attempt held for longer than max_lease_total")
+
 // ErrNotPending is returned as an error from Attempt methods that try
 // to change an Attempt's status if the status is not Pending.
 var ErrNotPending = errors.New("Attempt is not pending")
 
+// ErrBatchLength is returned from Worker.FinishAttempts() and
+// similar batch calls if the data list is non-nil but does not have
+// one entry per attempt.
+var ErrBatchLength = errors.New("Batch data does not match attempts")
+
+// ErrWrongWorker is returned from Worker.FinishAttempts() and
+// similar batch calls if an attempt belongs to a different worker.
+var ErrWrongWorker = errors.New("Attempt belongs to a different worker")
+
+// ErrWrongWorkUnit is returned from DataDiff() if an attempt
+// belongs to a different work unit.
+var ErrWrongWorkUnit = errors.New("Attempt belongs to a different work unit")
+
+// ErrBadOutcome is returned from Worker.CompleteAttempts() for an
+// outcome with no Attempt, or whose status is not Finished, Failed,
+// or Retryable.
+var ErrBadOutcome = errors.New("Attempt outcome must have an attempt and be finished, failed, or retryable")
+
+// ErrWorkUnitActive is returned as an error from WorkUnit.SetData()
+// if the work unit has an active attempt, which holds its own copy
+// of the work unit data.
+var ErrWorkUnitActive = errors.New("Work unit has an active attempt")
+
+// ErrBadCursor is returned from WorkSpec.WorkUnitsPage() if the
+// query's Cursor is not a cursor it returned.
+var ErrBadCursor = errors.New("Invalid work unit cursor")
+
 // ErrCannotBecomeContinuous is returned as an error from
 // WorkSpec.SetMeta() if the work spec was not defined with the
 // "continuous" flag set.
@@ -39,10 +81,25 @@ var ErrCannotBecomeContinuous = errors.New("Cannot set work spec to continuous")
 // different backends.  This is impossible in ordinary usage.
 var ErrWrongBackend = errors.New("Cannot combine coordinate objects from different backends")
 
+// ErrWrongNamespace is returned from functions that take two
+// coordinate objects and combine them, if the backend requires them
+// to be in the same namespace and they are not.
+var ErrWrongNamespace = errors.New("Cannot combine coordinate objects from different namespaces")
+
+// ErrNoSuchReservation is returned from ReleaseReservation() and
+// CompleteReservation() if the token does not name a reservation
+// that is still held.
+var ErrNoSuchReservation = errors.New("No such reservation")
+
 // ErrNoWork is returned from scheduler calls when there is no work to
 // do.
 var ErrNoWork = errors.New("No work to do")
 
+// ErrWatchNotSupported is returned from WorkWatcher.WatchWork() if
+// the work spec cannot deliver notifications of new work.  Callers
+// should fall back to polling.
+var ErrWatchNotSupported = errors.New("Work spec does not support watching for work")
+
 // ErrWorkUnitNotList is returned from ExtractAddWorkUnitItem if a
 // work unit as specified in a work unit's "output" field is not a
 // list.
@@ -58,6 +115,10 @@ var ErrWorkUnitTooShort = errors.New("too few parameters to work unit")
 // that is not a number.
 var ErrBadPriority = errors.New("priority must be a number")
 
+// ErrNoWorkUnitName is returned from AddWorkUnitItem.Validate() if a
+// work unit has an empty name.
+var ErrNoWorkUnitName = errors.New("work unit must have a name")
+
 // ErrGone is returned from various points in the API if the object is
 // determined to not exist, for instance because another caller in a
 // shared database has deleted it.  It makes no commitment as to which
@@ -75,6 +136,52 @@ func (err ErrNoSuchWorkSpec) Error() string {
 	return fmt.Sprintf("No such work spec %v", err.Name)
 }
 
+// ErrNoSuchNamespace is returned by functions that look up a namespace
+// without creating it, when it does not exist.
+type ErrNoSuchNamespace struct {
+	Name string
+}
+
+func (err ErrNoSuchNamespace) Error() string {
+	return fmt.Sprintf("No such namespace %v", err.Name)
+}
+
+// ErrTooManyNamespaces is returned by Coordinate.Namespace() if the
+// named namespace does not exist, and creating it would exceed the
+// limit set with NamespaceLimiter.SetMaxNamespaces().
+type ErrTooManyNamespaces struct {
+	Name string
+}
+
+func (err ErrTooManyNamespaces) Error() string {
+	return fmt.Sprintf("Cannot create namespace %q: too many namespaces", err.Name)
+}
+
+// ErrWorkSpecDataTooLarge is returned by Namespace.SetWorkSpec() and
+// WorkSpec.SetData() if the encoded work spec data is larger than the
+// limit set with WorkSpecDataLimiter.SetMaxWorkSpecData().
+type ErrWorkSpecDataTooLarge struct {
+	WorkSpec string
+	Size     int
+	Limit    int
+}
+
+func (err ErrWorkSpecDataTooLarge) Error() string {
+	return fmt.Sprintf("Work spec %q data is %v bytes, more than the limit of %v", err.WorkSpec, err.Size, err.Limit)
+}
+
+// ErrSchemaVersionMismatch is returned by CheckSchemaVersion() if a
+// work spec's schema version is not the one the caller expected.
+type ErrSchemaVersionMismatch struct {
+	WorkSpec string
+	Expected int
+	Actual   int
+}
+
+func (err ErrSchemaVersionMismatch) Error() string {
+	return fmt.Sprintf("Work spec %q has schema version %v, expected %v", err.WorkSpec, err.Actual, err.Expected)
+}
+
 // ErrNoSuchWorkUnit is returned by WorkSpec.WorkUnit() and similar
 // functions that want to look up a work unit by name, but cannot find
 // it.
@@ -0,0 +1,68 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package coordinate
+
+import "sync"
+
+// SchedulingGater is implemented by Coordinate backends that can
+// consult a caller-provided function before scheduling work.  Like
+// DataHistorySetter, it is reached with a type assertion.  This is
+// meant for programs that embed a backend; an external controller
+// can set WorkSpecMeta.Gated instead.
+type SchedulingGater interface {
+	// SetSchedulingGate sets a function that Worker.RequestAttempts()
+	// calls with the namespace and work spec name of each work
+	// spec it is considering.  If it returns false, the work spec
+	// is skipped as though it were paused, and a worker gets work
+	// from some other work spec or none at all.  The gate only
+	// affects scheduling; attempts already handed out continue.
+	//
+	// The function may be called while the backend holds locks,
+	// so it should be fast and must not call back into the
+	// Coordinate.  Passing nil, the default, allows every work
+	// spec.
+	SetSchedulingGate(gate func(namespace, workSpec string) bool)
+}
+
+// SchedulingGate holds a scheduling gate function, to help backends
+// implement SchedulingGater.  The zero value allows every work spec.
+// It is safe for concurrent use.
+type SchedulingGate struct {
+	lock sync.RWMutex
+	gate func(namespace, workSpec string) bool
+}
+
+// Set changes the gate function.  nil allows every work spec.
+func (g *SchedulingGate) Set(gate func(namespace, workSpec string) bool) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.gate = gate
+}
+
+// Allow reports whether the gate allows scheduling the named work
+// spec in the named namespace.
+func (g *SchedulingGate) Allow(namespace, workSpec string) bool {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return g.gate == nil || g.gate(namespace, workSpec)
+}
+
+// LimitMetas returns a metadata map limited to the work specs in
+// the named namespace that the gate allows.  If there is no gate
+// function, metas is returned unmodified; otherwise a new map is
+// returned.
+func (g *SchedulingGate) LimitMetas(namespace string, metas map[string]*WorkSpecMeta) map[string]*WorkSpecMeta {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	if g.gate == nil {
+		return metas
+	}
+	newMetas := make(map[string]*WorkSpecMeta)
+	for name, meta := range metas {
+		if g.gate(namespace, name) {
+			newMetas[name] = meta
+		}
+	}
+	return newMetas
+}
@@ -4,9 +4,12 @@
 package coordinate
 
 import (
+	"encoding/base64"
 	"github.com/diffeo/go-coordinate/cborrpc"
 	"github.com/mitchellh/mapstructure"
+	"math"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 )
@@ -33,6 +36,11 @@ type WorkSpecData struct {
 	// running generated work units for continuous work specs.
 	Interval float64
 
+	// ContinuousData specifies the data dictionary given to each
+	// generated work unit for continuous work specs.  If empty,
+	// generated work units have empty data.
+	ContinuousData map[string]interface{} `mapstructure:"continuous_data"`
+
 	// Priority specifies an absolute priority for this work spec.
 	// Work specs with higher priority will always run before
 	// work specs with lower priority.  Defaults to 0.
@@ -68,6 +76,39 @@ type WorkSpecData struct {
 	// limit.
 	MaxRetries int `mapstructure:"max_retries"`
 
+	// ExpireWithWorker specifies that pending attempts should be
+	// expired when their worker dies, without waiting for the
+	// attempts' own expiration times.
+	ExpireWithWorker bool `mapstructure:"expire_with_worker"`
+
+	// MaxLeaseTotal specifies the maximum time, in seconds, that
+	// an attempt may be held across all of its renewals.  If
+	// zero, there is no limit.
+	MaxLeaseTotal float64 `mapstructure:"max_lease_total"`
+
+	// HeartbeatExtension specifies, in seconds, how far past
+	// each worker update that worker's pending attempts are
+	// extended.  If zero, worker updates do not affect attempts.
+	HeartbeatExtension float64 `mapstructure:"heartbeat_extension"`
+
+	// DefaultLease specifies, in seconds, how long attempts last
+	// if the worker does not ask for a specific time.  If zero,
+	// the system default applies.
+	DefaultLease float64 `mapstructure:"default_lease"`
+
+	// RetryDelays specifies, in seconds, how long a work unit
+	// waits before it becomes available again after its first,
+	// second, and later retries.  The last delay applies to all
+	// later retries.  If empty, retries are not delayed unless
+	// the worker asks for it.
+	RetryDelays []float64 `mapstructure:"retry_delays"`
+
+	// FailureData specifies additional data to record on work
+	// units that the system itself fails, for instance because
+	// they exceeded MaxRetries.  The failure reason is recorded
+	// as "traceback" unless this contains that key.
+	FailureData map[string]interface{} `mapstructure:"failure_data"`
+
 	// Then specifies the name of another work spec that runs
 	// after this one.  On successful completion, if Then is a
 	// non-empty string and the updated work unit data contains
@@ -78,6 +119,14 @@ type WorkSpecData struct {
 	// Runtime specifies the name and possibly version of a
 	// language runtime required to run this work spec.
 	Runtime string
+
+	// Order specifies the order in which work units are handed
+	// out, "priority" (the default), "fifo", or "lifo".
+	Order string
+
+	// SchemaVersion specifies the version of the data format
+	// this work spec and its work units use.
+	SchemaVersion int `mapstructure:"schema_version"`
 }
 
 // ExtractWorkSpecMeta fills in as much of a WorkSpecMeta object as
@@ -108,6 +157,22 @@ func ExtractWorkSpecMeta(workSpecDict map[string]interface{}) (name string, meta
 			err = ErrNoWorkSpecName
 		}
 	}
+	if err == nil {
+		switch data.Order {
+		case "":
+			data.Order = OrderPriority
+		case OrderPriority, OrderFIFO, OrderLIFO:
+		default:
+			err = ErrBadWorkSpecOrder
+		}
+	}
+	if err == nil {
+		for _, delay := range data.RetryDelays {
+			if delay < 0 {
+				err = ErrBadRetryDelays
+			}
+		}
+	}
 	if err == nil {
 		name = data.Name
 		if data.Weight == 0 {
@@ -126,12 +191,122 @@ func ExtractWorkSpecMeta(workSpecDict map[string]interface{}) (name string, meta
 		meta.MaxRunning = data.MaxRunning
 		meta.MaxAttemptsReturned = data.MaxGetwork
 		meta.MaxRetries = data.MaxRetries
+		meta.ExpireWithWorker = data.ExpireWithWorker
+		meta.MaxLeaseTotal = time.Duration(data.MaxLeaseTotal * float64(time.Second))
+		meta.HeartbeatExtension = time.Duration(data.HeartbeatExtension * float64(time.Second))
+		meta.DefaultLease = time.Duration(data.DefaultLease * float64(time.Second))
+		for _, delay := range data.RetryDelays {
+			meta.RetryDelays = append(meta.RetryDelays, time.Duration(delay*float64(time.Second)))
+		}
 		meta.NextWorkSpecName = data.Then
 		meta.Runtime = data.Runtime
+		meta.Order = data.Order
+		meta.SchemaVersion = data.SchemaVersion
 	}
 	return
 }
 
+// RetryDelay returns the delay from meta.RetryDelays that applies to
+// a work unit's nth retry, counting from 1.  It returns zero if there
+// is no retry schedule.
+func (meta *WorkSpecMeta) RetryDelay(n int) time.Duration {
+	if len(meta.RetryDelays) == 0 {
+		return 0
+	}
+	if n < 1 {
+		n = 1
+	}
+	if n > len(meta.RetryDelays) {
+		n = len(meta.RetryDelays)
+	}
+	return meta.RetryDelays[n-1]
+}
+
+// CheckSchemaVersion checks that a work spec's schema version, from
+// its WorkSpecMeta, matches the version the caller expects.  If it
+// does not, returns ErrSchemaVersionMismatch.  A worker can call this
+// before running any of the work spec's work units, to avoid
+// misinterpreting data in a format it does not understand.
+func CheckSchemaVersion(spec WorkSpec, expected int) error {
+	meta, err := spec.Meta(false)
+	if err != nil {
+		return err
+	}
+	if meta.SchemaVersion != expected {
+		return ErrSchemaVersionMismatch{
+			WorkSpec: spec.Name(),
+			Expected: expected,
+			Actual:   meta.SchemaVersion,
+		}
+	}
+	return nil
+}
+
+// ExtractContinuousData returns the data dictionary for a newly
+// generated work unit of a continuous work spec, based on the
+// "continuous_data" key in the work spec definition.  The result is
+// always a new non-nil map, and nested maps and lists in it are
+// copies, so changing it does not change the work spec definition.
+func ExtractContinuousData(workSpecDict map[string]interface{}) (map[string]interface{}, error) {
+	data := WorkSpecData{}
+	err := mapstructure.Decode(workSpecDict, &data)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]interface{}, len(data.ContinuousData))
+	for key, value := range data.ContinuousData {
+		result[key] = copyValue(value)
+	}
+	return result, nil
+}
+
+// copyValue returns a copy of a value from a data dictionary,
+// recursively copying maps and lists.  Other values are returned
+// as they are.
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = copyValue(item)
+		}
+		return result
+	case map[interface{}]interface{}:
+		result := make(map[interface{}]interface{}, len(v))
+		for key, item := range v {
+			result[key] = copyValue(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = copyValue(item)
+		}
+		return result
+	default:
+		return value
+	}
+}
+
+// ExtractFailureData returns the data dictionary to record when the
+// system fails a work unit on its own, for instance because it has
+// exceeded its work spec's MaxRetries.  reason is a short
+// human-readable description of the failure, which is stored as
+// "traceback".  If the work spec definition has a "failure_data"
+// key, its contents are included as well, and override the reason.
+// A malformed "failure_data" is ignored, though ExtractWorkSpecMeta
+// would have rejected it in the first place.
+func ExtractFailureData(workSpecDict map[string]interface{}, reason string) map[string]interface{} {
+	result := map[string]interface{}{"traceback": reason}
+	data := WorkSpecData{}
+	if mapstructure.Decode(workSpecDict, &data) == nil {
+		for key, value := range data.FailureData {
+			result[key] = copyValue(value)
+		}
+	}
+	return result
+}
+
 // AddWorkUnitMeta describes the metadata fields that can appear
 // in work unit output.
 type AddWorkUnitMeta struct {
@@ -282,3 +457,186 @@ func ExtractAddWorkUnitItem(obj interface{}, now time.Time) (result AddWorkUnitI
 
 	return
 }
+
+// Validate checks that an AddWorkUnitItem could be added to a work
+// spec: it must have a non-empty key and a finite priority.
+func (item AddWorkUnitItem) Validate() error {
+	if item.Key == "" {
+		return ErrNoWorkUnitName
+	}
+	if math.IsNaN(item.Meta.Priority) || math.IsInf(item.Meta.Priority, 0) {
+		return ErrBadPriority
+	}
+	return nil
+}
+
+// AddWorkUnitResult is the outcome of adding a single work unit as
+// part of a batch.
+type AddWorkUnitResult struct {
+	// Key is the name of the work unit.
+	Key string
+
+	// Err is nil if the work unit was added, or the reason it
+	// was not.
+	Err error
+}
+
+// WorkUnitBatchAdder is implemented by work specs that can add many
+// work units in a single operation.  Like WorkWatcher, it is reached
+// with a type assertion on a WorkSpec; AddWorkUnitItems() uses it
+// when it is available.
+type WorkUnitBatchAdder interface {
+	// AddWorkUnits adds every one of items to this work spec,
+	// as though AddWorkUnit() were called on each in order.
+	// Every item must already pass Validate().  Returns an
+	// error if the batch could not be added; in that case the
+	// local backends will not have added any of the items.
+	AddWorkUnits(items []AddWorkUnitItem) error
+}
+
+// AddWorkUnitItems adds a batch of work units to a work spec, in
+// order.  An item that fails Validate() does not stop the remaining
+// items from being added; its result records why it was skipped.
+// Returns one result per item, in the same order as items.  If the
+// work spec itself fails to add the valid items, for instance
+// because it has been deleted, returns that error and no results.
+func AddWorkUnitItems(spec WorkSpec, items []AddWorkUnitItem) ([]AddWorkUnitResult, error) {
+	results := make([]AddWorkUnitResult, len(items))
+	var valid []AddWorkUnitItem
+	for i, item := range items {
+		results[i].Key = item.Key
+		results[i].Err = item.Validate()
+		if results[i].Err == nil {
+			valid = append(valid, item)
+		}
+	}
+	if len(valid) == 0 {
+		return results, nil
+	}
+	if adder, ok := spec.(WorkUnitBatchAdder); ok {
+		if err := adder.AddWorkUnits(valid); err != nil {
+			return nil, err
+		}
+		return results, nil
+	}
+	for _, item := range valid {
+		if _, err := spec.AddWorkUnit(item.Key, item.Data, item.Meta); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// workUnitCursorPrefix begins every work unit cursor, so that
+// arbitrary strings are not mistaken for cursors.
+const workUnitCursorPrefix = "wu1:"
+
+// ResolveCursor returns a copy of q with its Cursor, if any,
+// replaced by the equivalent PreviousName and a zero Offset.  Returns
+// ErrBadCursor if the Cursor was not produced by PageWorkUnits().
+func (q WorkUnitQuery) ResolveCursor() (WorkUnitQuery, error) {
+	if q.Cursor == "" {
+		return q, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil || !strings.HasPrefix(string(raw), workUnitCursorPrefix) {
+		return q, ErrBadCursor
+	}
+	q.PreviousName = strings.TrimPrefix(string(raw), workUnitCursorPrefix)
+	q.Offset = 0
+	q.Cursor = ""
+	return q, nil
+}
+
+// PageWorkUnits implements WorkSpec.WorkUnitsPage() in terms of
+// WorkSpec.WorkUnits().  It resolves the query's cursor into a
+// starting name, asks for one more work unit than the limit to
+// tell whether there is another page, and returns a cursor naming
+// the last work unit it returns.
+func PageWorkUnits(spec WorkSpec, q WorkUnitQuery) (map[string]WorkUnit, string, error) {
+	q, err := q.ResolveCursor()
+	if err != nil {
+		return nil, "", err
+	}
+	if q.Limit <= 0 {
+		units, err := spec.WorkUnits(q)
+		return units, "", err
+	}
+	limit := q.Limit
+	q.Limit++
+	units, err := spec.WorkUnits(q)
+	if err != nil || len(units) <= limit {
+		return units, "", err
+	}
+	names := make([]string, 0, len(units))
+	for name := range units {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names[limit:] {
+		delete(units, name)
+	}
+	cursor := base64.RawURLEncoding.EncodeToString(
+		[]byte(workUnitCursorPrefix + names[limit-1]))
+	return units, cursor, nil
+}
+
+// WorkSpecChain follows the Successors() links from the named work
+// spec, returning that work spec's name followed by every work spec
+// reachable from it, each exactly once, in breadth-first order.
+// cyclic is true if any of those work specs leads back to one already
+// in the chain.  If a successor does not exist, returns the chain so
+// far and ErrNoSuchWorkSpec.
+func WorkSpecChain(namespace Namespace, name string) (chain []string, cyclic bool, err error) {
+	seen := map[string]bool{name: true}
+	chain = []string{name}
+	for i := 0; i < len(chain); i++ {
+		var spec WorkSpec
+		spec, err = namespace.WorkSpec(chain[i])
+		if err != nil {
+			return
+		}
+		var next []string
+		next, err = spec.Successors()
+		if err != nil {
+			return
+		}
+		for _, nextName := range next {
+			if seen[nextName] {
+				cyclic = true
+				continue
+			}
+			seen[nextName] = true
+			chain = append(chain, nextName)
+		}
+	}
+	return
+}
+
+// CompleteAttempts implements Worker.CompleteAttempts() by completing
+// each attempt in turn with its own Finish(), Fail(), or Retry()
+// call.
+func CompleteAttempts(worker Worker, outcomes []AttemptOutcome) []error {
+	errs := make([]error, len(outcomes))
+	for i, outcome := range outcomes {
+		if outcome.Attempt == nil {
+			errs[i] = ErrBadOutcome
+			continue
+		}
+		if outcome.Attempt.Worker().Name() != worker.Name() {
+			errs[i] = ErrWrongWorker
+			continue
+		}
+		switch outcome.Status {
+		case Finished:
+			errs[i] = outcome.Attempt.Finish(outcome.Data)
+		case Failed:
+			errs[i] = outcome.Attempt.Fail(outcome.Data)
+		case Retryable:
+			errs[i] = outcome.Attempt.Retry(outcome.Data, outcome.Delay)
+		default:
+			errs[i] = ErrBadOutcome
+		}
+	}
+	return errs
+}
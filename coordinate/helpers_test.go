@@ -5,6 +5,7 @@ package coordinate
 
 import (
 	"github.com/stretchr/testify/assert"
+	"math"
 	"testing"
 	"time"
 )
@@ -74,3 +75,73 @@ func TestOutputLists(t *testing.T) {
 		},
 	}, items)
 }
+
+func TestAddWorkUnitItemValidate(t *testing.T) {
+	assert.NoError(t, AddWorkUnitItem{Key: "unit"}.Validate())
+	assert.Equal(t, ErrNoWorkUnitName, AddWorkUnitItem{}.Validate())
+	assert.Equal(t, ErrBadPriority, AddWorkUnitItem{
+		Key:  "unit",
+		Meta: WorkUnitMeta{Priority: math.NaN()},
+	}.Validate())
+	assert.Equal(t, ErrBadPriority, AddWorkUnitItem{
+		Key:  "unit",
+		Meta: WorkUnitMeta{Priority: math.Inf(1)},
+	}.Validate())
+}
+
+func TestExtractRetryDelays(t *testing.T) {
+	_, meta, err := ExtractWorkSpecMeta(map[string]interface{}{
+		"name":         "spec",
+		"retry_delays": []interface{}{10, 60, 1.5},
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []time.Duration{
+			10 * time.Second,
+			60 * time.Second,
+			1500 * time.Millisecond,
+		}, meta.RetryDelays)
+	}
+
+	_, _, err = ExtractWorkSpecMeta(map[string]interface{}{
+		"name":         "spec",
+		"retry_delays": []interface{}{10, -1},
+	})
+	assert.Equal(t, ErrBadRetryDelays, err)
+}
+
+func TestRetryDelay(t *testing.T) {
+	meta := WorkSpecMeta{}
+	assert.Equal(t, time.Duration(0), meta.RetryDelay(1))
+
+	meta.RetryDelays = []time.Duration{10 * time.Second, time.Minute}
+	assert.Equal(t, 10*time.Second, meta.RetryDelay(0))
+	assert.Equal(t, 10*time.Second, meta.RetryDelay(1))
+	assert.Equal(t, time.Minute, meta.RetryDelay(2))
+	assert.Equal(t, time.Minute, meta.RetryDelay(3))
+}
+
+// TestExtractContinuousDataCopies checks that changing nested values
+// in generated work unit data does not change the work spec.
+func TestExtractContinuousDataCopies(t *testing.T) {
+	spec := map[string]interface{}{
+		"name": "spec",
+		"continuous_data": map[string]interface{}{
+			"nested": map[string]interface{}{"k": "v"},
+			"list":   []interface{}{"a"},
+		},
+	}
+	data, err := ExtractContinuousData(spec)
+	if !assert.NoError(t, err) {
+		return
+	}
+	data["nested"].(map[string]interface{})["k"] = "changed"
+	data["list"].([]interface{})[0] = "changed"
+
+	again, err := ExtractContinuousData(spec)
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]interface{}{
+			"nested": map[string]interface{}{"k": "v"},
+			"list":   []interface{}{"a"},
+		}, again)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package coordinate
+
+import "sync"
+
+// KeyNormalizerSetter is implemented by Coordinate backends that can
+// rewrite work unit keys, for instance to make them case-insensitive.
+// Like DataHistorySetter, it is reached with a type assertion.
+type KeyNormalizerSetter interface {
+	// SetKeyNormalizer sets a function that is applied to every
+	// work unit key on its way into the backend.  This includes
+	// keys passed to WorkSpec.AddWorkUnit() and WorkSpec.WorkUnit(),
+	// the Names in a WorkUnitQuery, the names passed to
+	// WorkSpec.ReorderAvailable(), and the keys of work units
+	// chained from a finished attempt's output, so that "Foo" and
+	// "foo" name the same work unit.  WorkUnit.Name() returns the
+	// normalized key.
+	//
+	// The function must be applied symmetrically, on both adds
+	// and lookups, or a work unit could be stored under one key
+	// and searched for under another; for that reason it should
+	// be idempotent, and it should be set before any work units
+	// are created and not changed afterwards.  Work units that
+	// already exist are not renamed.  Passing nil, the default,
+	// leaves keys unchanged.
+	SetKeyNormalizer(normalize func(string) string)
+}
+
+// KeyNormalizer holds a key normalization function, to help backends
+// implement KeyNormalizerSetter.  The zero value leaves keys
+// unchanged.  It is safe for concurrent use.
+type KeyNormalizer struct {
+	lock      sync.RWMutex
+	normalize func(string) string
+}
+
+// Set changes the normalization function.  nil disables
+// normalization.
+func (n *KeyNormalizer) Set(normalize func(string) string) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.normalize = normalize
+}
+
+// Key returns the normalized form of a single key.
+func (n *KeyNormalizer) Key(key string) string {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+	if n.normalize == nil {
+		return key
+	}
+	return n.normalize(key)
+}
+
+// Keys returns the normalized forms of a list of keys.  If there is
+// no normalization function, returns keys itself.
+func (n *KeyNormalizer) Keys(keys []string) []string {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+	if n.normalize == nil || keys == nil {
+		return keys
+	}
+	result := make([]string, len(keys))
+	for i, key := range keys {
+		result[i] = n.normalize(key)
+	}
+	return result
+}
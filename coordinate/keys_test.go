@@ -0,0 +1,29 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package coordinate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyNormalizerZero(t *testing.T) {
+	var n KeyNormalizer
+	assert.Equal(t, " Foo ", n.Key(" Foo "))
+	assert.Equal(t, []string{"A", "b"}, n.Keys([]string{"A", "b"}))
+	assert.Nil(t, n.Keys(nil))
+}
+
+func TestKeyNormalizer(t *testing.T) {
+	var n KeyNormalizer
+	n.Set(strings.ToLower)
+	assert.Equal(t, "foo", n.Key("Foo"))
+	assert.Equal(t, []string{"a", "b"}, n.Keys([]string{"A", "b"}))
+	assert.Nil(t, n.Keys(nil))
+
+	n.Set(nil)
+	assert.Equal(t, "Foo", n.Key("Foo"))
+}
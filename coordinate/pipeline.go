@@ -0,0 +1,272 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package coordinate
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Kinds of PipelineIssue.
+const (
+	// PipelineUnworked flags a pipeline stage that has available
+	// work units, but no live worker is running any of its work
+	// units or is idle and could pick them up.  Either there are
+	// no workers at all for it, or the workers holding its
+	// pending attempts have been deactivated or have stopped
+	// checking in, and every other live worker is busy.
+	PipelineUnworked = "unworked"
+
+	// PipelinePaused flags a paused pipeline stage with work
+	// waiting for it, either its own available work units or
+	// available or pending work units in earlier stages.
+	PipelinePaused = "paused"
+
+	// PipelineMissing flags a pipeline stage that names a next
+	// work spec that does not exist, so its output work units
+	// have nowhere to go.
+	PipelineMissing = "missing"
+)
+
+// PipelineIssue describes one problem found by
+// Namespace.PipelineHealth().
+type PipelineIssue struct {
+	// Kind is the kind of problem, one of PipelineUnworked,
+	// PipelinePaused, or PipelineMissing.
+	Kind string `json:"kind"`
+
+	// WorkSpec is the name of the pipeline stage with the
+	// problem.
+	WorkSpec string `json:"work_spec"`
+
+	// Available is the number of available work units in
+	// WorkSpec.
+	Available int `json:"available"`
+
+	// Upstream is the number of available and pending work units
+	// in all of the stages that feed into WorkSpec, directly or
+	// indirectly.
+	Upstream int `json:"upstream"`
+
+	// Successor, for PipelineMissing issues, is the name of the
+	// next work spec that does not exist.
+	Successor string `json:"successor,omitempty"`
+
+	// Message is a human-readable description of the problem.
+	Message string `json:"message"`
+}
+
+// pipelineStage is what PipelineHealth knows about one work spec.
+type pipelineStage struct {
+	spec         WorkSpec
+	meta         WorkSpecMeta
+	successors   []string
+	predecessors []string
+}
+
+// PipelineHealth implements Namespace.PipelineHealth() using only
+// the public Coordinate interfaces.  It considers every work spec
+// that has a successor or a predecessor, and returns the issues
+// sorted by work spec name and then kind.  A worker is live if it is
+// active and its expiration time is not before now, and idle if it
+// is live and has no active attempts.  This reads every work spec's
+// metadata and, for stages with available work, the active attempts
+// of pending work units and of every worker, so it is not cheap.
+func PipelineHealth(namespace Namespace, now time.Time) ([]PipelineIssue, error) {
+	names, err := namespace.WorkSpecNames()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read every work spec and link them up
+	stages := make(map[string]*pipelineStage)
+	for _, name := range names {
+		spec, err := namespace.WorkSpec(name)
+		if _, missing := err.(ErrNoSuchWorkSpec); missing {
+			// Deleted since WorkSpecNames()
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		stage := &pipelineStage{spec: spec}
+		stage.meta, err = spec.Meta(true)
+		if err == nil {
+			stage.successors, err = spec.Successors()
+		}
+		if err != nil {
+			return nil, err
+		}
+		stages[name] = stage
+	}
+	for name, stage := range stages {
+		for _, next := range stage.successors {
+			if nextStage := stages[next]; nextStage != nil {
+				nextStage.predecessors = append(nextStage.predecessors, name)
+			}
+		}
+	}
+
+	// Whether any live worker is idle, computed the first time a
+	// stage with available work has no live worker of its own
+	var idleKnown, idle bool
+
+	issues := []PipelineIssue{}
+	for name, stage := range stages {
+		if len(stage.successors) == 0 && len(stage.predecessors) == 0 {
+			// Not part of a pipeline
+			continue
+		}
+		issue := PipelineIssue{
+			WorkSpec:  name,
+			Available: stage.meta.AvailableCount,
+			Upstream:  upstreamBacklog(stages, name),
+		}
+
+		for _, next := range stage.successors {
+			if stages[next] == nil {
+				missing := issue
+				missing.Kind = PipelineMissing
+				missing.Successor = next
+				missing.Message = fmt.Sprintf("next work spec %q does not exist", next)
+				issues = append(issues, missing)
+			}
+		}
+
+		if stage.meta.Paused {
+			if issue.Available > 0 || issue.Upstream > 0 {
+				issue.Kind = PipelinePaused
+				issue.Message = fmt.Sprintf("paused with %v available work units and %v upstream", issue.Available, issue.Upstream)
+				issues = append(issues, issue)
+			}
+		} else if issue.Available > 0 {
+			worked, err := hasLiveWorker(stage.spec, stage.meta, now)
+			if err == nil && !worked && !idleKnown {
+				idle, err = hasIdleWorker(namespace, now)
+				idleKnown = err == nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			if !worked && !idle {
+				issue.Kind = PipelineUnworked
+				issue.Message = fmt.Sprintf("%v available work units but no live workers", issue.Available)
+				issues = append(issues, issue)
+			}
+		}
+	}
+
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].WorkSpec != issues[j].WorkSpec {
+			return issues[i].WorkSpec < issues[j].WorkSpec
+		}
+		if issues[i].Kind != issues[j].Kind {
+			return issues[i].Kind < issues[j].Kind
+		}
+		return issues[i].Successor < issues[j].Successor
+	})
+	return issues, nil
+}
+
+// upstreamBacklog adds up the available and pending work units in
+// every stage that leads to name, following predecessor links.
+func upstreamBacklog(stages map[string]*pipelineStage, name string) int {
+	seen := map[string]bool{name: true}
+	queue := []string{name}
+	backlog := 0
+	for len(queue) > 0 {
+		stage := stages[queue[0]]
+		queue = queue[1:]
+		for _, prev := range stage.predecessors {
+			if seen[prev] {
+				continue
+			}
+			seen[prev] = true
+			queue = append(queue, prev)
+			meta := stages[prev].meta
+			backlog += meta.AvailableCount + meta.PendingCount
+		}
+	}
+	return backlog
+}
+
+// hasLiveWorker determines whether any pending work unit in spec is
+// held by a live worker.  meta is spec's metadata with counts.
+func hasLiveWorker(spec WorkSpec, meta WorkSpecMeta, now time.Time) (bool, error) {
+	if meta.PendingCount == 0 {
+		return false, nil
+	}
+	query := WorkUnitQuery{
+		Statuses: []WorkUnitStatus{PendingUnit},
+		Limit:    100,
+	}
+	for {
+		units, err := spec.WorkUnits(query)
+		if err != nil {
+			return false, err
+		}
+		for name, unit := range units {
+			attempt, err := unit.ActiveAttempt()
+			if err != nil {
+				return false, err
+			}
+			if attempt != nil {
+				live, err := isWorkerLive(attempt.Worker(), now)
+				if err != nil {
+					return false, err
+				}
+				if live {
+					return true, nil
+				}
+			}
+			if name > query.PreviousName {
+				query.PreviousName = name
+			}
+		}
+		if len(units) < query.Limit {
+			return false, nil
+		}
+	}
+}
+
+// hasIdleWorker determines whether any worker in namespace is live
+// but has no active attempts, and so could pick up available work.
+func hasIdleWorker(namespace Namespace, now time.Time) (bool, error) {
+	workers, err := namespace.Workers()
+	if err != nil {
+		return false, err
+	}
+	for _, worker := range workers {
+		live, err := isWorkerLive(worker, now)
+		if err != nil {
+			return false, err
+		}
+		if !live {
+			continue
+		}
+		attempts, err := worker.ActiveAttempts()
+		if err != nil {
+			return false, err
+		}
+		if len(attempts) == 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isWorkerLive determines whether worker is active and has checked in
+// recently enough that its expiration time has not passed.
+func isWorkerLive(worker Worker, now time.Time) (bool, error) {
+	active, err := worker.Active()
+	if err != nil || !active {
+		return false, err
+	}
+	expiration, err := worker.Expiration()
+	if err != nil {
+		return false, err
+	}
+	return !expiration.Before(now), nil
+}
@@ -0,0 +1,191 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package coordinate
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// reservationWorker is the name of the worker that holds every
+// reservation made by Reserve() in a namespace.  Older versions made
+// a worker per reservation, named "reservation-" and a random
+// suffix, and findReservation() still accepts those.
+const reservationWorker = "reservations"
+
+// Reservation is a short claim on a single work unit, for consumers
+// such as scripts that are not long-lived workers.  It is an attempt
+// held by a worker shared by all of a namespace's reservations;
+// rather than keeping track of these, the consumer keeps Token and
+// passes it to ReleaseReservation() or CompleteReservation() before
+// Expires.
+type Reservation struct {
+	// Token identifies the reservation.  It is opaque to callers.
+	Token string `json:"token"`
+
+	// WorkSpec is the name of the reserved work unit's work
+	// spec.
+	WorkSpec string `json:"work_spec"`
+
+	// WorkUnit is the name of the reserved work unit.
+	WorkUnit string `json:"work_unit"`
+
+	// Data is the work unit data at the time it was reserved.
+	Data map[string]interface{} `json:"data"`
+
+	// Expires is the time the reservation ends, after which the
+	// work unit becomes available to others again.
+	Expires time.Time `json:"expires"`
+}
+
+// reservationToken is the decoded form of Reservation.Token.  It
+// names the attempt that holds the reservation.
+type reservationToken struct {
+	WorkSpec string `json:"s"`
+	WorkUnit string `json:"u"`
+	Attempt  string `json:"a"`
+	Worker   string `json:"w"`
+}
+
+// Reserve claims one available work unit from the named work specs,
+// or any work spec if workSpecs is empty.  The reservation lasts for
+// ttl, or the work spec's default lease if ttl is zero.  If
+// there is no work to do, returns nil and no error.
+//
+// This is implemented using only the public Coordinate interfaces: it
+// requests a single attempt with the namespace's reservation worker.
+// If anything fails after that, the attempt is released again.
+func Reserve(ns Namespace, workSpecs []string, ttl time.Duration) (*Reservation, error) {
+	worker, err := ns.Worker(reservationWorker)
+	if err != nil {
+		return nil, err
+	}
+	attempts, err := worker.RequestAttempts(AttemptRequest{
+		Lifetime:          ttl,
+		NumberOfWorkUnits: 1,
+		WorkSpecs:         workSpecs,
+	})
+	if err != nil || len(attempts) == 0 {
+		return nil, err
+	}
+	attempt := attempts[0]
+	res, err := reserveAttempt(worker, attempt)
+	if err != nil {
+		_ = attempt.Release(nil)
+		return nil, err
+	}
+	return res, nil
+}
+
+// reserveAttempt builds the Reservation for a newly made attempt.
+func reserveAttempt(worker Worker, attempt Attempt) (*Reservation, error) {
+	var err error
+	unit := attempt.WorkUnit()
+	res := Reservation{
+		WorkSpec: unit.WorkSpec().Name(),
+		WorkUnit: unit.Name(),
+	}
+	res.Data, err = attempt.Data()
+	if err != nil {
+		return nil, err
+	}
+	start, err := attempt.StartTime()
+	if err != nil {
+		return nil, err
+	}
+	res.Expires, err = attempt.ExpirationTime()
+	if err != nil {
+		return nil, err
+	}
+	// Keep the worker alive for as long as its longest
+	// reservation, in case the work spec expires attempts with
+	// their workers
+	expiration, err := worker.Expiration()
+	if err != nil {
+		return nil, err
+	}
+	if res.Expires.After(expiration) {
+		err = worker.Update(nil, start, res.Expires, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+	token, err := json.Marshal(reservationToken{
+		WorkSpec: res.WorkSpec,
+		WorkUnit: res.WorkUnit,
+		Attempt:  attempt.ID(),
+		Worker:   worker.Name(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	res.Token = base64.RawURLEncoding.EncodeToString(token)
+	return &res, nil
+}
+
+// ReleaseReservation gives up a reservation made by Reserve(),
+// making its work unit available again.  This does not count as a
+// retry of the work unit.  Returns ErrNoSuchReservation
+// if token does not name a reservation that is still held, because it
+// has expired, been released, or been completed.
+func ReleaseReservation(ns Namespace, token string) error {
+	attempt, err := findReservation(ns, token)
+	if err != nil {
+		return err
+	}
+	return attempt.Release(nil)
+}
+
+// CompleteReservation finishes the work unit held by a reservation
+// made by Reserve().  If data is non-nil, it replaces the work unit
+// data.  Returns errors as ReleaseReservation().
+func CompleteReservation(ns Namespace, token string, data map[string]interface{}) error {
+	attempt, err := findReservation(ns, token)
+	if err != nil {
+		return err
+	}
+	return attempt.Finish(data)
+}
+
+// findReservation finds the pending attempt named by a reservation
+// token.
+func findReservation(ns Namespace, token string) (Attempt, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrNoSuchReservation
+	}
+	var rt reservationToken
+	if err = json.Unmarshal(bytes, &rt); err != nil || !strings.HasPrefix(rt.Worker, "reservation") {
+		return nil, ErrNoSuchReservation
+	}
+	spec, err := ns.WorkSpec(rt.WorkSpec)
+	if _, missing := err.(ErrNoSuchWorkSpec); missing {
+		return nil, ErrNoSuchReservation
+	} else if err != nil {
+		return nil, err
+	}
+	unit, err := spec.WorkUnit(rt.WorkUnit)
+	if _, missing := err.(ErrNoSuchWorkUnit); missing {
+		return nil, ErrNoSuchReservation
+	} else if err != nil {
+		return nil, err
+	}
+	attempt, err := unit.ActiveAttempt()
+	if err != nil {
+		return nil, err
+	}
+	if attempt == nil || attempt.ID() != rt.Attempt || attempt.Worker().Name() != rt.Worker {
+		return nil, ErrNoSuchReservation
+	}
+	status, err := attempt.Status()
+	if err != nil {
+		return nil, err
+	}
+	if status != Pending {
+		return nil, ErrNoSuchReservation
+	}
+	return attempt, nil
+}
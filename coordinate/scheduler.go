@@ -11,11 +11,11 @@ import (
 
 // CanStartContinuous decides whether this work spec can start a new
 // continuous work unit.  For this to be true, the metadata must indicate
-// that the work spec can generate continuous work units at all; it must
-// have no other incomplete work units; and the next-continuous time
-// must have passed.
+// that the work spec can generate continuous work units at all and
+// that generation is not paused; it must have no other incomplete
+// work units; and the next-continuous time must have passed.
 func (meta *WorkSpecMeta) CanStartContinuous(now time.Time) bool {
-	if !meta.Continuous {
+	if !meta.Continuous || meta.ContinuousPaused {
 		return false
 	}
 	if meta.AvailableCount > 0 || meta.PendingCount > 0 {
@@ -28,23 +28,37 @@ func (meta *WorkSpecMeta) CanStartContinuous(now time.Time) bool {
 }
 
 // CanDoWork decides whether this work spec can do any work at all.
-// This generally means the work spec is not paused and has positive
-// weight, and either it has at least one available work unit or it is
-// continuous, and it has not hit a max-running constraint.
+// This generally means the work spec is not paused or gated and has
+// positive weight, and either it has at least one available work unit
+// or it is continuous, and it has not hit a max-running constraint.
 func (meta *WorkSpecMeta) CanDoWork(now time.Time) bool {
-	if meta.Paused || meta.Weight <= 0 {
-		return false
+	ok, _ := meta.Schedulable(now)
+	return ok
+}
+
+// Schedulable decides whether this work spec can do any work at all,
+// as CanDoWork.  If not, it also returns a human-readable reason
+// why not.  The metadata must include counts.
+func (meta *WorkSpecMeta) Schedulable(now time.Time) (bool, string) {
+	if meta.Paused {
+		return false, "paused"
+	}
+	if meta.Gated {
+		return false, "gated"
+	}
+	if meta.Weight <= 0 {
+		return false, "non-positive weight"
 	}
 	if meta.MaxRunning > 0 && meta.PendingCount >= meta.MaxRunning {
-		return false
+		return false, "max_running reached"
 	}
 	if meta.AvailableCount > 0 {
-		return true
+		return true, ""
 	}
 	if meta.CanStartContinuous(now) {
-		return true
+		return true, ""
 	}
-	return false
+	return false, "no available work units"
 }
 
 // SimplifiedScheduler chooses a work spec to do work from a mapping
@@ -166,7 +180,9 @@ func LimitMetasToNames(metas map[string]*WorkSpecMeta, names []string) map[strin
 // unmodified; otherwise a new map is returned where the keys and
 // values are identical to meta, except that any pairs where the
 // meta.Runtime value is not exactly equal to one of runtimes are
-// not copied into the output.
+// not copied into the output.  This only considers work spec
+// runtimes; a work spec that is dropped could still have individual
+// work units whose WorkUnitMeta.Runtime matches.
 func LimitMetasToRuntimes(metas map[string]*WorkSpecMeta, runtimes []string) map[string]*WorkSpecMeta {
 	if len(runtimes) == 0 {
 		return metas
@@ -186,3 +202,48 @@ func LimitMetasToRuntimes(metas map[string]*WorkSpecMeta, runtimes []string) map
 	}
 	return newMetas
 }
+
+// ReorderPriorities computes new work unit priorities that put work
+// units in a specific order.  priorities maps the names of the work
+// units under consideration to their current priorities.  The
+// returned map contains new priorities for every name in orderedNames
+// that is also in priorities, such that each listed work unit has a
+// strictly higher priority than the next, and the last listed work
+// unit has a strictly higher priority than any unlisted work unit.
+// Unlisted work units keep their current priorities and are not in
+// the returned map.  Names in orderedNames that are not in priorities
+// are ignored, and if a name is repeated only its first position
+// counts.
+func ReorderPriorities(priorities map[string]float64, orderedNames []string) map[string]float64 {
+	var listed []string
+	seen := make(map[string]struct{})
+	for _, name := range orderedNames {
+		if _, present := priorities[name]; !present {
+			continue
+		}
+		if _, dup := seen[name]; dup {
+			continue
+		}
+		seen[name] = struct{}{}
+		listed = append(listed, name)
+	}
+
+	// Find the floor that the listed units need to be above
+	var base float64
+	first := true
+	for name, priority := range priorities {
+		if _, isListed := seen[name]; isListed {
+			continue
+		}
+		if first || priority > base {
+			base = priority
+			first = false
+		}
+	}
+
+	result := make(map[string]float64, len(listed))
+	for i, name := range listed {
+		result[name] = base + float64(len(listed)-i)
+	}
+	return result
+}
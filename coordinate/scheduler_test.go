@@ -299,6 +299,50 @@ func TestTwoSpecsContinuousBusy(t *testing.T) {
 	assert.Equal(t, trials, counts["one"])
 }
 
+// TestTwoSpecsContinuousPaused tests that a continuous work spec
+// whose generation is paused will not be returned if it has no work
+// units, but will be if it does.
+func TestTwoSpecsContinuousPaused(t *testing.T) {
+	metas := map[string]*WorkSpecMeta{
+		"one": &WorkSpecMeta{
+			Weight:         1,
+			AvailableCount: 1000,
+		},
+		"two": &WorkSpecMeta{
+			Weight:           1,
+			Continuous:       true,
+			ContinuousPaused: true,
+		},
+	}
+	trials := 1000
+	counts := runScheduler(t, metas, trials)
+	assert.Equal(t, trials, counts["one"])
+
+	metas["two"].AvailableCount = 1000
+	counts = runScheduler(t, metas, trials)
+	assert.InDelta(t, trials/2, counts["one"], 3*stdDev(trials, 1, 2))
+	assert.InDelta(t, trials/2, counts["two"], 3*stdDev(trials, 1, 2))
+}
+
+// TestTwoSpecsGated tests that a gated work spec is never returned,
+// even if it has work.
+func TestTwoSpecsGated(t *testing.T) {
+	metas := map[string]*WorkSpecMeta{
+		"one": &WorkSpecMeta{
+			Weight:         1,
+			AvailableCount: 1000,
+		},
+		"two": &WorkSpecMeta{
+			Weight:         1,
+			AvailableCount: 1000,
+			Gated:          true,
+		},
+	}
+	trials := 1000
+	counts := runScheduler(t, metas, trials)
+	assert.Equal(t, trials, counts["one"])
+}
+
 // TestThreeSpecsEqual tests that the scheduler behaves consistently
 // with three equal work specs.
 func TestThreeSpecsEqual(t *testing.T) {
@@ -368,3 +412,28 @@ func TestThreeSpecsPriorityBusy(t *testing.T) {
 	assert.InDelta(t, trials/2, counts["one"], 3*stdDev(trials, 1, 2))
 	assert.InDelta(t, trials/2, counts["two"], 3*stdDev(trials, 1, 2))
 }
+
+func TestReorderPriorities(t *testing.T) {
+	priorities := map[string]float64{
+		"a": 0,
+		"b": 0,
+		"c": 10,
+		"d": 5,
+	}
+	result := ReorderPriorities(priorities, []string{"b", "x", "a", "b"})
+	assert.Equal(t, map[string]float64{
+		"b": 12,
+		"a": 11,
+	}, result)
+
+	result = ReorderPriorities(priorities, []string{"d", "c", "b", "a"})
+	assert.Equal(t, map[string]float64{
+		"d": 4,
+		"c": 3,
+		"b": 2,
+		"a": 1,
+	}, result)
+
+	result = ReorderPriorities(priorities, nil)
+	assert.Empty(t, result)
+}
@@ -0,0 +1,82 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package coordinate
+
+import (
+	"sync"
+	"time"
+)
+
+// RequestThrottler is implemented by Coordinate backends that can
+// limit how often a single worker gets work.  Like
+// DataHistorySetter, it is reached with a type assertion.
+type RequestThrottler interface {
+	// SetRequestInterval sets the minimum time between calls to
+	// Worker.RequestAttempts() from any one worker.  A call that
+	// comes sooner than this after the worker's previous one
+	// returns no attempts and no error, as though there were no
+	// work, so a worker polling in a tight loop gets work at
+	// most once per interval.  Zero, the default, is unlimited.
+	SetRequestInterval(interval time.Duration)
+}
+
+// RequestThrottle records when workers last requested attempts, to
+// help backends implement RequestThrottler.  The zero value does not
+// throttle anything.  It is safe for concurrent use.
+type RequestThrottle struct {
+	lock     sync.Mutex
+	interval time.Duration
+	last     map[string]time.Time
+	pruneAt  int
+}
+
+// minPruneAt is the smallest number of workers RequestThrottle tracks
+// before it discards stale entries.
+const minPruneAt = 1024
+
+// SetInterval changes the minimum time between requests.
+func (t *RequestThrottle) SetInterval(interval time.Duration) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.interval = interval
+	if interval <= 0 {
+		t.last = nil
+	}
+}
+
+// Allow reports whether the named worker in the named namespace may
+// request attempts at time now.  If it may, now is recorded as its
+// most recent request.
+func (t *RequestThrottle) Allow(namespace, worker string, now time.Time) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.interval <= 0 {
+		return true
+	}
+	key := namespace + "\x00" + worker
+	if last, ok := t.last[key]; ok && now.Sub(last) < t.interval {
+		return false
+	}
+	if t.last == nil {
+		t.last = make(map[string]time.Time)
+	}
+	t.last[key] = now
+	t.prune(now)
+	return true
+}
+
+// prune discards entries for workers whose last request was long
+// enough ago that they would not be throttled anyway, once enough
+// workers are tracked to make this worthwhile.  Assumes t.lock.
+func (t *RequestThrottle) prune(now time.Time) {
+	if len(t.last) < t.pruneAt || len(t.last) < minPruneAt {
+		return
+	}
+	for key, last := range t.last {
+		if now.Sub(last) >= t.interval {
+			delete(t.last, key)
+		}
+	}
+	t.pruneAt = 2 * len(t.last)
+}
@@ -0,0 +1,34 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package coordinate
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRequestThrottleZero(t *testing.T) {
+	var throttle RequestThrottle
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		assert.True(t, throttle.Allow("", "worker", now))
+	}
+}
+
+func TestRequestThrottle(t *testing.T) {
+	var throttle RequestThrottle
+	throttle.SetInterval(time.Second)
+	start := time.Now()
+
+	assert.True(t, throttle.Allow("", "fast", start))
+	assert.False(t, throttle.Allow("", "fast", start.Add(500*time.Millisecond)))
+	assert.True(t, throttle.Allow("", "fast", start.Add(1*time.Second)))
+
+	// Same worker name in another namespace is separate
+	assert.True(t, throttle.Allow("other", "fast", start.Add(1*time.Second)))
+
+	throttle.SetInterval(0)
+	assert.True(t, throttle.Allow("", "fast", start.Add(1*time.Second)))
+}
@@ -0,0 +1,80 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package coordinate
+
+import (
+	"context"
+	"sync"
+)
+
+// WorkWatcher is implemented by work specs that can announce when
+// new work arrives, so that a worker can wake up right away rather
+// than waiting to poll Worker.RequestAttempts() again.  Like
+// DataHistorySetter, it is reached with a type assertion, here on a
+// WorkSpec.
+//
+// Notifications are advisory.  A notification does not guarantee
+// that any work will be there when the worker asks for it, and some
+// ways work units become available, such as delayed work units
+// reaching their NotBefore time, may not produce one at all.  Callers
+// should still poll, just less urgently.
+type WorkWatcher interface {
+	// WatchWork returns a channel that receives a value soon
+	// after a work unit is added to this work spec or otherwise
+	// becomes available.  Several changes close together may
+	// produce only a single value.  The channel is closed once
+	// ctx is done, or earlier if the backend stops being able to
+	// deliver notifications.  Returns ErrWatchNotSupported if
+	// this work spec cannot deliver notifications at all.
+	WatchWork(ctx context.Context) (<-chan struct{}, error)
+}
+
+// WorkBroadcast delivers work notifications to any number of
+// watchers, to help backends implement WorkWatcher.  The zero value
+// has no watchers.  It is safe for concurrent use.
+type WorkBroadcast struct {
+	lock     sync.Mutex
+	watchers map[chan struct{}]struct{}
+}
+
+// Watch returns a channel that receives a value after calls to
+// Notify.  If the channel already has a value waiting, Notify does
+// not add another.  The channel is closed once ctx is done.
+func (b *WorkBroadcast) Watch(ctx context.Context) <-chan struct{} {
+	ch := make(chan struct{}, 1)
+	b.lock.Lock()
+	if b.watchers == nil {
+		b.watchers = make(map[chan struct{}]struct{})
+	}
+	b.watchers[ch] = struct{}{}
+	b.lock.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.lock.Lock()
+		delete(b.watchers, ch)
+		b.lock.Unlock()
+		close(ch)
+	}()
+	return ch
+}
+
+// Notify wakes every current watcher.  It never blocks.
+func (b *WorkBroadcast) Notify() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for ch := range b.watchers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Watching returns the number of current watchers.
+func (b *WorkBroadcast) Watching() int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return len(b.watchers)
+}
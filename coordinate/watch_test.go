@@ -0,0 +1,50 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package coordinate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkBroadcastZero(t *testing.T) {
+	var b WorkBroadcast
+	b.Notify()
+	assert.Equal(t, 0, b.Watching())
+}
+
+func TestWorkBroadcast(t *testing.T) {
+	var b WorkBroadcast
+	ctx, cancel := context.WithCancel(context.Background())
+	one := b.Watch(ctx)
+	two := b.Watch(ctx)
+	assert.Equal(t, 2, b.Watching())
+
+	// Several notifications collapse into one
+	b.Notify()
+	b.Notify()
+	for _, ch := range []<-chan struct{}{one, two} {
+		select {
+		case <-ch:
+		default:
+			assert.Fail(t, "no notification")
+		}
+		select {
+		case <-ch:
+			assert.Fail(t, "extra notification")
+		default:
+		}
+	}
+
+	// Cancelling the context closes the channels
+	cancel()
+	_, ok := <-one
+	assert.False(t, ok)
+	_, ok = <-two
+	assert.False(t, ok)
+	assert.Equal(t, 0, b.Watching())
+	b.Notify()
+}
@@ -0,0 +1,161 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+// Package export copies a single work spec and its work units to and
+// from a portable stream, so that it can be inspected offline or
+// loaded into a different Coordinate backend.
+//
+// The stream is a sequence of JSON objects, one per line.  The first
+// holds the work spec definition under a "work_spec" key; every later
+// one holds a single work unit under a "work_unit" key.  Data
+// dictionaries use the restdata.DataDict encoding, so values that do
+// not survive plain JSON, such as Python tuples and UUIDs, round-trip
+// correctly.
+//
+// Only the work units' names, data, and metadata are exported.
+// Attempts are not, and so every imported work unit is available
+// (or delayed, if its metadata has a not-before time).
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+
+	"github.com/diffeo/go-coordinate/coordinate"
+	"github.com/diffeo/go-coordinate/restdata"
+)
+
+// DefaultBatchSize is the number of work units fetched from the
+// backend at a time while exporting.
+const DefaultBatchSize = 1000
+
+// ErrNoWorkSpec is returned from ReadWorkSpec if the stream does not
+// begin with a work spec definition.
+var ErrNoWorkSpec = errors.New("export stream does not start with a work spec")
+
+// Record is a single line of an export stream.  Exactly one of its
+// fields is set.
+type Record struct {
+	// WorkSpec is the work spec definition, as returned from
+	// WorkSpec.Data().
+	WorkSpec restdata.DataDict `json:"work_spec,omitempty"`
+
+	// WorkUnit is a single work unit.
+	WorkUnit *WorkUnit `json:"work_unit,omitempty"`
+}
+
+// WorkUnit is the exported form of a single work unit.
+type WorkUnit struct {
+	Name string                  `json:"name"`
+	Data restdata.DataDict       `json:"data"`
+	Meta coordinate.WorkUnitMeta `json:"meta"`
+}
+
+// WriteWorkSpec writes spec and all of its work units to w.  Work
+// units are fetched batchSize at a time in name order, so the whole
+// work spec never needs to be held in memory; if batchSize is zero
+// DefaultBatchSize is used.  Returns the number of work units
+// written.
+func WriteWorkSpec(w io.Writer, spec coordinate.WorkSpec, batchSize int) (int, error) {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	encoder := json.NewEncoder(w)
+
+	data, err := spec.Data()
+	if err != nil {
+		return 0, err
+	}
+	err = encoder.Encode(Record{WorkSpec: data})
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	query := coordinate.WorkUnitQuery{Limit: batchSize}
+	for {
+		units, err := spec.WorkUnits(query)
+		if err != nil {
+			return count, err
+		}
+		if len(units) == 0 {
+			return count, nil
+		}
+		names := make([]string, 0, len(units))
+		for name := range units {
+			names = append(names, name)
+		}
+		// The backends return the first Limit units in name
+		// order, but as a map; restore that order so that the
+		// output is deterministic.
+		sort.Strings(names)
+		for _, name := range names {
+			record, err := exportWorkUnit(units[name])
+			if err != nil {
+				return count, err
+			}
+			err = encoder.Encode(Record{WorkUnit: record})
+			if err != nil {
+				return count, err
+			}
+			count++
+		}
+		query.PreviousName = names[len(names)-1]
+	}
+}
+
+func exportWorkUnit(unit coordinate.WorkUnit) (*WorkUnit, error) {
+	data, err := unit.Data()
+	if err != nil {
+		return nil, err
+	}
+	meta, err := unit.Meta()
+	if err != nil {
+		return nil, err
+	}
+	return &WorkUnit{Name: unit.Name(), Data: data, Meta: meta}, nil
+}
+
+// ReadWorkSpec reads an export stream from r and loads it into
+// namespace.  The work spec is created or replaced with the exported
+// definition, and then each work unit is added in turn, replacing
+// any existing work unit with the same name.  Returns the work spec
+// and the number of work units added.
+func ReadWorkSpec(r io.Reader, namespace coordinate.Namespace) (coordinate.WorkSpec, int, error) {
+	decoder := json.NewDecoder(r)
+
+	var header Record
+	err := decoder.Decode(&header)
+	if err == io.EOF || (err == nil && header.WorkSpec == nil) {
+		return nil, 0, ErrNoWorkSpec
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	spec, err := namespace.SetWorkSpec(header.WorkSpec)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count := 0
+	for {
+		var record Record
+		err = decoder.Decode(&record)
+		if err == io.EOF {
+			return spec, count, nil
+		}
+		if err != nil {
+			return spec, count, err
+		}
+		if record.WorkUnit == nil {
+			continue
+		}
+		_, err = spec.AddWorkUnit(record.WorkUnit.Name, record.WorkUnit.Data, record.WorkUnit.Meta)
+		if err != nil {
+			return spec, count, err
+		}
+		count++
+	}
+}
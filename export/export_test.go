@@ -0,0 +1,121 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/diffeo/go-coordinate/coordinate"
+	"github.com/diffeo/go-coordinate/memory"
+	"github.com/diffeo/go-coordinate/restclient"
+	"github.com/diffeo/go-coordinate/restserver"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRoundTrip exports a work spec from a memory backend and imports
+// it into a REST backend, checking that everything survives.
+func TestRoundTrip(t *testing.T) {
+	source, err := memory.New().Namespace("source")
+	if !assert.NoError(t, err) {
+		return
+	}
+	spec, err := source.SetWorkSpec(map[string]interface{}{
+		"name":     "spec",
+		"priority": 10,
+		"task":     "do_things",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	notBefore := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	keys := make([]uuid.UUID, 5)
+	for i := 0; i < 5; i++ {
+		keys[i] = uuid.NewV4()
+		name := fmt.Sprintf("unit%d", i)
+		meta := coordinate.WorkUnitMeta{Priority: float64(i)}
+		if i == 4 {
+			meta.NotBefore = notBefore
+		}
+		_, err = spec.AddWorkUnit(name, map[string]interface{}{
+			"index": i,
+			"key":   keys[i],
+		}, meta)
+		if !assert.NoError(t, err) {
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	count, err := WriteWorkSpec(&buf, spec, 2)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 5, count)
+	assert.Equal(t, 6, strings.Count(buf.String(), "\n"))
+
+	server := httptest.NewServer(restserver.NewRouter(memory.New()))
+	defer server.Close()
+	target, err := restclient.New(server.URL)
+	if !assert.NoError(t, err) {
+		return
+	}
+	dest, err := target.Namespace("dest")
+	if !assert.NoError(t, err) {
+		return
+	}
+	newSpec, count, err := ReadWorkSpec(&buf, dest)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 5, count)
+	assert.Equal(t, "spec", newSpec.Name())
+
+	meta, err := newSpec.Meta(false)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 10, meta.Priority)
+	}
+	data, err := newSpec.Data()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "do_things", data["task"])
+	}
+
+	for i := 0; i < 5; i++ {
+		name := fmt.Sprintf("unit%d", i)
+		unit, err := newSpec.WorkUnit(name)
+		if !assert.NoError(t, err, name) {
+			continue
+		}
+		data, err := unit.Data()
+		if assert.NoError(t, err, name) {
+			assert.EqualValues(t, i, data["index"], name)
+			assert.Equal(t, keys[i], data["key"], name)
+		}
+		meta, err := unit.Meta()
+		if assert.NoError(t, err, name) {
+			assert.Equal(t, float64(i), meta.Priority, name)
+			if i == 4 {
+				assert.True(t, notBefore.Equal(meta.NotBefore), name)
+			}
+		}
+	}
+}
+
+// TestReadNoWorkSpec checks that a stream must begin with a work spec.
+func TestReadNoWorkSpec(t *testing.T) {
+	ns, err := memory.New().Namespace("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, _, err = ReadWorkSpec(strings.NewReader(""), ns)
+	assert.Equal(t, ErrNoWorkSpec, err)
+
+	_, _, err = ReadWorkSpec(strings.NewReader(`{"work_unit":{"name":"u"}}`+"\n"), ns)
+	assert.Equal(t, ErrNoWorkSpec, err)
+}
@@ -1,6 +1,6 @@
 module github.com/diffeo/go-coordinate
 
-go 1.19
+go 1.20
 
 require (
 	github.com/benbjohnson/clock v0.0.0-20161215174838-7dc76406b6d3
@@ -10,6 +10,7 @@ require (
 	github.com/lib/pq v0.0.0-20170313200423-472a0745531a
 	github.com/mitchellh/mapstructure v1.1.2
 	github.com/prometheus/client_golang v1.11.1
+	github.com/prometheus/client_model v0.2.0
 	github.com/rubenv/sql-migrate v0.0.0-20170314191533-a3e296353799
 	github.com/satori/go.uuid v1.0.0
 	github.com/sirupsen/logrus v1.6.0
@@ -30,7 +31,6 @@ require (
 	github.com/mattn/go-sqlite3 v1.10.0 // indirect
 	github.com/matttproud/golang_protobuf_extensions v1.0.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/prometheus/client_model v0.2.0 // indirect
 	github.com/prometheus/common v0.26.0 // indirect
 	github.com/prometheus/procfs v0.6.0 // indirect
 	github.com/russross/blackfriday/v2 v2.0.1 // indirect
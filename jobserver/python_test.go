@@ -15,6 +15,7 @@ import (
 	"github.com/satori/go.uuid"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 // Clock contains the mock time source.
@@ -523,6 +524,59 @@ func TestGetManyMaxGetwork(t *testing.T) {
 	}
 }
 
+// TestGetWorkDefaultLease tests that GetWork without a "lease_time"
+// parameter uses the work spec's "default_lease", and that an explicit
+// "lease_time" overrides it.
+func TestGetWorkDefaultLease(t *testing.T) {
+	j := setUpTest(t, "TestGetWorkDefaultLease")
+	defer tearDownTest(t, j)
+
+	data := makeWorkSpec(map[string]interface{}{"default_lease": 3600})
+	workSpecName := setWorkSpec(t, j, data)
+	addPrefixedWorkUnits(t, j, workSpecName, "u", 3)
+
+	workSpec, err := j.Namespace.WorkSpec(workSpecName)
+	if !assert.NoError(t, err) {
+		return
+	}
+	checkLease := func(name string, lease time.Duration) {
+		unit, err := workSpec.WorkUnit(name)
+		if !assert.NoError(t, err) {
+			return
+		}
+		attempt, err := unit.ActiveAttempt()
+		if !assert.NoError(t, err) || !assert.NotNil(t, attempt) {
+			return
+		}
+		start, err := attempt.StartTime()
+		if !assert.NoError(t, err) {
+			return
+		}
+		expiration, err := attempt.ExpirationTime()
+		if assert.NoError(t, err) {
+			assert.Equal(t, lease, expiration.Sub(start))
+		}
+	}
+
+	ok, _, key, _ := getOneWork(t, j)
+	if assert.True(t, ok) {
+		checkLease(key, time.Hour)
+	}
+
+	_, msg, err := j.GetWork("test", map[string]interface{}{"available_gb": 1, "lease_time": 300})
+	if assert.NoError(t, err) {
+		assert.Empty(t, msg)
+		checkLease("u002", 5*time.Minute)
+	}
+
+	// Leases are capped at a day
+	_, msg, err = j.GetWork("test", map[string]interface{}{"available_gb": 1, "lease_time": 7 * 24 * 60 * 60})
+	if assert.NoError(t, err) {
+		assert.Empty(t, msg)
+		checkLease("u003", 24*time.Hour)
+	}
+}
+
 // TestGetTooMany tests what happens when there are two work specs,
 // and the one that gets chosen has fewer work units than are requested.
 // This test validates that the higher-weight work spec is chosen and
@@ -91,17 +91,7 @@ func (jobs *JobServer) Clear() (count int, err error) {
 	if err != nil {
 		return
 	}
-	for _, name := range names {
-		err = jobs.Namespace.DestroyWorkSpec(name)
-		if err != nil {
-			// Ignore concurrent deletes
-			if _, missing := err.(coordinate.ErrNoSuchWorkSpec); !missing {
-				return
-			}
-		}
-	}
-	count = len(names)
-	return
+	return jobs.Namespace.DestroyWorkSpecs(names)
 }
 
 // GetWorkSpec retrieves the definition of a work spec.  If the named
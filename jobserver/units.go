@@ -5,44 +5,78 @@ package jobserver
 
 import (
 	"errors"
+	"fmt"
 	"github.com/diffeo/go-coordinate/cborrpc"
 	"github.com/diffeo/go-coordinate/coordinate"
 	"github.com/mitchellh/mapstructure"
 	"math"
 	"reflect"
+	"strings"
 )
 
 // AddWorkUnits adds any number of work units to a work spec.  Each oy
 // the work units is a cborrpc.PythonTuple or slice containing a
 // string with the work unit key, a dictionary with the work unit
 // data, and an optional dictionary with additional metadata.
+//
+// Work units that cannot be parsed do not stop the rest of the
+// batch.  If every work unit was added, returns true; otherwise
+// returns false and a message listing each failed work unit by its
+// position in workUnitKvp, with the reason it failed.  If the
+// backend cannot add the batch at all, returns its error.
 func (jobs *JobServer) AddWorkUnits(workSpecName string, workUnitKvp []interface{}) (bool, string, error) {
 	spec, err := jobs.Namespace.WorkSpec(workSpecName)
 	if err != nil {
 		return false, "", err
 	}
 
-	// Unmarshal the work unit list into a []AddWorkUnitItem.
-	// Fail now if any are invalid.
+	// Unmarshal the work unit list into a []AddWorkUnitItem,
+	// remembering which ones are invalid.
 	now := jobs.Clock.Now()
-	items := make([]coordinate.AddWorkUnitItem, len(workUnitKvp))
+	results := make([]coordinate.AddWorkUnitResult, len(workUnitKvp))
+	var items []coordinate.AddWorkUnitItem
+	var positions []int
 	for i, kvp := range workUnitKvp {
-		items[i], err = coordinate.ExtractAddWorkUnitItem(kvp, now)
+		item, err := coordinate.ExtractAddWorkUnitItem(kvp, now)
 		if err != nil {
-			return false, "", err
+			results[i] = coordinate.AddWorkUnitResult{Key: item.Key, Err: err}
+			continue
 		}
+		items = append(items, item)
+		positions = append(positions, i)
 	}
 
-	// Now go through and add them all
-	for _, item := range items {
-		_, err = spec.AddWorkUnit(item.Key, item.Data, item.Meta)
-		if err != nil {
-			// Again, Python coordinate expects to never see
-			// a failure here?
-			return false, "", err
+	// Now add all of the valid ones in one batch
+	added, err := coordinate.AddWorkUnitItems(spec, items)
+	if err != nil {
+		return false, "", err
+	}
+	for i, result := range added {
+		results[positions[i]] = result
+	}
+	return addWorkUnitsMessage(results)
+}
+
+// addWorkUnitsMessage produces the return values from AddWorkUnits()
+// describing the failed items in results.
+func addWorkUnitsMessage(results []coordinate.AddWorkUnitResult) (bool, string, error) {
+	var failures []string
+	for i, result := range results {
+		if result.Err == nil {
+			continue
 		}
+		if result.Key == "" {
+			failures = append(failures, fmt.Sprintf("[%d]: %v", i, result.Err))
+		} else {
+			failures = append(failures, fmt.Sprintf("[%d] %q: %v", i, result.Key, result.Err))
+		}
+	}
+	if len(failures) == 0 {
+		return true, "", nil
 	}
-	return true, "", nil
+	msg := fmt.Sprintf("%d of %d work units not added: %s",
+		len(failures), len(results), strings.Join(failures, "; "))
+	return false, msg, nil
 }
 
 // GetWorkUnitsOptions contains unmarshaled options for GetWorkUnits().
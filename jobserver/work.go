@@ -21,8 +21,9 @@ type GetWorkOptions struct {
 	AvailableGb float64 `mapstructure:"available_gb"`
 
 	// LeaseTime specifies the number of seconds to complete the
-	// work.  If zero, use a default value of 5 minutes.  Cannot
-	// be less than 1 second or more than 1 day.
+	// work.  If zero, use the work spec's "default_lease", or 15
+	// minutes if it has none.  Cannot be more than 1 day; longer
+	// requests get 1 day.
 	LeaseTime int `mapstructure:"lease_time"`
 
 	// MaxJobs indicates the number of jobs requested.  If zero,
@@ -39,6 +40,23 @@ type GetWorkOptions struct {
 	WorkSpecNames []string `mapstructure:"work_spec_names"`
 }
 
+// maxLeaseTime is the longest lease GetWork grants, in seconds.
+const maxLeaseTime = 24 * 60 * 60
+
+// LeaseDuration converts the requested LeaseTime to a duration.  If
+// no lease time was requested, returns zero, so that the backend uses
+// the work spec's default lease.
+func (opts GetWorkOptions) LeaseDuration() time.Duration {
+	if opts.LeaseTime < 1 {
+		return 0
+	}
+	leaseTime := opts.LeaseTime
+	if leaseTime > maxLeaseTime {
+		leaseTime = maxLeaseTime
+	}
+	return time.Duration(leaseTime) * time.Second
+}
+
 // GetWork requests one or more work units to perform.  The work unit
 // attempts are associated with workerID, which need not have been
 // previously registered.  If there is no work to do, may return
@@ -71,6 +89,7 @@ func (jobs *JobServer) GetWork(workerID string, options map[string]interface{})
 			gwOptions.MaxJobs = 1
 		}
 		req := coordinate.AttemptRequest{
+			Lifetime:          gwOptions.LeaseDuration(),
 			NumberOfWorkUnits: gwOptions.MaxJobs,
 			Runtimes:          []string{""},
 			WorkSpecs:         gwOptions.WorkSpecNames,
@@ -7,6 +7,7 @@ package jobserver_test
 
 import (
 	"github.com/diffeo/go-coordinate/cborrpc"
+	"github.com/diffeo/go-coordinate/coordinate"
 	"github.com/diffeo/go-coordinate/jobserver"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -132,3 +133,40 @@ func TestDelayedUnit(t *testing.T) {
 	checkWorkUnitStatus(t, j, workSpecName, "unit", jobserver.Available)
 	doOneWork(t, j, workSpecName, "unit")
 }
+
+// TestAddWorkUnitsPartialFailure adds a batch with some invalid work
+// units, and checks that the valid ones are added and the invalid
+// ones are reported.
+func TestAddWorkUnitsPartialFailure(t *testing.T) {
+	j := setUpTest(t, "TestAddWorkUnitsPartialFailure")
+	defer tearDownTest(t, j)
+
+	empty := map[string]interface{}{}
+	workSpecName := setWorkSpec(t, j, WorkSpecData)
+
+	ok, msg, err := j.AddWorkUnits(workSpecName, []interface{}{
+		cborrpc.PythonTuple{Items: []interface{}{"first", empty}},
+		cborrpc.PythonTuple{Items: []interface{}{"bad", empty, nil, "high"}},
+		cborrpc.PythonTuple{Items: []interface{}{"short"}},
+		cborrpc.PythonTuple{Items: []interface{}{"", empty}},
+		cborrpc.PythonTuple{Items: []interface{}{"second", empty}},
+	})
+	if assert.NoError(t, err) {
+		assert.False(t, ok)
+		assert.Equal(t, "3 of 5 work units not added: "+
+			"[1] \"bad\": priority must be a number; "+
+			"[2]: too few parameters to work unit; "+
+			"[3]: work unit must have a name", msg)
+	}
+
+	spec, err := j.Namespace.WorkSpec(workSpecName)
+	if !assert.NoError(t, err) {
+		return
+	}
+	units, err := spec.WorkUnits(coordinate.WorkUnitQuery{})
+	if assert.NoError(t, err) {
+		assert.Len(t, units, 2)
+		assert.Contains(t, units, "first")
+		assert.Contains(t, units, "second")
+	}
+}
@@ -11,6 +11,7 @@ import (
 // Attempt type:
 
 type attempt struct {
+	id             string
 	workUnit       *workUnit
 	worker         *worker
 	status         coordinate.AttemptStatus
@@ -18,6 +19,14 @@ type attempt struct {
 	startTime      time.Time
 	endTime        time.Time
 	expirationTime time.Time
+	finishPrepared bool
+	released       bool
+	history        []coordinate.DataSnapshot
+	logs           []string
+}
+
+func (attempt *attempt) ID() string {
+	return attempt.id
 }
 
 func (attempt *attempt) WorkUnit() coordinate.WorkUnit {
@@ -29,15 +38,21 @@ func (attempt *attempt) Worker() coordinate.Worker {
 }
 
 func (attempt *attempt) do(f func() error) error {
-	globalLock(attempt)
-	defer globalUnlock(attempt)
+	attempt.workUnit.workSpec.namespace.lock()
+	defer attempt.workUnit.workSpec.namespace.unlock()
 
-	if attempt.workUnit.deleted || attempt.workUnit.workSpec.deleted || attempt.workUnit.workSpec.namespace.deleted {
+	if attempt.isGone() {
 		return coordinate.ErrGone
 	}
 	return f()
 }
 
+// isGone returns whether this attempt's work unit, work spec, or
+// namespace has been deleted.  Assumes the namespace lock.
+func (attempt *attempt) isGone() bool {
+	return attempt.workUnit.deleted || attempt.workUnit.workSpec.deleted || attempt.workUnit.workSpec.namespace.deleted
+}
+
 func (attempt *attempt) Status() (status coordinate.AttemptStatus, err error) {
 	err = attempt.do(func() error {
 		attempt.workUnit.workSpec.expireUnits()
@@ -92,7 +107,7 @@ func (attempt *attempt) isPending() bool {
 
 // finish marks an attempt as finished in some form.  It updates the
 // completion time, status, and data, and removes itself as the active
-// work unit where possible.  Assumes the global lock.
+// work unit where possible.  Assumes the namespace lock.
 func (attempt *attempt) finish(status coordinate.AttemptStatus, data map[string]interface{}) {
 	attempt.endTime = attempt.Coordinate().clock.Now()
 	attempt.status = status
@@ -100,9 +115,28 @@ func (attempt *attempt) finish(status coordinate.AttemptStatus, data map[string]
 		attempt.data = data
 	}
 	attempt.worker.completeAttempt(attempt)
-	if status == coordinate.Expired || status == coordinate.Retryable {
+	if (status == coordinate.Expired || status == coordinate.Retryable) &&
+		attempt.workUnit.activeAttempt == attempt {
+		if !attempt.released {
+			attempt.workUnit.retry()
+		}
 		attempt.workUnit.resetAttempt()
 	}
+	attempt.workUnit.archiveAttempts()
+}
+
+// lostLease checks whether a pending attempt being completed has
+// lost its work unit to another attempt, and the coordinate refuses
+// to complete such attempts.  If so, it expires the attempt and
+// returns true.  Assumes the namespace lock.
+func (attempt *attempt) lostLease(data map[string]interface{}) bool {
+	if attempt.status != coordinate.Pending ||
+		attempt.workUnit.activeAttempt == attempt ||
+		!attempt.Coordinate().strict {
+		return false
+	}
+	attempt.finish(coordinate.Expired, data)
+	return true
 }
 
 func (attempt *attempt) Renew(extendDuration time.Duration, data map[string]interface{}) error {
@@ -120,8 +154,24 @@ func (attempt *attempt) Renew(extendDuration time.Duration, data map[string]inte
 			attempt.finish(coordinate.Expired, data)
 			return coordinate.ErrLostLease
 		}
+		attempt.recordHistory(data)
+		// Check: we must not have held this attempt for too
+		// long already.
+		now := attempt.Coordinate().clock.Now()
+		expiration := now.Add(extendDuration)
+		maxLeaseTotal := attempt.workUnit.workSpec.meta.MaxLeaseTotal
+		if maxLeaseTotal > 0 {
+			limit := attempt.startTime.Add(maxLeaseTotal)
+			if !now.Before(limit) {
+				attempt.finish(coordinate.Expired, data)
+				return coordinate.ErrLeaseTotalExceeded
+			}
+			if expiration.After(limit) {
+				expiration = limit
+			}
+		}
 		// Otherwise, we get to extend our lease.
-		attempt.expirationTime = attempt.Coordinate().clock.Now().Add(extendDuration)
+		attempt.expirationTime = expiration
 		attempt.status = coordinate.Pending
 		if data != nil {
 			attempt.data = data
@@ -130,6 +180,54 @@ func (attempt *attempt) Renew(extendDuration time.Duration, data map[string]inte
 	})
 }
 
+// recordHistory adds data to the attempt's data history, if the
+// coordinate is keeping one, discarding the oldest snapshots beyond
+// its limit.  Assumes the namespace lock.
+func (attempt *attempt) recordHistory(data map[string]interface{}) {
+	limit := attempt.Coordinate().dataHistory
+	if data == nil || limit <= 0 {
+		return
+	}
+	attempt.history = append(attempt.history, coordinate.DataSnapshot{
+		Time: attempt.Coordinate().clock.Now(),
+		Data: data,
+	})
+	if len(attempt.history) > limit {
+		attempt.history = attempt.history[len(attempt.history)-limit:]
+	}
+}
+
+func (attempt *attempt) DataHistory() (history []coordinate.DataSnapshot, err error) {
+	err = attempt.do(func() error {
+		history = make([]coordinate.DataSnapshot, len(attempt.history))
+		copy(history, attempt.history)
+		return nil
+	})
+	return
+}
+
+func (attempt *attempt) AppendLog(line string) error {
+	return attempt.do(func() error {
+		if !attempt.isPending() {
+			return coordinate.ErrNotPending
+		}
+		attempt.logs = append(attempt.logs, line)
+		if len(attempt.logs) > coordinate.MaxAttemptLogLines {
+			attempt.logs = attempt.logs[len(attempt.logs)-coordinate.MaxAttemptLogLines:]
+		}
+		return nil
+	})
+}
+
+func (attempt *attempt) Logs() (logs []string, err error) {
+	err = attempt.do(func() error {
+		logs = make([]string, len(attempt.logs))
+		copy(logs, attempt.logs)
+		return nil
+	})
+	return
+}
+
 func (attempt *attempt) Expire(data map[string]interface{}) error {
 	return attempt.do(func() error {
 		// No-op if already expired; error if not pending
@@ -144,63 +242,134 @@ func (attempt *attempt) Expire(data map[string]interface{}) error {
 	})
 }
 
-func (attempt *attempt) Finish(data map[string]interface{}) error {
+func (attempt *attempt) Release(data map[string]interface{}) error {
 	return attempt.do(func() error {
-		if attempt.status != coordinate.Failed && !attempt.isPending() {
+		if attempt.status != coordinate.Pending {
 			return coordinate.ErrNotPending
 		}
-		attempt.finish(coordinate.Finished, data)
+		attempt.released = true
+		attempt.finish(coordinate.Expired, data)
+		return nil
+	})
+}
 
-		// Does the work unit data include an "output" key
-		// that we understand?
-		if attempt.workUnit.activeAttempt != attempt {
-			return nil
-		}
-		if data == nil {
-			data = attempt.data
-		}
-		if data == nil {
-			data = attempt.workUnit.data
-		}
-		var newUnits map[string]coordinate.AddWorkUnitItem
-		var nextWorkSpec *workSpec
-		output, ok := data["output"]
-		if ok {
-			newUnits = coordinate.ExtractWorkUnitOutput(output, attempt.Coordinate().clock.Now())
+func (attempt *attempt) Finish(data map[string]interface{}) error {
+	return attempt.do(func() error {
+		return attempt.complete(coordinate.Finished, data, 0)
+	})
+}
+
+func (attempt *attempt) PrepareFinish(data map[string]interface{}) error {
+	return attempt.do(func() error {
+		if !attempt.isPending() {
+			return coordinate.ErrNotPending
 		}
-		if newUnits != nil {
-			then := attempt.workUnit.workSpec.meta.NextWorkSpecName
-			if then != "" {
-				nextWorkSpec, ok = attempt.workUnit.workSpec.namespace.workSpecs[then]
-				nextWorkSpec.addWorkUnits(newUnits)
-			}
+		attempt.finishPrepared = true
+		if data != nil {
+			attempt.data = data
 		}
+		return nil
+	})
+}
 
+func (attempt *attempt) FinishPrepared() (prepared bool, err error) {
+	err = attempt.do(func() error {
+		prepared = attempt.finishPrepared
 		return nil
 	})
+	return
 }
 
 func (attempt *attempt) Fail(data map[string]interface{}) error {
 	return attempt.do(func() error {
-		if !attempt.isPending() {
-			return coordinate.ErrNotPending
-		}
-		attempt.finish(coordinate.Failed, data)
-		return nil
+		return attempt.complete(coordinate.Failed, data, 0)
 	})
 }
 
 func (attempt *attempt) Retry(data map[string]interface{}, delay time.Duration) error {
 	return attempt.do(func() error {
-		if !attempt.isPending() {
-			return coordinate.ErrNotPending
-		}
-		attempt.finish(coordinate.Retryable, data)
-		attempt.workUnit.meta.NotBefore = attempt.Coordinate().clock.Now().Add(delay)
-		return nil
+		return attempt.complete(coordinate.Retryable, data, delay)
 	})
 }
 
+// canComplete returns ErrNotPending if this attempt cannot move to
+// status, or nil if it can.  A failed attempt can still be finished.
+// Assumes the namespace lock.
+func (attempt *attempt) canComplete(status coordinate.AttemptStatus) error {
+	if status == coordinate.Finished && attempt.status == coordinate.Failed {
+		return nil
+	}
+	if !attempt.isPending() {
+		return coordinate.ErrNotPending
+	}
+	return nil
+}
+
+// complete is the implementation of Finish(), Fail(), and Retry(),
+// and their batch versions on the worker.  delay is only used for
+// Retryable status.  Retrying a work unit that has already had its
+// work spec's MaxRetries attempts fails it instead.  Assumes the
+// namespace lock.
+func (attempt *attempt) complete(status coordinate.AttemptStatus, data map[string]interface{}, delay time.Duration) error {
+	if err := attempt.canComplete(status); err != nil {
+		return err
+	}
+	if attempt.lostLease(data) {
+		return coordinate.ErrLostLease
+	}
+	unit := attempt.workUnit
+	active := unit.activeAttempt == attempt
+	if status == coordinate.Retryable && active && unit.retriesExhausted() {
+		// Fail it now, as RequestAttempts() would fail the
+		// next attempt
+		status = coordinate.Failed
+		data = coordinate.ExtractFailureData(unit.workSpec.data, "too many retries")
+	}
+	attempt.finish(status, data)
+	if !active {
+		return nil
+	}
+	switch status {
+	case coordinate.Finished:
+		attempt.addOutput(data)
+	case coordinate.Retryable:
+		// An explicit delay overrides the work spec's
+		// retry schedule, which finish() applied; but an
+		// attempt that is not active leaves the unit alone
+		if delay > 0 || len(unit.workSpec.meta.RetryDelays) == 0 {
+			unit.meta.NotBefore = attempt.Coordinate().clock.Now().Add(delay)
+		}
+	}
+	return nil
+}
+
+// addOutput creates new work units from an "output" key in the data
+// of a just-finished attempt, if the work spec names a next work
+// spec.  Assumes the namespace lock.
+func (attempt *attempt) addOutput(data map[string]interface{}) {
+	if data == nil {
+		data = attempt.data
+	}
+	if data == nil {
+		data = attempt.workUnit.data
+	}
+	output, ok := data["output"]
+	if !ok {
+		return
+	}
+	newUnits := coordinate.ExtractWorkUnitOutput(output, attempt.Coordinate().clock.Now())
+	if newUnits == nil {
+		return
+	}
+	then := attempt.workUnit.workSpec.meta.NextWorkSpecName
+	if then == "" {
+		return
+	}
+	if nextWorkSpec, ok := attempt.workUnit.workSpec.namespace.workSpecs[then]; ok {
+		nextWorkSpec.addWorkUnits(newUnits)
+	}
+}
+
 func (attempt *attempt) Coordinate() *memCoordinate {
 	return attempt.workUnit.workSpec.namespace.coordinate
 }
@@ -5,14 +5,20 @@ package memory
 
 import (
 	"container/heap"
+
+	"github.com/diffeo/go-coordinate/coordinate"
 )
 
 // availableUnits is a priority queue of work units.
 type availableUnits []*workUnit
 
-// Add a work unit to this queue in the appropriate spot.
+// Add a work unit to this queue in the appropriate spot, and tell
+// anything watching its work spec that there is new work.
 func (q *availableUnits) Add(unit *workUnit) {
 	heap.Push(q, unit)
+	if unit.workSpec != nil {
+		unit.workSpec.watch.Notify()
+	}
 }
 
 // Next gets the next available unit, with the highest priority and lowest name.
@@ -20,6 +26,32 @@ func (q *availableUnits) Next() *workUnit {
 	return heap.Pop(q).(*workUnit)
 }
 
+// PeekMatching finds the next available unit for which match returns
+// true, without removing it.  Returns nil if there is none.  Unless
+// the highest-priority unit matches, this scans the entire queue.
+func (q availableUnits) PeekMatching(match func(*workUnit) bool) *workUnit {
+	if len(q) > 0 && match(q[0]) {
+		return q[0]
+	}
+	var best *workUnit
+	for _, unit := range q {
+		if match(unit) && (best == nil || isUnitHigherPriority(unit, best)) {
+			best = unit
+		}
+	}
+	return best
+}
+
+// NextMatching gets the next available unit for which match returns
+// true.  Returns nil if there is none.
+func (q *availableUnits) NextMatching(match func(*workUnit) bool) *workUnit {
+	unit := q.PeekMatching(match)
+	if unit != nil {
+		q.Remove(unit)
+	}
+	return unit
+}
+
 // Remove a specific work unit.
 func (q *availableUnits) Remove(unit *workUnit) {
 	if unit.availableIndex > 0 {
@@ -41,7 +73,25 @@ func (q availableUnits) Len() int {
 }
 
 // isUnitHigherPriority returns true if a is more important than b.
+// This depends on the work spec's configured order; both units are
+// expected to be in the same work spec.
 func isUnitHigherPriority(a, b *workUnit) bool {
+	order := coordinate.OrderPriority
+	if a.workSpec != nil {
+		order = a.workSpec.meta.Order
+	}
+	switch order {
+	case coordinate.OrderFIFO:
+		if !a.createdAt.Equal(b.createdAt) {
+			return a.createdAt.Before(b.createdAt)
+		}
+		return a.name < b.name
+	case coordinate.OrderLIFO:
+		if !a.createdAt.Equal(b.createdAt) {
+			return a.createdAt.After(b.createdAt)
+		}
+		return a.name < b.name
+	}
 	if a.meta.Priority > b.meta.Priority {
 		return true
 	}
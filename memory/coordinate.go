@@ -2,10 +2,13 @@
 // This software is released under an MIT/X11 open source license.
 
 // Package memory provides an in-process, in-memory implementation of
-// Coordinate.  There is no persistence on this job queue, nor is
-// there any automatic sharing.  The entire system is behind a single
-// global semaphore to protect against concurrent updates; in some
-// cases this can limit performance in the name of correctness.
+// Coordinate.  There is no automatic persistence on this job queue,
+// though Save() and Load() can write its state out and read it back,
+// nor is there any automatic sharing.  Each namespace is behind its
+// own semaphore to protect against concurrent updates, so work in
+// different namespaces proceeds in parallel, but everything within a
+// namespace is serialized; in some cases this can limit performance
+// in the name of correctness.
 //
 // This is mostly intended as a simple reference implementation of
 // Coordinate that can be used for testing, including in-process
@@ -17,6 +20,8 @@ import (
 	"github.com/benbjohnson/clock"
 	"github.com/diffeo/go-coordinate/coordinate"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // This is the only external entry point to this package:
@@ -47,7 +52,9 @@ type coordinable interface {
 }
 
 // globalLock locks the coordinate object at the root of the object
-// tree.  Pair this with globalUnlock, as
+// tree.  This excludes every namespace lock, so it is for changing
+// the set of namespaces or backend-wide settings, and for the rare
+// operations that span namespaces.  Pair this with globalUnlock, as
 //
 //     globalLock(self)
 //     defer globalUnlock(self)
@@ -64,26 +71,142 @@ func globalUnlock(c coordinable) {
 // Coordinate wrapper type:
 
 type memCoordinate struct {
-	namespaces map[string]*namespace
-	sem        sync.Mutex
-	clock      clock.Clock
+	namespaces      map[string]*namespace
+	sem             sync.RWMutex
+	clock           clock.Clock
+	dataHistory     int
+	maxNamespaces   int
+	throttle        coordinate.RequestThrottle
+	workerGrace     time.Duration
+	keys            coordinate.KeyNormalizer
+	archiveKeep     int64
+	archiveAge      int64
+	strict          bool
+	maxWorkSpecData int
+	gate            coordinate.SchedulingGate
 }
 
 func (c *memCoordinate) Namespace(namespace string) (coordinate.Namespace, error) {
+	// Most calls find an existing namespace, and only need to
+	// share the lock
+	c.sem.RLock()
+	ns := c.namespaces[namespace]
+	c.sem.RUnlock()
+	if ns != nil {
+		return ns, nil
+	}
+
 	globalLock(c)
 	defer globalUnlock(c)
 
-	ns := c.namespaces[namespace]
+	ns = c.namespaces[namespace]
 	if ns == nil {
+		if c.maxNamespaces > 0 && len(c.namespaces) >= c.maxNamespaces {
+			return nil, coordinate.ErrTooManyNamespaces{Name: namespace}
+		}
 		ns = newNamespace(c, namespace)
 		c.namespaces[namespace] = ns
 	}
 	return ns, nil
 }
 
-func (c *memCoordinate) Namespaces() (map[string]coordinate.Namespace, error) {
+// SetDataHistory sets the number of data snapshots kept for each
+// attempt, implementing coordinate.DataHistorySetter.
+func (c *memCoordinate) SetDataHistory(limit int) {
 	globalLock(c)
 	defer globalUnlock(c)
+	c.dataHistory = limit
+}
+
+// SetMaxNamespaces limits the number of namespaces this will create,
+// implementing coordinate.NamespaceLimiter.
+func (c *memCoordinate) SetMaxNamespaces(limit int) {
+	globalLock(c)
+	defer globalUnlock(c)
+	c.maxNamespaces = limit
+}
+
+// SetRequestInterval limits how often each worker can get attempts,
+// implementing coordinate.RequestThrottler.
+func (c *memCoordinate) SetRequestInterval(interval time.Duration) {
+	c.throttle.SetInterval(interval)
+}
+
+// SetWorkerGracePeriod sets how long a worker stays alive past its
+// expiration, implementing coordinate.WorkerGracePeriodSetter.
+func (c *memCoordinate) SetWorkerGracePeriod(grace time.Duration) {
+	globalLock(c)
+	defer globalUnlock(c)
+	c.workerGrace = grace
+}
+
+// SetAttemptArchive sets when completed attempts are archived,
+// implementing coordinate.AttemptArchiver.  The settings are only
+// read while archiving a work unit's attempts under its namespace
+// lock, so rather than stopping every namespace with the global
+// lock, they are stored atomically.
+func (c *memCoordinate) SetAttemptArchive(keep int, age time.Duration) {
+	atomic.StoreInt64(&c.archiveKeep, int64(keep))
+	atomic.StoreInt64(&c.archiveAge, int64(age))
+}
+
+// attemptArchive returns the current attempt archive settings.
+func (c *memCoordinate) attemptArchive() (keep int, age time.Duration) {
+	keep = int(atomic.LoadInt64(&c.archiveKeep))
+	age = time.Duration(atomic.LoadInt64(&c.archiveAge))
+	return
+}
+
+// SetStrictCompletion sets whether completing an attempt that is no
+// longer active fails, implementing coordinate.StrictCompletionSetter.
+func (c *memCoordinate) SetStrictCompletion(strict bool) {
+	globalLock(c)
+	defer globalUnlock(c)
+	c.strict = strict
+}
+
+// SetMaxWorkSpecData limits the encoded size of work spec data,
+// implementing coordinate.WorkSpecDataLimiter.
+func (c *memCoordinate) SetMaxWorkSpecData(limit int) {
+	globalLock(c)
+	defer globalUnlock(c)
+	c.maxWorkSpecData = limit
+}
+
+// SetKeyNormalizer sets a function applied to work unit keys,
+// implementing coordinate.KeyNormalizerSetter.
+func (c *memCoordinate) SetKeyNormalizer(normalize func(string) string) {
+	c.keys.Set(normalize)
+}
+
+// SetSchedulingGate sets a function that can veto scheduling work
+// specs, implementing coordinate.SchedulingGater.  RequestAttempts()
+// calls it with the namespace lock held.
+func (c *memCoordinate) SetSchedulingGate(gate func(namespace, workSpec string) bool) {
+	c.gate.Set(gate)
+}
+
+// Supports reports which optional features this backend has,
+// implementing coordinate.Capable.
+func (c *memCoordinate) Supports(feature string) bool {
+	switch feature {
+	case coordinate.FeatureDataHistory,
+		coordinate.FeatureNamespaceLimit,
+		coordinate.FeatureRequestThrottle,
+		coordinate.FeatureWorkerGracePeriod,
+		coordinate.FeatureKeyNormalization,
+		coordinate.FeatureAttemptArchive,
+		coordinate.FeatureStrictCompletion,
+		coordinate.FeatureWorkSpecDataLimit,
+		coordinate.FeatureSchedulingGate:
+		return true
+	}
+	return false
+}
+
+func (c *memCoordinate) Namespaces() (map[string]coordinate.Namespace, error) {
+	c.sem.RLock()
+	defer c.sem.RUnlock()
 
 	result := make(map[string]coordinate.Namespace)
 	for name, namespace := range c.namespaces {
@@ -92,13 +215,21 @@ func (c *memCoordinate) Namespaces() (map[string]coordinate.Namespace, error) {
 	return result, nil
 }
 
+func (c *memCoordinate) ServerTime() (time.Time, error) {
+	return c.clock.Now(), nil
+}
+
 func (c *memCoordinate) Summarize() (coordinate.Summary, error) {
-	globalLock(c)
-	defer globalUnlock(c)
+	c.sem.RLock()
+	defer c.sem.RUnlock()
 
+	// Summarize one namespace at a time, so the others can keep
+	// working
 	var result coordinate.Summary
 	for _, ns := range c.namespaces {
+		ns.sem.Lock()
 		result = append(result, ns.summarize()...)
+		ns.sem.Unlock()
 	}
 	return result, nil
 }
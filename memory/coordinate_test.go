@@ -4,10 +4,15 @@
 package memory_test
 
 import (
+	"fmt"
+	"github.com/benbjohnson/clock"
+	"github.com/diffeo/go-coordinate/coordinate"
 	"github.com/diffeo/go-coordinate/coordinate/coordinatetest"
 	"github.com/diffeo/go-coordinate/memory"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"testing"
+	"time"
 )
 
 // Suite runs the generic Coordinate tests with a memory backend.
@@ -19,9 +24,124 @@ type Suite struct {
 func (s *Suite) SetupSuite() {
 	s.Suite.SetupSuite()
 	s.Coordinate = memory.NewWithClock(s.Clock)
+	s.DataHistory = 3
+	s.Coordinate.(coordinate.DataHistorySetter).SetDataHistory(s.DataHistory)
 }
 
 // TestCoordinate runs the generic Coordinate tests with a memory backend.
 func TestCoordinate(t *testing.T) {
 	suite.Run(t, &Suite{})
 }
+
+// TestMaxNamespaces checks that a namespace limit prevents creating
+// new namespaces but still allows retrieving existing ones.
+func TestMaxNamespaces(t *testing.T) {
+	c := memory.New()
+	_, err := c.Namespace("a")
+	assert.NoError(t, err)
+	_, err = c.Namespace("b")
+	assert.NoError(t, err)
+
+	c.(coordinate.NamespaceLimiter).SetMaxNamespaces(2)
+
+	ns, err := c.Namespace("a")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "a", ns.Name())
+	}
+	_, err = c.Namespace("c")
+	assert.Equal(t, coordinate.ErrTooManyNamespaces{Name: "c"}, err)
+
+	namespaces, err := c.Namespaces()
+	if assert.NoError(t, err) {
+		assert.Len(t, namespaces, 2)
+	}
+
+	c.(coordinate.NamespaceLimiter).SetMaxNamespaces(0)
+	_, err = c.Namespace("c")
+	assert.NoError(t, err)
+}
+
+// TestRequestInterval checks that a worker calling RequestAttempts
+// too often gets no work, while a worker calling it at a reasonable
+// pace is unaffected.
+func TestRequestInterval(t *testing.T) {
+	clk := clock.NewMock()
+	c := memory.NewWithClock(clk)
+	c.(coordinate.RequestThrottler).SetRequestInterval(10 * time.Second)
+	ns, err := c.Namespace("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	spec, err := ns.SetWorkSpec(map[string]interface{}{"name": "spec"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	for i := 0; i < 10; i++ {
+		_, err = spec.AddWorkUnit(fmt.Sprintf("u%02d", i), map[string]interface{}{}, coordinate.WorkUnitMeta{})
+		assert.NoError(t, err)
+	}
+	fast, err := ns.Worker("fast")
+	if !assert.NoError(t, err) {
+		return
+	}
+	slow, err := ns.Worker("slow")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	fastCount, slowCount := 0, 0
+	for i := 0; i < 4; i++ {
+		// fast polls every second; slow every ten
+		for j := 0; j < 10; j++ {
+			attempts, err := fast.RequestAttempts(coordinate.AttemptRequest{})
+			assert.NoError(t, err)
+			fastCount += len(attempts)
+			clk.Add(1 * time.Second)
+		}
+		attempts, err := slow.RequestAttempts(coordinate.AttemptRequest{})
+		assert.NoError(t, err)
+		slowCount += len(attempts)
+	}
+	assert.Equal(t, 4, fastCount)
+	assert.Equal(t, 4, slowCount)
+}
+
+// TestMakeAttemptOtherNamespace checks that a worker cannot make an
+// attempt for a work unit in a different namespace, since each
+// namespace has its own lock.
+func TestMakeAttemptOtherNamespace(t *testing.T) {
+	c := memory.New()
+	ns, err := c.Namespace("a")
+	if !assert.NoError(t, err) {
+		return
+	}
+	spec, err := ns.SetWorkSpec(map[string]interface{}{"name": "spec"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	unit, err := spec.AddWorkUnit("unit", map[string]interface{}{}, coordinate.WorkUnitMeta{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	other, err := c.Namespace("b")
+	if !assert.NoError(t, err) {
+		return
+	}
+	worker, err := other.Worker("worker")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = worker.MakeAttempt(unit, 0)
+	assert.Equal(t, coordinate.ErrWrongNamespace, err)
+	status, err := unit.Status()
+	if assert.NoError(t, err) {
+		assert.Equal(t, coordinate.AvailableUnit, status)
+	}
+}
+
+// BenchmarkParallel runs the generic parallel benchmark with a
+// memory backend.
+func BenchmarkParallel(b *testing.B) {
+	coordinatetest.BenchmarkParallel(b, memory.New())
+}
@@ -0,0 +1,21 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package memory
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var continuousUnits = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "coordinate",
+		Subsystem: "memory",
+		Name:      "continuous_units_total",
+		Help:      "Number of work units generated for continuous work specs",
+	},
+	[]string{"namespace", "work_spec"})
+
+func init() {
+	prometheus.MustRegister(continuousUnits)
+}
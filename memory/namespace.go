@@ -5,12 +5,21 @@ package memory
 
 import (
 	"github.com/diffeo/go-coordinate/coordinate"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
-// namespace is a container type for a coordinate.Namespace.
+// namespace is a container type for a coordinate.Namespace.  Its
+// semaphore protects everything in the namespace: work specs, work
+// units, attempts, and workers.  Work specs share it, rather than
+// having their own, because workers, the scheduler, and chained work
+// specs all cross between them.
 type namespace struct {
 	name       string
 	coordinate *memCoordinate
+	sem        sync.Mutex
 	workSpecs  map[string]*workSpec
 	workers    map[string]*worker
 	deleted    bool
@@ -40,9 +49,26 @@ func (ns *namespace) Destroy() error {
 	return nil
 }
 
+// lock takes this namespace's lock.  It also shares the global
+// lock, so that backend-wide settings hold still and this cannot
+// overlap globalLock().  Pair this with unlock, as
+//
+//	ns.lock()
+//	defer ns.unlock()
+func (ns *namespace) lock() {
+	ns.coordinate.sem.RLock()
+	ns.sem.Lock()
+}
+
+// unlock releases this namespace's lock.
+func (ns *namespace) unlock() {
+	ns.sem.Unlock()
+	ns.coordinate.sem.RUnlock()
+}
+
 func (ns *namespace) do(f func() error) error {
-	globalLock(ns)
-	defer globalUnlock(ns)
+	ns.lock()
+	defer ns.unlock()
 
 	if ns.deleted {
 		return coordinate.ErrGone
@@ -61,6 +87,10 @@ func (ns *namespace) SetWorkSpec(data map[string]interface{}) (spec coordinate.W
 		if !ok {
 			return coordinate.ErrBadWorkSpecName
 		}
+		err := ns.checkWorkSpecData(name, data)
+		if err != nil {
+			return err
+		}
 		theSpec := ns.workSpecs[name]
 		if theSpec == nil {
 			theSpec = newWorkSpec(ns, name)
@@ -72,6 +102,25 @@ func (ns *namespace) SetWorkSpec(data map[string]interface{}) (spec coordinate.W
 	return
 }
 
+// checkWorkSpecData returns ErrWorkSpecDataTooLarge if data is
+// larger than the coordinate's work spec data limit.  It assumes the
+// namespace lock.
+func (ns *namespace) checkWorkSpecData(name string, data map[string]interface{}) error {
+	limit := ns.coordinate.maxWorkSpecData
+	if limit <= 0 {
+		return nil
+	}
+	size, err := encodedSize(data)
+	if err == nil && size > limit {
+		err = coordinate.ErrWorkSpecDataTooLarge{
+			WorkSpec: name,
+			Size:     size,
+			Limit:    limit,
+		}
+	}
+	return err
+}
+
 func (ns *namespace) WorkSpec(name string) (spec coordinate.WorkSpec, err error) {
 	err = ns.do(func() error {
 		var present bool
@@ -90,10 +139,38 @@ func (ns *namespace) DestroyWorkSpec(name string) error {
 		if !present {
 			return coordinate.ErrNoSuchWorkSpec{Name: name}
 		}
-		spec.deleted = true
-		delete(ns.workSpecs, name)
+		ns.destroyWorkSpec(spec)
+		return nil
+	})
+}
+
+func (ns *namespace) DestroyWorkSpecs(names []string) (count int, err error) {
+	err = ns.do(func() error {
+		for _, name := range names {
+			spec, present := ns.workSpecs[name]
+			if !present {
+				continue
+			}
+			ns.destroyWorkSpec(spec)
+			count++
+		}
 		return nil
 	})
+	return
+}
+
+// destroyWorkSpec removes a work spec from this namespace, and
+// removes its attempts from their workers so that nothing refers to
+// them.  Assumes the namespace lock.
+func (ns *namespace) destroyWorkSpec(spec *workSpec) {
+	for _, unit := range spec.workUnits {
+		for _, attempt := range unit.attempts {
+			attempt.worker.completeAttempt(attempt)
+			attempt.worker.removeAttempt(attempt)
+		}
+	}
+	spec.deleted = true
+	delete(ns.workSpecs, spec.name)
 }
 
 func (ns *namespace) WorkSpecNames() (names []string, err error) {
@@ -102,13 +179,34 @@ func (ns *namespace) WorkSpecNames() (names []string, err error) {
 		for name := range ns.workSpecs {
 			names = append(names, name)
 		}
+		sort.Strings(names)
+		return nil
+	})
+	return
+}
+
+func (ns *namespace) AvailableRuntimes() (runtimes []string, err error) {
+	err = ns.do(func() error {
+		seen := make(map[string]bool)
+		runtimes = []string{}
+		for _, spec := range ns.workSpecs {
+			spec.expireUnits()
+			for _, unit := range spec.available {
+				runtime := unit.meta.EffectiveRuntime(spec.meta.Runtime)
+				if !seen[runtime] && unit.status() == coordinate.AvailableUnit {
+					seen[runtime] = true
+					runtimes = append(runtimes, runtime)
+				}
+			}
+		}
+		sort.Strings(runtimes)
 		return nil
 	})
 	return
 }
 
 // allMetas retrieves the metadata for all work specs.  This cannot
-// fail.  It expects to run within the global lock.
+// fail.  It expects to run within the namespace lock.
 func (ns *namespace) allMetas(withCounts bool) (map[string]*workSpec, map[string]*coordinate.WorkSpecMeta) {
 	metas := make(map[string]*coordinate.WorkSpecMeta)
 	for name, spec := range ns.workSpecs {
@@ -143,6 +241,153 @@ func (ns *namespace) Workers() (workers map[string]coordinate.Worker, err error)
 	return
 }
 
+func (ns *namespace) DeactivateWorkers(q coordinate.WorkerQuery) (count int, err error) {
+	err = ns.do(func() error {
+		now := ns.Coordinate().clock.Now()
+		for name, worker := range ns.workers {
+			if !strings.HasPrefix(name, q.NamePrefix) {
+				continue
+			}
+			if q.Expired && !worker.expiration.Before(now) {
+				continue
+			}
+			if worker.active {
+				worker.active = false
+				count++
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// DeleteWorkers removes the workers matching q.  Worker parents may
+// be in other namespaces, so this takes the global lock.
+func (ns *namespace) DeleteWorkers(q coordinate.WorkerQuery) (count int, err error) {
+	globalLock(ns)
+	defer globalUnlock(ns)
+	if ns.deleted {
+		return 0, coordinate.ErrGone
+	}
+
+	now := ns.Coordinate().clock.Now()
+	deleted := make(map[*worker]bool)
+	for name, worker := range ns.workers {
+		if !strings.HasPrefix(name, q.NamePrefix) {
+			continue
+		}
+		if q.Expired && !worker.expiration.Before(now) {
+			continue
+		}
+		if worker.holdsCompletedUnit() {
+			continue
+		}
+		worker.delete()
+		deleted[worker] = true
+	}
+	if len(deleted) == 0 {
+		return 0, nil
+	}
+
+	// Drop the deleted workers' attempts from their work units'
+	// histories, including archived attempts that the workers no
+	// longer track themselves
+	dropAttempts := func(list []*attempt) []*attempt {
+		var kept []*attempt
+		for _, a := range list {
+			if !deleted[a.worker] {
+				kept = append(kept, a)
+			}
+		}
+		return kept
+	}
+	for _, spec := range ns.workSpecs {
+		for _, unit := range spec.workUnits {
+			unit.attempts = dropAttempts(unit.attempts)
+			unit.archived = dropAttempts(unit.archived)
+		}
+	}
+	return len(deleted), nil
+}
+
+func (ns *namespace) ExpiringAttempts(within time.Duration) (attempts []coordinate.Attempt, err error) {
+	err = ns.do(func() error {
+		deadline := ns.Coordinate().clock.Now().Add(within)
+		var expiring []*attempt
+		for _, spec := range ns.workSpecs {
+			spec.expireUnits()
+			for _, unit := range spec.workUnits {
+				a := unit.activeAttempt
+				if a == nil || a.status != coordinate.Pending {
+					continue
+				}
+				if a.expirationTime.After(deadline) {
+					continue
+				}
+				expiring = append(expiring, a)
+			}
+		}
+		sort.Slice(expiring, func(i, j int) bool {
+			ai, aj := expiring[i], expiring[j]
+			if !ai.expirationTime.Equal(aj.expirationTime) {
+				return ai.expirationTime.Before(aj.expirationTime)
+			}
+			if ai.workUnit.workSpec.name != aj.workUnit.workSpec.name {
+				return ai.workUnit.workSpec.name < aj.workUnit.workSpec.name
+			}
+			return ai.workUnit.name < aj.workUnit.name
+		})
+		attempts = make([]coordinate.Attempt, len(expiring))
+		for i, a := range expiring {
+			attempts[i] = a
+		}
+		return nil
+	})
+	return
+}
+
+func (ns *namespace) RebalanceAttempts(maxPending int) (count int, err error) {
+	if maxPending < 0 {
+		maxPending = 0
+	}
+	err = ns.do(func() error {
+		// Clear out already-expired attempts first so they
+		// do not count against their workers
+		for _, spec := range ns.workSpecs {
+			spec.expireUnits()
+		}
+		for _, worker := range ns.workers {
+			// activeAttempts is in the order the attempts
+			// were made; keep the oldest ones
+			var excess []*attempt
+			kept := 0
+			for _, a := range worker.activeAttempts {
+				if !a.isPending() {
+					continue
+				}
+				if kept < maxPending {
+					kept++
+				} else {
+					excess = append(excess, a)
+				}
+			}
+			for _, a := range excess {
+				a.released = true
+				a.finish(coordinate.Expired, nil)
+				count++
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// PipelineHealth uses the generic implementation, so each work spec
+// is examined under its own lock rather than all at once.
+func (ns *namespace) PipelineHealth() ([]coordinate.PipelineIssue, error) {
+	return coordinate.PipelineHealth(ns, ns.Coordinate().clock.Now())
+}
+
 // coordinate.Summarizable interface:
 
 func (ns *namespace) Summarize() (result coordinate.Summary, err error) {
@@ -0,0 +1,454 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package memory
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/diffeo/go-coordinate/cborrpc"
+	"github.com/diffeo/go-coordinate/coordinate"
+	"github.com/satori/go.uuid"
+	"github.com/ugorji/go/codec"
+)
+
+// snapshotVersion is the version number written by Save.  Load
+// rejects snapshots with any other version.
+const snapshotVersion = 1
+
+// ErrSnapshotVersion is returned from Load if the snapshot was
+// written with a format version this package does not understand.
+type ErrSnapshotVersion struct {
+	Version int
+}
+
+func (err ErrSnapshotVersion) Error() string {
+	return fmt.Sprintf("unsupported memory snapshot version %v", err.Version)
+}
+
+// ErrSnapshotAttempt is returned from Load if a worker in the
+// snapshot refers to an attempt that the snapshot does not contain.
+// LoadSkipping reports it and drops the reference instead.
+type ErrSnapshotAttempt struct {
+	Worker   string
+	WorkSpec string
+	WorkUnit string
+	Index    int
+}
+
+func (err ErrSnapshotAttempt) Error() string {
+	return fmt.Sprintf("snapshot worker %q refers to missing attempt %v of %q in %q", err.Worker, err.Index, err.WorkUnit, err.WorkSpec)
+}
+
+// The snapshot types mirror the in-memory object graph, replacing
+// pointers with names.  Attempts are stored with their work units;
+// workers refer to them by position.
+
+type snapshot struct {
+	Version int
+	// Time is the backend's clock time when the snapshot was
+	// taken.  Older snapshots do not have it.
+	Time       time.Time
+	Namespaces []snapNamespace
+}
+
+type snapNamespace struct {
+	Name      string
+	WorkSpecs []snapWorkSpec
+	Workers   []snapWorker
+}
+
+type snapWorkSpec struct {
+	Name      string
+	Data      map[string]interface{}
+	Meta      coordinate.WorkSpecMeta
+	WorkUnits []snapWorkUnit
+}
+
+type snapWorkUnit struct {
+	Name      string
+	Data      map[string]interface{}
+	Meta      coordinate.WorkUnitMeta
+	CreatedAt time.Time
+	Available bool
+	// ActiveAttempt is the index of the active attempt in
+	// Attempts, or -1 if there is none.
+	ActiveAttempt int
+	Attempts      []snapAttempt
+	Archived      []snapAttempt
+	Retries       int
+}
+
+type snapAttempt struct {
+	ID             string
+	Worker         string
+	Status         coordinate.AttemptStatus
+	Data           map[string]interface{}
+	StartTime      time.Time
+	EndTime        time.Time
+	ExpirationTime time.Time
+	FinishPrepared bool
+	Released       bool
+	History        []coordinate.DataSnapshot
+	Logs           []string
+}
+
+type snapWorker struct {
+	Name           string
+	Parent         string
+	Data           map[string]interface{}
+	Active         bool
+	Expiration     time.Time
+	LastUpdate     time.Time
+	Mode           string
+	ActiveAttempts []snapAttemptRef
+	Attempts       []snapAttemptRef
+}
+
+// snapAttemptRef identifies an attempt by its work spec, work unit,
+// and position in the work unit's attempt list.
+type snapAttemptRef struct {
+	WorkSpec string
+	WorkUnit string
+	Index    int
+}
+
+func snapshotHandle() (*codec.CborHandle, error) {
+	cbor := new(codec.CborHandle)
+	err := cborrpc.SetExts(cbor)
+	return cbor, err
+}
+
+// Save writes the entire state of an in-memory Coordinate backend
+// to w, in a compact binary form that Load can read back.  Settings
+// such as the data history length and request throttle are not
+// saved.  Returns coordinate.ErrWrongBackend if c was not created by
+// this package.
+func Save(c coordinate.Coordinate, w io.Writer) error {
+	mc, ok := c.(*memCoordinate)
+	if !ok {
+		return coordinate.ErrWrongBackend
+	}
+	return mc.Snapshot(w)
+}
+
+// Snapshot writes the entire state of this backend to w, as Save()
+// does.  This is not part of the coordinate.Coordinate interface,
+// but callers holding the object returned from New() can reach it
+// with a type assertion, without depending on this package.
+func (c *memCoordinate) Snapshot(w io.Writer) error {
+	cbor, err := snapshotHandle()
+	if err != nil {
+		return err
+	}
+
+	globalLock(c)
+	snap := c.snapshot()
+	globalUnlock(c)
+
+	return codec.NewEncoder(w, cbor).Encode(&snap)
+}
+
+// snapshot builds the serializable form of the entire backend.
+// Assumes the global lock.
+func (c *memCoordinate) snapshot() snapshot {
+	snap := snapshot{
+		Version: snapshotVersion,
+		Time:    c.clock.Now(),
+	}
+	for _, ns := range c.namespaces {
+		// Index every attempt so workers can refer to them
+		refs := make(map[*attempt]snapAttemptRef)
+		snapNS := snapNamespace{Name: ns.name}
+		for _, spec := range ns.workSpecs {
+			snapSpec := snapWorkSpec{
+				Name: spec.name,
+				Data: spec.data,
+				Meta: spec.meta,
+			}
+			for _, unit := range spec.workUnits {
+				snapUnit := snapWorkUnit{
+					Name:          unit.name,
+					Data:          unit.data,
+					Meta:          unit.meta,
+					CreatedAt:     unit.createdAt,
+					Available:     unit.availableIndex > 0,
+					ActiveAttempt: -1,
+					Retries:       unit.retries,
+				}
+				for i, a := range unit.attempts {
+					if a == unit.activeAttempt {
+						snapUnit.ActiveAttempt = i
+					}
+					refs[a] = snapAttemptRef{
+						WorkSpec: spec.name,
+						WorkUnit: unit.name,
+						Index:    i,
+					}
+					snapUnit.Attempts = append(snapUnit.Attempts, a.snapshot())
+				}
+				for _, a := range unit.archived {
+					snapUnit.Archived = append(snapUnit.Archived, a.snapshot())
+				}
+				snapSpec.WorkUnits = append(snapSpec.WorkUnits, snapUnit)
+			}
+			snapNS.WorkSpecs = append(snapNS.WorkSpecs, snapSpec)
+		}
+		for _, worker := range ns.workers {
+			snapWorker := snapWorker{
+				Name:       worker.name,
+				Data:       worker.data,
+				Active:     worker.active,
+				Expiration: worker.expiration,
+				LastUpdate: worker.lastUpdate,
+				Mode:       worker.mode,
+			}
+			if worker.parent != nil {
+				snapWorker.Parent = worker.parent.name
+			}
+			// Skip attempts that are not in any work unit,
+			// such as those of a destroyed work spec, since
+			// Load could not resolve them
+			for _, a := range worker.activeAttempts {
+				if ref, ok := refs[a]; ok {
+					snapWorker.ActiveAttempts = append(snapWorker.ActiveAttempts, ref)
+				}
+			}
+			for _, a := range worker.attempts {
+				if ref, ok := refs[a]; ok {
+					snapWorker.Attempts = append(snapWorker.Attempts, ref)
+				}
+			}
+			snapNS.Workers = append(snapNS.Workers, snapWorker)
+		}
+		snap.Namespaces = append(snap.Namespaces, snapNS)
+	}
+	return snap
+}
+
+// snapshot builds the serializable form of a single attempt.
+func (a *attempt) snapshot() snapAttempt {
+	return snapAttempt{
+		ID:             a.id,
+		Worker:         a.worker.name,
+		Status:         a.status,
+		Data:           a.data,
+		StartTime:      a.startTime,
+		EndTime:        a.endTime,
+		ExpirationTime: a.expirationTime,
+		FinishPrepared: a.finishPrepared,
+		Released:       a.released,
+		History:        a.history,
+		Logs:           a.logs,
+	}
+}
+
+// Load creates a new in-memory Coordinate backend with the state
+// previously written by Save.  Pending attempts keep their
+// expiration times, so any that ran out while the backend was not
+// running expire the next time anything looks at them.
+func Load(r io.Reader) (coordinate.Coordinate, error) {
+	return LoadWithClock(r, clock.New())
+}
+
+// Restore is the same as Load, for symmetry with Coordinate's
+// Snapshot method.
+func Restore(r io.Reader) (coordinate.Coordinate, error) {
+	return Load(r)
+}
+
+// LoadWithClock creates a new in-memory Coordinate backend with the
+// state previously written by Save, and an explicitly specified time
+// source.  This is intended for use in tests.  If clk is a mock
+// clock that is behind the time the snapshot was taken, it is moved
+// forward to that time, so that pending attempts have the same time
+// left before they expire as they did when they were saved.
+func LoadWithClock(r io.Reader, clk clock.Clock) (coordinate.Coordinate, error) {
+	return load(r, clk, nil)
+}
+
+// LoadSkipping creates a new in-memory Coordinate backend with the
+// state previously written by Save, as Load does.  Where a worker
+// refers to an attempt that is not in the snapshot, rather than
+// failing, it calls skip with an ErrSnapshotAttempt and leaves that
+// attempt out of the worker's lists.
+func LoadSkipping(r io.Reader, skip func(error)) (coordinate.Coordinate, error) {
+	return load(r, clock.New(), skip)
+}
+
+// load is the implementation of the Load functions.  If skip is
+// non-nil, bad attempt references are passed to it rather than
+// failing the load.
+func load(r io.Reader, clk clock.Clock, skip func(error)) (coordinate.Coordinate, error) {
+	cbor, err := snapshotHandle()
+	if err != nil {
+		return nil, err
+	}
+	var snap snapshot
+	err = codec.NewDecoder(r, cbor).Decode(&snap)
+	if err != nil {
+		return nil, err
+	}
+	if snap.Version != snapshotVersion {
+		return nil, ErrSnapshotVersion{Version: snap.Version}
+	}
+	if mock, ok := clk.(*clock.Mock); ok && mock.Now().Before(snap.Time) {
+		mock.Set(snap.Time)
+	}
+
+	c := NewWithClock(clk).(*memCoordinate)
+	for _, snapNS := range snap.Namespaces {
+		err = c.restoreNamespace(snapNS, skip)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// restoreNamespace recreates a single namespace from a snapshot.  It
+// does not need the global lock since the coordinate object is not
+// yet shared.  skip is as for load().
+func (c *memCoordinate) restoreNamespace(snapNS snapNamespace, skip func(error)) error {
+	ns := newNamespace(c, snapNS.Name)
+	c.namespaces[ns.name] = ns
+
+	// Create all of the workers first, since attempts refer to them
+	for _, snapWorker := range snapNS.Workers {
+		worker := newWorker(ns, snapWorker.Name)
+		worker.data = snapWorker.Data
+		worker.active = snapWorker.Active
+		worker.expiration = snapWorker.Expiration
+		worker.lastUpdate = snapWorker.LastUpdate
+		worker.mode = snapWorker.Mode
+		ns.workers[worker.name] = worker
+	}
+	for _, snapWorker := range snapNS.Workers {
+		if snapWorker.Parent == "" {
+			continue
+		}
+		parent := ns.workers[snapWorker.Parent]
+		if parent == nil {
+			return fmt.Errorf("snapshot worker %q has missing parent %q", snapWorker.Name, snapWorker.Parent)
+		}
+		worker := ns.workers[snapWorker.Name]
+		worker.parent = parent
+		parent.children[worker.name] = worker
+	}
+
+	for _, snapSpec := range snapNS.WorkSpecs {
+		spec := newWorkSpec(ns, snapSpec.Name)
+		if snapSpec.Data != nil {
+			spec.data = snapSpec.Data
+		}
+		spec.meta = snapSpec.Meta
+		ns.workSpecs[spec.name] = spec
+		for _, snapUnit := range snapSpec.WorkUnits {
+			unit := &workUnit{
+				name:      snapUnit.Name,
+				data:      snapUnit.Data,
+				createdAt: snapUnit.CreatedAt,
+				retries:   snapUnit.Retries,
+				workSpec:  spec,
+			}
+			spec.setUnitMeta(unit, snapUnit.Meta)
+			for _, snapAttempt := range snapUnit.Attempts {
+				a, err := ns.restoreAttempt(unit, snapAttempt)
+				if err != nil {
+					return err
+				}
+				unit.attempts = append(unit.attempts, a)
+			}
+			for _, snapAttempt := range snapUnit.Archived {
+				a, err := ns.restoreAttempt(unit, snapAttempt)
+				if err != nil {
+					return err
+				}
+				unit.archived = append(unit.archived, a)
+			}
+			if snapUnit.ActiveAttempt >= 0 && snapUnit.ActiveAttempt < len(unit.attempts) {
+				unit.activeAttempt = unit.attempts[snapUnit.ActiveAttempt]
+			}
+			spec.workUnits[unit.name] = unit
+			if snapUnit.Available {
+				spec.available.Add(unit)
+			}
+		}
+	}
+
+	// Now that all of the attempts exist, reconnect the workers
+	for _, snapWorker := range snapNS.Workers {
+		worker := ns.workers[snapWorker.Name]
+		for _, ref := range snapWorker.ActiveAttempts {
+			a, err := ns.findAttempt(worker, ref)
+			if err != nil && skip != nil {
+				skip(err)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			worker.activeAttempts = append(worker.activeAttempts, a)
+		}
+		for _, ref := range snapWorker.Attempts {
+			a, err := ns.findAttempt(worker, ref)
+			if err != nil && skip != nil {
+				skip(err)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			worker.attempts = append(worker.attempts, a)
+		}
+	}
+	return nil
+}
+
+// restoreAttempt recreates a single attempt on unit from a snapshot.
+// The attempt's worker must already have been restored.
+func (ns *namespace) restoreAttempt(unit *workUnit, snapAttempt snapAttempt) (*attempt, error) {
+	worker := ns.workers[snapAttempt.Worker]
+	if worker == nil {
+		return nil, fmt.Errorf("snapshot attempt on %q has missing worker %q", unit.name, snapAttempt.Worker)
+	}
+	id := snapAttempt.ID
+	if id == "" {
+		id = uuid.NewV4().String()
+	}
+	return &attempt{
+		id:             id,
+		workUnit:       unit,
+		worker:         worker,
+		status:         snapAttempt.Status,
+		data:           snapAttempt.Data,
+		startTime:      snapAttempt.StartTime,
+		endTime:        snapAttempt.EndTime,
+		expirationTime: snapAttempt.ExpirationTime,
+		finishPrepared: snapAttempt.FinishPrepared,
+		released:       snapAttempt.Released,
+		history:        snapAttempt.History,
+		logs:           snapAttempt.Logs,
+	}, nil
+}
+
+// findAttempt resolves an attempt reference from a snapshot, made by
+// worker.
+func (ns *namespace) findAttempt(worker *worker, ref snapAttemptRef) (*attempt, error) {
+	spec := ns.workSpecs[ref.WorkSpec]
+	if spec != nil {
+		unit := spec.workUnits[ref.WorkUnit]
+		if unit != nil && ref.Index >= 0 && ref.Index < len(unit.attempts) {
+			return unit.attempts[ref.Index], nil
+		}
+	}
+	return nil, ErrSnapshotAttempt{
+		Worker:   worker.name,
+		WorkSpec: ref.WorkSpec,
+		WorkUnit: ref.WorkUnit,
+		Index:    ref.Index,
+	}
+}
@@ -0,0 +1,380 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package memory_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/diffeo/go-coordinate/coordinate"
+	"github.com/diffeo/go-coordinate/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/ugorji/go/codec"
+)
+
+// TestSnapshotRoundTrip saves a populated backend and checks that
+// the loaded copy has the same work specs, work units, workers, and
+// attempts.
+func TestSnapshotRoundTrip(t *testing.T) {
+	clk := clock.NewMock()
+	c := memory.NewWithClock(clk)
+	ns, err := c.Namespace("ns")
+	if !assert.NoError(t, err) {
+		return
+	}
+	spec, err := ns.SetWorkSpec(map[string]interface{}{
+		"name":     "spec",
+		"priority": 5,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	units := map[string]coordinate.WorkUnitMeta{
+		"available": {Priority: 10},
+		"pending":   {},
+		"finished":  {},
+		"delayed":   {NotBefore: clk.Now().Add(time.Hour)},
+	}
+	for name, meta := range units {
+		_, err = spec.AddWorkUnit(name, map[string]interface{}{"k": name}, meta)
+		if !assert.NoError(t, err) {
+			return
+		}
+	}
+	parent, err := ns.Worker("parent")
+	if !assert.NoError(t, err) {
+		return
+	}
+	child, err := ns.Worker("child")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, child.SetParent(parent))
+	assert.NoError(t, child.Update(map[string]interface{}{"x": "y"}, clk.Now(), clk.Now().Add(time.Hour), "run"))
+	for _, name := range []string{"pending", "finished"} {
+		unit, err := spec.WorkUnit(name)
+		if !assert.NoError(t, err) {
+			return
+		}
+		attempt, err := child.MakeAttempt(unit, time.Hour)
+		if !assert.NoError(t, err) {
+			return
+		}
+		if name == "finished" {
+			assert.NoError(t, attempt.Finish(map[string]interface{}{"done": true}))
+		}
+	}
+
+	var buf bytes.Buffer
+	err = memory.Save(c, &buf)
+	if !assert.NoError(t, err) {
+		return
+	}
+	loaded, err := memory.LoadWithClock(&buf, clk)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ns, err = loaded.Namespace("ns")
+	if !assert.NoError(t, err) {
+		return
+	}
+	spec, err = ns.WorkSpec("spec")
+	if !assert.NoError(t, err) {
+		return
+	}
+	meta, err := spec.Meta(false)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 5, meta.Priority)
+	}
+	statuses := map[string]coordinate.WorkUnitStatus{
+		"available": coordinate.AvailableUnit,
+		"pending":   coordinate.PendingUnit,
+		"finished":  coordinate.FinishedUnit,
+		"delayed":   coordinate.DelayedUnit,
+	}
+	for name, expected := range statuses {
+		unit, err := spec.WorkUnit(name)
+		if !assert.NoError(t, err, name) {
+			continue
+		}
+		status, err := unit.Status()
+		if assert.NoError(t, err, name) {
+			assert.Equal(t, expected, status, name)
+		}
+		if name == "finished" {
+			// its data is now the attempt's data
+			continue
+		}
+		data, err := unit.Data()
+		if assert.NoError(t, err, name) {
+			assert.Equal(t, name, data["k"], name)
+		}
+	}
+
+	unit, err := spec.WorkUnit("finished")
+	if assert.NoError(t, err) {
+		attempt, err := unit.ActiveAttempt()
+		if assert.NoError(t, err) && assert.NotNil(t, attempt) {
+			assert.Equal(t, "child", attempt.Worker().Name())
+			data, err := attempt.Data()
+			if assert.NoError(t, err) {
+				assert.Equal(t, true, data["done"])
+			}
+		}
+	}
+
+	child, err = ns.Worker("child")
+	if !assert.NoError(t, err) {
+		return
+	}
+	parent, err = child.Parent()
+	if assert.NoError(t, err) && assert.NotNil(t, parent) {
+		assert.Equal(t, "parent", parent.Name())
+	}
+	mode, err := child.Mode()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "run", mode)
+	}
+	active, err := child.ActiveAttempts()
+	if assert.NoError(t, err) && assert.Len(t, active, 1) {
+		assert.Equal(t, "pending", active[0].WorkUnit().Name())
+	}
+	all, err := child.AllAttempts()
+	if assert.NoError(t, err) {
+		assert.Len(t, all, 2)
+	}
+
+	// The available unit should still be queued for work
+	attempts, err := parent.RequestAttempts(coordinate.AttemptRequest{})
+	if assert.NoError(t, err) && assert.Len(t, attempts, 1) {
+		assert.Equal(t, "available", attempts[0].WorkUnit().Name())
+	}
+}
+
+// TestSnapshotDestroyedWorkSpec checks that a backend can be saved
+// and loaded after destroying work specs that had attempts, one at a
+// time or in bulk.
+func TestSnapshotDestroyedWorkSpec(t *testing.T) {
+	clk := clock.NewMock()
+	c := memory.NewWithClock(clk)
+	ns, err := c.Namespace("ns")
+	if !assert.NoError(t, err) {
+		return
+	}
+	worker, err := ns.Worker("worker")
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		spec, err := ns.SetWorkSpec(map[string]interface{}{"name": name})
+		if !assert.NoError(t, err) {
+			return
+		}
+		for _, unitName := range []string{"finished", "pending"} {
+			unit, err := spec.AddWorkUnit(unitName, map[string]interface{}{}, coordinate.WorkUnitMeta{})
+			if !assert.NoError(t, err) {
+				return
+			}
+			attempt, err := worker.MakeAttempt(unit, time.Hour)
+			if !assert.NoError(t, err) {
+				return
+			}
+			if unitName == "finished" {
+				assert.NoError(t, attempt.Finish(nil))
+			}
+		}
+	}
+	assert.NoError(t, ns.DestroyWorkSpec("a"))
+	count, err := ns.DestroyWorkSpecs([]string{"c"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, count)
+	}
+
+	var buf bytes.Buffer
+	err = memory.Save(c, &buf)
+	if !assert.NoError(t, err) {
+		return
+	}
+	loaded, err := memory.LoadWithClock(&buf, clk)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ns, err = loaded.Namespace("ns")
+	if !assert.NoError(t, err) {
+		return
+	}
+	worker, err = ns.Worker("worker")
+	if !assert.NoError(t, err) {
+		return
+	}
+	attempts, err := worker.AllAttempts()
+	if assert.NoError(t, err) && assert.Len(t, attempts, 2) {
+		for _, attempt := range attempts {
+			assert.Equal(t, "b", attempt.WorkUnit().WorkSpec().Name())
+		}
+	}
+	attempts, err = worker.ActiveAttempts()
+	if assert.NoError(t, err) && assert.Len(t, attempts, 1) {
+		assert.Equal(t, "pending", attempts[0].WorkUnit().Name())
+	}
+}
+
+// TestSnapshotVersion checks that Load rejects a snapshot from a
+// format version it does not know.
+func TestSnapshotVersion(t *testing.T) {
+	var buf bytes.Buffer
+	err := codec.NewEncoder(&buf, new(codec.CborHandle)).Encode(map[string]interface{}{
+		"Version": 99,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = memory.Load(&buf)
+	assert.Equal(t, memory.ErrSnapshotVersion{Version: 99}, err)
+}
+
+// TestSnapshotMissingAttempt checks that Load rejects a snapshot
+// where a worker refers to an attempt that is not there, and that
+// LoadSkipping reports and drops the reference.
+func TestSnapshotMissingAttempt(t *testing.T) {
+	var buf bytes.Buffer
+	err := codec.NewEncoder(&buf, new(codec.CborHandle)).Encode(map[string]interface{}{
+		"Version": 1,
+		"Namespaces": []interface{}{
+			map[string]interface{}{
+				"Name": "ns",
+				"Workers": []interface{}{
+					map[string]interface{}{
+						"Name": "worker",
+						"Attempts": []interface{}{
+							map[string]interface{}{
+								"WorkSpec": "spec",
+								"WorkUnit": "unit",
+								"Index":    0,
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	expected := memory.ErrSnapshotAttempt{
+		Worker:   "worker",
+		WorkSpec: "spec",
+		WorkUnit: "unit",
+		Index:    0,
+	}
+
+	_, err = memory.Load(bytes.NewReader(buf.Bytes()))
+	assert.Equal(t, expected, err)
+
+	var skipped []error
+	loaded, err := memory.LoadSkipping(bytes.NewReader(buf.Bytes()), func(err error) {
+		skipped = append(skipped, err)
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []error{expected}, skipped)
+	ns, err := loaded.Namespace("ns")
+	if !assert.NoError(t, err) {
+		return
+	}
+	worker, err := ns.Worker("worker")
+	if !assert.NoError(t, err) {
+		return
+	}
+	attempts, err := worker.AllAttempts()
+	if assert.NoError(t, err) {
+		assert.Empty(t, attempts)
+	}
+}
+
+// TestSnapshotClock checks that loading a snapshot with a fresh mock
+// clock picks up the time the snapshot was taken, so a pending
+// attempt keeps its expiration time and expires on schedule.
+func TestSnapshotClock(t *testing.T) {
+	clk := clock.NewMock()
+	clk.Add(90*time.Minute + 1234*time.Nanosecond)
+	c := memory.NewWithClock(clk)
+	ns, err := c.Namespace("ns")
+	if !assert.NoError(t, err) {
+		return
+	}
+	spec, err := ns.SetWorkSpec(map[string]interface{}{"name": "spec"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	unit, err := spec.AddWorkUnit("unit", map[string]interface{}{}, coordinate.WorkUnitMeta{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	worker, err := ns.Worker("worker")
+	if !assert.NoError(t, err) {
+		return
+	}
+	attempt, err := worker.MakeAttempt(unit, 5*time.Minute)
+	if !assert.NoError(t, err) {
+		return
+	}
+	expiration, err := attempt.ExpirationTime()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var buf bytes.Buffer
+	err = c.(interface {
+		Snapshot(w io.Writer) error
+	}).Snapshot(&buf)
+	if !assert.NoError(t, err) {
+		return
+	}
+	loadedClock := clock.NewMock()
+	loaded, err := memory.LoadWithClock(&buf, loadedClock)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, clk.Now().Equal(loadedClock.Now()),
+		"loaded clock %v, saved %v", loadedClock.Now(), clk.Now())
+
+	ns, err = loaded.Namespace("ns")
+	if !assert.NoError(t, err) {
+		return
+	}
+	spec, err = ns.WorkSpec("spec")
+	if !assert.NoError(t, err) {
+		return
+	}
+	unit, err = spec.WorkUnit("unit")
+	if !assert.NoError(t, err) {
+		return
+	}
+	attempt, err = unit.ActiveAttempt()
+	if !assert.NoError(t, err) || !assert.NotNil(t, attempt) {
+		return
+	}
+	loadedExpiration, err := attempt.ExpirationTime()
+	if assert.NoError(t, err) {
+		assert.True(t, expiration.Equal(loadedExpiration),
+			"loaded expiration %v, saved %v", loadedExpiration, expiration)
+	}
+
+	loadedClock.Add(4 * time.Minute)
+	status, err := attempt.Status()
+	if assert.NoError(t, err) {
+		assert.Equal(t, coordinate.Pending, status)
+	}
+	loadedClock.Add(2 * time.Minute)
+	status, err = attempt.Status()
+	if assert.NoError(t, err) {
+		assert.Equal(t, coordinate.Expired, status)
+	}
+}
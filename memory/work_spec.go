@@ -4,8 +4,13 @@
 package memory
 
 import (
+	"container/heap"
+	"context"
+	"github.com/diffeo/go-coordinate/cborrpc"
 	"github.com/diffeo/go-coordinate/coordinate"
+	"github.com/ugorji/go/codec"
 	"sort"
+	"time"
 )
 
 type workSpec struct {
@@ -15,7 +20,13 @@ type workSpec struct {
 	meta      coordinate.WorkSpecMeta
 	workUnits map[string]*workUnit
 	available availableUnits
+	watch     coordinate.WorkBroadcast
 	deleted   bool
+
+	// runtimeUnits counts the work units with a
+	// WorkUnitMeta.Runtime override, so that requests for work
+	// only look for them in work specs that have some.
+	runtimeUnits int
 }
 
 func newWorkSpec(namespace *namespace, name string) *workSpec {
@@ -31,9 +42,13 @@ func (spec *workSpec) Name() string {
 	return spec.name
 }
 
+func (spec *workSpec) Namespace() coordinate.Namespace {
+	return spec.namespace
+}
+
 func (spec *workSpec) do(f func() error) error {
-	globalLock(spec)
-	defer globalUnlock(spec)
+	spec.namespace.lock()
+	defer spec.namespace.unlock()
 
 	if spec.deleted || spec.namespace.deleted {
 		return coordinate.ErrGone
@@ -52,12 +67,16 @@ func (spec *workSpec) Data() (data map[string]interface{}, err error) {
 
 func (spec *workSpec) SetData(data map[string]interface{}) error {
 	return spec.do(func() error {
+		err := spec.namespace.checkWorkSpecData(spec.name, data)
+		if err != nil {
+			return err
+		}
 		return spec.setData(data)
 	})
 }
 
 // setData is an internal version of SetData() with the same constraints,
-// guarantees, and checking.  It assumes the global lock.
+// guarantees, and checking.  It assumes the namespace lock.
 func (spec *workSpec) setData(data map[string]interface{}) error {
 	name, meta, err := coordinate.ExtractWorkSpecMeta(data)
 	if err == nil {
@@ -66,8 +85,15 @@ func (spec *workSpec) setData(data map[string]interface{}) error {
 		}
 	}
 	if err == nil {
+		reorder := meta.Order != spec.meta.Order
+		// The work spec data does not say whether the spec
+		// is gated; only SetMeta() changes that
+		meta.Gated = spec.meta.Gated
 		spec.data = data
 		spec.meta = meta
+		if reorder {
+			heap.Init(&spec.available)
+		}
 	}
 	return err
 }
@@ -81,7 +107,7 @@ func (spec *workSpec) Meta(withCounts bool) (meta coordinate.WorkSpecMeta, err e
 }
 
 // getMeta gets a copy of this spec's metadata, optionally with counts
-// filled in.  It expects to run within the global lock.
+// filled in.  It expects to run within the namespace lock.
 func (spec *workSpec) getMeta(withCounts bool) coordinate.WorkSpecMeta {
 	result := spec.meta
 	result.AvailableCount = 0
@@ -100,12 +126,26 @@ func (spec *workSpec) getMeta(withCounts bool) coordinate.WorkSpecMeta {
 	return result
 }
 
+func (spec *workSpec) IsSchedulable() (ok bool, reason string, err error) {
+	err = spec.do(func() error {
+		meta := spec.getMeta(true)
+		ok, reason = meta.Schedulable(spec.Coordinate().clock.Now())
+		if ok && !spec.Coordinate().gate.Allow(spec.namespace.name, spec.name) {
+			ok, reason = false, "gated"
+		}
+		return nil
+	})
+	return
+}
+
 func (spec *workSpec) SetMeta(meta coordinate.WorkSpecMeta) error {
 	return spec.do(func() error {
 		// Preserve immutable fields (taking advantage of meta pass-by-value)
 		meta.CanBeContinuous = spec.meta.CanBeContinuous
 		meta.NextWorkSpecName = spec.meta.NextWorkSpecName
 		meta.Runtime = spec.meta.Runtime
+		meta.Order = spec.meta.Order
+		meta.SchemaVersion = spec.meta.SchemaVersion
 
 		// If this cannot be continuous, force-clear that flag
 		if !meta.CanBeContinuous {
@@ -117,54 +157,103 @@ func (spec *workSpec) SetMeta(meta coordinate.WorkSpecMeta) error {
 	})
 }
 
+// WatchWork implements coordinate.WorkWatcher.  Every work unit
+// added to spec.available produces a notification.
+func (spec *workSpec) WatchWork(ctx context.Context) (ch <-chan struct{}, err error) {
+	err = spec.do(func() error {
+		ch = spec.watch.Watch(ctx)
+		return nil
+	})
+	return
+}
+
+func (spec *workSpec) SetNextContinuous(t time.Time) error {
+	return spec.do(func() error {
+		spec.meta.NextContinuous = t
+		return nil
+	})
+}
+
 func (spec *workSpec) AddWorkUnit(name string, data map[string]interface{}, meta coordinate.WorkUnitMeta) (unit coordinate.WorkUnit, err error) {
+	name = spec.Coordinate().keys.Key(name)
 	err = spec.do(func() error {
 		now := spec.Coordinate().clock.Now()
-		theUnit, exists := spec.workUnits[name]
-		if exists {
-			theUnit.data = data
-			theUnit.meta = meta
-			// NB: we do not care if the unit is expired;
-			// that would only cause it to transition
-			// pending -> available which does not affect
-			// this case
-			switch theUnit.status() {
-			case coordinate.AvailableUnit, coordinate.PendingUnit, coordinate.DelayedUnit:
-				// do nothing
-			default:
-				// drop the existing (completed) attempt and
-				// make the work unit be available again
-				theUnit.activeAttempt = nil
-				if !now.Before(theUnit.meta.NotBefore) {
-					spec.available.Add(theUnit)
-				}
-			}
-		} else {
-			theUnit = new(workUnit)
-			theUnit.name = name
-			theUnit.data = data
-			theUnit.meta = meta
-			theUnit.workSpec = spec
-			spec.workUnits[name] = theUnit
+		unit = spec.addWorkUnit(name, data, meta, now)
+		return nil
+	})
+	return
+}
+
+// AddWorkUnits implements coordinate.WorkUnitBatchAdder, adding all
+// of the items under a single lock.
+func (spec *workSpec) AddWorkUnits(items []coordinate.AddWorkUnitItem) error {
+	return spec.do(func() error {
+		now := spec.Coordinate().clock.Now()
+		for _, item := range items {
+			name := spec.Coordinate().keys.Key(item.Key)
+			spec.addWorkUnit(name, item.Data, item.Meta, now)
+		}
+		return nil
+	})
+}
+
+// addWorkUnit does the work of AddWorkUnit, adding a new work unit
+// or replacing the data and metadata of an existing one.  Assumes
+// the namespace lock, and that name is already normalized.
+func (spec *workSpec) addWorkUnit(name string, data map[string]interface{}, meta coordinate.WorkUnitMeta, now time.Time) *workUnit {
+	theUnit, exists := spec.workUnits[name]
+	if exists {
+		theUnit.data = data
+		spec.setUnitMeta(theUnit, meta)
+		// NB: we do not care if the unit is expired;
+		// that would only cause it to transition
+		// pending -> available which does not affect
+		// this case
+		switch theUnit.status() {
+		case coordinate.AvailableUnit, coordinate.PendingUnit, coordinate.DelayedUnit:
+			// do nothing
+		default:
+			// drop the existing (completed) attempt and
+			// make the work unit be available again
+			theUnit.activeAttempt = nil
 			if !now.Before(theUnit.meta.NotBefore) {
 				spec.available.Add(theUnit)
 			}
 		}
-		unit = theUnit
-		return nil
-	})
-	return
+	} else {
+		theUnit = new(workUnit)
+		theUnit.name = name
+		theUnit.data = data
+		spec.setUnitMeta(theUnit, meta)
+		theUnit.createdAt = now
+		theUnit.workSpec = spec
+		spec.workUnits[name] = theUnit
+		if !now.Before(theUnit.meta.NotBefore) {
+			spec.available.Add(theUnit)
+		}
+	}
+	return theUnit
 }
 
 func (spec *workSpec) addWorkUnits(units map[string]coordinate.AddWorkUnitItem) {
 	now := spec.Coordinate().clock.Now()
 	for name, item := range units {
+		name = spec.Coordinate().keys.Key(name)
+		createdAt := now
+		if old := spec.workUnits[name]; old != nil {
+			// A replaced unit keeps its original creation time
+			createdAt = old.createdAt
+			if old.meta.Runtime != "" {
+				spec.runtimeUnits--
+			}
+		}
 		unit := workUnit{
-			name:     name,
-			data:     item.Data,
-			meta:     item.Meta,
-			workSpec: spec,
+			name:      name,
+			data:      item.Data,
+			createdAt: createdAt,
+			workSpec:  spec,
 		}
+		spec.setUnitMeta(&unit, item.Meta)
 		spec.workUnits[name] = &unit
 		if !now.Before(unit.meta.NotBefore) {
 			spec.available.Add(&unit)
@@ -172,7 +261,20 @@ func (spec *workSpec) addWorkUnits(units map[string]coordinate.AddWorkUnitItem)
 	}
 }
 
+// setUnitMeta changes the metadata of a work unit in this work spec,
+// keeping runtimeUnits up to date.  Assumes the namespace lock.
+func (spec *workSpec) setUnitMeta(unit *workUnit, meta coordinate.WorkUnitMeta) {
+	if unit.meta.Runtime != "" {
+		spec.runtimeUnits--
+	}
+	unit.meta = meta
+	if unit.meta.Runtime != "" {
+		spec.runtimeUnits++
+	}
+}
+
 func (spec *workSpec) WorkUnit(name string) (unit coordinate.WorkUnit, err error) {
+	name = spec.Coordinate().keys.Key(name)
 	err = spec.do(func() error {
 		var present bool
 		unit, present = spec.workUnits[name]
@@ -185,12 +287,13 @@ func (spec *workSpec) WorkUnit(name string) (unit coordinate.WorkUnit, err error
 }
 
 // queryWithoutLimit calls a callback function for every work unit that
-// a coordinate.WorkUnitQuery selects, ignoring the limit field (which
-// requires sorting).
+// a coordinate.WorkUnitQuery selects, ignoring the offset and limit
+// fields (which require sorting).
 func (spec *workSpec) queryWithoutLimit(query coordinate.WorkUnitQuery, f func(*workUnit)) {
 	// Clarity over efficiency: iterate through *all* of the work
 	// units and keep the ones that match the query.  If Limit is
 	// specified then sort the result after the fact.
+	query.Names = spec.Coordinate().keys.Keys(query.Names)
 	for name, unit := range spec.workUnits {
 		if name <= query.PreviousName {
 			continue
@@ -220,6 +323,13 @@ func (spec *workSpec) queryWithoutLimit(query coordinate.WorkUnitQuery, f func(*
 				continue
 			}
 		}
+		if query.NeverAttempted && unit.numAttempts() > 0 {
+			continue
+		}
+		if query.WorkerName != "" && (unit.activeAttempt == nil ||
+			unit.activeAttempt.worker.name != query.WorkerName) {
+			continue
+		}
 		// If we are here we have passed all filters
 		f(unit)
 	}
@@ -227,25 +337,33 @@ func (spec *workSpec) queryWithoutLimit(query coordinate.WorkUnitQuery, f func(*
 }
 
 // query calls a callback function for every work unit that a
-// coordinate.WorkUnitQuery selects, in sorted order if limit is specified.
+// coordinate.WorkUnitQuery selects, in sorted order if offset or
+// limit is specified.
 func (spec *workSpec) query(query coordinate.WorkUnitQuery, f func(*workUnit)) {
 	// The query could mention a state, in which case we need to
 	// run expiry to distinguish available vs. pending
 	spec.expireUnits()
-	// No limit?  We know how to do that
-	if query.Limit <= 0 {
+	// No offset or limit?  We know how to do that
+	if query.Offset <= 0 && query.Limit <= 0 {
 		spec.queryWithoutLimit(query, f)
 		return
 	}
-	// Otherwise there *is* a limit.  Collect the interesting keys:
+	// Otherwise collect the interesting keys:
 	var names []string
 	spec.queryWithoutLimit(query, func(unit *workUnit) {
 		names = append(names, unit.name)
 	})
 	// Sort them:
 	sort.Strings(names)
-	// Apply the limit:
-	if len(names) > query.Limit {
+	// Apply the offset and limit:
+	if query.Offset > 0 {
+		if len(names) > query.Offset {
+			names = names[query.Offset:]
+		} else {
+			names = nil
+		}
+	}
+	if query.Limit > 0 && len(names) > query.Limit {
 		names = names[:query.Limit]
 	}
 	// Call the callback
@@ -265,6 +383,39 @@ func (spec *workSpec) WorkUnits(query coordinate.WorkUnitQuery) (result map[stri
 	return
 }
 
+func (spec *workSpec) WorkUnitsPage(query coordinate.WorkUnitQuery) (map[string]coordinate.WorkUnit, string, error) {
+	return coordinate.PageWorkUnits(spec, query)
+}
+
+func (spec *workSpec) WorkUnitStatuses(names []string) (result map[string]coordinate.WorkUnitStatus, err error) {
+	names = spec.Coordinate().keys.Keys(names)
+	err = spec.do(func() error {
+		spec.expireUnits()
+		result = make(map[string]coordinate.WorkUnitStatus)
+		for _, name := range names {
+			if unit, present := spec.workUnits[name]; present {
+				result[name] = unit.status()
+			}
+		}
+		return nil
+	})
+	return
+}
+
+func (spec *workSpec) WorkUnitPriorities(names []string) (result map[string]float64, err error) {
+	names = spec.Coordinate().keys.Keys(names)
+	err = spec.do(func() error {
+		result = make(map[string]float64)
+		for _, name := range names {
+			if unit, present := spec.workUnits[name]; present {
+				result[name] = unit.meta.Priority
+			}
+		}
+		return nil
+	})
+	return
+}
+
 func (spec *workSpec) CountWorkUnitStatus() (result map[coordinate.WorkUnitStatus]int, err error) {
 	err = spec.do(func() error {
 		result = spec.countWorkUnitStatus()
@@ -282,6 +433,56 @@ func (spec *workSpec) countWorkUnitStatus() map[coordinate.WorkUnitStatus]int {
 	return result
 }
 
+func (spec *workSpec) PriorityHistogram() (result map[float64]int, err error) {
+	err = spec.do(func() error {
+		result = make(map[float64]int)
+		for _, unit := range spec.workUnits {
+			result[unit.meta.Priority]++
+		}
+		return nil
+	})
+	return
+}
+
+func (spec *workSpec) DataSize() (size int64, err error) {
+	err = spec.do(func() error {
+		for _, unit := range spec.workUnits {
+			unitSize, err := encodedSize(unit.data)
+			if err != nil {
+				return err
+			}
+			size += int64(unitSize)
+		}
+		return nil
+	})
+	return
+}
+
+// encodedSize returns the length of the CBOR encoding of data.  This
+// is the same encoding the PostgreSQL backend stores, so sizes and
+// limits roughly agree between the two.
+func encodedSize(data map[string]interface{}) (int, error) {
+	cbor := new(codec.CborHandle)
+	err := cborrpc.SetExts(cbor)
+	if err != nil {
+		return 0, err
+	}
+	var buf []byte
+	err = codec.NewEncoderBytes(&buf, cbor).Encode(data)
+	return len(buf), err
+}
+
+func (spec *workSpec) Successors() (names []string, err error) {
+	err = spec.do(func() error {
+		names = []string{}
+		if spec.meta.NextWorkSpecName != "" {
+			names = append(names, spec.meta.NextWorkSpecName)
+		}
+		return nil
+	})
+	return
+}
+
 func (spec *workSpec) SetWorkUnitPriorities(query coordinate.WorkUnitQuery, priority float64) error {
 	return spec.do(func() error {
 		spec.query(query, func(unit *workUnit) {
@@ -302,6 +503,104 @@ func (spec *workSpec) AdjustWorkUnitPriorities(query coordinate.WorkUnitQuery, a
 	})
 }
 
+func (spec *workSpec) ResetPriorities(query coordinate.WorkUnitQuery) error {
+	return spec.SetWorkUnitPriorities(query, 0)
+}
+
+func (spec *workSpec) ReorderAvailable(orderedNames []string) error {
+	orderedNames = spec.Coordinate().keys.Keys(orderedNames)
+	return spec.do(func() error {
+		// Delayed units are included so that they do not jump
+		// ahead of the reordered units once they become available
+		query := coordinate.WorkUnitQuery{
+			Statuses: []coordinate.WorkUnitStatus{
+				coordinate.AvailableUnit,
+				coordinate.DelayedUnit,
+			},
+		}
+		priorities := make(map[string]float64)
+		spec.query(query, func(unit *workUnit) {
+			priorities[unit.name] = unit.meta.Priority
+		})
+		for name, priority := range coordinate.ReorderPriorities(priorities, orderedNames) {
+			unit := spec.workUnits[name]
+			unit.meta.Priority = priority
+			spec.available.Reprioritize(unit)
+		}
+		return nil
+	})
+}
+
+func (spec *workSpec) PurgeAttempts(before time.Time, statuses []coordinate.AttemptStatus) (count int, err error) {
+	if len(statuses) == 0 {
+		statuses = coordinate.TerminalAttemptStatuses()
+	}
+	err = spec.do(func() error {
+		spec.expireUnits()
+		for _, unit := range spec.workUnits {
+			var purged int
+			unit.attempts, purged = unit.purgeAttempts(unit.attempts, before, statuses)
+			count += purged
+			unit.archived, purged = unit.purgeAttempts(unit.archived, before, statuses)
+			count += purged
+		}
+		return nil
+	})
+	return
+}
+
+// purgeAttempts removes the attempts in list that PurgeAttempts
+// should delete from their workers, and returns the remaining
+// attempts and the number removed.  Assumes the namespace lock.
+func (unit *workUnit) purgeAttempts(list []*attempt, before time.Time, statuses []coordinate.AttemptStatus) ([]*attempt, int) {
+	var kept []*attempt
+	count := 0
+	for _, attempt := range list {
+		if attempt == unit.activeAttempt ||
+			attempt.status == coordinate.Pending ||
+			!attempt.endTime.Before(before) ||
+			!attemptStatusIn(attempt.status, statuses) {
+			kept = append(kept, attempt)
+			continue
+		}
+		attempt.worker.removeAttempt(attempt)
+		count++
+	}
+	return kept, count
+}
+
+// attemptStatusIn determines whether status is in statuses; if
+// statuses is empty, every status matches.
+func attemptStatusIn(status coordinate.AttemptStatus, statuses []coordinate.AttemptStatus) bool {
+	if len(statuses) == 0 {
+		return true
+	}
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func (spec *workSpec) CountWorkUnits(query coordinate.WorkUnitQuery) (count int, err error) {
+	err = spec.do(func() error {
+		spec.query(query, func(*workUnit) { count++ })
+		return nil
+	})
+	return
+}
+
+func (spec *workSpec) Summary() (summary coordinate.WorkSpecSummary, err error) {
+	err = spec.do(func() error {
+		summary.Data = spec.data
+		summary.Meta = spec.getMeta(true)
+		summary.Counts = spec.countWorkUnitStatus()
+		return nil
+	})
+	return
+}
+
 func (spec *workSpec) DeleteWorkUnits(query coordinate.WorkUnitQuery) (count int, err error) {
 	err = spec.do(func() error {
 		// NB: This depends somewhat on Go having good behavior if we
@@ -314,6 +613,9 @@ func (spec *workSpec) DeleteWorkUnits(query coordinate.WorkUnitQuery) (count int
 				attempt.worker.removeAttempt(attempt)
 			}
 			delete(spec.workUnits, workUnit.name)
+			if workUnit.meta.Runtime != "" {
+				spec.runtimeUnits--
+			}
 			workUnit.deleted = true
 			spec.available.Remove(workUnit)
 			count++
@@ -326,16 +628,23 @@ func (spec *workSpec) DeleteWorkUnits(query coordinate.WorkUnitQuery) (count int
 
 // expireUnits scans all work units in this work spec, and if any have
 // an active attempt whose expiration time has passed, marks them as
-// expired and clears that active attempt.  It assumes the global
+// expired and clears that active attempt.  It assumes the namespace
 // lock.
 func (spec *workSpec) expireUnits() {
 	now := spec.Coordinate().clock.Now()
 	for _, unit := range spec.workUnits {
 		switch unit.status() {
 		case coordinate.PendingUnit:
-			// If the attempt's expiration time has passed,
+			// If the attempt's worker has been deleted,
+			// release it; if its expiration time has
+			// passed, or its worker has died and we care,
 			// expire it
-			if unit.activeAttempt.expirationTime.Before(now) {
+			if unit.activeAttempt.worker.deleted {
+				unit.activeAttempt.released = true
+				unit.activeAttempt.finish(coordinate.Expired, nil)
+			} else if unit.activeAttempt.expirationTime.Before(now) {
+				unit.activeAttempt.finish(coordinate.Expired, nil)
+			} else if spec.meta.ExpireWithWorker && !unit.activeAttempt.worker.isAlive(now) {
 				unit.activeAttempt.finish(coordinate.Expired, nil)
 			}
 		case coordinate.AvailableUnit:
@@ -4,6 +4,8 @@
 package memory
 
 import (
+	"time"
+
 	"github.com/diffeo/go-coordinate/coordinate"
 )
 
@@ -11,8 +13,11 @@ type workUnit struct {
 	name           string
 	data           map[string]interface{}
 	meta           coordinate.WorkUnitMeta
+	createdAt      time.Time
 	activeAttempt  *attempt
 	attempts       []*attempt
+	archived       []*attempt
+	retries        int
 	workSpec       *workSpec
 	availableIndex int
 	deleted        bool
@@ -35,13 +40,24 @@ func (unit *workUnit) Data() (data map[string]interface{}, err error) {
 	return
 }
 
+func (unit *workUnit) SetData(data map[string]interface{}) error {
+	return unit.do(func() error {
+		unit.workSpec.expireUnits()
+		if unit.activeAttempt != nil {
+			return coordinate.ErrWorkUnitActive
+		}
+		unit.data = data
+		return nil
+	})
+}
+
 func (unit *workUnit) WorkSpec() coordinate.WorkSpec {
 	return unit.workSpec
 }
 
 func (unit *workUnit) do(f func() error) error {
-	globalLock(unit)
-	defer globalUnlock(unit)
+	unit.workSpec.namespace.lock()
+	defer unit.workSpec.namespace.unlock()
 	if unit.deleted || unit.workSpec.deleted || unit.workSpec.namespace.deleted {
 		return coordinate.ErrGone
 	}
@@ -58,33 +74,28 @@ func (unit *workUnit) Status() (status coordinate.WorkUnitStatus, err error) {
 }
 
 // status is an internal helper that converts a single unit's attempt
-// status to a work unit status.  It assumes the global lock (and that
+// status to a work unit status.  It assumes the namespace lock (and that
 // the active attempt will not change under it).  It assumes that, if
 // expiry is necessary, it has already been run.
 func (unit *workUnit) status() coordinate.WorkUnitStatus {
-	if unit.activeAttempt == nil {
-		now := unit.Coordinate().clock.Now()
-		switch {
-		case now.Before(unit.meta.NotBefore):
-			return coordinate.DelayedUnit
+	if unit.activeAttempt != nil {
+		switch unit.activeAttempt.status {
+		case coordinate.Pending:
+			return coordinate.PendingUnit
+		case coordinate.Finished:
+			return coordinate.FinishedUnit
+		case coordinate.Failed:
+			return coordinate.FailedUnit
+		case coordinate.Expired, coordinate.Retryable:
+			// same as no active attempt
 		default:
-			return coordinate.AvailableUnit
+			panic("invalid attempt status")
 		}
 	}
-	switch unit.activeAttempt.status {
-	case coordinate.Pending:
-		return coordinate.PendingUnit
-	case coordinate.Expired:
-		return coordinate.AvailableUnit
-	case coordinate.Finished:
-		return coordinate.FinishedUnit
-	case coordinate.Failed:
-		return coordinate.FailedUnit
-	case coordinate.Retryable:
-		return coordinate.AvailableUnit
-	default:
-		panic("invalid attempt status")
+	if unit.Coordinate().clock.Now().Before(unit.meta.NotBefore) {
+		return coordinate.DelayedUnit
 	}
+	return coordinate.AvailableUnit
 }
 
 func (unit *workUnit) Meta() (meta coordinate.WorkUnitMeta, err error) {
@@ -97,7 +108,7 @@ func (unit *workUnit) Meta() (meta coordinate.WorkUnitMeta, err error) {
 
 func (unit *workUnit) SetMeta(meta coordinate.WorkUnitMeta) error {
 	return unit.do(func() error {
-		unit.meta = meta
+		unit.workSpec.setUnitMeta(unit, meta)
 		unit.workSpec.available.Reprioritize(unit)
 		return nil
 	})
@@ -116,6 +127,14 @@ func (unit *workUnit) SetPriority(priority float64) error {
 	})
 }
 
+func (unit *workUnit) CreatedAt() (createdAt time.Time, err error) {
+	err = unit.do(func() error {
+		createdAt = unit.createdAt
+		return nil
+	})
+	return
+}
+
 func (unit *workUnit) ActiveAttempt() (attempt coordinate.Attempt, err error) {
 	err = unit.do(func() error {
 		unit.workSpec.expireUnits()
@@ -132,11 +151,27 @@ func (unit *workUnit) ActiveAttempt() (attempt coordinate.Attempt, err error) {
 }
 
 // resetAttempt clears the active attempt for a unit and returns it
-// to its work spec's available list.  Assumes the global lock.
+// to its work spec's available list.  Assumes the namespace lock.
 func (unit *workUnit) resetAttempt() {
 	if unit.activeAttempt != nil {
 		unit.activeAttempt = nil
-		unit.workSpec.available.Add(unit)
+		// A delayed unit is added to the available list by
+		// expireUnits() once its delay passes
+		if unit.status() == coordinate.AvailableUnit {
+			unit.workSpec.available.Add(unit)
+		}
+	}
+}
+
+// retry records that the active attempt is being retried or has
+// expired, and applies the work spec's retry delay schedule.  It
+// must be called before resetAttempt().  Assumes the namespace lock.
+func (unit *workUnit) retry() {
+	unit.retries++
+	meta := &unit.workSpec.meta
+	if len(meta.RetryDelays) > 0 {
+		now := unit.Coordinate().clock.Now()
+		unit.meta.NotBefore = now.Add(meta.RetryDelay(unit.retries))
 	}
 }
 
@@ -150,23 +185,87 @@ func (unit *workUnit) ClearActiveAttempt() error {
 func (unit *workUnit) NumAttempts() (int, error) {
 	num := 0
 	unit.do(func() error {
-		num = len(unit.attempts)
+		num = unit.numAttempts()
 		return nil
 	})
 	return num, nil
 }
 
-func (unit *workUnit) Attempts() (attempts []coordinate.Attempt, err error) {
+// numAttempts returns the number of attempts on this work unit,
+// including archived attempts.  Assumes the namespace lock.
+func (unit *workUnit) numAttempts() int {
+	return len(unit.archived) + len(unit.attempts)
+}
+
+// retriesExhausted returns whether this work unit has as many
+// attempts as its work spec's MaxRetries allows, so that another
+// attempt would fail it.  Assumes the namespace lock.
+func (unit *workUnit) retriesExhausted() bool {
+	maxRetries := unit.workSpec.meta.MaxRetries
+	return maxRetries > 0 && unit.countedAttempts() >= maxRetries
+}
+
+// countedAttempts returns the number of attempts for this work unit
+// that count against its work spec's MaxRetries, which is all of
+// them except those given back with Release.  Assumes the namespace
+// lock.
+func (unit *workUnit) countedAttempts() int {
+	count := 0
+	for _, attempts := range [][]*attempt{unit.archived, unit.attempts} {
+		for _, attempt := range attempts {
+			if !attempt.released {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func (unit *workUnit) Attempts() ([]coordinate.Attempt, error) {
+	return unit.QueryAttempts(coordinate.AttemptQuery{})
+}
+
+func (unit *workUnit) QueryAttempts(q coordinate.AttemptQuery) (attempts []coordinate.Attempt, err error) {
 	err = unit.do(func() error {
-		attempts = make([]coordinate.Attempt, len(unit.attempts))
-		for i, attempt := range unit.attempts {
-			attempts[i] = attempt
+		attempts = make([]coordinate.Attempt, 0, unit.numAttempts())
+		for _, attempt := range unit.archived {
+			attempts = append(attempts, attempt)
+		}
+		if !q.ArchivedOnly {
+			for _, attempt := range unit.attempts {
+				attempts = append(attempts, attempt)
+			}
 		}
 		return nil
 	})
 	return
 }
 
+// archiveAttempts moves old completed attempts from this work unit's
+// attempt list to its archive, following the coordinate's archive
+// settings.  Archived attempts are also dropped from their workers'
+// attempt lists.  Assumes the namespace lock.
+func (unit *workUnit) archiveAttempts() {
+	c := unit.Coordinate()
+	keep, age := c.attemptArchive()
+	if keep <= 0 && age <= 0 {
+		return
+	}
+	cutoff := c.clock.Now().Add(-age)
+	var kept []*attempt
+	for i, attempt := range unit.attempts {
+		recent := keep <= 0 || i >= len(unit.attempts)-keep
+		young := age <= 0 || !attempt.endTime.Before(cutoff)
+		if attempt == unit.activeAttempt || attempt.isPending() || (recent && young) {
+			kept = append(kept, attempt)
+			continue
+		}
+		attempt.worker.removeAttempt(attempt)
+		unit.archived = append(unit.archived, attempt)
+	}
+	unit.attempts = kept
+}
+
 // memory.coordinable interface:
 
 func (unit *workUnit) Coordinate() *memCoordinate {
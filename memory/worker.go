@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/diffeo/go-coordinate/coordinate"
+	"github.com/satori/go.uuid"
 	"time"
 )
 
@@ -22,6 +23,7 @@ type worker struct {
 	activeAttempts []*attempt
 	attempts       []*attempt
 	namespace      *namespace
+	deleted        bool
 }
 
 func newWorker(namespace *namespace, name string) *worker {
@@ -46,8 +48,8 @@ func (w *worker) Name() string {
 }
 
 func (w *worker) Parent() (coordinate.Worker, error) {
-	globalLock(w)
-	defer globalUnlock(w)
+	w.namespace.lock()
+	defer w.namespace.unlock()
 
 	if w.parent == nil {
 		return nil, nil
@@ -55,12 +57,17 @@ func (w *worker) Parent() (coordinate.Worker, error) {
 	return w.parent, nil
 }
 
+// SetParent changes this worker's parent.  The parent may be in a
+// different namespace, so this takes the global lock.
 func (w *worker) SetParent(parent coordinate.Worker) error {
-	oldParent := w.parent
 	newParent, ok := parent.(*worker)
 	if !ok {
 		return errors.New("cannot set parent from a different backend")
 	}
+	globalLock(w)
+	defer globalUnlock(w)
+
+	oldParent := w.parent
 	if oldParent == newParent {
 		return nil // no-op
 	}
@@ -75,8 +82,8 @@ func (w *worker) SetParent(parent coordinate.Worker) error {
 }
 
 func (w *worker) Children() ([]coordinate.Worker, error) {
-	globalLock(w)
-	defer globalUnlock(w)
+	w.namespace.lock()
+	defer w.namespace.unlock()
 
 	var result []coordinate.Worker
 	for _, child := range w.children {
@@ -86,74 +93,131 @@ func (w *worker) Children() ([]coordinate.Worker, error) {
 }
 
 func (w *worker) Active() (bool, error) {
-	globalLock(w)
-	defer globalUnlock(w)
+	w.namespace.lock()
+	defer w.namespace.unlock()
 	return w.active, nil
 }
 
+// isAlive determines whether this worker is still running: it has
+// not been deactivated and it has checked in before its expiration
+// time, plus the coordinate's grace period.  It expects to run
+// within the namespace lock.
+func (w *worker) isAlive(now time.Time) bool {
+	deadline := w.expiration.Add(w.Coordinate().workerGrace)
+	return w.active && !deadline.Before(now)
+}
+
 func (w *worker) Deactivate() error {
-	globalLock(w)
-	defer globalUnlock(w)
+	w.namespace.lock()
+	defer w.namespace.unlock()
 	w.active = false
 	return nil
 }
 
 func (w *worker) Mode() (string, error) {
-	globalLock(w)
-	defer globalUnlock(w)
+	w.namespace.lock()
+	defer w.namespace.unlock()
 	return w.mode, nil
 }
 
 func (w *worker) Data() (map[string]interface{}, error) {
-	globalLock(w)
-	defer globalUnlock(w)
+	w.namespace.lock()
+	defer w.namespace.unlock()
 	return w.data, nil
 }
 
 func (w *worker) Expiration() (time.Time, error) {
-	globalLock(w)
-	defer globalUnlock(w)
+	w.namespace.lock()
+	defer w.namespace.unlock()
 	return w.expiration, nil
 }
 
 func (w *worker) LastUpdate() (time.Time, error) {
-	globalLock(w)
-	defer globalUnlock(w)
+	w.namespace.lock()
+	defer w.namespace.unlock()
 	return w.lastUpdate, nil
 }
 
 func (w *worker) Update(data map[string]interface{}, now, expiration time.Time, mode string) error {
-	globalLock(w)
-	defer globalUnlock(w)
+	w.namespace.lock()
+	defer w.namespace.unlock()
 	w.active = true
 	w.data = data
 	w.lastUpdate = now
 	w.expiration = expiration
 	w.mode = mode
+	w.extendAttempts()
 	return nil
 }
 
+// extendAttempts pushes back the expiration time of this worker's
+// pending attempts in work specs that have a heartbeat extension.
+// Assumes the namespace lock.
+func (w *worker) extendAttempts() {
+	now := w.Coordinate().clock.Now()
+	for _, attempt := range w.activeAttempts {
+		if attempt.status != coordinate.Pending || attempt.expirationTime.Before(now) {
+			continue
+		}
+		meta := attempt.workUnit.workSpec.meta
+		if meta.HeartbeatExtension <= 0 {
+			continue
+		}
+		expiration := now.Add(meta.HeartbeatExtension)
+		if meta.MaxLeaseTotal > 0 {
+			limit := attempt.startTime.Add(meta.MaxLeaseTotal)
+			if expiration.After(limit) {
+				expiration = limit
+			}
+		}
+		if expiration.After(attempt.expirationTime) {
+			attempt.expirationTime = expiration
+		}
+	}
+}
+
 func (w *worker) RequestAttempts(req coordinate.AttemptRequest) ([]coordinate.Attempt, error) {
-	globalLock(w)
-	defer globalUnlock(w)
+	w.namespace.lock()
+	defer w.namespace.unlock()
 
+	if w.deleted {
+		return nil, coordinate.ErrGone
+	}
 	if req.NumberOfWorkUnits < 1 {
 		req.NumberOfWorkUnits = 1
 	}
 
+	now := w.Coordinate().clock.Now()
+	if !w.Coordinate().throttle.Allow(w.namespace.name, w.name, now) {
+		return nil, nil
+	}
+
 	// Get the metadata and choose a work spec
 	specs, metas := w.namespace.allMetas(true)
 	metas = coordinate.LimitMetasToNames(metas, req.WorkSpecs)
-	metas = coordinate.LimitMetasToRuntimes(metas, req.Runtimes)
-	now := w.Coordinate().clock.Now()
-	name, err := coordinate.SimplifiedScheduler(metas, now, req.AvailableGb)
-	if err == coordinate.ErrNoWork {
-		return nil, nil
-	} else if err != nil {
-		return nil, err
+	metas = limitMetasToRuntimes(specs, metas, req)
+	metas = w.Coordinate().gate.LimitMetas(w.namespace.name, metas)
+	var (
+		spec *workSpec
+		meta *coordinate.WorkSpecMeta
+	)
+	for {
+		name, err := coordinate.SimplifiedScheduler(metas, now, req.AvailableGb)
+		if err == coordinate.ErrNoWork {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		spec = specs[name]
+		meta = metas[name]
+		if canGetWorkFromSpec(spec, meta, req, now) {
+			break
+		}
+		// Everything here is below the requested priority
+		// or needs a different runtime, so pick some other
+		// work spec
+		delete(metas, name)
 	}
-	spec := specs[name]
-	meta := metas[name]
 
 	// Get more work units, but not more than either the number
 	// requested or the maximum allowed
@@ -167,7 +231,7 @@ func (w *worker) RequestAttempts(req coordinate.AttemptRequest) ([]coordinate.At
 	var attempts []*attempt
 	for len(attempts) == 0 {
 		for len(attempts) < count {
-			attempt := w.getWorkFromSpec(spec, meta)
+			attempt := w.getWorkFromSpec(spec, meta, req)
 			if attempt == nil {
 				break
 			}
@@ -182,10 +246,8 @@ func (w *worker) RequestAttempts(req coordinate.AttemptRequest) ([]coordinate.At
 			gotAttempts := attempts
 			attempts = nil
 			for _, a := range gotAttempts {
-				if len(a.workUnit.attempts) > meta.MaxRetries {
-					a.finish(coordinate.Failed, map[string]interface{}{
-						"traceback": "too many retries",
-					})
+				if a.workUnit.countedAttempts() > meta.MaxRetries {
+					a.finish(coordinate.Failed, coordinate.ExtractFailureData(spec.data, "too many retries"))
 				} else {
 					attempts = append(attempts, a)
 				}
@@ -200,16 +262,79 @@ func (w *worker) RequestAttempts(req coordinate.AttemptRequest) ([]coordinate.At
 	return result, nil
 }
 
+// limitMetasToRuntimes limits metas to the work specs that could have
+// work for req.Runtimes: those whose own runtime matches, and those
+// with available work units whose runtime overrides match.  Only work
+// specs with some runtime override are scanned.  Assumes the
+// namespace lock.
+func limitMetasToRuntimes(specs map[string]*workSpec, metas map[string]*coordinate.WorkSpecMeta, req coordinate.AttemptRequest) map[string]*coordinate.WorkSpecMeta {
+	if len(req.Runtimes) == 0 {
+		return metas
+	}
+	limited := coordinate.LimitMetasToRuntimes(metas, req.Runtimes)
+	for name, meta := range metas {
+		if _, present := limited[name]; present || specs[name].runtimeUnits == 0 {
+			continue
+		}
+		for _, unit := range specs[name].available {
+			if unit.meta.Runtime != "" && req.AllowsRuntime(unit.meta.Runtime) {
+				limited[name] = meta
+				break
+			}
+		}
+	}
+	return limited
+}
+
+// filtersUnits returns whether req excludes some work units
+// individually, so that getWorkFromSpec cannot just take the next
+// one.
+func filtersUnits(req coordinate.AttemptRequest) bool {
+	return req.MinPriority != nil || len(req.Runtimes) > 0
+}
+
+// unitMatches returns whether unit can be returned for req,
+// considering req.MinPriority and the unit's runtime.
+func unitMatches(unit *workUnit, req coordinate.AttemptRequest) bool {
+	return req.AllowsPriority(unit.meta.Priority) &&
+		req.AllowsRuntime(unit.meta.EffectiveRuntime(unit.workSpec.meta.Runtime))
+}
+
+// canGetWorkFromSpec returns whether getWorkFromSpec could find a
+// work unit for req, if spec has any work at all.  This only matters
+// if req has a MinPriority or Runtimes.  Assumes the namespace lock.
+func canGetWorkFromSpec(spec *workSpec, meta *coordinate.WorkSpecMeta, req coordinate.AttemptRequest, now time.Time) bool {
+	if !filtersUnits(req) {
+		return true
+	}
+	match := func(unit *workUnit) bool { return unitMatches(unit, req) }
+	if spec.available.PeekMatching(match) != nil {
+		return true
+	}
+	return len(spec.available) == 0 && req.AllowsPriority(0) &&
+		req.AllowsRuntime(meta.Runtime) && meta.CanStartContinuous(now)
+}
+
 // getWorkFromSpec forcibly retrieves a work unit from a work spec.
 // It could create a work unit if spec is a continuous spec with no
 // available units.  It ignores other constraints, such as whether the
-// work spec is paused.
-func (w *worker) getWorkFromSpec(spec *workSpec, meta *coordinate.WorkSpecMeta) *attempt {
+// work spec is paused, but does honor req.MinPriority and the
+// runtimes of individual work units.
+func (w *worker) getWorkFromSpec(spec *workSpec, meta *coordinate.WorkSpecMeta, req coordinate.AttemptRequest) *attempt {
 	var unit *workUnit
 	now := w.Coordinate().clock.Now()
-	if len(spec.available) != 0 {
+	if filtersUnits(req) {
+		unit = spec.available.NextMatching(func(unit *workUnit) bool {
+			return unitMatches(unit, req)
+		})
+	} else if len(spec.available) != 0 {
 		unit = spec.available.Next()
-	} else if meta.CanStartContinuous(now) {
+	}
+	if unit == nil {
+		if len(spec.available) != 0 || !req.AllowsPriority(0) ||
+			!req.AllowsRuntime(meta.Runtime) || !meta.CanStartContinuous(now) {
+			return nil
+		}
 		// Make a brand new work unit.  Its key is the string
 		// form of a time_t.
 		seconds := now.Unix()
@@ -219,28 +344,39 @@ func (w *worker) getWorkFromSpec(spec *workSpec, meta *coordinate.WorkSpecMeta)
 		var exists bool
 		unit, exists = spec.workUnits[name]
 		if !exists {
+			// spec.data passed ExtractWorkSpecMeta() in
+			// setData(), so this shouldn't fail
+			data, err := coordinate.ExtractContinuousData(spec.data)
+			if err != nil {
+				return nil
+			}
 			unit = &workUnit{
-				name:     name,
-				data:     map[string]interface{}{},
-				workSpec: spec,
+				name:      name,
+				data:      data,
+				createdAt: now,
+				workSpec:  spec,
 			}
 			spec.workUnits[name] = unit
+			continuousUnits.WithLabelValues(spec.namespace.name, spec.name).Inc()
 		}
 		spec.meta.NextContinuous = now.Add(meta.Interval)
-	} else {
-		return nil
 	}
-	return w.makeAttempt(unit, time.Duration(0))
+	return w.makeAttempt(unit, req.Lifetime)
 }
 
 func (w *worker) MakeAttempt(cUnit coordinate.WorkUnit, duration time.Duration) (coordinate.Attempt, error) {
-	globalLock(w)
-	defer globalUnlock(w)
+	w.namespace.lock()
+	defer w.namespace.unlock()
 	unit, ok := cUnit.(*workUnit)
 	if !ok {
 		return nil, coordinate.ErrWrongBackend
 	}
-	if unit.deleted || unit.workSpec.deleted || unit.workSpec.namespace.deleted {
+	// The attempt would be reachable from both namespaces, but
+	// only protected by one lock
+	if unit.workSpec.namespace != w.namespace {
+		return nil, coordinate.ErrWrongNamespace
+	}
+	if w.deleted || unit.deleted || unit.workSpec.deleted || unit.workSpec.namespace.deleted {
 		return nil, coordinate.ErrGone
 	}
 	attempt := w.makeAttempt(unit, duration)
@@ -249,14 +385,23 @@ func (w *worker) MakeAttempt(cUnit coordinate.WorkUnit, duration time.Duration)
 
 // makeAttempt creates an attempt and makes it the active attempt.
 // This is the implementation for MakeAttempt(), and also is called at
-// the bottom of the stack for RequestAttempts().  Assumes the global
-// lock and never fails.
+// the bottom of the stack for RequestAttempts().  If duration is
+// zero, uses the work spec's default lease.  The lease is no longer
+// than the work spec's MaxLeaseTotal, if it has one.  Assumes the
+// namespace lock and never fails.
 func (w *worker) makeAttempt(workUnit *workUnit, duration time.Duration) *attempt {
 	start := w.Coordinate().clock.Now()
+	if duration == time.Duration(0) {
+		duration = workUnit.workSpec.meta.DefaultLease
+	}
 	if duration == time.Duration(0) {
 		duration = time.Duration(15) * time.Minute
 	}
+	if maxLease := workUnit.workSpec.meta.MaxLeaseTotal; maxLease > 0 && duration > maxLease {
+		duration = maxLease
+	}
 	attempt := &attempt{
+		id:             uuid.NewV4().String(),
 		workUnit:       workUnit,
 		worker:         w,
 		status:         coordinate.Pending,
@@ -266,13 +411,16 @@ func (w *worker) makeAttempt(workUnit *workUnit, duration time.Duration) *attemp
 	}
 	workUnit.activeAttempt = attempt
 	workUnit.attempts = append(workUnit.attempts, attempt)
+	// RequestAttempts has already taken the unit off the
+	// available list, but MakeAttempt has not
+	workUnit.workSpec.available.Remove(workUnit)
 	w.addAttempt(attempt)
 	return attempt
 }
 
 func (w *worker) ActiveAttempts() ([]coordinate.Attempt, error) {
-	globalLock(w)
-	defer globalUnlock(w)
+	w.namespace.lock()
+	defer w.namespace.unlock()
 
 	result := make([]coordinate.Attempt, len(w.activeAttempts))
 	for i, attempt := range w.activeAttempts {
@@ -282,8 +430,8 @@ func (w *worker) ActiveAttempts() ([]coordinate.Attempt, error) {
 }
 
 func (w *worker) AllAttempts() ([]coordinate.Attempt, error) {
-	globalLock(w)
-	defer globalUnlock(w)
+	w.namespace.lock()
+	defer w.namespace.unlock()
 
 	result := make([]coordinate.Attempt, len(w.attempts))
 	for i, attempt := range w.attempts {
@@ -292,6 +440,26 @@ func (w *worker) AllAttempts() ([]coordinate.Attempt, error) {
 	return result, nil
 }
 
+func (w *worker) AttemptsInWindow(start, end time.Time) ([]coordinate.Attempt, error) {
+	w.namespace.lock()
+	defer w.namespace.unlock()
+
+	result := []coordinate.Attempt{}
+	for _, attempt := range w.attempts {
+		if !start.IsZero() && attempt.startTime.Before(start) {
+			continue
+		}
+		if !end.IsZero() && !attempt.startTime.Before(end) {
+			continue
+		}
+		result = append(result, attempt)
+	}
+	return result, nil
+}
+
+// ChildAttempts returns the active attempts of this worker's
+// children.  Children may be in other namespaces, so this takes the
+// global lock.
 func (w *worker) ChildAttempts() (result []coordinate.Attempt, err error) {
 	globalLock(w)
 	defer globalUnlock(w)
@@ -304,8 +472,94 @@ func (w *worker) ChildAttempts() (result []coordinate.Attempt, err error) {
 	return
 }
 
+func (w *worker) FinishAttempts(attempts []coordinate.Attempt, data []map[string]interface{}) error {
+	return w.completeAttempts(attempts, data, coordinate.Finished, 0)
+}
+
+func (w *worker) FailAttempts(attempts []coordinate.Attempt, data []map[string]interface{}) error {
+	return w.completeAttempts(attempts, data, coordinate.Failed, 0)
+}
+
+func (w *worker) RetryAttempts(attempts []coordinate.Attempt, data []map[string]interface{}, delay time.Duration) error {
+	return w.completeAttempts(attempts, data, coordinate.Retryable, delay)
+}
+
+func (w *worker) CompleteAttempts(outcomes []coordinate.AttemptOutcome) ([]error, error) {
+	w.namespace.lock()
+	defer w.namespace.unlock()
+
+	errs := make([]error, len(outcomes))
+	for i, outcome := range outcomes {
+		errs[i] = w.completeOutcome(outcome)
+	}
+	return errs, nil
+}
+
+// completeOutcome completes a single attempt for CompleteAttempts().
+// Assumes the namespace lock.
+func (w *worker) completeOutcome(outcome coordinate.AttemptOutcome) error {
+	if outcome.Attempt == nil {
+		return coordinate.ErrBadOutcome
+	}
+	attempt, ok := outcome.Attempt.(*attempt)
+	if !ok {
+		return coordinate.ErrWrongBackend
+	}
+	if attempt.isGone() {
+		return coordinate.ErrGone
+	}
+	if attempt.worker != w {
+		return coordinate.ErrWrongWorker
+	}
+	switch outcome.Status {
+	case coordinate.Finished, coordinate.Failed, coordinate.Retryable:
+		return attempt.complete(outcome.Status, outcome.Data, outcome.Delay)
+	}
+	return coordinate.ErrBadOutcome
+}
+
+// completeAttempts is the implementation of the batch completion
+// calls.  It checks every attempt before changing any of them, and
+// then completes each in turn, holding the namespace lock throughout.
+func (w *worker) completeAttempts(cAttempts []coordinate.Attempt, data []map[string]interface{}, status coordinate.AttemptStatus, delay time.Duration) error {
+	if data != nil && len(data) != len(cAttempts) {
+		return coordinate.ErrBatchLength
+	}
+	w.namespace.lock()
+	defer w.namespace.unlock()
+
+	attempts := make([]*attempt, len(cAttempts))
+	for i, cAttempt := range cAttempts {
+		attempt, ok := cAttempt.(*attempt)
+		if !ok {
+			return coordinate.ErrWrongBackend
+		}
+		if attempt.isGone() {
+			return coordinate.ErrGone
+		}
+		if attempt.worker != w {
+			return coordinate.ErrWrongWorker
+		}
+		if err := attempt.canComplete(status); err != nil {
+			return err
+		}
+		attempts[i] = attempt
+	}
+	var err error
+	for i, attempt := range attempts {
+		var attemptData map[string]interface{}
+		if data != nil {
+			attemptData = data[i]
+		}
+		if thisErr := attempt.complete(status, attemptData, delay); thisErr != nil {
+			err = thisErr
+		}
+	}
+	return err
+}
+
 // addAttempt adds an attempt to both the active and historic attempts
-// list.  Does not check for duplicates.  Assumes the global lock.
+// list.  Does not check for duplicates.  Assumes the namespace lock.
 // Never fails.
 func (w *worker) addAttempt(attempt *attempt) {
 	w.attempts = append(w.attempts, attempt)
@@ -333,17 +587,53 @@ func removeAttemptFromList(attempt *attempt, list []*attempt) []*attempt {
 }
 
 // completeAttempt removes an attempt from the active attempts list,
-// if it is there.  Assumes the global lock.  Never fails.
+// if it is there.  Assumes the namespace lock.  Never fails.
 func (w *worker) completeAttempt(attempt *attempt) {
 	w.activeAttempts = removeAttemptFromList(attempt, w.activeAttempts)
 }
 
 // removeAttempt removes an attempt from the history attempts list,
-// if it is there.  Assumes the global lock.  Never fails.
+// if it is there.  Assumes the namespace lock.  Never fails.
 func (w *worker) removeAttempt(attempt *attempt) {
 	w.attempts = removeAttemptFromList(attempt, w.attempts)
 }
 
+// holdsCompletedUnit returns whether one of this worker's attempts
+// is the active attempt of a finished or failed work unit.  Assumes
+// the namespace lock.
+func (w *worker) holdsCompletedUnit() bool {
+	for _, attempt := range w.attempts {
+		if attempt.workUnit.activeAttempt != attempt {
+			continue
+		}
+		if attempt.status == coordinate.Finished || attempt.status == coordinate.Failed {
+			return true
+		}
+	}
+	return false
+}
+
+// delete releases this worker's pending attempts and removes it
+// from its namespace and from the worker hierarchy.  The caller is
+// responsible for removing its attempts from their work units.
+// Assumes the global lock.
+func (w *worker) delete() {
+	for _, attempt := range append([]*attempt(nil), w.activeAttempts...) {
+		if attempt.status == coordinate.Pending {
+			attempt.released = true
+			attempt.finish(coordinate.Expired, nil)
+		}
+	}
+	for _, child := range w.children {
+		child.parent = nil
+	}
+	if w.parent != nil {
+		delete(w.parent.children, w.name)
+	}
+	delete(w.namespace.workers, w.name)
+	w.deleted = true
+}
+
 // memory.coordinable interface:
 
 func (w *worker) Coordinate() *memCoordinate {
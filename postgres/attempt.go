@@ -8,17 +8,48 @@ import (
 	"fmt"
 	"github.com/diffeo/go-coordinate/coordinate"
 	"github.com/lib/pq"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
 type attempt struct {
-	unit   *workUnit
-	worker *worker
-	id     int
+	unit     *workUnit
+	worker   *worker
+	id       int
+	archived bool
+}
+
+// table returns the name of the table holding this attempt.
+func (a *attempt) table() string {
+	if a.archived {
+		return attemptArchiveTable
+	}
+	return attemptTable
+}
+
+// selectColumn reads one column of this attempt into dest.  A handle
+// made before its attempt was archived still looks in the attempt
+// table, so if the attempt is not there, this falls back to the
+// archive table.  Returns ErrGone if the attempt is in neither.
+func (a *attempt) selectColumn(tx *sql.Tx, column string, dest interface{}) error {
+	err := tx.QueryRow("SELECT "+column+" FROM "+a.table()+" WHERE id=$1", a.id).Scan(dest)
+	if err == sql.ErrNoRows && !a.archived {
+		err = tx.QueryRow("SELECT "+column+" FROM "+attemptArchiveTable+" WHERE id=$1", a.id).Scan(dest)
+	}
+	if err == sql.ErrNoRows {
+		err = coordinate.ErrGone
+	}
+	return err
 }
 
 // Attempt interface
 
+func (a *attempt) ID() string {
+	return strconv.Itoa(a.id)
+}
+
 func (a *attempt) WorkUnit() coordinate.WorkUnit {
 	return a.unit
 }
@@ -28,15 +59,12 @@ func (a *attempt) Worker() coordinate.Worker {
 }
 
 func (a *attempt) Status() (coordinate.AttemptStatus, error) {
-	a.Coordinate().Expiry.Do(a)
+	a.Coordinate().Expiry.DoForSpec(a.unit.spec)
 
 	var status string
 	err := withTx(a, true, func(tx *sql.Tx) error {
-		return tx.QueryRow("SELECT status FROM attempt WHERE id=$1", a.id).Scan(&status)
+		return a.selectColumn(tx, "status", &status)
 	})
-	if err == sql.ErrNoRows {
-		err = coordinate.ErrGone
-	}
 	if err != nil {
 		return 0, err
 	}
@@ -59,18 +87,14 @@ func (a *attempt) Data() (map[string]interface{}, error) {
 	var result map[string]interface{}
 	err := withTx(a, true, func(tx *sql.Tx) error {
 		var dataBytes []byte
-		row := tx.QueryRow("SELECT data FROM attempt WHERE id=$1", a.id)
-		err := row.Scan(&dataBytes)
-		if err == sql.ErrNoRows {
-			err = coordinate.ErrGone
-		}
+		err := a.selectColumn(tx, "data", &dataBytes)
 		if err != nil {
 			return err
 		}
 		if dataBytes == nil {
 			// null data in the attempt; get the unmodified
 			// work unit data
-			row = tx.QueryRow("SELECT data FROM work_unit WHERE id=$1", a.unit.id)
+			row := tx.QueryRow("SELECT data FROM work_unit WHERE id=$1", a.unit.id)
 			err = row.Scan(&dataBytes)
 			if err == sql.ErrNoRows {
 				err = coordinate.ErrGone
@@ -90,24 +114,18 @@ func (a *attempt) Data() (map[string]interface{}, error) {
 
 func (a *attempt) StartTime() (result time.Time, err error) {
 	err = withTx(a, true, func(tx *sql.Tx) error {
-		return tx.QueryRow("SELECT start_time FROM attempt WHERE id=$1", a.id).Scan(&result)
+		return a.selectColumn(tx, "start_time", &result)
 	})
-	if err == sql.ErrNoRows {
-		err = coordinate.ErrGone
-	}
 	return
 }
 
 func (a *attempt) EndTime() (time.Time, error) {
-	a.Coordinate().Expiry.Do(a)
+	a.Coordinate().Expiry.DoForSpec(a.unit.spec)
 
 	var nt pq.NullTime
 	err := withTx(a, true, func(tx *sql.Tx) error {
-		return tx.QueryRow("SELECT end_time FROM attempt WHERE id=$1", a.id).Scan(&nt)
+		return a.selectColumn(tx, "end_time", &nt)
 	})
-	if err == sql.ErrNoRows {
-		err = coordinate.ErrGone
-	}
 	if err != nil {
 		return time.Time{}, err
 	}
@@ -116,55 +134,282 @@ func (a *attempt) EndTime() (time.Time, error) {
 }
 
 func (a *attempt) ExpirationTime() (result time.Time, err error) {
-	a.Coordinate().Expiry.Do(a)
+	a.Coordinate().Expiry.DoForSpec(a.unit.spec)
 
 	err = withTx(a, true, func(tx *sql.Tx) error {
-		return tx.QueryRow("SELECT expiration_time FROM attempt WHERE id=$1", a.id).Scan(&result)
+		return a.selectColumn(tx, "expiration_time", &result)
 	})
-	if err == sql.ErrNoRows {
-		err = coordinate.ErrGone
-	}
 	return
 }
 
 func (a *attempt) Renew(extendDuration time.Duration, data map[string]interface{}) error {
-	// TODO(dmaze): check valid state and active status
+	if a.archived {
+		return coordinate.ErrNotPending
+	}
 	now := a.Coordinate().clock.Now()
-	params := queryParams{}
-	fields := fieldList{}
-	fields.Add(&params, "expiration_time", now.Add(extendDuration))
-	if data != nil {
-		dataBytes, err := mapToBytes(data)
+	expiration := now.Add(extendDuration)
+	var exceeded, lost bool
+	err := withTx(a, false, func(tx *sql.Tx) error {
+		exceeded = false
+		lost = false
+
+		// Only an attempt that has not been completed can be
+		// renewed, and only if it is still the active attempt
+		current, active, err := a.currentStatus(tx)
 		if err != nil {
 			return err
 		}
-		fields.Add(&params, "data", dataBytes)
+		if current != "pending" && current != "expired" {
+			return coordinate.ErrNotPending
+		}
+		if !active {
+			lost = true
+			if current == "pending" {
+				return a.complete(tx, data, "expired")
+			}
+			return nil
+		}
+
+		// Find out if the work spec limits the total lease
+		var (
+			startTime     time.Time
+			maxLeaseTotal string
+			maxLease      time.Duration
+		)
+		params := queryParams{}
+		query := buildSelect([]string{
+			attemptStartTime,
+			workSpecMaxLeaseTotal,
+		}, []string{
+			attemptTable,
+			workSpecTable,
+		}, []string{
+			isAttempt(&params, a.id),
+			attemptInThisSpec,
+		})
+		err = tx.QueryRow(query, params...).Scan(&startTime, &maxLeaseTotal)
+		if err == sql.ErrNoRows {
+			return coordinate.ErrGone
+		}
+		if err == nil {
+			maxLease, err = sqlToDuration(maxLeaseTotal)
+		}
+		if err == nil {
+			err = a.recordHistory(tx, now, data)
+		}
+		if err != nil {
+			return err
+		}
+		if maxLease > 0 {
+			limit := startTime.Add(maxLease)
+			if !now.Before(limit) {
+				// Expire the attempt, but report the
+				// error only after this commits
+				exceeded = true
+				return a.complete(tx, data, "expired")
+			}
+			if expiration.After(limit) {
+				expiration = limit
+			}
+		}
+
+		params = queryParams{}
+		fields := fieldList{}
+		fields.Add(&params, "expiration_time", expiration)
+		fields.AddDirect("status", "'pending'")
+		if data != nil {
+			dataBytes, err := mapToBytes(data)
+			if err != nil {
+				return err
+			}
+			fields.Add(&params, "data", dataBytes)
+		}
+		query = buildUpdate(attemptTable, fields.UpdateChanges(), []string{
+			isAttempt(&params, a.id),
+		})
+		_, err = tx.Exec(query, params...)
+		return err
+	})
+	if err == nil && exceeded {
+		err = coordinate.ErrLeaseTotalExceeded
 	}
-	query := buildUpdate(attemptTable, fields.UpdateChanges(), []string{
-		isAttempt(&params, a.id),
+	if err == nil && lost {
+		err = coordinate.ErrLostLease
+	}
+	return err
+}
+
+// recordHistory adds data to this attempt's data history, if the
+// coordinate is keeping one, and deletes the oldest snapshots beyond
+// its limit.
+func (a *attempt) recordHistory(tx *sql.Tx, now time.Time, data map[string]interface{}) error {
+	limit := atomic.LoadInt64(&a.Coordinate().dataHistory)
+	if data == nil || limit <= 0 {
+		return nil
+	}
+	dataBytes, err := mapToBytes(data)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO "+attemptDataHistoryTable+"(attempt_id, work_unit_id, time, data) SELECT id, work_unit_id, $2, $3 FROM "+attemptTable+" WHERE id=$1", a.id, now, dataBytes)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM "+attemptDataHistoryTable+" WHERE attempt_id=$1 AND id NOT IN (SELECT id FROM "+attemptDataHistoryTable+" WHERE attempt_id=$1 ORDER BY id DESC LIMIT $2)", a.id, limit)
+	return err
+}
+
+func (a *attempt) DataHistory() ([]coordinate.DataSnapshot, error) {
+	result := []coordinate.DataSnapshot{}
+	query := "SELECT time, data FROM " + attemptDataHistoryTable + " WHERE attempt_id=$1 ORDER BY id ASC"
+	err := queryAndScan(a, query, queryParams{a.id}, func(rows *sql.Rows) error {
+		var (
+			snapshot  coordinate.DataSnapshot
+			dataBytes []byte
+		)
+		err := rows.Scan(&snapshot.Time, &dataBytes)
+		if err == nil {
+			snapshot.Data, err = bytesToMap(dataBytes)
+		}
+		if err == nil {
+			result = append(result, snapshot)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (a *attempt) AppendLog(line string) error {
+	return withTx(a, false, func(tx *sql.Tx) error {
+		var status string
+		err := a.selectColumn(tx, "status", &status)
+		if err != nil {
+			return err
+		}
+		if status != "pending" {
+			return coordinate.ErrNotPending
+		}
+		_, err = tx.Exec("INSERT INTO "+attemptLogTable+"(attempt_id, work_unit_id, line) SELECT id, work_unit_id, $2 FROM "+attemptTable+" WHERE id=$1", a.id, line)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM "+attemptLogTable+" WHERE attempt_id=$1 AND id NOT IN (SELECT id FROM "+attemptLogTable+" WHERE attempt_id=$1 ORDER BY id DESC LIMIT $2)", a.id, coordinate.MaxAttemptLogLines)
+		return err
 	})
-	return execInTx(a, query, params, true)
+}
+
+func (a *attempt) Logs() ([]string, error) {
+	result := []string{}
+	query := "SELECT line FROM " + attemptLogTable + " WHERE attempt_id=$1 ORDER BY id ASC"
+	err := queryAndScan(a, query, queryParams{a.id}, func(rows *sql.Rows) error {
+		var line string
+		err := rows.Scan(&line)
+		if err == nil {
+			result = append(result, line)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
 func (a *attempt) Expire(data map[string]interface{}) error {
+	if a.archived {
+		return coordinate.ErrNotPending
+	}
 	return withTx(a, false, func(tx *sql.Tx) error {
+		// Expiring an expired attempt does nothing
+		current, _, err := a.currentStatus(tx)
+		if err != nil || current == "expired" {
+			return err
+		}
 		return a.complete(tx, data, "expired")
 	})
 }
 
+func (a *attempt) Release(data map[string]interface{}) error {
+	if a.archived {
+		return coordinate.ErrNotPending
+	}
+	return withTx(a, false, func(tx *sql.Tx) error {
+		current, _, err := a.currentStatus(tx)
+		if err != nil {
+			return err
+		}
+		if current != "pending" {
+			return coordinate.ErrNotPending
+		}
+
+		// Mark the attempt as released
+		params := queryParams{}
+		fields := fieldList{}
+		fields.AddDirect("active", "FALSE")
+		fields.AddDirect("released", "TRUE")
+		fields.Add(&params, "status", "expired")
+		fields.Add(&params, "end_time", a.Coordinate().clock.Now())
+		if data != nil {
+			dataBytes, err := mapToBytes(data)
+			if err != nil {
+				return err
+			}
+			fields.Add(&params, "data", dataBytes)
+		}
+		query := buildUpdate(attemptTable, fields.UpdateChanges(), []string{
+			isAttempt(&params, a.id),
+		})
+		_, err = tx.Exec(query, params...)
+		if err != nil {
+			return err
+		}
+
+		// Make the work unit available again, without counting
+		// this as a retry
+		params = queryParams{}
+		query = buildUpdate(workUnitTable, []string{
+			"active_attempt_id=NULL",
+		}, []string{
+			workUnitHasAttempt(&params, a.id),
+		})
+		_, err = releaseWorkUnits(tx, query, params)
+		if err == nil {
+			err = a.unit.archiveAttempts(tx)
+		}
+		return err
+	})
+}
+
 func (a *attempt) Finish(data map[string]interface{}) error {
 	// Mark the attempt finished, then create any new work units
 	// declared in an "output" key.
 	//
 	// These do not have to happen atomically.  So first, just mark
 	// the attempt as done.
-	err := withTx(a, false, func(tx *sql.Tx) error {
-		return a.complete(tx, data, "finished")
+	var lost bool
+	err := withTx(a, false, func(tx *sql.Tx) (err error) {
+		lost, err = a.lostLease(tx, data)
+		if err == nil && !lost {
+			err = a.complete(tx, data, "finished")
+		}
+		return
 	})
+	if err == nil && lost {
+		err = coordinate.ErrLostLease
+	}
 	if err != nil {
 		return err
 	}
+	return a.addOutput(data)
+}
 
+// addOutput creates new work units from an "output" key in the data
+// of a just-finished attempt, if it is still the active attempt and
+// its work spec names a next work spec.
+func (a *attempt) addOutput(data map[string]interface{}) error {
 	// A fast path: if we have a data dictionary and there is
 	// no "output", stop.
 	if data != nil {
@@ -205,7 +450,7 @@ func (a *attempt) Finish(data map[string]interface{}) error {
 	}
 	query := buildSelect(outputs, tables, conditions)
 	spec := workSpec{namespace: a.unit.spec.namespace}
-	err = withTx(a, true, func(tx *sql.Tx) (err error) {
+	err := withTx(a, true, func(tx *sql.Tx) (err error) {
 		row := tx.QueryRow(query, params...)
 		if data == nil {
 			var unitData, attemptData []byte
@@ -257,23 +502,98 @@ func (a *attempt) Finish(data map[string]interface{}) error {
 	return nil
 }
 
-func (a *attempt) Fail(data map[string]interface{}) error {
+func (a *attempt) PrepareFinish(data map[string]interface{}) error {
+	if a.archived {
+		return coordinate.ErrNotPending
+	}
 	return withTx(a, false, func(tx *sql.Tx) error {
-		return a.complete(tx, data, "failed")
+		// As in canComplete(), an expired attempt that is
+		// still active can be finished, and so prepared
+		current, active, err := a.currentStatus(tx)
+		if err != nil {
+			return err
+		}
+		if current != "pending" && !(current == "expired" && active) {
+			return coordinate.ErrNotPending
+		}
+
+		params := queryParams{}
+		fields := fieldList{}
+		fields.AddDirect("finish_prepared", "TRUE")
+		if data != nil {
+			dataBytes, err := mapToBytes(data)
+			if err != nil {
+				return err
+			}
+			fields.Add(&params, "data", dataBytes)
+		}
+		query := buildUpdate(attemptTable, fields.UpdateChanges(), []string{
+			isAttempt(&params, a.id),
+		})
+		_, err = tx.Exec(query, params...)
+		return err
+	})
+}
+
+func (a *attempt) FinishPrepared() (prepared bool, err error) {
+	err = withTx(a, true, func(tx *sql.Tx) error {
+		return a.selectColumn(tx, "finish_prepared", &prepared)
+	})
+	return
+}
+
+func (a *attempt) Fail(data map[string]interface{}) error {
+	var lost bool
+	err := withTx(a, false, func(tx *sql.Tx) (err error) {
+		lost, err = a.lostLease(tx, data)
+		if err == nil && !lost {
+			err = a.complete(tx, data, "failed")
+		}
+		return
 	})
+	if err == nil && lost {
+		err = coordinate.ErrLostLease
+	}
+	return err
 }
 
 func (a *attempt) Retry(data map[string]interface{}, delay time.Duration) error {
-	return withTx(a, false, func(tx *sql.Tx) error {
-		err := a.complete(tx, data, "retryable")
+	var lost bool
+	err := withTx(a, false, func(tx *sql.Tx) error {
+		var (
+			active bool
+			err    error
+		)
+		lost, err = a.lostLease(tx, data)
+		if err != nil || lost {
+			return err
+		}
+		active, err = a.isActive(tx)
+		if err == nil && active {
+			// Fail it now if it is out of retries, as
+			// RequestAttempts() would fail the next attempt
+			var failureData map[string]interface{}
+			failureData, err = a.retryFailureData(tx)
+			if err == nil && failureData != nil {
+				return a.complete(tx, failureData, "failed")
+			}
+		}
 		if err == nil {
-			// Also update the "not before" time on the work unit
+			err = a.complete(tx, data, "retryable")
+		}
+		if err == nil && active {
+			// Also update the "not before" time on the work
+			// unit; an explicit delay overrides the work
+			// spec's retry schedule, which complete() applied
 			then := a.Coordinate().clock.Now().Add(delay)
 			params := queryParams{}
-			fields := fieldList{}
-			fields.Add(&params, "not_before", then)
-			query := buildUpdate(workUnitTable,
-				fields.UpdateChanges(),
+			var changes []string
+			if delay > 0 {
+				changes = []string{"not_before=" + params.Param(then)}
+			} else {
+				changes = []string{"not_before=CASE WHEN " + workUnitHasRetryDelays + " THEN not_before ELSE " + params.Param(then) + " END"}
+			}
+			query := buildUpdate(workUnitTable, changes,
 				[]string{
 					isWorkUnit(&params, a.unit.id),
 				})
@@ -281,11 +601,160 @@ func (a *attempt) Retry(data map[string]interface{}, delay time.Duration) error
 		}
 		return err
 	})
+	if err == nil && lost {
+		err = coordinate.ErrLostLease
+	}
+	return err
+}
+
+// retryFailureData returns the data to fail this attempt with instead
+// of retrying it, if its work unit already has as many attempts as its
+// work spec's max_retries allows, or nil if it can be retried.
+func (a *attempt) retryFailureData(tx *sql.Tx) (map[string]interface{}, error) {
+	failures, err := retryFailures(tx, []*attempt{a})
+	return failures[a.id], err
+}
+
+// retryFailures finds which of attempts should fail instead of being
+// retried, as retryFailureData does for a single attempt, in one
+// query.  It returns a map from attempt ID to failure data, with no
+// entries for attempts that can be retried.
+func retryFailures(tx *sql.Tx, attempts []*attempt) (map[int]map[string]interface{}, error) {
+	failures := make(map[int]map[string]interface{})
+	if len(attempts) == 0 {
+		return failures, nil
+	}
+	params := queryParams{}
+	ids := make([]string, len(attempts))
+	for i, a := range attempts {
+		ids[i] = params.Param(a.id)
+	}
+	var counts []string
+	for _, table := range []string{attemptTable, attemptArchiveTable} {
+		counts = append(counts, "("+buildSelect(
+			[]string{"COUNT(*)"},
+			[]string{table + " AS counted"},
+			[]string{
+				"counted.work_unit_id=" + attemptWorkUnitID,
+				"NOT counted.released",
+			},
+		)+")")
+	}
+	query := buildSelect([]string{
+		attemptID,
+		workSpecMaxRetries,
+		workSpecData,
+		strings.Join(counts, " + "),
+	}, []string{
+		attemptTable,
+		workSpecTable,
+	}, []string{
+		attemptID + " IN (" + strings.Join(ids, ", ") + ")",
+		attemptInThisSpec,
+		workSpecMaxRetries + ">0",
+	})
+	rows, err := tx.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	err = scanRows(rows, func() error {
+		var (
+			id, maxRetries, count int
+			specBytes             []byte
+		)
+		err := rows.Scan(&id, &maxRetries, &specBytes, &count)
+		if err != nil || count < maxRetries {
+			return err
+		}
+		specData, err := bytesToMap(specBytes)
+		if err == nil {
+			failures[id] = coordinate.ExtractFailureData(specData, "too many retries")
+		}
+		return err
+	})
+	return failures, err
+}
+
+// isActive returns whether this attempt is its work unit's active
+// attempt.
+func (a *attempt) isActive(tx *sql.Tx) (bool, error) {
+	params := queryParams{}
+	query := buildSelect([]string{"1"}, []string{workUnitTable},
+		[]string{workUnitHasAttempt(&params, a.id)})
+	var one int
+	err := tx.QueryRow(query, params...).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// lostLease checks whether a pending attempt being completed has
+// lost its work unit to another attempt, and the coordinate refuses
+// to complete such attempts.  If so, it expires the attempt and
+// returns true.
+func (a *attempt) lostLease(tx *sql.Tx, data map[string]interface{}) (bool, error) {
+	if !a.Coordinate().strictCompletion() {
+		return false, nil
+	}
+	active, err := a.isActive(tx)
+	if err != nil || active {
+		return false, err
+	}
+	var status string
+	err = a.selectColumn(tx, "status", &status)
+	if err != nil || status != "pending" {
+		return false, err
+	}
+	return true, a.complete(tx, data, "expired")
+}
+
+// currentStatus returns this attempt's status, and whether it is its
+// work unit's active attempt.
+func (a *attempt) currentStatus(tx *sql.Tx) (status string, active bool, err error) {
+	params := queryParams{}
+	query := buildSelect([]string{
+		attemptStatus,
+		workUnitID + " IS NOT NULL",
+	}, []string{
+		attemptTable + " LEFT OUTER JOIN " + workUnitTable + " ON " + attemptIsTheActive,
+	}, []string{
+		isAttempt(&params, a.id),
+	})
+	err = tx.QueryRow(query, params...).Scan(&status, &active)
+	if err == sql.ErrNoRows {
+		// An archived attempt is never active
+		active = false
+		err = a.selectColumn(tx, "status", &status)
+	}
+	return
+}
+
+// canComplete returns ErrNotPending if this attempt cannot move to
+// status, or nil if it can.  As in the batch completions, the
+// attempt must be pending, or expired but still the active attempt;
+// but a failed attempt can still be finished.
+func (a *attempt) canComplete(tx *sql.Tx, status string) error {
+	if a.archived {
+		return coordinate.ErrNotPending
+	}
+	current, active, err := a.currentStatus(tx)
+	if err != nil {
+		return err
+	}
+	if current == "pending" ||
+		(current == "expired" && active) ||
+		(status == "finished" && current == "failed") {
+		return nil
+	}
+	return coordinate.ErrNotPending
 }
 
 func (a *attempt) complete(tx *sql.Tx, data map[string]interface{}, status string) error {
-	// TODO(dmaze): check valid state transitions
-	// TODO(dmaze): check if attempt is active if required
+	err := a.canComplete(tx, status)
+	if err != nil {
+		return err
+	}
 
 	// Mark the attempt as completed
 	params := queryParams{}
@@ -298,41 +767,183 @@ func (a *attempt) complete(tx *sql.Tx, data map[string]interface{}, status strin
 		if err != nil {
 			return err
 		}
-		fields.Add(&params, "data", dataBytes)
-	}
-	query := buildUpdate(attemptTable, fields.UpdateChanges(), []string{
-		isAttempt(&params, a.id),
+		fields.Add(&params, "data", dataBytes)
+	}
+	query := buildUpdate(attemptTable, fields.UpdateChanges(), []string{
+		isAttempt(&params, a.id),
+	})
+	result, err := tx.Exec(query, params...)
+	if err != nil {
+		return err
+	}
+	count, err := result.RowsAffected()
+	if err == nil && count == 0 {
+		err = coordinate.ErrGone
+	}
+	if err != nil {
+		return err
+	}
+
+	// If it was the active attempt, and this is a non-terminal
+	// resolution, also reset that
+	if status == "retryable" || status == "expired" {
+		params = queryParams{}
+		changes := append([]string{"active_attempt_id=NULL"},
+			workUnitRetried(&params, a.Coordinate().clock.Now())...)
+		query = buildUpdate(workUnitTable, changes, []string{
+			workUnitHasAttempt(&params, a.id),
+		})
+		_, err = releaseWorkUnits(tx, query, params)
+	}
+
+	if err == nil {
+		err = a.unit.archiveAttempts(tx)
+	}
+	return err
+}
+
+// archiveColumns lists the columns copied from the attempt table to
+// the attempt archive table.
+const archiveColumns = "id, work_unit_id, work_spec_id, worker_id, status, data, start_time, end_time, expiration_time, finish_prepared, released"
+
+// archiveAttempts moves old completed attempts for this work unit
+// from the attempt table to the archive table, following the
+// coordinate's archive settings.  Their data history and logs stay
+// where they are.
+func (unit *workUnit) archiveAttempts(tx *sql.Tx) error {
+	keep, age := unit.Coordinate().attemptArchive()
+	if keep <= 0 && age <= 0 {
+		return nil
+	}
+	params := queryParams{}
+	conditions := []string{
+		attemptForUnit(&params, unit.id),
+		"NOT " + attemptIsPending,
+		"NOT EXISTS (SELECT 1 FROM " + workUnitTable + " WHERE " + attemptIsTheActive + ")",
+	}
+	var limits []string
+	if keep > 0 {
+		recent := "SELECT id FROM " + attemptTable +
+			" WHERE work_unit_id=" + params.Param(unit.id) +
+			" ORDER BY start_time DESC, id DESC LIMIT " + params.Param(keep)
+		limits = append(limits, attemptID+" NOT IN ("+recent+")")
+	}
+	if age > 0 {
+		cutoff := unit.Coordinate().clock.Now().Add(-age)
+		limits = append(limits, attemptEndTime+"<"+params.Param(cutoff))
+	}
+	conditions = append(conditions, "("+strings.Join(limits, " OR ")+")")
+	query := "WITH moved AS (DELETE FROM " + attemptTable +
+		" WHERE " + strings.Join(conditions, " AND ") +
+		" RETURNING " + archiveColumns + ") " +
+		"INSERT INTO " + attemptArchiveTable + "(" + archiveColumns + ") " +
+		"SELECT " + archiveColumns + " FROM moved"
+	_, err := tx.Exec(query, params...)
+	return err
+}
+
+// Namespace attempt functions
+
+func (ns *namespace) ExpiringAttempts(within time.Duration) ([]coordinate.Attempt, error) {
+	ns.Coordinate().Expiry.DoForNamespace(ns)
+	deadline := ns.Coordinate().clock.Now().Add(within)
+	params := queryParams{}
+	query := buildSelect([]string{
+		attemptID,
+		workUnitID,
+		workUnitName,
+		workSpecID,
+		workSpecName,
+		workerID,
+		workerName,
+	}, []string{
+		attemptTable,
+		workUnitTable,
+		workSpecTable,
+		workerTable,
+	}, []string{
+		workSpecInNamespace(&params, ns.id),
+		attemptThisWorkUnit,
+		workUnitInThisSpec,
+		attemptThisWorker,
+		attemptIsPending,
+		attemptExpirationTime + "<=" + params.Param(deadline),
+	})
+	query += " ORDER BY " + attemptExpirationTime + " ASC, " +
+		workSpecName + " ASC, " + workUnitName + " ASC"
+	result := []coordinate.Attempt{}
+	err := queryAndScan(ns, query, params, func(rows *sql.Rows) error {
+		spec := workSpec{namespace: ns}
+		unit := workUnit{spec: &spec}
+		theWorker := worker{namespace: ns}
+		a := attempt{worker: &theWorker, unit: &unit}
+		err := rows.Scan(&a.id,
+			&unit.id, &unit.name,
+			&spec.id, &spec.name,
+			&theWorker.id, &theWorker.name)
+		if err == nil {
+			result = append(result, &a)
+		}
+		return err
 	})
-	result, err := tx.Exec(query, params...)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	count, err := result.RowsAffected()
-	if err == nil && count == 0 {
-		err = coordinate.ErrGone
+	return result, nil
+}
+
+// WorkSpec attempt functions
+
+func (spec *workSpec) PurgeAttempts(before time.Time, statuses []coordinate.AttemptStatus) (int, error) {
+	spec.Coordinate().Expiry.DoForSpec(spec)
+	params := queryParams{}
+	conditions := []string{
+		attemptWorkSpecID + "=" + params.Param(spec.id),
+		attemptEndTime + "<" + params.Param(before),
+		"NOT " + attemptIsPending,
+		"NOT EXISTS (SELECT 1 FROM " + workUnitTable + " WHERE " + attemptIsTheActive + ")",
 	}
-	if err != nil {
-		return err
+	if len(statuses) == 0 {
+		statuses = coordinate.TerminalAttemptStatuses()
 	}
-
-	// If it was the active attempt, and this is a non-terminal
-	// resolution, also reset that
-	if status == "retryable" || status == "expired" {
-		query = buildUpdate(workUnitTable, []string{
-			"active_attempt_id=NULL",
-		}, []string{
-			"active_attempt_id=$1",
-		})
-		_, err = tx.Exec(query, a.id)
+	var names []string
+	for _, status := range statuses {
+		text, err := status.MarshalText()
+		if err != nil {
+			return 0, err
+		}
+		names = append(names, params.Param(string(text)))
 	}
-
-	return err
+	conditions = append(conditions, attemptStatus+" IN ("+strings.Join(names, ", ")+")")
+	where := " WHERE " + strings.Join(conditions, " AND ")
+	var count int64
+	err := withTx(spec, false, func(tx *sql.Tx) error {
+		count = 0
+		// Archived attempts are never active, so the same
+		// conditions work against the archive table.  Data
+		// history and logs are not tied to either table, so
+		// delete them explicitly.
+		for _, table := range []string{attemptTable, attemptArchiveTable} {
+			query := "WITH purged AS (DELETE FROM " + table + " AS " + attemptTable + where + " RETURNING " + attemptID + "), " +
+				"history AS (DELETE FROM " + attemptDataHistoryTable + " WHERE attempt_id IN (SELECT id FROM purged)), " +
+				"logs AS (DELETE FROM " + attemptLogTable + " WHERE attempt_id IN (SELECT id FROM purged)) " +
+				"SELECT COUNT(*) FROM purged"
+			var n int64
+			err := tx.QueryRow(query, params...).Scan(&n)
+			if err != nil {
+				return err
+			}
+			count += n
+		}
+		return nil
+	})
+	return int(count), err
 }
 
 // WorkUnit attempt functions
 
 func (unit *workUnit) ActiveAttempt() (coordinate.Attempt, error) {
-	unit.Coordinate().Expiry.Do(unit)
+	unit.Coordinate().Expiry.DoForSpec(unit.spec)
 	w := worker{namespace: unit.spec.namespace}
 	a := attempt{unit: unit, worker: &w}
 	query := buildSelect([]string{
@@ -367,7 +978,13 @@ func (unit *workUnit) ClearActiveAttempt() error {
 	}, []string{
 		isWorkUnit(&params, unit.id),
 	})
-	return execInTx(unit, query, params, true)
+	return withTx(unit, false, func(tx *sql.Tx) error {
+		count, err := releaseWorkUnits(tx, query, params)
+		if err == nil && count == 0 {
+			err = coordinate.ErrGone
+		}
+		return err
+	})
 }
 
 func (unit *workUnit) NumAttempts() (int, error) {
@@ -381,13 +998,37 @@ func (unit *workUnit) NumAttempts() (int, error) {
 }
 
 func (unit *workUnit) Attempts() ([]coordinate.Attempt, error) {
+	return unit.QueryAttempts(coordinate.AttemptQuery{})
+}
+
+func (unit *workUnit) QueryAttempts(q coordinate.AttemptQuery) ([]coordinate.Attempt, error) {
+	// Archived attempts are older, so return them first
+	result, err := unit.findAttempts(true)
+	if err == nil && !q.ArchivedOnly {
+		var current []coordinate.Attempt
+		current, err = unit.findAttempts(false)
+		result = append(result, current...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// findAttempts returns the attempts for this work unit from either
+// the attempt table or the archive table.
+func (unit *workUnit) findAttempts(archived bool) ([]coordinate.Attempt, error) {
+	table := attemptTable
+	if archived {
+		table = attemptArchiveTable
+	}
 	params := queryParams{}
 	query := buildSelect([]string{
 		attemptID,
 		workerID,
 		workerName,
 	}, []string{
-		attemptTable,
+		table + " AS " + attemptTable,
 		workerTable,
 	}, []string{
 		attemptForUnit(&params, unit.id),
@@ -396,26 +1037,42 @@ func (unit *workUnit) Attempts() ([]coordinate.Attempt, error) {
 	var result []coordinate.Attempt
 	err := queryAndScan(unit, query, params, func(rows *sql.Rows) error {
 		w := worker{namespace: unit.spec.namespace}
-		a := attempt{worker: &w, unit: unit}
+		a := attempt{worker: &w, unit: unit, archived: archived}
 		err := rows.Scan(&a.id, &w.id, &w.name)
 		if err == nil {
 			result = append(result, &a)
 		}
 		return err
 	})
-	if err != nil {
-		return nil, err
-	}
-	return result, nil
+	return result, err
 }
 
+// countAttempts returns the number of attempts for this work unit,
+// including archived attempts.
 func (unit *workUnit) countAttempts(tx *sql.Tx) (int, error) {
+	return unit.countAttemptsWhere(tx)
+}
+
+// countRetries returns the number of attempts for this work unit
+// that count against its work spec's max_retries, which is all of
+// them except those given back with Release.
+func (unit *workUnit) countRetries(tx *sql.Tx) (int, error) {
+	return unit.countAttemptsWhere(tx, "NOT "+attemptReleased)
+}
+
+// countAttemptsWhere returns the number of attempts for this work
+// unit, including archived attempts, that match all of conditions.
+func (unit *workUnit) countAttemptsWhere(tx *sql.Tx, conditions ...string) (int, error) {
 	params := queryParams{}
-	query := buildSelect(
-		[]string{"COUNT(*)"},
-		[]string{attemptTable},
-		[]string{attemptForUnit(&params, unit.id)},
-	)
+	var counts []string
+	for _, table := range []string{attemptTable, attemptArchiveTable} {
+		counts = append(counts, "("+buildSelect(
+			[]string{"COUNT(*)"},
+			[]string{table + " AS " + attemptTable},
+			append([]string{attemptForUnit(&params, unit.id)}, conditions...),
+		)+")")
+	}
+	query := "SELECT " + strings.Join(counts, " + ")
 	var count int
 	err := tx.QueryRow(query, params...).Scan(&count)
 	return count, err
@@ -433,8 +1090,13 @@ func (w *worker) RequestAttempts(req coordinate.AttemptRequest) ([]coordinate.At
 		meta  *coordinate.WorkSpecMeta
 	)
 
-	// Run system-global expiry.
-	w.Coordinate().Expiry.Do(w)
+	if !w.Coordinate().throttle.Allow(w.namespace.name, w.name, w.Coordinate().clock.Now()) {
+		return nil, nil
+	}
+
+	// Run expiry on this worker's namespace; nothing outside it
+	// can be scheduled here.
+	w.Coordinate().Expiry.DoForNamespace(w.namespace)
 
 	// Collect the set of candidate work specs and metadata outside
 	// the main transaction.  This is pretty expensive to collect
@@ -446,9 +1108,22 @@ func (w *worker) RequestAttempts(req coordinate.AttemptRequest) ([]coordinate.At
 	// another worker picks those up.  That means the scheduler
 	// could pick something but we then fail to get any work from
 	// it.
+	//
+	// If the request has a minimum priority, a work spec can have
+	// available work units that are all below it.  Remember those
+	// work specs and don't pick them again.
+	//
+	// Similarly, if the request has runtimes, some work units can
+	// have runtime overrides that exclude them, even if their work
+	// spec's runtime matches.
+	excluded := make(map[string]bool)
 	for {
+		var unitRuntimes map[string][]string
 		err = withTx(w, true, func(tx *sql.Tx) (err error) {
 			specs, metas, err = w.namespace.allMetas(tx, true)
+			if err == nil && len(req.Runtimes) > 0 {
+				unitRuntimes, err = w.namespace.unitRuntimes(tx)
+			}
 			return
 		})
 		if err != nil {
@@ -458,7 +1133,11 @@ func (w *worker) RequestAttempts(req coordinate.AttemptRequest) ([]coordinate.At
 		// Now pick something (this is stateless, but see TODO above)
 		// (If this picks nothing, we're done)
 		metas = coordinate.LimitMetasToNames(metas, req.WorkSpecs)
-		metas = coordinate.LimitMetasToRuntimes(metas, req.Runtimes)
+		metas, filtered := limitMetasToRuntimes(metas, unitRuntimes, req)
+		metas = w.Coordinate().gate.LimitMetas(w.namespace.name, metas)
+		for name := range excluded {
+			delete(metas, name)
+		}
 		now := w.Coordinate().clock.Now()
 		name, err = coordinate.SimplifiedScheduler(metas, now, req.AvailableGb)
 		if err == coordinate.ErrNoWork {
@@ -470,7 +1149,7 @@ func (w *worker) RequestAttempts(req coordinate.AttemptRequest) ([]coordinate.At
 		meta = metas[name]
 
 		// Then get some attempts
-		attempts, err := w.requestAttemptsForSpec(req, spec, meta)
+		attempts, failed, err := w.requestAttemptsForSpec(req, spec, meta)
 		if err != nil {
 			return nil, err
 		}
@@ -484,17 +1163,63 @@ func (w *worker) RequestAttempts(req coordinate.AttemptRequest) ([]coordinate.At
 			return result, nil
 		}
 		// Otherwise reloop
+		if req.MinPriority != nil || filtered[name] {
+			excluded[name] = true
+			continue
+		}
+		// Failing work units that were out of retries is not
+		// losing a race to another worker
+		if failed == 0 {
+			contentionRetries.WithLabelValues(contentionRequestAttempts).Inc()
+		}
+	}
+}
+
+// limitMetasToRuntimes limits metas to the work specs that could have
+// work for req.Runtimes: those whose own runtime matches, and those
+// with available work units whose runtime overrides match.
+// unitRuntimes is the result of namespace.unitRuntimes(); it may be
+// nil if req.Runtimes is empty.  Also returns the set of work specs
+// where some available work units do not match, and so
+// chooseAndMakeAttempts could find nothing even without contention.
+func limitMetasToRuntimes(metas map[string]*coordinate.WorkSpecMeta, unitRuntimes map[string][]string, req coordinate.AttemptRequest) (map[string]*coordinate.WorkSpecMeta, map[string]bool) {
+	filtered := make(map[string]bool)
+	if len(req.Runtimes) == 0 {
+		return metas, filtered
 	}
+	limited := coordinate.LimitMetasToRuntimes(metas, req.Runtimes)
+	for name, runtimes := range unitRuntimes {
+		meta, present := metas[name]
+		if !present {
+			continue
+		}
+		for _, runtime := range runtimes {
+			if req.AllowsRuntime(runtime) {
+				limited[name] = meta
+			} else {
+				filtered[name] = true
+			}
+		}
+		if !req.AllowsRuntime(meta.Runtime) {
+			// Only the overrides can match
+			filtered[name] = true
+		}
+	}
+	return limited, filtered
 }
 
+// requestAttemptsForSpec makes attempts for req from a single work
+// spec.  It also returns the number of attempts it made but then
+// failed because their work units were out of retries.
 func (w *worker) requestAttemptsForSpec(
 	req coordinate.AttemptRequest,
 	spec *workSpec,
 	meta *coordinate.WorkSpecMeta,
-) ([]*attempt, error) {
+) ([]*attempt, int, error) {
 	var (
 		attempts []*attempt
 		count    int
+		failed   int
 		err      error
 	)
 
@@ -511,7 +1236,16 @@ func (w *worker) requestAttemptsForSpec(
 	}
 
 	continuous := false
-	length := time.Duration(15) * time.Minute
+	length := req.Lifetime
+	if length == 0 {
+		length = meta.DefaultLease
+	}
+	if length == 0 {
+		length = defaultAttemptLength
+	}
+	if meta.MaxLeaseTotal > 0 && length > meta.MaxLeaseTotal {
+		length = meta.MaxLeaseTotal
+	}
 	err = withTx(w, false, func(tx *sql.Tx) error {
 		var err error
 		now := w.Coordinate().clock.Now()
@@ -529,11 +1263,22 @@ func (w *worker) requestAttemptsForSpec(
 			return err
 		}
 
+		// meta.PendingCount came from an earlier transaction,
+		// and other workers may have gotten work since then,
+		// so check max_running again here
+		n := count
+		if meta.MaxRunning > 0 {
+			n, err = limitToMaxRunning(tx, spec, meta.MaxRunning, count)
+			if err != nil || n <= 0 {
+				return err
+			}
+		}
+
 		// Try to create attempts from pre-existing work units
 		// (assuming we expect there to be some)
 		if meta.AvailableCount > 0 {
 			attempts, err = w.chooseAndMakeAttempts(
-				tx, spec, count, now, length)
+				tx, spec, meta, req, n, now, length)
 		}
 		if err != nil || len(attempts) > 0 {
 			return err
@@ -542,7 +1287,7 @@ func (w *worker) requestAttemptsForSpec(
 		// If there were none, but the selected work spec is
 		// continuous, maybe we can create a work unit and an
 		// attempt
-		if meta.CanStartContinuous(now) {
+		if req.AllowsPriority(0) && req.AllowsRuntime(meta.Runtime) && meta.CanStartContinuous(now) {
 			var unit *workUnit
 			var a *attempt
 			continuous = true
@@ -567,6 +1312,11 @@ func (w *worker) requestAttemptsForSpec(
 		attempts = nil
 		err = nil
 	}
+	// Only count a continuous work unit once its transaction has
+	// committed, so that rollbacks and retries are not counted.
+	if continuous && err == nil && len(attempts) > 0 {
+		continuousUnits.WithLabelValues(spec.namespace.name, spec.name).Inc()
+	}
 	// If we got attempts, but for a work spec with a max-retries
 	// limit, recheck whether we need to fail some of those attempts.
 	// (If this fails _some_ of the attempts, return less than the
@@ -585,15 +1335,64 @@ func (w *worker) requestAttemptsForSpec(
 		// there is a database error at this point, it's
 		// better to err on the side of returning them to the
 		// caller and having them retried an extra time.
-		_ = withTx(w, false, func(tx *sql.Tx) error {
+		made := attempts
+		err := withTx(w, false, func(tx *sql.Tx) error {
 			var err error
 			attempts, err = w.maybeFailAttempts(
-				tx, attempts, meta.MaxRetries)
+				tx, spec, made, meta.MaxRetries)
 			return err
 		})
+		if err == nil {
+			failed = len(made) - len(attempts)
+		} else {
+			attempts = made
+		}
+	}
+
+	return attempts, failed, err
+}
+
+// limitToMaxRunning returns the number of attempts, up to count, that
+// can be made for spec without it having more than maxRunning pending
+// attempts.  The pending attempts are counted within tx.
+//
+// The advisory lock in requestAttemptsForSpec() does not help by
+// itself, since a repeatable-read transaction's snapshot is taken
+// before it waits for the lock, and would not see attempts that the
+// lock holder commits.  So this also updates the work spec's row.  Of
+// two transactions that do that concurrently, the later one to get
+// there gets a serialization failure, and withTx() retries it with a
+// new snapshot that includes the other's attempts.
+func limitToMaxRunning(tx *sql.Tx, spec *workSpec, maxRunning, count int) (int, error) {
+	params := queryParams{}
+	query := buildUpdate(workSpecTable, []string{
+		"max_running=max_running",
+	}, []string{
+		isWorkSpec(&params, spec.id),
+	})
+	_, err := tx.Exec(query, params...)
+	if err != nil {
+		return 0, err
 	}
 
-	return attempts, err
+	params = queryParams{}
+	query = buildSelect([]string{
+		"COUNT(*)",
+	}, []string{
+		attemptTable,
+	}, []string{
+		attemptWorkSpecID + "=" + params.Param(spec.id),
+		attemptIsPending,
+	})
+	var pending int
+	err = tx.QueryRow(query, params...).Scan(&pending)
+	if err != nil {
+		return 0, err
+	}
+	if count > maxRunning-pending {
+		count = maxRunning - pending
+	}
+	return count, nil
 }
 
 // chooseMakeFailAttempts finds work units to do for a specific work
@@ -602,24 +1401,31 @@ func (w *worker) requestAttemptsForSpec(
 // chooseAndMakeAttempts instead.
 func (w *worker) maybeFailAttempts(
 	tx *sql.Tx,
+	spec *workSpec,
 	moreAttempts []*attempt,
 	maxRetries int,
 ) ([]*attempt, error) {
-	var attempts []*attempt
+	var (
+		attempts    []*attempt
+		failureData map[string]interface{}
+	)
 	// For each of the (new) attempts, count the number of
 	// existing attempts for the work unit and maybe fail it.
 	// (It might be nice to do this in a batch?)
 	for _, a := range moreAttempts {
-		count, err := a.unit.countAttempts(tx)
+		count, err := a.unit.countRetries(tx)
 		if err != nil {
 			return nil, err
 		}
 		if count > maxRetries {
-			err = a.complete(tx,
-				map[string]interface{}{
-					"traceback": "too many retries",
-				},
-				"failed")
+			if failureData == nil {
+				specData, err := spec.txData(tx)
+				if err != nil {
+					return nil, err
+				}
+				failureData = coordinate.ExtractFailureData(specData, "too many retries")
+			}
+			err = a.complete(tx, failureData, "failed")
 			if err != nil {
 				return nil, err
 			}
@@ -633,27 +1439,40 @@ func (w *worker) maybeFailAttempts(
 
 // chooseAndMakeAttempts, in one SQL query, finds work units to do for
 // a specific work spec, creates attempts for them, and returns the
-// corresponding attempt objects.
+// corresponding attempt objects.  meta is the work spec's metadata,
+// which sets the order to choose work units in.  Only work units
+// allowed by req.MinPriority and req.Runtimes are chosen.
 func (w *worker) chooseAndMakeAttempts(
 	tx *sql.Tx,
 	spec *workSpec,
+	meta *coordinate.WorkSpecMeta,
+	req coordinate.AttemptRequest,
 	numUnits int,
 	now time.Time,
 	length time.Duration,
 ) ([]*attempt, error) {
 	params := queryParams{}
 
+	conditions := []string{
+		workUnitInSpec(&params, spec.id),
+		workUnitHasNoAttempt,
+		"NOT " + workUnitTooSoon(&params, now),
+	}
+	if req.MinPriority != nil {
+		conditions = append(conditions, workUnitPriority+">="+params.Param(*req.MinPriority))
+	}
+	if len(req.Runtimes) > 0 {
+		conditions = append(conditions, workUnitRuntimeAllowed(&params, req, meta.Runtime))
+	}
 	choose := buildSelect([]string{
 		workUnitID,
 		workUnitName,
+		workUnitPriority,
+		workUnitCreatedAt,
 	}, []string{
 		workUnitTable,
-	}, []string{
-		workUnitInSpec(&params, spec.id),
-		workUnitHasNoAttempt,
-		"NOT " + workUnitTooSoon(&params, now),
-	})
-	choose += " ORDER BY priority DESC, name ASC"
+	}, conditions)
+	choose += " ORDER BY " + unitOrderBy(meta.Order)
 	choose += fmt.Sprintf(" LIMIT %v", numUnits)
 
 	expiration := now.Add(length)
@@ -675,9 +1494,15 @@ func (w *worker) chooseAndMakeAttempts(
 		"SET active_attempt_id=a.id " +
 		"FROM a, u " +
 		"WHERE " + workUnitID + "=u.id AND a.work_unit_id=u.id " +
-		"RETURNING u.id, u.name, a.id"
+		"RETURNING u.id, u.name, u.priority, u.created_at, a.id AS attempt_id"
 
-	query := "WITH u AS (" + choose + "), a AS (" + attempts + ") " + update
+	// UPDATE ... RETURNING does not keep the order of u, so sort
+	// the (at most numUnits) results again, to return attempts in
+	// the same order the memory backend does
+	query := "WITH u AS (" + choose + "), a AS (" + attempts + "), " +
+		"updated AS (" + update + ") " +
+		"SELECT updated.id, updated.name, updated.attempt_id FROM updated " +
+		"ORDER BY " + unitOrderByIn("updated", meta.Order)
 
 	rows, err := tx.Query(query, params...)
 	if err != nil {
@@ -740,7 +1565,15 @@ func (w *worker) createContinuousUnit(tx *sql.Tx, spec *workSpec, meta *coordina
 	nano := now.Nanosecond()
 	milli := nano / 1000000
 	name := fmt.Sprintf("%d.%03d", seconds, milli)
-	dataBytes, err := mapToBytes(map[string]interface{}{})
+	specData, err := spec.txData(tx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := coordinate.ExtractContinuousData(specData)
+	if err != nil {
+		return nil, err
+	}
+	dataBytes, err := mapToBytes(data)
 	if err != nil {
 		return nil, err
 	}
@@ -764,7 +1597,41 @@ func (w *worker) MakeAttempt(cUnit coordinate.WorkUnit, length time.Duration) (c
 	var a *attempt
 	var err error
 	err = withTx(w, false, func(tx *sql.Tx) error {
-		a, err = makeAttempt(tx, unit, w, length)
+		var defaultLease, maxLeaseTotal string
+		params := queryParams{}
+		query := buildSelect([]string{
+			workSpecDefaultLease,
+			workSpecMaxLeaseTotal,
+		}, []string{
+			workSpecTable,
+		}, []string{
+			isWorkSpec(&params, unit.spec.id),
+		})
+		err := tx.QueryRow(query, params...).Scan(&defaultLease, &maxLeaseTotal)
+		if err == sql.ErrNoRows {
+			return coordinate.ErrGone
+		}
+		if err != nil {
+			return err
+		}
+		lease := length
+		if lease == 0 {
+			lease, err = sqlToDuration(defaultLease)
+			if err != nil {
+				return err
+			}
+		}
+		if lease == 0 {
+			lease = defaultAttemptLength
+		}
+		maxLease, err := sqlToDuration(maxLeaseTotal)
+		if err != nil {
+			return err
+		}
+		if maxLease > 0 && lease > maxLease {
+			lease = maxLease
+		}
+		a, err = makeAttempt(tx, unit, w, lease)
 		return err
 	})
 	if err != nil {
@@ -773,6 +1640,10 @@ func (w *worker) MakeAttempt(cUnit coordinate.WorkUnit, length time.Duration) (c
 	return a, nil
 }
 
+// defaultAttemptLength is how long attempts last if neither the
+// worker nor the work spec says otherwise.
+const defaultAttemptLength = 15 * time.Minute
+
 func makeAttempt(tx *sql.Tx, unit *workUnit, w *worker, length time.Duration) (*attempt, error) {
 	a := attempt{unit: unit, worker: w}
 
@@ -818,6 +1689,18 @@ func (w *worker) AllAttempts() ([]coordinate.Attempt, error) {
 	}, &qp, false)
 }
 
+func (w *worker) AttemptsInWindow(start, end time.Time) ([]coordinate.Attempt, error) {
+	qp := queryParams{}
+	conditions := []string{attemptByWorker(&qp, w.id)}
+	if !start.IsZero() {
+		conditions = append(conditions, attemptStartTime+">="+qp.Param(start))
+	}
+	if !end.IsZero() {
+		conditions = append(conditions, attemptStartTime+"<"+qp.Param(end))
+	}
+	return w.findAttempts(conditions, &qp, false)
+}
+
 func (w *worker) ChildAttempts() ([]coordinate.Attempt, error) {
 	qp := queryParams{}
 	return w.findAttempts([]string{
@@ -827,6 +1710,264 @@ func (w *worker) ChildAttempts() ([]coordinate.Attempt, error) {
 	}, &qp, true)
 }
 
+func (w *worker) FinishAttempts(attempts []coordinate.Attempt, data []map[string]interface{}) error {
+	done, outputs, err := w.completeAttempts(attempts, data, "finished", 0)
+	if err != nil && err != coordinate.ErrLostLease {
+		return err
+	}
+	// As in Finish(), create "output" work units after the
+	// attempts are marked finished
+	for _, i := range outputs {
+		var attemptData map[string]interface{}
+		if data != nil {
+			attemptData = data[i]
+		}
+		if outErr := done[i].addOutput(attemptData); outErr != nil {
+			return outErr
+		}
+	}
+	return err
+}
+
+func (w *worker) FailAttempts(attempts []coordinate.Attempt, data []map[string]interface{}) error {
+	_, _, err := w.completeAttempts(attempts, data, "failed", 0)
+	return err
+}
+
+func (w *worker) RetryAttempts(attempts []coordinate.Attempt, data []map[string]interface{}, delay time.Duration) error {
+	_, _, err := w.completeAttempts(attempts, data, "retryable", delay)
+	return err
+}
+
+// CompleteAttempts completes each attempt in its own transaction,
+// exactly as calling its Finish(), Fail(), or Retry() method would.
+func (w *worker) CompleteAttempts(outcomes []coordinate.AttemptOutcome) ([]error, error) {
+	return coordinate.CompleteAttempts(w, outcomes), nil
+}
+
+// completeAttempts is the implementation of the batch completion
+// calls.  In one transaction it checks every attempt, marks them all
+// completed with a single UPDATE, and resets the work units of
+// retried attempts.  It returns the attempts, and the indexes of
+// those that were active and completed with status, rather than
+// expired for losing their lease.
+func (w *worker) completeAttempts(cAttempts []coordinate.Attempt, data []map[string]interface{}, status string, delay time.Duration) ([]*attempt, []int, error) {
+	if data != nil && len(data) != len(cAttempts) {
+		return nil, nil, coordinate.ErrBatchLength
+	}
+	attempts := make([]*attempt, len(cAttempts))
+	dataBytes := make([][]byte, len(cAttempts))
+	for i, cAttempt := range cAttempts {
+		a, ok := cAttempt.(*attempt)
+		if !ok {
+			return nil, nil, coordinate.ErrWrongBackend
+		}
+		if a.worker.id != w.id {
+			return nil, nil, coordinate.ErrWrongWorker
+		}
+		if a.archived {
+			return nil, nil, coordinate.ErrNotPending
+		}
+		if data != nil && data[i] != nil {
+			var err error
+			dataBytes[i], err = mapToBytes(data[i])
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		attempts[i] = a
+	}
+	if len(attempts) == 0 {
+		return attempts, nil, nil
+	}
+
+	strict := w.Coordinate().strictCompletion()
+	now := w.Coordinate().clock.Now()
+	var (
+		outputs []int
+		lost    bool
+	)
+	err := withTx(w, false, func(tx *sql.Tx) error {
+		outputs = nil
+		lost = false
+
+		// Find the current status of every attempt, and
+		// whether it is its work unit's active attempt
+		params := queryParams{}
+		ids := make([]string, len(attempts))
+		for i, a := range attempts {
+			ids[i] = params.Param(a.id)
+		}
+		query := buildSelect([]string{
+			attemptID,
+			attemptStatus,
+			workUnitID + " IS NOT NULL",
+		}, []string{
+			attemptTable + " LEFT OUTER JOIN " + workUnitTable + " ON " + attemptIsTheActive,
+		}, []string{
+			attemptID + " IN (" + strings.Join(ids, ", ") + ")",
+		})
+		rows, err := tx.Query(query, params...)
+		if err != nil {
+			return err
+		}
+		statuses := make(map[int]string)
+		active := make(map[int]bool)
+		err = scanRows(rows, func() error {
+			var (
+				id       int
+				current  string
+				isActive bool
+			)
+			err := rows.Scan(&id, &current, &isActive)
+			if err == nil {
+				statuses[id] = current
+				active[id] = isActive
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		// Check everything before changing anything
+		for _, a := range attempts {
+			current, present := statuses[a.id]
+			if !present {
+				return coordinate.ErrGone
+			}
+			pending := current == "pending" ||
+				(current == "expired" && active[a.id]) ||
+				(status == "finished" && current == "failed")
+			if !pending {
+				return coordinate.ErrNotPending
+			}
+		}
+
+		// Work units out of retries fail instead, as in
+		// Retry(); find them all at once
+		var failures map[int]map[string]interface{}
+		if status == "retryable" {
+			var retryable []*attempt
+			for _, a := range attempts {
+				if active[a.id] {
+					retryable = append(retryable, a)
+				}
+			}
+			failures, err = retryFailures(tx, retryable)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Mark all of the attempts completed at once
+		params = queryParams{}
+		values := make([]string, len(attempts))
+		var retried []*attempt
+		for i, a := range attempts {
+			newStatus := status
+			newData := dataBytes[i]
+			if strict && statuses[a.id] == "pending" && !active[a.id] {
+				newStatus = "expired"
+				lost = true
+			} else if active[a.id] {
+				switch status {
+				case "finished":
+					outputs = append(outputs, i)
+				case "retryable":
+					failureData := failures[a.id]
+					if failureData == nil {
+						retried = append(retried, a)
+						break
+					}
+					newStatus = "failed"
+					newData, err = mapToBytes(failureData)
+					if err != nil {
+						return err
+					}
+				}
+			}
+			values[i] = "(" + params.Param(a.id) + "::INTEGER, " +
+				params.Param(newStatus) + "::attempt_status, " +
+				params.Param(newData) + "::BYTEA)"
+		}
+		query = "UPDATE " + attemptTable + " SET active=FALSE" +
+			", status=batch.status" +
+			", end_time=" + params.Param(now) +
+			", data=COALESCE(batch.data, " + attemptData + ")" +
+			" FROM (VALUES " + strings.Join(values, ", ") + ") AS batch(id, status, data)" +
+			" WHERE " + attemptID + "=batch.id"
+		result, err := tx.Exec(query, params...)
+		if err != nil {
+			return err
+		}
+		count, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if count < int64(len(statuses)) {
+			return coordinate.ErrGone
+		}
+
+		// Retried attempts that were active release their
+		// work units, which follow the retry schedule unless
+		// there is an explicit delay
+		if len(retried) > 0 {
+			params = queryParams{}
+			ids = make([]string, len(retried))
+			for i, a := range retried {
+				ids[i] = params.Param(a.id)
+			}
+			changes := append([]string{"active_attempt_id=NULL"},
+				workUnitRetried(&params, now)...)
+			query = buildUpdate(workUnitTable, changes, []string{
+				workUnitAttempt + " IN (" + strings.Join(ids, ", ") + ")",
+			})
+			_, err = releaseWorkUnits(tx, query, params)
+			if err != nil {
+				return err
+			}
+			params = queryParams{}
+			for i, a := range retried {
+				ids[i] = params.Param(a.unit.id)
+			}
+			then := now.Add(delay)
+			if delay > 0 {
+				changes = []string{"not_before=" + params.Param(then)}
+			} else {
+				changes = []string{"not_before=CASE WHEN " + workUnitHasRetryDelays + " THEN not_before ELSE " + params.Param(then) + " END"}
+			}
+			query = buildUpdate(workUnitTable, changes, []string{
+				workUnitID + " IN (" + strings.Join(ids, ", ") + ")",
+			})
+			_, err = tx.Exec(query, params...)
+			if err != nil {
+				return err
+			}
+		}
+
+		archived := make(map[int]bool)
+		for _, a := range attempts {
+			if archived[a.unit.id] {
+				continue
+			}
+			archived[a.unit.id] = true
+			err = a.unit.archiveAttempts(tx)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil && lost {
+		err = coordinate.ErrLostLease
+	}
+	if err != nil && err != coordinate.ErrLostLease {
+		return nil, nil, err
+	}
+	return attempts, outputs, err
+}
+
 func (w *worker) findAttempts(conditions []string, qp *queryParams, forOtherWorkers bool) ([]coordinate.Attempt, error) {
 	outputs := []string{
 		attemptID,
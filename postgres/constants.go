@@ -15,16 +15,23 @@ package postgres
 // tableIsThisThing: foreign-key test for some other joined table
 
 import (
+	"strings"
 	"time"
+
+	"github.com/diffeo/go-coordinate/coordinate"
+	"github.com/lib/pq"
 )
 
 const (
 	// SQL table names:
-	attemptTable   = "attempt"
-	namespaceTable = "namespace"
-	workerTable    = "worker"
-	workSpecTable  = "work_spec"
-	workUnitTable  = "work_unit"
+	attemptTable            = "attempt"
+	attemptArchiveTable     = "attempt_archive"
+	attemptDataHistoryTable = "attempt_data_history"
+	attemptLogTable         = "attempt_log"
+	namespaceTable          = "namespace"
+	workerTable             = "worker"
+	workSpecTable           = "work_spec"
+	workUnitTable           = "work_unit"
 
 	// SQL column names:
 	attemptID                   = attemptTable + ".id"
@@ -36,13 +43,17 @@ const (
 	attemptEndTime              = attemptTable + ".end_time"
 	attemptExpirationTime       = attemptTable + ".expiration_time"
 	attemptActive               = attemptTable + ".active"
+	attemptReleased             = attemptTable + ".released"
 	attemptWorkSpecID           = attemptTable + ".work_spec_id"
+	attemptArchiveWorkUnitID    = attemptArchiveTable + ".work_unit_id"
 	namespaceName               = namespaceTable + ".name"
 	namespaceID                 = namespaceTable + ".id"
 	workerID                    = workerTable + ".id"
 	workerNamespace             = workerTable + ".namespace_id"
 	workerName                  = workerTable + ".name"
 	workerParent                = workerTable + ".parent"
+	workerActive                = workerTable + ".active"
+	workerExpiration            = workerTable + ".expiration"
 	workSpecID                  = workSpecTable + ".id"
 	workSpecName                = workSpecTable + ".name"
 	workSpecNamespace           = workSpecTable + ".namespace_id"
@@ -51,6 +62,8 @@ const (
 	workSpecWeight              = workSpecTable + ".weight"
 	workSpecPaused              = workSpecTable + ".paused"
 	workSpecContinuous          = workSpecTable + ".continuous"
+	workSpecContinuousPaused    = workSpecTable + ".continuous_paused"
+	workSpecGated               = workSpecTable + ".gated"
 	workSpecCanBeContinuous     = workSpecTable + ".can_be_continuous"
 	workSpecMinMemoryGb         = workSpecTable + ".min_memory_gb"
 	workSpecInterval            = workSpecTable + ".interval"
@@ -58,8 +71,15 @@ const (
 	workSpecMaxRunning          = workSpecTable + ".max_running"
 	workSpecMaxAttemptsReturned = workSpecTable + ".max_attempts_returned"
 	workSpecMaxRetries          = workSpecTable + ".max_retries"
+	workSpecExpireWithWorker    = workSpecTable + ".expire_with_worker"
+	workSpecMaxLeaseTotal       = workSpecTable + ".max_lease_total"
+	workSpecHeartbeatExtension  = workSpecTable + ".heartbeat_extension"
+	workSpecDefaultLease        = workSpecTable + ".default_lease"
+	workSpecRetryDelays         = workSpecTable + ".retry_delays"
 	workSpecNextWorkSpec        = workSpecTable + ".next_work_spec_name"
 	workSpecRuntime             = workSpecTable + ".runtime"
+	workSpecUnitOrder           = workSpecTable + ".unit_order"
+	workSpecSchemaVersion       = workSpecTable + ".schema_version"
 	workUnitID                  = workUnitTable + ".id"
 	workUnitName                = workUnitTable + ".name"
 	workUnitData                = workUnitTable + ".data"
@@ -67,6 +87,8 @@ const (
 	workUnitAttempt             = workUnitTable + ".active_attempt_id"
 	workUnitPriority            = workUnitTable + ".priority"
 	workUnitNotBefore           = workUnitTable + ".not_before"
+	workUnitCreatedAt           = workUnitTable + ".created_at"
+	workUnitRuntime             = workUnitTable + ".runtime"
 
 	// WHERE clause fragments:
 	workSpecInThisNamespace = workSpecNamespace + "=" + namespaceID
@@ -85,6 +107,25 @@ const (
 		attemptTable + "  ON " + attemptIsTheActive)
 )
 
+// unitOrderBy returns the ORDER BY clause that hands out work units
+// in a work spec with the given WorkSpecMeta.Order.
+func unitOrderBy(order string) string {
+	return unitOrderByIn(workUnitTable, order)
+}
+
+// unitOrderByIn is like unitOrderBy, but sorts rows of table, which
+// must have the priority, created_at, and name columns of work_unit.
+func unitOrderByIn(table, order string) string {
+	switch order {
+	case coordinate.OrderFIFO:
+		return table + ".created_at ASC, " + table + ".name ASC"
+	case coordinate.OrderLIFO:
+		return table + ".created_at DESC, " + table + ".name ASC"
+	default:
+		return table + ".priority DESC, " + table + ".name ASC"
+	}
+}
+
 // More WHERE clause fragments, that depend on query params:
 
 func isNamespace(params *queryParams, id int) string {
@@ -133,15 +174,54 @@ func workUnitTooSoon(params *queryParams, now time.Time) string {
 	return "(" + workUnitNotBefore + " IS NOT NULL AND " + params.Param(now) + "<" + workUnitNotBefore + ")"
 }
 
+// workUnitRuntimeAllowed determines whether req.Runtimes allows a
+// work unit's runtime: its own runtime if it has one, or else
+// specRuntime, its work spec's runtime.  req.Runtimes must not be
+// empty.
+func workUnitRuntimeAllowed(params *queryParams, req coordinate.AttemptRequest, specRuntime string) string {
+	override := "(" + workUnitRuntime + "<>'' AND " + workUnitRuntime + "=ANY(" + params.Param(pq.StringArray(req.Runtimes)) + "))"
+	if req.AllowsRuntime(specRuntime) {
+		return "(" + workUnitRuntime + "='' OR " + override + ")"
+	}
+	return override
+}
+
+// workUnitRetryDelays selects the retry_delays schedule for a work
+// unit's work spec, for use in an UPDATE of the work_unit table.
+const workUnitRetryDelays = "(SELECT " + workSpecRetryDelays + " FROM " + workSpecTable + " WHERE " + workSpecID + "=work_unit.work_spec_id)"
+
+// workUnitHasRetryDelays determines whether a work unit's work spec
+// has a retry_delays schedule.
+const workUnitHasRetryDelays = "COALESCE(array_length(" + workUnitRetryDelays + ", 1), 0)>0"
+
+// workUnitRetried returns the changes to make to a work unit when
+// its active attempt is retried or expires, for use in an UPDATE of
+// the work_unit table.  This counts the retry, and applies the work
+// spec's retry_delays schedule to the work unit's not_before time
+// if there is one.
+func workUnitRetried(params *queryParams, now time.Time) []string {
+	delay := workUnitRetryDelays + "[LEAST(retries+1, array_length(" + workUnitRetryDelays + ", 1))]"
+	return []string{
+		"retries=retries+1",
+		"not_before=CASE WHEN " + workUnitHasRetryDelays +
+			" THEN CAST(" + params.Param(now) + " AS TIMESTAMP WITH TIME ZONE) + " + delay + " * INTERVAL '1 second'" +
+			" ELSE not_before END",
+	}
+}
+
 // workUnitAvailable determines whether a work unit is really available.
+// This only looks at the work unit table, so it does not need a join
+// against the attempt table, and can use the
+// work_unit_spec_available_name partial index.
 func workUnitAvailable(params *queryParams, now time.Time) string {
-	return "(" + attemptStatus + " IS NULL AND NOT (" + workUnitTooSoon(params, now) + "))"
+	return "(" + workUnitAttempt + " IS NULL AND NOT (" + workUnitTooSoon(params, now) + "))"
 }
 
 // workUnitDelayed determines whether a work unit is delayed: it has no
-// active attempt but it is too soon for it to start.
+// active attempt but it is too soon for it to start.  Like
+// workUnitAvailable, this does not need the attempt table.
 func workUnitDelayed(params *queryParams, now time.Time) string {
-	return "(" + attemptStatus + " IS NULL AND (" + workUnitTooSoon(params, now) + "))"
+	return "(" + workUnitAttempt + " IS NULL AND (" + workUnitTooSoon(params, now) + "))"
 }
 
 func isAttempt(params *queryParams, id int) string {
@@ -160,6 +240,13 @@ func attemptIsExpired(params *queryParams, now time.Time) string {
 	return attemptExpirationTime + "<" + params.Param(now)
 }
 
+// workerIsDead determines whether a worker has been deactivated or
+// has failed to check in before its expiration time plus a grace
+// period.
+func workerIsDead(params *queryParams, now time.Time, grace time.Duration) string {
+	return "(NOT " + workerActive + " OR " + workerExpiration + "<" + params.Param(now.Add(-grace)) + ")"
+}
+
 func isWorker(params *queryParams, id int) string {
 	return workerID + "=" + params.Param(id)
 }
@@ -172,6 +259,15 @@ func workerHasName(params *queryParams, name string) string {
 	return workerName + "=" + params.Param(name)
 }
 
+// workerHasNamePrefix selects workers whose names begin with prefix.
+func workerHasNamePrefix(params *queryParams, prefix string) string {
+	return workerName + " LIKE " + params.Param(likeEscaper.Replace(prefix)+"%")
+}
+
+// likeEscaper quotes the characters that are special in an SQL LIKE
+// pattern, using LIKE's default escape character.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
 func workerHasParent(params *queryParams, id int) string {
 	return workerParent + "=" + params.Param(id)
 }
@@ -4,6 +4,7 @@
 package postgres_test
 
 import (
+	"github.com/diffeo/go-coordinate/coordinate"
 	"github.com/diffeo/go-coordinate/coordinate/coordinatetest"
 	"github.com/diffeo/go-coordinate/postgres"
 	"github.com/stretchr/testify/suite"
@@ -23,6 +24,8 @@ func (s *Suite) SetupSuite() {
 		panic(err)
 	}
 	s.Coordinate = c
+	s.DataHistory = 3
+	c.(coordinate.DataHistorySetter).SetDataHistory(s.DataHistory)
 }
 
 // TestCoordinate runs the generic Coordinate tests with a PostgreSQL
@@ -11,15 +11,29 @@ import (
 
 // expiry manages the semi-global expiration process.  In particular
 // it ensures that not more than one instance of expiration is running
-// at a time.
+// at a time, for the whole system or for any one namespace or work
+// spec.
 type expiry struct {
 	Cond    *sync.Cond
 	Running bool
+
+	// Scoped holds a channel for each namespace or work spec
+	// whose expiry is running, which is closed when it finishes.
+	// It is protected by Cond.L.
+	Scoped map[expiryScope]chan struct{}
+}
+
+// expiryScope identifies the namespace or work spec a scoped expiry
+// run covers; exactly one of its fields is nonzero.
+type expiryScope struct {
+	namespaceID int
+	specID      int
 }
 
 // Init initializes an expiry object.
 func (exp *expiry) Init() {
 	exp.Cond = sync.NewCond(&sync.Mutex{})
+	exp.Scoped = make(map[expiryScope]chan struct{})
 }
 
 // Do runs expiry.  When it returns, an instance of expiry has run to
@@ -54,8 +68,82 @@ func (exp *expiry) Do(c coordinable) {
 	exp.Cond.L.Unlock()
 }
 
-// expireAttempts finds all attempts whose expiration time has passed
-// and expires them.  It runs on all attempts for all work units in all
+// DoForSpec runs expiry on only the attempts in a single work spec.
+// As with Do, if expiry is already running for this work spec, this
+// waits for it instead of running it again; concurrent calls for
+// different work specs touch disjoint rows and do not wait for each
+// other.
+func (exp *expiry) DoForSpec(spec *workSpec) {
+	exp.doScoped(expiryScope{specID: spec.id}, func() {
+		_ = withTx(spec, false, func(tx *sql.Tx) error {
+			return expireAttemptsForSpec(spec, tx)
+		})
+	})
+}
+
+// DoForNamespace runs expiry on only the attempts in a single
+// namespace, waiting for a run already in progress for the same
+// namespace as DoForSpec does.
+func (exp *expiry) DoForNamespace(ns *namespace) {
+	exp.doScoped(expiryScope{namespaceID: ns.id}, func() {
+		_ = withTx(ns, false, func(tx *sql.Tx) error {
+			return expireAttemptsForNamespace(ns, tx)
+		})
+	})
+}
+
+// doScoped calls run, unless another goroutine is already running
+// expiry for scope, in which case it waits for that to finish.
+func (exp *expiry) doScoped(scope expiryScope, run func()) {
+	exp.Cond.L.Lock()
+	if done, running := exp.Scoped[scope]; running {
+		exp.Cond.L.Unlock()
+		<-done
+		return
+	}
+	done := make(chan struct{})
+	exp.Scoped[scope] = done
+	exp.Cond.L.Unlock()
+
+	defer func() {
+		exp.Cond.L.Lock()
+		delete(exp.Scoped, scope)
+		exp.Cond.L.Unlock()
+		close(done)
+	}()
+	run()
+}
+
+// expiringAttempts builds a query that selects the IDs of pending
+// attempts that should be expired: those whose expiration time has
+// passed, and those in work specs with ExpireWithWorker set whose
+// worker is dead, allowing for the worker grace period.  If scope is
+// non-nil, it returns an additional condition on the work spec table
+// that limits the attempts considered.
+func expiringAttempts(params *queryParams, now time.Time, grace time.Duration, scope func(*queryParams) string) string {
+	conditions := []string{
+		attemptInThisSpec,
+		attemptThisWorker,
+		attemptIsPending,
+		"(" + attemptIsExpired(params, now) + " OR (" +
+			workSpecExpireWithWorker + " AND " +
+			workerIsDead(params, now, grace) + "))",
+	}
+	if scope != nil {
+		conditions = append(conditions, scope(params))
+	}
+	return buildSelect([]string{
+		attemptID,
+	}, []string{
+		attemptTable,
+		workSpecTable,
+		workerTable,
+	}, conditions)
+}
+
+// expireAttempts finds all attempts whose expiration time has passed,
+// or whose worker has died if their work spec asks for it, and
+// expires them.  It runs on all attempts for all work units in all
 // work specs in all namespaces (which simplifies the query).  Expired
 // attempts' statuses become "expired", and those attempts cease to be
 // the active attempt for their corresponding work unit.
@@ -74,65 +162,93 @@ func (exp *expiry) Do(c coordinable) {
 // system-global, the other expirer will clean up for us) or there is
 // an operational error (and the caller will fail afterwards).
 func expireAttempts(c coordinable, tx *sql.Tx) error {
-	// There are several places this is called with much smaller
-	// scope.  For instance, Attempt.Status() needs to invoke
-	// expiry but only actually cares about this very specific
-	// attempt.  If there are multiple namespaces,
-	// Worker.RequestAttempts() only cares about this namespace
-	// (though it will run on all work specs).  It may help system
-	// performance to try to run this with narrower scope.
-	//
-	// This is probably also an excellent candidate for a stored
-	// procedure.
-	var (
-		now        time.Time
-		cte, query string
-		count      int64
-		result     sql.Result
-		err        error
-	)
-
-	now = c.Coordinate().clock.Now()
+	return expireScoped(c, tx, 0, 0)
+}
+
+// expireAttemptsForSpec is like expireAttempts, but only expires
+// attempts in a single work spec.  Most read paths only care about
+// one work spec, and this touches (and locks) far fewer rows.
+func expireAttemptsForSpec(spec *workSpec, tx *sql.Tx) error {
+	return expireScoped(spec, tx, 0, spec.id)
+}
+
+// expireAttemptsForNamespace is like expireAttempts, but only
+// expires attempts in work specs in a single namespace.
+func expireAttemptsForNamespace(ns *namespace, tx *sql.Tx) error {
+	return expireScoped(ns, tx, ns.id, 0)
+}
+
+// hasExpireFunction determines whether the database has the
+// coordinate_expire_attempts() stored procedure.  Upgrade() installs
+// it, but a database managed by hand may not have it yet.
+func hasExpireFunction(db *sql.DB) (bool, error) {
+	var exists bool
+	row := db.QueryRow("SELECT EXISTS (SELECT 1 FROM pg_proc WHERE proname='coordinate_expire_attempts')")
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+// expireScoped implements expireAttempts and its scoped variants.  If
+// namespaceID is nonzero, only attempts in that namespace are
+// considered; if specID is nonzero, only attempts in that work spec.
+// If the database has the coordinate_expire_attempts() stored
+// procedure this makes a single call to it, and otherwise it runs the
+// equivalent queries itself.
+func expireScoped(c coordinable, tx *sql.Tx, namespaceID, specID int) error {
+	now := c.Coordinate().clock.Now()
+	grace := c.Coordinate().workerGracePeriod()
+	if c.Coordinate().expireInDB {
+		return expireInDB(tx, now, grace, namespaceID, specID)
+	}
+
+	var scope func(*queryParams) string
+	switch {
+	case specID != 0:
+		scope = func(params *queryParams) string {
+			return isWorkSpec(params, specID)
+		}
+	case namespaceID != 0:
+		scope = func(params *queryParams) string {
+			return workSpecInNamespace(params, namespaceID)
+		}
+	}
 
 	// Remove expiring attempts from their work unit
 	qp := queryParams{}
-	cte = buildSelect([]string{
-		attemptID,
-	}, []string{
-		attemptTable,
-	}, []string{
-		attemptIsPending,
-		attemptIsExpired(&qp, now),
-	})
-	query = buildUpdate(workUnitTable,
-		[]string{"active_attempt_id=NULL"},
-		[]string{"active_attempt_id IN (" + cte + ")"})
-	result, err = tx.Exec(query, qp...)
+	query := buildUpdate(workUnitTable,
+		append([]string{"active_attempt_id=NULL"}, workUnitRetried(&qp, now)...),
+		[]string{"active_attempt_id IN (" + expiringAttempts(&qp, now, grace, scope) + ")"})
+	count, err := releaseWorkUnits(tx, query, qp)
 	if err != nil {
 		return err
 	}
 
 	// If this marked nothing as expired, we're done
-	count, err = result.RowsAffected()
-	if err != nil {
-		return err
-	}
 	if count == 0 {
 		return nil
 	}
 
 	// Mark attempts as expired
 	qp = queryParams{}
-	// A slightly exotic setup, since we want to reuse the "now"
-	// param
-	dollarsNow := qp.Param(now)
 	fields := fieldList{}
-	fields.AddDirect("expiration_time", dollarsNow)
+	fields.Add(&qp, "expiration_time", now)
 	fields.AddDirect("status", "'expired'")
 	query = buildUpdate(attemptTable, fields.UpdateChanges(), []string{
-		attemptIsPending,
-		attemptExpirationTime + "<" + dollarsNow,
+		"id IN (" + expiringAttempts(&qp, now, grace, scope) + ")",
 	})
 	_, err = tx.Exec(query, qp...)
 	return err
 }
+
+// expireInDB runs expiry with the coordinate_expire_attempts() stored
+// procedure, which releases the work units, sends notifications, and
+// marks the attempts expired in one round trip.  A zero namespaceID
+// or specID is passed as NULL, meaning unscoped.
+func expireInDB(tx *sql.Tx, now time.Time, grace time.Duration, namespaceID, specID int) error {
+	scopeID := func(id int) sql.NullInt64 {
+		return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+	}
+	_, err := tx.Exec("SELECT coordinate_expire_attempts($1, $2 * INTERVAL '1 second', $3::INTEGER, $4::INTEGER)",
+		now, grace.Seconds(), scopeID(namespaceID), scopeID(specID))
+	return err
+}
@@ -0,0 +1,137 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package postgres
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/diffeo/go-coordinate/coordinate"
+	"github.com/stretchr/testify/assert"
+)
+
+// makeExpiringAttempt creates a work spec with a single work unit
+// and gives worker a one-minute attempt on it.
+func makeExpiringAttempt(t *testing.T, ns coordinate.Namespace, worker coordinate.Worker, specName string) *attempt {
+	spec, err := ns.SetWorkSpec(map[string]interface{}{"name": specName})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	unit, err := spec.AddWorkUnit("unit", map[string]interface{}{}, coordinate.WorkUnitMeta{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	a, err := worker.MakeAttempt(unit, 1*time.Minute)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return a.(*attempt)
+}
+
+// TestScopedExpiryShared checks that concurrent scoped expiry calls
+// for the same scope share one run, while other scopes run on their
+// own.
+func TestScopedExpiryShared(t *testing.T) {
+	var exp expiry
+	exp.Init()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go exp.doScoped(expiryScope{specID: 1}, func() {
+		close(started)
+		<-release
+	})
+	<-started
+
+	// Another work spec runs right away
+	ran := false
+	exp.doScoped(expiryScope{specID: 2}, func() { ran = true })
+	assert.True(t, ran)
+
+	// The same work spec waits for the first run and does not
+	// run again
+	done := make(chan bool)
+	go func() {
+		ran := false
+		exp.doScoped(expiryScope{specID: 1}, func() { ran = true })
+		done <- ran
+	}()
+	select {
+	case <-done:
+		t.Fatal("second expiry did not wait for the first")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+	assert.False(t, <-done)
+}
+
+// rawAttemptStatus reads an attempt's status straight from the
+// database, without running expiry first.
+func rawAttemptStatus(t *testing.T, a *attempt) string {
+	var status string
+	err := withTx(a, true, func(tx *sql.Tx) error {
+		return tx.QueryRow("SELECT status FROM attempt WHERE id=$1", a.id).Scan(&status)
+	})
+	assert.NoError(t, err)
+	return status
+}
+
+// TestScopedExpiry checks that expiring a single work spec or
+// namespace leaves overdue attempts elsewhere alone, both with the
+// coordinate_expire_attempts() stored procedure and without it.
+func TestScopedExpiry(t *testing.T) {
+	t.Run("Procedure", func(t *testing.T) { testScopedExpiry(t, true) })
+	t.Run("Fallback", func(t *testing.T) { testScopedExpiry(t, false) })
+}
+
+func testScopedExpiry(t *testing.T, inDB bool) {
+	mock := clock.NewMock()
+	c, err := NewWithClock("", mock)
+	if !assert.NoError(t, err) {
+		return
+	}
+	if inDB {
+		assert.True(t, c.(*pgCoordinate).expireInDB)
+	} else {
+		c.(*pgCoordinate).expireInDB = false
+	}
+	var attempts []*attempt
+	var namespaces []*namespace
+	for _, name := range []string{"TestScopedExpiry1", "TestScopedExpiry2"} {
+		ns, err := c.Namespace(name)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer ns.Destroy()
+		namespaces = append(namespaces, ns.(*namespace))
+		worker, err := ns.Worker("worker")
+		if !assert.NoError(t, err) {
+			return
+		}
+		attempts = append(attempts,
+			makeExpiringAttempt(t, ns, worker, "a"),
+			makeExpiringAttempt(t, ns, worker, "b"))
+	}
+	mock.Add(5 * time.Minute)
+
+	err = withTx(c.(*pgCoordinate), false, func(tx *sql.Tx) error {
+		return expireAttemptsForSpec(attempts[0].unit.spec, tx)
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "expired", rawAttemptStatus(t, attempts[0]))
+		for _, a := range attempts[1:] {
+			assert.Equal(t, "pending", rawAttemptStatus(t, a))
+		}
+	}
+
+	err = withTx(c.(*pgCoordinate), false, func(tx *sql.Tx) error {
+		return expireAttemptsForNamespace(namespaces[0], tx)
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "expired", rawAttemptStatus(t, attempts[1]))
+		assert.Equal(t, "pending", rawAttemptStatus(t, attempts[2]))
+		assert.Equal(t, "pending", rawAttemptStatus(t, attempts[3]))
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package postgres
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Labels for contentionRetries.
+const (
+	// contentionTransaction counts transactions that were rolled
+	// back and rerun because of a serialization failure or
+	// deadlock.
+	contentionTransaction = "transaction"
+
+	// contentionAddWorkUnit counts passes through the INSERT or
+	// UPDATE loop in addWorkUnit, beyond the first, because a
+	// concurrent change made both fail.
+	contentionAddWorkUnit = "add_work_unit"
+
+	// contentionRequestAttempts counts times RequestAttempts chose
+	// a work spec but got no attempts from it, usually because
+	// another worker claimed its work units first.
+	contentionRequestAttempts = "request_attempts"
+
+	// contentionConnection counts times withTx waited and tried
+	// again to start a transaction because the server had too
+	// many connections.
+	contentionConnection = "connection"
+)
+
+var contentionRetries = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "coordinate",
+		Subsystem: "postgres",
+		Name:      "contention_retries_total",
+		Help:      "Number of operations retried due to concurrent activity",
+	},
+	[]string{"operation"})
+
+var continuousUnits = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "coordinate",
+		Subsystem: "postgres",
+		Name:      "continuous_units_total",
+		Help:      "Number of work units generated for continuous work specs",
+	},
+	[]string{"namespace", "work_spec"})
+
+func init() {
+	prometheus.MustRegister(contentionRetries)
+	prometheus.MustRegister(continuousUnits)
+}
@@ -0,0 +1,134 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package postgres
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/diffeo/go-coordinate/coordinate"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRequestAttemptsContention checks that a work spec yielding no
+// attempts only counts as contention when another worker got there
+// first, and not when its work unit was out of retries.
+func TestRequestAttemptsContention(t *testing.T) {
+	mock := clock.NewMock()
+	c, err := NewWithClock("", mock)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ns, err := c.Namespace("TestRequestAttemptsContention")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer ns.Destroy()
+
+	spec, err := ns.SetWorkSpec(map[string]interface{}{
+		"name":        "spec",
+		"max_retries": 1,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = spec.AddWorkUnit("unit", map[string]interface{}{}, coordinate.WorkUnitMeta{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	w, err := ns.Worker("worker")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	attempts, err := w.RequestAttempts(coordinate.AttemptRequest{})
+	if assert.NoError(t, err) {
+		assert.Len(t, attempts, 1)
+	}
+	mock.Add(1 * time.Hour)
+
+	// The only work unit is over its retry limit, so the
+	// scheduler picks its work spec but gets nothing back
+	counter := contentionRetries.WithLabelValues(contentionRequestAttempts)
+	before := testutil.ToFloat64(counter)
+	attempts, err = w.RequestAttempts(coordinate.AttemptRequest{})
+	if assert.NoError(t, err) {
+		assert.Empty(t, attempts)
+	}
+	assert.Equal(t, before, testutil.ToFloat64(counter))
+
+	// Rather than depending on the timing of concurrent workers,
+	// take a snapshot of the work spec, let another worker take
+	// its work, and then try to get work from the stale snapshot
+	_, err = spec.AddWorkUnit("raced", map[string]interface{}{}, coordinate.WorkUnitMeta{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	var (
+		specs map[string]*workSpec
+		metas map[string]*coordinate.WorkSpecMeta
+	)
+	err = withTx(c.(*pgCoordinate), true, func(tx *sql.Tx) (err error) {
+		specs, metas, err = ns.(*namespace).allMetas(tx, true)
+		return
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	other, err := ns.Worker("other")
+	if !assert.NoError(t, err) {
+		return
+	}
+	attempts, err = other.RequestAttempts(coordinate.AttemptRequest{})
+	if assert.NoError(t, err) {
+		assert.Len(t, attempts, 1)
+	}
+	lost, failed, err := w.(*worker).requestAttemptsForSpec(coordinate.AttemptRequest{}, specs["spec"], metas["spec"])
+	if assert.NoError(t, err) {
+		assert.Empty(t, lost)
+		assert.Equal(t, 0, failed)
+	}
+}
+
+// TestContinuousUnitsCounted checks that every continuous work unit
+// that RequestAttempts generates is counted against its work spec.
+func TestContinuousUnitsCounted(t *testing.T) {
+	mock := clock.NewMock()
+	c, err := NewWithClock("", mock)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ns, err := c.Namespace("TestContinuousUnitsCounted")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer ns.Destroy()
+
+	_, err = ns.SetWorkSpec(map[string]interface{}{
+		"name":       "spec",
+		"continuous": true,
+		"interval":   60,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	worker, err := ns.Worker("worker")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	counter := continuousUnits.WithLabelValues("TestContinuousUnitsCounted", "spec")
+	before := testutil.ToFloat64(counter)
+	for i := 0; i < 3; i++ {
+		attempts, err := worker.RequestAttempts(coordinate.AttemptRequest{})
+		if assert.NoError(t, err) && assert.Len(t, attempts, 1) {
+			assert.NoError(t, attempts[0].Finish(nil))
+		}
+		mock.Add(1 * time.Minute)
+	}
+	assert.Equal(t, before+3, testutil.ToFloat64(counter))
+}
@@ -16,6 +16,27 @@
 // migrations/20170316-index.sql
 // migrations/20170523-work-unit-max-retries.sql
 // migrations/20170523-work-unit-max-retries.sql~
+// migrations/202610170134-work-spec-expire-with-worker.sql
+// migrations/202610170140-work-spec-max-lease-total.sql
+// migrations/202610170148-work-unit-created-at.sql
+// migrations/202610170153-work-spec-heartbeat-extension.sql
+// migrations/202610170158-attempt-finish-prepared.sql
+// migrations/202610170201-attempt-data-history.sql
+// migrations/202610170223-work-spec-unit-order.sql
+// migrations/202610170231-work-spec-schema-version.sql
+// migrations/202610170254-attempt-archive.sql
+// migrations/202610170258-retry-delays.sql
+// migrations/202610170306-attempt-log.sql
+// migrations/202610170317-work-spec-continuous-paused.sql
+// migrations/202610170356-work-unit-runtime.sql
+// migrations/202610170412-work-unit-available-name.sql
+// migrations/202610170415-work-spec-gated.sql
+// migrations/202610170537-work-spec-default-lease.sql
+// migrations/202610170410-expire-attempts.sql
+// migrations/202610170609-attempt-released.sql
+// migrations/202610170618-attempt-history-unit.sql
+// migrations/202610170627-work-unit-runtime-available.sql
+// migrations/202610170650-work-unit-available-created-at.sql
 // DO NOT EDIT!
 
 package postgres
@@ -403,6 +424,426 @@ func migrations20170523WorkUnitMaxRetriesSql2() (*asset, error) {
 	return a, nil
 }
 
+var _migrations202610170134WorkSpecExpireWithWorkerSql = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\x74\x8d\x51\x0b\x82\x30\x00\x84\xdf\xfd\x15\xf7\x5c\xac\x1f\xa0\x4f\xb3\xcd\xa7\xe5\xc2\xf4\x59\xc4\x2d\x1b\xa9\x5b\xdb\xc2\x7e\x7e\x08\x41\x04\x09\xc7\xc1\xc1\xdd\x7d\x84\x80\xec\x08\x26\xab\x74\x8a\xf0\x18\xb3\xd5\x88\xf3\x56\x3d\xfb\x98\xc2\xd9\x10\x07\xaf\xc3\x5a\x4a\xc8\x2a\x50\xa5\x02\xba\x19\xfa\xe5\x8c\xd7\xed\x62\xe2\xad\x5d\xac\xbf\x6b\x8f\xab\xd1\xa3\x42\xb4\x58\x73\x1b\x9c\xee\x0f\x9f\xd1\x7e\x32\x83\xef\xa2\x46\xe3\x12\x2a\x6a\x5e\xa1\xa6\xb9\xe0\xdf\x22\x28\x63\x38\x4a\xd1\x9c\xca\x7f\xcf\xb9\x94\x82\xd3\x12\xa5\xac\x51\x36\x42\x80\xf1\x82\x36\xa2\x46\x41\xc5\x85\x67\xc9\x0f\x83\xd9\x65\xde\xa0\xb0\x4a\x9e\xb7\x31\x59\xf2\x06\x00\x00\xff\xff\x01\x00\x00\xff\xff\xc1\x90\x88\x00\x0f\x01\x00\x00")
+
+func migrations202610170134WorkSpecExpireWithWorkerSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations202610170134WorkSpecExpireWithWorkerSql,
+		"migrations/202610170134-work-spec-expire-with-worker.sql",
+	)
+}
+
+func migrations202610170134WorkSpecExpireWithWorkerSql() (*asset, error) {
+	bytes, err := migrations202610170134WorkSpecExpireWithWorkerSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/202610170134-work-spec-expire-with-worker.sql", size: 271, mode: os.FileMode(420), modTime: time.Unix(1792200815, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _migrations202610170140WorkSpecMaxLeaseTotalSql = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\x74\x8d\x41\x0b\x82\x30\x00\x85\xef\xfb\x15\xef\x16\x14\x8b\xce\x7a\x5a\xcd\x20\x58\x33\x64\x76\x95\xe1\x96\x48\xda\x96\x5b\xd8\xcf\x2f\x21\x88\x08\xe1\xf1\x4e\xdf\xfb\x1e\xa5\xa0\x4b\x8a\xde\x19\x9b\x20\xdc\xbb\x74\x2a\xea\x07\x67\x1e\x75\x4c\xe0\x5d\x88\xcd\x60\xc3\x04\x11\x3a\x05\xcc\x98\x00\x8d\x5e\x3f\xab\xce\xea\x60\xab\xe8\xa2\xee\x70\x69\x6d\x67\x10\x1d\x46\x37\x5c\xab\xe0\x6d\xbd\xfe\x0c\x56\x7d\xdb\x0c\x3a\x5a\x94\x9e\x30\xa1\xb2\x02\x8a\x6d\x45\xf6\x05\xc1\x38\xc7\x2e\x17\xe5\x51\xfe\x69\x0f\xf2\x3d\x38\x33\x01\x99\x2b\xc8\x52\x08\xf0\x6c\xcf\x4a\xa1\xb0\xd8\x2c\x52\xf2\xe3\xe7\x6e\xbc\xcd\x3c\xf0\x22\x3f\xcd\x5c\xa4\xe4\x05\x00\x00\xff\xff\x01\x00\x00\xff\xff\x7f\xc1\x7d\xe5\x04\x01\x00\x00")
+
+func migrations202610170140WorkSpecMaxLeaseTotalSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations202610170140WorkSpecMaxLeaseTotalSql,
+		"migrations/202610170140-work-spec-max-lease-total.sql",
+	)
+}
+
+func migrations202610170140WorkSpecMaxLeaseTotalSql() (*asset, error) {
+	bytes, err := migrations202610170140WorkSpecMaxLeaseTotalSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/202610170140-work-spec-max-lease-total.sql", size: 260, mode: os.FileMode(420), modTime: time.Unix(1792201153, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _migrations202610170148WorkUnitCreatedAtSql = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\x74\x8e\xd1\x4a\xc3\x30\x14\x86\xef\xfb\x14\xff\xa5\x4e\xb2\x07\x58\xaf\xe2\x92\xe1\x20\x6d\xc7\x4c\x11\xbc\x19\x61\xc9\xba\xb0\x35\xe9\x92\x94\xfa\xf8\x2e\x53\x51\x11\xe1\x70\xe0\x7c\x9c\xf3\x7f\x87\x10\x90\x19\x41\xef\xb5\x59\x20\x5e\xce\x65\x6e\x64\x08\x5e\x8f\xfb\xb4\xc0\xe0\x63\xea\x82\x89\x79\xa9\x20\xb9\x40\xb5\x8e\x50\xd8\x07\xa3\x92\xd1\x3b\x95\x70\xb0\xe6\xac\x91\x3c\x26\x1f\x4e\xbb\xd1\xd9\x34\x07\xf8\x9b\x8d\xc9\xba\xee\x06\x91\xe1\xf5\x2a\x98\x9c\xd0\xab\x70\x32\x1a\x2a\x7e\x85\x60\x3a\x1a\x87\x74\xb4\x11\xbd\xed\x82\x4a\xd6\x3b\x84\xd1\xc5\xf9\xa7\xf3\xe1\x03\x1b\xb4\x43\x41\x85\xe4\x5b\x48\xfa\x28\xf8\xb7\x10\x94\x31\x2c\x1b\xd1\x56\xf5\xcf\xcf\xe4\xba\xe2\xcf\x92\x56\x1b\xbc\xac\xe5\xd3\x6d\xc4\x6b\x53\x73\xd4\x8d\x44\xdd\x0a\x01\xc6\x57\xb4\x15\x12\xce\x4f\x77\xf7\x65\xf1\x4b\xc6\xfc\xe4\xfe\xd1\xb1\x6d\xb3\xf9\xeb\x2b\x8b\x77\x00\x00\x00\xff\xff\x01\x00\x00\xff\xff\x92\xce\xca\x2c\x4f\x01\x00\x00")
+
+func migrations202610170148WorkUnitCreatedAtSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations202610170148WorkUnitCreatedAtSql,
+		"migrations/202610170148-work-unit-created-at.sql",
+	)
+}
+
+func migrations202610170148WorkUnitCreatedAtSql() (*asset, error) {
+	bytes, err := migrations202610170148WorkUnitCreatedAtSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/202610170148-work-unit-created-at.sql", size: 335, mode: os.FileMode(420), modTime: time.Unix(1792201681, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _migrations202610170153WorkSpecHeartbeatExtensionSql = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\x7c\x8d\xc1\x0a\x82\x40\x18\x84\xef\x3e\xc5\xdc\x84\x62\xa3\xb3\x9e\xb6\xd6\x20\xd8\x34\x44\xbb\x8a\xb9\x7f\x26\xa9\x6b\xbb\x1b\xf6\xf8\x25\x04\x11\x54\x30\xcc\x69\xbe\xf9\x18\x03\x9b\x31\x74\x5a\x51\x00\x7b\x6d\xc3\xa9\xd8\x60\xb4\xba\x55\x2e\xc0\xa0\xad\xab\x0d\xd9\x69\xe4\xb1\x29\xe0\x4a\x59\x94\x38\x53\x69\xdc\x91\x4a\x57\xd0\xdd\x51\x6f\x1b\xdd\xe3\xd4\x50\xab\xe0\x34\x46\x6d\x2e\x85\x1d\xa8\x5a\xbc\xa0\x79\xd7\xd4\xa6\x74\x84\x7c\xf0\xb8\xcc\xa2\x14\x19\x5f\xc9\xe8\x3d\x04\x17\x02\xeb\x44\xe6\xbb\xf8\xeb\xf5\x36\x7e\x42\x07\x2e\x11\x27\x19\xe2\x5c\x4a\x88\x68\xc3\x73\x99\xc1\x5f\xfa\xa1\xf7\xe1\x10\x7a\xec\x7f\x58\x44\x9a\xec\xff\x68\x42\xef\x01\x00\x00\xff\xff\x01\x00\x00\xff\xff\x99\x68\x42\x41\x10\x01\x00\x00")
+
+func migrations202610170153WorkSpecHeartbeatExtensionSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations202610170153WorkSpecHeartbeatExtensionSql,
+		"migrations/202610170153-work-spec-heartbeat-extension.sql",
+	)
+}
+
+func migrations202610170153WorkSpecHeartbeatExtensionSql() (*asset, error) {
+	bytes, err := migrations202610170153WorkSpecHeartbeatExtensionSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/202610170153-work-spec-heartbeat-extension.sql", size: 272, mode: os.FileMode(420), modTime: time.Unix(1792201964, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _migrations202610170158AttemptFinishPreparedSql = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\x74\x8e\x41\x6e\xc2\x30\x10\x45\xf7\x39\xc5\x5f\x16\x5a\x73\x00\xb2\x32\xb5\xb3\x32\x31\xa2\xc9\xba\x32\xf5\x24\x44\x22\xd8\xd8\x83\x10\xb7\xaf\x02\xb4\x52\xd5\x56\x1a\xcd\x62\xf4\xdf\x7f\x23\x04\xc4\x5c\x60\x0c\x9e\x96\xc8\xa7\x43\x39\x2d\x11\x53\xf0\xe7\x0f\x5e\x22\x86\xcc\x7d\xa2\x3c\x85\x0a\x31\x0d\xa4\xf7\x19\x0e\xdd\x70\x1c\xf2\xfe\x3d\x26\x8a\x2e\x91\x47\x77\x70\x3d\x38\xc0\x31\xd3\x18\xf9\x05\x99\x18\xbb\xeb\x8d\xb8\x9f\x16\x9b\x7b\xb6\xba\x91\x4f\x33\xec\xa8\x0b\x89\xc0\x7b\xfa\xa2\x30\xe4\x47\x31\xf9\xc5\xc3\xf7\x3c\x0e\x7d\x72\x4c\x68\x63\x21\x4d\xa3\xb7\x68\xe4\xca\xe8\x6f\x44\x2a\x85\x57\x6b\xda\x75\xfd\xeb\xa7\x95\xb5\x46\xcb\x1a\xb5\x6d\x50\xb7\xc6\x40\xe9\x4a\xb6\xa6\x41\x25\xcd\x9b\x2e\x8b\x1f\xf5\x2a\x5c\x8e\x7f\x0a\xd4\xd6\x6e\xfe\x31\x94\xc5\x27\x00\x00\x00\xff\xff\x01\x00\x00\xff\xff\x2e\x07\xf9\xf9\x40\x01\x00\x00")
+
+func migrations202610170158AttemptFinishPreparedSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations202610170158AttemptFinishPreparedSql,
+		"migrations/202610170158-attempt-finish-prepared.sql",
+	)
+}
+
+func migrations202610170158AttemptFinishPreparedSql() (*asset, error) {
+	bytes, err := migrations202610170158AttemptFinishPreparedSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/202610170158-attempt-finish-prepared.sql", size: 320, mode: os.FileMode(420), modTime: time.Unix(1792202241, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _migrations202610170201AttemptDataHistorySql = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\x7c\x51\x41\x6e\xc2\x30\x10\xbc\xe7\x15\x73\x84\xb6\xe1\x01\x70\x32\x64\xdb\x46\x0d\x01\x05\xa3\x96\x5e\x90\x8b\x9d\xc4\x6a\x12\xa7\x8e\x11\xe2\xf7\x4d\x28\x81\x56\x42\x5d\xad\x2c\x59\x9e\x99\x9d\x1d\xfb\x3e\xfc\x3b\x1f\xa5\x91\x6a\x8c\xe6\xab\x98\x74\x87\x5f\x5b\x23\xf7\x3b\x37\x46\x6d\x1a\x97\x59\xd5\x74\x20\xcf\xef\x1a\x4c\xca\x06\x02\x4e\x7c\x14\x0a\xb9\x29\xa4\xae\x32\x34\x95\xa8\x9b\xdc\xb8\x06\x26\x85\x70\x4e\x95\xb5\x83\x14\x4e\x20\xb5\xa6\x84\x12\xbb\x1c\x56\x55\xea\x20\x8a\x51\x27\xc2\x73\xdd\xa0\x6d\x53\x15\x47\x1c\xac\x6e\x19\x15\x74\x0a\x97\x2b\xec\x8c\xb1\xad\xa8\x70\xaa\x43\xec\x4c\x95\xea\x6c\x6f\x95\x84\x33\xf8\x54\xaa\x3e\xe9\x76\x22\xad\x86\x33\xf6\x38\x3a\x1b\xbb\x2f\x75\x66\x3b\xd6\xba\xf6\x66\x09\x31\x4e\xe0\x6c\x1a\x51\xef\x67\xdb\xf1\xb6\x67\xd2\xc0\xc3\x4f\x69\x89\x15\x25\x21\x8b\xb0\x4c\xc2\x39\x4b\x36\x78\xa1\xcd\x43\xff\xda\x53\x5b\x54\x18\x73\x7a\xa2\x04\xf1\x82\x23\x5e\x47\x51\x0f\xf9\x55\x09\x3d\x52\x42\xf1\x8c\x56\x3d\x71\xa0\xe5\x10\x8b\x18\x01\x45\xd4\xfa\x99\xb1\xd5\x8c\x05\x74\x91\x77\xba\x54\xe0\xe1\x9c\x56\x9c\xcd\x97\x78\x0d\xf9\xf3\xe9\x8a\xf7\x45\x4c\x97\x49\x17\xf8\x29\xd0\xe9\x86\x13\xbb\xba\x18\x4e\xfa\x65\xc3\x38\xa0\xb7\x9b\xcb\x6e\xfb\x1f\x69\x9d\xdc\x0c\xe3\xba\x66\x2b\xf7\x27\xcb\xc0\x1c\x2a\x2f\x48\x16\xcb\x7f\xb2\x9c\x78\xdf\x00\x00\x00\xff\xff\x01\x00\x00\xff\xff\x3e\x05\x14\x08\x46\x02\x00\x00")
+
+func migrations202610170201AttemptDataHistorySqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations202610170201AttemptDataHistorySql,
+		"migrations/202610170201-attempt-data-history.sql",
+	)
+}
+
+func migrations202610170201AttemptDataHistorySql() (*asset, error) {
+	bytes, err := migrations202610170201AttemptDataHistorySqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/202610170201-attempt-data-history.sql", size: 582, mode: os.FileMode(420), modTime: time.Unix(1792202411, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _migrations202610170223WorkSpecUnitOrderSql = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\x74\x8d\xd1\x0a\x82\x30\x00\x45\xdf\xfd\x8a\xfb\x26\x14\xeb\x03\xf4\x69\x39\xa3\x87\xa5\x21\xb3\x57\x11\xb7\x64\xa4\x6e\x6d\x13\xe9\xef\x43\x08\x2a\x22\xb8\xdc\xa7\xc3\x39\x84\x80\x6c\x08\x46\x23\x55\x02\x7f\x1f\xd2\xf5\x88\x75\x46\xce\x5d\x48\x60\x8d\x0f\xbd\x53\x7e\x85\x22\xb2\x0e\x54\x4a\x8f\x16\xf3\xa4\x43\x63\x9c\x54\x0e\x57\xad\x06\x89\x60\xb0\x18\x77\x6b\xbc\x55\xdd\xee\xc5\x6e\x47\xdd\xbb\x36\x28\xd4\x36\xa2\x5c\xe4\x15\x04\xdd\xf3\xfc\x0d\x82\x32\x86\xac\xe4\xf5\xa9\xf8\x34\x5e\x68\x95\x1d\x69\x85\xa2\x14\x28\x6a\xce\xc1\xf2\x03\xad\xb9\x40\x6c\x9d\x36\x4e\x87\x47\x9c\x46\x5f\x01\x66\x96\xe9\x4f\x82\x55\xe5\xf9\xb7\x91\x46\x4f\x00\x00\x00\xff\xff\x01\x00\x00\xff\xff\x5b\xe6\xa1\x04\xfb\x00\x00\x00")
+
+func migrations202610170223WorkSpecUnitOrderSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations202610170223WorkSpecUnitOrderSql,
+		"migrations/202610170223-work-spec-unit-order.sql",
+	)
+}
+
+func migrations202610170223WorkSpecUnitOrderSql() (*asset, error) {
+	bytes, err := migrations202610170223WorkSpecUnitOrderSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/202610170223-work-spec-unit-order.sql", size: 251, mode: os.FileMode(420), modTime: time.Unix(1792203734, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _migrations202610170231WorkSpecSchemaVersionSql = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\x74\xcc\x41\x0b\x82\x30\x18\xc6\xf1\xbb\x9f\xe2\x39\x17\x8b\xce\x7a\x5a\xcd\x22\x58\x33\x64\x3b\x87\xb8\x65\x92\xba\xb5\xad\xfc\xfa\x25\x04\x51\x14\xbc\xbc\xa7\xff\xf3\x23\x04\x64\x46\xd0\x5b\x6d\x52\x84\x6b\x97\x4d\x8f\x38\x6f\xf5\xad\x8e\x29\x9c\x0d\xb1\xf1\x26\x4c\x51\x42\xa6\x03\xd5\x3a\xa0\x42\xa8\xcf\xa6\xaf\x8e\x77\xe3\x43\x6b\x07\x9c\x5a\xd3\x69\x44\x8b\xd1\xfa\xcb\x31\x38\x53\x2f\x5e\xfd\xbc\x6f\x1b\x5f\x45\x03\xe5\x12\xca\x65\x5e\x42\xd2\x15\xcf\xdf\x21\x28\x63\x58\x17\x5c\xed\xc5\xb7\xba\x13\x32\xdf\x3e\x17\xa2\x90\x10\x8a\x73\xb0\x7c\x43\x15\x97\x58\x66\xc9\x87\xcd\xec\x38\xfc\xd1\x59\x59\x1c\x7e\xf3\x59\xf2\x00\x00\x00\xff\xff\x01\x00\x00\xff\xff\x7b\xe5\xae\xec\xfe\x00\x00\x00")
+
+func migrations202610170231WorkSpecSchemaVersionSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations202610170231WorkSpecSchemaVersionSql,
+		"migrations/202610170231-work-spec-schema-version.sql",
+	)
+}
+
+func migrations202610170231WorkSpecSchemaVersionSql() (*asset, error) {
+	bytes, err := migrations202610170231WorkSpecSchemaVersionSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/202610170231-work-spec-schema-version.sql", size: 254, mode: os.FileMode(420), modTime: time.Unix(1792204238, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _migrations202610170254AttemptArchiveSql = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\x9c\x52\xcd\x72\x9b\x30\x10\xbe\xf3\x14\x7b\x4c\xda\x90\x07\x88\x4f\xb2\xbd\x6e\x99\x62\xf0\x60\x79\x5a\xf7\xc2\x28\x48\x36\x9a\x02\x22\x92\xb0\xeb\xb7\xaf\x94\x00\xb1\xdd\x4e\x9a\x56\xa3\x41\xb3\xec\xf7\x23\x96\x2f\x0c\x21\xfc\x10\x42\xad\xb8\x78\x00\xf3\x54\x4d\xfc\x23\x6c\xb5\xe2\x5d\x61\x1f\xa0\x55\xc6\xee\xb5\x30\x1e\x14\x84\x7e\x03\xe1\xdc\x00\x03\xcb\x1e\x2b\x01\xa5\xaa\xb8\x6c\xf6\xe0\x0e\x28\x54\xdd\x56\xc2\x0a\x0e\xcc\x5a\x51\xb7\xd6\xdc\x39\xdd\x83\xab\x55\x67\x41\xed\xc0\x96\xc2\x0b\xf4\xdd\x5e\xc1\x28\x10\x07\xa1\x4f\x9c\x9d\xe0\xa9\x13\x5a\x3a\x33\x07\xd4\xae\x63\xdd\x2b\x53\xb3\xaa\xba\x07\xa0\xa5\x34\xe0\xb6\x6a\xaa\x93\x17\x39\x6a\xe9\x64\x1a\x90\xcf\xb2\xce\x5b\x69\x77\x11\x66\x85\x07\x15\xaa\xd9\xc9\x7d\xa7\x9d\xb5\x55\xc0\x74\x51\xca\x83\x18\x6f\x75\xdf\x7f\xc8\xc7\x5a\xee\xb5\x67\x6c\xda\x60\x96\x21\xa1\x08\x94\x4c\x63\x1c\x80\x79\x4f\xbc\x09\xe0\x65\x49\x0e\x51\x42\xf1\x13\x66\xb0\xca\xa2\x25\xc9\xb6\xf0\x05\xb7\x77\x43\xfb\xa8\xf4\x8f\xbc\x6b\xa4\xcd\xcf\x80\x49\x4a\x21\xd9\xc4\xf1\x00\xba\x58\x19\x2e\x30\xc3\x64\x86\xeb\x57\xf2\x8d\xe4\xb7\x90\x26\x30\xc7\x18\xdd\x8d\x66\x64\x3d\x23\x73\xbc\x34\x31\xad\x28\xfe\xdb\xc4\x93\xdf\x61\x22\xf4\xfb\x1c\xae\xe4\x85\xfe\x8b\xb6\xfb\xab\xb6\x33\xe3\x8c\xfb\x72\xd0\x1f\x61\x9c\x59\x06\xd3\x2d\x45\x72\xce\xd4\x36\xb7\xb2\x16\x40\xa3\x25\xae\x29\x59\xae\xe0\x6b\x44\x3f\x3f\x97\xf0\x3d\x4d\xf0\x77\x1d\xd1\xf0\xb7\x29\xaf\xc8\x9f\xad\x74\x71\x90\xaa\xf9\x57\x8f\x9d\x6c\xa4\x29\xf3\x56\x8b\x96\xf9\xcc\x4d\xd3\x34\x46\x92\x8c\x40\x37\x8a\x05\xd9\xc4\x14\x16\x24\x5e\x63\x70\x3b\x19\xe2\x16\x25\x73\xfc\x76\x1d\xb7\x7c\x8c\x82\x9f\xe2\x75\x16\xcf\x43\xe6\x84\x2e\x72\x3c\x57\xc7\x26\x98\x67\xe9\xea\xcf\x39\x9e\x04\xbf\x00\x00\x00\xff\xff\x01\x00\x00\xff\xff\x60\x00\xd9\x84\xed\x03\x00\x00")
+
+func migrations202610170254AttemptArchiveSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations202610170254AttemptArchiveSql,
+		"migrations/202610170254-attempt-archive.sql",
+	)
+}
+
+func migrations202610170254AttemptArchiveSql() (*asset, error) {
+	bytes, err := migrations202610170254AttemptArchiveSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/202610170254-attempt-archive.sql", size: 1005, mode: os.FileMode(420), modTime: time.Unix(1792205496, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _migrations202610170258RetryDelaysSql = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\x74\xcf\x51\x4b\xc3\x30\x14\x05\xe0\xf7\xfe\x8a\xf3\x36\xd0\x45\x7c\x5e\x9e\xea\xd2\x8d\x42\x4c\x47\x6d\x9f\x44\x46\x49\xe2\x56\xec\x92\x9a\xa4\x8c\x21\xfe\x77\x1b\x71\xc8\x3a\x06\xe1\x3e\x9d\xfb\xe5\x5c\x42\x40\xee\x08\x0e\x56\xe9\x05\xfc\x67\x47\xe3\x20\xbd\xb3\x6a\x90\x61\x81\xde\xfa\xb0\x73\xda\xc7\x50\x42\xe2\x43\xaa\x94\x47\x03\xa7\x83\x3b\x6d\x95\xee\x9a\x93\x87\x97\x7b\xad\x86\x4e\x23\x58\x1c\xad\xfb\xd8\xfa\x5e\xcb\x39\x5a\x03\xaf\xa5\x35\xca\xcf\xd1\x18\x35\x6e\x49\x3b\x98\x10\x15\xfb\xfe\x2b\xb4\x23\x7d\xde\x19\x4c\x1b\x1e\xfe\x3e\xb9\x3f\xb4\x3b\xd7\x04\x8d\xba\x4f\x52\x5e\x65\x25\xaa\xf4\x89\x67\xff\x38\x52\xc6\xb0\x2c\x78\xfd\x2c\x2e\xab\xb0\xa2\x8e\xc1\x4d\x99\x2d\xf3\x97\xbc\x10\xaf\x6f\x10\x45\x05\x51\x73\x0e\x96\xad\xd2\x9a\x57\x98\x7d\x7d\xcf\xe8\xb5\x1b\x0b\x4c\xdd\x58\x30\x17\x55\xb6\x1e\xa3\x57\xce\x23\x4d\x2e\xca\x32\x7b\x34\x37\x58\x56\x16\x9b\x89\x4b\x6f\x5c\x36\x8d\x9e\x4f\xa3\xc9\x0f\x00\x00\x00\xff\xff\x01\x00\x00\xff\xff\x89\x14\x98\x59\xad\x01\x00\x00")
+
+func migrations202610170258RetryDelaysSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations202610170258RetryDelaysSql,
+		"migrations/202610170258-retry-delays.sql",
+	)
+}
+
+func migrations202610170258RetryDelaysSql() (*asset, error) {
+	bytes, err := migrations202610170258RetryDelaysSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/202610170258-retry-delays.sql", size: 429, mode: os.FileMode(420), modTime: time.Unix(1792205836, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _migrations202610170306AttemptLogSql = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\x64\x50\xdd\x6e\x82\x30\x14\xbe\xe7\x29\xbe\x4b\xdd\x86\x0f\xa0\x57\x1d\x9c\x2d\x64\x0c\x4c\xc1\x44\xaf\x0c\xda\x8a\x64\xd8\xb2\x52\xb2\xf8\xf6\x2b\x99\x9d\x2c\x3b\x69\x7a\x73\xbe\xdf\x13\x86\x08\x1f\x42\x5c\xb4\x90\x4b\xf4\x9f\xed\x6a\xfc\xc2\xce\x68\x31\x1c\xed\x12\x9d\xee\x6d\x6d\x64\x3f\x82\x82\x70\x7c\x60\x42\xf4\xa8\x60\xab\x43\x2b\x71\xd6\xad\x68\x54\x8d\xb6\x51\x0e\xa4\x4f\xd0\x83\xed\x06\x8b\x56\xd7\xb5\x14\x38\x5c\x61\x06\xa5\x46\x44\x65\xad\xbc\x74\xb6\x5f\x8c\x1a\xb9\x6a\xaf\xb0\x67\xe9\x7c\x7b\x0b\x23\x8f\x52\xd9\x9b\xc6\x49\x1b\xc8\xea\x78\xf6\x04\x54\x46\xe2\x43\x76\x76\x71\xf3\x7f\xbc\x34\xb5\xa9\xac\xc4\xa6\x0b\x22\x4e\xac\x24\x94\xec\x39\x25\x4f\xd8\x3b\xef\x59\x80\x9f\x69\x04\x0a\xe2\x09\x4b\xb1\xe6\xc9\x3b\xe3\x3b\xbc\xd1\xee\xc9\x6f\x3d\xc3\xa1\x92\xac\xa4\x57\xe2\xc8\xf2\x12\xd9\x26\x4d\x3d\x64\x32\x9c\x5e\x88\x53\x16\x51\xe1\x89\xb3\x46\xcc\x91\x67\x88\x29\x25\x17\x23\x62\x45\xc4\x62\xfa\x95\x1f\x0b\xa1\xa4\x6d\x79\x57\x9d\xaf\x7c\xe6\x24\x8b\x69\x3b\xcd\xbc\xf7\x85\x9d\xe0\xb4\xca\x3d\xa4\x23\xff\x39\x40\xac\xbf\x54\x10\xf3\x7c\xfd\xff\x00\xab\xe0\x1b\x00\x00\xff\xff\x01\x00\x00\xff\xff\xed\x17\x8a\x51\xd9\x01\x00\x00")
+
+func migrations202610170306AttemptLogSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations202610170306AttemptLogSql,
+		"migrations/202610170306-attempt-log.sql",
+	)
+}
+
+func migrations202610170306AttemptLogSql() (*asset, error) {
+	bytes, err := migrations202610170306AttemptLogSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/202610170306-attempt-log.sql", size: 473, mode: os.FileMode(420), modTime: time.Unix(1792206230, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _migrations202610170317WorkSpecContinuousPausedSql = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\x74\x8e\xc1\x4e\xc3\x30\x10\x44\xef\xf9\x8a\xb9\x21\x81\xcc\x07\x34\x27\x97\xa4\x27\x93\xa0\x92\x9c\x2b\x2b\x31\x89\xd5\xd4\x6b\xb2\x6b\xe5\xf7\xa9\x01\x89\x72\xa8\xb4\x9a\xcb\xce\xcc\x1b\xa5\xa0\x1e\x15\x2e\x34\xba\x1d\xf8\x73\x29\xb3\xa8\xb8\xd2\x98\x06\xd9\x21\x12\xcb\xb4\x3a\xce\xa6\x42\xe5\x83\x1e\x47\x86\xc5\xc7\x62\x27\x08\x61\xa3\xf5\x7c\xe2\xe8\x06\xc8\x6c\x05\x2c\x14\x19\x03\x05\xf1\x21\x51\xe2\xef\x3f\x52\xf0\x92\xb3\x93\x0b\x6e\xb5\xe2\x29\x60\xf3\x32\x53\x12\x44\x9b\xd8\x87\x6b\xd5\xec\x7e\xbc\xb9\xeb\x81\xb1\x3a\xbb\xfc\x85\xf9\xf9\x97\xfe\x74\xf1\xd3\xb5\xc1\xa1\x8f\x85\x36\x5d\x7d\x44\xa7\xf7\xa6\xbe\x99\xa1\xab\x0a\x2f\xad\xe9\x5f\x9b\x9b\x19\xa7\x8c\x71\x23\xf6\x6d\x6b\x6a\xdd\xa0\x69\x3b\x34\xbd\x31\xa8\xea\x83\xee\x4d\x87\x83\x36\xef\x75\x59\xfc\x43\x54\xb4\x85\x3b\x90\xea\xd8\xbe\xdd\xa5\x94\xc5\x17\x00\x00\x00\xff\xff\x01\x00\x00\xff\xff\x1f\x77\x69\x1c\x56\x01\x00\x00")
+
+func migrations202610170317WorkSpecContinuousPausedSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations202610170317WorkSpecContinuousPausedSql,
+		"migrations/202610170317-work-spec-continuous-paused.sql",
+	)
+}
+
+func migrations202610170317WorkSpecContinuousPausedSql() (*asset, error) {
+	bytes, err := migrations202610170317WorkSpecContinuousPausedSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/202610170317-work-spec-continuous-paused.sql", size: 342, mode: os.FileMode(420), modTime: time.Unix(1792206979, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _migrations202610170356WorkUnitRuntimeSql = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\x74\xcd\xcd\x4a\xc3\x40\x14\x05\xe0\x7d\x9e\xe2\xd0\x4d\x40\x9d\x3e\x40\xb3\x1a\x9b\x88\x8b\x31\x91\x30\x71\x2b\x31\x33\x4d\x87\x66\x7e\x9c\xb9\xb1\xf8\xf6\x26\xa5\x14\x14\x84\xcb\x59\x9d\xfb\x1d\xc6\xc0\xee\x18\xac\x57\x7a\x87\xf4\x39\x15\x6b\xb0\x10\xbd\x9a\x07\xda\x21\xf8\x44\x63\xd4\x69\x2d\x65\x6c\x3d\xc8\xa3\x49\xe8\x95\x5a\x02\x9b\x38\x3b\x32\x56\x6f\x30\xf8\x69\xb6\x0e\xe4\x41\x47\x8d\xb3\x8f\x27\xcc\xce\x10\xa8\xff\x98\xf4\x03\xfc\x97\x8e\xd1\x28\xe3\xc6\x95\xb8\x55\x52\xd0\x43\x9e\x70\x55\x60\x0e\x58\x5e\x16\xde\x79\x82\xb6\x81\xbe\xb7\xd7\xd1\x7b\x6b\xc6\xd8\x93\x46\x17\x32\x2e\x64\xd5\x42\xf2\x47\x51\x5d\x94\xf7\xcb\x10\x2f\x4b\xec\x1b\xd1\xbd\xd4\x37\xee\x8d\xb7\xfb\x67\xde\xa2\x6e\x24\xea\x4e\x08\x94\xd5\x13\xef\x84\x44\x9e\x17\xd9\x2f\xb5\xf4\x67\xf7\x8f\x5b\xb6\xcd\xeb\x1f\xb8\xc8\x7e\x00\x00\x00\xff\xff\x01\x00\x00\xff\xff\x9b\xc4\xf6\xdf\x36\x01\x00\x00")
+
+func migrations202610170356WorkUnitRuntimeSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations202610170356WorkUnitRuntimeSql,
+		"migrations/202610170356-work-unit-runtime.sql",
+	)
+}
+
+func migrations202610170356WorkUnitRuntimeSql() (*asset, error) {
+	bytes, err := migrations202610170356WorkUnitRuntimeSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/202610170356-work-unit-runtime.sql", size: 310, mode: os.FileMode(420), modTime: time.Unix(1792209244, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _migrations202610170412WorkUnitAvailableNameSql = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\xac\x91\xdd\x6e\x82\x40\x10\x85\xef\x79\x8a\xb9\xd4\x56\x7c\x00\xb9\x6a\x2a\x49\x4d\x88\x5a\x7f\xd2\xde\x91\x2d\x3b\xc0\x56\xd8\xa5\xbb\x8b\xe8\xdb\x77\x67\xb1\x6a\x93\x9a\xf6\xa2\x84\x90\x85\x39\x73\xe6\x3b\x43\x18\x42\x78\x17\x42\xad\x38\x4e\xc0\x7c\x54\x11\x3d\xc2\x46\x2b\xde\x66\x76\x02\x8d\x32\xb6\xd0\x68\x48\x14\x84\x74\xc3\xa6\x14\x06\x3a\xc1\x51\x1a\xb0\x25\x82\x90\x1c\x0f\xa0\x72\x60\x7b\x26\x2a\xf6\x56\x21\x74\x4a\xef\xa0\x95\xc2\x3a\x85\x72\x82\xac\x6a\x39\x7a\x31\x55\xc8\x84\x8a\x20\x59\x8d\x63\x80\x44\x18\x2b\x64\xf1\x73\xbf\x90\xc0\xfa\x77\xd3\x60\x06\x85\x42\xfa\x46\x16\xd4\x0d\x4a\x73\xd4\x23\x60\x92\x3b\x24\x5b\xba\x19\xc2\x9c\x88\x96\x3d\xfa\xfa\x39\x81\x8c\x49\xe8\x58\xb5\x83\xf7\xd6\x58\xe2\xa0\xfe\x1b\xe3\x8c\x45\xc6\x29\x0e\xee\x51\x1f\x2f\xb5\x33\x7e\x0f\x52\x32\x43\x26\x24\x72\x67\xee\x62\x6c\xa8\xee\xd6\xa2\x4f\xf3\x39\xa1\x7a\x13\x07\xe5\xe2\x51\xbf\xaa\x9c\xb5\x44\xe0\x82\x8f\xc0\x28\x72\xb8\xfe\xec\xd3\xb1\x8e\x1d\x9d\xdd\x60\x89\x92\xbb\xbe\x11\xe4\x42\x0a\x53\x22\xef\x63\xe6\x8e\x1a\xf9\x15\xb3\xcf\xa2\x11\x72\xd5\xba\xb2\x2d\xb5\x6a\x8b\xd2\xdb\xe2\xe1\xb4\x59\x12\xa7\x24\x4e\x99\xb5\x58\x37\xb6\x47\x1c\x0f\x4f\xbf\xf4\xbe\x16\x85\x66\x16\x61\xdb\x04\x8f\xab\xf8\x61\x13\xc3\x6c\x3e\x8d\x5f\xaf\x1a\x29\x74\x7a\x5e\x59\xea\x97\xbf\x98\x5f\x04\x03\x7f\xf2\x2a\xca\x46\xf5\x61\x00\xfd\xf5\xf2\x14\xaf\x62\x60\x99\x15\x7b\xfc\x22\x70\x2a\x98\xad\x61\xbe\x4d\x92\x28\x98\xae\x16\xcb\x5f\x26\x46\xc1\x37\xd0\xa9\xea\xe4\xdf\x50\x6f\x53\xfe\x2b\x9f\xdf\x48\x14\x7c\x02\x00\x00\xff\xff\x01\x00\x00\xff\xff\x25\xc8\xe5\x22\x4f\x03\x00\x00")
+
+func migrations202610170412WorkUnitAvailableNameSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations202610170412WorkUnitAvailableNameSql,
+		"migrations/202610170412-work-unit-available-name.sql",
+	)
+}
+
+func migrations202610170412WorkUnitAvailableNameSql() (*asset, error) {
+	bytes, err := migrations202610170412WorkUnitAvailableNameSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/202610170412-work-unit-available-name.sql", size: 847, mode: os.FileMode(420), modTime: time.Unix(1792210279, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _migrations202610170415WorkSpecGatedSql = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\x74\xce\xc1\x4e\xc3\x30\x0c\x06\xe0\x7b\x9f\xe2\x3f\x03\xe1\x01\xd6\x53\x46\xba\x53\x68\xd0\x68\xcf\x28\x6a\xbc\x6e\x22\xad\x43\x92\x6a\xf0\xf6\x24\x88\x03\x3b\x20\x59\x3e\x58\xbf\x3f\x5b\x08\x88\x3b\x81\x85\x1d\xed\x90\x3e\x7c\x5b\x9b\x08\x91\xdd\x36\xe5\x1d\x02\xa7\x3c\x47\x4a\x35\xd4\x88\x5a\x90\xce\x25\x58\x9c\xbc\x9d\x91\x19\x57\x8e\xef\x6f\x29\xd0\x84\x7c\xb6\x19\x76\x05\x7d\x66\x8a\xab\xf5\x98\x78\xcd\x91\xbd\xa7\x88\xa9\xcc\x13\xe5\xb2\x50\x89\x94\x39\x20\x4d\x67\x72\x9b\xbf\xac\x73\xd1\xaa\x82\xaa\x3c\x94\x58\xb0\xd1\x66\xf2\x5f\x38\x45\x5e\x0a\x4b\x08\x76\x4b\xe4\x7e\x6e\x3e\xfe\xbe\x71\xbf\x5c\xe6\x1a\xc3\x18\x1a\xa9\x87\xee\x88\x41\xee\x75\xf7\xe7\x1f\xa9\x14\x9e\x8c\x1e\x9f\x7b\xcc\x25\xe8\xb0\x37\x46\x77\xb2\x47\x6f\x06\xf4\xa3\xd6\x50\xdd\x41\x8e\x7a\xc0\x41\xea\xd7\xae\x6d\x6e\x58\xc5\xd7\xf5\x1f\x58\x1d\xcd\xcb\x8d\xdc\x36\xdf\x00\x00\x00\xff\xff\x01\x00\x00\xff\xff\xcf\x14\x8d\x36\x47\x01\x00\x00")
+
+func migrations202610170415WorkSpecGatedSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations202610170415WorkSpecGatedSql,
+		"migrations/202610170415-work-spec-gated.sql",
+	)
+}
+
+func migrations202610170415WorkSpecGatedSql() (*asset, error) {
+	bytes, err := migrations202610170415WorkSpecGatedSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/202610170415-work-spec-gated.sql", size: 327, mode: os.FileMode(420), modTime: time.Unix(1792210413, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _migrations202610170537WorkSpecDefaultLeaseSql = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\x74\xcd\x41\x0b\x82\x30\x18\xc6\xf1\xbb\x9f\xe2\xb9\x09\xc5\xa2\xb3\x9e\x56\x33\x08\xd6\x0c\x71\x5d\x65\xb8\x29\x92\xb6\xe5\x26\x7e\xfd\x12\x82\x28\x0a\x5e\x9e\xd3\x8f\xff\x4b\x08\xc8\x8a\x60\xb0\xda\x24\xf0\xf7\x3e\x5d\x86\xb8\xd1\xea\xa9\x0e\x09\x9c\xf5\xa1\x1d\x8d\x5f\x50\x44\x96\x03\xd5\xda\x43\x41\x9b\x46\x4d\x7d\xa8\x7a\xa3\xbc\x41\xd3\x99\x5e\x23\x58\xcc\x76\xbc\x56\xde\x99\x7a\xf3\xe2\xeb\xa1\x6b\x47\x15\x0c\xa4\x8b\x28\x2f\xb3\x02\x25\xdd\xf1\xec\x0d\x41\x19\xc3\x3e\xe7\xf2\x24\xbe\xa2\x47\xf1\xe4\x17\xca\x21\xf2\x12\x42\x72\x0e\x96\x1d\xa8\xe4\x25\xe2\x6d\x9c\x46\x1f\x75\x66\xe7\xdb\x9f\x3e\x2b\xf2\xf3\xcf\x07\x69\xf4\x00\x00\x00\xff\xff\x01\x00\x00\xff\xff\xf9\x00\x62\x00\xfe\x00\x00\x00")
+
+func migrations202610170537WorkSpecDefaultLeaseSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations202610170537WorkSpecDefaultLeaseSql,
+		"migrations/202610170537-work-spec-default-lease.sql",
+	)
+}
+
+func migrations202610170537WorkSpecDefaultLeaseSql() (*asset, error) {
+	bytes, err := migrations202610170537WorkSpecDefaultLeaseSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/202610170537-work-spec-default-lease.sql", size: 254, mode: os.FileMode(420), modTime: time.Unix(1792215278, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _migrations202610170410ExpireAttemptsSql = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\x94\x56\x5d\x6f\xa3\x38\x14\x7d\xe7\x57\xdc\x87\x4a\x09\xd3\x24\x6a\x5f\x9b\xcd\x4a\x4c\xe2\xa4\x48\x29\x54\x40\xb6\xda\x1d\x8d\x10\x13\x9c\x14\x2d\x31\x2c\x38\xd3\x56\x9a\x1f\xbf\xd7\x36\x36\xd0\xd0\xd9\x59\x54\x45\xc5\x3e\xf7\xf8\x7e\x9d\x8b\xa7\x53\x98\x7e\x9a\xc2\xa9\x48\xe9\x1d\xd4\xff\xe4\x73\xf1\x33\x2d\xab\x22\x3d\xef\xf9\x1d\x94\x45\xcd\x8f\x15\xad\x05\xc8\x9a\x8a\x3f\x70\xd2\xb4\x86\x04\xf6\x45\x51\xa5\x19\x4b\x38\x8d\xe9\x6b\x99\x55\x34\x4e\x38\xa7\xa7\x92\xd7\x63\x1b\x0e\x67\xb6\xe7\x59\xc1\x80\x3f\x27\x1c\xd4\x7e\x0d\xc5\x77\x5a\xa5\x67\x2a\x48\x34\x16\x32\x06\x05\xa3\x50\x15\x67\x96\x02\xaf\xb2\x72\x8e\x36\x14\x36\x45\x63\x15\xee\x8b\x92\xa6\x48\xb9\x4f\xf2\x1c\xe1\x1c\xb2\x03\xfe\x0a\x0e\xfa\x9a\xd5\xbc\x9e\x01\x3c\x52\x86\x9e\x1c\x5b\x52\x65\x2a\x90\xc8\x95\x55\xea\x3d\x51\x0e\x65\x27\xdc\xa8\x85\xfd\x37\x7a\x28\x10\x95\xf0\x58\x2c\x4e\xa0\xa8\x5a\x8b\x97\xa2\xfa\x1b\xea\x92\xee\xe1\x39\xd1\x7c\xf1\x4b\xc6\x9f\x63\xb1\x43\x2b\xa8\xa9\xf4\x21\x11\x5e\x1b\x0b\x5c\xcf\x44\x48\x09\x06\xff\x9d\x0a\x42\x65\x99\x62\x7e\xf1\x24\x4c\x06\x83\x63\x95\xec\xf1\xd0\x63\x31\x13\xf6\xee\x01\x6a\x11\x61\xcc\x92\x13\xad\x4b\xb1\x85\x56\x6a\x49\x30\xc6\xd2\x07\x24\x65\x05\x07\x6f\xb7\xdd\xa2\x9b\x2c\x7f\x33\xa1\x0a\x8e\xac\x49\x73\x8f\xa2\x0d\x20\xc1\x93\xf7\x05\xab\xb3\x94\xa2\x27\x98\xae\x80\xf2\x73\xc5\x6a\x99\x67\x76\x3e\x7d\xa3\x95\x60\x29\x0e\xca\xe6\xcc\x32\xcc\x60\x45\x73\x9a\xd4\x08\x6f\x8a\x7e\x7d\xca\xd0\x71\x4e\x61\x57\xf6\x5e\x43\x8e\xbf\x27\xca\xf8\x67\x7a\xcc\x98\xb5\x0c\x88\x13\x11\x58\xef\xbc\x65\xe4\xfa\xde\xcf\x9a\xc4\x02\x7c\x9a\xdc\x43\xe4\x3e\x90\x30\x72\x1e\x1e\xe1\xc9\x8d\xee\xe5\x2b\xfc\xe5\x7b\x64\x22\x51\x2a\x65\xae\x17\x91\xe0\x0f\x67\x0b\x2b\xb2\x76\x76\xdb\xa8\x5d\x18\xdd\x8c\x14\xf0\x7d\x2a\x05\x62\x43\x02\x63\x21\x13\xd8\x41\xb6\x19\x1e\x42\xda\x56\x40\xa2\x5d\xe0\x85\x66\xd7\x09\xe1\xea\xca\x5a\x91\xe5\xd6\x09\x88\xe4\x91\x51\x89\xde\x6b\x20\x5f\xbe\xce\xe5\xba\xce\x9f\x5e\x57\xab\xe2\xa8\x38\x43\xf9\x74\xd0\x9f\xc9\xc6\xf5\xe4\x6e\x48\xb6\x64\x19\x61\xbd\xaa\xe4\x2d\x4e\x8e\xc7\x71\x93\xab\x59\x96\xda\xc2\xc2\x37\xa7\x49\xf8\x3a\xf0\x1f\x74\x1f\x4c\xc0\x84\x32\x69\x3a\x51\x62\x9e\xee\x49\x40\x34\x68\x66\x30\xe8\xc3\xc2\xbc\x20\xbd\xc4\x3a\xde\xaa\x87\xa4\x95\x86\xd1\x6a\x08\x53\x63\xe9\xcf\xf5\x62\x54\x2a\xf5\x8d\x0c\xc0\xf8\xdd\xca\x4e\x56\xf9\x37\x5d\x6d\x3f\x90\x58\xf9\x8c\x5b\x3f\x06\x54\x86\x74\x2d\x14\xc6\x9e\x1f\x35\xd1\xcd\x1a\x89\xf9\x81\x5e\x68\x0f\xd3\xe7\x4c\x65\xdf\xd8\xb6\xdd\x7a\x76\xd1\x20\xa1\xac\xb4\xa6\x51\x7e\x98\x6d\x11\xff\x3b\x8b\x0b\xae\x4e\x0b\x0d\x71\x19\x06\xb3\x64\xab\x56\x70\xd7\x9d\xde\x69\x2c\xa3\x7b\xe2\x99\x70\x55\xef\xc1\x8d\x82\x13\x3c\xd1\x5d\xcf\x2d\xf9\x82\x02\x0c\x54\x7f\x49\x0d\xb7\xb2\x9d\xc0\xa1\xc8\xf3\xe2\x45\x90\x9a\x1d\x71\xe8\x48\x28\x9a\x57\x6f\xda\xbc\xde\x3f\xd3\xf4\x9c\xe3\xd0\xc3\xe9\x26\x50\x3b\x34\xc7\xb9\x50\x65\x72\xd6\xa6\x05\xad\x55\xff\x08\x35\xea\x5e\x8e\xd5\x68\x40\x11\x8c\x8d\x97\xbb\xc7\x95\x10\xbc\x8c\x4e\x6c\x9b\x8d\x90\x60\x27\xcb\x1a\x69\xc9\x8b\x6c\xb6\x02\xd4\x4f\x25\x0f\xad\x17\x86\x61\xd6\xac\x5c\xdf\xf6\x81\x38\x00\x63\x35\xb1\x17\x4b\x27\x24\xbd\xbd\xa6\xd5\x3d\x58\xfa\xce\x96\x84\x4b\x32\x56\x22\xca\x29\x3b\xf2\xe7\x4e\x8b\xc9\x24\xc4\x29\xcd\x93\x37\x4c\xd6\xad\x3d\x81\x1b\xfb\xf7\x9b\x0b\x2e\x51\x08\x33\x9b\xae\x2f\xb6\xe5\x33\x4c\xfa\x65\x4b\x9c\x30\x1a\xff\x67\x34\xbd\xe7\x17\x9d\xb5\xbf\xc2\xa7\x61\x92\x76\x12\xde\xe2\x87\x09\x87\x7d\x3a\xba\x00\x92\x6d\xd8\xa9\xd3\xac\x4d\x67\x0f\x49\x3a\x82\x93\x03\xc6\xb8\x63\x75\x12\x1d\x90\x7e\x8b\xb7\xb4\xdd\x11\x63\x2c\x84\x5c\x5a\xc8\x65\x57\x38\xde\x9f\x63\x2d\x06\xfb\x9d\x00\x5c\x6f\x03\xc3\xf4\x76\x77\x68\x2e\xfd\x9d\x17\x8d\x3f\x61\x41\xdb\xf1\xb9\x72\xc3\xc8\xc5\x0f\x11\xf4\xac\xd4\x24\xd5\x3d\x3d\x31\x33\xb9\x9d\xa9\xfd\x7e\x37\x72\xd5\xcb\x8b\x9b\xff\x23\x54\x87\x31\xbc\xda\xec\x5b\xa5\x4a\xc9\x61\xf6\xb3\xc3\xdb\x13\xbe\x86\x78\x7c\xdd\x95\xdc\x23\x09\xd6\x7e\xf0\x00\xe5\x31\x56\xa8\xf1\xa8\xf3\x15\x95\xa1\x8c\xe0\xc7\x8f\x0f\xe6\xd5\xdd\x1d\xa7\xaf\xfc\xa3\x5e\xeb\xdb\xd8\xd6\x07\x55\x1e\xa8\xb0\x28\x91\xce\x94\xdd\x44\xd7\x68\xbf\x29\xa4\xa5\x55\xff\x6e\xea\x2f\xcc\xfd\x4a\x7f\x30\x9a\x3b\xd1\x68\xe0\x0b\xf5\xbe\x19\x54\x4e\x9b\x0c\xeb\x02\xcc\x2d\x4c\xf2\xdc\xba\xba\x82\xad\xe3\x6d\x76\xce\x86\x40\x99\x97\x47\x71\x79\x1d\xbe\x9b\x10\x96\x5a\xbd\x9d\x55\xf1\xc2\xac\x55\xe0\x3f\xfe\xd2\x4d\xe5\xc3\xcb\x89\xd1\xdd\x4f\xb4\x3d\x24\x55\xfc\xf2\x4f\xf4\x3f\x18\xe2\xbf\x00\x00\x00\xff\xff\x01\x00\x00\xff\xff\xc4\x88\x44\x09\x83\x0b\x00\x00")
+
+func migrations202610170410ExpireAttemptsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations202610170410ExpireAttemptsSql,
+		"migrations/202610170410-expire-attempts.sql",
+	)
+}
+
+func migrations202610170410ExpireAttemptsSql() (*asset, error) {
+	bytes, err := migrations202610170410ExpireAttemptsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/202610170410-expire-attempts.sql", size: 2947, mode: os.FileMode(420), modTime: time.Unix(1792221950, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _migrations202610170609AttemptReleasedSql = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\x9c\x8f\x41\x4e\xc3\x30\x10\x45\xf7\x3d\xc5\xdf\x21\x01\xee\x01\xda\x95\x4b\xd2\x95\x49\x50\x49\xd6\xd5\x34\x36\xa9\xd5\xc4\x0e\xf6\x84\xc0\xed\x89\xa5\x8a\x0a\x54\x58\x20\x59\xb3\xb0\xff\xbc\xff\x2c\x04\xc4\xad\x40\xef\xb5\x59\x21\xbe\x76\xeb\x34\xc4\x10\xbc\x1e\x1b\x5e\x61\xf0\x91\xdb\x60\x62\x0a\x2d\x44\x3a\x90\x5a\x47\x10\x82\xe9\x0c\x45\xa3\xf1\xd2\x51\x0b\xf6\x20\x66\xd3\x0f\x1c\x97\x80\xbc\xbc\x9e\x6f\x31\x51\x44\x6b\xdf\x8c\x4b\x88\x03\x35\x27\x4c\x96\x8f\x7e\xe4\x39\x4a\x5d\xf7\x81\x83\xb1\x6e\xe6\x04\x6b\xf4\x3d\xc8\x69\x68\x3f\xd7\x3a\xcf\x68\xfc\xe8\x18\xd4\x92\x75\x91\x61\x39\x26\xc4\xe4\xc3\x09\x71\x30\xcd\x4d\x44\x4f\xef\xfb\x60\xd2\x6a\x5c\x9e\x25\xef\x7a\xdb\x06\x62\x83\x7a\x58\x48\x55\xe5\x3b\x54\x72\xa3\xf2\x2f\x1d\x99\x65\x78\x28\x55\xfd\x58\x5c\x54\x37\x65\xa9\x72\x59\xa0\x28\x2b\x14\xb5\x52\xc8\xf2\xad\xac\x55\x85\xad\x54\xcf\xf9\xfa\x1a\x67\x4f\xa1\x39\xce\xbf\xfa\x17\xef\x9b\x67\xe6\x27\xf7\x67\x43\xb6\x2b\x9f\x7e\x56\x5c\x75\xfa\x25\xf9\x09\x00\x00\xff\xff\x01\x00\x00\xff\xff\x33\x13\x3b\xa4\xe9\x01\x00\x00")
+
+func migrations202610170609AttemptReleasedSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations202610170609AttemptReleasedSql,
+		"migrations/202610170609-attempt-released.sql",
+	)
+}
+
+func migrations202610170609AttemptReleasedSql() (*asset, error) {
+	bytes, err := migrations202610170609AttemptReleasedSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/202610170609-attempt-released.sql", size: 489, mode: os.FileMode(420), modTime: time.Unix(1792217381, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _migrations202610170618AttemptHistoryUnitSql = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\xbc\x54\xd1\x6e\x9b\x30\x14\x7d\xe7\x2b\xee\x63\xd2\x15\x3e\xa0\x68\x0f\x0c\x9c\x36\x2a\x85\x08\x1c\x4d\x7b\x42\x6e\xec\x12\xab\x04\x33\xdb\x49\x96\xbf\x9f\x9d\x92\x40\x34\x50\x33\x6d\x2d\x02\x24\xdb\xe7\x9e\x7b\x7c\x0e\xd8\x75\xc1\xbd\x71\x61\x23\x28\xbb\x03\xf5\xb3\xf2\xed\xcb\x6d\xa4\xa0\xdb\x95\xbe\x83\x46\x28\x5d\x4a\xa6\x2c\xc8\x71\xed\x0d\x98\x9b\x21\xd1\x9a\x6d\x1a\x0d\x94\x68\x02\x6b\xae\xb4\x90\x07\x20\x35\x85\x4a\x94\x0a\xb4\x80\xbd\x90\xaf\xb0\xad\xb9\x56\xc0\x6b\xa5\x19\xa1\x20\x5e\x40\xaf\x99\xa5\x38\x55\x6b\xf2\x5c\xb1\x5b\x50\xc2\x2c\x10\x6d\x57\x0f\xa0\xb6\x72\xc7\x77\xcc\x0e\xce\xb8\x67\xc6\xeb\xd2\x68\xdc\x31\x6a\xb8\x7b\x0c\x05\x91\xab\xb5\x41\x7b\x00\xd8\x16\x13\xc9\x40\x69\x5e\x55\x40\x59\xc5\xb4\x81\x93\x4a\x98\xd2\x3d\xd7\x6b\xcb\xc8\xe5\x51\x98\x65\xb0\xda\x6e\x8f\x92\x17\x5b\x59\xb2\xe0\x8d\x50\xb5\x85\xca\xa2\x37\xc0\x7e\x35\x15\x5f\x71\x5d\x1d\xbc\x76\xf7\x5f\x36\xbc\x94\x44\x33\x58\x36\x4e\x10\x63\x94\x01\x0e\xbe\xc5\xe8\x2c\xc8\x1a\x52\xb4\x86\x38\x70\xbc\x82\x28\x82\x30\x8d\x97\x4f\xc9\xb1\x79\x61\x3b\x17\x9c\xc2\x3c\xc1\xe8\x1e\x65\x2d\xca\x5e\x19\x9a\xa1\x0c\x25\x21\xca\x3b\xe4\x84\xd3\x29\xa4\x09\x44\x28\x46\x18\x41\x18\xe4\x61\x10\x21\xdf\x59\x2e\xa2\x00\x0f\xf7\x85\x1c\xe1\x8b\x56\x5f\x5b\x94\xd7\x9f\x3c\xf5\x9d\x65\xe9\xd3\xd9\xe9\xef\x0f\x46\xc0\x69\xe4\x75\x95\x17\xfc\xde\x69\x92\x53\xff\x5d\x13\xe0\x0d\x30\x64\x80\x95\x99\xa4\xe6\x59\xc6\xb1\x7f\xad\x9b\x51\x96\x2e\x0c\x5b\x92\xe3\x2c\x30\x0e\x0e\x42\x8b\x4e\x60\xf1\xf2\xca\x0e\xbe\x33\xc8\x6e\xbe\xd5\xcf\x8c\xc8\xb4\xfb\x88\x64\x0c\xed\xbb\x81\xd8\xd6\xff\x92\x43\xe7\xd4\x98\xfd\x06\x31\xe0\x7a\xff\x87\x89\xc4\xbe\x76\x5a\x8b\xfa\x3b\xeb\x91\x5f\x6e\xd2\x4a\xb3\xb2\xe6\x09\x4c\x72\x53\x17\x62\x30\x33\xfd\xd2\xa9\x7f\x55\xae\xd7\x88\xed\x45\x3c\x4b\x33\x34\xbf\x4f\xe0\x11\xfd\x80\x49\x07\x9b\xf6\xb3\x6f\xa7\xc7\x92\x1f\xcb\xa0\x75\xef\x8f\x08\x8c\x55\x43\xce\x0c\x7c\xfe\xff\xc9\xa2\xd1\x63\xea\xaf\xfe\xab\x8f\x37\xed\xe2\x24\x19\x77\xef\x37\x00\x00\x00\xff\xff\x01\x00\x00\xff\xff\xbe\xf9\x55\x97\xc6\x06\x00\x00")
+
+func migrations202610170618AttemptHistoryUnitSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations202610170618AttemptHistoryUnitSql,
+		"migrations/202610170618-attempt-history-unit.sql",
+	)
+}
+
+func migrations202610170618AttemptHistoryUnitSql() (*asset, error) {
+	bytes, err := migrations202610170618AttemptHistoryUnitSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/202610170618-attempt-history-unit.sql", size: 1734, mode: os.FileMode(420), modTime: time.Unix(1792217913, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _migrations202610170627WorkUnitRuntimeAvailableSql = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\x8c\x90\xcd\x6e\xc2\x30\x10\x84\xef\x79\x8a\xb9\xd1\x1f\xc2\x03\x40\x55\x09\x35\x91\x8a\x84\x42\x45\x8b\xda\x5b\xb4\x4d\x16\xb0\x48\x6c\x63\x6f\x42\xfb\xf6\x8d\x03\xb4\x54\xea\xa1\x96\x65\x59\xbb\xdf\x8e\x67\x1c\xc7\x88\x6f\x62\xd4\xa6\xe4\x31\xfc\xbe\x9a\x84\x23\xb6\xce\x94\x4d\x21\x63\x58\xe3\x65\xe3\xd8\x07\x28\x8a\xc3\xc6\x4c\x97\xfc\xd1\x55\x64\xcb\xa0\x96\x54\x45\xef\x15\xe3\x60\xdc\x0e\x8d\x56\x12\x1a\x24\x30\x2d\x3b\xa7\x4a\x0e\x98\x72\xc7\xb6\xb7\x5c\x0c\x7c\xd0\x70\x8d\x16\x55\xf3\x08\x48\x3b\xee\x13\x8e\xf7\x0d\x7b\xc1\xda\x9c\xd0\x83\x92\xed\x99\xf2\xa8\x8c\xd9\xf9\xbe\xd9\xa9\x79\x1e\x06\x09\xd2\x25\xa8\xaa\x3b\x7f\xd0\xe6\xf2\xf9\x2d\xb5\x0c\xa3\x79\x08\x6f\x40\xb0\xe4\x44\x51\x05\x15\x6c\x63\xc7\x6c\x8f\x0e\x83\x46\xd0\x6d\x2c\xd6\xce\xd4\xf0\x05\x69\xad\xf4\x06\xdc\x3b\xfa\x23\xd9\xe8\xf4\x01\xb7\xb5\xda\x38\x12\xc6\xca\x46\x0f\xcb\x74\xfa\x92\x62\x96\x25\xe9\x5b\x4f\xe6\x81\xcc\x43\xd2\xfc\x5b\x22\x3f\x05\xc1\x22\xfb\x61\xae\xfa\x5b\x0f\xaa\x72\x78\xce\x7a\x1d\xe1\xb8\x5e\x1f\xd3\x65\x7a\xae\xde\xdd\x0f\x06\x98\x66\x09\xa8\x10\xd5\x72\x4e\x22\x5c\x5b\xe9\x06\x31\x7b\x46\xb6\x9a\xcf\x27\xd1\x2f\x67\x89\x39\xe8\x28\x59\x2e\x9e\xfe\xe9\x6c\x12\x7d\x01\x00\x00\xff\xff\x01\x00\x00\xff\xff\xa4\x56\xbd\xad\x08\x02\x00\x00")
+
+func migrations202610170627WorkUnitRuntimeAvailableSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations202610170627WorkUnitRuntimeAvailableSql,
+		"migrations/202610170627-work-unit-runtime-available.sql",
+	)
+}
+
+func migrations202610170627WorkUnitRuntimeAvailableSql() (*asset, error) {
+	bytes, err := migrations202610170627WorkUnitRuntimeAvailableSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/202610170627-work-unit-runtime-available.sql", size: 520, mode: os.FileMode(420), modTime: time.Unix(1792218454, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _migrations202610170650WorkUnitAvailableCreatedAtSql = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\x8c\x90\xcd\x6e\xc2\x30\x10\x84\xef\x79\x8a\x11\x97\xfe\x11\x1e\x00\x4e\x55\x89\x54\x24\x04\x15\x05\xd1\x5b\xe4\xc4\x1b\xb0\x48\xec\xe0\x35\x49\x79\xfb\xda\x2e\xfd\x93\x38\xd4\xb2\xac\xf5\x7a\x34\xfb\x8d\xd3\x14\xe9\x7d\x8a\xc6\x48\x1a\x83\x8f\xf5\x24\x1c\x69\x6b\x8d\x3c\x95\x6e\x8c\xd6\xb0\xdb\x59\xe2\x20\x4a\xd2\xb0\x31\xd3\x92\xde\x7d\xc7\xed\x09\xa2\x13\xaa\x16\x45\x4d\xe8\x8d\x3d\xe0\xa4\x95\x63\x14\x67\x94\x96\x84\x53\x46\xc3\xa9\x86\x46\xc0\x36\xbc\x72\x4b\x25\x07\x87\x5e\xb9\x3d\x06\x95\xaa\xcc\x00\xc6\x62\x50\x5f\x2a\x49\x16\xe5\xde\x18\xbe\x6a\x47\x32\x17\x6e\x08\xa1\xe5\x97\x87\x39\x39\x4f\xa1\x18\x2a\x20\x81\x3a\xb2\x67\x58\x3a\x9e\x88\x1d\x2a\xef\x1c\x5d\xd8\x58\xef\x22\xea\x1a\xa6\x8a\xd0\xa1\x1b\x2c\x02\xcf\x0d\x5f\xcd\xe0\x91\xd7\x5e\xc9\xa2\xa1\x8b\x39\x93\xed\x7c\xe8\xc2\x78\xf4\x48\xca\xc3\xe8\x51\x0a\xad\x95\xde\x41\xc5\x89\xbd\xb0\x92\x43\xa6\x42\x94\x87\x78\x19\x5d\x7e\xed\xa1\x51\x3b\xeb\x43\x60\xd3\x26\x4f\xab\xec\x71\x9d\x61\xb6\x98\x66\x6f\x71\x68\x1e\x86\xe6\x81\x27\xff\xa6\xc9\x7f\x42\x63\xb9\xf8\x91\xdd\xc6\x2a\x6a\x95\x1c\xfe\xfa\x9a\xbb\x04\x9f\x6b\xfb\x9c\xad\x32\x88\xd2\xa9\x8e\x7c\xdf\x51\xd3\x3a\xaf\xc5\xec\x15\x8b\xcd\x7c\x3e\x49\xfe\xf0\x4c\x4d\xaf\x93\xe9\x6a\xf9\xf2\x7f\x9e\x49\xf2\x01\x00\x00\xff\xff\x01\x00\x00\xff\xff\x39\xdf\x1c\x08\x36\x02\x00\x00")
+
+func migrations202610170650WorkUnitAvailableCreatedAtSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations202610170650WorkUnitAvailableCreatedAtSql,
+		"migrations/202610170650-work-unit-available-created-at.sql",
+	)
+}
+
+func migrations202610170650WorkUnitAvailableCreatedAtSql() (*asset, error) {
+	bytes, err := migrations202610170650WorkUnitAvailableCreatedAtSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/202610170650-work-unit-available-created-at.sql", size: 566, mode: os.FileMode(420), modTime: time.Unix(1792220866, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 // Asset loads and returns the asset for the given name.
 // It returns an error if the asset could not be found or
 // could not be loaded.
@@ -471,6 +912,27 @@ var _bindata = map[string]func() (*asset, error){
 	"migrations/20170316-index.sql": migrations20170316IndexSql,
 	"migrations/20170523-work-unit-max-retries.sql": migrations20170523WorkUnitMaxRetriesSql,
 	"migrations/20170523-work-unit-max-retries.sql~": migrations20170523WorkUnitMaxRetriesSql2,
+	"migrations/202610170134-work-spec-expire-with-worker.sql": migrations202610170134WorkSpecExpireWithWorkerSql,
+	"migrations/202610170140-work-spec-max-lease-total.sql": migrations202610170140WorkSpecMaxLeaseTotalSql,
+	"migrations/202610170148-work-unit-created-at.sql": migrations202610170148WorkUnitCreatedAtSql,
+	"migrations/202610170153-work-spec-heartbeat-extension.sql": migrations202610170153WorkSpecHeartbeatExtensionSql,
+	"migrations/202610170158-attempt-finish-prepared.sql": migrations202610170158AttemptFinishPreparedSql,
+	"migrations/202610170201-attempt-data-history.sql": migrations202610170201AttemptDataHistorySql,
+	"migrations/202610170223-work-spec-unit-order.sql": migrations202610170223WorkSpecUnitOrderSql,
+	"migrations/202610170231-work-spec-schema-version.sql": migrations202610170231WorkSpecSchemaVersionSql,
+	"migrations/202610170254-attempt-archive.sql": migrations202610170254AttemptArchiveSql,
+	"migrations/202610170258-retry-delays.sql": migrations202610170258RetryDelaysSql,
+	"migrations/202610170306-attempt-log.sql": migrations202610170306AttemptLogSql,
+	"migrations/202610170317-work-spec-continuous-paused.sql": migrations202610170317WorkSpecContinuousPausedSql,
+	"migrations/202610170356-work-unit-runtime.sql": migrations202610170356WorkUnitRuntimeSql,
+	"migrations/202610170412-work-unit-available-name.sql": migrations202610170412WorkUnitAvailableNameSql,
+	"migrations/202610170415-work-spec-gated.sql": migrations202610170415WorkSpecGatedSql,
+	"migrations/202610170537-work-spec-default-lease.sql": migrations202610170537WorkSpecDefaultLeaseSql,
+	"migrations/202610170410-expire-attempts.sql": migrations202610170410ExpireAttemptsSql,
+	"migrations/202610170609-attempt-released.sql": migrations202610170609AttemptReleasedSql,
+	"migrations/202610170618-attempt-history-unit.sql": migrations202610170618AttemptHistoryUnitSql,
+	"migrations/202610170627-work-unit-runtime-available.sql": migrations202610170627WorkUnitRuntimeAvailableSql,
+	"migrations/202610170650-work-unit-available-created-at.sql": migrations202610170650WorkUnitAvailableCreatedAtSql,
 }
 
 // AssetDir returns the file names below a certain
@@ -530,6 +992,27 @@ var _bintree = &bintree{nil, map[string]*bintree{
 		"20170316-index.sql": &bintree{migrations20170316IndexSql, map[string]*bintree{}},
 		"20170523-work-unit-max-retries.sql": &bintree{migrations20170523WorkUnitMaxRetriesSql, map[string]*bintree{}},
 		"20170523-work-unit-max-retries.sql~": &bintree{migrations20170523WorkUnitMaxRetriesSql2, map[string]*bintree{}},
+		"202610170134-work-spec-expire-with-worker.sql": &bintree{migrations202610170134WorkSpecExpireWithWorkerSql, map[string]*bintree{}},
+		"202610170140-work-spec-max-lease-total.sql": &bintree{migrations202610170140WorkSpecMaxLeaseTotalSql, map[string]*bintree{}},
+		"202610170148-work-unit-created-at.sql": &bintree{migrations202610170148WorkUnitCreatedAtSql, map[string]*bintree{}},
+		"202610170153-work-spec-heartbeat-extension.sql": &bintree{migrations202610170153WorkSpecHeartbeatExtensionSql, map[string]*bintree{}},
+		"202610170158-attempt-finish-prepared.sql": &bintree{migrations202610170158AttemptFinishPreparedSql, map[string]*bintree{}},
+		"202610170201-attempt-data-history.sql": &bintree{migrations202610170201AttemptDataHistorySql, map[string]*bintree{}},
+		"202610170223-work-spec-unit-order.sql": &bintree{migrations202610170223WorkSpecUnitOrderSql, map[string]*bintree{}},
+		"202610170231-work-spec-schema-version.sql": &bintree{migrations202610170231WorkSpecSchemaVersionSql, map[string]*bintree{}},
+		"202610170254-attempt-archive.sql": &bintree{migrations202610170254AttemptArchiveSql, map[string]*bintree{}},
+		"202610170258-retry-delays.sql": &bintree{migrations202610170258RetryDelaysSql, map[string]*bintree{}},
+		"202610170306-attempt-log.sql": &bintree{migrations202610170306AttemptLogSql, map[string]*bintree{}},
+		"202610170317-work-spec-continuous-paused.sql": &bintree{migrations202610170317WorkSpecContinuousPausedSql, map[string]*bintree{}},
+		"202610170356-work-unit-runtime.sql": &bintree{migrations202610170356WorkUnitRuntimeSql, map[string]*bintree{}},
+		"202610170412-work-unit-available-name.sql": &bintree{migrations202610170412WorkUnitAvailableNameSql, map[string]*bintree{}},
+		"202610170415-work-spec-gated.sql": &bintree{migrations202610170415WorkSpecGatedSql, map[string]*bintree{}},
+		"202610170537-work-spec-default-lease.sql": &bintree{migrations202610170537WorkSpecDefaultLeaseSql, map[string]*bintree{}},
+		"202610170410-expire-attempts.sql": &bintree{migrations202610170410ExpireAttemptsSql, map[string]*bintree{}},
+		"202610170609-attempt-released.sql": &bintree{migrations202610170609AttemptReleasedSql, map[string]*bintree{}},
+		"202610170618-attempt-history-unit.sql": &bintree{migrations202610170618AttemptHistoryUnitSql, map[string]*bintree{}},
+		"202610170627-work-unit-runtime-available.sql": &bintree{migrations202610170627WorkUnitRuntimeAvailableSql, map[string]*bintree{}},
+		"202610170650-work-unit-available-created-at.sql": &bintree{migrations202610170650WorkUnitAvailableCreatedAtSql, map[string]*bintree{}},
 	}},
 }}
 
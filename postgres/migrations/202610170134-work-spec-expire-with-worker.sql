@@ -0,0 +1,9 @@
+-- -*- mode: sql; sql-product: postgres -*-
+--
+-- Adds an expire_with_worker field to work_spec.
+--
+-- +migrate Up
+ALTER TABLE work_spec ADD COLUMN expire_with_worker BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +migrate Down
+ALTER TABLE work_spec DROP COLUMN expire_with_worker;
@@ -0,0 +1,9 @@
+-- -*- mode: sql; sql-product: postgres -*-
+--
+-- Adds a max_lease_total field to work_spec.
+--
+-- +migrate Up
+ALTER TABLE work_spec ADD COLUMN max_lease_total INTERVAL NOT NULL DEFAULT '0';
+
+-- +migrate Down
+ALTER TABLE work_spec DROP COLUMN max_lease_total;
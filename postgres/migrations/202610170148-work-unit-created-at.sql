@@ -0,0 +1,10 @@
+-- -*- mode: sql; sql-product: postgres -*-
+--
+-- Adds a created_at field to work_unit.  Existing work units are
+-- marked as created when this migration runs.
+--
+-- +migrate Up
+ALTER TABLE work_unit ADD COLUMN created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now();
+
+-- +migrate Down
+ALTER TABLE work_unit DROP COLUMN created_at;
@@ -0,0 +1,9 @@
+-- -*- mode: sql; sql-product: postgres -*-
+--
+-- Adds a heartbeat_extension field to work_spec.
+--
+-- +migrate Up
+ALTER TABLE work_spec ADD COLUMN heartbeat_extension INTERVAL NOT NULL DEFAULT '0';
+
+-- +migrate Down
+ALTER TABLE work_spec DROP COLUMN heartbeat_extension;
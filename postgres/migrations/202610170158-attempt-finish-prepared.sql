@@ -0,0 +1,10 @@
+-- -*- mode: sql; sql-product: postgres -*-
+--
+-- Adds a finish_prepared flag to attempt, set by
+-- Attempt.PrepareFinish() before the attempt is finished.
+--
+-- +migrate Up
+ALTER TABLE attempt ADD COLUMN finish_prepared BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +migrate Down
+ALTER TABLE attempt DROP COLUMN finish_prepared;
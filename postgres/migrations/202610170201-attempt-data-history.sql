@@ -0,0 +1,18 @@
+-- -*- mode: sql; sql-product: postgres -*-
+--
+-- Adds a table holding snapshots of attempt data from each renewal.
+-- This is only written if the coordinate is configured to keep data
+-- history.
+--
+-- +migrate Up
+CREATE TABLE attempt_data_history(
+       id SERIAL PRIMARY KEY,
+       attempt_id INTEGER NOT NULL
+                  REFERENCES attempt(id) ON DELETE CASCADE,
+       time TIMESTAMP WITH TIME ZONE NOT NULL,
+       data BYTEA NOT NULL
+);
+CREATE INDEX attempt_data_history_attempt ON attempt_data_history(attempt_id);
+
+-- +migrate Down
+DROP TABLE attempt_data_history;
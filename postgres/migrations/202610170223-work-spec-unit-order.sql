@@ -0,0 +1,9 @@
+-- -*- mode: sql; sql-product: postgres -*-
+--
+-- Adds a unit_order field to work_spec.
+--
+-- +migrate Up
+ALTER TABLE work_spec ADD COLUMN unit_order VARCHAR NOT NULL DEFAULT 'priority';
+
+-- +migrate Down
+ALTER TABLE work_spec DROP COLUMN unit_order;
@@ -0,0 +1,9 @@
+-- -*- mode: sql; sql-product: postgres -*-
+--
+-- Adds a schema_version field to work_spec.
+--
+-- +migrate Up
+ALTER TABLE work_spec ADD COLUMN schema_version INTEGER NOT NULL DEFAULT 0;
+
+-- +migrate Down
+ALTER TABLE work_spec DROP COLUMN schema_version;
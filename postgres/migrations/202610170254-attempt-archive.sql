@@ -0,0 +1,26 @@
+-- -*- mode: sql; sql-product: postgres -*-
+--
+-- Adds a table holding old completed attempts, moved out of the
+-- attempt table so everyday queries there stay small.  This is only
+-- written if the coordinate is configured to archive attempts.
+--
+-- +migrate Up
+CREATE TABLE attempt_archive(
+       id INTEGER PRIMARY KEY,
+       work_unit_id INTEGER NOT NULL
+                    REFERENCES work_unit(id) ON DELETE CASCADE,
+       work_spec_id INTEGER NOT NULL
+                    REFERENCES work_spec(id) ON DELETE CASCADE,
+       worker_id INTEGER NOT NULL
+                 REFERENCES worker(id) ON DELETE CASCADE,
+       status attempt_status NOT NULL,
+       data BYTEA,
+       start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+       end_time TIMESTAMP WITH TIME ZONE,
+       expiration_time TIMESTAMP WITH TIME ZONE NOT NULL,
+       finish_prepared BOOLEAN NOT NULL DEFAULT FALSE
+);
+CREATE INDEX attempt_archive_work_unit ON attempt_archive(work_unit_id);
+
+-- +migrate Down
+DROP TABLE attempt_archive;
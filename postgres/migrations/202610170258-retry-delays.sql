@@ -0,0 +1,12 @@
+-- -*- mode: sql; sql-product: postgres -*-
+--
+-- Adds a retry_delays schedule to work_spec, in seconds, and a count
+-- of retries to work_unit.
+--
+-- +migrate Up
+ALTER TABLE work_spec ADD COLUMN retry_delays DOUBLE PRECISION[] NOT NULL DEFAULT '{}';
+ALTER TABLE work_unit ADD COLUMN retries INTEGER NOT NULL DEFAULT 0;
+
+-- +migrate Down
+ALTER TABLE work_unit DROP COLUMN retries;
+ALTER TABLE work_spec DROP COLUMN retry_delays;
@@ -0,0 +1,16 @@
+-- -*- mode: sql; sql-product: postgres -*-
+--
+-- Adds a table holding lines of output logged by running attempts.
+-- Only the most recent lines for each attempt are kept.
+--
+-- +migrate Up
+CREATE TABLE attempt_log(
+       id SERIAL PRIMARY KEY,
+       attempt_id INTEGER NOT NULL
+                  REFERENCES attempt(id) ON DELETE CASCADE,
+       line TEXT NOT NULL
+);
+CREATE INDEX attempt_log_attempt ON attempt_log(attempt_id);
+
+-- +migrate Down
+DROP TABLE attempt_log;
@@ -0,0 +1,10 @@
+-- -*- mode: sql; sql-product: postgres -*-
+--
+-- Adds a flag to work_spec that stops continuous work unit
+-- generation without pausing the work spec's real work units.
+--
+-- +migrate Up
+ALTER TABLE work_spec ADD COLUMN continuous_paused BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +migrate Down
+ALTER TABLE work_spec DROP COLUMN continuous_paused;
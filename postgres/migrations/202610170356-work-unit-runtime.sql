@@ -0,0 +1,10 @@
+-- -*- mode: sql; sql-product: postgres -*-
+--
+-- This adds a "runtime" column to the work unit table, overriding
+-- the work spec's runtime if it is not empty.
+--
+-- +migrate Up
+ALTER TABLE work_unit ADD COLUMN runtime VARCHAR NOT NULL DEFAULT '';
+
+-- +migrate Down
+ALTER TABLE work_unit DROP COLUMN runtime;
@@ -0,0 +1,79 @@
+-- -*- mode: sql; sql-product: postgres -*-
+--
+-- Adds a coordinate_expire_attempts() function that expires overdue
+-- attempts in one round trip; the Go expireScoped() calls it if it
+-- exists.  Pending attempts expire if their expiration time is
+-- before at_time, or if their work spec has expire_with_worker set
+-- and their worker is inactive or expired more than grace ago.
+-- If scope_namespace or scope_work_spec is not NULL, only attempts
+-- in that namespace or work spec are considered.  Returns the number
+-- of work units released.
+--
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE FUNCTION coordinate_expire_attempts(
+    at_time TIMESTAMP WITH TIME ZONE,
+    grace INTERVAL DEFAULT INTERVAL '0',
+    scope_namespace INTEGER DEFAULT NULL,
+    scope_work_spec INTEGER DEFAULT NULL)
+RETURNS INTEGER AS $$
+DECLARE
+    expiring INTEGER[];
+    released INTEGER;
+    spec_ids INTEGER[];
+BEGIN
+    SELECT array_agg(attempt.id) INTO expiring
+    FROM attempt, work_spec, worker
+    WHERE attempt.work_spec_id=work_spec.id
+    AND attempt.worker_id=worker.id
+    AND attempt.status='pending'
+    AND (attempt.expiration_time<at_time OR
+         (work_spec.expire_with_worker AND
+          (NOT worker.active OR worker.expiration<at_time-grace)))
+    AND (scope_namespace IS NULL OR work_spec.namespace_id=scope_namespace)
+    AND (scope_work_spec IS NULL OR work_spec.id=scope_work_spec);
+    IF expiring IS NULL THEN
+        RETURN 0;
+    END IF;
+
+    -- Release the work units, following the work spec's retry
+    -- schedule, as workUnitRetried() does
+    WITH released_units AS (
+        UPDATE work_unit
+        SET active_attempt_id=NULL,
+            retries=work_unit.retries+1,
+            not_before=CASE
+                WHEN COALESCE(array_length(work_spec.retry_delays, 1), 0)>0
+                THEN at_time +
+                     work_spec.retry_delays[LEAST(work_unit.retries+1,
+                         array_length(work_spec.retry_delays, 1))] *
+                     INTERVAL '1 second'
+                ELSE work_unit.not_before
+            END
+        FROM work_spec
+        WHERE work_spec.id=work_unit.work_spec_id
+        AND work_unit.active_attempt_id=ANY(expiring)
+        RETURNING work_unit.work_spec_id)
+    SELECT COUNT(*), array_agg(DISTINCT work_spec_id) INTO released, spec_ids
+    FROM released_units;
+    IF released=0 THEN
+        RETURN 0;
+    END IF;
+
+    -- Announce the work, as notifyWorkSpecs() does
+    PERFORM pg_notify('coordinate_work_' || work_spec.namespace_id::text,
+                      work_spec.name)
+    FROM work_spec
+    WHERE work_spec.id=ANY(spec_ids);
+
+    UPDATE attempt
+    SET expiration_time=at_time, status='expired'
+    WHERE attempt.id=ANY(expiring);
+    RETURN released;
+END;
+$$ LANGUAGE plpgsql;
+-- +migrate StatementEnd
+
+-- +migrate Down
+DROP FUNCTION coordinate_expire_attempts(TIMESTAMP WITH TIME ZONE, INTERVAL,
+                                         INTEGER, INTEGER);
@@ -0,0 +1,19 @@
+-- -*- mode: sql; sql-product: postgres -*-
+--
+-- This widens the index of available work units to include the work
+-- unit name.  Listing available work units in a work spec goes in
+-- name order, and with this index PostgreSQL can walk just the
+-- available work units instead of every work unit the work spec has
+-- ever had.  The wider index does everything the old one did, so
+-- the old one goes away.  (Pending, finished, and failed work units
+-- are found through the existing work_unit_attempt index.)
+--
+-- +migrate Up
+CREATE INDEX work_unit_spec_available_name ON work_unit(work_spec_id, name)
+       WHERE active_attempt_id IS NULL;
+DROP INDEX work_unit_spec_available;
+
+-- +migrate Down
+CREATE INDEX work_unit_spec_available ON work_unit(work_spec_id)
+       WHERE active_attempt_id IS NULL;
+DROP INDEX work_unit_spec_available_name;
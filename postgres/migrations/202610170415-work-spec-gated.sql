@@ -0,0 +1,10 @@
+-- -*- mode: sql; sql-product: postgres -*-
+--
+-- Adds a flag to work_spec that an external controller can set to
+-- stop scheduling a work spec, separately from the paused flag.
+--
+-- +migrate Up
+ALTER TABLE work_spec ADD COLUMN gated BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +migrate Down
+ALTER TABLE work_spec DROP COLUMN gated;
@@ -0,0 +1,9 @@
+-- -*- mode: sql; sql-product: postgres -*-
+--
+-- Adds a default_lease field to work_spec.
+--
+-- +migrate Up
+ALTER TABLE work_spec ADD COLUMN default_lease INTERVAL NOT NULL DEFAULT '0';
+
+-- +migrate Down
+ALTER TABLE work_spec DROP COLUMN default_lease;
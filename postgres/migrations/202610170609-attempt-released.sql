@@ -0,0 +1,13 @@
+-- -*- mode: sql; sql-product: postgres -*-
+--
+-- Adds a released flag to attempts.  A released attempt was given
+-- back without really being tried, and does not count against its
+-- work spec's max_retries.
+--
+-- +migrate Up
+ALTER TABLE attempt ADD COLUMN released BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE attempt_archive ADD COLUMN released BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +migrate Down
+ALTER TABLE attempt_archive DROP COLUMN released;
+ALTER TABLE attempt DROP COLUMN released;
@@ -0,0 +1,40 @@
+-- -*- mode: sql; sql-product: postgres -*-
+--
+-- Ties attempt data history and logs to work units instead of the
+-- attempt table, so that they survive the attempt being moved to
+-- attempt_archive.  They are still deleted along with their work
+-- unit, and PurgeAttempts deletes them explicitly.
+--
+-- +migrate Up
+ALTER TABLE attempt_data_history
+      ADD COLUMN work_unit_id INTEGER
+          REFERENCES work_unit(id) ON DELETE CASCADE;
+UPDATE attempt_data_history SET work_unit_id=attempt.work_unit_id
+       FROM attempt WHERE attempt.id=attempt_data_history.attempt_id;
+ALTER TABLE attempt_data_history ALTER COLUMN work_unit_id SET NOT NULL;
+ALTER TABLE attempt_data_history
+      DROP CONSTRAINT attempt_data_history_attempt_id_fkey;
+
+ALTER TABLE attempt_log
+      ADD COLUMN work_unit_id INTEGER
+          REFERENCES work_unit(id) ON DELETE CASCADE;
+UPDATE attempt_log SET work_unit_id=attempt.work_unit_id
+       FROM attempt WHERE attempt.id=attempt_log.attempt_id;
+ALTER TABLE attempt_log ALTER COLUMN work_unit_id SET NOT NULL;
+ALTER TABLE attempt_log
+      DROP CONSTRAINT attempt_log_attempt_id_fkey;
+
+-- +migrate Down
+DELETE FROM attempt_log
+       WHERE attempt_id NOT IN (SELECT id FROM attempt);
+ALTER TABLE attempt_log
+      ADD CONSTRAINT attempt_log_attempt_id_fkey
+          FOREIGN KEY (attempt_id) REFERENCES attempt(id) ON DELETE CASCADE;
+ALTER TABLE attempt_log DROP COLUMN work_unit_id;
+
+DELETE FROM attempt_data_history
+       WHERE attempt_id NOT IN (SELECT id FROM attempt);
+ALTER TABLE attempt_data_history
+      ADD CONSTRAINT attempt_data_history_attempt_id_fkey
+          FOREIGN KEY (attempt_id) REFERENCES attempt(id) ON DELETE CASCADE;
+ALTER TABLE attempt_data_history DROP COLUMN work_unit_id;
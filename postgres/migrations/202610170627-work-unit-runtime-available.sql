@@ -0,0 +1,13 @@
+-- -*- mode: sql; sql-product: postgres -*-
+--
+-- Indexes the available work units that override their work spec's
+-- runtime.  Every request for work with runtimes looks for these,
+-- and almost no work units have one, so a partial index keeps that
+-- lookup from scanning every available work unit.
+--
+-- +migrate Up
+CREATE INDEX work_unit_spec_available_runtime ON work_unit(work_spec_id, runtime)
+       WHERE runtime<>'' AND active_attempt_id IS NULL;
+
+-- +migrate Down
+DROP INDEX work_unit_spec_available_runtime;
@@ -0,0 +1,14 @@
+-- -*- mode: sql; sql-product: postgres -*-
+--
+-- Indexes the available work units by creation time.  Work specs
+-- with "fifo" or "lifo" order choose work units by created_at, and
+-- without this index every request for work sorts all of the work
+-- spec's available work units.  The same index serves both orders,
+-- scanning it forwards or backwards.
+--
+-- +migrate Up
+CREATE INDEX work_unit_spec_available_created_at ON work_unit(work_spec_id, created_at)
+       WHERE active_attempt_id IS NULL;
+
+-- +migrate Down
+DROP INDEX work_unit_spec_available_created_at;
@@ -6,6 +6,7 @@ package postgres
 import (
 	"database/sql"
 	"github.com/diffeo/go-coordinate/coordinate"
+	"sync/atomic"
 )
 
 type namespace struct {
@@ -25,6 +26,17 @@ func (c *pgCoordinate) Namespace(name string) (coordinate.Namespace, error) {
 		row := tx.QueryRow("SELECT id FROM namespace WHERE name=$1", name)
 		err := row.Scan(&ns.id)
 		if err == sql.ErrNoRows {
+			limit := atomic.LoadInt64(&c.maxNamespaces)
+			if limit > 0 {
+				var count int64
+				err = tx.QueryRow("SELECT COUNT(*) FROM namespace").Scan(&count)
+				if err != nil {
+					return err
+				}
+				if count >= limit {
+					return coordinate.ErrTooManyNamespaces{Name: name}
+				}
+			}
 			// Create the namespace
 			row = tx.QueryRow("INSERT INTO namespace(name) VALUES ($1) RETURNING id", name)
 			err = row.Scan(&ns.id)
@@ -73,6 +85,39 @@ func (ns *namespace) Destroy() error {
 	return execInTx(ns, query, params, false)
 }
 
+func (ns *namespace) AvailableRuntimes() ([]string, error) {
+	ns.Coordinate().Expiry.DoForNamespace(ns)
+	params := queryParams{}
+	runtime := "CASE WHEN " + workUnitRuntime + "<>'' THEN " + workUnitRuntime + " ELSE " + workSpecRuntime + " END"
+	query := buildSelect([]string{
+		"DISTINCT " + runtime,
+	}, []string{
+		workSpecTable,
+		workUnitTable,
+	}, []string{
+		workSpecInNamespace(&params, ns.id),
+		workUnitInThisSpec,
+		workUnitAvailable(&params, ns.Coordinate().clock.Now()),
+	}) + " ORDER BY 1"
+	result := []string{}
+	err := queryAndScan(ns, query, params, func(rows *sql.Rows) error {
+		var runtime string
+		err := rows.Scan(&runtime)
+		if err == nil {
+			result = append(result, runtime)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (ns *namespace) PipelineHealth() ([]coordinate.PipelineIssue, error) {
+	return coordinate.PipelineHealth(ns, ns.Coordinate().clock.Now())
+}
+
 // coordinable interface:
 
 func (ns *namespace) Coordinate() *pgCoordinate {
@@ -0,0 +1,251 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diffeo/go-coordinate/coordinate"
+	"github.com/lib/pq"
+)
+
+// workChannelPrefix begins the name of the PostgreSQL notification
+// channel that announces new work in a namespace.  The rest of the
+// channel name is the namespace ID, and the payload is the work spec
+// name.
+const workChannelPrefix = "coordinate_work_"
+
+// workChannel returns the name of the notification channel for a
+// namespace.
+func workChannel(namespaceID int) string {
+	return workChannelPrefix + strconv.Itoa(namespaceID)
+}
+
+// notifyWorkSpecs announces that the work specs with the given IDs
+// may have new work.  PostgreSQL holds the notifications until tx
+// commits, and drops them if it rolls back.
+func notifyWorkSpecs(tx *sql.Tx, specIDs ...int) error {
+	if len(specIDs) == 0 {
+		return nil
+	}
+	params := queryParams{}
+	ids := make([]string, len(specIDs))
+	for i, id := range specIDs {
+		ids[i] = params.Param(id)
+	}
+	query := buildSelect([]string{
+		"pg_notify('" + workChannelPrefix + "' || " + workSpecNamespace + "::text, " + workSpecName + ")",
+	}, []string{
+		workSpecTable,
+	}, []string{
+		workSpecID + " IN (" + strings.Join(ids, ", ") + ")",
+	})
+	_, err := tx.Exec(query, params...)
+	return err
+}
+
+// releaseWorkUnits runs update, an UPDATE of the work unit table that
+// clears active attempts, and announces that the affected work specs
+// have work again.  It returns the number of work units changed.
+func releaseWorkUnits(tx *sql.Tx, update string, params queryParams) (int64, error) {
+	rows, err := tx.Query(update+" RETURNING "+workUnitSpec, params...)
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	seen := make(map[int]bool)
+	var specIDs []int
+	err = scanRows(rows, func() error {
+		var specID int
+		err := rows.Scan(&specID)
+		if err == nil {
+			count++
+			if !seen[specID] {
+				seen[specID] = true
+				specIDs = append(specIDs, specID)
+			}
+		}
+		return err
+	})
+	if err == nil {
+		err = notifyWorkSpecs(tx, specIDs...)
+	}
+	return count, err
+}
+
+// namespaceWatch holds the broadcasts for one namespace's
+// notification channel.
+type namespaceWatch struct {
+	// all is notified for every work spec in the namespace
+	all coordinate.WorkBroadcast
+
+	// specs are notified only for their own work spec, by name
+	specs map[string]*coordinate.WorkBroadcast
+}
+
+// notify notifies everything watching work spec name, or everything
+// watching the namespace at all if name is empty.
+func (w *namespaceWatch) notify(name string) {
+	w.all.Notify()
+	if name == "" {
+		for _, b := range w.specs {
+			b.Notify()
+		}
+	} else if b := w.specs[name]; b != nil {
+		b.Notify()
+	}
+}
+
+// notifier distributes PostgreSQL notifications about new work to
+// WorkWatcher channels and WaitForWork() callers.  It opens its own
+// database connection, separate from the query pool, the first time
+// something watches for work, and keeps it for the life of the
+// process; pq.Listener reconnects it if it drops.
+type notifier struct {
+	lock     sync.Mutex
+	listener *pq.Listener
+	channels map[string]*namespaceWatch
+}
+
+// broadcast returns the coordinate.WorkBroadcast for a work spec, or
+// for the whole namespace if specName is empty, starting the
+// listener if needed.  If ctx is done before the listener is
+// listening, returns ctx's error.
+func (n *notifier) broadcast(ctx context.Context, c *pgCoordinate, namespaceID int, specName string) (*coordinate.WorkBroadcast, error) {
+	channel := workChannel(namespaceID)
+	err := n.listen(ctx, c, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	w := n.channels[channel]
+	if w == nil {
+		w = &namespaceWatch{specs: make(map[string]*coordinate.WorkBroadcast)}
+		n.channels[channel] = w
+	}
+	if specName == "" {
+		return &w.all, nil
+	}
+	b := w.specs[specName]
+	if b == nil {
+		b = new(coordinate.WorkBroadcast)
+		w.specs[specName] = b
+	}
+	return b, nil
+}
+
+// listen makes sure the listener exists and is listening on
+// channel.  This does not hold the lock while waiting for the
+// server, since run() needs it to keep notifications flowing.
+//
+// pq.Listener.Listen blocks until the database connection is up,
+// which could be forever, so it runs in its own goroutine and listen
+// gives up when ctx is done.  The listener remembers the channel
+// either way, and listens on it once it reconnects; the nil
+// notification it sends then wakes up every watcher.
+func (n *notifier) listen(ctx context.Context, c *pgCoordinate, channel string) error {
+	n.lock.Lock()
+	if n.listener == nil {
+		n.listener = pq.NewListener(c.connString, 1*time.Second, 1*time.Minute, nil)
+		n.channels = make(map[string]*namespaceWatch)
+		go n.run(n.listener)
+	}
+	listener := n.listener
+	listening := n.channels[channel] != nil
+	n.lock.Unlock()
+	if listening {
+		return nil
+	}
+	result := make(chan error, 1)
+	go func() {
+		result <- listener.Listen(channel)
+	}()
+	select {
+	case err := <-result:
+		if err == pq.ErrChannelAlreadyOpen {
+			// Another goroutine got here first
+			err = nil
+		}
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run receives notifications from listener until it is closed.
+func (n *notifier) run(listener *pq.Listener) {
+	for notification := range listener.Notify {
+		n.lock.Lock()
+		if notification == nil {
+			// The connection was lost and re-established,
+			// so notifications may have been missed; wake
+			// everyone up
+			for _, w := range n.channels {
+				w.notify("")
+			}
+		} else if w := n.channels[notification.Channel]; w != nil && notification.Extra != "" {
+			w.notify(notification.Extra)
+		}
+		n.lock.Unlock()
+	}
+}
+
+// WatchWork implements coordinate.WorkWatcher.  New work is announced
+// with PostgreSQL NOTIFY, so this works across every process sharing
+// the database.  Adding work units, and releasing them from retried,
+// expired, or cleared attempts, sends a notification; work units
+// whose delay runs out do not.
+func (spec *workSpec) WatchWork(ctx context.Context) (<-chan struct{}, error) {
+	c := spec.Coordinate()
+	b, err := c.notify.broadcast(ctx, c, spec.namespace.id, spec.name)
+	if err != nil {
+		return nil, err
+	}
+	return b.Watch(ctx), nil
+}
+
+// WaitForWork blocks until some work spec in the named namespace may
+// have new work, as WatchWork() describes, or until ctx is done, in
+// which case it returns ctx's error.  Unlike Namespace(), this does
+// not create the namespace; if it does not exist, returns
+// coordinate.ErrNoSuchNamespace.  This is not part of the
+// coordinate.Coordinate interface, but callers holding the object
+// returned from New() can reach it with a type assertion.
+func (c *pgCoordinate) WaitForWork(ctx context.Context, name string) error {
+	var id int
+	params := queryParams{}
+	query := buildSelect([]string{
+		namespaceID,
+	}, []string{
+		namespaceTable,
+	}, []string{
+		namespaceName + "=" + params.Param(name),
+	})
+	err := withTx(c, true, func(tx *sql.Tx) error {
+		return tx.QueryRow(query, params...).Scan(&id)
+	})
+	if err == sql.ErrNoRows {
+		return coordinate.ErrNoSuchNamespace{Name: name}
+	}
+	if err != nil {
+		return err
+	}
+	b, err := c.notify.broadcast(ctx, c, id, "")
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if _, ok := <-b.Watch(ctx); !ok {
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package postgres
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/diffeo/go-coordinate/coordinate"
+	"github.com/stretchr/testify/assert"
+)
+
+// waitWhile calls WaitForWork in the background, and calls f
+// repeatedly until WaitForWork returns, since the listener may not
+// be ready the first time.  Returns WaitForWork's error.
+func waitWhile(t *testing.T, c *pgCoordinate, name string, f func(int)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.WaitForWork(ctx, name)
+	}()
+	for i := 0; ; i++ {
+		f(i)
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// TestWaitForWork checks that adding and releasing work units wakes
+// up WaitForWork, and that it gives up when its context is done.
+func TestWaitForWork(t *testing.T) {
+	cc, err := New("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	c := cc.(*pgCoordinate)
+	ns, err := c.Namespace("TestWaitForWork")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer ns.Destroy()
+	spec, err := ns.SetWorkSpec(map[string]interface{}{"name": "spec"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	worker, err := ns.Worker("worker")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Adding work wakes up the waiter
+	err = waitWhile(t, c, "TestWaitForWork", func(i int) {
+		_, err := spec.AddWorkUnit("unit"+strconv.Itoa(i), map[string]interface{}{}, coordinate.WorkUnitMeta{})
+		assert.NoError(t, err)
+	})
+	assert.NoError(t, err)
+
+	// So does retrying an attempt, which makes its work unit
+	// available again
+	err = waitWhile(t, c, "TestWaitForWork", func(int) {
+		attempts, err := worker.RequestAttempts(coordinate.AttemptRequest{})
+		if assert.NoError(t, err) && assert.Len(t, attempts, 1) {
+			assert.NoError(t, attempts[0].Retry(nil, 0))
+		}
+	})
+	assert.NoError(t, err)
+
+	// With nothing happening, the context runs out
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err = c.WaitForWork(ctx, "TestWaitForWork")
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	// Waiting on a namespace that does not exist does not
+	// create it
+	err = c.WaitForWork(ctx, "TestWaitForWorkMissing")
+	assert.Equal(t, coordinate.ErrNoSuchNamespace{Name: "TestWaitForWorkMissing"}, err)
+	namespaces, err := c.Namespaces()
+	if assert.NoError(t, err) {
+		assert.NotContains(t, namespaces, "TestWaitForWorkMissing")
+	}
+}
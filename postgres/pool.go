@@ -0,0 +1,122 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Connection pool parameters that can be given in the connection
+// string.  These are removed before the string is passed to the
+// database driver.
+const (
+	// poolMaxOpen is the maximum number of open connections.
+	// The default, zero, is unlimited; set this below the
+	// server's max_connections, less whatever other clients
+	// need, to avoid "too many connections" errors.
+	poolMaxOpen = "max_open"
+
+	// poolMaxIdle is the maximum number of idle connections kept
+	// in the pool.  The default is 2, which causes a lot of
+	// connection churn under load; setting it to about a quarter
+	// of max_open is reasonable.  Values above max_open are
+	// reduced to it.
+	poolMaxIdle = "max_idle"
+
+	// poolConnLifetime is the longest time a connection is
+	// reused, as a Go duration string like "5m".  The default,
+	// zero, reuses connections forever; a few minutes lets a
+	// load balancer or failover move connections over.
+	poolConnLifetime = "conn_lifetime"
+)
+
+// poolSettings holds connection pool parameters from the connection
+// string.  A nil field was not given, and leaves the database/sql
+// default alone.
+type poolSettings struct {
+	maxOpen      *int
+	maxIdle      *int
+	connLifetime *time.Duration
+}
+
+// set parses one connection pool parameter.
+func (p *poolSettings) set(key, value string) error {
+	switch key {
+	case poolMaxOpen, poolMaxIdle:
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("postgres: invalid %s %q", key, value)
+		}
+		if key == poolMaxOpen {
+			p.maxOpen = &n
+		} else {
+			p.maxIdle = &n
+		}
+	case poolConnLifetime:
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return fmt.Errorf("postgres: invalid %s %q", key, value)
+		}
+		p.connLifetime = &d
+	}
+	return nil
+}
+
+// apply configures db with the settings that were given.
+func (p poolSettings) apply(db *sql.DB) {
+	if p.maxOpen != nil {
+		db.SetMaxOpenConns(*p.maxOpen)
+	}
+	if p.maxIdle != nil {
+		db.SetMaxIdleConns(*p.maxIdle)
+	}
+	if p.connLifetime != nil {
+		db.SetConnMaxLifetime(*p.connLifetime)
+	}
+}
+
+// poolParamPattern matches a connection pool parameter in a
+// "key=value" connection string.
+var poolParamPattern = regexp.MustCompile(`(^|\s+)(` + poolMaxOpen + `|` + poolMaxIdle + `|` + poolConnLifetime + `)\s*=\s*(\S*)`)
+
+// extractPoolSettings removes the connection pool parameters from
+// a connection string, which may be a "postgres:" URL with them as
+// query parameters or a "key=value" string.  It returns the remaining
+// connection string and the settings.
+func extractPoolSettings(connectionString string) (string, poolSettings, error) {
+	var settings poolSettings
+	if u, err := url.Parse(connectionString); err == nil && u.Scheme != "" && u.Opaque == "" {
+		query := u.Query()
+		for _, key := range []string{poolMaxOpen, poolMaxIdle, poolConnLifetime} {
+			if _, present := query[key]; !present {
+				continue
+			}
+			if err := settings.set(key, query.Get(key)); err != nil {
+				return "", settings, err
+			}
+			query.Del(key)
+		}
+		u.RawQuery = query.Encode()
+		return u.String(), settings, nil
+	}
+
+	var err error
+	rest := poolParamPattern.ReplaceAllStringFunc(connectionString, func(param string) string {
+		match := poolParamPattern.FindStringSubmatch(param)
+		if setErr := settings.set(match[2], match[3]); setErr != nil && err == nil {
+			err = setErr
+		}
+		return ""
+	})
+	if err != nil {
+		return "", settings, err
+	}
+	return strings.TrimSpace(rest), settings, nil
+}
@@ -37,14 +37,36 @@ import (
 	"github.com/diffeo/go-coordinate/coordinate"
 )
 
+// connectionRetries is the number of times withTx will retry
+// starting a transaction if the server has too many connections, and
+// connectionBackoff is the delay before the first retry.  The delay
+// doubles on each further retry.
+var (
+	connectionRetries = 5
+	connectionBackoff = 50 * time.Millisecond
+)
+
+// isTooManyConnections determines whether err is the PostgreSQL
+// error returned when the server has reached its connection limit.
+func isTooManyConnections(err error) bool {
+	pqerr, ok := err.(*pq.Error)
+	return ok && pqerr.Code == "53300"
+}
+
 // withTx calls some function with a database/sql transaction object.
 // If f panics or returns a non-nil error, rolls the transaction back;
 // otherwise commits it before returning.  Returns the error value from
 // f, or some other error related to transaction management.
+//
+// If the database server refuses the connection because it has too
+// many clients, retries a few times with a short backoff before
+// giving up.
 func withTx(c coordinable, readOnly bool, f func(*sql.Tx) error) (err error) {
 	var (
-		tx   *sql.Tx
-		done bool
+		tx      *sql.Tx
+		done    bool
+		tries   int
+		backoff = connectionBackoff
 	)
 
 	// If we have a failure, roll back; and if that rollback fails
@@ -63,6 +85,13 @@ func withTx(c coordinable, readOnly bool, f func(*sql.Tx) error) (err error) {
 	for {
 		// Create the transaction
 		tx, err = c.Coordinate().db.Begin()
+		if isTooManyConnections(err) && tries < connectionRetries {
+			tries++
+			contentionRetries.WithLabelValues(contentionConnection).Inc()
+			time.Sleep(backoff)
+			backoff *= 2
+			continue
+		}
 		if err != nil {
 			return
 		}
@@ -100,6 +129,7 @@ func withTx(c coordinable, readOnly bool, f func(*sql.Tx) error) (err error) {
 					return
 				}
 				tx = nil
+				contentionRetries.WithLabelValues(contentionTransaction).Inc()
 				continue
 
 			case "23503":
@@ -239,6 +269,26 @@ func sqlToDuration(sql string) (time.Duration, error) {
 	return d, err
 }
 
+// durationsToSQL converts a list of time.Duration to an array of
+// floating-point seconds.
+func durationsToSQL(ds []time.Duration) pq.Float64Array {
+	seconds := make(pq.Float64Array, len(ds))
+	for i, d := range ds {
+		seconds[i] = d.Seconds()
+	}
+	return seconds
+}
+
+// sqlToDurations converts an array of floating-point seconds to a
+// list of time.Duration, mapping an empty array to nil.
+func sqlToDurations(seconds pq.Float64Array) []time.Duration {
+	var ds []time.Duration
+	for _, s := range seconds {
+		ds = append(ds, time.Duration(s*float64(time.Second)))
+	}
+	return ds
+}
+
 // timeToNullTime encodes a time as a pq-specific NullTime, by mapping the
 // zero time to null.
 func timeToNullTime(t time.Time) pq.NullTime {
@@ -4,9 +4,16 @@
 package postgres
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"testing"
 	"time"
 
+	"github.com/benbjohnson/clock"
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -40,3 +47,94 @@ func TestSQLToDuration(t *testing.T) {
 		}
 	}
 }
+
+// crowdedConnector is a database/sql connector whose first few
+// connections fail as though the server had too many clients.
+// Connections that do succeed can only begin and end transactions.
+type crowdedConnector struct {
+	failures int
+	opens    int
+}
+
+func (c *crowdedConnector) Connect(context.Context) (driver.Conn, error) {
+	c.opens++
+	if c.opens <= c.failures {
+		return nil, &pq.Error{
+			Code:    "53300",
+			Message: "sorry, too many clients already",
+		}
+	}
+	return crowdedConn{}, nil
+}
+
+func (c *crowdedConnector) Driver() driver.Driver {
+	return nil
+}
+
+type crowdedConn struct{}
+
+func (crowdedConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (crowdedConn) Close() error {
+	return nil
+}
+
+func (crowdedConn) Begin() (driver.Tx, error) {
+	return crowdedConn{}, nil
+}
+
+func (crowdedConn) Commit() error {
+	return nil
+}
+
+func (crowdedConn) Rollback() error {
+	return nil
+}
+
+// withCrowdedServer runs a test function with a coordinate object
+// whose database fails the first few connections.
+func withCrowdedServer(failures int, f func(*pgCoordinate, *crowdedConnector)) {
+	oldBackoff := connectionBackoff
+	connectionBackoff = time.Millisecond
+	defer func() { connectionBackoff = oldBackoff }()
+
+	connector := &crowdedConnector{failures: failures}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	f(&pgCoordinate{db: db, clock: clock.NewMock()}, connector)
+}
+
+// TestTooManyConnectionsRetried checks that withTx retries when the
+// server briefly refuses connections.
+func TestTooManyConnectionsRetried(t *testing.T) {
+	withCrowdedServer(2, func(c *pgCoordinate, connector *crowdedConnector) {
+		counter := contentionRetries.WithLabelValues(contentionConnection)
+		before := testutil.ToFloat64(counter)
+		ran := false
+		err := withTx(c, false, func(tx *sql.Tx) error {
+			ran = true
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.True(t, ran)
+		assert.Equal(t, 3, connector.opens)
+		assert.Equal(t, before+2, testutil.ToFloat64(counter))
+	})
+}
+
+// TestTooManyConnectionsGivesUp checks that withTx eventually
+// returns the error if the server keeps refusing connections.
+func TestTooManyConnectionsGivesUp(t *testing.T) {
+	withCrowdedServer(100, func(c *pgCoordinate, connector *crowdedConnector) {
+		ran := false
+		err := withTx(c, false, func(tx *sql.Tx) error {
+			ran = true
+			return nil
+		})
+		assert.True(t, isTooManyConnections(err))
+		assert.False(t, ran)
+		assert.Equal(t, connectionRetries+1, connector.opens)
+	})
+}
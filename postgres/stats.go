@@ -50,25 +50,9 @@ func summarize(
 		if err != nil {
 			return err
 		}
-		if !status.Valid {
-			if delayed {
-				record.Status = coordinate.DelayedUnit
-			} else {
-				record.Status = coordinate.AvailableUnit
-			}
-		} else {
-			switch status.String {
-			case "expired":
-				record.Status = coordinate.AvailableUnit
-			case "retryable":
-				record.Status = coordinate.AvailableUnit
-			case "pending":
-				record.Status = coordinate.PendingUnit
-			case "finished":
-				record.Status = coordinate.FinishedUnit
-			case "failed":
-				record.Status = coordinate.FailedUnit
-			}
+		record.Status, err = unitStatus(status, delayed)
+		if err != nil {
+			return err
 		}
 		result = append(result, record)
 		return nil
@@ -5,6 +5,9 @@ package postgres
 
 import (
 	"database/sql"
+	"strings"
+	"time"
+
 	"github.com/diffeo/go-coordinate/coordinate"
 	"github.com/lib/pq"
 )
@@ -44,6 +47,9 @@ func (ns *namespace) SetWorkSpec(data map[string]interface{}) (coordinate.WorkSp
 		} else if err == sql.ErrNoRows {
 			var dataBytes []byte
 			dataBytes, err = mapToBytes(data)
+			if err == nil {
+				err = ns.Coordinate().checkWorkSpecData(name, dataBytes)
+			}
 			if err != nil {
 				return err
 			}
@@ -56,6 +62,8 @@ func (ns *namespace) SetWorkSpec(data map[string]interface{}) (coordinate.WorkSp
 			fields.Add(&params, "weight", meta.Weight)
 			fields.Add(&params, "paused", meta.Paused)
 			fields.Add(&params, "continuous", meta.Continuous)
+			fields.Add(&params, "continuous_paused", meta.ContinuousPaused)
+			fields.Add(&params, "gated", meta.Gated)
 			fields.Add(&params, "can_be_continuous", meta.CanBeContinuous)
 			fields.Add(&params, "min_memory_gb", meta.MinMemoryGb)
 			fields.Add(&params, "interval", durationToSQL(meta.Interval))
@@ -63,9 +71,16 @@ func (ns *namespace) SetWorkSpec(data map[string]interface{}) (coordinate.WorkSp
 			fields.Add(&params, "max_running", meta.MaxRunning)
 			fields.Add(&params, "max_attempts_returned", meta.MaxAttemptsReturned)
 			fields.Add(&params, "max_retries", meta.MaxRetries)
+			fields.Add(&params, "expire_with_worker", meta.ExpireWithWorker)
+			fields.Add(&params, "max_lease_total", durationToSQL(meta.MaxLeaseTotal))
+			fields.Add(&params, "heartbeat_extension", durationToSQL(meta.HeartbeatExtension))
+			fields.Add(&params, "default_lease", durationToSQL(meta.DefaultLease))
+			fields.Add(&params, "retry_delays", durationsToSQL(meta.RetryDelays))
 			fields.Add(&params, "next_work_spec_name", meta.NextWorkSpecName)
 			fields.AddDirect("next_work_spec_preempts", "FALSE")
 			fields.Add(&params, "runtime", meta.Runtime)
+			fields.Add(&params, "unit_order", meta.Order)
+			fields.Add(&params, "schema_version", meta.SchemaVersion)
 			query = fields.InsertStatement(workSpecTable) + "RETURNING id"
 			row = tx.QueryRow(query, params...)
 			err = row.Scan(&spec.id)
@@ -124,6 +139,29 @@ func (ns *namespace) DestroyWorkSpec(name string) error {
 	return err
 }
 
+func (ns *namespace) DestroyWorkSpecs(names []string) (int, error) {
+	if len(names) == 0 {
+		return 0, nil
+	}
+	params := queryParams{}
+	nameparams := make([]string, len(names))
+	for i, name := range names {
+		nameparams[i] = params.Param(name)
+	}
+	query := "DELETE FROM " + workSpecTable + " " +
+		"WHERE " + workSpecInNamespace(&params, ns.id) + " " +
+		"AND " + workSpecName + " IN (" + strings.Join(nameparams, ", ") + ")"
+	var count int64
+	err := withTx(ns, false, func(tx *sql.Tx) error {
+		result, err := tx.Exec(query, params...)
+		if err == nil {
+			count, err = result.RowsAffected()
+		}
+		return err
+	})
+	return int(count), err
+}
+
 func (ns *namespace) WorkSpecNames() (result []string, err error) {
 	params := queryParams{}
 	query := buildSelect([]string{
@@ -132,7 +170,7 @@ func (ns *namespace) WorkSpecNames() (result []string, err error) {
 		workSpecTable,
 	}, []string{
 		workSpecInNamespace(&params, ns.id),
-	})
+	}) + " ORDER BY " + workSpecName
 	err = queryAndScan(ns, query, params, func(rows *sql.Rows) error {
 		var name string
 		if err := rows.Scan(&name); err == nil {
@@ -149,23 +187,32 @@ func (spec *workSpec) Name() string {
 	return spec.name
 }
 
-func (spec *workSpec) Data() (map[string]interface{}, error) {
-	var dataBytes []byte
-	err := withTx(spec, true, func(tx *sql.Tx) error {
-		row := tx.QueryRow("SELECT data FROM work_spec WHERE id=$1", spec.id)
-		return row.Scan(&dataBytes)
+func (spec *workSpec) Namespace() coordinate.Namespace {
+	return spec.namespace
+}
+
+func (spec *workSpec) Data() (data map[string]interface{}, err error) {
+	err = withTx(spec, true, func(tx *sql.Tx) error {
+		var err error
+		data, err = spec.txData(tx)
+		return err
 	})
+	return
+}
+
+// txData retrieves the data dictionary for this work spec within an
+// existing transaction.
+func (spec *workSpec) txData(tx *sql.Tx) (map[string]interface{}, error) {
+	var dataBytes []byte
+	row := tx.QueryRow("SELECT data FROM work_spec WHERE id=$1", spec.id)
+	err := row.Scan(&dataBytes)
 	if err == sql.ErrNoRows {
 		return nil, coordinate.ErrGone
 	}
 	if err != nil {
 		return nil, err
 	}
-	data, err := bytesToMap(dataBytes)
-	if err != nil {
-		return nil, err
-	}
-	return data, nil
+	return bytesToMap(dataBytes)
 }
 
 func (spec *workSpec) SetData(data map[string]interface{}) error {
@@ -183,6 +230,9 @@ func (spec *workSpec) SetData(data map[string]interface{}) error {
 
 func (spec *workSpec) setData(tx *sql.Tx, data map[string]interface{}, meta coordinate.WorkSpecMeta) error {
 	dataBytes, err := mapToBytes(data)
+	if err == nil {
+		err = spec.Coordinate().checkWorkSpecData(spec.name, dataBytes)
+	}
 	if err != nil {
 		return err
 	}
@@ -194,6 +244,9 @@ func (spec *workSpec) setData(tx *sql.Tx, data map[string]interface{}, meta coor
 	fields.Add(&params, "weight", meta.Weight)
 	fields.Add(&params, "paused", meta.Paused)
 	fields.Add(&params, "continuous", meta.Continuous)
+	fields.Add(&params, "continuous_paused", meta.ContinuousPaused)
+	// "gated" is left alone, since the work spec data does
+	// not set it
 	fields.Add(&params, "can_be_continuous", meta.CanBeContinuous)
 	fields.Add(&params, "min_memory_gb", meta.MinMemoryGb)
 	fields.Add(&params, "interval", durationToSQL(meta.Interval))
@@ -201,9 +254,16 @@ func (spec *workSpec) setData(tx *sql.Tx, data map[string]interface{}, meta coor
 	fields.Add(&params, "max_running", meta.MaxRunning)
 	fields.Add(&params, "max_attempts_returned", meta.MaxAttemptsReturned)
 	fields.Add(&params, "max_retries", meta.MaxRetries)
+	fields.Add(&params, "expire_with_worker", meta.ExpireWithWorker)
+	fields.Add(&params, "max_lease_total", durationToSQL(meta.MaxLeaseTotal))
+	fields.Add(&params, "heartbeat_extension", durationToSQL(meta.HeartbeatExtension))
+	fields.Add(&params, "default_lease", durationToSQL(meta.DefaultLease))
+	fields.Add(&params, "retry_delays", durationsToSQL(meta.RetryDelays))
 	fields.Add(&params, "next_work_spec_name", meta.NextWorkSpecName)
 	fields.AddDirect("next_work_spec_preempts", "FALSE")
 	fields.Add(&params, "runtime", meta.Runtime)
+	fields.Add(&params, "unit_order", meta.Order)
+	fields.Add(&params, "schema_version", meta.SchemaVersion)
 	query := buildUpdate(workSpecTable, fields.UpdateChanges(), []string{
 		isWorkSpec(&params, spec.id),
 	})
@@ -214,104 +274,139 @@ func (spec *workSpec) Meta(withCounts bool) (coordinate.WorkSpecMeta, error) {
 	// If we need counts, we need to run expiry so that the
 	// available/pending counts are rightish
 	if withCounts {
-		spec.Coordinate().Expiry.Do(spec)
+		spec.Coordinate().Expiry.DoForSpec(spec)
 	}
 	var meta coordinate.WorkSpecMeta
 	err := withTx(spec, true, func(tx *sql.Tx) error {
-		var (
-			params         queryParams
-			query          string
-			interval       string
-			nextContinuous pq.NullTime
-		)
-		query = buildSelect([]string{
-			workSpecPriority,
-			workSpecWeight,
-			workSpecPaused,
-			workSpecContinuous,
-			workSpecCanBeContinuous,
-			workSpecMinMemoryGb,
-			workSpecInterval,
-			workSpecNextContinuous,
-			workSpecMaxRunning,
-			workSpecMaxAttemptsReturned,
-			workSpecMaxRetries,
-			workSpecNextWorkSpec,
-			workSpecRuntime,
-		}, []string{
-			workSpecTable,
-		}, []string{
-			isWorkSpec(&params, spec.id),
-		})
-		row := tx.QueryRow(query, params...)
-		err := row.Scan(
-			&meta.Priority,
-			&meta.Weight,
-			&meta.Paused,
-			&meta.Continuous,
-			&meta.CanBeContinuous,
-			&meta.MinMemoryGb,
-			&interval,
-			&nextContinuous,
-			&meta.MaxRunning,
-			&meta.MaxAttemptsReturned,
-			&meta.MaxRetries,
-			&meta.NextWorkSpecName,
-			&meta.Runtime,
-		)
-		if err == sql.ErrNoRows {
-			return coordinate.ErrGone
-		}
-		if err != nil {
-			return err
-		}
-		meta.NextContinuous = nullTimeToTime(nextContinuous)
-		meta.Interval, err = sqlToDuration(interval)
-		if err != nil {
-			return err
-		}
+		var err error
+		meta, err = spec.txMeta(tx, withCounts)
+		return err
+	})
+	return meta, err
+}
 
-		// Find counts with a second query, if requested
-		if !withCounts {
-			return nil
+func (spec *workSpec) Summary() (summary coordinate.WorkSpecSummary, err error) {
+	spec.Coordinate().Expiry.DoForSpec(spec)
+	err = withTx(spec, true, func(tx *sql.Tx) error {
+		var err error
+		summary.Data, err = spec.txData(tx)
+		if err == nil {
+			summary.Meta, err = spec.txMeta(tx, true)
 		}
-		params = queryParams{}
-		query = buildSelect([]string{
-			attemptStatus,
-			"COUNT(*)",
-		}, []string{
-			workUnitAttemptJoin,
-		}, []string{
-			workUnitInSpec(&params, spec.id),
-		})
-		query += " GROUP BY " + attemptStatus
-		rows, err := tx.Query(query, params...)
-		if err != nil {
-			return err
+		if err == nil {
+			summary.Counts, err = spec.txCountWorkUnitStatus(tx)
 		}
-		return scanRows(rows, func() error {
-			var status sql.NullString
-			var count int
-			err := rows.Scan(&status, &count)
-			if err != nil {
-				return err
-			}
-			if !status.Valid {
-				meta.AvailableCount += count
-			} else {
-				switch status.String {
-				case "expired":
-					meta.AvailableCount += count
-				case "retryable":
-					meta.AvailableCount += count
-				case "pending":
-					meta.PendingCount += count
-				}
-			}
-			return nil
-		})
+		return err
 	})
-	return meta, err
+	return
+}
+
+// txMeta retrieves the metadata for this work spec within an
+// existing transaction.  If counts are requested, the caller should
+// run expiry first.
+func (spec *workSpec) txMeta(tx *sql.Tx, withCounts bool) (coordinate.WorkSpecMeta, error) {
+	var (
+		meta           coordinate.WorkSpecMeta
+		params         queryParams
+		query          string
+		interval       string
+		maxLeaseTotal  string
+		heartbeatExt   string
+		defaultLease   string
+		retryDelays    pq.Float64Array
+		nextContinuous pq.NullTime
+	)
+	query = buildSelect([]string{
+		workSpecPriority,
+		workSpecWeight,
+		workSpecPaused,
+		workSpecContinuous,
+		workSpecContinuousPaused,
+		workSpecGated,
+		workSpecCanBeContinuous,
+		workSpecMinMemoryGb,
+		workSpecInterval,
+		workSpecNextContinuous,
+		workSpecMaxRunning,
+		workSpecMaxAttemptsReturned,
+		workSpecMaxRetries,
+		workSpecExpireWithWorker,
+		workSpecMaxLeaseTotal,
+		workSpecHeartbeatExtension,
+		workSpecDefaultLease,
+		workSpecNextWorkSpec,
+		workSpecRuntime,
+		workSpecUnitOrder,
+		workSpecSchemaVersion,
+		workSpecRetryDelays,
+	}, []string{
+		workSpecTable,
+	}, []string{
+		isWorkSpec(&params, spec.id),
+	})
+	row := tx.QueryRow(query, params...)
+	err := row.Scan(
+		&meta.Priority,
+		&meta.Weight,
+		&meta.Paused,
+		&meta.Continuous,
+		&meta.ContinuousPaused,
+		&meta.Gated,
+		&meta.CanBeContinuous,
+		&meta.MinMemoryGb,
+		&interval,
+		&nextContinuous,
+		&meta.MaxRunning,
+		&meta.MaxAttemptsReturned,
+		&meta.MaxRetries,
+		&meta.ExpireWithWorker,
+		&maxLeaseTotal,
+		&heartbeatExt,
+		&defaultLease,
+		&meta.NextWorkSpecName,
+		&meta.Runtime,
+		&meta.Order,
+		&meta.SchemaVersion,
+		&retryDelays,
+	)
+	if err == sql.ErrNoRows {
+		return meta, coordinate.ErrGone
+	}
+	if err != nil {
+		return meta, err
+	}
+	meta.NextContinuous = nullTimeToTime(nextContinuous)
+	meta.Interval, err = sqlToDuration(interval)
+	if err != nil {
+		return meta, err
+	}
+	meta.MaxLeaseTotal, err = sqlToDuration(maxLeaseTotal)
+	if err != nil {
+		return meta, err
+	}
+	meta.HeartbeatExtension, err = sqlToDuration(heartbeatExt)
+	if err != nil {
+		return meta, err
+	}
+	meta.DefaultLease, err = sqlToDuration(defaultLease)
+	if err != nil {
+		return meta, err
+	}
+	meta.RetryDelays = sqlToDurations(retryDelays)
+
+	// Find counts with a second query, if requested
+	if !withCounts {
+		return meta, nil
+	}
+	// Delayed work units are not available, matching
+	// CountWorkUnitStatus()
+	counts, err := spec.txCountWorkUnitStatus(tx)
+	if err != nil {
+		return meta, err
+	}
+	meta.AvailableCount = counts[coordinate.AvailableUnit]
+	meta.PendingCount = counts[coordinate.PendingUnit]
+	return meta, nil
 }
 
 // AllMetas retrieves the metadata for all work specs.  This is
@@ -327,6 +422,8 @@ func (ns *namespace) allMetas(tx *sql.Tx, withCounts bool) (map[string]*workSpec
 		workSpecWeight,
 		workSpecPaused,
 		workSpecContinuous,
+		workSpecContinuousPaused,
+		workSpecGated,
 		workSpecCanBeContinuous,
 		workSpecMinMemoryGb,
 		workSpecInterval,
@@ -334,8 +431,15 @@ func (ns *namespace) allMetas(tx *sql.Tx, withCounts bool) (map[string]*workSpec
 		workSpecMaxRunning,
 		workSpecMaxAttemptsReturned,
 		workSpecMaxRetries,
+		workSpecExpireWithWorker,
+		workSpecMaxLeaseTotal,
+		workSpecHeartbeatExtension,
+		workSpecDefaultLease,
 		workSpecNextWorkSpec,
 		workSpecRuntime,
+		workSpecUnitOrder,
+		workSpecSchemaVersion,
+		workSpecRetryDelays,
 	}, []string{
 		workSpecTable,
 	}, []string{
@@ -352,15 +456,25 @@ func (ns *namespace) allMetas(tx *sql.Tx, withCounts bool) (map[string]*workSpec
 			spec           workSpec
 			meta           coordinate.WorkSpecMeta
 			interval       string
+			maxLeaseTotal  string
+			heartbeatExt   string
+			defaultLease   string
+			retryDelays    pq.Float64Array
 			nextContinuous pq.NullTime
 			err            error
 		)
 		err = rows.Scan(&spec.id, &spec.name, &meta.Priority,
 			&meta.Weight, &meta.Paused, &meta.Continuous,
-			&meta.CanBeContinuous, &meta.MinMemoryGb,
+			&meta.ContinuousPaused, &meta.Gated,
+			&meta.CanBeContinuous,
+			&meta.MinMemoryGb,
 			&interval, &nextContinuous, &meta.MaxRunning,
 			&meta.MaxAttemptsReturned, &meta.MaxRetries,
-			&meta.NextWorkSpecName, &meta.Runtime)
+			&meta.ExpireWithWorker, &maxLeaseTotal,
+			&heartbeatExt, &defaultLease,
+			&meta.NextWorkSpecName,
+			&meta.Runtime, &meta.Order,
+			&meta.SchemaVersion, &retryDelays)
 		if err != nil {
 			return err
 		}
@@ -370,6 +484,19 @@ func (ns *namespace) allMetas(tx *sql.Tx, withCounts bool) (map[string]*workSpec
 		if err != nil {
 			return err
 		}
+		meta.MaxLeaseTotal, err = sqlToDuration(maxLeaseTotal)
+		if err != nil {
+			return err
+		}
+		meta.HeartbeatExtension, err = sqlToDuration(heartbeatExt)
+		if err != nil {
+			return err
+		}
+		meta.DefaultLease, err = sqlToDuration(defaultLease)
+		if err != nil {
+			return err
+		}
+		meta.RetryDelays = sqlToDurations(retryDelays)
 		specs[spec.name] = &spec
 		metas[spec.name] = &meta
 		return nil
@@ -444,6 +571,43 @@ func (ns *namespace) allMetas(tx *sql.Tx, withCounts bool) (map[string]*workSpec
 	return specs, metas, nil
 }
 
+// unitRuntimes finds the runtime overrides of available work units
+// in this namespace.  It returns a map from work spec name to the
+// distinct non-empty WorkUnitMeta.Runtime values of that work spec's
+// available work units.  This is expected to run within a
+// pre-existing transaction.
+func (ns *namespace) unitRuntimes(tx *sql.Tx) (map[string][]string, error) {
+	now := ns.Coordinate().clock.Now()
+	params := queryParams{}
+	query := buildSelect([]string{
+		"DISTINCT " + workSpecName,
+		workUnitRuntime,
+	}, []string{
+		workSpecTable,
+		workUnitTable,
+	}, []string{
+		workSpecInNamespace(&params, ns.id),
+		workUnitInThisSpec,
+		workUnitRuntime + "<>''",
+		workUnitHasNoAttempt,
+		"NOT " + workUnitTooSoon(&params, now),
+	})
+	rows, err := tx.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string][]string)
+	err = scanRows(rows, func() error {
+		var name, runtime string
+		err := rows.Scan(&name, &runtime)
+		if err == nil {
+			result[name] = append(result[name], runtime)
+		}
+		return err
+	})
+	return result, err
+}
+
 func (spec *workSpec) SetMeta(meta coordinate.WorkSpecMeta) error {
 	// There are a couple of fields we can't set; in this implementation
 	// we can just not update them and be done with it.
@@ -453,18 +617,47 @@ func (spec *workSpec) SetMeta(meta coordinate.WorkSpecMeta) error {
 	fields.Add(&params, "weight", meta.Weight)
 	fields.Add(&params, "paused", meta.Paused)
 	fields.AddDirect("continuous", params.Param(meta.Continuous)+" AND can_be_continuous")
+	fields.Add(&params, "continuous_paused", meta.ContinuousPaused)
+	fields.Add(&params, "gated", meta.Gated)
 	fields.Add(&params, "min_memory_gb", meta.MinMemoryGb)
 	fields.Add(&params, "interval", durationToSQL(meta.Interval))
 	fields.Add(&params, "next_continuous", timeToNullTime(meta.NextContinuous))
 	fields.Add(&params, "max_running", meta.MaxRunning)
 	fields.Add(&params, "max_attempts_returned", meta.MaxAttemptsReturned)
 	fields.Add(&params, "max_retries", meta.MaxRetries)
+	fields.Add(&params, "expire_with_worker", meta.ExpireWithWorker)
+	fields.Add(&params, "max_lease_total", durationToSQL(meta.MaxLeaseTotal))
+	fields.Add(&params, "heartbeat_extension", durationToSQL(meta.HeartbeatExtension))
+	fields.Add(&params, "default_lease", durationToSQL(meta.DefaultLease))
+	fields.Add(&params, "retry_delays", durationsToSQL(meta.RetryDelays))
 	query := buildUpdate(workSpecTable, fields.UpdateChanges(), []string{
 		isWorkSpec(&params, spec.id),
 	})
 	return execInTx(spec, query, params, true)
 }
 
+func (spec *workSpec) SetNextContinuous(t time.Time) error {
+	params := queryParams{}
+	fields := fieldList{}
+	fields.Add(&params, "next_continuous", timeToNullTime(t))
+	query := buildUpdate(workSpecTable, fields.UpdateChanges(), []string{
+		isWorkSpec(&params, spec.id),
+	})
+	return execInTx(spec, query, params, true)
+}
+
+func (spec *workSpec) IsSchedulable() (bool, string, error) {
+	meta, err := spec.Meta(true)
+	if err != nil {
+		return false, "", err
+	}
+	ok, reason := meta.Schedulable(spec.Coordinate().clock.Now())
+	if ok && !spec.Coordinate().gate.Allow(spec.namespace.name, spec.name) {
+		ok, reason = false, "gated"
+	}
+	return ok, reason, nil
+}
+
 // coordinable interface:
 
 func (spec *workSpec) Coordinate() *pgCoordinate {
@@ -41,6 +41,8 @@ func (spec *workSpec) insertWorkUnit(tx *sql.Tx, name string, dataBytes []byte,
 	fields.Add(&params, "data", dataBytes)
 	fields.Add(&params, "priority", meta.Priority)
 	fields.Add(&params, "not_before", timeToNullTime(meta.NotBefore))
+	fields.Add(&params, "runtime", meta.Runtime)
+	fields.Add(&params, "created_at", spec.Coordinate().clock.Now())
 	query := fields.InsertStatement(workUnitTable) + " RETURNING id"
 	err := tx.QueryRow(query, params...).Scan(&unit.id)
 	return &unit, err
@@ -67,6 +69,7 @@ func isDuplicateUnitName(err error) bool {
 // transactions, principally because it needs to be able to retry on a
 // failed INSERT.
 func (spec *workSpec) addWorkUnit(name string, dataBytes []byte, meta coordinate.WorkUnitMeta) (unit *workUnit, err error) {
+	name = spec.Coordinate().keys.Key(name)
 	// This is, fundamentally, an UPSERT.  PostgreSQL 9.5 has
 	// support for it but is (as of this writing) extremely new.
 	// SERIALIZABLE transaction mode should in theory help --
@@ -85,6 +88,9 @@ func (spec *workSpec) addWorkUnit(name string, dataBytes []byte, meta coordinate
 		err = withTx(spec, false, func(tx *sql.Tx) error {
 			var err error
 			unit, err = spec.insertWorkUnit(tx, name, dataBytes, meta)
+			if err == nil {
+				err = notifyWorkSpecs(tx, spec.id)
+			}
 			return err
 		})
 		if err == sql.ErrNoRows {
@@ -99,47 +105,18 @@ func (spec *workSpec) addWorkUnit(name string, dataBytes []byte, meta coordinate
 
 		// Okay, so it already exists.  Let's try to UPDATE
 		// an existing unit.
-		unit = &workUnit{spec: spec, name: name}
-		params := queryParams{}
-		fields := fieldList{}
-		fields.Add(&params, "data", dataBytes)
-		fields.Add(&params, "priority", meta.Priority)
-		fields.Add(&params, "not_before", timeToNullTime(meta.NotBefore))
-		query := buildUpdate(workUnitTable,
-			fields.UpdateChanges(),
-			[]string{
-				workUnitInSpec(&params, spec.id),
-				workUnitHasName(&params, name),
-			}) +
-			" RETURNING id"
-
-		// Let's also set up a second query.  If that UPDATE
-		// does return a work unit, and it has an active
-		// attempt, and the attempt is not pending, then we
-		// need to (within the same transaction) clear the
-		// active attempt.  This is a little more complicated,
-		// and involves some non-default syntax, so let's
-		// write it out:
-		queryAttempt := "UPDATE " + workUnitTable + " " +
-			"SET active_attempt_id=NULL " +
-			"FROM " + attemptTable + " " +
-			"WHERE " + workUnitID + "=$1 " +
-			"AND " + attemptIsTheActive + " " +
-			"AND " + attemptStatus + "!='pending'"
-
 		err = withTx(spec, false, func(tx *sql.Tx) error {
-			row := tx.QueryRow(query, params...)
-			err := row.Scan(&unit.id)
+			var err error
 			// Could be ErrNoRows; we'll just return that
-			// If that is successful, though, do the
-			// second update for the active attempt
+			unit, err = spec.updateWorkUnit(tx, name, dataBytes, meta)
+			// Updating an existing unit may have made it
+			// available again
 			if err == nil {
-				_, err = tx.Exec(queryAttempt, unit.id)
+				err = notifyWorkSpecs(tx, spec.id)
 			}
 			return err
 		})
 		if err == nil {
-			// Updated an existing unit
 			return
 		}
 		if err != sql.ErrNoRows {
@@ -147,10 +124,92 @@ func (spec *workSpec) addWorkUnit(name string, dataBytes []byte, meta coordinate
 			return
 		}
 		// Otherwise the update didn't find anything; reloop
+		contentionRetries.WithLabelValues(contentionAddWorkUnit).Inc()
+	}
+}
+
+// updateWorkUnit replaces the data and metadata of an existing work
+// unit, making it available again if it had finished.  Returns
+// sql.ErrNoRows if there is no work unit with this name.
+func (spec *workSpec) updateWorkUnit(tx *sql.Tx, name string, dataBytes []byte, meta coordinate.WorkUnitMeta) (*workUnit, error) {
+	unit := workUnit{spec: spec, name: name}
+	params := queryParams{}
+	fields := fieldList{}
+	fields.Add(&params, "data", dataBytes)
+	fields.Add(&params, "priority", meta.Priority)
+	fields.Add(&params, "not_before", timeToNullTime(meta.NotBefore))
+	fields.Add(&params, "runtime", meta.Runtime)
+	query := buildUpdate(workUnitTable,
+		fields.UpdateChanges(),
+		[]string{
+			workUnitInSpec(&params, spec.id),
+			workUnitHasName(&params, name),
+		}) +
+		" RETURNING id"
+	err := tx.QueryRow(query, params...).Scan(&unit.id)
+	if err != nil {
+		return nil, err
+	}
+
+	// If that UPDATE does return a work unit, and it has an
+	// active attempt, and the attempt is not pending, then we
+	// need to (within the same transaction) clear the active
+	// attempt.  This is a little more complicated, and involves
+	// some non-default syntax, so let's write it out:
+	queryAttempt := "UPDATE " + workUnitTable + " " +
+		"SET active_attempt_id=NULL " +
+		"FROM " + attemptTable + " " +
+		"WHERE " + workUnitID + "=$1 " +
+		"AND " + attemptIsTheActive + " " +
+		"AND " + attemptStatus + "!='pending'"
+	_, err = tx.Exec(queryAttempt, unit.id)
+	if err != nil {
+		return nil, err
+	}
+	return &unit, nil
+}
+
+// AddWorkUnits implements coordinate.WorkUnitBatchAdder, adding all
+// of the items in a single transaction.  Each item updates an
+// existing work unit if there is one and inserts a new one
+// otherwise; if another caller inserts one of the same work units
+// concurrently, the whole transaction is retried.
+func (spec *workSpec) AddWorkUnits(items []coordinate.AddWorkUnitItem) error {
+	names := make([]string, len(items))
+	dataBytes := make([][]byte, len(items))
+	for i, item := range items {
+		var err error
+		names[i] = spec.Coordinate().keys.Key(item.Key)
+		dataBytes[i], err = mapToBytes(item.Data)
+		if err != nil {
+			return err
+		}
+	}
+	for {
+		err := withTx(spec, false, func(tx *sql.Tx) error {
+			for i, item := range items {
+				_, err := spec.updateWorkUnit(tx, names[i], dataBytes[i], item.Meta)
+				if err == sql.ErrNoRows {
+					_, err = spec.insertWorkUnit(tx, names[i], dataBytes[i], item.Meta)
+				}
+				if err == sql.ErrNoRows {
+					return coordinate.ErrGone
+				}
+				if err != nil {
+					return err
+				}
+			}
+			return notifyWorkSpecs(tx, spec.id)
+		})
+		if !isDuplicateUnitName(err) {
+			return err
+		}
+		contentionRetries.WithLabelValues(contentionAddWorkUnit).Inc()
 	}
 }
 
 func (spec *workSpec) WorkUnit(name string) (coordinate.WorkUnit, error) {
+	name = spec.Coordinate().keys.Key(name)
 	unit := workUnit{spec: spec, name: name}
 	params := queryParams{}
 	query := buildSelect([]string{
@@ -187,7 +246,7 @@ func (spec *workSpec) selectUnits(q coordinate.WorkUnitQuery, now time.Time) (st
 
 	if len(q.Names) > 0 {
 		nameparams := make([]string, len(q.Names))
-		for i, name := range q.Names {
+		for i, name := range spec.Coordinate().keys.Keys(q.Names) {
 			nameparams[i] = params.Param(name)
 		}
 		cond := "name IN (" + strings.Join(nameparams, ", ") + ")"
@@ -196,7 +255,7 @@ func (spec *workSpec) selectUnits(q coordinate.WorkUnitQuery, now time.Time) (st
 
 	if len(q.Statuses) > 0 {
 		var statusBits []string
-		var foundAny bool
+		var foundAny, needAttempt bool
 		for _, status := range q.Statuses {
 			switch status {
 			case coordinate.AnyStatus:
@@ -205,10 +264,13 @@ func (spec *workSpec) selectUnits(q coordinate.WorkUnitQuery, now time.Time) (st
 				statusBits = append(statusBits, workUnitAvailable(&params, now))
 			case coordinate.PendingUnit:
 				statusBits = append(statusBits, attemptStatus+"='pending'")
+				needAttempt = true
 			case coordinate.FinishedUnit:
 				statusBits = append(statusBits, attemptStatus+"='finished'")
+				needAttempt = true
 			case coordinate.FailedUnit:
 				statusBits = append(statusBits, attemptStatus+"='failed'")
+				needAttempt = true
 			case coordinate.DelayedUnit:
 				statusBits = append(statusBits, workUnitDelayed(&params, now))
 				// Anything else is an internal error but
@@ -218,9 +280,14 @@ func (spec *workSpec) selectUnits(q coordinate.WorkUnitQuery, now time.Time) (st
 		// If AnyStatus was in the list, then this is really
 		// a no-op; possibly AnyStatus should just go away
 		if !foundAny {
-			// Do an outer join on available attempt; this
+			// Available and delayed work units have no active
+			// attempt, so those only need the work unit table,
+			// and can use the partial index on it; otherwise
+			// do an outer join on the active attempt, which
 			// replaces the plain "work_unit" table
-			tables = []string{workUnitAttemptJoin}
+			if needAttempt {
+				tables = []string{workUnitAttemptJoin}
+			}
 			cond := "(" + strings.Join(statusBits, " OR ") + ")"
 			conditions = append(conditions, cond)
 		}
@@ -230,17 +297,44 @@ func (spec *workSpec) selectUnits(q coordinate.WorkUnitQuery, now time.Time) (st
 		conditions = append(conditions, "name>"+params.Param(q.PreviousName))
 	}
 
+	if q.NeverAttempted {
+		anyAttempt := buildSelect([]string{"1"}, []string{attemptTable},
+			[]string{attemptThisWorkUnit})
+		anyArchived := buildSelect([]string{"1"}, []string{attemptArchiveTable},
+			[]string{attemptArchiveWorkUnitID + "=" + workUnitID})
+		conditions = append(conditions,
+			"NOT EXISTS ("+anyAttempt+")",
+			"NOT EXISTS ("+anyArchived+")")
+	}
+
+	if q.WorkerName != "" {
+		activeByWorker := buildSelect([]string{"1"},
+			[]string{attemptTable, workerTable},
+			[]string{
+				attemptIsTheActive,
+				attemptThisWorker,
+				workerHasName(&params, q.WorkerName),
+			})
+		conditions = append(conditions, "EXISTS ("+activeByWorker+")")
+	}
+
 	query := buildSelect(outputs, tables, conditions)
 
+	if q.Offset > 0 || q.Limit > 0 {
+		query += " ORDER BY name ASC"
+	}
 	if q.Limit > 0 {
-		query += fmt.Sprintf(" ORDER BY name ASC LIMIT %v", q.Limit)
+		query += fmt.Sprintf(" LIMIT %v", q.Limit)
+	}
+	if q.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET %v", q.Offset)
 	}
 
 	return query, params
 }
 
 func (spec *workSpec) WorkUnits(q coordinate.WorkUnitQuery) (map[string]coordinate.WorkUnit, error) {
-	spec.Coordinate().Expiry.Do(spec)
+	spec.Coordinate().Expiry.DoForSpec(spec)
 	cte, params := spec.selectUnits(q, spec.Coordinate().clock.Now())
 	query := buildSelect([]string{
 		"id",
@@ -265,8 +359,102 @@ func (spec *workSpec) WorkUnits(q coordinate.WorkUnitQuery) (map[string]coordina
 	return result, nil
 }
 
-func (spec *workSpec) CountWorkUnitStatus() (map[coordinate.WorkUnitStatus]int, error) {
-	spec.Coordinate().Expiry.Do(spec)
+func (spec *workSpec) WorkUnitStatuses(names []string) (map[string]coordinate.WorkUnitStatus, error) {
+	result := make(map[string]coordinate.WorkUnitStatus)
+	if len(names) == 0 {
+		return result, nil
+	}
+	spec.Coordinate().Expiry.DoForSpec(spec)
+	now := spec.Coordinate().clock.Now()
+	params := queryParams{}
+	nameparams := make([]string, len(names))
+	for i, name := range spec.Coordinate().keys.Keys(names) {
+		nameparams[i] = params.Param(name)
+	}
+	query := buildSelect([]string{
+		workUnitName,
+		attemptStatus,
+		workUnitTooSoon(&params, now) + " AS delayed",
+	}, []string{
+		workUnitAttemptJoin,
+	}, []string{
+		workUnitInSpec(&params, spec.id),
+		workUnitName + " IN (" + strings.Join(nameparams, ", ") + ")",
+	})
+	err := queryAndScan(spec, query, params, func(rows *sql.Rows) error {
+		var (
+			name    string
+			ns      sql.NullString
+			delayed bool
+		)
+		err := rows.Scan(&name, &ns, &delayed)
+		if err == nil {
+			result[name], err = unitStatus(ns, delayed)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (spec *workSpec) WorkUnitPriorities(names []string) (map[string]float64, error) {
+	result := make(map[string]float64)
+	if len(names) == 0 {
+		return result, nil
+	}
+	params := queryParams{}
+	nameparams := make([]string, len(names))
+	for i, name := range spec.Coordinate().keys.Keys(names) {
+		nameparams[i] = params.Param(name)
+	}
+	query := buildSelect([]string{
+		workUnitName,
+		workUnitPriority,
+	}, []string{
+		workUnitTable,
+	}, []string{
+		workUnitInSpec(&params, spec.id),
+		workUnitName + " IN (" + strings.Join(nameparams, ", ") + ")",
+	})
+	err := queryAndScan(spec, query, params, func(rows *sql.Rows) error {
+		var (
+			name     string
+			priority float64
+		)
+		err := rows.Scan(&name, &priority)
+		if err == nil {
+			result[name] = priority
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// WorkUnitsPage resolves the query's cursor into a PreviousName,
+// which selectUnits turns into a keyset condition on the name.
+func (spec *workSpec) WorkUnitsPage(q coordinate.WorkUnitQuery) (map[string]coordinate.WorkUnit, string, error) {
+	return coordinate.PageWorkUnits(spec, q)
+}
+
+func (spec *workSpec) CountWorkUnitStatus() (result map[coordinate.WorkUnitStatus]int, err error) {
+	spec.Coordinate().Expiry.DoForSpec(spec)
+	err = withTx(spec, true, func(tx *sql.Tx) error {
+		var err error
+		result, err = spec.txCountWorkUnitStatus(tx)
+		return err
+	})
+	return
+}
+
+// txCountWorkUnitStatus counts the work units in this work spec in
+// each status within an existing transaction.  The caller should run
+// expiry first.
+func (spec *workSpec) txCountWorkUnitStatus(tx *sql.Tx) (map[coordinate.WorkUnitStatus]int, error) {
 	now := spec.Coordinate().clock.Now()
 	result := make(map[coordinate.WorkUnitStatus]int)
 	params := queryParams{}
@@ -279,45 +467,106 @@ func (spec *workSpec) CountWorkUnitStatus() (map[coordinate.WorkUnitStatus]int,
 	}, []string{
 		workUnitInSpec(&params, spec.id),
 	}) + " GROUP BY " + attemptStatus + ", delayed"
-	err := queryAndScan(spec, query, params, func(rows *sql.Rows) error {
+	rows, err := tx.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	err = scanRows(rows, func() error {
 		var (
-			status     sql.NullString
-			unitStatus coordinate.WorkUnitStatus
-			count      int
-			delayed    bool
-			err        error
+			status  sql.NullString
+			count   int
+			delayed bool
 		)
-		err = rows.Scan(&status, &delayed, &count)
+		err := rows.Scan(&status, &delayed, &count)
 		if err != nil {
 			return err
 		}
-		if delayed {
-			unitStatus = coordinate.DelayedUnit
-		} else {
-			unitStatus = coordinate.AvailableUnit
-		}
-		if status.Valid {
-			switch status.String {
-			case "expired", "retryable":
-				// same as "available" more or less
-			case "pending":
-				unitStatus = coordinate.PendingUnit
-			case "finished":
-				unitStatus = coordinate.FinishedUnit
-			case "failed":
-				unitStatus = coordinate.FailedUnit
-			default:
-				return fmt.Errorf("unexpected work unit status %v", status)
-			}
+		us, err := unitStatus(status, delayed)
+		if err == nil {
+			result[us] += count
 		}
-		result[unitStatus] += count
-		return nil
+		return err
 	})
 	return result, err
 }
 
+func (spec *workSpec) DataSize() (size int64, err error) {
+	params := queryParams{}
+	query := buildSelect([]string{
+		"COALESCE(SUM(LENGTH(" + workUnitData + ")), 0)",
+	}, []string{
+		workUnitTable,
+	}, []string{
+		workUnitInSpec(&params, spec.id),
+	})
+	err = withTx(spec, true, func(tx *sql.Tx) error {
+		return tx.QueryRow(query, params...).Scan(&size)
+	})
+	return
+}
+
+func (spec *workSpec) PriorityHistogram() (map[float64]int, error) {
+	result := make(map[float64]int)
+	params := queryParams{}
+	query := buildSelect([]string{
+		workUnitPriority,
+		"COUNT(*)",
+	}, []string{
+		workUnitTable,
+	}, []string{
+		workUnitInSpec(&params, spec.id),
+	}) + " GROUP BY " + workUnitPriority
+	err := withTx(spec, true, func(tx *sql.Tx) error {
+		rows, err := tx.Query(query, params...)
+		if err != nil {
+			return err
+		}
+		return scanRows(rows, func() error {
+			var (
+				priority float64
+				count    int
+			)
+			err := rows.Scan(&priority, &count)
+			if err == nil {
+				result[priority] = count
+			}
+			return err
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (spec *workSpec) Successors() ([]string, error) {
+	var next string
+	params := queryParams{}
+	query := buildSelect([]string{
+		workSpecNextWorkSpec,
+	}, []string{
+		workSpecTable,
+	}, []string{
+		isWorkSpec(&params, spec.id),
+	})
+	err := withTx(spec, true, func(tx *sql.Tx) error {
+		return tx.QueryRow(query, params...).Scan(&next)
+	})
+	if err == sql.ErrNoRows {
+		err = coordinate.ErrGone
+	}
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	if next != "" {
+		names = append(names, next)
+	}
+	return names, nil
+}
+
 func (spec *workSpec) SetWorkUnitPriorities(q coordinate.WorkUnitQuery, priority float64) error {
-	spec.Coordinate().Expiry.Do(spec)
+	spec.Coordinate().Expiry.DoForSpec(spec)
 	cte, params := spec.selectUnits(q, spec.Coordinate().clock.Now())
 	fields := fieldList{}
 	fields.Add(&params, "priority", priority)
@@ -328,7 +577,7 @@ func (spec *workSpec) SetWorkUnitPriorities(q coordinate.WorkUnitQuery, priority
 }
 
 func (spec *workSpec) AdjustWorkUnitPriorities(q coordinate.WorkUnitQuery, priority float64) error {
-	spec.Coordinate().Expiry.Do(spec)
+	spec.Coordinate().Expiry.DoForSpec(spec)
 	cte, params := spec.selectUnits(q, spec.Coordinate().clock.Now())
 	fields := fieldList{}
 	fields.AddDirect("priority", "priority+"+params.Param(priority))
@@ -338,14 +587,93 @@ func (spec *workSpec) AdjustWorkUnitPriorities(q coordinate.WorkUnitQuery, prior
 	return execInTx(spec, query, params, false)
 }
 
+func (spec *workSpec) ResetPriorities(q coordinate.WorkUnitQuery) error {
+	return spec.SetWorkUnitPriorities(q, 0)
+}
+
+func (spec *workSpec) ReorderAvailable(orderedNames []string) error {
+	orderedNames = spec.Coordinate().keys.Keys(orderedNames)
+	spec.Coordinate().Expiry.DoForSpec(spec)
+	// Delayed units are included so that they do not jump ahead
+	// of the reordered units once they become available
+	q := coordinate.WorkUnitQuery{
+		Statuses: []coordinate.WorkUnitStatus{
+			coordinate.AvailableUnit,
+			coordinate.DelayedUnit,
+		},
+	}
+	cte, params := spec.selectUnits(q, spec.Coordinate().clock.Now())
+	query := buildSelect([]string{
+		workUnitName,
+		workUnitPriority,
+	}, []string{
+		workUnitTable,
+	}, []string{
+		"id IN (" + cte + ")",
+	})
+	return withTx(spec, false, func(tx *sql.Tx) error {
+		rows, err := tx.Query(query, params...)
+		if err != nil {
+			return err
+		}
+		priorities := make(map[string]float64)
+		err = scanRows(rows, func() error {
+			var (
+				name     string
+				priority float64
+			)
+			err := rows.Scan(&name, &priority)
+			if err == nil {
+				priorities[name] = priority
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		for name, priority := range coordinate.ReorderPriorities(priorities, orderedNames) {
+			params := queryParams{}
+			fields := fieldList{}
+			fields.Add(&params, "priority", priority)
+			query := buildUpdate(workUnitTable, fields.UpdateChanges(), []string{
+				workUnitInSpec(&params, spec.id),
+				workUnitHasName(&params, name),
+			})
+			_, err = tx.Exec(query, params...)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (spec *workSpec) CountWorkUnits(q coordinate.WorkUnitQuery) (count int, err error) {
+	spec.Coordinate().Expiry.DoForSpec(spec)
+	cte, params := spec.selectUnits(q, spec.Coordinate().clock.Now())
+	query := "SELECT COUNT(*) FROM (" + cte + ") matched"
+	err = withTx(spec, true, func(tx *sql.Tx) error {
+		return tx.QueryRow(query, params...).Scan(&count)
+	})
+	return
+}
+
 func (spec *workSpec) DeleteWorkUnits(q coordinate.WorkUnitQuery) (count int, err error) {
-	spec.Coordinate().Expiry.Do(spec)
+	spec.Coordinate().Expiry.DoForSpec(spec)
 	// If we're trying to delete *everything*, and work is still
 	// ongoing, this is extremely likely to hit conflicts.  Do this
 	// in smaller batches in a loop.  That makes this non-atomic,
 	// but does mean it's extremely likely to complete.
+	//
+	// The batch limit wraps the caller's query, which may have
+	// its own ordering and offset.  If the caller gave a limit,
+	// though, re-running the query would delete past it, so
+	// delete that page in a single statement.
 	cte, params := spec.selectUnits(q, spec.Coordinate().clock.Now())
-	query := "DELETE FROM work_unit WHERE id IN (" + cte + " LIMIT 100)"
+	query := "DELETE FROM work_unit WHERE id IN (" + cte + ")"
+	if q.Limit == 0 {
+		query = "DELETE FROM work_unit WHERE id IN (SELECT id FROM (" + cte + ") AS units LIMIT 100)"
+	}
 	keepGoing := true
 	for keepGoing && err == nil {
 		err = withTx(spec, false, func(tx *sql.Tx) error {
@@ -354,7 +682,7 @@ func (spec *workSpec) DeleteWorkUnits(q coordinate.WorkUnitQuery) (count int, er
 				var count64 int64
 				count64, err = result.RowsAffected()
 				count += int(count64)
-				keepGoing = count64 != 0
+				keepGoing = count64 != 0 && q.Limit == 0
 			}
 			return err
 		})
@@ -400,12 +728,47 @@ func (unit *workUnit) Data() (map[string]interface{}, error) {
 	return result, nil
 }
 
+func (unit *workUnit) SetData(data map[string]interface{}) error {
+	dataBytes, err := mapToBytes(data)
+	if err != nil {
+		return err
+	}
+	unit.Coordinate().Expiry.DoForSpec(unit.spec)
+	return withTx(unit, false, func(tx *sql.Tx) error {
+		params := queryParams{}
+		fields := fieldList{}
+		fields.Add(&params, "data", dataBytes)
+		query := buildUpdate(workUnitTable, fields.UpdateChanges(), []string{
+			isWorkUnit(&params, unit.id),
+			workUnitAttempt + " IS NULL",
+		})
+		result, err := tx.Exec(query, params...)
+		if err != nil {
+			return err
+		}
+		count, err := result.RowsAffected()
+		if err != nil || count > 0 {
+			return err
+		}
+		// Either the work unit is gone or it has an active
+		// attempt; find out which
+		var exists bool
+		err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM work_unit WHERE id=$1)", unit.id).Scan(&exists)
+		if err == nil && exists {
+			err = coordinate.ErrWorkUnitActive
+		} else if err == nil {
+			err = coordinate.ErrGone
+		}
+		return err
+	})
+}
+
 func (unit *workUnit) WorkSpec() coordinate.WorkSpec {
 	return unit.spec
 }
 
 func (unit *workUnit) Status() (coordinate.WorkUnitStatus, error) {
-	unit.Coordinate().Expiry.Do(unit)
+	unit.Coordinate().Expiry.DoForSpec(unit.spec)
 	now := unit.Coordinate().clock.Now()
 	params := queryParams{}
 	query := buildSelect([]string{
@@ -427,25 +790,32 @@ func (unit *workUnit) Status() (coordinate.WorkUnitStatus, error) {
 	if err != nil {
 		return 0, err
 	}
-	if !ns.Valid {
-		if delayed {
-			return coordinate.DelayedUnit, nil
+	return unitStatus(ns, delayed)
+}
+
+// unitStatus converts the status of a work unit's active attempt, or
+// NULL if there is none, and whether the work unit is delayed into
+// a work unit status.  Every status computation goes through here, so
+// they all agree on which work units are delayed.
+func unitStatus(ns sql.NullString, delayed bool) (coordinate.WorkUnitStatus, error) {
+	if ns.Valid {
+		switch ns.String {
+		case "pending":
+			return coordinate.PendingUnit, nil
+		case "finished":
+			return coordinate.FinishedUnit, nil
+		case "failed":
+			return coordinate.FailedUnit, nil
+		case "expired", "retryable":
+			// same as no active attempt
+		default:
+			return 0, fmt.Errorf("invalid attempt status in database %v", ns.String)
 		}
-		return coordinate.AvailableUnit, nil
 	}
-	switch ns.String {
-	case "pending":
-		return coordinate.PendingUnit, nil
-	case "expired":
-		return coordinate.AvailableUnit, nil
-	case "finished":
-		return coordinate.FinishedUnit, nil
-	case "failed":
-		return coordinate.FailedUnit, nil
-	case "retryable":
-		return coordinate.AvailableUnit, nil
+	if delayed {
+		return coordinate.DelayedUnit, nil
 	}
-	return 0, fmt.Errorf("invalid attempt status in database %v", ns.String)
+	return coordinate.AvailableUnit, nil
 }
 
 func (unit *workUnit) Meta() (meta coordinate.WorkUnitMeta, err error) {
@@ -454,13 +824,14 @@ func (unit *workUnit) Meta() (meta coordinate.WorkUnitMeta, err error) {
 	query := buildSelect([]string{
 		workUnitPriority,
 		workUnitNotBefore,
+		workUnitRuntime,
 	}, []string{
 		workUnitTable,
 	}, []string{
 		isWorkUnit(&params, unit.id),
 	})
 	err = withTx(unit, true, func(tx *sql.Tx) error {
-		return tx.QueryRow(query, params...).Scan(&meta.Priority, &notBefore)
+		return tx.QueryRow(query, params...).Scan(&meta.Priority, &notBefore, &meta.Runtime)
 	})
 	if err == sql.ErrNoRows {
 		err = coordinate.ErrGone
@@ -469,11 +840,30 @@ func (unit *workUnit) Meta() (meta coordinate.WorkUnitMeta, err error) {
 	return
 }
 
+func (unit *workUnit) CreatedAt() (createdAt time.Time, err error) {
+	params := queryParams{}
+	query := buildSelect([]string{
+		workUnitCreatedAt,
+	}, []string{
+		workUnitTable,
+	}, []string{
+		isWorkUnit(&params, unit.id),
+	})
+	err = withTx(unit, true, func(tx *sql.Tx) error {
+		return tx.QueryRow(query, params...).Scan(&createdAt)
+	})
+	if err == sql.ErrNoRows {
+		err = coordinate.ErrGone
+	}
+	return
+}
+
 func (unit *workUnit) SetMeta(meta coordinate.WorkUnitMeta) error {
 	params := queryParams{}
 	fields := fieldList{}
 	fields.Add(&params, "priority", meta.Priority)
 	fields.Add(&params, "not_before", timeToNullTime(meta.NotBefore))
+	fields.Add(&params, "runtime", meta.Runtime)
 	query := buildUpdate(workUnitTable, fields.UpdateChanges(), []string{
 		isWorkUnit(&params, unit.id),
 	})
@@ -0,0 +1,175 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package postgres
+
+import (
+	"database/sql"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/diffeo/go-coordinate/coordinate"
+	"github.com/stretchr/testify/assert"
+)
+
+// availableQuery lists the first page of available work units, the
+// way a UI or a "coordinate summary" pass would.
+var availableQuery = coordinate.WorkUnitQuery{
+	Statuses: []coordinate.WorkUnitStatus{coordinate.AvailableUnit},
+	Limit:    100,
+}
+
+// addFinishedUnits adds n work units to spec, named after the ones
+// already there, each with a finished attempt by worker.  This goes
+// straight to the database, since going through the API would take
+// far too long for the numbers benchmarks want.
+func addFinishedUnits(tb testing.TB, spec *workSpec, worker *worker, n int) {
+	err := withTx(spec, false, func(tx *sql.Tx) error {
+		var first int
+		err := tx.QueryRow("SELECT COUNT(*) FROM work_unit WHERE work_spec_id=$1", spec.id).Scan(&first)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("INSERT INTO work_unit(work_spec_id, name, data, priority) "+
+			"SELECT $1, 'finished' || lpad(i::text, 9, '0'), $2, 0 "+
+			"FROM generate_series($3::INTEGER, $4::INTEGER) i",
+			spec.id, []byte{0xa0}, first, first+n-1)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("INSERT INTO attempt(work_unit_id, worker_id, work_spec_id, status, start_time, end_time, expiration_time, active) "+
+			"SELECT id, $1, $2, 'finished', NOW(), NOW(), NOW(), FALSE "+
+			"FROM work_unit WHERE work_spec_id=$2 AND active_attempt_id IS NULL AND name LIKE 'finished%'",
+			worker.id, spec.id)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("UPDATE work_unit SET active_attempt_id=attempt.id "+
+			"FROM attempt WHERE attempt.work_unit_id=work_unit.id "+
+			"AND work_unit.work_spec_id=$1 AND work_unit.active_attempt_id IS NULL",
+			spec.id)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("ANALYZE work_unit")
+		if err == nil {
+			_, err = tx.Exec("ANALYZE attempt")
+		}
+		return err
+	})
+	if !assert.NoError(tb, err) {
+		tb.FailNow()
+	}
+}
+
+// planCostPattern matches the total estimated cost on the first line
+// of EXPLAIN output.
+var planCostPattern = regexp.MustCompile(`cost=[0-9.]+\.\.([0-9.]+)`)
+
+// explainAvailable runs EXPLAIN on the query behind
+// spec.WorkUnits(availableQuery), returning the plan and its total
+// estimated cost.
+func explainAvailable(tb testing.TB, spec *workSpec) (string, float64) {
+	query, params := spec.selectUnits(availableQuery, spec.Coordinate().clock.Now())
+	var lines []string
+	err := queryAndScan(spec, "EXPLAIN "+query, params, func(rows *sql.Rows) error {
+		var line string
+		err := rows.Scan(&line)
+		lines = append(lines, line)
+		return err
+	})
+	if !assert.NoError(tb, err) {
+		tb.FailNow()
+	}
+	if !assert.NotEmpty(tb, lines) {
+		tb.FailNow()
+	}
+	match := planCostPattern.FindStringSubmatch(lines[0])
+	if !assert.NotNil(tb, match, lines[0]) {
+		tb.FailNow()
+	}
+	cost, err := strconv.ParseFloat(match[1], 64)
+	if !assert.NoError(tb, err) {
+		tb.FailNow()
+	}
+	return strings.Join(lines, "\n"), cost
+}
+
+// setUpAvailable creates a work spec with a handful of available
+// work units and a worker to finish others.
+func setUpAvailable(tb testing.TB, name string) (coordinate.Namespace, *workSpec, *worker) {
+	c, err := New("")
+	if !assert.NoError(tb, err) {
+		tb.FailNow()
+	}
+	ns, err := c.Namespace(name)
+	if !assert.NoError(tb, err) {
+		tb.FailNow()
+	}
+	spec, err := ns.SetWorkSpec(map[string]interface{}{"name": "spec"})
+	if !assert.NoError(tb, err) {
+		tb.FailNow()
+	}
+	for i := 0; i < 10; i++ {
+		_, err = spec.AddWorkUnit("unit"+strconv.Itoa(i), map[string]interface{}{}, coordinate.WorkUnitMeta{})
+		if !assert.NoError(tb, err) {
+			tb.FailNow()
+		}
+	}
+	w, err := ns.Worker("worker")
+	if !assert.NoError(tb, err) {
+		tb.FailNow()
+	}
+	return ns, spec.(*workSpec), w.(*worker)
+}
+
+// TestAvailableUnitsPlan checks that listing available work units
+// uses an index, not a scan over every work unit, and that its
+// estimated cost grows much more slowly than the number of finished
+// work units.
+func TestAvailableUnitsPlan(t *testing.T) {
+	ns, spec, worker := setUpAvailable(t, "TestAvailableUnitsPlan")
+	defer ns.Destroy()
+
+	addFinishedUnits(t, spec, worker, 1000)
+	_, smallCost := explainAvailable(t, spec)
+
+	addFinishedUnits(t, spec, worker, 19000)
+	plan, bigCost := explainAvailable(t, spec)
+	assert.NotContains(t, plan, "Seq Scan on work_unit")
+	assert.NotContains(t, plan, "on attempt")
+	assert.True(t, bigCost < 4*smallCost,
+		"cost %v with 20000 finished units, %v with 1000\n%v", bigCost, smallCost, plan)
+
+	units, err := spec.WorkUnits(availableQuery)
+	if assert.NoError(t, err) {
+		assert.Len(t, units, 10)
+	}
+}
+
+// BenchmarkAvailableUnits times listing available work units in a
+// work spec with growing numbers of finished work units, also
+// reporting the planner's estimated cost.
+func BenchmarkAvailableUnits(b *testing.B) {
+	ns, spec, worker := setUpAvailable(b, "BenchmarkAvailableUnits")
+	defer ns.Destroy()
+
+	finished := 0
+	for _, total := range []int{1000, 10000, 100000} {
+		addFinishedUnits(b, spec, worker, total-finished)
+		finished = total
+		b.Run(strconv.Itoa(total), func(b *testing.B) {
+			_, cost := explainAvailable(b, spec)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := spec.WorkUnits(availableQuery)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(cost, "plan-cost")
+		})
+	}
+}
@@ -76,6 +76,156 @@ func (ns *namespace) Workers() (map[string]coordinate.Worker, error) {
 	return result, nil
 }
 
+// workerQueryConditions returns WHERE clause fragments that select
+// the workers in ns matching q.
+func workerQueryConditions(params *queryParams, ns *namespace, q coordinate.WorkerQuery, now time.Time) []string {
+	conditions := []string{workerInNamespace(params, ns.id)}
+	if q.NamePrefix != "" {
+		conditions = append(conditions, workerHasNamePrefix(params, q.NamePrefix))
+	}
+	if q.Expired {
+		conditions = append(conditions, workerExpiration+"<"+params.Param(now))
+	}
+	return conditions
+}
+
+func (ns *namespace) DeactivateWorkers(q coordinate.WorkerQuery) (int, error) {
+	// This is the same UPDATE as worker.Deactivate(), just over
+	// more workers; attempts in ExpireWithWorker work specs then
+	// expire the same way
+	var count int64
+	now := ns.Coordinate().clock.Now()
+	params := queryParams{}
+	conditions := workerQueryConditions(&params, ns, q, now)
+	conditions = append(conditions, workerActive)
+	query := buildUpdate(workerTable, []string{"active=FALSE"}, conditions)
+	err := withTx(ns, false, func(tx *sql.Tx) error {
+		result, err := tx.Exec(query, params...)
+		if err != nil {
+			return err
+		}
+		count, err = result.RowsAffected()
+		return err
+	})
+	return int(count), err
+}
+
+// deletableWorkers builds a query that selects the IDs of the
+// workers in ns matching q, except for workers that hold the active
+// attempt of a finished or failed work unit.
+func deletableWorkers(params *queryParams, ns *namespace, q coordinate.WorkerQuery, now time.Time) string {
+	conditions := workerQueryConditions(params, ns, q, now)
+	conditions = append(conditions, "NOT EXISTS ("+buildSelect([]string{
+		"1",
+	}, []string{
+		attemptTable,
+		workUnitTable,
+	}, []string{
+		attemptThisWorker,
+		attemptIsTheActive,
+		attemptStatus + " IN ('finished', 'failed')",
+	})+")")
+	return buildSelect([]string{workerID}, []string{workerTable}, conditions)
+}
+
+func (ns *namespace) DeleteWorkers(q coordinate.WorkerQuery) (int, error) {
+	var count int64
+	now := ns.Coordinate().clock.Now()
+	err := withTx(ns, false, func(tx *sql.Tx) error {
+		// Release the work units of the workers' pending
+		// attempts first, as in Attempt.Release; deleting the
+		// workers would clear them anyway, but would not
+		// notify anyone waiting for work
+		params := queryParams{}
+		pending := buildSelect([]string{
+			attemptID,
+		}, []string{
+			attemptTable,
+		}, []string{
+			attemptIsPending,
+			attemptWorkerID + " IN (" + deletableWorkers(&params, ns, q, now) + ")",
+		})
+		query := buildUpdate(workUnitTable,
+			[]string{"active_attempt_id=NULL"},
+			[]string{"active_attempt_id IN (" + pending + ")"})
+		_, err := releaseWorkUnits(tx, query, params)
+		if err != nil {
+			return err
+		}
+
+		// Deleting the workers deletes all of their attempts
+		params = queryParams{}
+		query = "DELETE FROM " + workerTable + " WHERE id IN (" +
+			deletableWorkers(&params, ns, q, now) + ")"
+		result, err := tx.Exec(query, params...)
+		if err != nil {
+			return err
+		}
+		count, err = result.RowsAffected()
+		return err
+	})
+	return int(count), err
+}
+
+// excessAttempts builds a query that selects the IDs of pending
+// attempts held by workers in ns beyond the first maxPending for each
+// worker, oldest first.
+func excessAttempts(params *queryParams, ns *namespace, maxPending int) string {
+	held := buildSelect([]string{
+		attemptID,
+		"ROW_NUMBER() OVER (PARTITION BY " + attemptWorkerID +
+			" ORDER BY " + attemptStartTime + " ASC, " +
+			attemptID + " ASC) AS n",
+	}, []string{
+		attemptTable,
+		workerTable,
+	}, []string{
+		workerInNamespace(params, ns.id),
+		attemptThisWorker,
+		attemptIsPending,
+	})
+	return "SELECT id FROM (" + held + ") held WHERE n>" + params.Param(maxPending)
+}
+
+func (ns *namespace) RebalanceAttempts(maxPending int) (int, error) {
+	var count int64
+	if maxPending < 0 {
+		maxPending = 0
+	}
+	ns.Coordinate().Expiry.DoForNamespace(ns)
+	now := ns.Coordinate().clock.Now()
+	err := withTx(ns, false, func(tx *sql.Tx) error {
+		// Release the work units first, then expire the
+		// attempts, as expireAttempts does; as in
+		// Attempt.Release, this is not a retry
+		params := queryParams{}
+		query := buildUpdate(workUnitTable,
+			[]string{"active_attempt_id=NULL"},
+			[]string{"active_attempt_id IN (" + excessAttempts(&params, ns, maxPending) + ")"})
+		_, err := releaseWorkUnits(tx, query, params)
+		if err != nil {
+			return err
+		}
+
+		params = queryParams{}
+		fields := fieldList{}
+		fields.AddDirect("active", "FALSE")
+		fields.AddDirect("released", "TRUE")
+		fields.AddDirect("status", "'expired'")
+		fields.Add(&params, "end_time", now)
+		query = buildUpdate(attemptTable, fields.UpdateChanges(), []string{
+			"id IN (" + excessAttempts(&params, ns, maxPending) + ")",
+		})
+		result, err := tx.Exec(query, params...)
+		if err != nil {
+			return err
+		}
+		count, err = result.RowsAffected()
+		return err
+	})
+	return int(count), err
+}
+
 // coordinate.Worker interface
 
 func (w *worker) Name() string {
@@ -239,7 +389,93 @@ func (w *worker) Update(data map[string]interface{}, now, expiration time.Time,
 	query := buildUpdate(workerTable, fields.UpdateChanges(), []string{
 		isWorker(&params, w.id),
 	})
-	return execInTx(w, query, params, true)
+	return withTx(w, false, func(tx *sql.Tx) error {
+		_, err := tx.Exec(query, params...)
+		if err == nil {
+			err = w.extendAttempts(tx)
+		}
+		return err
+	})
+}
+
+// extendAttempts pushes back the expiration time of this worker's
+// pending attempts in work specs that have a heartbeat extension.
+func (w *worker) extendAttempts(tx *sql.Tx) error {
+	now := w.Coordinate().clock.Now()
+	params := queryParams{}
+	query := buildSelect([]string{
+		attemptID,
+		attemptStartTime,
+		attemptExpirationTime,
+		workSpecHeartbeatExtension,
+		workSpecMaxLeaseTotal,
+	}, []string{
+		attemptTable,
+		workSpecTable,
+	}, []string{
+		attemptByWorker(&params, w.id),
+		attemptIsPending,
+		attemptInThisSpec,
+		"NOT " + attemptIsExpired(&params, now),
+		workSpecHeartbeatExtension + ">'0'",
+	})
+	rows, err := tx.Query(query, params...)
+	if err != nil {
+		return err
+	}
+	type extension struct {
+		id         int
+		expiration time.Time
+	}
+	var extensions []extension
+	err = scanRows(rows, func() error {
+		var (
+			id                    int
+			startTime, expiration time.Time
+			heartbeatExtension    string
+			maxLeaseTotal         string
+		)
+		err := rows.Scan(&id, &startTime, &expiration, &heartbeatExtension, &maxLeaseTotal)
+		if err != nil {
+			return err
+		}
+		extend, err := sqlToDuration(heartbeatExtension)
+		if err != nil {
+			return err
+		}
+		maxLease, err := sqlToDuration(maxLeaseTotal)
+		if err != nil {
+			return err
+		}
+		newExpiration := now.Add(extend)
+		if maxLease > 0 {
+			limit := startTime.Add(maxLease)
+			if newExpiration.After(limit) {
+				newExpiration = limit
+			}
+		}
+		if newExpiration.After(expiration) {
+			extensions = append(extensions, extension{id, newExpiration})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, ext := range extensions {
+		params := queryParams{}
+		fields := fieldList{}
+		fields.Add(&params, "expiration_time", ext.expiration)
+		query := buildUpdate(attemptTable, fields.UpdateChanges(), []string{
+			isAttempt(&params, ext.id),
+		})
+		_, err = tx.Exec(query, params...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // coordinable interface
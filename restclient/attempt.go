@@ -19,7 +19,7 @@ type attempt struct {
 
 func attemptFromURL(parent *resource, path string, workUnit *workUnit, worker *worker) (a *attempt, err error) {
 	a = &attempt{}
-	a.URL, err = parent.Template(path, map[string]interface{}{})
+	a.resource, err = parent.Resolve(path, map[string]interface{}{})
 	if err == nil {
 		err = a.Refresh()
 	}
@@ -64,6 +64,10 @@ func (a *attempt) Refresh() error {
 	return a.Get(&a.Representation)
 }
 
+func (a *attempt) ID() string {
+	return a.Representation.ID
+}
+
 func (a *attempt) WorkUnit() coordinate.WorkUnit {
 	return a.workUnit
 }
@@ -108,9 +112,42 @@ func (a *attempt) ExpirationTime() (time.Time, error) {
 	return time.Time{}, err
 }
 
+func (a *attempt) DataHistory() ([]coordinate.DataSnapshot, error) {
+	var repr restdata.DataHistory
+	err := a.GetFrom(a.Representation.DataHistoryURL, map[string]interface{}{}, &repr)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]coordinate.DataSnapshot, len(repr.Snapshots))
+	for i, snapshot := range repr.Snapshots {
+		result[i] = coordinate.DataSnapshot{
+			Time: snapshot.Time,
+			Data: snapshot.Data,
+		}
+	}
+	return result, nil
+}
+
+func (a *attempt) AppendLog(line string) error {
+	repr := restdata.AttemptLog{Lines: []string{line}}
+	return a.PostTo(a.Representation.LogsURL, map[string]interface{}{}, repr, nil)
+}
+
+func (a *attempt) Logs() ([]string, error) {
+	var repr restdata.AttemptLog
+	err := a.GetFrom(a.Representation.LogsURL, map[string]interface{}{}, &repr)
+	if err != nil {
+		return nil, err
+	}
+	if repr.Lines == nil {
+		return []string{}, nil
+	}
+	return repr.Lines, nil
+}
+
 func (a *attempt) Renew(extendDuration time.Duration, data map[string]interface{}) error {
 	repr := restdata.AttemptCompletion{
-		ExtendDuration: extendDuration,
+		ExtendDuration: restdata.Duration(extendDuration),
 		Data:           data,
 	}
 	return a.PostTo(a.Representation.RenewURL, map[string]interface{}{}, repr, nil)
@@ -121,17 +158,35 @@ func (a *attempt) Expire(data map[string]interface{}) error {
 	return a.PostTo(a.Representation.ExpireURL, map[string]interface{}{}, repr, nil)
 }
 
+func (a *attempt) Release(data map[string]interface{}) error {
+	repr := restdata.AttemptCompletion{Data: data}
+	return a.PostTo(a.Representation.ReleaseURL, map[string]interface{}{}, repr, nil)
+}
+
 func (a *attempt) Finish(data map[string]interface{}) error {
 	repr := restdata.AttemptCompletion{Data: data}
 	return a.PostTo(a.Representation.FinishURL, map[string]interface{}{}, repr, nil)
 }
 
+func (a *attempt) PrepareFinish(data map[string]interface{}) error {
+	repr := restdata.AttemptCompletion{Data: data}
+	return a.PostTo(a.Representation.PrepareFinishURL, map[string]interface{}{}, repr, nil)
+}
+
+func (a *attempt) FinishPrepared() (bool, error) {
+	err := a.Refresh()
+	if err == nil {
+		return a.Representation.FinishPrepared, nil
+	}
+	return false, err
+}
+
 func (a *attempt) Fail(data map[string]interface{}) error {
 	repr := restdata.AttemptCompletion{Data: data}
 	return a.PostTo(a.Representation.FailURL, map[string]interface{}{}, repr, nil)
 }
 
 func (a *attempt) Retry(data map[string]interface{}, delay time.Duration) error {
-	repr := restdata.AttemptCompletion{Data: data, Delay: delay}
+	repr := restdata.AttemptCompletion{Data: data, Delay: restdata.Duration(delay)}
 	return a.PostTo(a.Representation.RetryURL, map[string]interface{}{}, repr, nil)
 }
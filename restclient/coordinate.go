@@ -15,11 +15,21 @@ import (
 	"github.com/diffeo/go-coordinate/coordinate"
 	"github.com/diffeo/go-coordinate/restdata"
 	"net/url"
+	"time"
 )
 
 // New creates a new Coordinate interface that speaks to an external
 // REST server.
 func New(baseURL string) (coordinate.Coordinate, error) {
+	return NewWithAuthorization(baseURL, "")
+}
+
+// NewWithAuthorization creates a new Coordinate interface that speaks
+// to an external REST server that requires authentication.  The
+// authorization string is sent as the HTTP Authorization header on
+// every request, for instance "Bearer my-secret-token".  If it is
+// empty, no header is sent, just as with New.
+func NewWithAuthorization(baseURL, authorization string) (coordinate.Coordinate, error) {
 	var (
 		err       error
 		parsedURL *url.URL
@@ -28,7 +38,11 @@ func New(baseURL string) (coordinate.Coordinate, error) {
 	parsedURL, err = url.Parse(baseURL)
 	if err == nil {
 		c = &restCoordinate{
-			resource: resource{URL: parsedURL},
+			resource: resource{
+				URL:           parsedURL,
+				Authorization: authorization,
+				cache:         newResponseCache(),
+			},
 		}
 		err = c.Refresh()
 	}
@@ -49,10 +63,17 @@ func (c *restCoordinate) Refresh() error {
 	return c.Get(&c.Representation)
 }
 
+// Supports implements coordinate.Capable.  None of the optional
+// features are available, since they configure the backend behind
+// the REST server, and that can only be done where it runs.
+func (c *restCoordinate) Supports(feature string) bool {
+	return false
+}
+
 func (c *restCoordinate) Namespace(name string) (coordinate.Namespace, error) {
 	var err error
 	ns := &namespace{}
-	ns.URL, err = c.Template(c.Representation.NamespaceURL, map[string]interface{}{"namespace": name})
+	ns.resource, err = c.Resolve(c.Representation.NamespaceURL, map[string]interface{}{"namespace": name})
 	if err == nil {
 		err = ns.Refresh()
 	}
@@ -71,7 +92,7 @@ func (c *restCoordinate) Namespaces() (map[string]coordinate.Namespace, error) {
 	result := make(map[string]coordinate.Namespace)
 	for _, nsR := range resp.Namespaces {
 		ns := namespace{}
-		ns.URL, err = c.URL.Parse(nsR.URL)
+		ns.resource, err = c.Resolve(nsR.URL, map[string]interface{}{})
 		if err != nil {
 			return nil, err
 		}
@@ -84,6 +105,12 @@ func (c *restCoordinate) Namespaces() (map[string]coordinate.Namespace, error) {
 	return result, nil
 }
 
+func (c *restCoordinate) ServerTime() (time.Time, error) {
+	var resp restdata.ServerTime
+	err := c.GetFrom(c.Representation.ServerTimeURL, nil, &resp)
+	return resp.Time, err
+}
+
 func (c *restCoordinate) Summarize() (coordinate.Summary, error) {
 	var summary coordinate.Summary
 	err := c.GetFrom(c.Representation.SummaryURL, nil, &summary)
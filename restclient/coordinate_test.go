@@ -4,12 +4,17 @@
 package restclient_test
 
 import (
+	"github.com/diffeo/go-coordinate/coordinate"
 	"github.com/diffeo/go-coordinate/coordinate/coordinatetest"
 	"github.com/diffeo/go-coordinate/memory"
 	"github.com/diffeo/go-coordinate/restclient"
+	"github.com/diffeo/go-coordinate/restdata"
 	"github.com/diffeo/go-coordinate/restserver"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -25,6 +30,8 @@ func (s *Suite) SetupSuite() {
 	// This sets up an object stack where the REST client code talks
 	// to the REST server code, which points at an in-memory backend.
 	memBackend := memory.NewWithClock(s.Clock)
+	s.DataHistory = 3
+	memBackend.(coordinate.DataHistorySetter).SetDataHistory(s.DataHistory)
 	router := restserver.NewRouter(memBackend)
 	server := httptest.NewServer(router)
 	backend, err := restclient.New(server.URL)
@@ -45,3 +52,199 @@ func TestEmptyURL(t *testing.T) {
 		t.Fatal("Expected error when given empty URL.")
 	}
 }
+
+// TestMaxNamespaces checks that the server's namespace limit error
+// reaches the client intact.
+func TestMaxNamespaces(t *testing.T) {
+	memBackend := memory.New()
+	memBackend.(coordinate.NamespaceLimiter).SetMaxNamespaces(1)
+	server := httptest.NewServer(restserver.NewRouter(memBackend))
+	defer server.Close()
+	c, err := restclient.New(server.URL)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = c.Namespace("a")
+	assert.NoError(t, err)
+	_, err = c.Namespace("a")
+	assert.NoError(t, err)
+	_, err = c.Namespace("b")
+	assert.Equal(t, coordinate.ErrTooManyNamespaces{Name: "b"}, err)
+}
+
+// TestMaxWorkSpecData checks that the server's work spec data limit
+// error reaches the client intact.
+func TestMaxWorkSpecData(t *testing.T) {
+	memBackend := memory.New()
+	memBackend.(coordinate.WorkSpecDataLimiter).SetMaxWorkSpecData(100)
+	server := httptest.NewServer(restserver.NewRouter(memBackend))
+	defer server.Close()
+	c, err := restclient.New(server.URL)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ns, err := c.Namespace("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = ns.SetWorkSpec(map[string]interface{}{
+		"name": "spec",
+		"blob": strings.Repeat("x", 200),
+	})
+	if tooLarge, ok := err.(coordinate.ErrWorkSpecDataTooLarge); assert.True(t, ok, "%+v", err) {
+		assert.Equal(t, "spec", tooLarge.WorkSpec)
+		assert.Equal(t, 100, tooLarge.Limit)
+		assert.True(t, tooLarge.Size > 200)
+	}
+}
+
+// TestAuthorization checks that a client created with credentials
+// sends them on every request, including to objects it discovers,
+// and that a client without them is rejected.
+func TestAuthorization(t *testing.T) {
+	router := restserver.NewRouter(memory.New())
+	server := httptest.NewServer(restserver.RequireAuth(router, restserver.BearerToken("secret")))
+	defer server.Close()
+
+	_, err := restclient.New(server.URL)
+	assert.IsType(t, restdata.ErrUnauthorized{}, err)
+
+	c, err := restclient.NewWithAuthorization(server.URL, "Bearer secret")
+	if !assert.NoError(t, err) {
+		return
+	}
+	ns, err := c.Namespace("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	spec, err := ns.SetWorkSpec(map[string]interface{}{"name": "spec"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = spec.AddWorkUnit("unit", map[string]interface{}{}, coordinate.WorkUnitMeta{})
+	assert.NoError(t, err)
+	worker, err := ns.Worker("worker")
+	if !assert.NoError(t, err) {
+		return
+	}
+	attempts, err := worker.RequestAttempts(coordinate.AttemptRequest{})
+	if assert.NoError(t, err) && assert.Len(t, attempts, 1) {
+		assert.NoError(t, attempts[0].Finish(nil))
+		status, err := attempts[0].WorkUnit().Status()
+		if assert.NoError(t, err) {
+			assert.Equal(t, coordinate.FinishedUnit, status)
+		}
+	}
+}
+
+// TestResponseCache checks that the client revalidates resources it
+// has already fetched, and uses its saved copy when the server says
+// they are unchanged.
+func TestResponseCache(t *testing.T) {
+	var statuses []int
+	router := restserver.NewRouter(memory.New())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		if req.Method == http.MethodGet {
+			statuses = append(statuses, recorder.Code)
+		}
+		for key, values := range recorder.Header() {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(recorder.Code)
+		_, _ = w.Write(recorder.Body.Bytes())
+	}))
+	defer server.Close()
+
+	c, err := restclient.New(server.URL)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ns, err := c.Namespace("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	spec, err := ns.SetWorkSpec(map[string]interface{}{"name": "spec"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// SetWorkSpec fetched the work spec, so getting its data
+	// again only revalidates it
+	statuses = nil
+	for i := 0; i < 2; i++ {
+		data, err := spec.Data()
+		if assert.NoError(t, err) {
+			assert.Equal(t, map[string]interface{}{"name": "spec"}, data)
+		}
+	}
+	assert.Equal(t, []int{http.StatusNotModified, http.StatusNotModified}, statuses)
+
+	err = spec.SetData(map[string]interface{}{"name": "spec", "changed": "yes"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	statuses = nil
+	data, err := spec.Data()
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]interface{}{"name": "spec", "changed": "yes"}, data)
+	}
+	assert.Equal(t, []int{http.StatusOK}, statuses)
+}
+
+// TestStrictCompletion checks that the server's strict completion
+// reaches the client, both for single attempts and for batches.
+func TestStrictCompletion(t *testing.T) {
+	memBackend := memory.New()
+	memBackend.(coordinate.StrictCompletionSetter).SetStrictCompletion(true)
+	server := httptest.NewServer(restserver.NewRouter(memBackend))
+	defer server.Close()
+	c, err := restclient.New(server.URL)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ns, err := c.Namespace("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	spec, err := ns.SetWorkSpec(map[string]interface{}{"name": "spec"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	worker, err := ns.Worker("worker")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for _, name := range []string{"single", "batch"} {
+		unit, err := spec.AddWorkUnit(name, map[string]interface{}{}, coordinate.WorkUnitMeta{})
+		if !assert.NoError(t, err) {
+			return
+		}
+		inactive, err := worker.MakeAttempt(unit, 0)
+		if !assert.NoError(t, err) {
+			return
+		}
+		if !assert.NoError(t, unit.ClearActiveAttempt()) {
+			return
+		}
+		_, err = worker.MakeAttempt(unit, 0)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		if name == "single" {
+			err = inactive.Finish(nil)
+		} else {
+			err = worker.FinishAttempts([]coordinate.Attempt{inactive}, nil)
+		}
+		assert.Equal(t, coordinate.ErrLostLease, err, name)
+		status, err := inactive.Status()
+		if assert.NoError(t, err) {
+			assert.Equal(t, coordinate.Expired, status, name)
+		}
+	}
+}
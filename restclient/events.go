@@ -0,0 +1,120 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package restclient
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/diffeo/go-coordinate/coordinate"
+	"github.com/diffeo/go-coordinate/restdata"
+)
+
+// eventRetry is how long to wait before reconnecting a dropped event
+// stream.
+var eventRetry = 1 * time.Second
+
+// WatchWork implements coordinate.WorkWatcher by reading the
+// server's Server-Sent Events stream for this work spec.  If the
+// connection drops, the channel receives a value, since work may
+// have arrived in the meantime, and the client reconnects once; if
+// that fails, the channel is closed and the caller should go back to
+// polling.
+func (spec *workSpec) WatchWork(ctx context.Context) (<-chan struct{}, error) {
+	if spec.Representation.EventsURL == "" {
+		// Older servers do not provide events at all
+		return nil, coordinate.ErrWatchNotSupported
+	}
+	url, err := spec.Template(spec.Representation.EventsURL, map[string]interface{}{})
+	if err != nil {
+		return nil, err
+	}
+	body, err := spec.openEvents(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan struct{}, 1)
+	go spec.readEvents(ctx, url, body, ch)
+	return ch, nil
+}
+
+// openEvents starts an event stream request, returning the response
+// body once the server has accepted it.
+func (r *resource) openEvents(ctx context.Context, url *url.URL) (io.ReadCloser, error) {
+	req, err := http.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", restdata.EventStreamMediaType)
+	if r.Authorization != "" {
+		req.Header.Set("Authorization", r.Authorization)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkHTTPStatus(resp); err != nil {
+		_ = resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// readEvents forwards events from body to ch, reconnecting to url if
+// the stream ends, until ctx is done.  Closes ch before returning.
+func (r *resource) readEvents(ctx context.Context, url *url.URL, body io.ReadCloser, ch chan<- struct{}) {
+	defer close(ch)
+	for {
+		scanEvents(body, ch)
+		_ = body.Close()
+		wake(ch)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(eventRetry):
+		}
+		var err error
+		body, err = r.openEvents(ctx, url)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// scanEvents reads a Server-Sent Events stream until it ends,
+// sending to ch for each work-available event.
+func scanEvents(body io.Reader, ch chan<- struct{}) {
+	scanner := bufio.NewScanner(body)
+	event := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			// End of one event
+			if event == restdata.WorkAvailableEvent {
+				wake(ch)
+			}
+			event = ""
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		}
+		// Ignore data lines, since the work spec is already
+		// known, and comments, which are only keepalives
+	}
+}
+
+// wake sends to ch if it does not already have a value waiting.
+func wake(ch chan<- struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
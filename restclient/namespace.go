@@ -4,7 +4,8 @@
 package restclient
 
 import (
-	"errors"
+	"time"
+
 	"github.com/diffeo/go-coordinate/coordinate"
 	"github.com/diffeo/go-coordinate/restdata"
 )
@@ -28,8 +29,8 @@ func (ns *namespace) Destroy() error {
 }
 
 func (ns *namespace) makeWorkSpec(name string) (spec *workSpec, err error) {
-	spec = &workSpec{}
-	spec.URL, err = ns.Template(ns.Representation.WorkSpecURL, map[string]interface{}{"spec": name})
+	spec = &workSpec{namespace: ns}
+	spec.resource, err = ns.Resolve(ns.Representation.WorkSpecURL, map[string]interface{}{"spec": name})
 	if err == nil {
 		err = spec.Refresh()
 	}
@@ -44,12 +45,12 @@ func (ns *namespace) SetWorkSpec(data map[string]interface{}) (coordinate.WorkSp
 		spec     *workSpec
 	)
 	reqdata = restdata.WorkSpec{Data: data}
-	spec = &workSpec{}
+	spec = &workSpec{namespace: ns}
 	if err == nil {
 		err = ns.PostTo(ns.Representation.WorkSpecsURL, map[string]interface{}{}, reqdata, &respdata)
 	}
 	if err == nil {
-		spec.URL, err = ns.Template(respdata.URL, map[string]interface{}{})
+		spec.resource, err = ns.Resolve(respdata.URL, map[string]interface{}{})
 	}
 	if err == nil {
 		err = spec.Refresh()
@@ -81,6 +82,13 @@ func (ns *namespace) DestroyWorkSpec(name string) error {
 	return err
 }
 
+func (ns *namespace) DestroyWorkSpecs(names []string) (int, error) {
+	req := restdata.WorkSpecDestroy{Names: names}
+	var resp restdata.WorkSpecsDestroyed
+	err := ns.PostTo(ns.Representation.DestroyWorkSpecsURL, map[string]interface{}{}, req, &resp)
+	return resp.Destroyed, err
+}
+
 func (ns *namespace) WorkSpecNames() ([]string, error) {
 	repr := restdata.WorkSpecList{}
 	err := ns.GetFrom(ns.Representation.WorkSpecsURL, map[string]interface{}{}, &repr)
@@ -94,18 +102,99 @@ func (ns *namespace) WorkSpecNames() ([]string, error) {
 	return result, nil
 }
 
+func (ns *namespace) AvailableRuntimes() ([]string, error) {
+	repr := restdata.RuntimeList{}
+	err := ns.GetFrom(ns.Representation.AvailableRuntimesURL, map[string]interface{}{}, &repr)
+	if err != nil {
+		return nil, err
+	}
+	if repr.Runtimes == nil {
+		return []string{}, nil
+	}
+	return repr.Runtimes, nil
+}
+
 func (ns *namespace) Worker(name string) (coordinate.Worker, error) {
 	var w worker
 	var err error
-	w.URL, err = ns.Template(ns.Representation.WorkerURL, map[string]interface{}{"worker": name})
+	w.resource, err = ns.Resolve(ns.Representation.WorkerURL, map[string]interface{}{"worker": name})
 	if err == nil {
 		err = w.Refresh()
 	}
 	return &w, err
 }
 
+func (ns *namespace) DeactivateWorkers(q coordinate.WorkerQuery) (int, error) {
+	req := restdata.WorkerDeactivate{
+		NamePrefix: q.NamePrefix,
+		Expired:    q.Expired,
+	}
+	var resp restdata.WorkersDeactivated
+	err := ns.PostTo(ns.Representation.DeactivateWorkersURL, map[string]interface{}{}, req, &resp)
+	return resp.Deactivated, err
+}
+
+func (ns *namespace) DeleteWorkers(q coordinate.WorkerQuery) (int, error) {
+	req := restdata.WorkerDelete{
+		NamePrefix: q.NamePrefix,
+		Expired:    q.Expired,
+	}
+	var resp restdata.WorkersDeleted
+	err := ns.PostTo(ns.Representation.DeleteWorkersURL, map[string]interface{}{}, req, &resp)
+	return resp.Deleted, err
+}
+
+func (ns *namespace) ExpiringAttempts(within time.Duration) ([]coordinate.Attempt, error) {
+	var repr restdata.AttemptList
+	params := map[string]interface{}{"within": within.String()}
+	err := ns.GetFrom(ns.Representation.ExpiringAttemptsURL, params, &repr)
+	if err != nil {
+		return nil, err
+	}
+	attempts := make([]coordinate.Attempt, len(repr.Attempts))
+	for i, attempt := range repr.Attempts {
+		attempts[i], err = attemptFromURL(&ns.resource, attempt.URL, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return attempts, nil
+}
+
+func (ns *namespace) RebalanceAttempts(maxPending int) (int, error) {
+	req := restdata.AttemptRebalance{MaxPending: maxPending}
+	var resp restdata.AttemptsRebalanced
+	err := ns.PostTo(ns.Representation.RebalanceAttemptsURL, map[string]interface{}{}, req, &resp)
+	return resp.Released, err
+}
+
+func (ns *namespace) PipelineHealth() ([]coordinate.PipelineIssue, error) {
+	var repr restdata.PipelineHealth
+	err := ns.GetFrom(ns.Representation.PipelineHealthURL, map[string]interface{}{}, &repr)
+	if err != nil {
+		return nil, err
+	}
+	if repr.Issues == nil {
+		return []coordinate.PipelineIssue{}, nil
+	}
+	return repr.Issues, nil
+}
+
 func (ns *namespace) Workers() (map[string]coordinate.Worker, error) {
-	return nil, errors.New("not implemented")
+	var repr restdata.WorkerList
+	err := ns.GetFrom(ns.Representation.WorkersURL, map[string]interface{}{}, &repr)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]coordinate.Worker, len(repr.Workers))
+	for _, short := range repr.Workers {
+		w, err := workerFromURL(&ns.resource, short.URL)
+		if err != nil {
+			return nil, err
+		}
+		result[short.Name] = w
+	}
+	return result, nil
 }
 
 func (ns *namespace) Summarize() (coordinate.Summary, error) {
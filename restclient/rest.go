@@ -14,6 +14,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"sync"
 )
 
 // refreshable is any object that knows how to retrieve its own content.
@@ -25,6 +26,63 @@ type refreshable interface {
 // resource is any object that has a URL and a representation.
 type resource struct {
 	URL *url.URL
+
+	// Authorization, if non-empty, is sent as the Authorization
+	// header on every request.
+	Authorization string
+
+	// cache, if non-nil, holds GET responses that had ETags, so
+	// that unchanged resources need not be downloaded again.
+	cache *responseCache
+}
+
+// maxCachedResponses is the largest number of responses a
+// responseCache holds.
+const maxCachedResponses = 1024
+
+// cachedResponse is a GET response body saved with its entity tag.
+type cachedResponse struct {
+	ETag        string
+	ContentType string
+	Body        []byte
+}
+
+// responseCache holds GET responses by URL.  It is shared between a
+// client and all of the resources it creates.
+type responseCache struct {
+	lock      sync.Mutex
+	responses map[string]cachedResponse
+}
+
+func newResponseCache() *responseCache {
+	return &responseCache{responses: make(map[string]cachedResponse)}
+}
+
+func (c *responseCache) get(url string) (cachedResponse, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	response, ok := c.responses[url]
+	return response, ok
+}
+
+func (c *responseCache) put(url string, response cachedResponse) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, present := c.responses[url]; !present && len(c.responses) >= maxCachedResponses {
+		// Make room by dropping an arbitrary entry; the
+		// worst case is an extra download later
+		for other := range c.responses {
+			delete(c.responses, other)
+			break
+		}
+	}
+	c.responses[url] = response
+}
+
+func (c *responseCache) remove(url string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.responses, url)
 }
 
 func (r *resource) Template(template string, vars map[string]interface{}) (*url.URL, error) {
@@ -58,10 +116,25 @@ func (r *resource) Template(template string, vars map[string]interface{}) (*url.
 	return r.URL.Parse(expanded)
 }
 
+// Resolve creates a new resource from a URI template, interpreted as
+// in Template.  The new resource makes requests with the same
+// credentials as this one.
+func (r *resource) Resolve(template string, vars map[string]interface{}) (resource, error) {
+	url, err := r.Template(template, vars)
+	if err != nil {
+		return resource{}, err
+	}
+	return resource{URL: url, Authorization: r.Authorization, cache: r.cache}, nil
+}
+
 // Do performs some HTTP action.  If in is non-nil, the request data is
 // serialized and sent as the body of, for instance, a POST request.
 // If out is non-nil, the response data (if any) is deserialized into
 // this object, which must be of pointer type.
+//
+// If r has a response cache, GET responses with an ETag are saved,
+// and a later GET of the same URL asks the server to only send the
+// body if it has changed.
 func (r *resource) Do(method string, url *url.URL, in, out interface{}) (err error) {
 	json := &codec.JsonHandle{}
 
@@ -93,6 +166,18 @@ func (r *resource) Do(method string, url *url.URL, in, out interface{}) (err err
 	if out != nil {
 		req.Header.Set("Accept", restdata.V1JSONMediaType)
 	}
+	if r.Authorization != "" {
+		req.Header.Set("Authorization", r.Authorization)
+	}
+	useCache := r.cache != nil && method == "GET" && out != nil
+	var cached cachedResponse
+	var haveCached bool
+	if useCache {
+		cached, haveCached = r.cache.get(url.String())
+		if haveCached {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+	}
 
 	// Actually do the request
 	resp, err := http.DefaultClient.Do(req)
@@ -107,11 +192,35 @@ func (r *resource) Do(method string, url *url.URL, in, out interface{}) (err err
 		}()
 	}
 
+	// If what we have is still current, use it
+	if resp.StatusCode == http.StatusNotModified && haveCached {
+		return restdata.Decode(cached.ContentType, bytes.NewReader(cached.Body), out)
+	}
+
 	// Check the response code
 	if err = checkHTTPStatus(resp); err != nil {
 		return err
 	}
 
+	// Save a copy of the body if it can be revalidated later
+	if useCache {
+		etag := resp.Header.Get("ETag")
+		if etag == "" || resp.Body == nil {
+			r.cache.remove(url.String())
+		} else {
+			response := cachedResponse{
+				ETag:        etag,
+				ContentType: resp.Header.Get("Content-Type"),
+			}
+			response.Body, err = ioutil.ReadAll(resp.Body)
+			if err != nil {
+				return err
+			}
+			r.cache.put(url.String(), response)
+			return restdata.Decode(response.ContentType, bytes.NewReader(response.Body), out)
+		}
+	}
+
 	// If there is both a body and a requested output,
 	// decode it
 	if resp.Body != nil && out != nil {
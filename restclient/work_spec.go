@@ -4,22 +4,33 @@
 package restclient
 
 import (
+	"errors"
 	"github.com/diffeo/go-coordinate/coordinate"
 	"github.com/diffeo/go-coordinate/restdata"
+	"time"
 )
 
 type workSpec struct {
 	resource
 	Representation restdata.WorkSpec
+	namespace      *namespace
 }
 
+// workSpecFromURL creates a work spec found from somewhere other
+// than its namespace, and also finds the namespace.
 func workSpecFromURL(parent *resource, path string) (*workSpec, error) {
-	var spec workSpec
+	spec := workSpec{namespace: &namespace{}}
 	var err error
-	spec.URL, err = parent.Template(path, map[string]interface{}{})
+	spec.resource, err = parent.Resolve(path, map[string]interface{}{})
 	if err == nil {
 		err = spec.Refresh()
 	}
+	if err == nil {
+		spec.namespace.resource, err = spec.Resolve(spec.Representation.NamespaceURL, map[string]interface{}{})
+	}
+	if err == nil {
+		err = spec.namespace.Refresh()
+	}
 	return &spec, err
 }
 
@@ -32,6 +43,10 @@ func (spec *workSpec) Name() string {
 	return spec.Representation.Name
 }
 
+func (spec *workSpec) Namespace() coordinate.Namespace {
+	return spec.namespace
+}
+
 func (spec *workSpec) Data() (map[string]interface{}, error) {
 	err := spec.Refresh()
 	if err == nil {
@@ -54,6 +69,12 @@ func (spec *workSpec) SetMeta(meta coordinate.WorkSpecMeta) error {
 	return spec.PutTo(spec.Representation.MetaURL, map[string]interface{}{}, meta, nil)
 }
 
+func (spec *workSpec) IsSchedulable() (bool, string, error) {
+	var repr restdata.Schedulable
+	err := spec.GetFrom(spec.Representation.SchedulableURL, map[string]interface{}{}, &repr)
+	return repr.Schedulable, repr.Reason, err
+}
+
 func (spec *workSpec) AddWorkUnit(name string, data map[string]interface{}, meta coordinate.WorkUnitMeta) (coordinate.WorkUnit, error) {
 	repr := restdata.WorkUnit{}
 	repr.Name = name
@@ -63,7 +84,7 @@ func (spec *workSpec) AddWorkUnit(name string, data map[string]interface{}, meta
 	unit := workUnit{workSpec: spec}
 	err := spec.PostTo(spec.Representation.WorkUnitsURL, map[string]interface{}{}, repr, &unit.Representation)
 	if err == nil {
-		unit.URL, err = spec.Template(unit.Representation.URL, map[string]interface{}{})
+		unit.resource, err = spec.Resolve(unit.Representation.URL, map[string]interface{}{})
 	}
 	if err == nil {
 		return &unit, nil
@@ -71,10 +92,35 @@ func (spec *workSpec) AddWorkUnit(name string, data map[string]interface{}, meta
 	return nil, err
 }
 
+// AddWorkUnits implements coordinate.WorkUnitBatchAdder by posting
+// the whole batch to the server in one request.
+func (spec *workSpec) AddWorkUnits(items []coordinate.AddWorkUnitItem) error {
+	batch := restdata.WorkUnitBatch{
+		WorkUnits: make([]restdata.WorkUnit, len(items)),
+	}
+	for i, item := range items {
+		meta := item.Meta
+		batch.WorkUnits[i].Name = item.Key
+		batch.WorkUnits[i].Data = item.Data
+		batch.WorkUnits[i].Meta = &meta
+	}
+	var results restdata.WorkUnitBatchResults
+	err := spec.PostTo(spec.Representation.AddWorkUnitsURL, map[string]interface{}{}, batch, &results)
+	if err != nil {
+		return err
+	}
+	for _, result := range results.Results {
+		if result.Error != "" {
+			return errors.New(result.Error)
+		}
+	}
+	return nil
+}
+
 func (spec *workSpec) WorkUnit(name string) (coordinate.WorkUnit, error) {
 	unit := workUnit{workSpec: spec}
 	var err error
-	unit.URL, err = spec.Template(spec.Representation.WorkUnitURL, map[string]interface{}{"unit": name})
+	unit.resource, err = spec.Resolve(spec.Representation.WorkUnitURL, map[string]interface{}{"unit": name})
 	if err == nil {
 		err = unit.Refresh()
 	}
@@ -105,6 +151,15 @@ func queryToParams(q coordinate.WorkUnitQuery) map[string]interface{} {
 	if q.PreviousName != "" {
 		result["previous"] = q.PreviousName
 	}
+	if q.NeverAttempted {
+		result["never_attempted"] = true
+	}
+	if q.WorkerName != "" {
+		result["worker"] = q.WorkerName
+	}
+	if q.Offset != 0 {
+		result["offset"] = q.Offset
+	}
 	if q.Limit != 0 {
 		result["limit"] = q.Limit
 	}
@@ -116,23 +171,72 @@ func (spec *workSpec) WorkUnits(q coordinate.WorkUnitQuery) (map[string]coordina
 	var repr restdata.WorkUnitList
 	err := spec.GetFrom(spec.Representation.WorkUnitQueryURL, params, &repr)
 	if err == nil {
-		units := make(map[string]coordinate.WorkUnit)
-		for _, rUnit := range repr.WorkUnits {
-			unit, err := workUnitFromURL(&spec.resource, rUnit.URL, spec)
-			if err != nil {
-				return nil, err
-			}
-			err = unit.Refresh()
-			if err != nil {
-				return nil, err
-			}
-			units[unit.Name()] = unit
-		}
-		return units, nil
+		return spec.unitsFromList(repr)
 	}
 	return nil, err
 }
 
+func (spec *workSpec) WorkUnitsPage(q coordinate.WorkUnitQuery) (map[string]coordinate.WorkUnit, string, error) {
+	params := queryToParams(q)
+	if q.Cursor != "" {
+		params["cursor"] = q.Cursor
+	}
+	var repr restdata.WorkUnitList
+	err := spec.GetFrom(spec.Representation.WorkUnitQueryURL, params, &repr)
+	if err != nil {
+		return nil, "", err
+	}
+	units, err := spec.unitsFromList(repr)
+	if err != nil {
+		return nil, "", err
+	}
+	return units, repr.Cursor, nil
+}
+
+// unitsFromList builds work unit objects from a work unit list
+// response.
+func (spec *workSpec) unitsFromList(repr restdata.WorkUnitList) (map[string]coordinate.WorkUnit, error) {
+	units := make(map[string]coordinate.WorkUnit)
+	for _, rUnit := range repr.WorkUnits {
+		unit, err := workUnitFromURL(&spec.resource, rUnit.URL, spec)
+		if err != nil {
+			return nil, err
+		}
+		err = unit.Refresh()
+		if err != nil {
+			return nil, err
+		}
+		units[unit.Name()] = unit
+	}
+	return units, nil
+}
+
+func (spec *workSpec) WorkUnitStatuses(names []string) (map[string]coordinate.WorkUnitStatus, error) {
+	repr := restdata.WorkUnitNames{Names: names}
+	var resp restdata.WorkUnitStatuses
+	err := spec.PostTo(spec.Representation.WorkUnitStatusesURL, map[string]interface{}{}, repr, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Statuses == nil {
+		resp.Statuses = make(map[string]coordinate.WorkUnitStatus)
+	}
+	return resp.Statuses, nil
+}
+
+func (spec *workSpec) WorkUnitPriorities(names []string) (map[string]float64, error) {
+	repr := restdata.WorkUnitNames{Names: names}
+	var resp restdata.WorkUnitPriorities
+	err := spec.PostTo(spec.Representation.WorkUnitPrioritiesURL, map[string]interface{}{}, repr, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Priorities == nil {
+		resp.Priorities = make(map[string]float64)
+	}
+	return resp.Priorities, nil
+}
+
 func (spec *workSpec) CountWorkUnitStatus() (map[coordinate.WorkUnitStatus]int, error) {
 	result := make(map[coordinate.WorkUnitStatus]int)
 	err := spec.GetFrom(spec.Representation.WorkUnitCountsURL, map[string]interface{}{}, &result)
@@ -142,6 +246,37 @@ func (spec *workSpec) CountWorkUnitStatus() (map[coordinate.WorkUnitStatus]int,
 	return result, nil
 }
 
+func (spec *workSpec) DataSize() (int64, error) {
+	var repr restdata.DataSize
+	err := spec.GetFrom(spec.Representation.DataSizeURL, map[string]interface{}{}, &repr)
+	return repr.Bytes, err
+}
+
+func (spec *workSpec) PriorityHistogram() (map[float64]int, error) {
+	var repr restdata.PriorityHistogram
+	err := spec.GetFrom(spec.Representation.PriorityHistogramURL, map[string]interface{}{}, &repr)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[float64]int, len(repr.Priorities))
+	for _, item := range repr.Priorities {
+		result[item.Priority] = item.Count
+	}
+	return result, nil
+}
+
+func (spec *workSpec) Successors() ([]string, error) {
+	meta, err := spec.Meta(false)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	if meta.NextWorkSpecName != "" {
+		names = append(names, meta.NextWorkSpecName)
+	}
+	return names, nil
+}
+
 func (spec *workSpec) SetWorkUnitPriorities(q coordinate.WorkUnitQuery, priority float64) error {
 	params := queryToParams(q)
 	repr := restdata.WorkUnit{Meta: &coordinate.WorkUnitMeta{
@@ -158,6 +293,48 @@ func (spec *workSpec) AdjustWorkUnitPriorities(q coordinate.WorkUnitQuery, prior
 	return spec.PostTo(spec.Representation.WorkUnitAdjustURL, params, repr, nil)
 }
 
+func (spec *workSpec) ResetPriorities(q coordinate.WorkUnitQuery) error {
+	return spec.SetWorkUnitPriorities(q, 0)
+}
+
+func (spec *workSpec) SetNextContinuous(t time.Time) error {
+	repr := restdata.NextContinuous{NextContinuous: t}
+	return spec.PostTo(spec.Representation.NextContinuousURL, map[string]interface{}{}, repr, nil)
+}
+
+func (spec *workSpec) ReorderAvailable(orderedNames []string) error {
+	repr := restdata.WorkUnitOrder{Names: orderedNames}
+	return spec.PostTo(spec.Representation.WorkUnitReorderURL, map[string]interface{}{}, repr, nil)
+}
+
+func (spec *workSpec) PurgeAttempts(before time.Time, statuses []coordinate.AttemptStatus) (int, error) {
+	req := restdata.AttemptPurge{Before: before, Statuses: statuses}
+	var resp restdata.AttemptsPurged
+	err := spec.PostTo(spec.Representation.PurgeAttemptsURL, map[string]interface{}{}, req, &resp)
+	return resp.Purged, err
+}
+
+func (spec *workSpec) CountWorkUnits(q coordinate.WorkUnitQuery) (int, error) {
+	params := queryToParams(q)
+	params["count_only"] = true
+	var repr restdata.WorkUnitCount
+	err := spec.GetFrom(spec.Representation.WorkUnitQueryURL, params, &repr)
+	return repr.Count, err
+}
+
+func (spec *workSpec) Summary() (coordinate.WorkSpecSummary, error) {
+	var repr restdata.WorkSpecSummary
+	err := spec.GetFrom(spec.Representation.SpecSummaryURL, map[string]interface{}{}, &repr)
+	if err != nil {
+		return coordinate.WorkSpecSummary{}, err
+	}
+	return coordinate.WorkSpecSummary{
+		Data:   repr.Data,
+		Meta:   repr.Meta,
+		Counts: repr.Counts,
+	}, nil
+}
+
 func (spec *workSpec) DeleteWorkUnits(q coordinate.WorkUnitQuery) (int, error) {
 	params := queryToParams(q)
 	var repr restdata.WorkUnitDeleted
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"github.com/diffeo/go-coordinate/coordinate"
 	"github.com/diffeo/go-coordinate/restdata"
+	"time"
 )
 
 type workUnit struct {
@@ -20,7 +21,7 @@ func workUnitFromURL(parent *resource, path string, spec *workSpec) (*workUnit,
 		workSpec: spec,
 	}
 	var err error
-	unit.URL, err = parent.Template(path, map[string]interface{}{})
+	unit.resource, err = parent.Resolve(path, map[string]interface{}{})
 	if err == nil {
 		err = unit.Refresh()
 	}
@@ -70,6 +71,18 @@ func (unit *workUnit) Meta() (meta coordinate.WorkUnitMeta, err error) {
 	return
 }
 
+func (unit *workUnit) SetData(data map[string]interface{}) error {
+	// Units created from a list or an add only have the short
+	// representation, without the data URL
+	if unit.Representation.DataURL == "" {
+		if err := unit.Refresh(); err != nil {
+			return err
+		}
+	}
+	repr := restdata.WorkUnitData{Data: data}
+	return unit.PutTo(unit.Representation.DataURL, map[string]interface{}{}, repr, nil)
+}
+
 func (unit *workUnit) SetMeta(meta coordinate.WorkUnitMeta) error {
 	repr := restdata.WorkUnit{}
 	repr.Meta = &meta
@@ -89,6 +102,14 @@ func (unit *workUnit) SetPriority(p float64) error {
 	}, p)
 }
 
+func (unit *workUnit) CreatedAt() (time.Time, error) {
+	err := unit.Refresh()
+	if err == nil {
+		return unit.Representation.CreatedAt, nil
+	}
+	return time.Time{}, err
+}
+
 func (unit *workUnit) ActiveAttempt() (coordinate.Attempt, error) {
 	err := unit.Refresh()
 	if err == nil {
@@ -107,12 +128,33 @@ func (unit *workUnit) ClearActiveAttempt() error {
 	return unit.Put(repr, nil)
 }
 
+// getAttempts fetches the list of this work unit's attempts.  A
+// newly created work unit only knows its own URL, so this fetches
+// the full work unit first if needed.
+func (unit *workUnit) getAttempts(params map[string]interface{}, repr *restdata.AttemptList) error {
+	if unit.Representation.AttemptsURL == "" {
+		err := unit.Refresh()
+		if err != nil {
+			return err
+		}
+	}
+	return unit.GetFrom(unit.Representation.AttemptsURL, params, repr)
+}
+
 func (unit *workUnit) Attempts() ([]coordinate.Attempt, error) {
+	return unit.QueryAttempts(coordinate.AttemptQuery{})
+}
+
+func (unit *workUnit) QueryAttempts(q coordinate.AttemptQuery) ([]coordinate.Attempt, error) {
 	// See also commentary in worker.go returnAttempts().
 	// Note that at least most work units have very few attempts,
 	// and that every attempt should be for this work unit.
+	params := map[string]interface{}{}
+	if q.ArchivedOnly {
+		params["archived_only"] = "true"
+	}
 	var repr restdata.AttemptList
-	err := unit.GetFrom(unit.Representation.AttemptsURL, map[string]interface{}{}, &repr)
+	err := unit.getAttempts(params, &repr)
 	if err != nil {
 		return nil, err
 	}
@@ -132,7 +174,7 @@ func (unit *workUnit) Attempts() ([]coordinate.Attempt, error) {
 
 func (unit *workUnit) NumAttempts() (int, error) {
 	var repr restdata.AttemptList
-	err := unit.GetFrom(unit.Representation.AttemptsURL, map[string]interface{}{}, &repr)
+	err := unit.getAttempts(map[string]interface{}{}, &repr)
 	if err != nil {
 		return 0, err
 	}
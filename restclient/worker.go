@@ -17,7 +17,7 @@ type worker struct {
 func workerFromURL(parent *resource, path string) (*worker, error) {
 	w := &worker{}
 	var err error
-	w.URL, err = parent.Template(path, map[string]interface{}{})
+	w.resource, err = parent.Resolve(path, map[string]interface{}{})
 	if err == nil {
 		err = w.Refresh()
 	}
@@ -55,21 +55,19 @@ func (w *worker) SetParent(parent coordinate.Worker) error {
 }
 
 func (w *worker) Children() ([]coordinate.Worker, error) {
-	var children []coordinate.Worker
-	err := w.Refresh()
-	if err == nil {
-		children = make([]coordinate.Worker, len(w.Representation.ChildURLs))
-		for i, url := range w.Representation.ChildURLs {
-			children[i], err = workerFromURL(&w.resource, url)
-			if err != nil {
-				return nil, err
-			}
-		}
+	var repr restdata.WorkerList
+	err := w.GetFrom(w.Representation.ChildrenURL, map[string]interface{}{}, &repr)
+	if err != nil {
+		return nil, err
 	}
-	if err == nil {
-		return children, nil
+	children := make([]coordinate.Worker, len(repr.Workers))
+	for i, short := range repr.Workers {
+		children[i], err = workerFromURL(&w.resource, short.URL)
+		if err != nil {
+			return nil, err
+		}
 	}
-	return nil, err
+	return children, nil
 }
 
 func (w *worker) Active() (bool, error) {
@@ -132,13 +130,13 @@ func (w *worker) RequestAttempts(req coordinate.AttemptRequest) ([]coordinate.At
 		if err != nil {
 			return nil, err
 		}
-		url, err := w.Template(attemptRepr.URL, map[string]interface{}{})
+		resource, err := w.Resolve(attemptRepr.URL, map[string]interface{}{})
 		if err != nil {
 			return nil, err
 		}
 
 		attempts[i] = &attempt{
-			resource:       resource{URL: url},
+			resource:       resource,
 			Representation: attemptRepr,
 			workUnit:       unit,
 			worker:         w,
@@ -151,7 +149,7 @@ func (w *worker) MakeAttempt(unit coordinate.WorkUnit, lifetime time.Duration) (
 	req := restdata.AttemptSpecific{
 		WorkSpec: unit.WorkSpec().Name(),
 		WorkUnit: unit.Name(),
-		Lifetime: lifetime,
+		Lifetime: restdata.Duration(lifetime),
 	}
 	var a attempt
 	err := w.PostTo(w.Representation.MakeAttemptURL, map[string]interface{}{}, req, &a.Representation)
@@ -159,7 +157,7 @@ func (w *worker) MakeAttempt(unit coordinate.WorkUnit, lifetime time.Duration) (
 		return nil, err
 	}
 
-	a.URL, err = w.URL.Parse(a.Representation.URL)
+	a.resource, err = w.Resolve(a.Representation.URL, map[string]interface{}{})
 	if err != nil {
 		return nil, err
 	}
@@ -172,9 +170,9 @@ func (w *worker) MakeAttempt(unit coordinate.WorkUnit, lifetime time.Duration) (
 	return &a, nil
 }
 
-func (w *worker) returnAttempts(path string) ([]coordinate.Attempt, error) {
+func (w *worker) returnAttempts(path string, params map[string]interface{}) ([]coordinate.Attempt, error) {
 	repr := restdata.AttemptList{}
-	err := w.GetFrom(path, map[string]interface{}{}, &repr)
+	err := w.GetFrom(path, params, &repr)
 	if err != nil {
 		return nil, err
 	}
@@ -201,13 +199,108 @@ func (w *worker) returnAttempts(path string) ([]coordinate.Attempt, error) {
 }
 
 func (w *worker) ActiveAttempts() ([]coordinate.Attempt, error) {
-	return w.returnAttempts(w.Representation.ActiveAttemptsURL)
+	return w.returnAttempts(w.Representation.ActiveAttemptsURL, map[string]interface{}{})
 }
 
 func (w *worker) AllAttempts() ([]coordinate.Attempt, error) {
-	return w.returnAttempts(w.Representation.AllAttemptsURL)
+	return w.returnAttempts(w.Representation.AllAttemptsURL, map[string]interface{}{})
+}
+
+func (w *worker) AttemptsInWindow(start, end time.Time) ([]coordinate.Attempt, error) {
+	params := map[string]interface{}{}
+	if !start.IsZero() {
+		params["since"] = start.Format(time.RFC3339Nano)
+	}
+	if !end.IsZero() {
+		params["until"] = end.Format(time.RFC3339Nano)
+	}
+	return w.returnAttempts(w.Representation.AllAttemptsURL, params)
 }
 
 func (w *worker) ChildAttempts() ([]coordinate.Attempt, error) {
-	return w.returnAttempts(w.Representation.ChildAttemptsURL)
+	return w.returnAttempts(w.Representation.ChildAttemptsURL, map[string]interface{}{})
+}
+
+func (w *worker) FinishAttempts(attempts []coordinate.Attempt, data []map[string]interface{}) error {
+	return w.completeAttempts(w.Representation.FinishAttemptsURL, attempts, data, 0)
+}
+
+func (w *worker) FailAttempts(attempts []coordinate.Attempt, data []map[string]interface{}) error {
+	return w.completeAttempts(w.Representation.FailAttemptsURL, attempts, data, 0)
+}
+
+func (w *worker) RetryAttempts(attempts []coordinate.Attempt, data []map[string]interface{}, delay time.Duration) error {
+	return w.completeAttempts(w.Representation.RetryAttemptsURL, attempts, data, delay)
+}
+
+// completeAttempts posts an AttemptBatch naming attempts to path.
+func (w *worker) completeAttempts(path string, attempts []coordinate.Attempt, data []map[string]interface{}, delay time.Duration) error {
+	if data != nil && len(data) != len(attempts) {
+		return coordinate.ErrBatchLength
+	}
+	repr := restdata.AttemptBatch{
+		Attempts: make([]restdata.AttemptBatchItem, len(attempts)),
+		Delay:    restdata.Duration(delay),
+	}
+	for i, cAttempt := range attempts {
+		a, ok := cAttempt.(*attempt)
+		if !ok {
+			return coordinate.ErrWrongBackend
+		}
+		repr.Attempts[i] = restdata.AttemptBatchItem{
+			WorkSpec: a.workUnit.WorkSpec().Name(),
+			WorkUnit: a.workUnit.Name(),
+			ID:       a.ID(),
+		}
+		if data != nil {
+			repr.Attempts[i].Data = data[i]
+		}
+	}
+	return w.PostTo(path, map[string]interface{}{}, repr, nil)
+}
+
+func (w *worker) CompleteAttempts(outcomes []coordinate.AttemptOutcome) ([]error, error) {
+	// Outcomes without an attempt fail here, and the rest are
+	// sent to the server; sent[j] is the index of the jth one
+	errs := make([]error, len(outcomes))
+	var sent []int
+	var repr restdata.AttemptOutcomeList
+	for i, outcome := range outcomes {
+		if outcome.Attempt == nil {
+			errs[i] = coordinate.ErrBadOutcome
+			continue
+		}
+		a, ok := outcome.Attempt.(*attempt)
+		if !ok {
+			return nil, coordinate.ErrWrongBackend
+		}
+		sent = append(sent, i)
+		repr.Attempts = append(repr.Attempts, restdata.AttemptOutcomeItem{
+			AttemptBatchItem: restdata.AttemptBatchItem{
+				WorkSpec: a.workUnit.WorkSpec().Name(),
+				WorkUnit: a.workUnit.Name(),
+				ID:       a.ID(),
+				Data:     outcome.Data,
+			},
+			Status: outcome.Status,
+			Delay:  restdata.Duration(outcome.Delay),
+		})
+	}
+	if len(sent) == 0 {
+		return errs, nil
+	}
+	var resp restdata.AttemptOutcomeResults
+	err := w.PostTo(w.Representation.CompleteAttemptsURL, map[string]interface{}{}, repr, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Errors) != len(sent) {
+		return nil, coordinate.ErrBatchLength
+	}
+	for j, errResp := range resp.Errors {
+		if errResp != nil {
+			errs[sent[j]] = errResp.ToError()
+		}
+	}
+	return errs, nil
 }
@@ -64,6 +64,27 @@ func (e ErrBadRequest) HTTPStatus() int {
 	return http.StatusBadRequest
 }
 
+// ErrUnauthorized is returned as an error when a request does not
+// carry acceptable credentials.
+type ErrUnauthorized struct {
+	Err error
+
+	// Challenge, if non-empty, is sent in the WWW-Authenticate
+	// header to tell the client how to authenticate, for
+	// instance `Basic realm="coordinate"`.  It may hold several
+	// comma-separated challenges, as RFC 7235 allows.
+	Challenge string
+}
+
+func (e ErrUnauthorized) Error() string {
+	return e.Err.Error()
+}
+
+// HTTPStatus returns a fixed 401 Unauthorized HTTP status code.
+func (e ErrUnauthorized) HTTPStatus() int {
+	return http.StatusUnauthorized
+}
+
 // FromError populates an ErrorResponse to fill in its fields based
 // on an error value.  This remaps the well-known Coordinate errors
 // to specific e.Error codes.
@@ -73,18 +94,38 @@ func (e *ErrorResponse) FromError(err error) {
 		e.Error = "ErrNoWorkSpecName"
 	case coordinate.ErrBadWorkSpecName:
 		e.Error = "ErrBadWorkSpecName"
+	case coordinate.ErrBadWorkSpecOrder:
+		e.Error = "ErrBadWorkSpecOrder"
 	case coordinate.ErrChangedName:
 		e.Error = "ErrChangedName"
 	case coordinate.ErrLostLease:
 		e.Error = "ErrLostLease"
+	case coordinate.ErrLeaseTotalExceeded:
+		e.Error = "ErrLeaseTotalExceeded"
 	case coordinate.ErrNotPending:
 		e.Error = "ErrNotPending"
+	case coordinate.ErrWorkUnitActive:
+		e.Error = "ErrWorkUnitActive"
+	case coordinate.ErrBatchLength:
+		e.Error = "ErrBatchLength"
+	case coordinate.ErrWrongWorker:
+		e.Error = "ErrWrongWorker"
+	case coordinate.ErrWrongWorkUnit:
+		e.Error = "ErrWrongWorkUnit"
+	case coordinate.ErrBadOutcome:
+		e.Error = "ErrBadOutcome"
+	case coordinate.ErrBadCursor:
+		e.Error = "ErrBadCursor"
 	case coordinate.ErrCannotBecomeContinuous:
 		e.Error = "ErrCannotBecomeContinuous"
 	case coordinate.ErrWrongBackend:
 		e.Error = "ErrWrongBackend"
+	case coordinate.ErrWrongNamespace:
+		e.Error = "ErrWrongNamespace"
 	case coordinate.ErrNoWork:
 		e.Error = "ErrNoWork"
+	case coordinate.ErrNoSuchReservation:
+		e.Error = "ErrNoSuchReservation"
 	case coordinate.ErrWorkUnitNotList:
 		e.Error = "ErrWorkUnitNotList"
 	case coordinate.ErrWorkUnitTooShort:
@@ -93,6 +134,8 @@ func (e *ErrorResponse) FromError(err error) {
 		e.Error = "ErrBadPriority"
 	case coordinate.ErrGone:
 		e.Error = "ErrGone"
+	case coordinate.ErrWatchNotSupported:
+		e.Error = "ErrWatchNotSupported"
 	}
 	switch et := err.(type) {
 	case coordinate.ErrNoSuchWorkSpec:
@@ -101,14 +144,33 @@ func (e *ErrorResponse) FromError(err error) {
 	case coordinate.ErrNoSuchWorkUnit:
 		e.Error = "ErrNoSuchWorkUnit"
 		e.Value = et.Name
+	case coordinate.ErrTooManyNamespaces:
+		e.Error = "ErrTooManyNamespaces"
+		e.Value = et.Name
+	case coordinate.ErrWorkSpecDataTooLarge:
+		e.Error = "ErrWorkSpecDataTooLarge"
+		e.Value = et.WorkSpec
+		e.Size = et.Size
+		e.Limit = et.Limit
 	case ErrNotFound:
 		// Discard this wrapper and return the embedded error
 		e.FromError(et.Err)
 	case ErrBadRequest:
 		e.FromError(et.Err)
+	case ErrUnauthorized:
+		e.Error = "ErrUnauthorized"
 	}
 }
 
+// ErrorCode returns the e.Error code FromError gives err, such as
+// "ErrNoSuchWorkSpec", or "error" if err is not a well-known
+// Coordinate error.
+func ErrorCode(err error) string {
+	e := ErrorResponse{Error: "error"}
+	e.FromError(err)
+	return e.Error
+}
+
 // ToError converts e back to a Coordinate error, if that is possible.
 // If not, returns a plain error with e.Message text.
 func (e *ErrorResponse) ToError() error {
@@ -117,18 +179,38 @@ func (e *ErrorResponse) ToError() error {
 		return coordinate.ErrNoWorkSpecName
 	case "ErrBadWorkSpecName":
 		return coordinate.ErrBadWorkSpecName
+	case "ErrBadWorkSpecOrder":
+		return coordinate.ErrBadWorkSpecOrder
 	case "ErrChangedName":
 		return coordinate.ErrChangedName
 	case "ErrLostLease":
 		return coordinate.ErrLostLease
+	case "ErrLeaseTotalExceeded":
+		return coordinate.ErrLeaseTotalExceeded
 	case "ErrNotPending":
 		return coordinate.ErrNotPending
+	case "ErrWorkUnitActive":
+		return coordinate.ErrWorkUnitActive
+	case "ErrBatchLength":
+		return coordinate.ErrBatchLength
+	case "ErrWrongWorker":
+		return coordinate.ErrWrongWorker
+	case "ErrWrongWorkUnit":
+		return coordinate.ErrWrongWorkUnit
+	case "ErrBadOutcome":
+		return coordinate.ErrBadOutcome
+	case "ErrBadCursor":
+		return coordinate.ErrBadCursor
 	case "ErrCannotBecomeContinuous":
 		return coordinate.ErrCannotBecomeContinuous
 	case "ErrWrongBackend":
 		return coordinate.ErrWrongBackend
+	case "ErrWrongNamespace":
+		return coordinate.ErrWrongNamespace
 	case "ErrNoWork":
 		return coordinate.ErrNoWork
+	case "ErrNoSuchReservation":
+		return coordinate.ErrNoSuchReservation
 	case "ErrWorkUnitNotList":
 		return coordinate.ErrWorkUnitNotList
 	case "ErrWorkUnitTooShort":
@@ -137,10 +219,22 @@ func (e *ErrorResponse) ToError() error {
 		return coordinate.ErrBadPriority
 	case "ErrGone":
 		return coordinate.ErrGone
+	case "ErrWatchNotSupported":
+		return coordinate.ErrWatchNotSupported
 	case "ErrNoSuchWorkSpec":
 		return coordinate.ErrNoSuchWorkSpec{Name: e.Value}
 	case "ErrNoSuchWorkUnit":
 		return coordinate.ErrNoSuchWorkUnit{Name: e.Value}
+	case "ErrTooManyNamespaces":
+		return coordinate.ErrTooManyNamespaces{Name: e.Value}
+	case "ErrWorkSpecDataTooLarge":
+		return coordinate.ErrWorkSpecDataTooLarge{
+			WorkSpec: e.Value,
+			Size:     e.Size,
+			Limit:    e.Limit,
+		}
+	case "ErrUnauthorized":
+		return ErrUnauthorized{Err: errors.New(e.Message)}
 	default:
 		return errors.New(e.Message)
 	}
@@ -10,6 +10,8 @@ import (
 	"io"
 	"mime"
 	"reflect"
+	"strconv"
+	"time"
 )
 
 // Decode tries to decode a restdata object from a reader, such as an
@@ -181,3 +183,49 @@ func (d *DataDict) UnmarshalJSON(in []byte) error {
 	decoder := codec.NewDecoderBytes(b, h)
 	return decoder.Decode((*map[string]interface{})(d))
 }
+
+// Duration is a time.Duration that can be conveyed in JSON either as
+// a number of nanoseconds or as a string in Go duration syntax, such
+// as "15m0s" or "1.5h".  It is always encoded as a number, so that
+// servers that predate the string form can read it, but either form
+// is accepted when decoding.
+type Duration time.Duration
+
+// String returns the duration in Go duration syntax.
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// MarshalJSON returns the duration as a JSON number of nanoseconds.
+// This is deliberately not symmetric with UnmarshalJSON: a duration
+// decoded from a string is still encoded as a number, since older
+// servers and clients only read numbers.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatInt(int64(d), 10)), nil
+}
+
+// UnmarshalJSON decodes a duration from either a JSON number of
+// nanoseconds or a JSON string in Go duration syntax.
+func (d *Duration) UnmarshalJSON(in []byte) error {
+	jsonHandle := &codec.JsonHandle{}
+	if len(in) > 0 && in[0] == '"' {
+		var s string
+		err := codec.NewDecoderBytes(in, jsonHandle).Decode(&s)
+		if err != nil {
+			return err
+		}
+		duration, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		*d = Duration(duration)
+		return nil
+	}
+	var ns int64
+	err := codec.NewDecoderBytes(in, jsonHandle).Decode(&ns)
+	if err != nil {
+		return err
+	}
+	*d = Duration(ns)
+	return nil
+}
@@ -5,8 +5,11 @@ package restdata
 
 import (
 	"github.com/diffeo/go-coordinate/cborrpc"
+	"github.com/ugorji/go/codec"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestDataDictMarshal(t *testing.T) {
@@ -57,3 +60,81 @@ func TestDataDictMarshal(t *testing.T) {
 		}
 	}
 }
+
+func TestDurationMarshal(t *testing.T) {
+	tests := []struct {
+		JSON     string
+		Duration Duration
+		Numeric  string
+	}{
+		{"0", 0, "0"},
+		{"90000000000", Duration(90 * time.Second), "90000000000"},
+		{"\"90s\"", Duration(90 * time.Second), "90000000000"},
+		{"\"15m0s\"", Duration(15 * time.Minute), "900000000000"},
+		{"\"1.5h\"", Duration(90 * time.Minute), "5400000000000"},
+		{"\"-1ms\"", Duration(-time.Millisecond), "-1000000"},
+	}
+	for _, test := range tests {
+		var d Duration
+		err := (&d).UnmarshalJSON([]byte(test.JSON))
+		if err != nil {
+			t.Errorf("UnmarshalJSON(%v) => error %+v", test.JSON, err)
+			continue
+		}
+		if d != test.Duration {
+			t.Errorf("UnmarshalJSON(%v) => %v, want %v", test.JSON, d, test.Duration)
+		}
+
+		json, err := d.MarshalJSON()
+		if err != nil {
+			t.Errorf("MarshalJSON(%v) => error %+v", d, err)
+		} else if string(json) != test.Numeric {
+			t.Errorf("MarshalJSON(%v) => %v, want %v", d, string(json), test.Numeric)
+		}
+
+		// The string form round-trips through String()
+		var again Duration
+		s := "\"" + d.String() + "\""
+		err = (&again).UnmarshalJSON([]byte(s))
+		if err != nil {
+			t.Errorf("UnmarshalJSON(%v) => error %+v", s, err)
+		} else if again != d {
+			t.Errorf("UnmarshalJSON(%v) => %v, want %v", s, again, d)
+		}
+	}
+
+	for _, bad := range []string{"\"15 minutes\"", "\"\"", "true"} {
+		var d Duration
+		if err := (&d).UnmarshalJSON([]byte(bad)); err == nil {
+			t.Errorf("UnmarshalJSON(%v) => %v, want error", bad, d)
+		}
+	}
+}
+
+func TestDurationInObject(t *testing.T) {
+	for _, in := range []string{
+		`{"extend_duration":300000000000,"delay":90000000000}`,
+		`{"extend_duration":"5m","delay":"1m30s"}`,
+	} {
+		var repr AttemptCompletion
+		err := Decode(V1JSONMediaType, strings.NewReader(in), &repr)
+		if err != nil {
+			t.Errorf("Decode(%v) => error %+v", in, err)
+			continue
+		}
+		if repr.ExtendDuration != Duration(5*time.Minute) || repr.Delay != Duration(90*time.Second) {
+			t.Errorf("Decode(%v) => %+v", in, repr)
+		}
+	}
+
+	var out []byte
+	err := codec.NewEncoderBytes(&out, &codec.JsonHandle{}).Encode(AttemptBatch{
+		Attempts: []AttemptBatchItem{},
+		Delay:    Duration(time.Second),
+	})
+	if err != nil {
+		t.Errorf("Encode(AttemptBatch) => error %+v", err)
+	} else if !strings.Contains(string(out), `"delay":1000000000`) {
+		t.Errorf("Encode(AttemptBatch) => %v, want numeric delay", string(out))
+	}
+}
@@ -58,7 +58,12 @@
 //
 // Timestamps, when they appear, are represented in JSON as RFC 3339
 // strings, "2012-03-04T05:06:07.890Z".  Durations, when they appear,
-// represented in JSON as a number of nanoseconds.
+// are represented in JSON as a number of nanoseconds.  Duration
+// fields in the request objects defined here also accept a string in
+// Go duration syntax, such as "15m0s" or "90s", but this package
+// always writes them as numbers, so a value sent as "90s" comes back
+// as 90000000000; durations in the work spec metadata and attempt
+// requests defined by the coordinate package must be numbers.
 //
 // HTTP Considerations
 //
@@ -96,12 +101,10 @@
 //
 // Other Notes
 //
-// The coordinate Attempt type does not provide any sort of unique
-// identifier.  Implementations may assume that the triple of a work
-// unit, a worker, and its start time (in whole seconds) is enough to
-// identify an attempt.  Only test code is especially likely to run
-// into trouble with this, and it should address it with a mock time
-// source.
+// Attempts are identified by the opaque string returned from the
+// coordinate Attempt.ID() method, which is only unique within a
+// single work unit.  Attempt URLs therefore live under their work
+// unit's URL.
 package restdata
 
 import (
@@ -117,6 +120,21 @@ const V1JSONMediaType = "application/vnd.diffeo.coordinate.v1+json"
 // representation of this content.
 const JSONMediaType = "application/vnd.diffeo.coordinate+json"
 
+// EventStreamMediaType is the MIME type of a stream of Server-Sent
+// Events, as returned from WorkSpec.EventsURL.
+const EventStreamMediaType = "text/event-stream"
+
+// WorkAvailableEvent is the Server-Sent Events event type sent when
+// a work spec may have new work.  Its data is a WorkAvailable
+// object.
+const WorkAvailableEvent = "available"
+
+// RequestIDHeader is the HTTP header that carries a request's tracing
+// identifier.  A client may supply it to correlate its own logs with
+// the server's; if it is absent the server generates one.  In either
+// case the server echoes it back in the response.
+const RequestIDHeader = "X-Request-ID"
+
 // DataDict is an arbitrary user-provided data dictionary.  Many
 // objects have these, generally in a field named Data.  If any of the
 // values have (possibly further embedded) a cborrpc.PythonTuple or
@@ -169,6 +187,17 @@ type RootData struct {
 	// parameter, "namespace", which should be substituted for the
 	// (possibly escaped) name of the namespace.
 	NamespaceURL string `json:"namespace_url"`
+
+	// ServerTimeURL points at the server's current time.  This
+	// endpoint supports HTTP GET, returning a ServerTime.
+	ServerTimeURL string `json:"server_time_url"`
+}
+
+// ServerTime reports the server's notion of the current time.
+type ServerTime struct {
+	// Time is the current time on the server.  This is in RFC
+	// 3339 format, e.g. "2012-03-04T05:06:07.890Z".
+	Time time.Time `json:"time"`
 }
 
 // NamespaceShort provides minimal data to identify a single namespace.
@@ -205,9 +234,17 @@ type Namespace struct {
 	// spec.
 	WorkSpecURL string `json:"work_spec_url"`
 
+	// AvailableRuntimesURL points at the list of runtimes that
+	// have available work in this namespace.  This endpoint only
+	// supports HTTP GET, returning a RuntimeList.
+	AvailableRuntimesURL string `json:"available_runtimes_url"`
+
 	// WorkersURL points at the list of workers in this namespace.
-	// This endpoint supports HTTP GET, returning a WorkersList,
+	// This endpoint supports HTTP GET, returning a WorkerList,
 	// and HTTP POST, to submit a Worker and return a WorkerShort.
+	// This is a URI template with an optional parameter
+	// "parent"; if it is given, only the direct children of the
+	// named worker are listed.
 	//
 	// The semantics of HTTP GET of this URL are likely to change
 	// in the future.
@@ -224,6 +261,66 @@ type Namespace struct {
 	// changing their parents.  All of these are performed by HTTP
 	// PUT to this endpoint.
 	WorkerURL string `json:"worker_url"`
+
+	// DeactivateWorkersURL points at an endpoint to deactivate
+	// many workers at once.  This endpoint only supports HTTP
+	// POST, submitting a WorkerDeactivate and returning a
+	// WorkersDeactivated.
+	DeactivateWorkersURL string `json:"deactivate_workers_url"`
+
+	// DeleteWorkersURL points at an endpoint to delete many
+	// workers and their attempts at once.  This endpoint only
+	// supports HTTP POST, submitting a WorkerDelete and
+	// returning a WorkersDeleted.
+	DeleteWorkersURL string `json:"delete_workers_url"`
+
+	// ExpiringAttemptsURL points at a list of pending attempts
+	// that will expire soon.  This endpoint only supports HTTP
+	// GET, returning an AttemptList ordered by expiration time.
+	// This is a URI template with a single parameter, "within",
+	// which is a Go duration string such as "5m".
+	ExpiringAttemptsURL string `json:"expiring_attempts_url"`
+
+	// RebalanceAttemptsURL points at an endpoint to release
+	// excess pending attempts from workers.  This endpoint only
+	// supports HTTP POST, submitting an AttemptRebalance and
+	// returning an AttemptsRebalanced.
+	RebalanceAttemptsURL string `json:"rebalance_attempts_url"`
+
+	// PipelineHealthURL points at a report of stuck pipelines in
+	// this namespace.  This endpoint only supports HTTP GET,
+	// returning a PipelineHealth.
+	PipelineHealthURL string `json:"pipeline_health_url"`
+
+	// DestroyWorkSpecsURL points at an endpoint to destroy
+	// several work specs at once.  This endpoint only supports
+	// HTTP POST, submitting a WorkSpecDestroy and returning a
+	// WorkSpecsDestroyed.
+	DestroyWorkSpecsURL string `json:"destroy_work_specs_url"`
+
+	// ReserveURL points at an endpoint to reserve a single work
+	// unit without running a worker.  This endpoint only
+	// supports HTTP POST, submitting a ReservationRequest and
+	// returning a coordinate.Reservation, or 204 No Content if
+	// there is no work to do.
+	ReserveURL string `json:"reserve_url"`
+
+	// ReleaseURL points at an endpoint to give up a reservation
+	// made at ReserveURL.  This endpoint only supports HTTP
+	// POST, submitting a ReservationToken.
+	ReleaseURL string `json:"release_url"`
+
+	// CompleteURL points at an endpoint to finish the work unit
+	// held by a reservation made at ReserveURL.  This endpoint
+	// only supports HTTP POST, submitting a ReservationToken.
+	CompleteURL string `json:"complete_url"`
+}
+
+// RuntimeList is a list of work spec runtime names.
+type RuntimeList struct {
+	// Runtimes contains the runtime names, in sorted order.  The
+	// default runtime is an empty string.
+	Runtimes []string `json:"runtimes"`
 }
 
 // WorkSpecShort provides data that identifies a work spec, but no more.
@@ -248,6 +345,10 @@ type WorkSpec struct {
 	// base64-encoded CBOR encoding of a map.
 	Data DataDict `json:"data"`
 
+	// NamespaceURL points at the namespace containing this work
+	// spec.
+	NamespaceURL string `json:"namespace_url"`
+
 	// SummaryURL points at a summary of the statuses of all
 	// of the work units in this work spec.  This endpoint supports
 	// HTTP GET, returning a coordinate.Summary.
@@ -261,12 +362,27 @@ type WorkSpec struct {
 	// spec; WorkUnitQueryURL is more flexible.
 	WorkUnitsURL string `json:"work_units_url"`
 
+	// AddWorkUnitsURL points at an endpoint to add many work
+	// units at once.  This endpoint only supports HTTP POST,
+	// submitting a WorkUnitBatch and returning a
+	// WorkUnitBatchResults.  Work units that cannot be added do
+	// not prevent the rest of the batch from being added.
+	AddWorkUnitsURL string `json:"add_work_units_url"`
+
 	// WorkUnitQueryURL retrieves a subset of the work units for
 	// this work spec.  This endpoint supports HTTP GET, returning
 	// a WorkUnitList, and HTTP DELETE, returning a count via a
 	// WorkUnitDeleted object. This is a URI template with
-	// parameters "name", "status", "previous", and "limit",
-	// matching the fields in the WorkUnitQuery object.
+	// parameters "name", "status", "previous",
+	// "never_attempted", "worker", "offset", "limit", and
+	// "cursor", matching the fields in the WorkUnitQuery object.
+	// A GET response is one page from WorkSpec.WorkUnitsPage(),
+	// and includes a link to the next page if there is one.
+	// If the "details"
+	// parameter is true, each WorkUnitShort in a GET response
+	// also includes the work unit's status and priority.  If the
+	// "count_only" parameter is true, a GET response is instead
+	// a WorkUnitCount with the number of matching work units.
 	WorkUnitQueryURL string `json:"work_unit_query_url"`
 
 	// WorkUnitURL points at a single work unit by name.  This
@@ -281,9 +397,15 @@ type WorkSpec struct {
 	// set to "-", it clears the active attempt; this is an
 	// exception to the general rule that URLs cannot be
 	// resubmitted.  No other changes are allowed, and if other
-	// fields are provided (including Data) they are ignored.
+	// fields are provided (including Data) they are ignored; use
+	// the work unit's DataURL to change its data.
 	WorkUnitURL string `json:"work_unit_url"`
 
+	// SpecSummaryURL points at the work spec's data, metadata,
+	// and work unit counts, all together.  This endpoint only
+	// supports HTTP GET, and returns a WorkSpecSummary.
+	SpecSummaryURL string `json:"spec_summary_url"`
+
 	// WorkUnitCountsURL points at summary data about how many
 	// work units are in this work spec.  This endpoint only
 	// supports HTTP GET, and returns a
@@ -292,12 +414,34 @@ type WorkSpec struct {
 	// statuses, and whose values are numbers.
 	WorkUnitCountsURL string `json:"work_unit_counts_url"`
 
+	// WorkUnitStatusesURL points at an endpoint to get the
+	// status of many work units at once.  This endpoint only
+	// supports HTTP POST, submitting a WorkUnitNames and
+	// returning a WorkUnitStatuses.
+	WorkUnitStatusesURL string `json:"work_unit_statuses_url"`
+
+	// WorkUnitPrioritiesURL points at an endpoint to get the
+	// priority of many work units at once.  This endpoint only
+	// supports HTTP POST, submitting a WorkUnitNames and
+	// returning a WorkUnitPriorities.
+	WorkUnitPrioritiesURL string `json:"work_unit_priorities_url"`
+
+	// DataSizeURL points at the total size of this work spec's
+	// work unit data.  This endpoint only supports HTTP GET, and
+	// returns a DataSize object.
+	DataSizeURL string `json:"data_size_url"`
+
+	// PriorityHistogramURL points at the number of work units at
+	// each priority in this work spec.  This endpoint only
+	// supports HTTP GET, and returns a PriorityHistogram object.
+	PriorityHistogramURL string `json:"priority_histogram_url"`
+
 	// WorkUnitChangeURL points at an endpoint to make bulk
 	// changes to work units.  This endpoint only supports HTTP
 	// POST, submitting a WorkUnit and returning nothing.  This is
 	// a URI template with parameters "name", "status",
-	// "previous", and "limit", matching the fields in the
-	// WorkUnitQuery object.
+	// "previous", "never_attempted", "worker", and "limit",
+	// matching the fields in the WorkUnitQuery object.
 	//
 	// The only supported operation is to change the priority of
 	// the matched work units by setting it to the Priority of the
@@ -307,8 +451,9 @@ type WorkSpec struct {
 	// WorkUnitAdjustURL points at an endpoint to apply deltas to
 	// several work units.  This endpoint only supports HTTP POST,
 	// submitting a WorkUnit and returning nothing.  This is a URI
-	// template with parameters "name", "status", "previous", and
-	// "limit", matching the fields in the WorkUnitQuery object.
+	// template with parameters "name", "status", "previous",
+	// "never_attempted", "worker", and "limit", matching the
+	// fields in the WorkUnitQuery object.
 	//
 	// The only supported operation is to adjust the priority of
 	// the matched work units by adding the Priority of the posted
@@ -316,6 +461,24 @@ type WorkSpec struct {
 	// ignored.
 	WorkUnitAdjustURL string `json:"work_unit_adjust_url"`
 
+	// WorkUnitReorderURL points at an endpoint to change the
+	// priorities of the available and delayed work units so they
+	// run in a specific order.  This endpoint only supports HTTP POST,
+	// submitting a WorkUnitOrder and returning nothing.
+	WorkUnitReorderURL string `json:"work_unit_reorder_url"`
+
+	// NextContinuousURL points at the time the next continuous
+	// work unit can be generated for this work spec.  This
+	// endpoint supports HTTP GET and POST, and its representation
+	// is a NextContinuous.
+	NextContinuousURL string `json:"next_continuous_url"`
+
+	// PurgeAttemptsURL points at an endpoint to delete old
+	// attempts for work units in this work spec.  This endpoint
+	// only supports HTTP POST, submitting an AttemptPurge and
+	// returning an AttemptsPurged.
+	PurgeAttemptsURL string `json:"purge_attempts_url"`
+
 	// MetaURL points at control metadata for this work spec.
 	// This endpoint supports HTTP GET and PUT, and its
 	// representation is a coordinate.WorkSpecMeta.  This is a
@@ -329,17 +492,131 @@ type WorkSpec struct {
 	// entire structure must be provided for HTTP PUT; otherwise
 	// values will be reset to false or zero.
 	MetaURL string `json:"meta"`
+
+	// SchedulableURL reports whether the scheduler could
+	// currently choose this work spec.  This endpoint only
+	// supports HTTP GET, and returns a Schedulable object.
+	SchedulableURL string `json:"schedulable_url"`
+
+	// EventsURL points at a stream of notifications for this
+	// work spec.  This endpoint only supports HTTP GET, and
+	// returns a text/event-stream of Server-Sent Events.  Each
+	// time a work unit may have become available, the stream
+	// sends a WorkAvailableEvent event.  The stream also sends
+	// comment lines periodically to keep the connection open.
+	// If the backend cannot deliver notifications, this returns
+	// an ErrWatchNotSupported error with status 501 Not
+	// Implemented, and clients should poll instead.
+	EventsURL string `json:"events_url"`
+}
+
+// WorkAvailable is the data of a WorkAvailableEvent.
+type WorkAvailable struct {
+	// WorkSpec is the name of the work spec with new work.
+	WorkSpec string `json:"work_spec"`
+}
+
+// WorkUnitOrder gives a desired scheduling order for work units.
+type WorkUnitOrder struct {
+	// Names lists work unit names, in the order they should run.
+	Names []string `json:"names"`
+}
+
+// NextContinuous holds the earliest time a continuous work spec can
+// generate a new work unit.
+type NextContinuous struct {
+	// NextContinuous is the earliest generation time.  A zero
+	// time means a work unit can be generated immediately.
+	NextContinuous time.Time `json:"next_continuous"`
+}
+
+// Schedulable reports whether a work spec can currently hand out
+// work.
+type Schedulable struct {
+	// Schedulable is true if the scheduler could choose this
+	// work spec right now.
+	Schedulable bool `json:"schedulable"`
+
+	// Reason explains why the work spec is not schedulable, and
+	// is empty if it is.
+	Reason string `json:"reason,omitempty"`
+}
+
+// DataSize reports how much work unit data a work spec holds.
+type DataSize struct {
+	// Bytes is the total size of the encoded work unit data.
+	Bytes int64 `json:"bytes"`
+}
+
+// PriorityHistogram reports how many work units a work spec has at
+// each priority.  JSON objects cannot have numeric keys, so this is
+// a list rather than a map, sorted by increasing priority.
+type PriorityHistogram struct {
+	Priorities []PriorityCount `json:"priorities"`
+}
+
+// PriorityCount is the number of work units at a single priority.
+type PriorityCount struct {
+	Priority float64 `json:"priority"`
+	Count    int     `json:"count"`
+}
+
+// WorkUnitNames names some work units in a work spec.
+type WorkUnitNames struct {
+	Names []string `json:"names"`
+}
+
+// WorkUnitStatuses holds the statuses of some work units, keyed by
+// work unit name.  Names that did not name a work unit are absent.
+type WorkUnitStatuses struct {
+	Statuses map[string]coordinate.WorkUnitStatus `json:"statuses"`
+}
+
+// WorkUnitPriorities holds the priorities of some work units, keyed
+// by work unit name.  Names that did not name a work unit are absent.
+type WorkUnitPriorities struct {
+	Priorities map[string]float64 `json:"priorities"`
+}
+
+// WorkSpecSummary is the combined data, metadata, and work unit
+// counts for a work spec.
+type WorkSpecSummary struct {
+	// Data is the user-provided work spec data dictionary.
+	Data DataDict `json:"data"`
+
+	// Meta is the work spec metadata, including available and
+	// pending counts.
+	Meta coordinate.WorkSpecMeta `json:"meta"`
+
+	// Counts gives the number of work units in each status; in
+	// JSON, this is an object keyed by work unit status strings.
+	Counts map[coordinate.WorkUnitStatus]int `json:"counts"`
 }
 
 // WorkUnitShort provides minimal identifying information for a work
 // unit.
 type WorkUnitShort struct {
 	NamedResource
+
+	// Status and Priority are only filled in for work unit lists
+	// requested with the "details" query parameter set.  They
+	// save a separate request per work unit to build a queue
+	// view.
+	Status   *coordinate.WorkUnitStatus `json:"status,omitempty"`
+	Priority *float64                   `json:"priority,omitempty"`
 }
 
 // WorkUnitList is a list of WorkUnitShort.
 type WorkUnitList struct {
 	WorkUnits []WorkUnitShort `json:"work_units"`
+
+	// Cursor, if non-empty, is the opaque cursor for the next
+	// page of a paged query; see WorkSpec.WorkUnitsPage().
+	Cursor string `json:"cursor,omitempty"`
+
+	// Next, if non-empty, is the URL of the next page of a paged
+	// query.  It repeats the same query with Cursor set.
+	Next string `json:"next,omitempty"`
 }
 
 // WorkUnit provides complete static data for a work unit.  (Coordinate
@@ -359,6 +636,10 @@ type WorkUnit struct {
 	// be directly changed.
 	Status coordinate.WorkUnitStatus `json:"status"`
 
+	// CreatedAt is the time this work unit was first added.
+	// This cannot be changed.
+	CreatedAt time.Time `json:"created_at"`
+
 	// WorkSpecURL points to the work spec containing this unit.
 	// See Namespace for further details.
 	WorkSpecURL string `json:"work_spec_url"`
@@ -371,24 +652,189 @@ type WorkUnit struct {
 	// field set to "-" clears (and abandons) the active attempt.
 	ActiveAttemptURL string `json:"active_attempt_url,omitempty"`
 
+	// DataURL points to an endpoint that replaces this work
+	// unit's data.  It only supports HTTP PUT, submitting a
+	// WorkUnitData.  This fails with ErrWorkUnitActive if the
+	// work unit has an active attempt.
+	DataURL string `json:"data_url"`
+
 	// AttemptsURL points to an endpoint that retrieves all of the
 	// attempts, past and current, for this work unit.  It only
 	// supports HTTP GET, and its representation is an
-	// AttemptList.
+	// AttemptList.  This is a URI template with an optional
+	// boolean parameter "archived_only", which limits the result
+	// to attempts that have been archived.
 	AttemptsURL string `json:"attempts_url"`
 }
 
+// WorkUnitData is a request to replace a work unit's data.
+type WorkUnitData struct {
+	// Data is the new work unit data.
+	Data DataDict `json:"data"`
+}
+
+// WorkUnitCount is the response to a work unit query that only
+// counts the matching work units.
+type WorkUnitCount struct {
+	// Count has the number of work units matching a query.
+	Count int `json:"count"`
+}
+
+// WorkUnitBatch is a request to add several work units.
+type WorkUnitBatch struct {
+	// WorkUnits lists the work units to add.  Only the Name,
+	// Data, and Meta fields of each are used.
+	WorkUnits []WorkUnit `json:"work_units"`
+}
+
+// WorkUnitBatchResult reports whether a single work unit in a
+// WorkUnitBatch was added.
+type WorkUnitBatchResult struct {
+	// Name is the name of the work unit.
+	Name string `json:"name"`
+
+	// Error is empty if the work unit was added, or otherwise
+	// describes why it was not.
+	Error string `json:"error,omitempty"`
+}
+
+// WorkUnitBatchResults is the response to a WorkUnitBatch.
+type WorkUnitBatchResults struct {
+	// Results has one entry per submitted work unit, in the
+	// same order as the request.
+	Results []WorkUnitBatchResult `json:"results"`
+}
+
 // WorkUnitDeleted is the response to a batch delete request.
 type WorkUnitDeleted struct {
 	// Deleted has the number of work units actually deleted.
 	Deleted int
 }
 
+// AttemptPurge is a request to delete historical attempts.
+type AttemptPurge struct {
+	// Before is the cutoff time; only attempts that ended
+	// before this are deleted.
+	Before time.Time `json:"before"`
+
+	// Statuses limits the attempts deleted to those with these
+	// statuses.  If empty, any completed attempt can be deleted.
+	Statuses []coordinate.AttemptStatus `json:"statuses,omitempty"`
+}
+
+// AttemptsPurged is the response to an attempt purge request.
+type AttemptsPurged struct {
+	// Purged has the number of attempts actually deleted.
+	Purged int `json:"purged"`
+}
+
 // WorkerShort includes minimal data to identify a worker.
 type WorkerShort struct {
 	NamedResource
 }
 
+// WorkerList is a list of workers.
+type WorkerList struct {
+	// Workers contains the embedded list of workers.
+	Workers []WorkerShort `json:"workers"`
+}
+
+// WorkerDeactivate is a request to deactivate the workers matching
+// a query.  Its fields match coordinate.WorkerQuery.
+type WorkerDeactivate struct {
+	// NamePrefix selects workers whose names begin with this
+	// string.
+	NamePrefix string `json:"name_prefix,omitempty"`
+
+	// Expired selects workers whose expiration time has passed.
+	Expired bool `json:"expired,omitempty"`
+}
+
+// WorkersDeactivated is the response to a worker deactivation
+// request.
+type WorkersDeactivated struct {
+	// Deactivated has the number of workers that were active
+	// and are now inactive.
+	Deactivated int `json:"deactivated"`
+}
+
+// WorkerDelete is a request to delete the workers matching a
+// query.  Its fields match coordinate.WorkerQuery.
+type WorkerDelete struct {
+	// NamePrefix selects workers whose names begin with this
+	// string.
+	NamePrefix string `json:"name_prefix,omitempty"`
+
+	// Expired selects workers whose expiration time has passed.
+	Expired bool `json:"expired,omitempty"`
+}
+
+// WorkersDeleted is the response to a worker deletion request.
+type WorkersDeleted struct {
+	// Deleted has the number of workers that were deleted.
+	Deleted int `json:"deleted"`
+}
+
+// WorkSpecDestroy is a request to destroy several work specs.
+type WorkSpecDestroy struct {
+	// Names lists the work specs to destroy.  Names that do not
+	// exist are skipped.
+	Names []string `json:"names"`
+}
+
+// WorkSpecsDestroyed is the response to a work spec destruction
+// request.
+type WorkSpecsDestroyed struct {
+	// Destroyed has the number of work specs that existed and
+	// were destroyed.
+	Destroyed int `json:"destroyed"`
+}
+
+// AttemptRebalance is a request to release pending attempts from
+// workers that hold too many of them.
+type AttemptRebalance struct {
+	// MaxPending is the number of pending attempts each worker
+	// may keep.
+	MaxPending int `json:"max_pending"`
+}
+
+// AttemptsRebalanced is the response to an attempt rebalance
+// request.
+type AttemptsRebalanced struct {
+	// Released has the number of attempts that were expired.
+	Released int `json:"released"`
+}
+
+// ReservationRequest asks to reserve a single work unit, as
+// coordinate.Reserve().
+type ReservationRequest struct {
+	// WorkSpecs names the work specs to reserve from.  If
+	// empty, any work spec may be used.
+	WorkSpecs []string `json:"work_specs,omitempty"`
+
+	// TTL is the length of the reservation.  If zero, use a
+	// system-provided default, generally 15 minutes.
+	TTL Duration `json:"ttl,omitempty"`
+}
+
+// ReservationToken identifies a reservation to release or complete.
+type ReservationToken struct {
+	// Token is the token from the coordinate.Reservation.
+	Token string `json:"token"`
+
+	// Data, if present when completing a reservation, replaces
+	// the work unit data.
+	Data DataDict `json:"data,omitempty"`
+}
+
+// PipelineHealth lists the problems found in a namespace's
+// pipelines.
+type PipelineHealth struct {
+	// Issues has the problems found, sorted by work spec name.
+	// This is empty if nothing is wrong.
+	Issues []coordinate.PipelineIssue `json:"issues"`
+}
+
 // Worker contains details for a single worker.
 type Worker struct {
 	WorkerShort
@@ -409,6 +855,11 @@ type Worker struct {
 	// else.
 	ChildURLs []string `json:"child_urls,omitempty"`
 
+	// ChildrenURL points at the list of this worker's children.
+	// This endpoint only supports HTTP GET, returning a
+	// WorkerList.
+	ChildrenURL string `json:"children_url"`
+
 	// Active is a flag indicating whether this worker is still
 	// alive.
 	//
@@ -456,10 +907,28 @@ type Worker struct {
 	// currently doing, all attempts that this worker has ever
 	// done, and this worker's children's active attempts,
 	// respectively.  These endpoints all only support HTTP GET
-	// and return AttemptList.
+	// and return AttemptList.  AllAttemptsURL is a URI template
+	// with optional parameters "since" and "until", RFC 3339
+	// timestamps that limit the result to attempts started at or
+	// after "since" and before "until".
 	ActiveAttemptsURL string `json:"active_attempts_url"`
 	AllAttemptsURL    string `json:"all_attempts_url"`
 	ChildAttemptsURL  string `json:"child_attempts_url"`
+
+	// FinishAttemptsURL, FailAttemptsURL, and RetryAttemptsURL
+	// point at endpoints that complete several of this worker's
+	// attempts at once, as the corresponding Attempt endpoints
+	// would.  These endpoints only support HTTP POST, accepting
+	// an AttemptBatch and returning nothing.
+	FinishAttemptsURL string `json:"finish_attempts_url"`
+	FailAttemptsURL   string `json:"fail_attempts_url"`
+	RetryAttemptsURL  string `json:"retry_attempts_url"`
+
+	// CompleteAttemptsURL points at an endpoint that completes
+	// several of this worker's attempts, each with its own
+	// outcome.  This endpoint only supports HTTP POST, accepting
+	// an AttemptOutcomeList and returning an AttemptOutcomeResults.
+	CompleteAttemptsURL string `json:"complete_attempts_url"`
 }
 
 // AttemptSpecific names a specific work unit to attempt.  This is the
@@ -475,7 +944,65 @@ type AttemptSpecific struct {
 	// attempt; it must be completed or renewed by this deadline.
 	// If zero, use a system-provided default, generally 15
 	// minutes.
-	Lifetime time.Duration `json:"lifetime"`
+	Lifetime Duration `json:"lifetime"`
+}
+
+// AttemptBatch names several of a worker's attempts to complete at
+// once.  This is the input parameter to the Worker.FinishAttemptsURL,
+// Worker.FailAttemptsURL, and Worker.RetryAttemptsURL endpoints.
+type AttemptBatch struct {
+	// Attempts lists the attempts to complete.
+	Attempts []AttemptBatchItem `json:"attempts"`
+
+	// Delay holds the length of time to wait before retrying
+	// the work units, if this is a retry request.
+	Delay Duration `json:"delay,omitempty"`
+}
+
+// AttemptBatchItem identifies a single attempt in an AttemptBatch.
+type AttemptBatchItem struct {
+	// WorkSpec holds the name of the work spec.
+	WorkSpec string `json:"work_spec"`
+
+	// WorkUnit holds the name of the work unit.
+	WorkUnit string `json:"work_unit"`
+
+	// ID holds the attempt's identifier within its work unit.
+	ID string `json:"id"`
+
+	// Data holds updated data for the attempt.  If absent the
+	// attempt data is not updated.
+	Data DataDict `json:"data,omitempty"`
+}
+
+// AttemptOutcomeItem identifies a single attempt in an
+// AttemptOutcomeList, and how to complete it.
+type AttemptOutcomeItem struct {
+	AttemptBatchItem
+
+	// Status is the new status of the attempt, one of
+	// "finished", "failed", or "retryable".
+	Status coordinate.AttemptStatus `json:"status"`
+
+	// Delay holds the length of time to wait before retrying
+	// the work unit, if Status is "retryable".
+	Delay Duration `json:"delay,omitempty"`
+}
+
+// AttemptOutcomeList is the input parameter to the
+// Worker.CompleteAttemptsURL endpoint.
+type AttemptOutcomeList struct {
+	// Attempts lists the attempts to complete.
+	Attempts []AttemptOutcomeItem `json:"attempts"`
+}
+
+// AttemptOutcomeResults contains the response to the
+// Worker.CompleteAttemptsURL endpoint.
+type AttemptOutcomeResults struct {
+	// Errors has one entry for each entry in the request's
+	// Attempts.  It is null if that attempt was completed, or
+	// describes why it was not.
+	Errors []*ErrorResponse `json:"errors"`
 }
 
 // AttemptResponse contains the response to the
@@ -492,12 +1019,13 @@ type AttemptResponse struct {
 }
 
 // AttemptShort contains minimum information to identify an attempt.
-// Note that attempts do not have names or unique identifiers.  This
-// treats an attempt by a specific worker to do a specific work unit
-// at a specific start time as unique.
 type AttemptShort struct {
 	Resource
 
+	// ID is the attempt's identifier, unique within its work
+	// unit.  See coordinate.Attempt.ID().
+	ID string `json:"id"`
+
 	// WorkUnitURL points at the work unit being performed.  Its
 	// representation is a WorkUnit.
 	WorkUnitURL string `json:"work_unit_url"`
@@ -541,15 +1069,54 @@ type Attempt struct {
 	// 3339 format, e.g. "2012-03-04T05:06:07.890Z".
 	ExpirationTime time.Time `json:"expiration_time"`
 
-	// RenewURL, ExpireURL, FinishURL, FailURL, and RetryURL each
-	// point to endpoints to change the state of this attempt.
-	// These endpoints only support HTTP POST, accepting an
-	// AttemptCompletion and returning nothing.
-	RenewURL  string `json:"renew_url"`
-	ExpireURL string `json:"expire_url"`
-	FinishURL string `json:"finish_url"`
-	FailURL   string `json:"fail_url"`
-	RetryURL  string `json:"retry_url"`
+	// FinishPrepared is true if PrepareFinishURL has been
+	// called for this attempt.
+	FinishPrepared bool `json:"finish_prepared,omitempty"`
+
+	// DataHistoryURL points at the history of data passed to
+	// RenewURL.  This endpoint only supports HTTP GET, and
+	// returns a DataHistory object.
+	DataHistoryURL string `json:"data_history_url"`
+
+	// LogsURL points at the lines of output logged by this
+	// attempt.  This endpoint supports HTTP GET, returning an
+	// AttemptLog with every line kept, and HTTP POST, submitting
+	// an AttemptLog whose lines are appended in order and
+	// returning nothing.
+	LogsURL string `json:"logs_url"`
+
+	// RenewURL, ExpireURL, ReleaseURL, PrepareFinishURL,
+	// FinishURL, FailURL, and RetryURL each point to endpoints to
+	// change the state of this attempt.  These endpoints only support HTTP POST,
+	// accepting an AttemptCompletion and returning nothing.
+	RenewURL         string `json:"renew_url"`
+	ExpireURL        string `json:"expire_url"`
+	ReleaseURL       string `json:"release_url"`
+	PrepareFinishURL string `json:"prepare_finish_url"`
+	FinishURL        string `json:"finish_url"`
+	FailURL          string `json:"fail_url"`
+	RetryURL         string `json:"retry_url"`
+}
+
+// DataSnapshot is a single entry in an attempt's data history.
+type DataSnapshot struct {
+	// Time is the time the data was recorded.  This is in RFC
+	// 3339 format, e.g. "2012-03-04T05:06:07.890Z".
+	Time time.Time `json:"time"`
+
+	// Data is the attempt data as of Time.
+	Data DataDict `json:"data"`
+}
+
+// DataHistory holds an attempt's data history, oldest first.
+type DataHistory struct {
+	Snapshots []DataSnapshot `json:"snapshots"`
+}
+
+// AttemptLog holds lines of output logged by an attempt, oldest
+// first.
+type AttemptLog struct {
+	Lines []string `json:"lines"`
 }
 
 // AttemptCompletion contains data submitted as part of one of the
@@ -560,14 +1127,13 @@ type AttemptCompletion struct {
 	Data DataDict `json:"data,omitempty"`
 
 	// ExtendDuration holds the further length of time to extend
-	// the attempt, if this is a renew request.  This is a number
-	// in nanoseconds.
-	ExtendDuration time.Duration `json:"extend_duration"`
+	// the attempt, if this is a renew request.
+	ExtendDuration Duration `json:"extend_duration"`
 
 	// Delay holds the length of time to wait before retrying the
-	// work unit, if this is a retry request.  This is a number in
-	// nanoseconds.  (Added in Coordinate 0.3.0)
-	Delay time.Duration `json:"delay"`
+	// work unit, if this is a retry request.  (Added in
+	// Coordinate 0.3.0)
+	Delay Duration `json:"delay"`
 }
 
 // ErrorResponse can be a response to any method, generally accompanied
@@ -585,6 +1151,11 @@ type ErrorResponse struct {
 	// Value is an extra parameter to the error if applicable.
 	Value string `json:"value,omitempty"`
 
+	// Size and Limit are the sizes, in bytes, reported with
+	// ErrWorkSpecDataTooLarge.
+	Size  int `json:"size,omitempty"`
+	Limit int `json:"limit,omitempty"`
+
 	// Stack holds a formatted backtrace, if the method failed
 	// due to a panic.
 	Stack string `json:"stack,omitempty"`
@@ -10,7 +10,7 @@ import (
 	"time"
 )
 
-func (api *restAPI) attemptURLBuilder(namespace coordinate.Namespace, attempt coordinate.Attempt, startTime time.Time, err error) *urlBuilder {
+func (api *restAPI) attemptURLBuilder(namespace coordinate.Namespace, attempt coordinate.Attempt, err error) *urlBuilder {
 	unit := attempt.WorkUnit()
 	spec := unit.WorkSpec()
 	worker := attempt.Worker()
@@ -20,7 +20,7 @@ func (api *restAPI) attemptURLBuilder(namespace coordinate.Namespace, attempt co
 			"spec", spec.Name(),
 			"unit", unit.Name(),
 			"worker", worker.Name(),
-			"start", startTime.Format(time.RFC3339),
+			"attempt", attempt.ID(),
 		)
 	}
 	return &urlBuilder{Error: err}
@@ -28,8 +28,9 @@ func (api *restAPI) attemptURLBuilder(namespace coordinate.Namespace, attempt co
 
 func (api *restAPI) fillAttemptShort(namespace coordinate.Namespace, attempt coordinate.Attempt, short *restdata.AttemptShort) error {
 	var err error
+	short.ID = attempt.ID()
 	short.StartTime, err = attempt.StartTime()
-	builder := api.attemptURLBuilder(namespace, attempt, short.StartTime, err)
+	builder := api.attemptURLBuilder(namespace, attempt, err)
 	builder.URL(&short.URL, "attempt")
 	builder.URL(&short.WorkUnitURL, "workUnit")
 	builder.URL(&short.WorkerURL, "worker")
@@ -50,9 +51,16 @@ func (api *restAPI) fillAttempt(namespace coordinate.Namespace, attempt coordina
 	if err == nil {
 		repr.ExpirationTime, err = attempt.ExpirationTime()
 	}
-	builder := api.attemptURLBuilder(namespace, attempt, repr.StartTime, err)
+	if err == nil {
+		repr.FinishPrepared, err = attempt.FinishPrepared()
+	}
+	builder := api.attemptURLBuilder(namespace, attempt, err)
+	builder.URL(&repr.DataHistoryURL, "attemptDataHistory")
+	builder.URL(&repr.LogsURL, "attemptLogs")
 	builder.URL(&repr.RenewURL, "attemptRenew")
 	builder.URL(&repr.ExpireURL, "attemptExpire")
+	builder.URL(&repr.ReleaseURL, "attemptRelease")
+	builder.URL(&repr.PrepareFinishURL, "attemptPrepareFinish")
 	builder.URL(&repr.FinishURL, "attemptFinish")
 	builder.URL(&repr.FailURL, "attemptFail")
 	builder.URL(&repr.RetryURL, "attemptRetry")
@@ -80,12 +88,29 @@ func (api *restAPI) AttemptGet(ctx *context) (interface{}, error) {
 	return repr, nil
 }
 
+func (api *restAPI) AttemptDataHistory(ctx *context) (interface{}, error) {
+	history, err := ctx.Attempt.DataHistory()
+	if err != nil {
+		return nil, err
+	}
+	resp := restdata.DataHistory{
+		Snapshots: make([]restdata.DataSnapshot, len(history)),
+	}
+	for i, snapshot := range history {
+		resp.Snapshots[i] = restdata.DataSnapshot{
+			Time: snapshot.Time,
+			Data: snapshot.Data,
+		}
+	}
+	return resp, nil
+}
+
 func (api *restAPI) AttemptRenew(ctx *context, in interface{}) (interface{}, error) {
 	repr, valid := in.(restdata.AttemptCompletion)
 	if !valid {
 		return nil, errUnmarshal
 	}
-	err := ctx.Attempt.Renew(repr.ExtendDuration, repr.Data)
+	err := ctx.Attempt.Renew(time.Duration(repr.ExtendDuration), repr.Data)
 	return nil, err
 }
 
@@ -98,6 +123,24 @@ func (api *restAPI) AttemptExpire(ctx *context, in interface{}) (interface{}, er
 	return nil, err
 }
 
+func (api *restAPI) AttemptRelease(ctx *context, in interface{}) (interface{}, error) {
+	repr, valid := in.(restdata.AttemptCompletion)
+	if !valid {
+		return nil, errUnmarshal
+	}
+	err := ctx.Attempt.Release(repr.Data)
+	return nil, err
+}
+
+func (api *restAPI) AttemptPrepareFinish(ctx *context, in interface{}) (interface{}, error) {
+	repr, valid := in.(restdata.AttemptCompletion)
+	if !valid {
+		return nil, errUnmarshal
+	}
+	err := ctx.Attempt.PrepareFinish(repr.Data)
+	return nil, err
+}
+
 func (api *restAPI) AttemptFinish(ctx *context, in interface{}) (interface{}, error) {
 	repr, valid := in.(restdata.AttemptCompletion)
 	if !valid {
@@ -116,12 +159,34 @@ func (api *restAPI) AttemptFail(ctx *context, in interface{}) (interface{}, erro
 	return nil, err
 }
 
+func (api *restAPI) AttemptLogs(ctx *context) (interface{}, error) {
+	lines, err := ctx.Attempt.Logs()
+	if err != nil {
+		return nil, err
+	}
+	return restdata.AttemptLog{Lines: lines}, nil
+}
+
+func (api *restAPI) AttemptAppendLog(ctx *context, in interface{}) (interface{}, error) {
+	repr, valid := in.(restdata.AttemptLog)
+	if !valid {
+		return nil, errUnmarshal
+	}
+	for _, line := range repr.Lines {
+		err := ctx.Attempt.AppendLog(line)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
 func (api *restAPI) AttemptRetry(ctx *context, in interface{}) (interface{}, error) {
 	repr, valid := in.(restdata.AttemptCompletion)
 	if !valid {
 		return nil, errUnmarshal
 	}
-	err := ctx.Attempt.Retry(repr.Data, repr.Delay)
+	err := ctx.Attempt.Retry(repr.Data, time.Duration(repr.Delay))
 	return nil, err
 }
 
@@ -130,32 +195,56 @@ func (api *restAPI) PopulateAttempt(r *mux.Router) {
 		Representation: restdata.AttemptShort{},
 		Context:        api.Context,
 	})
-	r.Path("/attempt/{worker}/{start}").Name("attempt").Handler(&resourceHandler{
+	r.Path("/attempt/{attempt}").Name("attempt").Handler(&resourceHandler{
 		Representation: restdata.AttemptShort{},
 		Context:        api.Context,
 		Get:            api.AttemptGet,
+		NoCache:        true,
+	})
+	r.Path("/attempt/{attempt}/data_history").Name("attemptDataHistory").Handler(&resourceHandler{
+		Representation: restdata.DataHistory{},
+		Context:        api.Context,
+		Get:            api.AttemptDataHistory,
+		NoCache:        true,
+	})
+	r.Path("/attempt/{attempt}/logs").Name("attemptLogs").Handler(&resourceHandler{
+		Representation: restdata.AttemptLog{},
+		Context:        api.Context,
+		Get:            api.AttemptLogs,
+		Post:           api.AttemptAppendLog,
+		NoCache:        true,
 	})
-	r.Path("/attempt/{worker}/{start}/renew").Name("attemptRenew").Handler(&resourceHandler{
+	r.Path("/attempt/{attempt}/renew").Name("attemptRenew").Handler(&resourceHandler{
 		Representation: restdata.AttemptCompletion{},
 		Context:        api.Context,
 		Post:           api.AttemptRenew,
 	})
-	r.Path("/attempt/{worker}/{start}/expire").Name("attemptExpire").Handler(&resourceHandler{
+	r.Path("/attempt/{attempt}/expire").Name("attemptExpire").Handler(&resourceHandler{
 		Representation: restdata.AttemptCompletion{},
 		Context:        api.Context,
 		Post:           api.AttemptExpire,
 	})
-	r.Path("/attempt/{worker}/{start}/finish").Name("attemptFinish").Handler(&resourceHandler{
+	r.Path("/attempt/{attempt}/release").Name("attemptRelease").Handler(&resourceHandler{
+		Representation: restdata.AttemptCompletion{},
+		Context:        api.Context,
+		Post:           api.AttemptRelease,
+	})
+	r.Path("/attempt/{attempt}/prepare_finish").Name("attemptPrepareFinish").Handler(&resourceHandler{
+		Representation: restdata.AttemptCompletion{},
+		Context:        api.Context,
+		Post:           api.AttemptPrepareFinish,
+	})
+	r.Path("/attempt/{attempt}/finish").Name("attemptFinish").Handler(&resourceHandler{
 		Representation: restdata.AttemptCompletion{},
 		Context:        api.Context,
 		Post:           api.AttemptFinish,
 	})
-	r.Path("/attempt/{worker}/{start}/fail").Name("attemptFail").Handler(&resourceHandler{
+	r.Path("/attempt/{attempt}/fail").Name("attemptFail").Handler(&resourceHandler{
 		Representation: restdata.AttemptCompletion{},
 		Context:        api.Context,
 		Post:           api.AttemptFail,
 	})
-	r.Path("/attempt/{worker}/{start}/retry").Name("attemptRetry").Handler(&resourceHandler{
+	r.Path("/attempt/{attempt}/retry").Name("attemptRetry").Handler(&resourceHandler{
 		Representation: restdata.AttemptCompletion{},
 		Context:        api.Context,
 		Post:           api.AttemptRetry,
@@ -0,0 +1,154 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package restserver
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/diffeo/go-coordinate/restdata"
+)
+
+// An Authenticator decides whether a request may use the REST API.
+// It returns nil to allow the request, or an error to reject it.  If
+// the error implements restdata.ErrorStatus its status code is sent,
+// and otherwise the request fails with 401 Unauthorized.
+type Authenticator func(*http.Request) error
+
+// errAuthenticationRequired is the error the standard authenticators
+// return for a request without acceptable credentials.  It
+// deliberately does not say which part of the credentials was wrong.
+var errAuthenticationRequired = errors.New("authentication required")
+
+// Errors returned by the standard authenticators, including the
+// challenge for each authentication scheme.
+var (
+	errNoBearerToken = restdata.ErrUnauthorized{
+		Err:       errAuthenticationRequired,
+		Challenge: `Bearer realm="coordinate"`,
+	}
+	errNoBasicAuth = restdata.ErrUnauthorized{
+		Err:       errAuthenticationRequired,
+		Challenge: `Basic realm="coordinate"`,
+	}
+)
+
+// BearerToken returns an Authenticator that accepts requests with an
+// "Authorization: Bearer" header carrying any of tokens.  Empty
+// tokens are ignored, so an empty header never matches.
+func BearerToken(tokens ...string) Authenticator {
+	return func(req *http.Request) error {
+		header := req.Header.Get("Authorization")
+		if !strings.HasPrefix(header, "Bearer ") {
+			return errNoBearerToken
+		}
+		given := []byte(strings.TrimPrefix(header, "Bearer "))
+		if len(given) == 0 {
+			return errNoBearerToken
+		}
+		for _, token := range tokens {
+			if subtle.ConstantTimeCompare(given, []byte(token)) == 1 {
+				return nil
+			}
+		}
+		return errNoBearerToken
+	}
+}
+
+// BasicAuth returns an Authenticator that accepts requests with HTTP
+// Basic credentials matching users, a map from user name to
+// password.
+func BasicAuth(users map[string]string) Authenticator {
+	return func(req *http.Request) error {
+		user, password, ok := req.BasicAuth()
+		if !ok {
+			return errNoBasicAuth
+		}
+		expected, present := users[user]
+		if !present {
+			return errNoBasicAuth
+		}
+		if subtle.ConstantTimeCompare([]byte(password), []byte(expected)) != 1 {
+			return errNoBasicAuth
+		}
+		return nil
+	}
+}
+
+// AnyAuthenticator returns an Authenticator that accepts a request if
+// any of auths does.  If all of them reject it, the first error is
+// returned; if that is a restdata.ErrUnauthorized, it carries the
+// challenges from all of the errors, so the client learns every
+// scheme it could use.
+func AnyAuthenticator(auths ...Authenticator) Authenticator {
+	return func(req *http.Request) error {
+		var first error
+		var challenges []string
+		for _, auth := range auths {
+			err := auth(req)
+			if err == nil {
+				return nil
+			}
+			if first == nil {
+				first = err
+			}
+			if errU, ok := err.(restdata.ErrUnauthorized); ok && errU.Challenge != "" {
+				challenges = append(challenges, errU.Challenge)
+			}
+		}
+		if first == nil {
+			first = restdata.ErrUnauthorized{Err: errAuthenticationRequired}
+		}
+		if errU, ok := first.(restdata.ErrUnauthorized); ok {
+			errU.Challenge = strings.Join(challenges, ", ")
+			first = errU
+		}
+		return first
+	}
+}
+
+// RequireAuth wraps an HTTP handler so that every request must pass
+// auth before it reaches inner.  Rejected requests get an error
+// response in the same form as other REST API errors.  Requests for
+// any of the exempt URL paths, such as "/" for a health check, are
+// passed through without checking.  If auth is nil, inner is
+// returned as is.
+//
+// Browsers do not send credentials with CORS preflight requests, so
+// when combining this with AllowCORS, AllowCORS should be the outer
+// handler.
+func RequireAuth(inner http.Handler, auth Authenticator, exempt ...string) http.Handler {
+	if auth == nil {
+		return inner
+	}
+	exemptPaths := make(map[string]struct{}, len(exempt))
+	for _, path := range exempt {
+		exemptPaths[path] = struct{}{}
+	}
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if _, ok := exemptPaths[req.URL.Path]; ok {
+			inner.ServeHTTP(resp, req)
+			return
+		}
+		err := auth(req)
+		if err == nil {
+			inner.ServeHTTP(resp, req)
+			return
+		}
+		status := http.StatusUnauthorized
+		if errS, hasStatus := err.(restdata.ErrorStatus); hasStatus {
+			status = errS.HTTPStatus()
+		} else {
+			err = restdata.ErrUnauthorized{Err: err}
+		}
+		if errU, isUnauthorized := err.(restdata.ErrUnauthorized); isUnauthorized && errU.Challenge != "" {
+			resp.Header().Set("WWW-Authenticate", errU.Challenge)
+		}
+		out := restdata.ErrorResponse{Error: "error", Message: err.Error()}
+		out.FromError(err)
+		writeAResponse(resp, status, restdata.V1JSONMediaType, out, toJSON)
+	})
+}
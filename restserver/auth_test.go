@@ -0,0 +1,134 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package restserver
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/diffeo/go-coordinate/memory"
+	"github.com/diffeo/go-coordinate/restdata"
+	"github.com/stretchr/testify/assert"
+)
+
+// authRequest sends a GET request for path through handler, with an
+// Authorization header if authorization is non-empty.
+func authRequest(handler http.Handler, path, authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Accept", restdata.V1JSONMediaType)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	return resp
+}
+
+// TestAuthDisabled checks that a nil authenticator allows everything.
+func TestAuthDisabled(t *testing.T) {
+	handler := RequireAuth(NewRouter(memory.New()), nil)
+	resp := authRequest(handler, "/namespace/-", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+// TestAuthBearerToken checks that only requests with a configured
+// bearer token are allowed, and that others get a decodable error.
+func TestAuthBearerToken(t *testing.T) {
+	handler := RequireAuth(NewRouter(memory.New()), BearerToken("one", "two"))
+
+	for _, authorization := range []string{"Bearer one", "Bearer two"} {
+		resp := authRequest(handler, "/namespace/-", authorization)
+		assert.Equal(t, http.StatusOK, resp.Code, authorization)
+	}
+
+	for _, authorization := range []string{"", "Bearer three", "Bearer ", "one", "Basic b25lOm9uZQ=="} {
+		resp := authRequest(handler, "/namespace/-", authorization)
+		assert.Equal(t, http.StatusUnauthorized, resp.Code, authorization)
+		assert.Equal(t, `Bearer realm="coordinate"`, resp.Header().Get("WWW-Authenticate"), authorization)
+
+		var errResp restdata.ErrorResponse
+		err := restdata.Decode(resp.Header().Get("Content-Type"), resp.Body, &errResp)
+		if assert.NoError(t, err, authorization) {
+			assert.IsType(t, restdata.ErrUnauthorized{}, errResp.ToError(), authorization)
+		}
+	}
+}
+
+// TestAuthBearerTokenEmpty checks that an empty configured token
+// does not let in a request with an empty bearer token.
+func TestAuthBearerTokenEmpty(t *testing.T) {
+	handler := RequireAuth(NewRouter(memory.New()), BearerToken("", "one"))
+
+	resp := authRequest(handler, "/namespace/-", "Bearer ")
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	resp = authRequest(handler, "/namespace/-", "Bearer one")
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+// TestAuthBasic checks HTTP Basic authentication, alone and combined
+// with bearer tokens.
+func TestAuthBasic(t *testing.T) {
+	basic := BasicAuth(map[string]string{"user": "secret"})
+	for _, handler := range []http.Handler{
+		RequireAuth(NewRouter(memory.New()), basic),
+		RequireAuth(NewRouter(memory.New()), AnyAuthenticator(BearerToken("token"), basic)),
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/namespace/-", nil)
+		req.SetBasicAuth("user", "secret")
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		req = httptest.NewRequest(http.MethodGet, "/namespace/-", nil)
+		req.SetBasicAuth("user", "wrong")
+		resp = httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	}
+}
+
+// TestAuthAnyChallenges checks that a request rejected by every
+// authenticator is told about every scheme it could use.
+func TestAuthAnyChallenges(t *testing.T) {
+	handler := RequireAuth(NewRouter(memory.New()), AnyAuthenticator(
+		BearerToken("token"),
+		BasicAuth(map[string]string{"user": "secret"}),
+	))
+	resp := authRequest(handler, "/namespace/-", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Equal(t, `Bearer realm="coordinate", Basic realm="coordinate"`, resp.Header().Get("WWW-Authenticate"))
+}
+
+// TestAuthExempt checks that exempt paths skip authentication, and
+// only exactly those paths.
+func TestAuthExempt(t *testing.T) {
+	handler := RequireAuth(NewRouter(memory.New()), BearerToken("token"), "/")
+
+	resp := authRequest(handler, "/", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	resp = authRequest(handler, "/namespace/-", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+// TestAuthCustomError checks that an authenticator can pick its own
+// status code, and that a plain error is treated as unauthorized.
+func TestAuthCustomError(t *testing.T) {
+	handler := RequireAuth(NewRouter(memory.New()), func(req *http.Request) error {
+		if req.Header.Get("Authorization") == "" {
+			return errors.New("who are you?")
+		}
+		return restdata.ErrNotFound{Err: errors.New("nothing for you here")}
+	})
+
+	resp := authRequest(handler, "/namespace/-", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Empty(t, resp.Header().Get("WWW-Authenticate"))
+
+	resp = authRequest(handler, "/namespace/-", "Bearer token")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
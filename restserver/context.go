@@ -38,7 +38,7 @@ func (api *restAPI) Context(req *http.Request) (ctx *context, err error) {
 	vars := mux.Vars(req)
 
 	var present bool
-	var namespace, spec, unit, worker, start string
+	var namespace, spec, unit, worker, attemptID string
 
 	if namespace, present = vars["namespace"]; present && err == nil {
 		namespace, err = restdata.MaybeDecodeName(namespace)
@@ -77,43 +77,33 @@ func (api *restAPI) Context(req *http.Request) (ctx *context, err error) {
 		}
 	}
 
-	if start, present = vars["start"]; present && err == nil {
-		start, err = restdata.MaybeDecodeName(start)
+	if attemptID, present = vars["attempt"]; present && err == nil {
+		attemptID, err = restdata.MaybeDecodeName(attemptID)
 	}
 
-	if err == nil && ctx.WorkUnit != nil && ctx.Worker != nil && start != "" {
-		// This is enough information to try to find a worker.
-		// Guess that, of these things, the work unit will have
-		// the fewest attempts, and scanning them in linear time
-		// is sane.  We won't be able to exactly match times
-		// but we can check that their serializations match.
-		// (Even this isn't foolproof because of time zones.)
-		var attempts []coordinate.Attempt
-		attempts, err = ctx.WorkUnit.Attempts()
-		if err == nil {
-			for _, attempt := range attempts {
-				var startTime time.Time
-				startTime, err = attempt.StartTime()
-				if err != nil {
-					break
-				}
-				myStart := startTime.Format(time.RFC3339)
-				if attempt.Worker().Name() == ctx.Worker.Name() && myStart == start {
-					ctx.Attempt = attempt
-					break
-				}
-			}
-		}
-		// If we had all of these things, we clearly were
-		// expecting to find an attempt, so fail if we didn't.
-		if err == nil && ctx.Attempt == nil {
-			err = restdata.ErrNotFound{Err: errors.New("no such attempt")}
-		}
+	if err == nil && ctx.WorkUnit != nil && attemptID != "" {
+		ctx.Attempt, err = findAttempt(ctx.WorkUnit, attemptID)
 	}
 
 	return
 }
 
+// findAttempt finds the attempt on unit with the given ID.  Attempt
+// IDs are only unique within a work unit, and a work unit should have
+// few enough attempts that scanning them in linear time is sane.
+func findAttempt(unit coordinate.WorkUnit, id string) (coordinate.Attempt, error) {
+	attempts, err := unit.Attempts()
+	if err != nil {
+		return nil, err
+	}
+	for _, attempt := range attempts {
+		if attempt.ID() == id {
+			return attempt, nil
+		}
+	}
+	return nil, restdata.ErrNotFound{Err: errors.New("no such attempt")}
+}
+
 // BoolParam looks at ctx.QueryParams for a parameter named name.  If
 // it has a normally-truthy value (1, on, false, no, ...) then return
 // that value.  Otherwise (empty string, foo, ...) return def.
@@ -128,6 +118,36 @@ func (ctx *context) BoolParam(name string, def bool) bool {
 	}
 }
 
+// TimeParam looks at ctx.QueryParams for a parameter named name, and
+// parses it as an RFC 3339 timestamp.  If the parameter is absent,
+// returns a zero time.
+func (ctx *context) TimeParam(name string) (time.Time, error) {
+	value := ctx.QueryParams.Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, restdata.ErrBadRequest{Err: err}
+	}
+	return t, nil
+}
+
+// DurationParam looks at ctx.QueryParams for a parameter named name,
+// and parses it as a Go duration string.  If the parameter is absent,
+// returns zero.
+func (ctx *context) DurationParam(name string) (time.Duration, error) {
+	value := ctx.QueryParams.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, restdata.ErrBadRequest{Err: err}
+	}
+	return d, nil
+}
+
 // Build a work unit query from query parameters.  This can fail (if
 // invalid statuses are named, if a non-integer limit is provided)
 // so it should only be called if a specific route wants it.
@@ -143,9 +163,22 @@ func (ctx *context) WorkUnitQuery() (q coordinate.WorkUnitQuery, err error) {
 		}
 	}
 	q.PreviousName = ctx.QueryParams.Get("previous")
+	q.NeverAttempted = ctx.BoolParam("never_attempted", false)
+	q.WorkerName = ctx.QueryParams.Get("worker")
+	offset := ctx.QueryParams.Get("offset")
+	if offset != "" {
+		q.Offset, err = strconv.Atoi(offset)
+		if err != nil {
+			return
+		}
+	}
 	limit := ctx.QueryParams.Get("limit")
 	if limit != "" {
 		q.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			return
+		}
 	}
+	q.Cursor = ctx.QueryParams.Get("cursor")
 	return
 }
@@ -0,0 +1,134 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package restserver
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/diffeo/go-coordinate/restdata"
+)
+
+// DefaultCORSMethods lists the HTTP methods allowed for cross-origin
+// requests if a CORS configuration does not name any.
+var DefaultCORSMethods = []string{"GET", "POST", "PUT", "DELETE"}
+
+// DefaultCORSHeaders lists the request headers allowed for
+// cross-origin requests if a CORS configuration does not name any.
+// These include the headers needed for authentication and for
+// conditional requests with ETags.
+var DefaultCORSHeaders = []string{
+	"Accept",
+	"Authorization",
+	"Content-Type",
+	"If-None-Match",
+	restdata.RequestIDHeader,
+}
+
+// corsExposedHeaders lists the response headers that cross-origin
+// callers may read.
+var corsExposedHeaders = strings.Join([]string{
+	"ETag",
+	restdata.RequestIDHeader,
+}, ", ")
+
+// CORS describes which cross-origin requests are allowed, so that
+// browser-based tools served from a different origin can call the
+// REST API directly.
+type CORS struct {
+	// AllowedOrigins lists the origins, such as
+	// "https://dashboard.example.com", that may make cross-origin
+	// requests.  The single entry "*" allows any origin.  If this
+	// is empty, CORS is disabled and no headers are added.
+	AllowedOrigins []string `mapstructure:"allowed_origins"`
+
+	// AllowedMethods lists the HTTP methods that may be used in
+	// cross-origin requests.  If empty, DefaultCORSMethods is
+	// used.
+	AllowedMethods []string `mapstructure:"allowed_methods"`
+
+	// AllowedHeaders lists the request headers that may be sent
+	// in cross-origin requests.  If empty, DefaultCORSHeaders is
+	// used.
+	AllowedHeaders []string `mapstructure:"allowed_headers"`
+
+	// MaxAge is how long browsers may cache a preflight
+	// response.  If zero, no Access-Control-Max-Age header is
+	// sent.
+	MaxAge time.Duration `mapstructure:"max_age"`
+}
+
+// Enabled returns true if this configuration allows any cross-origin
+// requests at all.
+func (c CORS) Enabled() bool {
+	return len(c.AllowedOrigins) > 0
+}
+
+// allowOrigin determines the value of the Access-Control-Allow-Origin
+// header for a request from origin, or returns an empty string if
+// that origin is not allowed.
+func (c CORS) allowOrigin(origin string) string {
+	if origin == "" {
+		return ""
+	}
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" {
+			return "*"
+		}
+		if allowed == origin {
+			return origin
+		}
+	}
+	return ""
+}
+
+// AllowCORS wraps an HTTP handler so that it answers CORS preflight
+// requests and adds Access-Control-* headers to requests from allowed
+// origins.  Preflight requests, OPTIONS requests carrying an
+// Access-Control-Request-Method header, are answered directly and
+// never reach inner.  Requests without an Origin header, or from an
+// origin that is not allowed, are passed through unchanged.  If cors
+// is not enabled, inner is returned as is.
+func AllowCORS(inner http.Handler, cors CORS) http.Handler {
+	if !cors.Enabled() {
+		return inner
+	}
+	methods := cors.AllowedMethods
+	if len(methods) == 0 {
+		methods = DefaultCORSMethods
+	}
+	headers := cors.AllowedHeaders
+	if len(headers) == 0 {
+		headers = DefaultCORSHeaders
+	}
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(headers, ", ")
+
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		origin := cors.allowOrigin(req.Header.Get("Origin"))
+		if origin == "" {
+			inner.ServeHTTP(resp, req)
+			return
+		}
+
+		h := resp.Header()
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Add("Vary", "Origin")
+
+		if req.Method == http.MethodOptions && req.Header.Get("Access-Control-Request-Method") != "" {
+			h.Set("Access-Control-Allow-Methods", allowMethods)
+			h.Set("Access-Control-Allow-Headers", allowHeaders)
+			if cors.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(int(cors.MaxAge/time.Second)))
+			}
+			resp.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		h.Set("Access-Control-Expose-Headers", corsExposedHeaders)
+		inner.ServeHTTP(resp, req)
+	})
+}
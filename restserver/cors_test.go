@@ -0,0 +1,113 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package restserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/diffeo/go-coordinate/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCORSDisabled checks that the default configuration adds no
+// headers at all.
+func TestCORSDisabled(t *testing.T) {
+	handler := AllowCORS(NewRouter(memory.New()), CORS{})
+
+	req := httptest.NewRequest(http.MethodGet, "/namespace/-", nil)
+	req.Header.Set("Origin", "https://dashboard.example.com")
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Empty(t, resp.Header().Get("Access-Control-Allow-Origin"))
+}
+
+// TestCORSPreflight checks that an OPTIONS preflight from an allowed
+// origin is answered directly with the configured headers.
+func TestCORSPreflight(t *testing.T) {
+	handler := AllowCORS(NewRouter(memory.New()), CORS{
+		AllowedOrigins: []string{"https://dashboard.example.com"},
+		AllowedHeaders: []string{"Content-Type"},
+		MaxAge:         10 * time.Minute,
+	})
+
+	req := httptest.NewRequest(http.MethodOptions, "/namespace/-/work_spec", nil)
+	req.Header.Set("Origin", "https://dashboard.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	h := resp.Header()
+	assert.Equal(t, "https://dashboard.example.com", h.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, POST, PUT, DELETE", h.Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type", h.Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "600", h.Get("Access-Control-Max-Age"))
+	assert.Equal(t, "Origin", h.Get("Vary"))
+}
+
+// TestCORSActualRequest checks that a normal request from an allowed
+// origin reaches the inner handler and gets the CORS headers, and
+// that requests from other origins do not.
+func TestCORSActualRequest(t *testing.T) {
+	handler := AllowCORS(NewRouter(memory.New()), CORS{
+		AllowedOrigins: []string{"https://dashboard.example.com"},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/namespace/-", nil)
+	req.Header.Set("Origin", "https://dashboard.example.com")
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	h := resp.Header()
+	assert.Equal(t, "https://dashboard.example.com", h.Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, h.Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "ETag, X-Request-ID", h.Get("Access-Control-Expose-Headers"))
+	assert.Equal(t, []string{"Origin", "Accept"}, h.Values("Vary"))
+
+	req = httptest.NewRequest(http.MethodGet, "/namespace/-", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Empty(t, resp.Header().Get("Access-Control-Allow-Origin"))
+}
+
+// TestCORSDefaultHeaders checks that a preflight without configured
+// headers allows the headers for authentication and ETags.
+func TestCORSDefaultHeaders(t *testing.T) {
+	handler := AllowCORS(NewRouter(memory.New()), CORS{
+		AllowedOrigins: []string{"*"},
+	})
+
+	req := httptest.NewRequest(http.MethodOptions, "/namespace/-", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Equal(t, "Accept, Authorization, Content-Type, If-None-Match, X-Request-ID",
+		resp.Header().Get("Access-Control-Allow-Headers"))
+}
+
+// TestCORSWildcard checks that "*" allows any origin.
+func TestCORSWildcard(t *testing.T) {
+	handler := AllowCORS(NewRouter(memory.New()), CORS{
+		AllowedOrigins: []string{"*"},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/namespace/-", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+
+	assert.Equal(t, "*", resp.Header().Get("Access-Control-Allow-Origin"))
+}
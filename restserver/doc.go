@@ -14,8 +14,17 @@
 // the resource.  Clients should use the standard HTTP Accept: header to
 // request a different format.  See "MIME Types" below.
 //
-// This interface does not (currently) support HTTP caching or
-// authentication headers.
+// Successful GET responses carry a weak ETag header derived from the
+// response body.  A client that sends it back in an If-None-Match
+// header gets a 304 Not Modified response with no body if the
+// resource is unchanged.  Resources that change constantly, such as
+// attempts and lists of attempts, do not send ETags.  No other HTTP
+// caching headers are sent.
+//
+// This interface does not require authentication on its own, but
+// wrapping the router with RequireAuth makes every request carry
+// credentials that an Authenticator such as BearerToken or BasicAuth
+// accepts.  Rejected requests get a 401 Unauthorized error response.
 //
 // Code will generally follow conventions for the Github API as an
 // established example; see https://developer.github.com/v3/ for
@@ -35,6 +44,14 @@
 //
 // JSON representation of latest version of this interface.
 //
+//     text/event-stream
+//
+// Server-Sent Events, only for the work spec events URL.  Each
+// event is named "available" and carries a JSON object naming the
+// work spec.  If the backend cannot watch for work, or the HTTP
+// server cannot stream responses, this URL returns 501 Not
+// Implemented and clients should poll instead.
+//
 // URL Scheme
 //
 // In most cases, Coordinate objects follow their natural hierarchy
@@ -60,15 +77,17 @@
 //     /namespace/{namespace}/work_spec/{spec}/change
 //     /namespace/{namespace}/work_spec/{spec}/adjust
 //     /namespace/{namespace}/work_spec/{spec}/meta
+//     /namespace/{namespace}/work_spec/{spec}/events
 //     /namespace/{namespace}/work_spec/{spec}/work_unit
 //     /namespace/{namespace}/work_spec/{spec}/work_unit/{unit}
 //       .../attempts
-//       .../attempt/{worker}/{start_time}
-//       .../attempt/{worker}/{start_time}/renew
-//       .../attempt/{worker}/{start_time}/expire
-//       .../attempt/{worker}/{start_time}/finish
-//       .../attempt/{worker}/{start_time}/fail
-//       .../attempt/{worker}/{start_time}/retry
+//       .../attempt/{attempt}
+//       .../attempt/{attempt}/renew
+//       .../attempt/{attempt}/expire
+//       .../attempt/{attempt}/release
+//       .../attempt/{attempt}/finish
+//       .../attempt/{attempt}/fail
+//       .../attempt/{attempt}/retry
 //     /namespace/{namespace}/worker
 //     /namespace/{namespace}/worker/{worker}
 //     /namespace/{namespace}/worker/{worker}/request_attempts
@@ -76,4 +95,8 @@
 //     /namespace/{namespace}/worker/{worker}/active_attempts
 //     /namespace/{namespace}/worker/{worker}/all_attempts
 //     /namespace/{namespace}/worker/{worker}/child_attempts
+//     /namespace/{namespace}/worker/{worker}/finish_attempts
+//     /namespace/{namespace}/worker/{worker}/fail_attempts
+//     /namespace/{namespace}/worker/{worker}/retry_attempts
+//     /namespace/{namespace}/worker/{worker}/complete_attempts
 package restserver
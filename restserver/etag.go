@@ -0,0 +1,43 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package restserver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// makeETag computes a weak entity tag for a response body.  The tag
+// depends only on the bytes sent and their media type, so two
+// requests get the same tag exactly when they would get the same
+// response.  It is weak because the body is only compared after
+// encoding, not byte-for-byte against any stored state.
+func makeETag(responseType string, content []byte) string {
+	hash := sha256.New()
+	_, _ = hash.Write([]byte(responseType))
+	_, _ = hash.Write([]byte{0})
+	_, _ = hash.Write(content)
+	return `W/"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
+
+// etagMatches determines whether an If-None-Match header value
+// matches etag, using the weak comparison of RFC 7232 section 2.3.2.
+// The header may be "*" or a comma-separated list of entity tags.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
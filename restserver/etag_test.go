@@ -0,0 +1,122 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package restserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/diffeo/go-coordinate/coordinate"
+	"github.com/diffeo/go-coordinate/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+// etagRequest sends a request for path through handler, with an
+// If-None-Match header if ifNoneMatch is non-empty.
+func etagRequest(handler http.Handler, method, path, ifNoneMatch string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	return resp
+}
+
+// TestETag checks that GET responses carry an ETag that changes with
+// the resource, and that sending it back produces 304 Not Modified.
+func TestETag(t *testing.T) {
+	backend := memory.New()
+	namespace, err := backend.Namespace("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	spec, err := namespace.SetWorkSpec(map[string]interface{}{"name": "spec"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	router := NewRouter(backend)
+	path := "/namespace/-/work_spec/spec"
+
+	resp := etagRequest(router, http.MethodGet, path, "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	etag := resp.Header().Get("ETag")
+	if !assert.NotEmpty(t, etag) {
+		return
+	}
+	assert.Regexp(t, `^W/"[0-9a-f]+"$`, etag)
+	assert.Equal(t, "Accept", resp.Header().Get("Vary"))
+	body := resp.Body.String()
+
+	// The same resource gets the same tag
+	resp = etagRequest(router, http.MethodGet, path, "")
+	assert.Equal(t, etag, resp.Header().Get("ETag"))
+	assert.Equal(t, body, resp.Body.String())
+
+	// Sending it back gets nothing new
+	for _, ifNoneMatch := range []string{etag, `W/"other", ` + etag, "*"} {
+		resp = etagRequest(router, http.MethodGet, path, ifNoneMatch)
+		assert.Equal(t, http.StatusNotModified, resp.Code, ifNoneMatch)
+		assert.Equal(t, etag, resp.Header().Get("ETag"), ifNoneMatch)
+		assert.Equal(t, "Accept", resp.Header().Get("Vary"), ifNoneMatch)
+		assert.Empty(t, resp.Body.String(), ifNoneMatch)
+	}
+	resp = etagRequest(router, http.MethodHead, path, etag)
+	assert.Equal(t, http.StatusNotModified, resp.Code)
+
+	// A different tag gets the full body
+	resp = etagRequest(router, http.MethodGet, path, `W/"other"`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, body, resp.Body.String())
+
+	// Changing the resource changes the tag
+	err = spec.SetData(map[string]interface{}{"name": "spec", "changed": true})
+	if !assert.NoError(t, err) {
+		return
+	}
+	resp = etagRequest(router, http.MethodGet, path, etag)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.NotEqual(t, etag, resp.Header().Get("ETag"))
+	assert.NotEqual(t, body, resp.Body.String())
+}
+
+// TestETagNoCache checks that resources that opt out of caching do
+// not send ETags or honor If-None-Match.
+func TestETagNoCache(t *testing.T) {
+	backend := memory.New()
+	namespace, err := backend.Namespace("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	spec, err := namespace.SetWorkSpec(map[string]interface{}{"name": "spec"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = spec.AddWorkUnit("unit", map[string]interface{}{}, coordinate.WorkUnitMeta{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	router := NewRouter(backend)
+
+	resp := etagRequest(router, http.MethodGet, "/namespace/-/work_spec/spec/work_unit/unit/attempts", "*")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Empty(t, resp.Header().Get("ETag"))
+}
+
+func TestETagMatches(t *testing.T) {
+	etag := `W/"abc"`
+	for ifNoneMatch, expected := range map[string]bool{
+		"":                  false,
+		"*":                 true,
+		`W/"abc"`:           true,
+		`"abc"`:             true,
+		`W/"abd"`:           false,
+		`"x", W/"abc"`:      true,
+		`"x",W/"y" , "abc"`: true,
+		`"x", "y"`:          false,
+	} {
+		assert.Equal(t, expected, etagMatches(ifNoneMatch, etag), ifNoneMatch)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package restserver
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/diffeo/go-coordinate/coordinate"
+	"github.com/diffeo/go-coordinate/restdata"
+)
+
+// eventKeepalive is how often an idle event stream sends a comment
+// line, so that proxies and clients do not give up on it.
+var eventKeepalive = 30 * time.Second
+
+// writeEventError sends err as an ordinary JSON error response.  If
+// err does not carry its own HTTP status, status is used.
+func writeEventError(resp http.ResponseWriter, status int, err error) {
+	if errS, hasStatus := err.(restdata.ErrorStatus); hasStatus {
+		status = errS.HTTPStatus()
+	}
+	out := restdata.ErrorResponse{Error: "error", Message: err.Error()}
+	out.FromError(err)
+	writeAResponse(resp, status, restdata.V1JSONMediaType, out, toJSON)
+}
+
+// WorkSpecEvents streams Server-Sent Events announcing new work in
+// the current work spec, until the client goes away.  This does not
+// go through resourceHandler, since the response is a stream rather
+// than a single object.
+func (api *restAPI) WorkSpecEvents(resp http.ResponseWriter, req *http.Request) {
+	recordEndpoint(resp, req)
+	if req.Method != "GET" {
+		writeEventError(resp, http.StatusMethodNotAllowed, errMethodNotAllowed{Method: req.Method})
+		return
+	}
+	ctx, err := api.Context(req)
+	if err != nil {
+		writeEventError(resp, http.StatusBadRequest, err)
+		return
+	}
+
+	// Both the backend and the HTTP server need to cooperate
+	watcher, canWatch := ctx.WorkSpec.(coordinate.WorkWatcher)
+	flusher, canFlush := resp.(http.Flusher)
+	if !canWatch || !canFlush {
+		writeEventError(resp, http.StatusNotImplemented, coordinate.ErrWatchNotSupported)
+		return
+	}
+	events, err := watcher.WatchWork(req.Context())
+	if err == coordinate.ErrWatchNotSupported {
+		writeEventError(resp, http.StatusNotImplemented, err)
+		return
+	}
+	if err != nil {
+		writeEventError(resp, http.StatusInternalServerError, err)
+		return
+	}
+	data, err := toJSON(restdata.WorkAvailable{WorkSpec: ctx.WorkSpec.Name()})
+	if err != nil {
+		writeEventError(resp, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp.Header().Set("Content-Type", restdata.EventStreamMediaType)
+	resp.Header().Set("Cache-Control", "no-cache")
+	resp.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(eventKeepalive)
+	defer keepalive.Stop()
+	for {
+		// Write errors mean the client has gone away, which
+		// will also close events
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+			_, _ = fmt.Fprintf(resp, "event: %s\ndata: %s\n\n", restdata.WorkAvailableEvent, data)
+		case <-keepalive.C:
+			_, _ = io.WriteString(resp, ": keepalive\n\n")
+		}
+		flusher.Flush()
+	}
+}
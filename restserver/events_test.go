@@ -0,0 +1,95 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package restserver
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/diffeo/go-coordinate/coordinate"
+	"github.com/diffeo/go-coordinate/memory"
+	"github.com/diffeo/go-coordinate/restdata"
+	"github.com/stretchr/testify/assert"
+)
+
+// noFlushWriter hides the http.Flusher of a ResponseWriter.
+type noFlushWriter struct {
+	http.ResponseWriter
+}
+
+// TestWorkSpecEvents checks the event stream for a work spec.
+func TestWorkSpecEvents(t *testing.T) {
+	backend := memory.New()
+	namespace, err := backend.Namespace("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	spec, err := namespace.SetWorkSpec(map[string]interface{}{"name": "spec"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	server := httptest.NewServer(NewRouter(backend))
+	defer server.Close()
+	url := server.URL + "/namespace/-/work_spec/spec/events"
+
+	resp, err := http.Get(url)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, restdata.EventStreamMediaType, resp.Header.Get("Content-Type"))
+
+	_, err = spec.AddWorkUnit("unit", map[string]interface{}{}, coordinate.WorkUnitMeta{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	reader := bufio.NewReader(resp.Body)
+	for _, expected := range []string{
+		"event: available\n",
+		"data: {\"work_spec\":\"spec\"}\n",
+		"\n",
+	} {
+		line, err := reader.ReadString('\n')
+		if assert.NoError(t, err) {
+			assert.Equal(t, expected, line)
+		}
+	}
+}
+
+// TestWorkSpecEventsErrors checks the cases where there is no event
+// stream.
+func TestWorkSpecEventsErrors(t *testing.T) {
+	backend := memory.New()
+	namespace, err := backend.Namespace("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = namespace.SetWorkSpec(map[string]interface{}{"name": "spec"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	router := NewRouter(backend)
+	path := "/namespace/-/work_spec/spec/events"
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, path, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/namespace/-/work_spec/missing/events", nil))
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	// A server that cannot stream responses cannot send events
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(noFlushWriter{resp}, httptest.NewRequest(http.MethodGet, path, nil))
+	assert.Equal(t, http.StatusNotImplemented, resp.Code)
+	var errResp restdata.ErrorResponse
+	err = restdata.Decode(resp.Header().Get("Content-Type"), resp.Body, &errResp)
+	if assert.NoError(t, err) {
+		assert.Equal(t, coordinate.ErrWatchNotSupported, errResp.ToError())
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package restserver
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/diffeo/go-coordinate/restdata"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// statusRecorder is an http.ResponseWriter that remembers the status
+// code that was sent, and what the handlers reported about the
+// request through recordEndpoint() and recordError().
+type statusRecorder struct {
+	http.ResponseWriter
+	Status int
+
+	// Endpoint is the name of the route that handled the request,
+	// if it was routed at all.
+	Endpoint string
+
+	// Error is the restdata.ErrorResponse error code sent back,
+	// if any, such as "ErrNoSuchWorkSpec" or "panic".
+	Error string
+}
+
+func (rw *statusRecorder) WriteHeader(code int) {
+	rw.Status = code
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+// requestRecorder is implemented by statusRecorder, and lets handlers
+// running inside LogRequests report on the request.
+type requestRecorder interface {
+	setEndpoint(name string)
+	setError(code string)
+}
+
+func (rw *statusRecorder) setEndpoint(name string) {
+	rw.Endpoint = name
+}
+
+func (rw *statusRecorder) setError(code string) {
+	rw.Error = code
+}
+
+// recordEndpoint notes the name of the route handling req, if resp
+// is being recorded.
+func recordEndpoint(resp http.ResponseWriter, req *http.Request) {
+	if rec, ok := resp.(requestRecorder); ok {
+		if route := mux.CurrentRoute(req); route != nil {
+			rec.setEndpoint(route.GetName())
+		}
+	}
+}
+
+// recordError notes the error code of an error response, if resp is
+// being recorded.
+func recordError(resp http.ResponseWriter, code string) {
+	if rec, ok := resp.(requestRecorder); ok {
+		rec.setError(code)
+	}
+}
+
+// flushingStatusRecorder is a statusRecorder that also passes
+// through http.Flusher, for streaming responses.  It is only used if
+// the underlying writer can flush, so handlers can still tell when
+// it cannot.
+type flushingStatusRecorder struct {
+	*statusRecorder
+}
+
+func (rw flushingStatusRecorder) Flush() {
+	rw.ResponseWriter.(http.Flusher).Flush()
+}
+
+// LogRequests wraps an HTTP handler so that every request carries a
+// tracing identifier.  The identifier is taken from the incoming
+// X-Request-ID header, or generated if there is none; it is passed
+// on to the inner handler in the same header and returned to the
+// client in the response.  Every request is logged at debug level to
+// logger with a "request_id" field.  If slow is positive, requests
+// that take at least that long are additionally logged as warnings.
+//
+// Failed requests are also counted in the
+// coordinate_http_request_errors_total metric, labeled with the
+// route name and the error code from the response, such as
+// "ErrNoSuchWorkSpec" or "panic".  Errors that do not come from the
+// Coordinate API, like unknown URLs, are labeled with their HTTP
+// status instead.
+func LogRequests(inner http.Handler, logger *logrus.Logger, slow time.Duration) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		requestID := req.Header.Get(restdata.RequestIDHeader)
+		if requestID == "" {
+			requestID = uuid.NewV4().String()
+			req.Header.Set(restdata.RequestIDHeader, requestID)
+		}
+		resp.Header().Set(restdata.RequestIDHeader, requestID)
+
+		rec := &statusRecorder{ResponseWriter: resp, Status: http.StatusOK}
+		var out http.ResponseWriter = rec
+		if _, canFlush := resp.(http.Flusher); canFlush {
+			out = flushingStatusRecorder{rec}
+		}
+		start := time.Now()
+		inner.ServeHTTP(out, req)
+		elapsed := time.Since(start)
+
+		errorCode := rec.Error
+		if errorCode == "" && rec.Status >= http.StatusBadRequest {
+			errorCode = "http_" + strconv.Itoa(rec.Status)
+		}
+		if errorCode != "" {
+			endpoint := rec.Endpoint
+			if endpoint == "" {
+				endpoint = "unknown"
+			}
+			requestErrors.WithLabelValues(endpoint, errorCode).Inc()
+		}
+
+		entry := logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"method":     req.Method,
+			"path":       req.URL.Path,
+			"status":     rec.Status,
+			"duration":   elapsed,
+		})
+		entry.Debug("Request")
+		if slow > 0 && elapsed >= slow {
+			entry.Warn("Slow request")
+		}
+	})
+}
@@ -0,0 +1,144 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package restserver
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/diffeo/go-coordinate/coordinate"
+	"github.com/diffeo/go-coordinate/memory"
+	"github.com/diffeo/go-coordinate/restdata"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRequestIDPropagated checks that a client-supplied request ID is
+// echoed back and appears on both the request log line and the slow
+// request log line.
+func TestRequestIDPropagated(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+	// Any request at all is "slow" with a 1ns threshold.
+	handler := LogRequests(NewRouter(memory.New()), logger, time.Nanosecond)
+
+	req := httptest.NewRequest(http.MethodGet, "/namespace/-", nil)
+	req.Header.Set(restdata.RequestIDHeader, "trace-me")
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "trace-me", resp.Header().Get(restdata.RequestIDHeader))
+
+	messages := make(map[string]*logrus.Entry)
+	for _, entry := range hook.AllEntries() {
+		messages[entry.Message] = entry
+	}
+	if assert.Contains(t, messages, "Request") {
+		assert.Equal(t, "trace-me", messages["Request"].Data["request_id"])
+		assert.Equal(t, http.StatusOK, messages["Request"].Data["status"])
+	}
+	if assert.Contains(t, messages, "Slow request") {
+		assert.Equal(t, logrus.WarnLevel, messages["Slow request"].Level)
+		assert.Equal(t, "trace-me", messages["Slow request"].Data["request_id"])
+	}
+}
+
+// TestRequestIDGenerated checks that a request without an ID gets one,
+// and that the same ID is logged and returned.
+func TestRequestIDGenerated(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+	handler := LogRequests(NewRouter(memory.New()), logger, 0)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+
+	requestID := resp.Header().Get(restdata.RequestIDHeader)
+	assert.NotEmpty(t, requestID)
+	if assert.Len(t, hook.AllEntries(), 1) {
+		assert.Equal(t, requestID, hook.LastEntry().Data["request_id"])
+	}
+}
+
+// TestRequestLoggingStreams checks that the work spec event stream
+// still delivers events through LogRequests.
+func TestRequestLoggingStreams(t *testing.T) {
+	backend := memory.New()
+	namespace, err := backend.Namespace("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	spec, err := namespace.SetWorkSpec(map[string]interface{}{"name": "spec"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	logger, _ := test.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+	server := httptest.NewServer(LogRequests(NewRouter(backend), logger, 0))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/namespace/-/work_spec/spec/events")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, restdata.EventStreamMediaType, resp.Header.Get("Content-Type"))
+	assert.NotEmpty(t, resp.Header.Get(restdata.RequestIDHeader))
+
+	_, err = spec.AddWorkUnit("unit", map[string]interface{}{}, coordinate.WorkUnitMeta{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	reader := bufio.NewReader(resp.Body)
+	for _, expected := range []string{
+		"event: available\n",
+		"data: {\"work_spec\":\"spec\"}\n",
+		"\n",
+	} {
+		line, err := reader.ReadString('\n')
+		if assert.NoError(t, err) {
+			assert.Equal(t, expected, line)
+		}
+	}
+}
+
+// TestRequestErrorMetrics checks that failed requests are counted by
+// route and error type.
+func TestRequestErrorMetrics(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	handler := LogRequests(NewRouter(memory.New()), logger, 0)
+
+	counter := func(endpoint, errorType string) float64 {
+		return testutil.ToFloat64(requestErrors.WithLabelValues(endpoint, errorType))
+	}
+	specBefore := counter("workSpec", "ErrNoSuchWorkSpec")
+	unitBefore := counter("workUnit", "ErrNoSuchWorkSpec")
+	notFoundBefore := counter("unknown", "http_404")
+	okBefore := counter("namespace", "ErrNoSuchWorkSpec")
+
+	for _, path := range []string{
+		"/namespace/-/work_spec/missing",
+		"/namespace/-/work_spec/missing",
+		"/namespace/-/work_spec/missing/work_unit/unit",
+		"/no/such/path",
+		"/namespace/-",
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+	}
+
+	assert.Equal(t, specBefore+2, counter("workSpec", "ErrNoSuchWorkSpec"))
+	assert.Equal(t, unitBefore+1, counter("workUnit", "ErrNoSuchWorkSpec"))
+	assert.Equal(t, notFoundBefore+1, counter("unknown", "http_404"))
+	assert.Equal(t, okBefore, counter("namespace", "ErrNoSuchWorkSpec"))
+}
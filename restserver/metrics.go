@@ -0,0 +1,21 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package restserver
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var requestErrors = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "coordinate",
+		Subsystem: "http",
+		Name:      "request_errors_total",
+		Help:      "Number of HTTP requests that failed, by route and error type",
+	},
+	[]string{"endpoint", "error"})
+
+func init() {
+	prometheus.MustRegister(requestErrors)
+}
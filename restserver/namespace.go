@@ -4,6 +4,8 @@
 package restserver
 
 import (
+	"time"
+
 	"github.com/diffeo/go-coordinate/coordinate"
 	"github.com/diffeo/go-coordinate/restdata"
 	"github.com/gorilla/mux"
@@ -23,10 +25,24 @@ func (api *restAPI) fillNamespace(namespace coordinate.Namespace, result *restda
 			URL(&result.SummaryURL, "namespaceSummary").
 			URL(&result.WorkSpecsURL, "workSpecs").
 			Template(&result.WorkSpecURL, "workSpec", "spec").
+			URL(&result.AvailableRuntimesURL, "availableRuntimes").
 			URL(&result.WorkersURL, "workers").
 			Template(&result.WorkerURL, "worker", "worker").
+			URL(&result.DeactivateWorkersURL, "deactivateWorkers").
+			URL(&result.DeleteWorkersURL, "deleteWorkers").
+			URL(&result.ExpiringAttemptsURL, "expiringAttempts").
+			URL(&result.RebalanceAttemptsURL, "rebalanceAttempts").
+			URL(&result.PipelineHealthURL, "pipelineHealth").
+			URL(&result.DestroyWorkSpecsURL, "destroyWorkSpecs").
+			URL(&result.ReserveURL, "reserve").
+			URL(&result.ReleaseURL, "releaseReservation").
+			URL(&result.CompleteURL, "completeReservation").
 			Error
 	}
+	if err == nil {
+		result.WorkersURL += "{?parent}"
+		result.ExpiringAttemptsURL += "{?within}"
+	}
 	return err
 }
 
@@ -94,6 +110,144 @@ func (api *restAPI) NamespaceSummaryGet(ctx *context) (interface{}, error) {
 	return ctx.Namespace.Summarize()
 }
 
+// NamespaceAvailableRuntimesGet lists the runtimes that have
+// available work in a namespace.
+func (api *restAPI) NamespaceAvailableRuntimesGet(ctx *context) (interface{}, error) {
+	runtimes, err := ctx.Namespace.AvailableRuntimes()
+	if err != nil {
+		return nil, err
+	}
+	return restdata.RuntimeList{Runtimes: runtimes}, nil
+}
+
+// NamespaceDeactivateWorkers deactivates the workers in a namespace
+// that match a posted query.
+func (api *restAPI) NamespaceDeactivateWorkers(ctx *context, in interface{}) (interface{}, error) {
+	req, valid := in.(restdata.WorkerDeactivate)
+	if !valid {
+		return nil, errUnmarshal
+	}
+	count, err := ctx.Namespace.DeactivateWorkers(coordinate.WorkerQuery{
+		NamePrefix: req.NamePrefix,
+		Expired:    req.Expired,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return restdata.WorkersDeactivated{Deactivated: count}, nil
+}
+
+// NamespaceDeleteWorkers deletes the workers in a namespace that
+// match a posted query.
+func (api *restAPI) NamespaceDeleteWorkers(ctx *context, in interface{}) (interface{}, error) {
+	req, valid := in.(restdata.WorkerDelete)
+	if !valid {
+		return nil, errUnmarshal
+	}
+	count, err := ctx.Namespace.DeleteWorkers(coordinate.WorkerQuery{
+		NamePrefix: req.NamePrefix,
+		Expired:    req.Expired,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return restdata.WorkersDeleted{Deleted: count}, nil
+}
+
+// NamespaceDestroyWorkSpecs destroys the work specs in a namespace
+// named in a posted list.
+func (api *restAPI) NamespaceDestroyWorkSpecs(ctx *context, in interface{}) (interface{}, error) {
+	req, valid := in.(restdata.WorkSpecDestroy)
+	if !valid {
+		return nil, errUnmarshal
+	}
+	count, err := ctx.Namespace.DestroyWorkSpecs(req.Names)
+	if err != nil {
+		return nil, err
+	}
+	return restdata.WorkSpecsDestroyed{Destroyed: count}, nil
+}
+
+// NamespaceExpiringAttempts lists the pending attempts in a
+// namespace that will expire within a duration.
+func (api *restAPI) NamespaceExpiringAttempts(ctx *context) (interface{}, error) {
+	within, err := ctx.DurationParam("within")
+	if err != nil {
+		return nil, err
+	}
+	attempts, err := ctx.Namespace.ExpiringAttempts(within)
+	if err != nil {
+		return nil, err
+	}
+	return api.returnAttempts(ctx, attempts)
+}
+
+// NamespaceRebalanceAttempts releases excess pending attempts from
+// the workers in a namespace.
+func (api *restAPI) NamespaceRebalanceAttempts(ctx *context, in interface{}) (interface{}, error) {
+	req, valid := in.(restdata.AttemptRebalance)
+	if !valid {
+		return nil, errUnmarshal
+	}
+	count, err := ctx.Namespace.RebalanceAttempts(req.MaxPending)
+	if err != nil {
+		return nil, err
+	}
+	return restdata.AttemptsRebalanced{Released: count}, nil
+}
+
+// NamespacePipelineHealthGet reports stuck pipelines in a namespace.
+func (api *restAPI) NamespacePipelineHealthGet(ctx *context) (interface{}, error) {
+	issues, err := ctx.Namespace.PipelineHealth()
+	if err != nil {
+		return nil, err
+	}
+	return restdata.PipelineHealth{Issues: issues}, nil
+}
+
+// NamespaceReserve reserves a single work unit in a namespace.
+func (api *restAPI) NamespaceReserve(ctx *context, in interface{}) (interface{}, error) {
+	req, valid := in.(restdata.ReservationRequest)
+	if !valid {
+		return nil, errUnmarshal
+	}
+	res, err := coordinate.Reserve(ctx.Namespace, req.WorkSpecs, time.Duration(req.TTL))
+	if err != nil || res == nil {
+		// Return an untyped nil for no content
+		return nil, err
+	}
+	return *res, nil
+}
+
+// NamespaceReleaseReservation gives up a reservation in a namespace.
+func (api *restAPI) NamespaceReleaseReservation(ctx *context, in interface{}) (interface{}, error) {
+	req, valid := in.(restdata.ReservationToken)
+	if !valid {
+		return nil, errUnmarshal
+	}
+	return nil, reservationError(coordinate.ReleaseReservation(ctx.Namespace, req.Token))
+}
+
+// NamespaceCompleteReservation finishes the work unit held by a
+// reservation in a namespace.
+func (api *restAPI) NamespaceCompleteReservation(ctx *context, in interface{}) (interface{}, error) {
+	req, valid := in.(restdata.ReservationToken)
+	if !valid {
+		return nil, errUnmarshal
+	}
+	err := coordinate.CompleteReservation(ctx.Namespace, req.Token, req.Data)
+	return nil, reservationError(err)
+}
+
+// reservationError makes an unknown reservation a 404 Not Found
+// error.
+func reservationError(err error) error {
+	if err == coordinate.ErrNoSuchReservation {
+		return restdata.ErrNotFound{Err: err}
+	}
+	return err
+}
+
 // PopulateNamespace adds namespace-specific routes to a router.
 // r should be rooted at the root of the Coordinate URL tree, e.g. "/".
 func (api *restAPI) PopulateNamespace(r *mux.Router) {
@@ -114,6 +268,58 @@ func (api *restAPI) PopulateNamespace(r *mux.Router) {
 		Context:        api.Context,
 		Get:            api.NamespaceSummaryGet,
 	})
+	r.Path("/namespace/{namespace}/available_runtimes").Name("availableRuntimes").Handler(&resourceHandler{
+		Representation: restdata.RuntimeList{},
+		Context:        api.Context,
+		Get:            api.NamespaceAvailableRuntimesGet,
+	})
+	r.Path("/namespace/{namespace}/deactivate_workers").Name("deactivateWorkers").Handler(&resourceHandler{
+		Representation: restdata.WorkerDeactivate{},
+		Context:        api.Context,
+		Post:           api.NamespaceDeactivateWorkers,
+	})
+	r.Path("/namespace/{namespace}/delete_workers").Name("deleteWorkers").Handler(&resourceHandler{
+		Representation: restdata.WorkerDelete{},
+		Context:        api.Context,
+		Post:           api.NamespaceDeleteWorkers,
+	})
+	r.Path("/namespace/{namespace}/destroy_work_specs").Name("destroyWorkSpecs").Handler(&resourceHandler{
+		Representation: restdata.WorkSpecDestroy{},
+		Context:        api.Context,
+		Post:           api.NamespaceDestroyWorkSpecs,
+	})
+	r.Path("/namespace/{namespace}/expiring_attempts").Name("expiringAttempts").Handler(&resourceHandler{
+		Representation: restdata.AttemptList{},
+		Context:        api.Context,
+		Get:            api.NamespaceExpiringAttempts,
+		NoCache:        true,
+	})
+	r.Path("/namespace/{namespace}/rebalance_attempts").Name("rebalanceAttempts").Handler(&resourceHandler{
+		Representation: restdata.AttemptRebalance{},
+		Context:        api.Context,
+		Post:           api.NamespaceRebalanceAttempts,
+	})
+	r.Path("/namespace/{namespace}/pipeline_health").Name("pipelineHealth").Handler(&resourceHandler{
+		Representation: restdata.PipelineHealth{},
+		Context:        api.Context,
+		Get:            api.NamespacePipelineHealthGet,
+		NoCache:        true,
+	})
+	r.Path("/namespace/{namespace}/reserve").Name("reserve").Handler(&resourceHandler{
+		Representation: restdata.ReservationRequest{},
+		Context:        api.Context,
+		Post:           api.NamespaceReserve,
+	})
+	r.Path("/namespace/{namespace}/release").Name("releaseReservation").Handler(&resourceHandler{
+		Representation: restdata.ReservationToken{},
+		Context:        api.Context,
+		Post:           api.NamespaceReleaseReservation,
+	})
+	r.Path("/namespace/{namespace}/complete").Name("completeReservation").Handler(&resourceHandler{
+		Representation: restdata.ReservationToken{},
+		Context:        api.Context,
+		Post:           api.NamespaceCompleteReservation,
+	})
 	sr := r.PathPrefix("/namespace/{namespace}").Subrouter()
 	api.PopulateWorkSpec(sr)
 	api.PopulateWorker(sr)
@@ -126,6 +126,12 @@ type resourceHandler struct {
 	// Delete, if non-nil, deletes the object.  The return can be
 	// any useful return value.
 	Delete func(*context) (interface{}, error)
+
+	// NoCache, if true, suppresses the ETag header on successful
+	// GET responses, and ignores If-None-Match.  This is intended
+	// for resources that change so often that clients would
+	// never usefully revalidate them, such as attempt status.
+	NoCache bool
 }
 
 func toJSON(out interface{}) ([]byte, error) {
@@ -149,6 +155,9 @@ func writeAResponse(resp http.ResponseWriter, status int, responseType string, o
 		out = restdata.ErrorResponse{Error: "error", Message: err.Error()}
 		content, err = converter(out)
 	}
+	if errResp, isError := out.(restdata.ErrorResponse); isError {
+		recordError(resp, errResp.Error)
+	}
 	if err != nil {
 		// joy
 		status = http.StatusInternalServerError
@@ -157,7 +166,10 @@ func writeAResponse(resp http.ResponseWriter, status int, responseType string, o
 	}
 
 	// It should not be possible to panic beyond this point.
+	// The response type was negotiated from the Accept: header,
+	// so caches need to key on it too.
 	resp.Header().Set("Content-Type", responseType)
+	resp.Header().Add("Vary", "Accept")
 	resp.WriteHeader(status)
 	if content != nil {
 		// Any error at this point will be a network error and
@@ -175,6 +187,8 @@ func (h *resourceHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request)
 		responseType string
 	)
 
+	recordEndpoint(resp, req)
+
 	// Recover from panics by sending an HTTP error.
 	defer func() {
 		if recovered := recover(); recovered != nil {
@@ -264,9 +278,6 @@ func (h *resourceHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request)
 		}
 	} else {
 		status = http.StatusOK
-		if req.Method == "HEAD" {
-			out = nil
-		}
 	}
 
 	// Come up with a function to write the response.  If setting
@@ -286,6 +297,32 @@ func (h *resourceHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request)
 		out = restdata.ErrorResponse{Error: "error", Message: "Invalid response type " + responseType}
 	}
 
+	// Successful GET responses carry an ETag computed from the
+	// body, and if the client already has that body, it gets
+	// 304 Not Modified instead.  The body needs to be encoded to
+	// do this; reuse that encoding when writing it out.
+	cacheable := (req.Method == "GET" || req.Method == "HEAD") && !h.NoCache
+	if status == http.StatusOK && cacheable {
+		content, err := responseWriter(out)
+		if err == nil {
+			etag := makeETag(responseType, content)
+			resp.Header().Set("ETag", etag)
+			if etagMatches(req.Header.Get("If-None-Match"), etag) {
+				status = http.StatusNotModified
+				out = nil
+			} else {
+				responseWriter = func(interface{}) ([]byte, error) {
+					return content, nil
+				}
+			}
+		}
+		// If encoding failed, writeAResponse will fail the
+		// same way and report it
+	}
+	if status == http.StatusOK && req.Method == "HEAD" {
+		out = nil
+	}
+
 	writeAResponse(resp, status, responseType, out, responseWriter)
 }
 
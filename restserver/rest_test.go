@@ -10,12 +10,18 @@
 package restserver
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"github.com/diffeo/go-coordinate/coordinate"
 	"github.com/diffeo/go-coordinate/memory"
+	"github.com/diffeo/go-coordinate/restdata"
 	"github.com/stretchr/testify/assert"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 )
 
 type failResponseWriter struct {
@@ -70,3 +76,435 @@ func TestDoubleFault(t *testing.T) {
 	router.ServeHTTP(resp, req)
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 }
+
+// TestWorkUnitListDetails checks that the work unit list includes
+// status and priority only when asked.
+func TestWorkUnitListDetails(t *testing.T) {
+	backend := memory.New()
+	namespace, err := backend.Namespace("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	spec, err := namespace.SetWorkSpec(map[string]interface{}{
+		"name": "spec",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = spec.AddWorkUnit("a", map[string]interface{}{}, coordinate.WorkUnitMeta{Priority: 10})
+	if !assert.NoError(t, err) {
+		return
+	}
+	unit, err := spec.AddWorkUnit("b", map[string]interface{}{}, coordinate.WorkUnitMeta{Priority: -1})
+	if !assert.NoError(t, err) {
+		return
+	}
+	worker, err := namespace.Worker("worker")
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = worker.MakeAttempt(unit, 1*time.Hour)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	router := NewRouter(backend)
+	get := func(query string) map[string]restdata.WorkUnitShort {
+		req := httptest.NewRequest(http.MethodGet, "/namespace/-/work_spec/spec/work_unit"+query, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		var list restdata.WorkUnitList
+		err := json.Unmarshal(resp.Body.Bytes(), &list)
+		assert.NoError(t, err)
+		result := make(map[string]restdata.WorkUnitShort)
+		for _, short := range list.WorkUnits {
+			result[short.Name] = short
+		}
+		return result
+	}
+
+	units := get("")
+	if assert.Len(t, units, 2) {
+		assert.Nil(t, units["a"].Status)
+		assert.Nil(t, units["a"].Priority)
+	}
+
+	units = get("?details=true")
+	if assert.Len(t, units, 2) {
+		if assert.NotNil(t, units["a"].Status) && assert.NotNil(t, units["a"].Priority) {
+			assert.Equal(t, coordinate.AvailableUnit, *units["a"].Status)
+			assert.Equal(t, 10.0, *units["a"].Priority)
+		}
+		if assert.NotNil(t, units["b"].Status) && assert.NotNil(t, units["b"].Priority) {
+			assert.Equal(t, coordinate.PendingUnit, *units["b"].Status)
+			assert.Equal(t, -1.0, *units["b"].Priority)
+		}
+	}
+}
+
+// TestAddWorkUnitsBatch posts a batch with an invalid work unit and
+// checks the per-item results.
+func TestAddWorkUnitsBatch(t *testing.T) {
+	backend := memory.New()
+	namespace, err := backend.Namespace("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	spec, err := namespace.SetWorkSpec(map[string]interface{}{
+		"name": "spec",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	batch := restdata.WorkUnitBatch{
+		WorkUnits: make([]restdata.WorkUnit, 3),
+	}
+	batch.WorkUnits[0].Name = "a"
+	// batch.WorkUnits[1] has no name and is invalid
+	batch.WorkUnits[2].Name = "b"
+	batch.WorkUnits[2].Data = restdata.DataDict{"k": "v"}
+	batch.WorkUnits[2].Meta = &coordinate.WorkUnitMeta{Priority: 5}
+	body, err := json.Marshal(batch)
+	if !assert.NoError(t, err) {
+		return
+	}
+	router := NewRouter(backend)
+	req := httptest.NewRequest(http.MethodPost, "/namespace/-/work_spec/spec/add_work_units", bytes.NewReader(body))
+	req.Header.Set("Content-Type", restdata.V1JSONMediaType)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var results restdata.WorkUnitBatchResults
+	err = json.Unmarshal(resp.Body.Bytes(), &results)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []restdata.WorkUnitBatchResult{
+			{Name: "a"},
+			{Error: coordinate.ErrNoWorkUnitName.Error()},
+			{Name: "b"},
+		}, results.Results)
+	}
+
+	units, err := spec.WorkUnits(coordinate.WorkUnitQuery{})
+	if assert.NoError(t, err) {
+		assert.Len(t, units, 2)
+	}
+	unit, err := spec.WorkUnit("b")
+	if assert.NoError(t, err) {
+		priority, err := unit.Priority()
+		if assert.NoError(t, err) {
+			assert.Equal(t, 5.0, priority)
+		}
+	}
+}
+
+// TestWorkUnitListNext follows the "next" links of a paged work unit
+// list until they run out.
+func TestWorkUnitListNext(t *testing.T) {
+	backend := memory.New()
+	namespace, err := backend.Namespace("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	spec, err := namespace.SetWorkSpec(map[string]interface{}{
+		"name": "spec",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		_, err = spec.AddWorkUnit(name, map[string]interface{}{}, coordinate.WorkUnitMeta{})
+		if !assert.NoError(t, err) {
+			return
+		}
+	}
+
+	router := NewRouter(backend)
+	var names []string
+	next := "/namespace/-/work_spec/spec/work_unit?limit=2&details=true"
+	for pages := 0; next != "" && pages < 5; pages++ {
+		req := httptest.NewRequest(http.MethodGet, next, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		if !assert.Equal(t, http.StatusOK, resp.Code) {
+			return
+		}
+		var list restdata.WorkUnitList
+		err := json.Unmarshal(resp.Body.Bytes(), &list)
+		if !assert.NoError(t, err) {
+			return
+		}
+		for _, short := range list.WorkUnits {
+			names = append(names, short.Name)
+			// The next page repeats the rest of the query
+			assert.NotNil(t, short.Status)
+		}
+		next = list.Next
+	}
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, names)
+}
+
+// TestReservationEndpoints reserves a work unit over REST and
+// completes it, without any worker objects.
+func TestReservationEndpoints(t *testing.T) {
+	backend := memory.New()
+	namespace, err := backend.Namespace("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	spec, err := namespace.SetWorkSpec(map[string]interface{}{
+		"name": "spec",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = spec.AddWorkUnit("unit", map[string]interface{}{}, coordinate.WorkUnitMeta{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	router := NewRouter(backend)
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/namespace/-/"+path, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", restdata.V1JSONMediaType)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := post("reserve", `{"work_specs":["spec"],"ttl":"10m"}`)
+	if !assert.Equal(t, http.StatusOK, resp.Code) {
+		return
+	}
+	var res coordinate.Reservation
+	err = json.Unmarshal(resp.Body.Bytes(), &res)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "unit", res.WorkUnit)
+
+	// Nothing else to reserve
+	resp = post("reserve", `{}`)
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+
+	resp = post("release", `{"token":"garbage"}`)
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	token, err := json.Marshal(restdata.ReservationToken{Token: res.Token})
+	if !assert.NoError(t, err) {
+		return
+	}
+	resp = post("complete", string(token))
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	unit, err := spec.WorkUnit("unit")
+	if assert.NoError(t, err) {
+		status, err := unit.Status()
+		if assert.NoError(t, err) {
+			assert.Equal(t, coordinate.FinishedUnit, status)
+		}
+	}
+}
+
+// TestCompleteAttemptsLookup completes a batch naming an active
+// attempt, an attempt that has already finished, and one that does
+// not exist, and checks that each is found or reported.
+func TestCompleteAttemptsLookup(t *testing.T) {
+	backend := memory.New()
+	namespace, err := backend.Namespace("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	spec, err := namespace.SetWorkSpec(map[string]interface{}{
+		"name": "spec",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	worker, err := namespace.Worker("worker")
+	if !assert.NoError(t, err) {
+		return
+	}
+	attempts := make([]coordinate.Attempt, 2)
+	for i, name := range []string{"a", "b"} {
+		unit, err := spec.AddWorkUnit(name, map[string]interface{}{}, coordinate.WorkUnitMeta{})
+		if !assert.NoError(t, err) {
+			return
+		}
+		attempts[i], err = worker.MakeAttempt(unit, 0)
+		if !assert.NoError(t, err) {
+			return
+		}
+	}
+	err = attempts[1].Finish(nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var outcomes restdata.AttemptOutcomeList
+	for _, item := range []restdata.AttemptBatchItem{
+		{WorkSpec: "spec", WorkUnit: "a", ID: attempts[0].ID()},
+		{WorkSpec: "spec", WorkUnit: "b", ID: attempts[1].ID()},
+		{WorkSpec: "spec", WorkUnit: "a", ID: "nope"},
+	} {
+		outcomes.Attempts = append(outcomes.Attempts, restdata.AttemptOutcomeItem{
+			AttemptBatchItem: item,
+			Status:           coordinate.Finished,
+		})
+	}
+	body, err := json.Marshal(outcomes)
+	if !assert.NoError(t, err) {
+		return
+	}
+	router := NewRouter(backend)
+	req := httptest.NewRequest(http.MethodPost, "/namespace/-/worker/worker/complete_attempts", bytes.NewReader(body))
+	req.Header.Set("Content-Type", restdata.V1JSONMediaType)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var results restdata.AttemptOutcomeResults
+	err = json.Unmarshal(resp.Body.Bytes(), &results)
+	if assert.NoError(t, err) && assert.Len(t, results.Errors, 3) {
+		assert.Nil(t, results.Errors[0])
+		// The finished attempt is found, but cannot finish again
+		if assert.NotNil(t, results.Errors[1]) {
+			assert.Equal(t, "ErrNotPending", results.Errors[1].Error)
+		}
+		if assert.NotNil(t, results.Errors[2]) {
+			assert.Equal(t, "no such attempt", results.Errors[2].Message)
+		}
+	}
+
+	status, err := attempts[0].Status()
+	if assert.NoError(t, err) {
+		assert.Equal(t, coordinate.Finished, status)
+	}
+}
+
+// TestWorkerChildrenURL follows the children URL of a worker whose
+// name must be encoded, and checks that it lists only its child.
+func TestWorkerChildrenURL(t *testing.T) {
+	backend := memory.New()
+	namespace, err := backend.Namespace("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	parent, err := namespace.Worker("parent/one")
+	if !assert.NoError(t, err) {
+		return
+	}
+	child, err := namespace.Worker("child")
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = namespace.Worker("other")
+	if !assert.NoError(t, err) {
+		return
+	}
+	err = child.SetParent(parent)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	router := NewRouter(backend)
+	get := func(path string, out interface{}) bool {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return assert.Equal(t, http.StatusOK, resp.Code) &&
+			assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), out))
+	}
+
+	var worker restdata.Worker
+	path := "/namespace/-/worker/" + restdata.MaybeEncodeName(parent.Name())
+	if !get(path, &worker) {
+		return
+	}
+	var list restdata.WorkerList
+	if get(worker.ChildrenURL, &list) && assert.Len(t, list.Workers, 1) {
+		assert.Equal(t, "child", list.Workers[0].Name)
+	}
+}
+
+// TestWorkUnitPutIgnoresData puts back the full representation of a
+// pending work unit with a new priority, which must still work, and
+// changes the data of an available one through its data URL.
+func TestWorkUnitPutIgnoresData(t *testing.T) {
+	backend := memory.New()
+	namespace, err := backend.Namespace("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	spec, err := namespace.SetWorkSpec(map[string]interface{}{
+		"name": "spec",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	unit, err := spec.AddWorkUnit("a", map[string]interface{}{"value": 1}, coordinate.WorkUnitMeta{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	worker, err := namespace.Worker("worker")
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = worker.MakeAttempt(unit, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = spec.AddWorkUnit("b", map[string]interface{}{"value": 1}, coordinate.WorkUnitMeta{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	router := NewRouter(backend)
+	do := func(method, path string, in, out interface{}) bool {
+		var body []byte
+		if in != nil {
+			var err error
+			body, err = json.Marshal(in)
+			if !assert.NoError(t, err) {
+				return false
+			}
+		}
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", restdata.V1JSONMediaType)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		if !assert.True(t, resp.Code < 300, "%s %s: %d %s", method, path, resp.Code, resp.Body.String()) {
+			return false
+		}
+		return out == nil || assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), out))
+	}
+
+	var repr restdata.WorkUnit
+	if !do(http.MethodGet, "/namespace/-/work_spec/spec/work_unit/a", nil, &repr) {
+		return
+	}
+	repr.Meta.Priority = 5
+	repr.Data = restdata.DataDict{"value": 2}
+	if do(http.MethodPut, repr.URL, repr, nil) {
+		meta, err := unit.Meta()
+		if assert.NoError(t, err) {
+			assert.Equal(t, 5.0, meta.Priority)
+		}
+		data, err := unit.Data()
+		if assert.NoError(t, err) {
+			assert.EqualValues(t, 1, data["value"])
+		}
+	}
+
+	if !do(http.MethodGet, "/namespace/-/work_spec/spec/work_unit/b", nil, &repr) {
+		return
+	}
+	update := restdata.WorkUnitData{Data: restdata.DataDict{"value": 2}}
+	if do(http.MethodPut, repr.DataURL, update, nil) {
+		unit, err := spec.WorkUnit("b")
+		if assert.NoError(t, err) {
+			data, err := unit.Data()
+			if assert.NoError(t, err) {
+				assert.EqualValues(t, 2, data["value"])
+			}
+		}
+	}
+}
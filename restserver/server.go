@@ -54,6 +54,12 @@ func (api *restAPI) PopulateRouter(r *mux.Router) {
 		Context:        api.Context,
 		Get:            api.RootSummary,
 	})
+	r.Path("/server_time").Name("serverTime").Handler(&resourceHandler{
+		Representation: restdata.ServerTime{},
+		Context:        api.Context,
+		Get:            api.RootServerTime,
+		NoCache:        true,
+	})
 }
 
 func (api *restAPI) RootDocument(ctx *context) (interface{}, error) {
@@ -62,6 +68,7 @@ func (api *restAPI) RootDocument(ctx *context) (interface{}, error) {
 		URL(&resp.SummaryURL, "rootSummary").
 		URL(&resp.NamespacesURL, "namespaces").
 		Template(&resp.NamespaceURL, "namespace", "namespace").
+		URL(&resp.ServerTimeURL, "serverTime").
 		Error
 	return resp, err
 }
@@ -69,3 +76,11 @@ func (api *restAPI) RootDocument(ctx *context) (interface{}, error) {
 func (api *restAPI) RootSummary(ctx *context) (interface{}, error) {
 	return api.Coordinate.Summarize()
 }
+
+func (api *restAPI) RootServerTime(ctx *context) (interface{}, error) {
+	now, err := api.Coordinate.ServerTime()
+	if err != nil {
+		return nil, err
+	}
+	return restdata.ServerTime{Time: now}, nil
+}
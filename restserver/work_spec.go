@@ -8,6 +8,8 @@ import (
 	"github.com/diffeo/go-coordinate/coordinate"
 	"github.com/diffeo/go-coordinate/restdata"
 	"github.com/gorilla/mux"
+	"net/http"
+	"sort"
 )
 
 func (api *restAPI) fillWorkSpecShort(namespace coordinate.Namespace, name string, short *restdata.WorkSpecShort) error {
@@ -24,19 +26,32 @@ func (api *restAPI) fillWorkSpec(namespace coordinate.Namespace, name string, re
 		err = buildURLs(api.Router,
 			"namespace", namespace.Name(),
 			"spec", name).
+			URL(&repr.NamespaceURL, "namespace").
 			URL(&repr.SummaryURL, "workUnitSummary").
 			URL(&repr.WorkUnitsURL, "workUnits").
+			URL(&repr.AddWorkUnitsURL, "workSpecAddWorkUnits").
 			Template(&repr.WorkUnitURL, "workUnit", "unit").
 			URL(&repr.MetaURL, "workSpecMeta").
+			URL(&repr.SpecSummaryURL, "workSpecSummary").
 			URL(&repr.WorkUnitCountsURL, "workSpecCounts").
+			URL(&repr.WorkUnitStatusesURL, "workSpecStatuses").
+			URL(&repr.WorkUnitPrioritiesURL, "workSpecPriorities").
+			URL(&repr.DataSizeURL, "workSpecDataSize").
+			URL(&repr.PriorityHistogramURL, "workSpecPriorityHistogram").
 			URL(&repr.WorkUnitChangeURL, "workSpecChange").
 			URL(&repr.WorkUnitAdjustURL, "workSpecAdjust").
+			URL(&repr.WorkUnitReorderURL, "workSpecReorder").
+			URL(&repr.NextContinuousURL, "workSpecNextContinuous").
+			URL(&repr.PurgeAttemptsURL, "workSpecPurgeAttempts").
+			URL(&repr.SchedulableURL, "workSpecSchedulable").
+			URL(&repr.EventsURL, "workSpecEvents").
 			Error
 	}
 	if err == nil {
 		repr.MetaURL += "{?counts}"
-		qs := "{?name*,status*,previous,limit}"
-		repr.WorkUnitQueryURL = repr.WorkUnitsURL + qs
+		qs := "{?name*,status*,previous,never_attempted,worker,offset,limit}"
+		repr.WorkUnitQueryURL = repr.WorkUnitsURL +
+			"{?name*,status*,previous,never_attempted,worker,offset,limit,cursor,details,count_only}"
 		repr.WorkUnitChangeURL += qs
 		repr.WorkUnitAdjustURL += qs
 	}
@@ -69,7 +84,7 @@ func (api *restAPI) WorkSpecPost(ctx *context, in interface{}) (interface{}, err
 		return nil, restdata.ErrBadRequest{Err: errors.New("Missing data")}
 	}
 	spec, err := ctx.Namespace.SetWorkSpec(req.Data)
-	if err == coordinate.ErrNoWorkSpecName || err == coordinate.ErrBadWorkSpecName {
+	if err == coordinate.ErrNoWorkSpecName || err == coordinate.ErrBadWorkSpecName || err == coordinate.ErrBadWorkSpecOrder {
 		return nil, restdata.ErrBadRequest{Err: err}
 	} else if err != nil {
 		return nil, err
@@ -141,6 +156,80 @@ func (api *restAPI) WorkSpecCounts(ctx *context) (interface{}, error) {
 	return counts, err
 }
 
+// WorkSpecStatuses reports the statuses of a batch of work units in
+// the current work spec.
+func (api *restAPI) WorkSpecStatuses(ctx *context, in interface{}) (interface{}, error) {
+	repr, valid := in.(restdata.WorkUnitNames)
+	if !valid {
+		return nil, errUnmarshal
+	}
+	statuses, err := ctx.WorkSpec.WorkUnitStatuses(repr.Names)
+	if err != nil {
+		return nil, err
+	}
+	return restdata.WorkUnitStatuses{Statuses: statuses}, nil
+}
+
+// WorkSpecPriorities reports the priorities of a batch of work units
+// in the current work spec.
+func (api *restAPI) WorkSpecPriorities(ctx *context, in interface{}) (interface{}, error) {
+	repr, valid := in.(restdata.WorkUnitNames)
+	if !valid {
+		return nil, errUnmarshal
+	}
+	priorities, err := ctx.WorkSpec.WorkUnitPriorities(repr.Names)
+	if err != nil {
+		return nil, err
+	}
+	return restdata.WorkUnitPriorities{Priorities: priorities}, nil
+}
+
+// WorkSpecSpecSummary reports the current work spec's data, metadata,
+// and work unit counts together.
+func (api *restAPI) WorkSpecSpecSummary(ctx *context) (interface{}, error) {
+	summary, err := ctx.WorkSpec.Summary()
+	if err != nil {
+		return nil, err
+	}
+	return restdata.WorkSpecSummary{
+		Data:   summary.Data,
+		Meta:   summary.Meta,
+		Counts: summary.Counts,
+	}, nil
+}
+
+// WorkSpecDataSize reports the total size of the current work spec's
+// work unit data.
+func (api *restAPI) WorkSpecDataSize(ctx *context) (interface{}, error) {
+	size, err := ctx.WorkSpec.DataSize()
+	if err != nil {
+		return nil, err
+	}
+	return restdata.DataSize{Bytes: size}, nil
+}
+
+// WorkSpecPriorityHistogram reports the number of work units at each
+// priority in the current work spec, in increasing priority order.
+func (api *restAPI) WorkSpecPriorityHistogram(ctx *context) (interface{}, error) {
+	histogram, err := ctx.WorkSpec.PriorityHistogram()
+	if err != nil {
+		return nil, err
+	}
+	result := restdata.PriorityHistogram{
+		Priorities: make([]restdata.PriorityCount, 0, len(histogram)),
+	}
+	for priority, count := range histogram {
+		result.Priorities = append(result.Priorities, restdata.PriorityCount{
+			Priority: priority,
+			Count:    count,
+		})
+	}
+	sort.Slice(result.Priorities, func(i, j int) bool {
+		return result.Priorities[i].Priority < result.Priorities[j].Priority
+	})
+	return result, nil
+}
+
 func (api *restAPI) WorkSpecChange(ctx *context, in interface{}) (interface{}, error) {
 	var (
 		err   error
@@ -181,6 +270,93 @@ func (api *restAPI) WorkSpecAdjust(ctx *context, in interface{}) (interface{}, e
 	return nil, err
 }
 
+// WorkSpecReorder changes the priorities of the available and delayed
+// work units in the current work spec to match a posted order.
+func (api *restAPI) WorkSpecReorder(ctx *context, in interface{}) (interface{}, error) {
+	order, valid := in.(restdata.WorkUnitOrder)
+	if !valid {
+		return nil, errUnmarshal
+	}
+	err := ctx.WorkSpec.ReorderAvailable(order.Names)
+	return nil, err
+}
+
+// WorkSpecNextContinuousGet returns the time the current work spec
+// can next generate a continuous work unit.
+func (api *restAPI) WorkSpecNextContinuousGet(ctx *context) (interface{}, error) {
+	meta, err := ctx.WorkSpec.Meta(false)
+	if err != nil {
+		return nil, err
+	}
+	return restdata.NextContinuous{NextContinuous: meta.NextContinuous}, nil
+}
+
+// WorkSpecNextContinuousPost changes the time the current work spec
+// can next generate a continuous work unit.
+func (api *restAPI) WorkSpecNextContinuousPost(ctx *context, in interface{}) (interface{}, error) {
+	repr, valid := in.(restdata.NextContinuous)
+	if !valid {
+		return nil, errUnmarshal
+	}
+	err := ctx.WorkSpec.SetNextContinuous(repr.NextContinuous)
+	return nil, err
+}
+
+// WorkSpecAddWorkUnits adds a batch of work units to the current
+// work spec, reporting which ones succeeded.
+func (api *restAPI) WorkSpecAddWorkUnits(ctx *context, in interface{}) (interface{}, error) {
+	batch, valid := in.(restdata.WorkUnitBatch)
+	if !valid {
+		return nil, errUnmarshal
+	}
+	items := make([]coordinate.AddWorkUnitItem, len(batch.WorkUnits))
+	for i, repr := range batch.WorkUnits {
+		items[i].Key = repr.Name
+		items[i].Data = repr.Data
+		if repr.Meta != nil {
+			items[i].Meta = *repr.Meta
+		}
+	}
+	results, err := coordinate.AddWorkUnitItems(ctx.WorkSpec, items)
+	if err != nil {
+		return nil, err
+	}
+	resp := restdata.WorkUnitBatchResults{
+		Results: make([]restdata.WorkUnitBatchResult, len(items)),
+	}
+	for i, result := range results {
+		resp.Results[i].Name = result.Key
+		if result.Err != nil {
+			resp.Results[i].Error = result.Err.Error()
+		}
+	}
+	return resp, nil
+}
+
+// WorkSpecPurgeAttempts deletes old attempts from the current work
+// spec.
+func (api *restAPI) WorkSpecPurgeAttempts(ctx *context, in interface{}) (interface{}, error) {
+	req, valid := in.(restdata.AttemptPurge)
+	if !valid {
+		return nil, errUnmarshal
+	}
+	count, err := ctx.WorkSpec.PurgeAttempts(req.Before, req.Statuses)
+	if err != nil {
+		return nil, err
+	}
+	return restdata.AttemptsPurged{Purged: count}, nil
+}
+
+// WorkSpecSchedulable reports whether the current work spec can hand
+// out work right now.
+func (api *restAPI) WorkSpecSchedulable(ctx *context) (interface{}, error) {
+	ok, reason, err := ctx.WorkSpec.IsSchedulable()
+	if err != nil {
+		return nil, err
+	}
+	return restdata.Schedulable{Schedulable: ok, Reason: reason}, nil
+}
+
 // WorkSpecSummary produces a summary of the current work spec.
 func (api *restAPI) WorkSpecSummary(ctx *context) (interface{}, error) {
 	return ctx.WorkSpec.Summarize()
@@ -209,11 +385,36 @@ func (api *restAPI) PopulateWorkSpec(r *mux.Router) {
 		Get:            api.WorkSpecMetaGet,
 		Put:            api.WorkSpecMetaPut,
 	})
+	r.Path("/work_spec/{spec}/spec_summary").Name("workSpecSummary").Handler(&resourceHandler{
+		Representation: restdata.WorkSpecSummary{},
+		Context:        api.Context,
+		Get:            api.WorkSpecSpecSummary,
+	})
 	r.Path("/work_spec/{spec}/counts").Name("workSpecCounts").Handler(&resourceHandler{
 		Representation: make(map[coordinate.WorkUnitStatus]int),
 		Context:        api.Context,
 		Get:            api.WorkSpecCounts,
 	})
+	r.Path("/work_spec/{spec}/statuses").Name("workSpecStatuses").Handler(&resourceHandler{
+		Representation: restdata.WorkUnitNames{},
+		Context:        api.Context,
+		Post:           api.WorkSpecStatuses,
+	})
+	r.Path("/work_spec/{spec}/priorities").Name("workSpecPriorities").Handler(&resourceHandler{
+		Representation: restdata.WorkUnitNames{},
+		Context:        api.Context,
+		Post:           api.WorkSpecPriorities,
+	})
+	r.Path("/work_spec/{spec}/data_size").Name("workSpecDataSize").Handler(&resourceHandler{
+		Representation: restdata.DataSize{},
+		Context:        api.Context,
+		Get:            api.WorkSpecDataSize,
+	})
+	r.Path("/work_spec/{spec}/priority_histogram").Name("workSpecPriorityHistogram").Handler(&resourceHandler{
+		Representation: restdata.PriorityHistogram{},
+		Context:        api.Context,
+		Get:            api.WorkSpecPriorityHistogram,
+	})
 	r.Path("/work_spec/{spec}/change").Name("workSpecChange").Handler(&resourceHandler{
 		Representation: restdata.WorkUnit{},
 		Context:        api.Context,
@@ -224,6 +425,33 @@ func (api *restAPI) PopulateWorkSpec(r *mux.Router) {
 		Context:        api.Context,
 		Post:           api.WorkSpecAdjust,
 	})
+	r.Path("/work_spec/{spec}/reorder").Name("workSpecReorder").Handler(&resourceHandler{
+		Representation: restdata.WorkUnitOrder{},
+		Context:        api.Context,
+		Post:           api.WorkSpecReorder,
+	})
+	r.Path("/work_spec/{spec}/add_work_units").Name("workSpecAddWorkUnits").Handler(&resourceHandler{
+		Representation: restdata.WorkUnitBatch{},
+		Context:        api.Context,
+		Post:           api.WorkSpecAddWorkUnits,
+	})
+	r.Path("/work_spec/{spec}/next_continuous").Name("workSpecNextContinuous").Handler(&resourceHandler{
+		Representation: restdata.NextContinuous{},
+		Context:        api.Context,
+		Get:            api.WorkSpecNextContinuousGet,
+		Post:           api.WorkSpecNextContinuousPost,
+	})
+	r.Path("/work_spec/{spec}/purge_attempts").Name("workSpecPurgeAttempts").Handler(&resourceHandler{
+		Representation: restdata.AttemptPurge{},
+		Context:        api.Context,
+		Post:           api.WorkSpecPurgeAttempts,
+	})
+	r.Path("/work_spec/{spec}/schedulable").Name("workSpecSchedulable").Handler(&resourceHandler{
+		Representation: restdata.Schedulable{},
+		Context:        api.Context,
+		Get:            api.WorkSpecSchedulable,
+	})
+	r.Path("/work_spec/{spec}/events").Name("workSpecEvents").Handler(http.HandlerFunc(api.WorkSpecEvents))
 	r.Path("/work_spec/{spec}/summary").Name("workUnitSummary").Handler(&resourceHandler{
 		Representation: coordinate.Summary{},
 		Context:        api.Context,
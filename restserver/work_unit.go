@@ -7,6 +7,7 @@ import (
 	"github.com/diffeo/go-coordinate/coordinate"
 	"github.com/diffeo/go-coordinate/restdata"
 	"github.com/gorilla/mux"
+	"net/url"
 )
 
 func (api *restAPI) fillWorkUnitShort(namespace coordinate.Namespace, spec coordinate.WorkSpec, name string, short *restdata.WorkUnitShort) error {
@@ -31,6 +32,9 @@ func (api *restAPI) fillWorkUnit(namespace coordinate.Namespace, spec coordinate
 	if err == nil {
 		repr.Status, err = unit.Status()
 	}
+	if err == nil {
+		repr.CreatedAt, err = unit.CreatedAt()
+	}
 	if err == nil {
 		err = buildURLs(api.Router,
 			"namespace", namespace.Name(),
@@ -38,9 +42,13 @@ func (api *restAPI) fillWorkUnit(namespace coordinate.Namespace, spec coordinate
 			"unit", unit.Name(),
 		).
 			URL(&repr.WorkSpecURL, "workSpec").
+			URL(&repr.DataURL, "workUnitData").
 			URL(&repr.AttemptsURL, "workUnitAttempts").
 			Error
 	}
+	if err == nil {
+		repr.AttemptsURL += "{?archived_only}"
+	}
 	if err == nil {
 		var attempt coordinate.Attempt
 		attempt, err = unit.ActiveAttempt()
@@ -59,22 +67,70 @@ func (api *restAPI) fillWorkUnit(namespace coordinate.Namespace, spec coordinate
 
 func (api *restAPI) WorkUnitsGet(ctx *context) (interface{}, error) {
 	var (
-		err   error
-		q     coordinate.WorkUnitQuery
-		units map[string]coordinate.WorkUnit
-		resp  restdata.WorkUnitList
+		err    error
+		q      coordinate.WorkUnitQuery
+		units  map[string]coordinate.WorkUnit
+		cursor string
+		resp   restdata.WorkUnitList
 	)
 	q, err = ctx.WorkUnitQuery()
+	if err == nil && ctx.BoolParam("count_only", false) {
+		var count restdata.WorkUnitCount
+		count.Count, err = ctx.WorkSpec.CountWorkUnits(q)
+		if err != nil {
+			return nil, err
+		}
+		return count, nil
+	}
 	if err == nil {
-		units, err = ctx.WorkSpec.WorkUnits(q)
+		units, cursor, err = ctx.WorkSpec.WorkUnitsPage(q)
+	}
+	if err == nil && cursor != "" {
+		resp.Cursor = cursor
+		err = buildURLs(api.Router,
+			"namespace", ctx.Namespace.Name(),
+			"spec", ctx.WorkSpec.Name(),
+		).URL(&resp.Next, "workUnits").Error
+		if err == nil {
+			// The next page has the same query, picking up
+			// after the cursor
+			params := url.Values{}
+			for key, values := range ctx.QueryParams {
+				params[key] = values
+			}
+			params.Del("previous")
+			params.Del("offset")
+			params.Set("cursor", cursor)
+			resp.Next += "?" + params.Encode()
+		}
+	}
+	var (
+		statuses   map[string]coordinate.WorkUnitStatus
+		priorities map[string]float64
+	)
+	if err == nil && ctx.BoolParam("details", false) {
+		names := make([]string, 0, len(units))
+		for name := range units {
+			names = append(names, name)
+		}
+		statuses, err = ctx.WorkSpec.WorkUnitStatuses(names)
+		if err == nil {
+			priorities, err = ctx.WorkSpec.WorkUnitPriorities(names)
+		}
 	}
 	if err == nil {
-		for _, unit := range units {
+		for name, unit := range units {
 			var short restdata.WorkUnitShort
 			err = api.fillWorkUnitShort(ctx.Namespace, ctx.WorkSpec, unit.Name(), &short)
 			if err != nil {
 				return nil, err
 			}
+			if status, present := statuses[name]; present {
+				short.Status = &status
+			}
+			if priority, present := priorities[name]; present {
+				short.Priority = &priority
+			}
 			resp.WorkUnits = append(resp.WorkUnits, short)
 		}
 		return resp, nil
@@ -156,8 +212,20 @@ func (api *restAPI) WorkUnitPut(ctx *context, in interface{}) (interface{}, erro
 	return nil, err
 }
 
+// WorkUnitDataPut replaces the current work unit's data.
+func (api *restAPI) WorkUnitDataPut(ctx *context, in interface{}) (interface{}, error) {
+	repr, valid := in.(restdata.WorkUnitData)
+	if !valid {
+		return nil, errUnmarshal
+	}
+	return nil, ctx.WorkUnit.SetData(repr.Data)
+}
+
 func (api *restAPI) WorkUnitAttempts(ctx *context) (interface{}, error) {
-	attempts, err := ctx.WorkUnit.Attempts()
+	q := coordinate.AttemptQuery{
+		ArchivedOnly: ctx.BoolParam("archived_only", false),
+	}
+	attempts, err := ctx.WorkUnit.QueryAttempts(q)
 	if err != nil {
 		return nil, err
 	}
@@ -189,10 +257,16 @@ func (api *restAPI) PopulateWorkUnit(r *mux.Router) {
 		Get:            api.WorkUnitGet,
 		Put:            api.WorkUnitPut,
 	})
+	r.Path("/work_unit/{unit}/data").Name("workUnitData").Handler(&resourceHandler{
+		Representation: restdata.WorkUnitData{},
+		Context:        api.Context,
+		Put:            api.WorkUnitDataPut,
+	})
 	r.Path("/work_unit/{unit}/attempts").Name("workUnitAttempts").Handler(&resourceHandler{
 		Representation: restdata.AttemptList{},
 		Context:        api.Context,
 		Get:            api.WorkUnitAttempts,
+		NoCache:        true,
 	})
 	r.Path("/work_unit/{unit}/num-attempts").Name("workUnitNumAttempts").Handler(&resourceHandler{
 		Representation: 0,
@@ -7,6 +7,8 @@ import (
 	"github.com/diffeo/go-coordinate/coordinate"
 	"github.com/diffeo/go-coordinate/restdata"
 	"github.com/gorilla/mux"
+	"sort"
+	"time"
 )
 
 func (api *restAPI) fillWorkerShort(namespace coordinate.Namespace, worker coordinate.Worker, short *restdata.WorkerShort) error {
@@ -31,8 +33,23 @@ func (api *restAPI) fillWorker(namespace coordinate.Namespace, worker coordinate
 			URL(&result.ActiveAttemptsURL, "workerActiveAttempts").
 			URL(&result.AllAttemptsURL, "workerAllAttempts").
 			URL(&result.ChildAttemptsURL, "workerChildAttempts").
+			URL(&result.FinishAttemptsURL, "workerFinishAttempts").
+			URL(&result.FailAttemptsURL, "workerFailAttempts").
+			URL(&result.RetryAttemptsURL, "workerRetryAttempts").
+			URL(&result.CompleteAttemptsURL, "workerCompleteAttempts").
 			Error
 	}
+	if err == nil {
+		err = buildURLs(api.Router,
+			"namespace", namespace.Name(),
+			"parent", worker.Name(),
+		).
+			URL(&result.ChildrenURL, "workerChildren").
+			Error
+	}
+	if err == nil {
+		result.AllAttemptsURL += "{?since,until}"
+	}
 	var parent coordinate.Worker
 	if err == nil {
 		parent, err = worker.Parent()
@@ -83,6 +100,48 @@ func (api *restAPI) fillWorker(namespace coordinate.Namespace, worker coordinate
 	return err
 }
 
+// WorkerList returns all of the workers in the current namespace, or
+// only the children of the worker named in the "parent" query
+// parameter.
+func (api *restAPI) WorkerList(ctx *context) (interface{}, error) {
+	var workers []coordinate.Worker
+	if parentName := ctx.QueryParams.Get("parent"); parentName != "" {
+		name, err := restdata.MaybeDecodeName(parentName)
+		if err != nil {
+			return nil, err
+		}
+		parent, err := ctx.Namespace.Worker(name)
+		if err != nil {
+			return nil, err
+		}
+		workers, err = parent.Children()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		all, err := ctx.Namespace.Workers()
+		if err != nil {
+			return nil, err
+		}
+		for _, worker := range all {
+			workers = append(workers, worker)
+		}
+	}
+	sort.Slice(workers, func(i, j int) bool {
+		return workers[i].Name() < workers[j].Name()
+	})
+	resp := restdata.WorkerList{
+		Workers: make([]restdata.WorkerShort, len(workers)),
+	}
+	for i, worker := range workers {
+		err := api.fillWorkerShort(ctx.Namespace, worker, &resp.Workers[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
 func (api *restAPI) WorkerGet(ctx *context) (interface{}, error) {
 	repr := restdata.Worker{}
 	err := api.fillWorker(ctx.Namespace, ctx.Worker, &repr)
@@ -186,7 +245,7 @@ func (api *restAPI) WorkerMakeAttempt(ctx *context, in interface{}) (interface{}
 	}
 
 	// Now we can force the attempt
-	attempt, err := ctx.Worker.MakeAttempt(unit, req.Lifetime)
+	attempt, err := ctx.Worker.MakeAttempt(unit, time.Duration(req.Lifetime))
 	if err != nil {
 		return nil, err
 	}
@@ -208,7 +267,20 @@ func (api *restAPI) WorkerActiveAttempts(ctx *context) (interface{}, error) {
 }
 
 func (api *restAPI) WorkerAllAttempts(ctx *context) (interface{}, error) {
-	attempts, err := ctx.Worker.AllAttempts()
+	since, err := ctx.TimeParam("since")
+	if err != nil {
+		return nil, err
+	}
+	until, err := ctx.TimeParam("until")
+	if err != nil {
+		return nil, err
+	}
+	var attempts []coordinate.Attempt
+	if since.IsZero() && until.IsZero() {
+		attempts, err = ctx.Worker.AllAttempts()
+	} else {
+		attempts, err = ctx.Worker.AttemptsInWindow(since, until)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -223,12 +295,177 @@ func (api *restAPI) WorkerChildAttempts(ctx *context) (interface{}, error) {
 	return api.returnAttempts(ctx, attempts)
 }
 
+// attemptBatch finds the attempts named in an AttemptBatch, and
+// collects their data.
+func (api *restAPI) attemptBatch(ctx *context, in interface{}) ([]coordinate.Attempt, []map[string]interface{}, error) {
+	req, valid := in.(restdata.AttemptBatch)
+	if !valid {
+		return nil, nil, errUnmarshal
+	}
+	attempts, errs, err := api.batchAttempts(ctx, req.Attempts)
+	if err != nil {
+		return nil, nil, err
+	}
+	data := make([]map[string]interface{}, len(req.Attempts))
+	for i, item := range req.Attempts {
+		if errs[i] != nil {
+			return nil, nil, errs[i]
+		}
+		data[i] = item.Data
+	}
+	return attempts, data, nil
+}
+
+// attemptKey identifies an attempt by its work spec and work unit
+// names and its ID.
+type attemptKey struct {
+	WorkSpec string
+	WorkUnit string
+	ID       string
+}
+
+// batchAttempts finds the attempts named by items.  It looks first
+// among the worker's active attempts, which takes a single backend
+// call, and only looks up an attempt on its own if it is not there,
+// for instance because it has already finished.  Returns one attempt
+// and one error per item; an item that cannot be found has a nil
+// attempt and the reason in its error.  If the worker's active
+// attempts cannot be listed, returns that error.
+func (api *restAPI) batchAttempts(ctx *context, items []restdata.AttemptBatchItem) ([]coordinate.Attempt, []error, error) {
+	active, err := ctx.Worker.ActiveAttempts()
+	if err != nil {
+		return nil, nil, err
+	}
+	byKey := make(map[attemptKey]coordinate.Attempt, len(active))
+	for _, attempt := range active {
+		unit := attempt.WorkUnit()
+		byKey[attemptKey{
+			WorkSpec: unit.WorkSpec().Name(),
+			WorkUnit: unit.Name(),
+			ID:       attempt.ID(),
+		}] = attempt
+	}
+
+	attempts := make([]coordinate.Attempt, len(items))
+	errs := make([]error, len(items))
+	for i, item := range items {
+		attempt, found := byKey[attemptKey{
+			WorkSpec: item.WorkSpec,
+			WorkUnit: item.WorkUnit,
+			ID:       item.ID,
+		}]
+		if found {
+			attempts[i] = attempt
+		} else {
+			attempts[i], errs[i] = api.batchItemAttempt(ctx, item)
+		}
+	}
+	return attempts, errs, nil
+}
+
+// batchItemAttempt finds the attempt named by an AttemptBatchItem.
+func (api *restAPI) batchItemAttempt(ctx *context, item restdata.AttemptBatchItem) (coordinate.Attempt, error) {
+	spec, err := ctx.Namespace.WorkSpec(item.WorkSpec)
+	if err != nil {
+		return nil, err
+	}
+	unit, err := spec.WorkUnit(item.WorkUnit)
+	if err != nil {
+		return nil, err
+	}
+	return findAttempt(unit, item.ID)
+}
+
+func (api *restAPI) WorkerFinishAttempts(ctx *context, in interface{}) (interface{}, error) {
+	attempts, data, err := api.attemptBatch(ctx, in)
+	if err == nil {
+		err = ctx.Worker.FinishAttempts(attempts, data)
+	}
+	return nil, err
+}
+
+func (api *restAPI) WorkerFailAttempts(ctx *context, in interface{}) (interface{}, error) {
+	attempts, data, err := api.attemptBatch(ctx, in)
+	if err == nil {
+		err = ctx.Worker.FailAttempts(attempts, data)
+	}
+	return nil, err
+}
+
+func (api *restAPI) WorkerRetryAttempts(ctx *context, in interface{}) (interface{}, error) {
+	attempts, data, err := api.attemptBatch(ctx, in)
+	if err == nil {
+		err = ctx.Worker.RetryAttempts(attempts, data, time.Duration(in.(restdata.AttemptBatch).Delay))
+	}
+	return nil, err
+}
+
+func (api *restAPI) WorkerCompleteAttempts(ctx *context, in interface{}) (interface{}, error) {
+	req, valid := in.(restdata.AttemptOutcomeList)
+	if !valid {
+		return nil, errUnmarshal
+	}
+
+	// An attempt we cannot find gets an error of its own, and
+	// does not stop the others from completing
+	var outcomes []coordinate.AttemptOutcome
+	var indexes []int
+	resp := restdata.AttemptOutcomeResults{
+		Errors: make([]*restdata.ErrorResponse, len(req.Attempts)),
+	}
+	items := make([]restdata.AttemptBatchItem, len(req.Attempts))
+	for i, item := range req.Attempts {
+		items[i] = item.AttemptBatchItem
+	}
+	attempts, findErrs, err := api.batchAttempts(ctx, items)
+	if err != nil {
+		return nil, err
+	}
+	for i, item := range req.Attempts {
+		if findErrs[i] != nil {
+			resp.Errors[i] = outcomeError(findErrs[i])
+			continue
+		}
+		outcomes = append(outcomes, coordinate.AttemptOutcome{
+			Attempt: attempts[i],
+			Status:  item.Status,
+			Data:    item.Data,
+			Delay:   time.Duration(item.Delay),
+		})
+		indexes = append(indexes, i)
+	}
+	errs, err := ctx.Worker.CompleteAttempts(outcomes)
+	if err != nil {
+		return nil, err
+	}
+	for j, err := range errs {
+		if err != nil {
+			resp.Errors[indexes[j]] = outcomeError(err)
+		}
+	}
+	return resp, nil
+}
+
+// outcomeError converts an error completing a single attempt to an
+// ErrorResponse, as if it were the error from a whole request.
+func outcomeError(err error) *restdata.ErrorResponse {
+	resp := restdata.ErrorResponse{Error: "error", Message: err.Error()}
+	resp.FromError(err)
+	return &resp
+}
+
 // PopulateWorker adds worker-specific routes to a router.
 // r should be rooted at the root of the Coordinate URL tree, e.g. "/".
 func (api *restAPI) PopulateWorker(r *mux.Router) {
+	r.Path("/worker").Queries("parent", "{parent}").Name("workerChildren").Handler(&resourceHandler{
+		Representation: restdata.WorkerShort{},
+		Context:        api.Context,
+		Get:            api.WorkerList,
+	})
 	r.Path("/worker").Name("workers").Handler(&resourceHandler{
 		Representation: restdata.WorkerShort{},
 		Context:        api.Context,
+		Get:            api.WorkerList,
 	})
 	r.Path("/worker/{worker}").Name("worker").Handler(&resourceHandler{
 		Representation: restdata.Worker{},
@@ -250,15 +487,38 @@ func (api *restAPI) PopulateWorker(r *mux.Router) {
 		Representation: restdata.AttemptList{},
 		Context:        api.Context,
 		Get:            api.WorkerActiveAttempts,
+		NoCache:        true,
 	})
 	r.Path("/worker/{worker}/all_attempts").Name("workerAllAttempts").Handler(&resourceHandler{
 		Representation: restdata.AttemptList{},
 		Context:        api.Context,
 		Get:            api.WorkerAllAttempts,
+		NoCache:        true,
 	})
 	r.Path("/worker/{worker}/child_attempts").Name("workerChildAttempts").Handler(&resourceHandler{
 		Representation: restdata.AttemptList{},
 		Context:        api.Context,
 		Get:            api.WorkerChildAttempts,
+		NoCache:        true,
+	})
+	r.Path("/worker/{worker}/finish_attempts").Name("workerFinishAttempts").Handler(&resourceHandler{
+		Representation: restdata.AttemptBatch{},
+		Context:        api.Context,
+		Post:           api.WorkerFinishAttempts,
+	})
+	r.Path("/worker/{worker}/fail_attempts").Name("workerFailAttempts").Handler(&resourceHandler{
+		Representation: restdata.AttemptBatch{},
+		Context:        api.Context,
+		Post:           api.WorkerFailAttempts,
+	})
+	r.Path("/worker/{worker}/retry_attempts").Name("workerRetryAttempts").Handler(&resourceHandler{
+		Representation: restdata.AttemptBatch{},
+		Context:        api.Context,
+		Post:           api.WorkerRetryAttempts,
+	})
+	r.Path("/worker/{worker}/complete_attempts").Name("workerCompleteAttempts").Handler(&resourceHandler{
+		Representation: restdata.AttemptOutcomeList{},
+		Context:        api.Context,
+		Post:           api.WorkerCompleteAttempts,
 	})
 }
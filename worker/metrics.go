@@ -0,0 +1,203 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package worker
+
+import (
+	"github.com/diffeo/go-coordinate/coordinate"
+	"github.com/prometheus/client_golang/prometheus"
+	"sync"
+	"time"
+)
+
+// Labels for attemptsCompleted.
+const (
+	outcomeFinished = "finished"
+	outcomeFailed   = "failed"
+	outcomeRetried  = "retried"
+)
+
+var attemptsRequested = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "coordinate",
+		Subsystem: "worker",
+		Name:      "attempts_requested_total",
+		Help:      "Number of attempts the worker received from RequestAttempts",
+	},
+	[]string{"namespace", "work_spec"})
+
+var attemptsCompleted = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "coordinate",
+		Subsystem: "worker",
+		Name:      "attempts_completed_total",
+		Help:      "Number of attempts the worker saw finished, failed, or retried",
+	},
+	[]string{"namespace", "work_spec", "outcome"})
+
+var attemptsTimedOut = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "coordinate",
+		Subsystem: "worker",
+		Name:      "attempts_timed_out_total",
+		Help:      "Number of attempts the worker failed because they were about to expire",
+	},
+	[]string{"namespace", "work_spec"})
+
+var taskSeconds = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Namespace: "coordinate",
+		Subsystem: "worker",
+		Name:      "task_duration_seconds",
+		Help:      "Seconds spent in task functions",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 16),
+	},
+	[]string{"namespace", "work_spec"})
+
+var registerOnce sync.Once
+
+// registerMetrics adds the worker metrics to the default registry
+// the first time any worker runs, so that programs that only import
+// this package do not export them.
+func registerMetrics() {
+	registerOnce.Do(func() {
+		prometheus.MustRegister(attemptsRequested, attemptsCompleted, attemptsTimedOut, taskSeconds)
+	})
+}
+
+// countOutcome records that attempt ended with status.  Statuses
+// other than finished, failed, and retryable are not counted.
+func (w *Worker) countOutcome(attempt coordinate.Attempt, status coordinate.AttemptStatus) {
+	var outcome string
+	switch status {
+	case coordinate.Finished:
+		outcome = outcomeFinished
+	case coordinate.Failed:
+		outcome = outcomeFailed
+	case coordinate.Retryable:
+		outcome = outcomeRetried
+	default:
+		return
+	}
+	spec := attempt.WorkUnit().WorkSpec().Name()
+	attemptsCompleted.WithLabelValues(w.Namespace.Name(), spec, outcome).Inc()
+}
+
+// countAttempts wraps attempts before they are given to a task
+// function, so that each outcome is counted when the task completes
+// it, without looking up the status afterwards.
+func (w *Worker) countAttempts(attempts []coordinate.Attempt) []coordinate.Attempt {
+	counted := make([]coordinate.Attempt, len(attempts))
+	for i, attempt := range attempts {
+		counted[i] = countedAttempt{Attempt: attempt, w: w}
+	}
+	return counted
+}
+
+// uncounted returns the backend attempts underneath attempts, which
+// may have come from countAttempts.
+func uncounted(attempts []coordinate.Attempt) []coordinate.Attempt {
+	result := make([]coordinate.Attempt, len(attempts))
+	for i, attempt := range attempts {
+		if counted, ok := attempt.(countedAttempt); ok {
+			attempt = counted.Attempt
+		}
+		result[i] = attempt
+	}
+	return result
+}
+
+// countedAttempt is an attempt given to a task function.  It counts
+// the outcome when the task finishes, fails, or retries it.  A retry
+// that the backend turns into a failure, because the work unit has
+// run out of retries, is counted as retried.
+type countedAttempt struct {
+	coordinate.Attempt
+	w *Worker
+}
+
+func (a countedAttempt) Worker() coordinate.Worker {
+	return countedWorker{Worker: a.Attempt.Worker(), w: a.w}
+}
+
+func (a countedAttempt) Finish(data map[string]interface{}) error {
+	err := a.Attempt.Finish(data)
+	if err == nil {
+		a.w.countOutcome(a.Attempt, coordinate.Finished)
+	}
+	return err
+}
+
+func (a countedAttempt) Fail(data map[string]interface{}) error {
+	err := a.Attempt.Fail(data)
+	if err == nil {
+		a.w.countOutcome(a.Attempt, coordinate.Failed)
+	}
+	return err
+}
+
+func (a countedAttempt) Retry(data map[string]interface{}, delay time.Duration) error {
+	err := a.Attempt.Retry(data, delay)
+	if err == nil {
+		a.w.countOutcome(a.Attempt, coordinate.Retryable)
+	}
+	return err
+}
+
+// countedWorker is the worker of a countedAttempt.  Its batch
+// completion calls pass the backend attempts through and count their
+// outcomes.
+type countedWorker struct {
+	coordinate.Worker
+	w *Worker
+}
+
+// completeAttempts runs one of the batch completion calls on the
+// backend attempts, and counts every attempt if it succeeds.
+func (cw countedWorker) completeAttempts(attempts []coordinate.Attempt, status coordinate.AttemptStatus, complete func([]coordinate.Attempt) error) error {
+	inner := uncounted(attempts)
+	err := complete(inner)
+	if err == nil {
+		for _, attempt := range inner {
+			cw.w.countOutcome(attempt, status)
+		}
+	}
+	return err
+}
+
+func (cw countedWorker) FinishAttempts(attempts []coordinate.Attempt, data []map[string]interface{}) error {
+	return cw.completeAttempts(attempts, coordinate.Finished, func(inner []coordinate.Attempt) error {
+		return cw.Worker.FinishAttempts(inner, data)
+	})
+}
+
+func (cw countedWorker) FailAttempts(attempts []coordinate.Attempt, data []map[string]interface{}) error {
+	return cw.completeAttempts(attempts, coordinate.Failed, func(inner []coordinate.Attempt) error {
+		return cw.Worker.FailAttempts(inner, data)
+	})
+}
+
+func (cw countedWorker) RetryAttempts(attempts []coordinate.Attempt, data []map[string]interface{}, delay time.Duration) error {
+	return cw.completeAttempts(attempts, coordinate.Retryable, func(inner []coordinate.Attempt) error {
+		return cw.Worker.RetryAttempts(inner, data, delay)
+	})
+}
+
+func (cw countedWorker) CompleteAttempts(outcomes []coordinate.AttemptOutcome) ([]error, error) {
+	inner := make([]coordinate.AttemptOutcome, len(outcomes))
+	for i, outcome := range outcomes {
+		inner[i] = outcome
+		if counted, ok := outcome.Attempt.(countedAttempt); ok {
+			inner[i].Attempt = counted.Attempt
+		}
+	}
+	errs, err := cw.Worker.CompleteAttempts(inner)
+	if err == nil {
+		for i, outcome := range inner {
+			if errs[i] == nil {
+				cw.w.countOutcome(outcome.Attempt, outcome.Status)
+			}
+		}
+	}
+	return errs, err
+}
@@ -0,0 +1,130 @@
+// Copyright 2017 Diffeo, Inc.
+// This software is released under an MIT/X11 open source license.
+
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/diffeo/go-coordinate/coordinate"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+// taskSamples returns the number of task durations observed for
+// specName, and their total in seconds.
+func taskSamples(t *testing.T, specName string) (uint64, float64) {
+	var metric dto.Metric
+	observer := taskSeconds.WithLabelValues("", specName)
+	err := observer.(prometheus.Histogram).Write(&metric)
+	if !assert.NoError(t, err) {
+		return 0, 0
+	}
+	return metric.GetHistogram().GetSampleCount(), metric.GetHistogram().GetSampleSum()
+}
+
+// TestMetricsOutcomes checks that attempts are counted when they are
+// requested and when their task functions complete them, and that
+// the task's running time is recorded.
+func TestMetricsOutcomes(t *testing.T) {
+	var s Suite
+	s.SetUpTest(t)
+	s.Worker.Clock = s.Clock
+	s.Worker.Tasks["slow"] = func(ctx context.Context, attempts []coordinate.Attempt) {
+		s.Clock.Add(3 * time.Second)
+		for _, attempt := range attempts {
+			assert.NoError(t, attempt.Retry(nil, 0))
+		}
+	}
+	s.CreateSpecAndUnit(t, "slow", "metrics_outcomes", "go")
+	s.BootstrapWorker(t)
+
+	requested := attemptsRequested.WithLabelValues("", "metrics_outcomes")
+	retried := attemptsCompleted.WithLabelValues("", "metrics_outcomes", outcomeRetried)
+	finished := attemptsCompleted.WithLabelValues("", "metrics_outcomes", outcomeFinished)
+	beforeRequested := testutil.ToFloat64(requested)
+	beforeRetried := testutil.ToFloat64(retried)
+	beforeFinished := testutil.ToFloat64(finished)
+	beforeCount, beforeSum := taskSamples(t, "metrics_outcomes")
+
+	s.GoDoWork(t)
+	s.GetWork(t, true)
+	s.Finish(t)
+
+	assert.Equal(t, beforeRequested+1, testutil.ToFloat64(requested))
+	assert.Equal(t, beforeRetried+1, testutil.ToFloat64(retried))
+	assert.Equal(t, beforeFinished, testutil.ToFloat64(finished))
+	count, sum := taskSamples(t, "metrics_outcomes")
+	assert.Equal(t, beforeCount+1, count)
+	assert.Equal(t, beforeSum+3, sum)
+}
+
+// TestMetricsNoTask checks that attempts the worker fails because it
+// cannot run them are counted as failed, without a task duration.
+func TestMetricsNoTask(t *testing.T) {
+	var s Suite
+	s.SetUpTest(t)
+	s.CreateSpecAndUnit(t, "missing", "metrics_no_task", "go")
+	s.BootstrapWorker(t)
+
+	failed := attemptsCompleted.WithLabelValues("", "metrics_no_task", outcomeFailed)
+	beforeFailed := testutil.ToFloat64(failed)
+	beforeCount, _ := taskSamples(t, "metrics_no_task")
+
+	s.GoDoWork(t)
+	s.GetWork(t, true)
+	s.Finish(t)
+
+	assert.Equal(t, beforeFailed+1, testutil.ToFloat64(failed))
+	count, _ := taskSamples(t, "metrics_no_task")
+	assert.Equal(t, beforeCount, count)
+}
+
+// TestMetricsTimedOut checks that attempts findStaleUnits fails are
+// counted as timed out, and then as failed once their task returns.
+func TestMetricsTimedOut(t *testing.T) {
+	var s Suite
+	s.SetUpTest(t)
+	s.CreateSpecAndUnit(t, "timeout", "metrics_timed_out", "go")
+	s.BootstrapWorker(t)
+
+	timedOut := attemptsTimedOut.WithLabelValues("", "metrics_timed_out")
+	failed := attemptsCompleted.WithLabelValues("", "metrics_timed_out", outcomeFailed)
+	beforeTimedOut := testutil.ToFloat64(timedOut)
+	beforeFailed := testutil.ToFloat64(failed)
+
+	s.GoDoWork(t)
+	s.GetWork(t, true)
+
+	s.Clock.Add(14*time.Minute + 50*time.Second)
+	s.Worker.findStaleUnits()
+	assert.Equal(t, beforeTimedOut+1, testutil.ToFloat64(timedOut))
+
+	s.Finish(t)
+	assert.Equal(t, beforeFailed+1, testutil.ToFloat64(failed))
+}
+
+// TestMetricsBatchOutcomes checks that attempts a task completes
+// through its worker's batch calls are counted.
+func TestMetricsBatchOutcomes(t *testing.T) {
+	var s Suite
+	s.SetUpTest(t)
+	s.Worker.Tasks["batch"] = func(ctx context.Context, attempts []coordinate.Attempt) {
+		assert.NoError(t, attempts[0].Worker().FinishAttempts(attempts, nil))
+	}
+	s.CreateSpecAndUnit(t, "batch", "metrics_batch", "go")
+	s.BootstrapWorker(t)
+
+	finished := attemptsCompleted.WithLabelValues("", "metrics_batch", outcomeFinished)
+	beforeFinished := testutil.ToFloat64(finished)
+
+	s.GoDoWork(t)
+	s.GetWork(t, true)
+	s.Finish(t)
+
+	assert.Equal(t, beforeFinished+1, testutil.ToFloat64(finished))
+}
@@ -3,6 +3,12 @@
 
 // Package worker provides a library framework for processes that
 // execute Coordinate work units.
+//
+// Workers record Prometheus metrics, which the first call to
+// Worker.Run() adds to the default registry, labeled by namespace
+// and work spec: the number of attempts requested, how
+// many of them were finished, failed, retried, or timed out, and how
+// long task functions took to run.
 package worker
 
 import (
@@ -11,6 +17,7 @@ import (
 	"net"
 	"os"
 	"runtime"
+	"runtime/debug"
 	"sync"
 	"time"
 
@@ -42,8 +49,52 @@ type Worker struct {
 	//
 	// There is guaranteed to be at least one attempt.  All attempts
 	// are for the same worker and for the same work spec.
+	//
+	// If the task function panics, the worker recovers, fails
+	// any of the attempts that are still pending with the stack
+	// trace, reports the panic to ErrorHandler, and continues.
 	Tasks map[string]func(context.Context, []coordinate.Attempt)
 
+	// TaskLifetimes sets how long the worker should hold attempts
+	// for specific tasks, keyed by the same task names as Tasks.
+	// If every task a request for work could return has the same
+	// lifetime, the request asks for it directly.  Otherwise the
+	// attempts are requested with the server's default lifetime,
+	// and then renewed for the configured lifetime before the
+	// task function is called; attempts that cannot be renewed
+	// are reported to ErrorHandler and not run.  Tasks not in
+	// this map keep the default lifetime.
+	TaskLifetimes map[string]time.Duration
+
+	// SchemaVersions sets the work spec schema version each task
+	// expects, keyed by the same task names as Tasks.  If a task
+	// is in this map, attempts are only passed to its task
+	// function if their work spec's "schema_version" matches.
+	// Otherwise they are released for a compatible worker to
+	// run, the mismatch is reported to ErrorHandler, and this
+	// worker stops requesting work from that work spec.  Tasks
+	// not in this map accept any schema version.
+	SchemaVersions map[string]int
+
+	// TaskConcurrency limits how many calls to specific task
+	// functions may run at once in this worker, keyed by the same
+	// task names as Tasks.  When a task is at its limit, child
+	// workers only request work from work specs for other tasks,
+	// so the rest of the pool stays busy.  Tasks not in this map
+	// are limited only by Concurrency.
+	//
+	// Each call to a task function may receive several attempts;
+	// set the work spec's "max_getwork" to limit that too.  This
+	// limit applies only within this worker, while the work
+	// spec's "max_running" limits its pending work units across
+	// every worker in the system.  The smaller of the two limits
+	// applies.
+	//
+	// While a task is at its limit, each request for work looks
+	// up the work specs in the namespace (or in WorkSpecs, if it
+	// is set) to find the ones for other tasks.
+	TaskConcurrency map[string]int
+
 	// WorkerID provides the name of the worker as seen through the
 	// Coordinate API.  If unset, a worker ID will be generated.
 	WorkerID string
@@ -57,12 +108,28 @@ type Worker struct {
 	// unset, defaults to 1 second.
 	PollInterval time.Duration
 
+	// WatchWork, if set, asks each work spec that supports
+	// coordinate.WorkWatcher to announce new work units, so that
+	// an idle worker can start them right away instead of waiting
+	// for PollInterval.  The worker still polls, which finds work
+	// in specs that cannot announce it or that were created after
+	// Run started.
+	WatchWork bool
+
 	// HeartbeatInterval states how often the worker should report
 	// its status in the Coordinate worker data, and check for
 	// work units that are about to expire.  If unset, defaults to
 	// 15 seconds.
 	HeartbeatInterval time.Duration
 
+	// ExtraHeartbeatData, if set, is called on every heartbeat,
+	// and the fields it returns are merged into the published
+	// worker data.  Applications can use this to report things
+	// like their own version or internal queue depths.  The
+	// fields the worker publishes itself ("cpus", "pid", and so
+	// on) take precedence over any with the same name here.
+	ExtraHeartbeatData func() map[string]interface{}
+
 	// MaxAttempts limits the number of attempts that will be
 	// returned; it is exactly the
 	// coordinate.AttemptRequest.NumberOfWorkUnits parameter.  If
@@ -71,6 +138,15 @@ type Worker struct {
 	// key.
 	MaxAttempts int
 
+	// KeepWarm, if set, makes a child worker request its next
+	// batch of attempts as soon as its task function returns,
+	// rather than going back to the main loop and waiting to be
+	// scheduled again.  This shortens the gap between batches
+	// when there is a steady supply of work.  The child still
+	// stops as soon as a request comes back empty or the worker
+	// is stopped.
+	KeepWarm bool
+
 	// ErrorHandler is called when an error occurs in the worker
 	// main loop.
 	ErrorHandler func(error)
@@ -87,6 +163,37 @@ type Worker struct {
 	// used.
 	Runtimes []string
 
+	// WorkSpecs, if non-empty, limits this worker to the named
+	// work specs; it is passed as the
+	// coordinate.AttemptRequest.WorkSpecs parameter on every
+	// request for work.  A worker dedicated to a few kinds of
+	// work can set this once instead of filtering its Tasks.
+	WorkSpecs []string
+
+	// MinPriority, if non-nil, limits this worker to work units
+	// with at least this priority; it is passed as the
+	// coordinate.AttemptRequest.MinPriority parameter on every
+	// request for work.  A pool of workers reserved for urgent
+	// work can set this, leaving lower-priority work units for
+	// other workers.
+	MinPriority *float64
+
+	// ShutdownGrace is how long Run waits, once it is stopped,
+	// for running tasks to return; their contexts are cancelled
+	// as soon as it is stopped.  Attempts still pending after
+	// this time, including those of tasks that have not
+	// returned, are failed with a "worker shutting down"
+	// traceback, unless ReleaseOnShutdown is set.  If unset,
+	// Run does not wait.
+	ShutdownGrace time.Duration
+
+	// ReleaseOnShutdown, if true, releases the attempts still
+	// pending after ShutdownGrace instead of failing them, so
+	// that their work units can run again without counting a
+	// retry.  A worker that is routinely restarted, for
+	// instance in a rolling deploy, will generally want this.
+	ReleaseOnShutdown bool
+
 	// parentWorker is a saved Coordinate worker object with ID
 	// WorkerID.
 	parentWorker coordinate.Worker
@@ -107,6 +214,32 @@ type Worker struct {
 	// nothing.  In this case, there will not be another attempt
 	// to get work for PollDuration time.
 	systemIdle bool
+
+	// taskLock protects taskRunning, specTasks, and skipSpecs.
+	taskLock sync.Mutex
+
+	// taskRunning counts the running calls to each task function
+	// that has a limit in TaskConcurrency.
+	taskRunning map[string]int
+
+	// specTasks remembers the task for each work spec name, so
+	// that building a request for work does not fetch every work
+	// spec every time.  runAttempts updates it as it runs work.
+	specTasks map[string]string
+
+	// skipSpecs holds the names of work specs whose schema
+	// versions do not match SchemaVersions, so that this worker
+	// does not keep requesting work it will only give back.
+	skipSpecs map[string]struct{}
+
+	// runLock protects stopRun and stopped.
+	runLock sync.Mutex
+
+	// stopRun cancels the context of the current Run call.
+	stopRun context.CancelFunc
+
+	// stopped is closed when the current Run call returns.
+	stopped chan struct{}
 }
 
 var (
@@ -155,6 +288,9 @@ func (w *Worker) setDefaults() {
 func (w *Worker) bootstrap() error {
 	w.childWorkers = make(map[string]coordinate.Worker)
 	w.cancellations = new(sync.Map)
+	w.taskRunning = make(map[string]int)
+	w.specTasks = make(map[string]string)
+	w.skipSpecs = make(map[string]struct{})
 
 	// Get the parent worker
 	var err error
@@ -163,16 +299,34 @@ func (w *Worker) bootstrap() error {
 }
 
 // Run runs work units from Coordinate forever, or until the provided
-// context is cancelled.  If it returns, either there was a startup
-// error connecting to Coordinate, in which case the corresponding
-// error is returned, or execution was cancelled, returning nil.  If
-// there is an error while trying to get attempts it is ignored.
+// context is cancelled or Stop is called.  If it returns, either
+// there was a startup error connecting to Coordinate, in which case
+// the corresponding error is returned, or execution was cancelled,
+// returning nil.  If there is an error while trying to get attempts
+// it is ignored.
+//
+// When execution is cancelled, Run stops requesting work, waits up to
+// ShutdownGrace for running tasks to return, fails (or, with
+// ReleaseOnShutdown, releases) any attempts that are still pending,
+// and deactivates its child workers.
 func (w *Worker) Run(ctx context.Context) error {
+	registerMetrics()
 	w.setDefaults()
 	if err := w.bootstrap(); err != nil {
 		return err
 	}
 
+	ctx, cancel := context.WithCancel(ctx)
+	stopped := make(chan struct{})
+	w.runLock.Lock()
+	w.stopRun = cancel
+	w.stopped = stopped
+	w.runLock.Unlock()
+	defer func() {
+		cancel()
+		close(stopped)
+	}()
+
 	// This channel is signaled in doWork() after
 	// RequestAttempts() returns, with a true value if at least
 	// one attempt comes back.  If it does signal true, it
@@ -191,12 +345,19 @@ func (w *Worker) Run(ctx context.Context) error {
 	var ticker *clock.Ticker
 
 	// This channel is signaled to run the heartbeat task.
-	var heartbeat <-chan time.Time
 	heartbeater := w.Clock.Ticker(w.HeartbeatInterval)
+	heartbeat := heartbeater.C
+
+	// This channel, if non-nil, is signaled when a watched work
+	// spec announces new work.
+	var wake <-chan struct{}
+	if w.WatchWork {
+		wake = w.watchWork(ctx)
+	}
 
 	// We need to (asynchronously) kick off the world by telling
 	// ourselves that it's okay to get more work units.
-	go func() { gotWork <- true }()
+	go w.signalWork(gotWork, true)
 
 	// TODO(dmaze): check for and signal stale workers
 	//
@@ -212,12 +373,11 @@ func (w *Worker) Run(ctx context.Context) error {
 		select {
 		case <-ctx.Done():
 			// Shutting down
-			// TODO(dmaze): don't return *immediately*; wait
-			// for all of the workers to clean up
 			if ticker != nil {
 				ticker.Stop()
 			}
 			heartbeater.Stop()
+			w.shutdown(gotWork, finished)
 			return nil
 
 		case notIdle := <-gotWork:
@@ -253,6 +413,14 @@ func (w *Worker) Run(ctx context.Context) error {
 			// work.
 			w.maybeDoWork(ctx, gotWork, finished, true)
 
+		case <-wake:
+			// A work spec announced new work.  If we are
+			// busy we will find it anyway; if we are idle,
+			// look now rather than at the next tick.
+			if w.systemIdle {
+				w.maybeDoWork(ctx, gotWork, finished, true)
+			}
+
 		case <-heartbeat:
 			w.heartbeat()
 			w.findStaleUnits()
@@ -260,6 +428,128 @@ func (w *Worker) Run(ctx context.Context) error {
 	}
 }
 
+// watchWork subscribes to new work announcements from every work spec
+// this worker could run that supports coordinate.WorkWatcher, and
+// merges them into a single channel, which is never closed.  Work
+// specs that do not support watching are silently left to polling;
+// other errors are passed to ErrorHandler.
+func (w *Worker) watchWork(ctx context.Context) <-chan struct{} {
+	wake := make(chan struct{}, 1)
+	names := w.WorkSpecs
+	if len(names) == 0 {
+		var err error
+		names, err = w.Namespace.WorkSpecNames()
+		if err != nil {
+			if w.ErrorHandler != nil {
+				w.ErrorHandler(err)
+			}
+			return wake
+		}
+	}
+	for _, name := range names {
+		spec, err := w.Namespace.WorkSpec(name)
+		if _, missing := err.(coordinate.ErrNoSuchWorkSpec); missing {
+			// Polling will find it if it is created later
+			continue
+		}
+		var events <-chan struct{}
+		if err == nil {
+			watcher, canWatch := spec.(coordinate.WorkWatcher)
+			if !canWatch {
+				continue
+			}
+			events, err = watcher.WatchWork(ctx)
+		}
+		if err == coordinate.ErrWatchNotSupported {
+			continue
+		}
+		if err != nil {
+			if w.ErrorHandler != nil {
+				w.ErrorHandler(err)
+			}
+			continue
+		}
+		go func() {
+			for range events {
+				select {
+				case wake <- struct{}{}:
+				default:
+				}
+			}
+		}()
+	}
+	return wake
+}
+
+// Stop stops a running Run call, as though its context were
+// cancelled, and waits for it to return, which includes waiting for
+// running tasks as described in Run.  Attempts still pending after
+// ShutdownGrace are failed, or released if ReleaseOnShutdown is set.
+// If ctx is done first, returns its error.  If Run is not running, returns nil immediately.
+func (w *Worker) Stop(ctx context.Context) error {
+	w.runLock.Lock()
+	stopRun, stopped := w.stopRun, w.stopped
+	w.runLock.Unlock()
+	if stopRun == nil {
+		return nil
+	}
+	stopRun()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// shutdown waits up to ShutdownGrace for running child workers to
+// finish, fails or releases any attempts they still have, and
+// deactivates all of the child workers.  gotWork and finished are the channels Run
+// passes to doWork.
+func (w *Worker) shutdown(gotWork <-chan bool, finished <-chan string) {
+	running := len(w.childWorkers) - len(w.idleWorkers)
+	if running > 0 && w.ShutdownGrace > 0 {
+		timer := w.Clock.Timer(w.ShutdownGrace)
+		defer timer.Stop()
+	wait:
+		for running > 0 {
+			select {
+			case <-gotWork:
+			case <-finished:
+				running--
+			case <-timer.C:
+				break wait
+			}
+		}
+	}
+
+	attempts, err := w.parentWorker.ChildAttempts()
+	if err == nil {
+		failure := map[string]interface{}{
+			"traceback": "worker shutting down",
+		}
+		for _, attempt := range attempts {
+			if w.ReleaseOnShutdown {
+				_ = attempt.Release(nil)
+			} else if attempt.Fail(failure) == nil {
+				w.countOutcome(attempt, coordinate.Failed)
+			}
+		}
+	} else if w.ErrorHandler != nil {
+		w.ErrorHandler(err)
+	}
+
+	for id, child := range w.childWorkers {
+		err = child.Deactivate()
+		if err != nil && w.ErrorHandler != nil {
+			w.ErrorHandler(err)
+		}
+		delete(w.childWorkers, id)
+		w.cancellations.Delete(id)
+	}
+	w.idleWorkers = nil
+}
+
 // getIdleChild returns the worker ID of a child worker that is not
 // currently doing anything, or an empty string.  If the idle workers
 // list is empty but the child workers list could have another worker,
@@ -328,37 +618,238 @@ func (w *Worker) maybeDoWork(ctx context.Context, gotWork chan<- bool, finished
 }
 
 // doWork gets attempts and runs them.  It assumes it is running in its
-// own goroutine.  It signals gotWork when the call to RequestAttempts
-// returns, and signals finished immediately before returning.
+// own goroutine.  It signals gotWork each time a call to
+// RequestAttempts returns, and signals finished immediately before
+// returning.  Unless KeepWarm is set, it only requests work once.
 func (w *Worker) doWork(ctx context.Context, id string, worker coordinate.Worker, gotWork chan<- bool, finished chan<- string) {
 	// When we finish, signal the finished channel with our own ID
 	defer func() {
-		finished <- id
+		select {
+		case finished <- id:
+		case <-w.stoppedChan():
+		}
 	}()
 
-	attempts, err := worker.RequestAttempts(coordinate.AttemptRequest{
+	for {
+		req, ok, err := w.attemptRequest()
+		if err == nil && !ok {
+			// Every task we could run is at its limit
+			w.signalWork(gotWork, false)
+			return
+		}
+		var attempts []coordinate.Attempt
+		if err == nil {
+			attempts, err = worker.RequestAttempts(req)
+		}
+		if err != nil {
+			// Handle the error if we can, but otherwise act just
+			// like we got no attempts back
+			if w.ErrorHandler != nil {
+				w.ErrorHandler(err)
+			}
+			w.signalWork(gotWork, false)
+			return
+		}
+		if len(attempts) == 0 {
+			// Nothing to do
+			w.signalWork(gotWork, false)
+			return
+		}
+		// Otherwise we have actual work (and at least one attempt).
+		w.signalWork(gotWork, true)
+		spec := attempts[0].WorkUnit().WorkSpec().Name()
+		attemptsRequested.WithLabelValues(w.Namespace.Name(), spec).Add(float64(len(attempts)))
+
+		w.runAttempts(ctx, id, req.Lifetime, attempts)
+
+		if !w.KeepWarm || ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// signalWork tells Run whether a call to RequestAttempts returned
+// work, unless Run has already returned.
+func (w *Worker) signalWork(gotWork chan<- bool, notIdle bool) {
+	select {
+	case gotWork <- notIdle:
+	case <-w.stoppedChan():
+	}
+}
+
+// stoppedChan returns the channel that is closed when the current
+// Run call returns.
+func (w *Worker) stoppedChan() <-chan struct{} {
+	w.runLock.Lock()
+	defer w.runLock.Unlock()
+	return w.stopped
+}
+
+// attemptRequest builds the request a child worker makes for work.
+// If any tasks are at their TaskConcurrency limits, the request only
+// names work specs for other tasks; if there are none, returns false.
+func (w *Worker) attemptRequest() (coordinate.AttemptRequest, bool, error) {
+	req := coordinate.AttemptRequest{
 		Runtimes:          w.runtimes(),
+		WorkSpecs:         w.WorkSpecs,
+		MinPriority:       w.MinPriority,
 		NumberOfWorkUnits: w.MaxAttempts,
-	})
-	if err != nil {
-		// Handle the error if we can, but otherwise act just like
-		// we got no attempts back
-		if w.ErrorHandler != nil {
-			w.ErrorHandler(err)
+	}
+	busy := w.busyTasks()
+	skip := w.skippedSpecs()
+	if len(busy) == 0 && len(skip) == 0 {
+		var err error
+		req.Lifetime, err = w.requestLifetime(req.WorkSpecs)
+		return req, err == nil, err
+	}
+
+	names := w.WorkSpecs
+	if len(names) == 0 {
+		var err error
+		names, err = w.Namespace.WorkSpecNames()
+		if err != nil {
+			return req, false, err
+		}
+	}
+	// An empty WorkSpecs means "any work spec", so this must
+	// not be nil even if nothing is added to it
+	req.WorkSpecs = []string{}
+	for _, name := range names {
+		if _, skipped := skip[name]; skipped {
+			continue
+		}
+		task, err := w.specTaskByName(name)
+		if err != nil {
+			return req, false, err
+		}
+		if _, full := busy[task]; !full {
+			req.WorkSpecs = append(req.WorkSpecs, name)
+		}
+	}
+	if len(req.WorkSpecs) == 0 {
+		return req, false, nil
+	}
+	var err error
+	req.Lifetime, err = w.requestLifetime(req.WorkSpecs)
+	return req, err == nil, err
+}
+
+// requestLifetime returns the TaskLifetimes entry shared by the tasks
+// for all of the named work specs, or by every task in Tasks if names
+// is empty.  If the tasks have different lifetimes, returns zero, and
+// runAttempts renews the attempts it gets instead.
+func (w *Worker) requestLifetime(names []string) (time.Duration, error) {
+	var tasks []string
+	if len(names) == 0 {
+		for task := range w.Tasks {
+			tasks = append(tasks, task)
+		}
+	} else {
+		for _, name := range names {
+			task, err := w.specTaskByName(name)
+			if err != nil {
+				return 0, err
+			}
+			tasks = append(tasks, task)
+		}
+	}
+	if len(tasks) == 0 {
+		return 0, nil
+	}
+	lifetime := w.TaskLifetimes[tasks[0]]
+	for _, task := range tasks[1:] {
+		if w.TaskLifetimes[task] != lifetime {
+			return 0, nil
+		}
+	}
+	return lifetime, nil
+}
+
+// busyTasks returns the set of tasks that are at their
+// TaskConcurrency limits.
+func (w *Worker) busyTasks() map[string]struct{} {
+	w.taskLock.Lock()
+	defer w.taskLock.Unlock()
+	busy := make(map[string]struct{})
+	for task, limit := range w.TaskConcurrency {
+		if limit > 0 && w.taskRunning[task] >= limit {
+			busy[task] = struct{}{}
 		}
-		gotWork <- false
-		return
 	}
-	if len(attempts) == 0 {
-		// Nothing to do
-		gotWork <- false
+	return busy
+}
+
+// startTask records that a call to the task function for task is
+// starting.  If task is already at its TaskConcurrency limit, which
+// can happen if several child workers requested work for it at
+// once, returns false and records nothing.
+func (w *Worker) startTask(task string) bool {
+	limit := w.TaskConcurrency[task]
+	if limit <= 0 {
+		return true
+	}
+	w.taskLock.Lock()
+	defer w.taskLock.Unlock()
+	if w.taskRunning[task] >= limit {
+		return false
+	}
+	w.taskRunning[task]++
+	return true
+}
+
+// endTask records that a call to the task function for task has
+// returned.
+func (w *Worker) endTask(task string) {
+	if w.TaskConcurrency[task] <= 0 {
 		return
 	}
-	// Otherwise we have actual work (and at least one attempt).
-	gotWork <- true
+	w.taskLock.Lock()
+	defer w.taskLock.Unlock()
+	w.taskRunning[task]--
+}
 
-	// See if we can find a task for the work spec
-	spec := attempts[0].WorkUnit().WorkSpec()
+// specTaskByName returns the task for a named work spec, from
+// specTasks if possible, or else by fetching the work spec.
+func (w *Worker) specTaskByName(name string) (string, error) {
+	w.taskLock.Lock()
+	task, known := w.specTasks[name]
+	w.taskLock.Unlock()
+	if known {
+		return task, nil
+	}
+	spec, err := w.Namespace.WorkSpec(name)
+	if err != nil {
+		return "", err
+	}
+	task, err = specTask(spec)
+	if err == nil {
+		w.rememberSpecTask(name, task)
+	}
+	return task, err
+}
+
+// skippedSpecs returns a copy of skipSpecs.
+func (w *Worker) skippedSpecs() map[string]struct{} {
+	w.taskLock.Lock()
+	defer w.taskLock.Unlock()
+	skip := make(map[string]struct{}, len(w.skipSpecs))
+	for name := range w.skipSpecs {
+		skip[name] = struct{}{}
+	}
+	return skip
+}
+
+// rememberSpecTask records the task for a named work spec in
+// specTasks.
+func (w *Worker) rememberSpecTask(name, task string) {
+	w.taskLock.Lock()
+	defer w.taskLock.Unlock()
+	w.specTasks[name] = task
+}
+
+// specTask returns the name of the task that runs a work spec's work
+// units: its "task" field, or else its name.
+func specTask(spec coordinate.WorkSpec) (string, error) {
 	task := spec.Name()
 	data, err := spec.Data()
 	if err == nil {
@@ -370,6 +861,21 @@ func (w *Worker) doWork(ctx context.Context, id string, worker coordinate.Worker
 			}
 		}
 	}
+	return task, err
+}
+
+// runAttempts finds the task function for a non-empty set of
+// attempts and runs it.  If there is no task function the attempts
+// are failed.  If the task is already at its TaskConcurrency limit
+// the attempts are released, so that the work units can run later
+// without this counting as a retry.
+func (w *Worker) runAttempts(ctx context.Context, id string, lifetime time.Duration, attempts []coordinate.Attempt) {
+	// See if we can find a task for the work spec
+	spec := attempts[0].WorkUnit().WorkSpec()
+	task, err := specTask(spec)
+	if err == nil {
+		w.rememberSpecTask(spec.Name(), task)
+	}
 
 	// Try to find the task function
 	var taskFn func(context.Context, []coordinate.Attempt)
@@ -380,22 +886,114 @@ func (w *Worker) doWork(ctx context.Context, id string, worker coordinate.Worker
 		}
 	}
 
+	// Make sure we understand the work spec's data, and give
+	// the work back for another worker if we do not
+	if err == nil {
+		if expected, present := w.SchemaVersions[task]; present {
+			if schemaErr := coordinate.CheckSchemaVersion(spec, expected); schemaErr != nil {
+				w.taskLock.Lock()
+				w.skipSpecs[spec.Name()] = struct{}{}
+				w.taskLock.Unlock()
+				for _, attempt := range attempts {
+					_ = attempt.Release(nil)
+				}
+				if w.ErrorHandler != nil {
+					w.ErrorHandler(schemaErr)
+				}
+				return
+			}
+		}
+	}
+
+	// Give the work back if this task is already busy enough
+	if err == nil {
+		if !w.startTask(task) {
+			for _, attempt := range attempts {
+				_ = attempt.Release(nil)
+			}
+			return
+		}
+		defer w.endTask(task)
+	}
+
+	// Extend the attempts' leases if this task wants that
+	if err == nil {
+		attempts = w.renewForTask(task, lifetime, attempts)
+		if len(attempts) == 0 {
+			return
+		}
+	}
+
 	if err == nil {
 		taskCtx, cancellation := context.WithCancel(ctx)
 		w.cancellations.Store(id, cancellation)
-		taskFn(taskCtx, attempts)
+		start := w.Clock.Now()
+		w.callTask(taskCtx, taskFn, w.countAttempts(attempts))
+		elapsed := w.Clock.Now().Sub(start)
 		// It appears to be recommended to call this; calling
 		// it multiple times is documented to have no effect
 		cancellation()
+		taskSeconds.WithLabelValues(w.Namespace.Name(), spec.Name()).Observe(elapsed.Seconds())
 	} else {
 		failure := map[string]interface{}{
 			"traceback": err.Error(),
 		}
 		// Try to fail all the attempts, ignoring errors
 		for _, attempt := range attempts {
-			_ = attempt.Fail(failure)
+			if attempt.Fail(failure) == nil {
+				w.countOutcome(attempt, coordinate.Failed)
+			}
+		}
+	}
+}
+
+// callTask calls a task function.  If the task function panics, the
+// panic is reported to ErrorHandler and the attempts are failed with
+// the stack trace.
+func (w *Worker) callTask(ctx context.Context, taskFn func(context.Context, []coordinate.Attempt), attempts []coordinate.Attempt) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		failure := map[string]interface{}{
+			"traceback": fmt.Sprintf("panic: %v\n\n%s", r, debug.Stack()),
+		}
+		// Leave alone any attempts the task completed
+		// before it panicked
+		for _, attempt := range attempts {
+			status, err := attempt.Status()
+			if err == nil && status == coordinate.Pending {
+				_ = attempt.Fail(failure)
+			}
+		}
+		if w.ErrorHandler != nil {
+			w.ErrorHandler(fmt.Errorf("task for work spec %q panicked: %v",
+				attempts[0].WorkUnit().WorkSpec().Name(), r))
+		}
+	}()
+	taskFn(ctx, attempts)
+}
+
+// renewForTask renews attempts for the lifetime configured for task
+// in TaskLifetimes, unless they were already requested with that
+// lifetime.  It returns the attempts that can still run; any attempt
+// that fails to renew is reported to ErrorHandler and left out.
+func (w *Worker) renewForTask(task string, requested time.Duration, attempts []coordinate.Attempt) []coordinate.Attempt {
+	lifetime := w.TaskLifetimes[task]
+	if lifetime <= 0 || lifetime == requested {
+		return attempts
+	}
+	renewed := make([]coordinate.Attempt, 0, len(attempts))
+	for _, attempt := range attempts {
+		err := attempt.Renew(lifetime, nil)
+		if err == nil {
+			renewed = append(renewed, attempt)
+		} else if w.ErrorHandler != nil {
+			w.ErrorHandler(err)
 		}
 	}
+	return renewed
 }
 
 // runtimes returns the configured runtimes for this worker. If no
@@ -446,6 +1044,14 @@ func (w *Worker) heartbeat() {
 	// container (e.g. the pid will always be 1 and the IP address
 	// an uninformative host-local address).
 
+	if w.ExtraHeartbeatData != nil {
+		for key, value := range w.ExtraHeartbeatData() {
+			if _, present := data[key]; !present {
+				data[key] = value
+			}
+		}
+	}
+
 	now := w.Clock.Now()
 	then := now.Add(time.Duration(15) * time.Minute)
 	err = w.parentWorker.Update(data, now, then, "RUN")
@@ -477,10 +1083,17 @@ func (w *Worker) findStaleUnits() {
 				childrenToCancel[child] = struct{}{}
 			}
 			if remaining < expirationAlarm {
-				// Proactively fail the attempt
-				err = attempt.Fail(map[string]interface{}{
-					"traceback": "timed out",
-				})
+				// Proactively fail the attempt, with
+				// the work spec's failure_data as for
+				// any other system-initiated failure
+				workSpec := attempt.WorkUnit().WorkSpec()
+				specData, _ := workSpec.Data()
+				err = attempt.Fail(coordinate.ExtractFailureData(specData, "timed out"))
+				if err == nil {
+					spec := workSpec.Name()
+					attemptsTimedOut.WithLabelValues(w.Namespace.Name(), spec).Inc()
+					w.countOutcome(attempt, coordinate.Failed)
+				}
 			}
 		}
 	}
@@ -244,6 +244,390 @@ func TestDoOneWorkDiffRuntimes(t *testing.T) {
 	s.Finish(t)
 }
 
+func TestDoOneWorkSpecs(t *testing.T) {
+	var s Suite
+	s.SetUpTest(t)
+	s.CreateSpecAndUnit(t, "sanity", "spec", "go")
+	s.CreateSpecAndUnit(t, "sanity2", "spec2", "go")
+	s.Worker.WorkSpecs = []string{"spec2"}
+	s.BootstrapWorker(t)
+
+	s.GoDoWork(t)
+	s.GetWork(t, true)
+	s.Finish(t)
+
+	assert.True(t, s.Bit)
+
+	// Only spec2 had its work done
+	for name, expected := range map[string]coordinate.WorkUnitStatus{
+		"spec":  coordinate.AvailableUnit,
+		"spec2": coordinate.FinishedUnit,
+	} {
+		spec, err := s.Namespace.WorkSpec(name)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		unit, err := spec.WorkUnit("unit")
+		if !assert.NoError(t, err) {
+			continue
+		}
+		status, err := unit.Status()
+		if assert.NoError(t, err) {
+			assert.Equal(t, expected, status, name)
+		}
+	}
+
+	// And the worker will not pick up the remaining work
+	s.GoDoWork(t)
+	s.GetWork(t, false)
+	s.Finish(t)
+}
+
+// UnitStatus returns the status of the work unit "unit" in the named
+// work spec.
+func (s *Suite) UnitStatus(t *testing.T, specName string) coordinate.WorkUnitStatus {
+	spec, err := s.Namespace.WorkSpec(specName)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	unit, err := spec.WorkUnit("unit")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	status, err := unit.Status()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return status
+}
+
+func TestTaskConcurrency(t *testing.T) {
+	var s Suite
+	s.SetUpTest(t)
+	s.Worker.Tasks["heavy"] = func(ctx context.Context, attempts []coordinate.Attempt) {
+		for _, attempt := range attempts {
+			assert.NoError(t, attempt.Finish(nil))
+		}
+	}
+	s.Worker.TaskConcurrency = map[string]int{"heavy": 1}
+	s.CreateSpecAndUnit(t, "heavy", "heavy", "go")
+	s.CreateSpecAndUnit(t, "sanity", "spec", "go")
+	s.BootstrapWorker(t)
+
+	// Pretend the heavy task is already running
+	assert.True(t, s.Worker.startTask("heavy"))
+	assert.False(t, s.Worker.startTask("heavy"))
+
+	// The worker still does other work
+	s.GoDoWork(t)
+	s.GetWork(t, true)
+	s.Finish(t)
+	assert.True(t, s.Bit)
+	assert.Equal(t, coordinate.AvailableUnit, s.UnitStatus(t, "heavy"))
+
+	// But will not pick up more heavy work
+	s.GoDoWork(t)
+	s.GetWork(t, false)
+	s.Finish(t)
+	assert.Equal(t, coordinate.AvailableUnit, s.UnitStatus(t, "heavy"))
+
+	// Until the running heavy task finishes
+	s.Worker.endTask("heavy")
+	s.GoDoWork(t)
+	s.GetWork(t, true)
+	s.Finish(t)
+	assert.Equal(t, coordinate.FinishedUnit, s.UnitStatus(t, "heavy"))
+}
+
+func TestTaskConcurrencyRace(t *testing.T) {
+	var s Suite
+	s.SetUpTest(t)
+	s.Worker.TaskConcurrency = map[string]int{"sanity": 1}
+	s.CreateSpecAndUnit(t, "sanity", "spec", "go")
+	s.BootstrapWorker(t)
+	spec, err := s.Namespace.WorkSpec("spec")
+	if !assert.NoError(t, err) {
+		return
+	}
+	meta, err := spec.Meta(false)
+	if !assert.NoError(t, err) {
+		return
+	}
+	meta.MaxRetries = 1
+	if !assert.NoError(t, spec.SetMeta(meta)) {
+		return
+	}
+
+	// A child gets work for the task, but another child takes
+	// the task's only slot before it can run
+	child, err := s.Namespace.Worker("child")
+	if !assert.NoError(t, err) {
+		return
+	}
+	attempts, err := child.RequestAttempts(coordinate.AttemptRequest{})
+	if !assert.NoError(t, err) || !assert.Len(t, attempts, 1) {
+		return
+	}
+	assert.True(t, s.Worker.startTask("sanity"))
+
+	// The task does not run, and the work unit is given back
+	s.Worker.runAttempts(context.Background(), "child", 0, attempts)
+	assert.False(t, s.Bit)
+	assert.Equal(t, coordinate.AvailableUnit, s.UnitStatus(t, "spec"))
+
+	// Giving it back did not use up its only try
+	attempts, err = child.RequestAttempts(coordinate.AttemptRequest{})
+	if assert.NoError(t, err) {
+		assert.Len(t, attempts, 1)
+	}
+	assert.Equal(t, coordinate.PendingUnit, s.UnitStatus(t, "spec"))
+}
+
+func TestTaskLifetime(t *testing.T) {
+	var s Suite
+	s.SetUpTest(t)
+	var expiration time.Time
+	s.Worker.Tasks["long"] = func(ctx context.Context, attempts []coordinate.Attempt) {
+		if assert.Len(t, attempts, 1) {
+			var err error
+			expiration, err = attempts[0].ExpirationTime()
+			assert.NoError(t, err)
+			err = attempts[0].Finish(nil)
+			assert.NoError(t, err, "finishing attempt in long")
+		}
+	}
+	s.Worker.TaskLifetimes = map[string]time.Duration{
+		"long": 2 * time.Hour,
+	}
+	s.CreateSpecAndUnit(t, "long", "spec", "go")
+	s.BootstrapWorker(t)
+
+	s.GoDoWork(t)
+	s.GetWork(t, true)
+	s.Finish(t)
+
+	assert.Equal(t, s.Clock.Now().Add(2*time.Hour), expiration)
+}
+
+func TestTaskLifetimeRequested(t *testing.T) {
+	var s Suite
+	s.SetUpTest(t)
+	s.CreateSpecAndUnit(t, "sanity", "spec", "go")
+	s.CreateSpecAndUnit(t, "sanity2", "spec2", "go")
+	s.Worker.TaskLifetimes = map[string]time.Duration{
+		"sanity": 2 * time.Hour,
+	}
+	s.BootstrapWorker(t)
+
+	// Other tasks keep the default lifetime, so the request
+	// cannot ask for one
+	req, ok, err := s.Worker.attemptRequest()
+	if assert.NoError(t, err) && assert.True(t, ok) {
+		assert.Equal(t, time.Duration(0), req.Lifetime)
+	}
+
+	// Limited to the one work spec, it can
+	s.Worker.WorkSpecs = []string{"spec"}
+	req, ok, err = s.Worker.attemptRequest()
+	if assert.NoError(t, err) && assert.True(t, ok) {
+		assert.Equal(t, 2*time.Hour, req.Lifetime)
+	}
+}
+
+func TestTaskLifetimeRenewFailure(t *testing.T) {
+	var s Suite
+	s.SetUpTest(t)
+	var reported []error
+	s.Worker.ErrorHandler = func(err error) {
+		reported = append(reported, err)
+	}
+	s.Worker.TaskLifetimes = map[string]time.Duration{
+		"sanity": 2 * time.Hour,
+	}
+	s.CreateSpecAndUnit(t, "sanity", "spec", "go")
+	s.BootstrapWorker(t)
+
+	child, err := s.Namespace.Worker("child")
+	if !assert.NoError(t, err) {
+		return
+	}
+	attempts, err := child.RequestAttempts(coordinate.AttemptRequest{})
+	if !assert.NoError(t, err) || !assert.Len(t, attempts, 1) {
+		return
+	}
+	// Something else finishes the work before it can renew
+	if !assert.NoError(t, attempts[0].Finish(nil)) {
+		return
+	}
+
+	// The task does not run, the error is reported, and the
+	// attempt is not failed on top of that
+	s.Worker.runAttempts(context.Background(), "child", 0, attempts)
+	assert.False(t, s.Bit)
+	assert.Len(t, reported, 1)
+	assert.Equal(t, coordinate.FinishedUnit, s.UnitStatus(t, "spec"))
+}
+
+func TestSchemaVersionMismatch(t *testing.T) {
+	var s Suite
+	s.SetUpTest(t)
+	var reported []error
+	s.Worker.ErrorHandler = func(err error) {
+		reported = append(reported, err)
+	}
+	s.Worker.SchemaVersions = map[string]int{"sanity": 2}
+	s.CreateSpecAndUnit(t, "sanity", "spec", "go")
+	s.BootstrapWorker(t)
+
+	s.GoDoWork(t)
+	s.GetWork(t, true)
+	s.Finish(t)
+
+	// The task never ran, the mismatch was reported, and the
+	// unit is available for a compatible worker
+	assert.False(t, s.Bit)
+	assert.Len(t, reported, 1)
+	assert.Equal(t, coordinate.AvailableUnit, s.UnitStatus(t, "spec"))
+
+	// This worker no longer asks for that work spec's work
+	_, ok, err := s.Worker.attemptRequest()
+	if assert.NoError(t, err) {
+		assert.False(t, ok)
+	}
+}
+
+func TestTaskPanic(t *testing.T) {
+	var s Suite
+	s.SetUpTest(t)
+	s.Worker.Tasks["panic"] = func(ctx context.Context, attempts []coordinate.Attempt) {
+		panic("boom")
+	}
+	var handled []error
+	s.Worker.ErrorHandler = func(err error) {
+		handled = append(handled, err)
+	}
+	s.CreateSpecAndUnit(t, "panic", "panic", "go")
+	s.CreateSpecAndUnit(t, "sanity", "spec", "go")
+	s.Worker.WorkSpecs = []string{"panic"}
+	s.BootstrapWorker(t)
+
+	s.GoDoWork(t)
+	s.GetWork(t, true)
+	s.Finish(t)
+
+	// The panic was reported, and the attempt failed with it
+	if assert.Len(t, handled, 1) {
+		assert.Contains(t, handled[0].Error(), "boom")
+	}
+	assert.Equal(t, coordinate.FailedUnit, s.UnitStatus(t, "panic"))
+	spec, err := s.Namespace.WorkSpec("panic")
+	if !assert.NoError(t, err) {
+		return
+	}
+	unit, err := spec.WorkUnit("unit")
+	if !assert.NoError(t, err) {
+		return
+	}
+	attempt, err := unit.ActiveAttempt()
+	if assert.NoError(t, err) && assert.NotNil(t, attempt) {
+		data, err := attempt.Data()
+		if assert.NoError(t, err) {
+			assert.Contains(t, data["traceback"], "panic: boom")
+		}
+	}
+
+	// The worker carries on with other work
+	s.Worker.WorkSpecs = nil
+	s.GoDoWork(t)
+	s.GetWork(t, true)
+	s.Finish(t)
+	assert.True(t, s.Bit)
+}
+
+func TestKeepWarm(t *testing.T) {
+	var s Suite
+	s.SetUpTest(t)
+	var ran []string
+	s.Worker.Tasks["record"] = func(ctx context.Context, attempts []coordinate.Attempt) {
+		for _, attempt := range attempts {
+			ran = append(ran, attempt.WorkUnit().Name())
+			err := attempt.Finish(nil)
+			assert.NoError(t, err, "finishing attempt in record")
+		}
+	}
+	spec, err := s.Namespace.SetWorkSpec(map[string]interface{}{
+		"name":    "spec",
+		"runtime": "go",
+		"task":    "record",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, name := range []string{"a", "b"} {
+		_, err = spec.AddWorkUnit(name, map[string]interface{}{}, coordinate.WorkUnitMeta{})
+		if !assert.NoError(t, err) {
+			return
+		}
+	}
+	s.Worker.KeepWarm = true
+	s.Worker.MaxAttempts = 1
+	s.BootstrapWorker(t)
+
+	// A single doWork call should run both units, one at a time,
+	// and only stop when it runs out of work
+	s.GoDoWork(t)
+	s.GetWork(t, true)
+	s.GetWork(t, true)
+	s.GetWork(t, false)
+	s.Finish(t)
+
+	assert.Equal(t, []string{"a", "b"}, ran)
+}
+
+// TestWatchWork checks that a worker watching for work starts a new
+// work unit without waiting to poll.  The mock clock never advances,
+// so polling alone would never find it.
+func TestWatchWork(t *testing.T) {
+	var s Suite
+	s.SetUpTest(t)
+	started := make(chan struct{})
+	s.Worker.Tasks["drain"] = func(ctx context.Context, attempts []coordinate.Attempt) {
+		close(started)
+		<-ctx.Done()
+		err := attempts[0].Finish(nil)
+		assert.NoError(t, err, "finishing attempt in drain")
+	}
+	spec, err := s.Namespace.SetWorkSpec(map[string]interface{}{
+		"name":    "spec",
+		"runtime": "go",
+		"task":    "drain",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	s.Worker.WatchWork = true
+	s.Worker.ShutdownGrace = time.Minute
+	s.Worker.Clock = s.Clock
+
+	result := make(chan error)
+	go func() { result <- s.Worker.Run(context.Background()) }()
+
+	// Give the worker a chance to find nothing and go idle
+	time.Sleep(50 * time.Millisecond)
+	_, err = spec.AddWorkUnit("unit", map[string]interface{}{}, coordinate.WorkUnitMeta{})
+	if assert.NoError(t, err) {
+		select {
+		case <-started:
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "work unit never started")
+		}
+	}
+
+	assert.NoError(t, s.Worker.Stop(context.Background()))
+	assert.NoError(t, <-result)
+	s.checkShutdown(t, coordinate.FinishedUnit)
+}
+
 func TestHeartbeat(t *testing.T) {
 	var s Suite
 	s.SetUpTest(t)
@@ -264,6 +648,65 @@ func TestHeartbeat(t *testing.T) {
 	}
 }
 
+func TestHeartbeatExtraData(t *testing.T) {
+	var s Suite
+	s.SetUpTest(t)
+	s.Worker.ExtraHeartbeatData = func() map[string]interface{} {
+		return map[string]interface{}{
+			"app_version": "1.2.3",
+			"queue_depth": 17,
+			"pid":         -1,
+		}
+	}
+	s.BootstrapWorker(t)
+
+	s.Worker.heartbeat()
+
+	worker, err := s.Namespace.Worker(s.Worker.WorkerID)
+	if !assert.NoError(t, err) {
+		return
+	}
+	data, err := worker.Data()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "1.2.3", data["app_version"])
+		assert.Equal(t, 17, data["queue_depth"])
+		assert.Contains(t, data, "cpus")
+		assert.NotEqual(t, -1, data["pid"])
+	}
+}
+
+// TestRunHeartbeat checks that a running worker sends a heartbeat
+// every HeartbeatInterval.
+func TestRunHeartbeat(t *testing.T) {
+	var s Suite
+	s.SetUpTest(t)
+	beats := make(chan struct{}, 10)
+	s.Worker.ExtraHeartbeatData = func() map[string]interface{} {
+		select {
+		case beats <- struct{}{}:
+		default:
+		}
+		return nil
+	}
+	s.Worker.Clock = s.Clock
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error)
+	go func() { result <- s.Worker.Run(ctx) }()
+
+	// Give the worker a chance to start its heartbeat ticker
+	time.Sleep(50 * time.Millisecond)
+	s.Clock.Add(15 * time.Second)
+	select {
+	case <-beats:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "worker never sent a heartbeat")
+	}
+
+	cancel()
+	assert.NoError(t, <-result)
+}
+
 func TestNonExpiration(t *testing.T) {
 	var s Suite
 	s.SetUpTest(t)
@@ -360,3 +803,225 @@ func TestExpirationIgnoring(t *testing.T) {
 	s.Finish(t)
 	assert.False(t, s.Bit)
 }
+
+// TestExpirationFailureData checks that a unit failed for timing out
+// records its work spec's failure_data.
+func TestExpirationFailureData(t *testing.T) {
+	var s Suite
+	s.SetUpTest(t)
+	spec, err := s.Namespace.SetWorkSpec(map[string]interface{}{
+		"name":         "spec",
+		"runtime":      "go",
+		"task":         "timeout",
+		"failure_data": map[string]interface{}{"retry": false},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	unit, err := spec.AddWorkUnit("unit", map[string]interface{}{}, coordinate.WorkUnitMeta{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	s.BootstrapWorker(t)
+
+	s.GoDoWork(t)
+	s.GetWork(t, true)
+
+	s.Clock.Add(14*time.Minute + 50*time.Second)
+	s.Worker.findStaleUnits()
+
+	data, err := unit.Data()
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]interface{}{
+			"traceback": "timed out",
+			"retry":     false,
+		}, data)
+	}
+
+	s.Finish(t)
+	assert.False(t, s.Bit)
+}
+
+// runUntilStopped starts s.Worker.Run in the background, waits for
+// started to be signaled, and then stops the worker, checking that
+// Run returns nil.
+func (s *Suite) runUntilStopped(t *testing.T, started <-chan struct{}) {
+	s.Worker.Clock = s.Clock
+	result := make(chan error)
+	go func() { result <- s.Worker.Run(context.Background()) }()
+	<-started
+	assert.NoError(t, s.Worker.Stop(context.Background()))
+	assert.NoError(t, <-result)
+}
+
+// checkShutdown checks the status of the single work unit after the
+// worker has stopped, and that all of its children are inactive.
+func (s *Suite) checkShutdown(t *testing.T, expected coordinate.WorkUnitStatus) {
+	spec, err := s.Namespace.WorkSpec("spec")
+	if !assert.NoError(t, err) {
+		return
+	}
+	unit, err := spec.WorkUnit("unit")
+	if !assert.NoError(t, err) {
+		return
+	}
+	status, err := unit.Status()
+	if assert.NoError(t, err) {
+		assert.Equal(t, expected, status)
+	}
+
+	parent, err := s.Namespace.Worker(s.Worker.WorkerID)
+	if !assert.NoError(t, err) {
+		return
+	}
+	children, err := parent.Children()
+	if assert.NoError(t, err) {
+		assert.NotEmpty(t, children)
+		for _, child := range children {
+			active, err := child.Active()
+			if assert.NoError(t, err) {
+				assert.False(t, active, child.Name())
+			}
+		}
+	}
+}
+
+func TestStopGraceful(t *testing.T) {
+	var s Suite
+	s.SetUpTest(t)
+	started := make(chan struct{})
+	s.Worker.Tasks["drain"] = func(ctx context.Context, attempts []coordinate.Attempt) {
+		close(started)
+		<-ctx.Done()
+		// Wrap up the work in progress
+		err := attempts[0].Finish(nil)
+		assert.NoError(t, err, "finishing attempt in drain")
+	}
+	s.Worker.ShutdownGrace = time.Minute
+	s.CreateSpecAndUnit(t, "drain", "spec", "go")
+
+	s.runUntilStopped(t, started)
+	s.checkShutdown(t, coordinate.FinishedUnit)
+}
+
+// runStuck runs a task that ignores its context until the worker
+// has stopped, and returns its attempt.
+func (s *Suite) runStuck(t *testing.T) coordinate.Attempt {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	var attempt coordinate.Attempt
+	s.Worker.Tasks["stuck"] = func(ctx context.Context, attempts []coordinate.Attempt) {
+		attempt = attempts[0]
+		close(started)
+		<-release
+	}
+	s.CreateSpecAndUnit(t, "stuck", "spec", "go")
+
+	s.runUntilStopped(t, started)
+	return attempt
+}
+
+func TestStopIgnoring(t *testing.T) {
+	var s Suite
+	s.SetUpTest(t)
+	attempt := s.runStuck(t)
+	s.checkShutdown(t, coordinate.FailedUnit)
+	status, err := attempt.Status()
+	if assert.NoError(t, err) {
+		assert.Equal(t, coordinate.Failed, status)
+	}
+	data, err := attempt.Data()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "worker shutting down", data["traceback"])
+	}
+}
+
+func TestStopIgnoringRelease(t *testing.T) {
+	var s Suite
+	s.SetUpTest(t)
+	s.Worker.ReleaseOnShutdown = true
+	attempt := s.runStuck(t)
+	s.checkShutdown(t, coordinate.AvailableUnit)
+	status, err := attempt.Status()
+	if assert.NoError(t, err) {
+		assert.Equal(t, coordinate.Expired, status)
+	}
+}
+
+// benchmarkBatches runs b.N single-unit batches through a complete
+// worker, and reports the average idle time between one batch's task
+// function returning and the next batch's task function starting.
+// Each task re-adds its own work unit as it finishes, so the backend
+// always holds exactly one work unit.
+func benchmarkBatches(b *testing.B, keepWarm bool) {
+	namespace, err := memory.New().Namespace("")
+	if err != nil {
+		b.Fatal(err)
+	}
+	spec, err := namespace.SetWorkSpec(map[string]interface{}{
+		"name":    "spec",
+		"runtime": "go",
+		"task":    "finish",
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	_, err = spec.AddWorkUnit("unit", map[string]interface{}{}, coordinate.WorkUnitMeta{})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var (
+		done     int
+		idle     time.Duration
+		returned time.Time
+	)
+	worker := Worker{
+		Namespace: namespace,
+		Tasks: map[string]func(context.Context, []coordinate.Attempt){
+			"finish": func(ctx context.Context, attempts []coordinate.Attempt) {
+				if !returned.IsZero() {
+					idle += time.Since(returned)
+				}
+				for _, attempt := range attempts {
+					_ = attempt.Finish(nil)
+				}
+				done += len(attempts)
+				if done >= b.N {
+					cancel()
+					return
+				}
+				_, err := spec.AddWorkUnit("unit", map[string]interface{}{}, coordinate.WorkUnitMeta{})
+				if err != nil {
+					b.Error(err)
+					cancel()
+				}
+				returned = time.Now()
+			},
+		},
+		Concurrency: 1,
+		MaxAttempts: 1,
+		KeepWarm:    keepWarm,
+	}
+
+	b.ResetTimer()
+	err = worker.Run(ctx)
+	b.StopTimer()
+	if err != nil {
+		b.Fatal(err)
+	}
+	if done > 1 {
+		b.ReportMetric(float64(idle.Nanoseconds())/float64(done-1), "idle-ns/batch")
+	}
+}
+
+func BenchmarkBatches(b *testing.B) {
+	benchmarkBatches(b, false)
+}
+
+func BenchmarkBatchesKeepWarm(b *testing.B) {
+	benchmarkBatches(b, true)
+}